@@ -0,0 +1,71 @@
+// Command backfill-search fills in articles.search_vector for articles
+// created before the full-text index (migration 026). New and edited
+// articles are indexed by trigger, so this only needs to run once after
+// migrating; it is safe to re-run and to interrupt. Articles keep their
+// updated_at, so clients do not re-sync them; the database role needs
+// permission to set session_replication_role (see
+// ArticleRepo.BackfillSearchVectors).
+//
+// Usage:
+//
+//	DATABASE_URL=... go run ./cmd/backfill-search [-batch 500]
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"folio-server/internal/logger"
+	"folio-server/internal/repository"
+)
+
+func main() {
+	batch := flag.Int("batch", 500, "articles updated per transaction")
+	flag.Parse()
+
+	logger.Init()
+
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		slog.Error("DATABASE_URL is required")
+		os.Exit(1)
+	}
+	if *batch < 1 {
+		slog.Error("batch must be positive", "batch", *batch)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	pool, err := repository.NewPool(ctx, dsn)
+	if err != nil {
+		slog.Error("failed to connect to database", "error", err)
+		os.Exit(1)
+	}
+	defer pool.Close()
+
+	articleRepo := repository.NewArticleRepo(pool)
+
+	start := time.Now()
+	var total int64
+	for {
+		n, err := articleRepo.BackfillSearchVectors(ctx, *batch)
+		if err != nil {
+			slog.Error("backfill failed", "updated", total, "error", err)
+			pool.Close()
+			os.Exit(1)
+		}
+		if n == 0 {
+			break
+		}
+		total += n
+		slog.Info("backfill progress", "updated", total)
+	}
+	slog.Info("backfill complete", "updated", total, "elapsed", time.Since(start).Round(time.Millisecond))
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"net/http"
+
+	"folio-server/internal/api/middleware"
+	"folio-server/internal/domain"
+	"folio-server/internal/service"
+)
+
+type AccountHandler struct {
+	accountService *service.AccountService
+	exportService  *service.ExportService
+}
+
+func NewAccountHandler(accountService *service.AccountService, exportService *service.ExportService) *AccountHandler {
+	return &AccountHandler{accountService: accountService, exportService: exportService}
+}
+
+// HandleDeleteAccount handles DELETE /api/v1/me
+func (h *AccountHandler) HandleDeleteAccount(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	if err := h.accountService.Delete(r.Context(), userID); err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleExportData handles POST /api/v1/me/export.
+//
+// It starts a JSON export of everything stored about the user. Progress and
+// the download link are served by the /exports endpoints.
+func (h *AccountHandler) HandleExportData(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	job, err := h.exportService.Create(r.Context(), userID, domain.ExportFormatJSON, nil)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, toExportJobResponse(job))
+}
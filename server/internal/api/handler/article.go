@@ -23,6 +23,7 @@ type articleServicer interface {
 	SubmitURL(ctx context.Context, userID string, req service.SubmitURLRequest) (*service.SubmitURLResponse, error)
 	SubmitManualContent(ctx context.Context, userID string, req service.SubmitManualContentRequest) (*service.SubmitURLResponse, error)
 	ListByUser(ctx context.Context, params repository.ListArticlesParams) (*repository.ListArticlesResult, error)
+	ListPage(ctx context.Context, params repository.ListArticlesParams, cursor string, withTotal bool) (*repository.ArticlePage, error)
 	GetByID(ctx context.Context, userID, articleID string) (*domain.Article, error)
 	Update(ctx context.Context, userID, articleID string, params repository.UpdateArticleParams) error
 	Delete(ctx context.Context, userID, articleID string) error
@@ -188,6 +189,14 @@ func (h *ArticleHandler) HandleListArticles(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	// A cursor parameter, empty for the first page, selects keyset
+	// pagination; page is then ignored. See ArticleRepo.ListPage for the
+	// ordering guarantees.
+	if r.URL.Query().Has("cursor") {
+		h.listArticlesPage(w, r, params, user.SyncEpoch)
+		return
+	}
+
 	result, err := h.articleService.ListByUser(r.Context(), params)
 	if err != nil {
 		handleServiceError(w, r, err)
@@ -206,6 +215,36 @@ func (h *ArticleHandler) HandleListArticles(w http.ResponseWriter, r *http.Reque
 	})
 }
 
+// listArticlesPage writes a keyset-paginated page of articles. The total
+// costs a count over the whole listing and is included only when
+// include_total=true.
+func (h *ArticleHandler) listArticlesPage(w http.ResponseWriter, r *http.Request, params repository.ListArticlesParams, syncEpoch int) {
+	cursor := r.URL.Query().Get("cursor")
+	withTotal := r.URL.Query().Get("include_total") == "true"
+
+	page, err := h.articleService.ListPage(r.Context(), params, cursor, withTotal)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	pagination := CursorPaginationResponse{
+		PerPage: params.PerPage,
+		HasMore: page.Next != nil,
+		Total:   page.Total,
+	}
+	if page.Next != nil {
+		next := page.Next.Encode()
+		pagination.NextCursor = &next
+	}
+	writeJSON(w, http.StatusOK, CursorListResponse{
+		Data:       page.Articles,
+		Pagination: pagination,
+		ServerTime: time.Now().UTC().Format(time.RFC3339),
+		SyncEpoch:  syncEpoch,
+	})
+}
+
 func (h *ArticleHandler) HandleGetArticle(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.UserIDFromContext(r.Context())
 	articleID := chi.URLParam(r, "id")
@@ -21,6 +21,10 @@ type mockArticleService struct {
 	submitURLFn  func(ctx context.Context, userID string, req service.SubmitURLRequest) (*service.SubmitURLResponse, error)
 	lastSubmitReq *service.SubmitURLRequest
 	lastUserID   string
+
+	lastListParams *repository.ListArticlesParams
+	lastCursor     string
+	lastWithTotal  bool
 }
 
 func (m *mockArticleService) SubmitURL(ctx context.Context, userID string, req service.SubmitURLRequest) (*service.SubmitURLResponse, error) {
@@ -36,6 +40,13 @@ func (m *mockArticleService) ListByUser(ctx context.Context, params repository.L
 	return &repository.ListArticlesResult{}, nil
 }
 
+func (m *mockArticleService) ListPage(ctx context.Context, params repository.ListArticlesParams, cursor string, withTotal bool) (*repository.ArticlePage, error) {
+	m.lastListParams = &params
+	m.lastCursor = cursor
+	m.lastWithTotal = withTotal
+	return &repository.ArticlePage{Articles: []domain.Article{}}, nil
+}
+
 func (m *mockArticleService) GetByID(ctx context.Context, userID, articleID string) (*domain.Article, error) {
 	return nil, nil
 }
@@ -773,3 +784,38 @@ func TestHandleSubmitURL_LargeMarkdown_UTF8Safe(t *testing.T) {
 		})
 	}
 }
+
+// --- HandleListArticles cursor pagination ---
+
+func TestHandleListArticles_CursorMode(t *testing.T) {
+	mockSvc := &mockArticleService{}
+	h := newTestArticleHandler(mockSvc)
+
+	req := newAuthenticatedRequest("GET", "/api/v1/articles?cursor=&per_page=10&include_total=true", "", "user-1")
+	w := httptest.NewRecorder()
+	h.HandleListArticles(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code = %d, want %d", w.Code, http.StatusOK)
+	}
+	if mockSvc.lastListParams == nil || mockSvc.lastListParams.PerPage != 10 || mockSvc.lastCursor != "" || !mockSvc.lastWithTotal {
+		t.Fatalf("ListPage params = %+v, cursor %q, total %v", mockSvc.lastListParams, mockSvc.lastCursor, mockSvc.lastWithTotal)
+	}
+
+	var resp struct {
+		Pagination map[string]any `json:"pagination"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if next, ok := resp.Pagination["next_cursor"]; !ok || next != nil || resp.Pagination["has_more"] != false {
+		t.Errorf("pagination = %v, want a last page", resp.Pagination)
+	}
+
+	// Without a cursor parameter the offset listing is used.
+	mockSvc.lastListParams = nil
+	h.HandleListArticles(httptest.NewRecorder(), newAuthenticatedRequest("GET", "/api/v1/articles?page=2", "", "user-1"))
+	if mockSvc.lastListParams != nil {
+		t.Error("offset listing should not use ListPage")
+	}
+}
@@ -2,7 +2,9 @@ package handler
 
 import (
 	"encoding/json"
+	"net"
 	"net/http"
+	"strings"
 
 	"folio-server/internal/service"
 )
@@ -27,7 +29,7 @@ func (h *AuthHandler) HandleAppleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp, err := h.authService.LoginWithApple(r.Context(), req)
+	resp, err := h.authService.LoginWithApple(r.Context(), req, sessionInfo(r, req.DeviceName))
 	if err != nil {
 		handleServiceError(w, r, err)
 		return
@@ -69,7 +71,7 @@ func (h *AuthHandler) HandleVerifyCode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp, err := h.authService.VerifyEmailCode(r.Context(), req)
+	resp, err := h.authService.VerifyEmailCode(r.Context(), req, sessionInfo(r, req.DeviceName))
 	if err != nil {
 		handleServiceError(w, r, err)
 		return
@@ -100,3 +102,29 @@ func (h *AuthHandler) HandleRefreshToken(w http.ResponseWriter, r *http.Request)
 
 	writeJSON(w, http.StatusOK, resp)
 }
+
+// sessionInfo describes the device signing in.
+func sessionInfo(r *http.Request, deviceName string) service.SessionInfo {
+	return service.SessionInfo{
+		DeviceName: deviceName,
+		UserAgent:  r.UserAgent(),
+		IP:         clientIP(r),
+	}
+}
+
+// clientIP returns the address of the client. Behind Caddy the last
+// X-Forwarded-For entry is the one the proxy added; earlier entries come
+// from the client and cannot be trusted.
+func clientIP(r *http.Request) string {
+	if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+		parts := strings.Split(fwd, ",")
+		if ip := strings.TrimSpace(parts[len(parts)-1]); ip != "" {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
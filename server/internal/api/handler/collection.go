@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"folio-server/internal/api/middleware"
+	"folio-server/internal/domain"
+	"folio-server/internal/repository"
+	"folio-server/internal/service"
+)
+
+type CollectionHandler struct {
+	collectionService *service.CollectionService
+}
+
+func NewCollectionHandler(collectionService *service.CollectionService) *CollectionHandler {
+	return &CollectionHandler{collectionService: collectionService}
+}
+
+type createCollectionRequest struct {
+	Name   string               `json:"name"`
+	Filter domain.ArticleFilter `json:"filter"`
+}
+
+type updateCollectionRequest struct {
+	Name     *string               `json:"name,omitempty"`
+	Filter   *domain.ArticleFilter `json:"filter,omitempty"`
+	Position *int                  `json:"position,omitempty"`
+}
+
+type collectionResponse struct {
+	ID           string               `json:"id"`
+	Name         string               `json:"name"`
+	Filter       domain.ArticleFilter `json:"filter"`
+	Position     int                  `json:"position"`
+	ArticleCount *int                 `json:"article_count,omitempty"`
+	CreatedAt    string               `json:"created_at"`
+	UpdatedAt    string               `json:"updated_at"`
+}
+
+func toCollectionResponse(c *domain.Collection) collectionResponse {
+	return collectionResponse{
+		ID:        c.ID,
+		Name:      c.Name,
+		Filter:    c.Filter,
+		Position:  c.Position,
+		CreatedAt: c.CreatedAt.UTC().Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt: c.UpdatedAt.UTC().Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+// HandleListCollections handles GET /api/v1/collections. Each collection
+// carries the number of articles it currently matches.
+func (h *CollectionHandler) HandleListCollections(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	collections, err := h.collectionService.List(r.Context(), userID)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	data := make([]collectionResponse, 0, len(collections))
+	for i := range collections {
+		resp := toCollectionResponse(&collections[i])
+		resp.ArticleCount = &collections[i].ArticleCount
+		data = append(data, resp)
+	}
+
+	writeJSON(w, http.StatusOK, ListResponse{
+		Data: data,
+		Pagination: PaginationResponse{
+			Page:    1,
+			PerPage: len(data),
+			Total:   len(data),
+		},
+	})
+}
+
+// HandleCreateCollection handles POST /api/v1/collections
+func (h *CollectionHandler) HandleCreateCollection(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	var req createCollectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	collection, err := h.collectionService.Create(r.Context(), userID, req.Name, req.Filter)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, toCollectionResponse(collection))
+}
+
+// HandleGetCollection handles GET /api/v1/collections/{id}
+func (h *CollectionHandler) HandleGetCollection(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	collection, err := h.collectionService.Get(r.Context(), userID, chi.URLParam(r, "id"))
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toCollectionResponse(collection))
+}
+
+// HandleUpdateCollection handles PATCH /api/v1/collections/{id}. A filter
+// in the body replaces the saved filter as a whole.
+func (h *CollectionHandler) HandleUpdateCollection(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	var req updateCollectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	collection, err := h.collectionService.Update(r.Context(), userID, chi.URLParam(r, "id"), repository.UpdateCollectionParams{
+		Name:     req.Name,
+		Filter:   req.Filter,
+		Position: req.Position,
+	})
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toCollectionResponse(collection))
+}
+
+// HandleDeleteCollection handles DELETE /api/v1/collections/{id}. The
+// articles are not affected.
+func (h *CollectionHandler) HandleDeleteCollection(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	if err := h.collectionService.Delete(r.Context(), userID, chi.URLParam(r, "id")); err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleListCollectionArticles handles GET /api/v1/collections/{id}/articles
+func (h *CollectionHandler) HandleListCollectionArticles(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if page < 1 {
+		page = defaultPage
+	}
+	if perPage < 1 {
+		perPage = defaultPerPage
+	}
+	if perPage > maxPerPage {
+		perPage = maxPerPage
+	}
+
+	result, err := h.collectionService.Articles(r.Context(), userID, chi.URLParam(r, "id"), page, perPage)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ListResponse{
+		Data: result.Articles,
+		Pagination: PaginationResponse{
+			Page:    page,
+			PerPage: perPage,
+			Total:   result.Total,
+		},
+	})
+}
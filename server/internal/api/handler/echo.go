@@ -4,11 +4,13 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 
 	"folio-server/internal/api/middleware"
 	"folio-server/internal/domain"
+	"folio-server/internal/repository"
 	"folio-server/internal/service"
 )
 
@@ -21,16 +23,59 @@ func NewEchoHandler(echoService *service.EchoService) *EchoHandler {
 }
 
 type echoCardResponse struct {
-	ID            string  `json:"id"`
-	ArticleID     string  `json:"article_id"`
-	ArticleTitle  string  `json:"article_title"`
-	CardType      string  `json:"card_type"`
-	Question      string  `json:"question"`
-	Answer        string  `json:"answer"`
-	SourceContext *string `json:"source_context,omitempty"`
-	NextReviewAt  string  `json:"next_review_at"`
-	IntervalDays  int     `json:"interval_days"`
-	ReviewCount   int     `json:"review_count"`
+	ID            string             `json:"id"`
+	ArticleID     string             `json:"article_id"`
+	ArticleTitle  string             `json:"article_title"`
+	CardType      string             `json:"card_type"`
+	Question      string             `json:"question"`
+	Answer        string             `json:"answer"`
+	SourceContext *string            `json:"source_context,omitempty"`
+	NextReviewAt  string             `json:"next_review_at"`
+	IntervalDays  int                `json:"interval_days"`
+	ReviewCount   int                `json:"review_count"`
+	HighlightID   *string            `json:"highlight_id,omitempty"`
+	Cloze         *echoClozeResponse `json:"cloze,omitempty"`
+	SuspendedAt   *string            `json:"suspended_at,omitempty"`
+	BuriedUntil   *string            `json:"buried_until,omitempty"`
+	CreatedAt     string             `json:"created_at"`
+}
+
+// echoClozeResponse lets clients render a cloze card: the question is the
+// passage with the term blanked out, and Text with the rune offsets of the
+// term lets them show it in place once revealed.
+type echoClozeResponse struct {
+	Text        string `json:"text"`
+	StartOffset int    `json:"start_offset"`
+	EndOffset   int    `json:"end_offset"`
+}
+
+func toEchoCardResponse(c *domain.EchoCard) echoCardResponse {
+	resp := echoCardResponse{
+		ID:            c.ID,
+		ArticleID:     c.ArticleID,
+		ArticleTitle:  c.ArticleTitle,
+		CardType:      string(c.CardType),
+		Question:      c.ClozeQuestion(),
+		Answer:        c.Answer,
+		SourceContext: c.SourceContext,
+		NextReviewAt:  c.NextReviewAt.UTC().Format("2006-01-02T15:04:05Z07:00"),
+		IntervalDays:  c.IntervalDays,
+		ReviewCount:   c.ReviewCount,
+		HighlightID:   c.HighlightID,
+		CreatedAt:     c.CreatedAt.UTC().Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if c.CardType == domain.EchoCardCloze && c.ClozeStart != nil && c.ClozeEnd != nil {
+		resp.Cloze = &echoClozeResponse{Text: c.Question, StartOffset: *c.ClozeStart, EndOffset: *c.ClozeEnd}
+	}
+	if c.SuspendedAt != nil {
+		s := c.SuspendedAt.UTC().Format("2006-01-02T15:04:05Z07:00")
+		resp.SuspendedAt = &s
+	}
+	if c.BuriedUntil != nil && c.BuriedUntil.After(time.Now()) {
+		s := c.BuriedUntil.UTC().Format("2006-01-02T15:04:05Z07:00")
+		resp.BuriedUntil = &s
+	}
+	return resp
 }
 
 type getTodayResponse struct {
@@ -57,19 +102,8 @@ func (h *EchoHandler) HandleGetToday(w http.ResponseWriter, r *http.Request) {
 	}
 
 	data := make([]echoCardResponse, 0, len(cards))
-	for _, c := range cards {
-		data = append(data, echoCardResponse{
-			ID:            c.ID,
-			ArticleID:     c.ArticleID,
-			ArticleTitle:  c.ArticleTitle,
-			CardType:      string(c.CardType),
-			Question:      c.Question,
-			Answer:        c.Answer,
-			SourceContext: c.SourceContext,
-			NextReviewAt:  c.NextReviewAt.UTC().Format("2006-01-02T15:04:05Z07:00"),
-			IntervalDays:  c.IntervalDays,
-			ReviewCount:   c.ReviewCount,
-		})
+	for i := range cards {
+		data = append(data, toEchoCardResponse(&cards[i]))
 	}
 
 	writeJSON(w, http.StatusOK, getTodayResponse{
@@ -80,11 +114,27 @@ func (h *EchoHandler) HandleGetToday(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// submitReviewRequest grades a review with rating ("again", "hard", "good" or
+// "easy"). Older clients send a binary result instead, which is graded from
+// the response time.
 type submitReviewRequest struct {
-	Result         string `json:"result"`
+	Rating         string `json:"rating,omitempty"`
+	Result         string `json:"result,omitempty"`
 	ResponseTimeMs *int   `json:"response_time_ms,omitempty"`
 }
 
+// rating returns the grade of the review, or false if the request has none.
+func (req submitReviewRequest) rating() (domain.EchoRating, bool) {
+	if req.Rating != "" {
+		return domain.ParseEchoRating(req.Rating)
+	}
+	result := domain.EchoReviewResult(req.Result)
+	if result != domain.EchoRemembered && result != domain.EchoForgot {
+		return 0, false
+	}
+	return domain.RatingFromResult(result, req.ResponseTimeMs), true
+}
+
 type streakResponse struct {
 	WeeklyRate      int    `json:"weekly_rate"`
 	ConsecutiveDays int    `json:"consecutive_days"`
@@ -94,6 +144,8 @@ type streakResponse struct {
 type submitReviewResponse struct {
 	NextReviewAt string         `json:"next_review_at"`
 	IntervalDays int            `json:"interval_days"`
+	Stability    float64        `json:"stability"`
+	Difficulty   float64        `json:"difficulty"`
 	ReviewCount  int            `json:"review_count"`
 	CorrectCount int            `json:"correct_count"`
 	Streak       streakResponse `json:"streak"`
@@ -109,13 +161,13 @@ func (h *EchoHandler) HandleSubmitReview(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	result := domain.EchoReviewResult(req.Result)
-	if result != domain.EchoRemembered && result != domain.EchoForgot {
-		writeError(w, http.StatusBadRequest, "result must be \"remembered\" or \"forgot\"")
+	rating, ok := req.rating()
+	if !ok {
+		writeError(w, http.StatusBadRequest, "rating must be \"again\", \"hard\", \"good\" or \"easy\"")
 		return
 	}
 
-	rv, err := h.echoService.SubmitReview(r.Context(), userID, cardID, result, req.ResponseTimeMs)
+	rv, err := h.echoService.SubmitReview(r.Context(), userID, cardID, rating, req.ResponseTimeMs)
 	if err != nil {
 		handleServiceError(w, r, err)
 		return
@@ -124,6 +176,8 @@ func (h *EchoHandler) HandleSubmitReview(w http.ResponseWriter, r *http.Request)
 	writeJSON(w, http.StatusOK, submitReviewResponse{
 		NextReviewAt: rv.NextReviewAt.UTC().Format("2006-01-02T15:04:05Z07:00"),
 		IntervalDays: rv.IntervalDays,
+		Stability:    rv.Stability,
+		Difficulty:   rv.Difficulty,
 		ReviewCount:  rv.ReviewCount,
 		CorrectCount: rv.CorrectCount,
 		Streak: streakResponse{
@@ -133,3 +187,178 @@ func (h *EchoHandler) HandleSubmitReview(w http.ResponseWriter, r *http.Request)
 		},
 	})
 }
+
+type createEchoCardRequest struct {
+	ArticleID     string  `json:"article_id"`
+	HighlightID   *string `json:"highlight_id,omitempty"`
+	Question      string  `json:"question"`
+	Answer        string  `json:"answer"`
+	SourceContext *string `json:"source_context,omitempty"`
+}
+
+type updateEchoCardRequest struct {
+	Question *string `json:"question,omitempty"`
+	Answer   *string `json:"answer,omitempty"`
+}
+
+// HandleListCards handles GET /api/v1/echo/cards
+//
+// Optional filters: article_id, and suspended=true|false.
+func (h *EchoHandler) HandleListCards(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+	q := r.URL.Query()
+
+	page, _ := strconv.Atoi(q.Get("page"))
+	perPage, _ := strconv.Atoi(q.Get("per_page"))
+	if page < 1 {
+		page = defaultPage
+	}
+	if perPage < 1 {
+		perPage = defaultPerPage
+	}
+	if perPage > maxPerPage {
+		perPage = maxPerPage
+	}
+
+	params := repository.ListEchoCardsParams{UserID: userID, Page: page, PerPage: perPage}
+	if v := q.Get("article_id"); v != "" {
+		params.ArticleID = &v
+	}
+	if v := q.Get("suspended"); v != "" {
+		suspended, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "suspended must be true or false")
+			return
+		}
+		params.Suspended = &suspended
+	}
+
+	result, err := h.echoService.ListCards(r.Context(), params)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	data := make([]echoCardResponse, 0, len(result.Cards))
+	for i := range result.Cards {
+		data = append(data, toEchoCardResponse(&result.Cards[i]))
+	}
+
+	writeJSON(w, http.StatusOK, ListResponse{
+		Data: data,
+		Pagination: PaginationResponse{
+			Page:    page,
+			PerPage: perPage,
+			Total:   result.Total,
+		},
+	})
+}
+
+// HandleCreateCard handles POST /api/v1/echo/cards
+//
+// Creates a user-written card for an article, or for a highlight, in which
+// case article_id may be left out.
+func (h *EchoHandler) HandleCreateCard(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	var req createEchoCardRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	card, err := h.echoService.CreateCard(r.Context(), userID, service.CreateEchoCardInput{
+		ArticleID:     req.ArticleID,
+		HighlightID:   req.HighlightID,
+		Question:      req.Question,
+		Answer:        req.Answer,
+		SourceContext: req.SourceContext,
+	})
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, toEchoCardResponse(card))
+}
+
+// HandleGetCard handles GET /api/v1/echo/cards/{id}
+func (h *EchoHandler) HandleGetCard(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	card, err := h.echoService.GetCard(r.Context(), userID, chi.URLParam(r, "id"))
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toEchoCardResponse(card))
+}
+
+// HandleUpdateCard handles PATCH /api/v1/echo/cards/{id}
+func (h *EchoHandler) HandleUpdateCard(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	var req updateEchoCardRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	card, err := h.echoService.UpdateCard(r.Context(), userID, chi.URLParam(r, "id"), req.Question, req.Answer)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toEchoCardResponse(card))
+}
+
+// HandleSuspendCard handles POST /api/v1/echo/cards/{id}/suspend
+func (h *EchoHandler) HandleSuspendCard(w http.ResponseWriter, r *http.Request) {
+	h.setSuspended(w, r, true)
+}
+
+// HandleUnsuspendCard handles POST /api/v1/echo/cards/{id}/unsuspend
+func (h *EchoHandler) HandleUnsuspendCard(w http.ResponseWriter, r *http.Request) {
+	h.setSuspended(w, r, false)
+}
+
+func (h *EchoHandler) setSuspended(w http.ResponseWriter, r *http.Request, suspended bool) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	card, err := h.echoService.SuspendCard(r.Context(), userID, chi.URLParam(r, "id"), suspended)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toEchoCardResponse(card))
+}
+
+// HandleBuryCard handles POST /api/v1/echo/cards/{id}/bury
+//
+// The card is left out of due cards until tomorrow.
+func (h *EchoHandler) HandleBuryCard(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	card, err := h.echoService.BuryCard(r.Context(), userID, chi.URLParam(r, "id"))
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toEchoCardResponse(card))
+}
+
+// HandleDeleteCard handles DELETE /api/v1/echo/cards/{id}
+func (h *EchoHandler) HandleDeleteCard(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	if err := h.echoService.DeleteCard(r.Context(), userID, chi.URLParam(r, "id")); err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"folio-server/internal/api/middleware"
+	"folio-server/internal/domain"
+	"folio-server/internal/service"
+)
+
+type ExportHandler struct {
+	exportService *service.ExportService
+}
+
+func NewExportHandler(exportService *service.ExportService) *ExportHandler {
+	return &ExportHandler{exportService: exportService}
+}
+
+type createExportRequest struct {
+	Format       string  `json:"format"`
+	CollectionID *string `json:"collection_id"`
+}
+
+type exportJobResponse struct {
+	ID           string  `json:"id"`
+	Status       string  `json:"status"`
+	Format       string  `json:"format"`
+	CollectionID *string `json:"collection_id,omitempty"`
+	ArticleCount int     `json:"article_count"`
+	SizeBytes    int64   `json:"size_bytes"`
+	Error        *string `json:"error,omitempty"`
+	CreatedAt    string  `json:"created_at"`
+	FinishedAt   *string `json:"finished_at,omitempty"`
+	ExpiresAt    *string `json:"expires_at,omitempty"`
+}
+
+func toExportJobResponse(j *domain.ExportJob) exportJobResponse {
+	resp := exportJobResponse{
+		ID:           j.ID,
+		Status:       string(j.Status),
+		Format:       string(j.Format),
+		CollectionID: j.CollectionID,
+		ArticleCount: j.ArticleCount,
+		SizeBytes:    j.SizeBytes,
+		Error:        j.Error,
+		CreatedAt:    j.CreatedAt.UTC().Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if j.FinishedAt != nil {
+		s := j.FinishedAt.UTC().Format("2006-01-02T15:04:05Z07:00")
+		resp.FinishedAt = &s
+	}
+	if j.ExpiresAt != nil {
+		s := j.ExpiresAt.UTC().Format("2006-01-02T15:04:05Z07:00")
+		resp.ExpiresAt = &s
+	}
+	return resp
+}
+
+// HandleCreateExport handles POST /api/v1/exports.
+//
+// The optional body {"format": "markdown" | "json"} selects the archive
+// format; the default is a Markdown vault. With "collection_id", a Markdown
+// vault of just that collection's articles is built instead.
+func (h *ExportHandler) HandleCreateExport(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	var req createExportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	format := domain.ExportFormat(req.Format)
+	if format == "" {
+		format = domain.ExportFormatMarkdown
+	}
+
+	job, err := h.exportService.Create(r.Context(), userID, format, req.CollectionID)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, toExportJobResponse(job))
+}
+
+// HandleListExports handles GET /api/v1/exports
+func (h *ExportHandler) HandleListExports(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	jobs, err := h.exportService.ListByUser(r.Context(), userID)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	data := make([]exportJobResponse, 0, len(jobs))
+	for i := range jobs {
+		data = append(data, toExportJobResponse(&jobs[i]))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"data": data})
+}
+
+// HandleGetExport handles GET /api/v1/exports/{id}
+func (h *ExportHandler) HandleGetExport(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	job, err := h.exportService.Get(r.Context(), userID, chi.URLParam(r, "id"))
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toExportJobResponse(job))
+}
+
+// HandleDownloadExport handles GET /api/v1/exports/{id}/download by
+// redirecting to a short-lived signed URL for the archive.
+func (h *ExportHandler) HandleDownloadExport(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	url, err := h.exportService.DownloadURL(r.Context(), userID, chi.URLParam(r, "id"))
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, url, http.StatusFound)
+}
@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"folio-server/internal/api/middleware"
+	"folio-server/internal/domain"
+	"folio-server/internal/repository"
+	"folio-server/internal/service"
+)
+
+type FeedHandler struct {
+	feedService *service.FeedService
+}
+
+func NewFeedHandler(feedService *service.FeedService) *FeedHandler {
+	return &FeedHandler{feedService: feedService}
+}
+
+type createFeedRequest struct {
+	URL   string  `json:"url"`
+	Title *string `json:"title,omitempty"`
+}
+
+type updateFeedRequest struct {
+	Title    *string `json:"title,omitempty"`
+	IsActive *bool   `json:"is_active,omitempty"`
+}
+
+type feedResponse struct {
+	ID           string  `json:"id"`
+	URL          string  `json:"url"`
+	Title        *string `json:"title,omitempty"`
+	SiteURL      *string `json:"site_url,omitempty"`
+	IsActive     bool    `json:"is_active"`
+	LastPolledAt *string `json:"last_polled_at,omitempty"`
+	LastError    *string `json:"last_error,omitempty"`
+	ErrorCount   int     `json:"error_count"`
+	CreatedAt    string  `json:"created_at"`
+}
+
+func toFeedResponse(f *domain.Feed) feedResponse {
+	resp := feedResponse{
+		ID:         f.ID,
+		URL:        f.URL,
+		Title:      f.Title,
+		SiteURL:    f.SiteURL,
+		IsActive:   f.IsActive,
+		LastError:  f.LastError,
+		ErrorCount: f.ErrorCount,
+		CreatedAt:  f.CreatedAt.UTC().Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if f.LastPolledAt != nil {
+		s := f.LastPolledAt.UTC().Format("2006-01-02T15:04:05Z07:00")
+		resp.LastPolledAt = &s
+	}
+	return resp
+}
+
+// HandleListFeeds handles GET /api/v1/feeds
+func (h *FeedHandler) HandleListFeeds(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	feeds, err := h.feedService.ListByUser(r.Context(), userID)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	data := make([]feedResponse, 0, len(feeds))
+	for i := range feeds {
+		data = append(data, toFeedResponse(&feeds[i]))
+	}
+
+	writeJSON(w, http.StatusOK, ListResponse{
+		Data: data,
+		Pagination: PaginationResponse{
+			Page:    1,
+			PerPage: len(data),
+			Total:   len(data),
+		},
+	})
+}
+
+// HandleCreateFeed handles POST /api/v1/feeds
+func (h *FeedHandler) HandleCreateFeed(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	var req createFeedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.URL == "" {
+		writeError(w, http.StatusBadRequest, "url is required")
+		return
+	}
+
+	feed, err := h.feedService.Subscribe(r.Context(), userID, req.URL, req.Title)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, toFeedResponse(feed))
+}
+
+// HandleGetFeed handles GET /api/v1/feeds/{id}
+func (h *FeedHandler) HandleGetFeed(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+	feedID := chi.URLParam(r, "id")
+
+	feed, err := h.feedService.Get(r.Context(), userID, feedID)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toFeedResponse(feed))
+}
+
+// HandleUpdateFeed handles PUT /api/v1/feeds/{id}
+func (h *FeedHandler) HandleUpdateFeed(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+	feedID := chi.URLParam(r, "id")
+
+	var req updateFeedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	feed, err := h.feedService.Update(r.Context(), userID, feedID, repository.UpdateFeedParams{
+		Title:    req.Title,
+		IsActive: req.IsActive,
+	})
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toFeedResponse(feed))
+}
+
+// HandleDeleteFeed handles DELETE /api/v1/feeds/{id}
+func (h *FeedHandler) HandleDeleteFeed(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+	feedID := chi.URLParam(r, "id")
+
+	if err := h.feedService.Delete(r.Context(), userID, feedID); err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleRefreshFeed handles POST /api/v1/feeds/{id}/refresh
+func (h *FeedHandler) HandleRefreshFeed(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+	feedID := chi.URLParam(r, "id")
+
+	if err := h.feedService.Refresh(r.Context(), userID, feedID); err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "queued"})
+}
@@ -22,6 +22,7 @@ type createHighlightRequest struct {
 	Text        string `json:"text"`
 	StartOffset int    `json:"start_offset"`
 	EndOffset   int    `json:"end_offset"`
+	Page        *int   `json:"page,omitempty"`
 }
 
 type highlightResponse struct {
@@ -30,6 +31,7 @@ type highlightResponse struct {
 	Text        string  `json:"text"`
 	StartOffset int     `json:"start_offset"`
 	EndOffset   int     `json:"end_offset"`
+	Page        *int    `json:"page,omitempty"`
 	Color       string  `json:"color"`
 	Note        *string `json:"note,omitempty"`
 	CreatedAt   string  `json:"created_at"`
@@ -53,7 +55,7 @@ func (h *HighlightHandler) HandleCreateHighlight(w http.ResponseWriter, r *http.
 
 	highlight, err := h.highlightService.CreateHighlight(
 		r.Context(), userID, articleID,
-		req.Text, req.StartOffset, req.EndOffset,
+		req.Text, req.StartOffset, req.EndOffset, req.Page,
 	)
 	if err != nil {
 		handleServiceError(w, r, err)
@@ -66,6 +68,7 @@ func (h *HighlightHandler) HandleCreateHighlight(w http.ResponseWriter, r *http.
 		Text:        highlight.Text,
 		StartOffset: highlight.StartOffset,
 		EndOffset:   highlight.EndOffset,
+		Page:        highlight.Page,
 		Color:       highlight.Color,
 		Note:        highlight.Note,
 		CreatedAt:   highlight.CreatedAt.UTC().Format("2006-01-02T15:04:05Z07:00"),
@@ -91,6 +94,7 @@ func (h *HighlightHandler) HandleGetHighlights(w http.ResponseWriter, r *http.Re
 			Text:        hl.Text,
 			StartOffset: hl.StartOffset,
 			EndOffset:   hl.EndOffset,
+			Page:        hl.Page,
 			Color:       hl.Color,
 			Note:        hl.Note,
 			CreatedAt:   hl.CreatedAt.UTC().Format("2006-01-02T15:04:05Z07:00"),
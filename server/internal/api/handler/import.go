@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"folio-server/internal/api/middleware"
+	"folio-server/internal/domain"
+	"folio-server/internal/service"
+)
+
+type ImportHandler struct {
+	importService *service.ImportService
+}
+
+func NewImportHandler(importService *service.ImportService) *ImportHandler {
+	return &ImportHandler{importService: importService}
+}
+
+type importJobResponse struct {
+	ID             string  `json:"id"`
+	Source         string  `json:"source"`
+	Status         string  `json:"status"`
+	TotalItems     int     `json:"total_items"`
+	ProcessedItems int     `json:"processed_items"`
+	ImportedItems  int     `json:"imported_items"`
+	SkippedItems   int     `json:"skipped_items"`
+	FailedItems    int     `json:"failed_items"`
+	Progress       float64 `json:"progress"`
+	Error          *string `json:"error,omitempty"`
+	CreatedAt      string  `json:"created_at"`
+	FinishedAt     *string `json:"finished_at,omitempty"`
+}
+
+func toImportJobResponse(j *domain.ImportJob) importJobResponse {
+	resp := importJobResponse{
+		ID:             j.ID,
+		Source:         j.Source,
+		Status:         string(j.Status),
+		TotalItems:     j.TotalItems,
+		ProcessedItems: j.ProcessedItems(),
+		ImportedItems:  j.ImportedItems,
+		SkippedItems:   j.SkippedItems,
+		FailedItems:    j.FailedItems,
+		Error:          j.Error,
+		CreatedAt:      j.CreatedAt.UTC().Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if j.TotalItems > 0 {
+		resp.Progress = float64(resp.ProcessedItems) / float64(j.TotalItems)
+	}
+	if j.FinishedAt != nil {
+		s := j.FinishedAt.UTC().Format("2006-01-02T15:04:05Z07:00")
+		resp.FinishedAt = &s
+	}
+	return resp
+}
+
+// HandleCreateImport handles POST /api/v1/imports.
+//
+// The export file is sent as multipart/form-data in a "file" field, or as the
+// raw request body. The format (Pocket HTML/CSV, Instapaper CSV, Omnivore
+// JSON or zip, Netscape bookmarks) is detected from the content.
+func (h *ImportHandler) HandleCreateImport(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+	extendUploadDeadline(w)
+
+	data, err := readImportFile(r)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeError(w, http.StatusRequestEntityTooLarge, "import file too large")
+			return
+		}
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	job, err := h.importService.Create(r.Context(), userID, data)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, toImportJobResponse(job))
+}
+
+// HandleListImports handles GET /api/v1/imports
+func (h *ImportHandler) HandleListImports(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if page < 1 {
+		page = defaultPage
+	}
+	if perPage < 1 {
+		perPage = defaultPerPage
+	}
+	if perPage > maxPerPage {
+		perPage = maxPerPage
+	}
+
+	jobs, total, err := h.importService.ListByUser(r.Context(), userID, page, perPage)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	data := make([]importJobResponse, 0, len(jobs))
+	for i := range jobs {
+		data = append(data, toImportJobResponse(&jobs[i]))
+	}
+
+	writeJSON(w, http.StatusOK, ListResponse{
+		Data: data,
+		Pagination: PaginationResponse{
+			Page:    page,
+			PerPage: perPage,
+			Total:   total,
+		},
+	})
+}
+
+// HandleGetImport handles GET /api/v1/imports/{id}
+func (h *ImportHandler) HandleGetImport(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	job, err := h.importService.Get(r.Context(), userID, chi.URLParam(r, "id"))
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toImportJobResponse(job))
+}
+
+// readImportFile returns the uploaded export from either body format.
+func readImportFile(r *http.Request) ([]byte, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) == 0 {
+			return nil, errors.New("file is required")
+		}
+		return data, nil
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, errors.New("invalid multipart body")
+	}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil, errors.New("file is required")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+		data, err := io.ReadAll(part)
+		part.Close()
+		if err != nil {
+			return nil, err
+		}
+		if len(data) == 0 {
+			return nil, errors.New("file is required")
+		}
+		return data, nil
+	}
+}
@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"folio-server/internal/api/middleware"
+	"folio-server/internal/domain"
+	"folio-server/internal/service"
+)
+
+type MailHandler struct {
+	mailService   *service.MailService
+	webhookSecret string
+}
+
+// NewMailHandler creates the mail handler. An empty webhookSecret disables
+// the raw-message webhook.
+func NewMailHandler(mailService *service.MailService, webhookSecret string) *MailHandler {
+	return &MailHandler{mailService: mailService, webhookSecret: webhookSecret}
+}
+
+type mailSenderResponse struct {
+	ID        string `json:"id"`
+	Pattern   string `json:"pattern"`
+	CreatedAt string `json:"created_at"`
+}
+
+type mailInboxResponse struct {
+	Address        string               `json:"address"`
+	AllowedSenders []mailSenderResponse `json:"allowed_senders"`
+}
+
+type addMailSenderRequest struct {
+	Pattern string `json:"pattern"`
+}
+
+func toMailSenderResponse(s *domain.MailSender) mailSenderResponse {
+	return mailSenderResponse{
+		ID:        s.ID,
+		Pattern:   s.Pattern,
+		CreatedAt: s.CreatedAt.UTC().Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+func toMailInboxResponse(info *service.MailInboxInfo) mailInboxResponse {
+	resp := mailInboxResponse{
+		Address:        info.Address,
+		AllowedSenders: make([]mailSenderResponse, 0, len(info.Senders)),
+	}
+	for i := range info.Senders {
+		resp.AllowedSenders = append(resp.AllowedSenders, toMailSenderResponse(&info.Senders[i]))
+	}
+	return resp
+}
+
+// HandleGetInbox handles GET /api/v1/mail/inbox
+func (h *MailHandler) HandleGetInbox(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	info, err := h.mailService.GetInbox(r.Context(), userID)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toMailInboxResponse(info))
+}
+
+// HandleRotateInbox handles POST /api/v1/mail/inbox/rotate
+func (h *MailHandler) HandleRotateInbox(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	info, err := h.mailService.RotateAddress(r.Context(), userID)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toMailInboxResponse(info))
+}
+
+// HandleListSenders handles GET /api/v1/mail/senders
+func (h *MailHandler) HandleListSenders(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	senders, err := h.mailService.ListSenders(r.Context(), userID)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	data := make([]mailSenderResponse, 0, len(senders))
+	for i := range senders {
+		data = append(data, toMailSenderResponse(&senders[i]))
+	}
+
+	writeJSON(w, http.StatusOK, ListResponse{
+		Data: data,
+		Pagination: PaginationResponse{
+			Page:    1,
+			PerPage: len(data),
+			Total:   len(data),
+		},
+	})
+}
+
+// HandleAddSender handles POST /api/v1/mail/senders
+func (h *MailHandler) HandleAddSender(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	var req addMailSenderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Pattern == "" {
+		writeError(w, http.StatusBadRequest, "pattern is required")
+		return
+	}
+
+	sender, err := h.mailService.AddSender(r.Context(), userID, req.Pattern)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, toMailSenderResponse(sender))
+}
+
+// HandleDeleteSender handles DELETE /api/v1/mail/senders/{id}
+func (h *MailHandler) HandleDeleteSender(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+	senderID := chi.URLParam(r, "id")
+
+	if err := h.mailService.RemoveSender(r.Context(), userID, senderID); err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleWebhook handles POST /api/v1/webhook/mail.
+// This is a PUBLIC endpoint for inbound mail relays. The body is the raw
+// RFC 822 message; X-Envelope-From and X-Envelope-To (comma separated) are
+// optional, otherwise recipients are read from the message headers.
+func (h *MailHandler) HandleWebhook(w http.ResponseWriter, r *http.Request) {
+	if h.webhookSecret == "" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	secret := r.Header.Get("X-Mail-Webhook-Secret")
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(h.webhookSecret)) != 1 {
+		writeError(w, http.StatusUnauthorized, "invalid webhook secret")
+		return
+	}
+
+	raw, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeError(w, http.StatusRequestEntityTooLarge, "message too large")
+			return
+		}
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if len(raw) == 0 {
+		writeError(w, http.StatusBadRequest, "empty message")
+		return
+	}
+
+	var rcpts []string
+	for _, addr := range strings.Split(r.Header.Get("X-Envelope-To"), ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			rcpts = append(rcpts, addr)
+		}
+	}
+
+	result, err := h.mailService.Ingest(r.Context(), r.Header.Get("X-Envelope-From"), rcpts, raw)
+	if err != nil {
+		slog.Info("mail webhook: ingest failed", "error", err)
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]any{"articles": result.Articles})
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"folio-server/internal/api/middleware"
+	"folio-server/internal/service"
+)
+
+type PDFHandler struct {
+	pdfService *service.PDFService
+}
+
+func NewPDFHandler(pdfService *service.PDFService) *PDFHandler {
+	return &PDFHandler{pdfService: pdfService}
+}
+
+// pdfMagic is the header every PDF file starts with.
+var pdfMagic = []byte("%PDF-")
+
+// HandleUploadPDF handles POST /api/v1/articles/pdf.
+//
+// The file is sent either as multipart/form-data (fields "file", optional
+// "title" and repeated "tag_ids") or as a raw application/pdf body with an
+// optional ?title= query parameter.
+func (h *PDFHandler) HandleUploadPDF(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+	extendUploadDeadline(w)
+
+	req, err := readPDFUpload(r)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeError(w, http.StatusRequestEntityTooLarge, "PDF too large")
+			return
+		}
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !bytes.HasPrefix(req.Data, pdfMagic) {
+		writeError(w, http.StatusBadRequest, "file is not a PDF")
+		return
+	}
+
+	resp, err := h.pdfService.Upload(r.Context(), userID, req)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, resp)
+}
+
+// readPDFUpload reads the uploaded file and its metadata from either body format.
+func readPDFUpload(r *http.Request) (service.UploadPDFRequest, error) {
+	var req service.UploadPDFRequest
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "multipart/form-data":
+		mr, err := r.MultipartReader()
+		if err != nil {
+			return req, errors.New("invalid multipart body")
+		}
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return req, err
+			}
+			switch part.FormName() {
+			case "file":
+				req.Filename = part.FileName()
+				req.Data, err = io.ReadAll(part)
+			case "title":
+				var b []byte
+				b, err = io.ReadAll(io.LimitReader(part, 1024))
+				if title := strings.TrimSpace(string(b)); title != "" {
+					req.Title = &title
+				}
+			case "tag_ids":
+				var b []byte
+				b, err = io.ReadAll(io.LimitReader(part, 256))
+				if id := strings.TrimSpace(string(b)); id != "" {
+					req.TagIDs = append(req.TagIDs, id)
+				}
+			}
+			part.Close()
+			if err != nil {
+				return req, err
+			}
+		}
+		if req.Data == nil {
+			return req, errors.New("file is required")
+		}
+	case "application/pdf", "application/octet-stream":
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return req, err
+		}
+		req.Data = data
+		if title := strings.TrimSpace(r.URL.Query().Get("title")); title != "" {
+			req.Title = &title
+		}
+	default:
+		return req, errors.New("content type must be multipart/form-data or application/pdf")
+	}
+
+	if len(req.Data) == 0 {
+		return req, errors.New("file is empty")
+	}
+	return req, nil
+}
@@ -3,25 +3,59 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
 	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
 
 	"folio-server/internal/api/middleware"
+	"folio-server/internal/domain"
+	"folio-server/internal/repository"
 	"folio-server/internal/service"
 )
 
+// ragStreamWriteTimeout replaces the server's write timeout for streamed
+// answers, which outlast it.
+const ragStreamWriteTimeout = 90 * time.Second
+
 // RAGHandler handles RAG (Retrieval-Augmented Generation) endpoints.
 type RAGHandler struct {
-	ragService *service.RAGService
+	ragService        *service.RAGService
+	collectionService *service.CollectionService
 }
 
 // NewRAGHandler creates a new RAGHandler.
-func NewRAGHandler(ragService *service.RAGService) *RAGHandler {
-	return &RAGHandler{ragService: ragService}
+func NewRAGHandler(ragService *service.RAGService, collectionService *service.CollectionService) *RAGHandler {
+	return &RAGHandler{ragService: ragService, collectionService: collectionService}
 }
 
+// ragScopeMaxArticles caps an explicit article list in a query scope, and
+// how many of a collection's articles (best matches first) a scope uses.
+const ragScopeMaxArticles = 200
+
 type ragQueryRequest struct {
-	Question       string  `json:"question"`
-	ConversationID *string `json:"conversation_id"`
+	Question       string           `json:"question"`
+	ConversationID *string          `json:"conversation_id"`
+	Scope          *ragScopeRequest `json:"scope"`
+}
+
+// ragScopeRequest limits a query to matching articles; see domain.RAGScope.
+type ragScopeRequest struct {
+	TagIDs        []string   `json:"tag_ids"`
+	Categories    []string   `json:"categories"`
+	CreatedAfter  *time.Time `json:"created_after"`
+	CreatedBefore *time.Time `json:"created_before"`
+	SourceTypes   []string   `json:"source_types"`
+	FavoritesOnly bool       `json:"favorites_only"`
+	ArticleIDs    []string   `json:"article_ids"`
+	// CollectionID limits the query to the articles the collection matches
+	// now, intersected with ArticleIDs when both are set.
+	CollectionID *string `json:"collection_id"`
 }
 
 type ragSourceResponse struct {
@@ -31,6 +65,15 @@ type ragSourceResponse struct {
 	Summary   *string `json:"summary"`
 	CreatedAt string  `json:"created_at"`
 	Relevance float64 `json:"relevance"`
+	// Passage is the quoted text when a passage rather than the whole
+	// article was cited. Offsets are in the same units as highlight offsets.
+	Passage *ragPassageResponse `json:"passage,omitempty"`
+}
+
+type ragPassageResponse struct {
+	Text        string `json:"text"`
+	StartOffset int    `json:"start_offset"`
+	EndOffset   int    `json:"end_offset"`
 }
 
 type ragQueryResponse struct {
@@ -41,57 +84,417 @@ type ragQueryResponse struct {
 	ConversationID      string              `json:"conversation_id"`
 }
 
-// HandleQuery handles POST /api/v1/rag/query
-func (h *RAGHandler) HandleQuery(w http.ResponseWriter, r *http.Request) {
-	userID := middleware.UserIDFromContext(r.Context())
-
+// decodeRAGQuery reads and validates a query request, writing the error
+// response itself when it returns false.
+func decodeRAGQuery(w http.ResponseWriter, r *http.Request) (ragQueryRequest, bool) {
 	var req ragQueryRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid request body")
-		return
+		return req, false
 	}
 
 	if req.Question == "" {
 		writeError(w, http.StatusBadRequest, "question is required")
-		return
+		return req, false
 	}
 	if len([]rune(req.Question)) > 500 {
 		writeError(w, http.StatusBadRequest, "question must be 500 characters or fewer")
-		return
+		return req, false
 	}
+	if msg := req.Scope.validate(); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return req, false
+	}
+	return req, true
+}
 
-	conversationID := ""
-	if req.ConversationID != nil {
-		conversationID = *req.ConversationID
+// validate returns why the scope is invalid, or "" if it is valid.
+func (sc *ragScopeRequest) validate() string {
+	if sc == nil {
+		return ""
 	}
+	for _, t := range sc.SourceTypes {
+		if !domain.SourceType(t).IsKnown() {
+			return fmt.Sprintf("unknown source type %q", t)
+		}
+	}
+	if sc.CreatedAfter != nil && sc.CreatedBefore != nil && !sc.CreatedAfter.Before(*sc.CreatedBefore) {
+		return "created_after must be before created_before"
+	}
+	if len(sc.ArticleIDs) > ragScopeMaxArticles {
+		return fmt.Sprintf("scope may list at most %d articles", ragScopeMaxArticles)
+	}
+	for _, id := range sc.TagIDs {
+		if !repository.IsUUID(id) {
+			return fmt.Sprintf("invalid tag id %q", id)
+		}
+	}
+	for _, id := range sc.ArticleIDs {
+		if !repository.IsUUID(id) {
+			return fmt.Sprintf("invalid article id %q", id)
+		}
+	}
+	if sc.CollectionID != nil && !repository.IsUUID(*sc.CollectionID) {
+		return fmt.Sprintf("invalid collection id %q", *sc.CollectionID)
+	}
+	return ""
+}
 
-	result, err := h.ragService.Query(r.Context(), userID, req.Question, conversationID)
+func (req ragQueryRequest) scope() *domain.RAGScope {
+	sc := req.Scope
+	if sc == nil {
+		return nil
+	}
+	scope := &domain.RAGScope{
+		TagIDs:        sc.TagIDs,
+		CategorySlugs: sc.Categories,
+		CreatedAfter:  sc.CreatedAfter,
+		CreatedBefore: sc.CreatedBefore,
+		FavoritesOnly: sc.FavoritesOnly,
+		ArticleIDs:    sc.ArticleIDs,
+	}
+	for _, t := range sc.SourceTypes {
+		scope.SourceTypes = append(scope.SourceTypes, domain.SourceType(t))
+	}
+	return scope
+}
+
+// resolveScope returns the query's scope with its collection, if any,
+// resolved to article IDs. It writes the error response itself when it
+// returns false.
+func (h *RAGHandler) resolveScope(w http.ResponseWriter, r *http.Request, userID string, req ragQueryRequest) (*domain.RAGScope, bool) {
+	scope := req.scope()
+	if scope == nil || req.Scope.CollectionID == nil {
+		return scope, true
+	}
+
+	ids, err := h.collectionService.ArticleIDs(r.Context(), userID, *req.Scope.CollectionID, ragScopeMaxArticles)
 	if err != nil {
-		if errors.Is(err, service.ErrRAGQuotaExceeded) {
-			writeError(w, http.StatusTooManyRequests, "monthly RAG quota exceeded")
-			return
+		handleServiceError(w, r, err)
+		return nil, false
+	}
+	if len(scope.ArticleIDs) > 0 {
+		ids = slices.DeleteFunc(ids, func(id string) bool { return !slices.Contains(scope.ArticleIDs, id) })
+	}
+	// An empty ArticleIDs would lift the restriction instead of applying it.
+	if len(ids) == 0 {
+		writeError(w, http.StatusUnprocessableEntity, "no articles in collection scope")
+		return nil, false
+	}
+	scope.ArticleIDs = ids
+	return scope, true
+}
+
+func (req ragQueryRequest) conversationID() string {
+	if req.ConversationID != nil {
+		return *req.ConversationID
+	}
+	return ""
+}
+
+func toRAGSourceResponse(s domain.RAGSource) ragSourceResponse {
+	resp := ragSourceResponse{
+		ArticleID: s.ArticleID,
+		Title:     s.Title,
+		SiteName:  s.SiteName,
+		Summary:   s.Summary,
+		CreatedAt: s.CreatedAt.UTC().Format("2006-01-02T15:04:05Z07:00"),
+		Relevance: s.Relevance,
+	}
+	if s.Passage != nil {
+		resp.Passage = &ragPassageResponse{
+			Text:        s.Passage.Text,
+			StartOffset: s.Passage.StartOffset,
+			EndOffset:   s.Passage.EndOffset,
 		}
-		writeError(w, http.StatusInternalServerError, "internal error")
-		return
 	}
+	return resp
+}
 
+func toRAGQueryResponse(result *domain.RAGResponse) ragQueryResponse {
 	sources := make([]ragSourceResponse, 0, len(result.Sources))
 	for _, s := range result.Sources {
-		sources = append(sources, ragSourceResponse{
-			ArticleID: s.ArticleID,
-			Title:     s.Title,
-			SiteName:  s.SiteName,
-			Summary:   s.Summary,
-			CreatedAt: s.CreatedAt.UTC().Format("2006-01-02T15:04:05Z07:00"),
-			Relevance: s.Relevance,
-		})
+		sources = append(sources, toRAGSourceResponse(s))
 	}
-
-	writeJSON(w, http.StatusOK, ragQueryResponse{
+	followups := result.FollowupSuggestions
+	if followups == nil {
+		followups = []string{}
+	}
+	return ragQueryResponse{
 		Answer:              result.Answer,
 		Sources:             sources,
 		SourceCount:         result.SourceCount,
-		FollowupSuggestions: result.FollowupSuggestions,
+		FollowupSuggestions: followups,
 		ConversationID:      result.ConversationID,
+	}
+}
+
+// HandleQuery handles POST /api/v1/rag/query
+func (h *RAGHandler) HandleQuery(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	req, ok := decodeRAGQuery(w, r)
+	if !ok {
+		return
+	}
+	scope, ok := h.resolveScope(w, r, userID, req)
+	if !ok {
+		return
+	}
+
+	result, err := h.ragService.Query(r.Context(), userID, req.Question, req.conversationID(), scope)
+	if err != nil {
+		if errors.Is(err, service.ErrRAGQuotaExceeded) {
+			writeError(w, http.StatusTooManyRequests, "monthly RAG quota exceeded")
+			return
+		}
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toRAGQueryResponse(result))
+}
+
+type ragTokenEvent struct {
+	Text string `json:"text"`
+}
+
+type ragCitationEvent struct {
+	Index  int               `json:"index"`
+	Source ragSourceResponse `json:"source"`
+}
+
+// HandleQueryStream handles POST /api/v1/rag/query/stream
+//
+// The answer is sent as Server-Sent Events: "token" events with answer text
+// as it is generated, a "citation" event the first time each source is
+// cited, then one "done" event with the same body as POST /rag/query. A
+// failure after streaming has begun ends the stream with an "error" event.
+// Quota and validation errors are plain JSON responses, as for /rag/query.
+func (h *RAGHandler) HandleQueryStream(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	req, ok := decodeRAGQuery(w, r)
+	if !ok {
+		return
+	}
+	scope, ok := h.resolveScope(w, r, userID, req)
+	if !ok {
+		return
+	}
+
+	sse := newSSEWriter(w)
+	result, err := h.ragService.QueryStream(r.Context(), userID, req.Question, req.conversationID(), scope, func(ev service.RAGStreamEvent) error {
+		if ev.Citation != nil {
+			return sse.send("citation", ragCitationEvent{Index: ev.Index, Source: toRAGSourceResponse(*ev.Citation)})
+		}
+		return sse.send("token", ragTokenEvent{Text: ev.Text})
 	})
+	if err != nil {
+		if !sse.started {
+			if errors.Is(err, service.ErrRAGQuotaExceeded) {
+				writeError(w, http.StatusTooManyRequests, "monthly RAG quota exceeded")
+				return
+			}
+			handleServiceError(w, r, err)
+			return
+		}
+		if r.Context().Err() == nil {
+			slog.Error("rag stream failed", "user_id", userID, "error", err)
+			_ = sse.send("error", map[string]string{"error": "answer generation failed"})
+		}
+		return
+	}
+
+	if err := sse.send("done", toRAGQueryResponse(result)); err != nil {
+		slog.Debug("rag stream client gone before done", "error", err)
+	}
+}
+
+// sseWriter writes Server-Sent Events, sending the response headers with
+// the first event so errors before it can still be plain responses.
+type sseWriter struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	started bool
+}
+
+func newSSEWriter(w http.ResponseWriter) *sseWriter {
+	return &sseWriter{w: w, rc: http.NewResponseController(w)}
+}
+
+func (s *sseWriter) send(event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("marshal %s event: %w", event, err)
+	}
+	if !s.started {
+		s.started = true
+		// Not every writer supports deadlines; the server default then applies.
+		_ = s.rc.SetWriteDeadline(time.Now().Add(ragStreamWriteTimeout))
+		h := s.w.Header()
+		h.Set("Content-Type", "text/event-stream")
+		h.Set("Cache-Control", "no-cache")
+		h.Set("X-Accel-Buffering", "no")
+		s.w.WriteHeader(http.StatusOK)
+	}
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+type ragConversationResponse struct {
+	ID        string  `json:"id"`
+	Title     *string `json:"title"`
+	CreatedAt string  `json:"created_at"`
+	UpdatedAt string  `json:"updated_at"`
+}
+
+type ragMessageResponse struct {
+	ID        string              `json:"id"`
+	Role      string              `json:"role"`
+	Content   string              `json:"content"`
+	Sources   []ragSourceResponse `json:"sources"`
+	CreatedAt string              `json:"created_at"`
+}
+
+type ragConversationDetailResponse struct {
+	ragConversationResponse
+	Messages []ragMessageResponse `json:"messages"`
+}
+
+type renameRAGConversationRequest struct {
+	Title string `json:"title"`
+}
+
+func toRAGConversationResponse(c *domain.RAGConversation) ragConversationResponse {
+	return ragConversationResponse{
+		ID:        c.ID,
+		Title:     c.Title,
+		CreatedAt: c.CreatedAt.UTC().Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt: c.UpdatedAt.UTC().Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+// HandleListConversations handles GET /api/v1/rag/conversations
+func (h *RAGHandler) HandleListConversations(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if page < 1 {
+		page = defaultPage
+	}
+	if perPage < 1 {
+		perPage = defaultPerPage
+	}
+	if perPage > maxPerPage {
+		perPage = maxPerPage
+	}
+
+	result, err := h.ragService.ListConversations(r.Context(), userID, page, perPage)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	data := make([]ragConversationResponse, 0, len(result.Conversations))
+	for i := range result.Conversations {
+		data = append(data, toRAGConversationResponse(&result.Conversations[i]))
+	}
+
+	writeJSON(w, http.StatusOK, ListResponse{
+		Data: data,
+		Pagination: PaginationResponse{
+			Page:    page,
+			PerPage: perPage,
+			Total:   result.Total,
+		},
+	})
+}
+
+// HandleGetConversation handles GET /api/v1/rag/conversations/{id}
+//
+// Assistant messages carry the answer text and the cited articles that
+// still exist.
+func (h *RAGHandler) HandleGetConversation(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+	conversationID := chi.URLParam(r, "id")
+
+	conv, msgs, err := h.ragService.GetConversation(r.Context(), userID, conversationID)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	resp := ragConversationDetailResponse{
+		ragConversationResponse: toRAGConversationResponse(conv),
+		Messages:                make([]ragMessageResponse, 0, len(msgs)),
+	}
+	for _, m := range msgs {
+		sources := make([]ragSourceResponse, 0, len(m.Sources))
+		for _, s := range m.Sources {
+			sources = append(sources, toRAGSourceResponse(s))
+		}
+		resp.Messages = append(resp.Messages, ragMessageResponse{
+			ID:        m.ID,
+			Role:      m.Role,
+			Content:   m.Content,
+			Sources:   sources,
+			CreatedAt: m.CreatedAt.UTC().Format("2006-01-02T15:04:05Z07:00"),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// HandleRenameConversation handles PATCH /api/v1/rag/conversations/{id}
+func (h *RAGHandler) HandleRenameConversation(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+	conversationID := chi.URLParam(r, "id")
+
+	var req renameRAGConversationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	conv, err := h.ragService.RenameConversation(r.Context(), userID, conversationID, req.Title)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toRAGConversationResponse(conv))
+}
+
+// HandleDeleteConversation handles DELETE /api/v1/rag/conversations/{id}
+func (h *RAGHandler) HandleDeleteConversation(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+	conversationID := chi.URLParam(r, "id")
+
+	if err := h.ragService.DeleteConversation(r.Context(), userID, conversationID); err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleExportConversation handles GET /api/v1/rag/conversations/{id}/export
+func (h *RAGHandler) HandleExportConversation(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+	conversationID := chi.URLParam(r, "id")
+
+	name, data, err := h.ragService.ExportConversation(r.Context(), userID, conversationID)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
 }
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"folio-server/internal/domain"
+)
+
+func TestDecodeRAGQuery_Scope(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		code int // 0 when the request is valid
+	}{
+		{"no scope", `{"question":"q"}`, 0},
+		{"full scope", `{"question":"q","scope":{"tag_ids":["8c4b6f0e-2d1a-4f3b-9e7c-5a6d8b9c0e1f"],"article_ids":["0b9e8d7c-6a5f-4e3d-8c2b-1a0f9e8d7c6b"],"collection_id":"3f2e1d0c-9b8a-4c7d-b6e5-f4a3b2c1d0e9","categories":["tech"],"created_after":"2026-01-01T00:00:00Z","created_before":"2026-04-01T00:00:00Z","source_types":["pdf","web"],"favorites_only":true}}`, 0},
+		{"unknown source", `{"question":"q","scope":{"source_types":["fax"]}}`, http.StatusBadRequest},
+		{"bad date", `{"question":"q","scope":{"created_after":"last quarter"}}`, http.StatusBadRequest},
+		{"bad tag id", `{"question":"q","scope":{"tag_ids":["t"]}}`, http.StatusBadRequest},
+		{"bad article id", `{"question":"q","scope":{"article_ids":["article-1"]}}`, http.StatusBadRequest},
+		{"bad collection id", `{"question":"q","scope":{"collection_id":"1; DROP"}}`, http.StatusBadRequest},
+		{"inverted range", `{"question":"q","scope":{"created_after":"2026-04-01T00:00:00Z","created_before":"2026-01-01T00:00:00Z"}}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/rag/query", strings.NewReader(tt.body))
+			_, ok := decodeRAGQuery(rec, req)
+			if ok != (tt.code == 0) || (tt.code != 0 && rec.Code != tt.code) {
+				t.Errorf("ok = %v, code = %d", ok, rec.Code)
+			}
+		})
+	}
+}
+
+func TestRAGQueryRequestScope(t *testing.T) {
+	if (ragQueryRequest{}).scope() != nil {
+		t.Error("missing scope should be nil")
+	}
+	req := ragQueryRequest{Scope: &ragScopeRequest{Categories: []string{"tech"}, SourceTypes: []string{"pdf"}}}
+	scope := req.scope()
+	if scope.CategorySlugs[0] != "tech" || scope.SourceTypes[0] != domain.SourcePDF || scope.IsEmpty() {
+		t.Errorf("scope = %+v", scope)
+	}
+}
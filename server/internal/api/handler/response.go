@@ -36,6 +36,8 @@ func handleServiceError(w http.ResponseWriter, r *http.Request, err error) {
 		writeError(w, http.StatusUnauthorized, "invalid or expired verification code")
 	case errors.Is(err, service.ErrCodeRateLimit):
 		writeError(w, http.StatusTooManyRequests, "please wait before requesting another code")
+	case errors.Is(err, service.ErrTooManyAttempts):
+		writeError(w, http.StatusTooManyRequests, "too many attempts, please try again later")
 	case errors.Is(err, service.ErrDuplicateURL):
 		slog.Debug("duplicate URL", "path", r.URL.Path)
 		writeError(w, http.StatusConflict, "url already saved")
@@ -45,6 +47,70 @@ func handleServiceError(w http.ResponseWriter, r *http.Request, err error) {
 		writeError(w, http.StatusBadRequest, "bundle ID mismatch")
 	case errors.Is(err, service.ErrSubscriptionExpired):
 		writeError(w, http.StatusBadRequest, "subscription already expired")
+	case errors.Is(err, service.ErrInvalidConversationTitle):
+		writeError(w, http.StatusBadRequest, "invalid conversation title")
+	case errors.Is(err, service.ErrInvalidFeedURL):
+		writeError(w, http.StatusBadRequest, "invalid feed URL")
+	case errors.Is(err, service.ErrDuplicateFeed):
+		writeError(w, http.StatusConflict, "feed already subscribed")
+	case errors.Is(err, service.ErrFeedLimit):
+		writeError(w, http.StatusUnprocessableEntity, "feed subscription limit reached")
+	case errors.Is(err, service.ErrFeedInactive):
+		writeError(w, http.StatusConflict, "feed is paused")
+	case errors.Is(err, service.ErrInvalidSenderPattern):
+		writeError(w, http.StatusBadRequest, "invalid sender pattern")
+	case errors.Is(err, service.ErrMailRecipientUnknown):
+		writeError(w, http.StatusNotFound, "unknown mail recipient")
+	case errors.Is(err, service.ErrMailSenderRejected):
+		writeError(w, http.StatusForbidden, "sender not in allow-list")
+	case errors.Is(err, service.ErrMailMalformed):
+		writeError(w, http.StatusBadRequest, "malformed message")
+	case errors.Is(err, service.ErrMailEmpty):
+		writeError(w, http.StatusUnprocessableEntity, "message has no readable content")
+	case errors.Is(err, service.ErrInvalidPDF):
+		writeError(w, http.StatusBadRequest, "invalid PDF file")
+	case errors.Is(err, service.ErrPDFEncrypted):
+		writeError(w, http.StatusUnprocessableEntity, "encrypted PDFs are not supported")
+	case errors.Is(err, service.ErrPDFNoText):
+		writeError(w, http.StatusUnprocessableEntity, "PDF has no extractable text")
+	case errors.Is(err, service.ErrPDFTooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, "PDF too large")
+	case errors.Is(err, service.ErrPDFTooComplex):
+		writeError(w, http.StatusUnprocessableEntity, "PDF is too complex to extract")
+	case errors.Is(err, service.ErrInvalidCursor):
+		writeError(w, http.StatusBadRequest, "invalid cursor")
+	case errors.Is(err, service.ErrInvalidSearchQuery):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrInvalidCollection):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrDuplicateCollection):
+		writeError(w, http.StatusConflict, "collection name already in use")
+	case errors.Is(err, service.ErrCollectionLimit):
+		writeError(w, http.StatusUnprocessableEntity, "collection limit reached")
+	case errors.Is(err, service.ErrInvalidHighlightPage):
+		writeError(w, http.StatusBadRequest, "invalid highlight page")
+	case errors.Is(err, service.ErrInvalidEchoCard):
+		writeError(w, http.StatusBadRequest, "invalid echo card")
+	case errors.Is(err, service.ErrEchoCardSuspended):
+		writeError(w, http.StatusConflict, "echo card is suspended")
+	case errors.Is(err, service.ErrImportFormat):
+		writeError(w, http.StatusBadRequest, "unrecognized import file")
+	case errors.Is(err, service.ErrImportEmpty):
+		writeError(w, http.StatusUnprocessableEntity, "import file contains no links")
+	case errors.Is(err, service.ErrImportTooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, "import file contains too many links")
+	case errors.Is(err, service.ErrImportInProgress):
+		writeError(w, http.StatusConflict, "an import is already in progress")
+	case errors.Is(err, service.ErrExportInProgress):
+		writeError(w, http.StatusConflict, "an export is already in progress")
+	case errors.Is(err, service.ErrExportNotReady):
+		writeError(w, http.StatusConflict, "export is not finished")
+	case errors.Is(err, service.ErrExportExpired):
+		writeError(w, http.StatusGone, "export has expired")
+	case errors.Is(err, service.ErrExportUnavailable):
+		writeError(w, http.StatusServiceUnavailable, "exports are not available")
+	case errors.Is(err, service.ErrInvalidExportFormat):
+		writeError(w, http.StatusBadRequest, "invalid export format")
 	default:
 		slog.Error("internal error", "path", r.URL.Path, "error", err)
 		writeError(w, http.StatusInternalServerError, "internal error")
@@ -63,3 +129,19 @@ type ListResponse struct {
 	ServerTime string             `json:"server_time,omitempty"`
 	SyncEpoch  int                `json:"sync_epoch,omitempty"`
 }
+
+// CursorPaginationResponse describes a keyset-paginated page. NextCursor is
+// null on the last page; Total is present only when include_total=true.
+type CursorPaginationResponse struct {
+	PerPage    int     `json:"per_page"`
+	NextCursor *string `json:"next_cursor"`
+	HasMore    bool    `json:"has_more"`
+	Total      *int    `json:"total,omitempty"`
+}
+
+type CursorListResponse struct {
+	Data       any                      `json:"data"`
+	Pagination CursorPaginationResponse `json:"pagination"`
+	ServerTime string                   `json:"server_time,omitempty"`
+	SyncEpoch  int                      `json:"sync_epoch,omitempty"`
+}
@@ -2,19 +2,23 @@ package handler
 
 import (
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 
 	"folio-server/internal/api/middleware"
+	"folio-server/internal/domain"
 	"folio-server/internal/repository"
 	"folio-server/internal/service"
 )
 
 type SearchHandler struct {
 	articleService *service.ArticleService
+	searchService  *service.SearchService
 }
 
-func NewSearchHandler(articleService *service.ArticleService) *SearchHandler {
-	return &SearchHandler{articleService: articleService}
+func NewSearchHandler(articleService *service.ArticleService, searchService *service.SearchService) *SearchHandler {
+	return &SearchHandler{articleService: articleService, searchService: searchService}
 }
 
 func (h *SearchHandler) HandleSearch(w http.ResponseWriter, r *http.Request) {
@@ -63,3 +67,96 @@ func (h *SearchHandler) HandleSearch(w http.ResponseWriter, r *http.Request) {
 		},
 	})
 }
+
+type searchResultResponse struct {
+	Type      string               `json:"type"`
+	ID        string               `json:"id"`
+	Title     *string              `json:"title,omitempty"`
+	Snippet   *string              `json:"snippet,omitempty"`
+	Note      *string              `json:"note,omitempty"`
+	CreatedAt string               `json:"created_at"`
+	Target    searchTargetResponse `json:"target"`
+}
+
+// searchTargetResponse tells the client what to open for a result.
+type searchTargetResponse struct {
+	ArticleID      *string `json:"article_id,omitempty"`
+	HighlightID    *string `json:"highlight_id,omitempty"`
+	StartOffset    *int    `json:"start_offset,omitempty"`
+	EndOffset      *int    `json:"end_offset,omitempty"`
+	CardID         *string `json:"card_id,omitempty"`
+	ConversationID *string `json:"conversation_id,omitempty"`
+	MessageID      *string `json:"message_id,omitempty"`
+}
+
+// HandleUnifiedSearch searches articles, highlights, Echo cards and RAG
+// messages together. q takes the same syntax as /articles/search; types
+// optionally limits results to a comma-separated list of result types.
+func (h *SearchHandler) HandleUnifiedSearch(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		writeError(w, http.StatusBadRequest, "q parameter is required")
+		return
+	}
+
+	var types []domain.SearchResultType
+	if raw := r.URL.Query().Get("types"); raw != "" {
+		for _, t := range strings.Split(raw, ",") {
+			rt := domain.SearchResultType(strings.TrimSpace(t))
+			if !slices.Contains(domain.SearchResultTypes, rt) {
+				writeError(w, http.StatusBadRequest, "types must be article, highlight, echo_card or rag_message")
+				return
+			}
+			types = append(types, rt)
+		}
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if page < 1 {
+		page = defaultPage
+	}
+	if perPage < 1 {
+		perPage = defaultPerPage
+	}
+	if perPage > maxPerPage {
+		perPage = maxPerPage
+	}
+
+	result, err := h.searchService.Search(r.Context(), userID, query, types, page, perPage)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	data := make([]searchResultResponse, len(result.Results))
+	for i, res := range result.Results {
+		data[i] = searchResultResponse{
+			Type:      string(res.Type),
+			ID:        res.ID,
+			Title:     res.Title,
+			Snippet:   res.Snippet,
+			Note:      res.Note,
+			CreatedAt: res.CreatedAt.UTC().Format("2006-01-02T15:04:05Z07:00"),
+			Target: searchTargetResponse{
+				ArticleID:      res.Target.ArticleID,
+				HighlightID:    res.Target.HighlightID,
+				StartOffset:    res.Target.StartOffset,
+				EndOffset:      res.Target.EndOffset,
+				CardID:         res.Target.CardID,
+				ConversationID: res.Target.ConversationID,
+				MessageID:      res.Target.MessageID,
+			},
+		}
+	}
+
+	writeJSON(w, http.StatusOK, ListResponse{
+		Data: data,
+		Pagination: PaginationResponse{
+			Page:    page,
+			PerPage: perPage,
+			Total:   result.Total,
+		},
+	})
+}
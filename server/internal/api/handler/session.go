@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"folio-server/internal/api/middleware"
+	"folio-server/internal/domain"
+	"folio-server/internal/service"
+)
+
+type SessionHandler struct {
+	authService *service.AuthService
+}
+
+func NewSessionHandler(authService *service.AuthService) *SessionHandler {
+	return &SessionHandler{authService: authService}
+}
+
+type sessionResponse struct {
+	ID         string  `json:"id"`
+	DeviceName *string `json:"device_name,omitempty"`
+	UserAgent  *string `json:"user_agent,omitempty"`
+	Current    bool    `json:"current"`
+	LastUsedAt string  `json:"last_used_at"`
+	CreatedAt  string  `json:"created_at"`
+}
+
+func toSessionResponse(s domain.Session, currentID string) sessionResponse {
+	return sessionResponse{
+		ID:         s.ID,
+		DeviceName: s.DeviceName,
+		UserAgent:  s.UserAgent,
+		Current:    s.ID == currentID,
+		LastUsedAt: s.LastUsedAt.UTC().Format("2006-01-02T15:04:05Z07:00"),
+		CreatedAt:  s.CreatedAt.UTC().Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+// HandleList handles GET /api/v1/sessions
+func (h *SessionHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+	currentID := middleware.SessionIDFromContext(r.Context())
+
+	sessions, err := h.authService.ListSessions(r.Context(), userID)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	data := make([]sessionResponse, 0, len(sessions))
+	for _, s := range sessions {
+		data = append(data, toSessionResponse(s, currentID))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"data": data})
+}
+
+// HandleRevokeOthers handles DELETE /api/v1/sessions.
+//
+// It signs out every device except the one making the request.
+func (h *SessionHandler) HandleRevokeOthers(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+	currentID := middleware.SessionIDFromContext(r.Context())
+
+	revoked, err := h.authService.RevokeOtherSessions(r.Context(), userID, currentID)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int{"revoked": revoked})
+}
+
+// HandleRevoke handles DELETE /api/v1/sessions/{id}
+func (h *SessionHandler) HandleRevoke(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+	sessionID := chi.URLParam(r, "id")
+
+	if err := h.authService.RevokeSession(r.Context(), userID, sessionID); err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
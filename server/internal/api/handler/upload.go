@@ -0,0 +1,22 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+)
+
+// uploadTimeout bounds how long a document upload may take to arrive and be
+// answered. The server's read and write timeouts are sized for JSON requests
+// and would cut off a large file on a slow connection.
+const uploadTimeout = 10 * time.Minute
+
+// extendUploadDeadline lifts the server timeouts for the current request so a
+// large body can be read in full. It must be called before the body is read.
+func extendUploadDeadline(w http.ResponseWriter) {
+	deadline := time.Now().Add(uploadTimeout)
+	rc := http.NewResponseController(w)
+	// Writers that do not support deadlines (such as test recorders) have
+	// no server timeouts to lift.
+	_ = rc.SetReadDeadline(deadline)
+	_ = rc.SetWriteDeadline(deadline)
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestExtendUploadDeadline(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("extend") {
+			extendUploadDeadline(w)
+		}
+		if _, err := io.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	srv.Config.ReadTimeout = 100 * time.Millisecond
+	srv.Config.WriteTimeout = 100 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	// slowUpload sends the body in pieces over longer than the server's
+	// read timeout.
+	slowUpload := func(query string) (int, error) {
+		pr, pw := io.Pipe()
+		go func() {
+			for i := 0; i < 5; i++ {
+				time.Sleep(60 * time.Millisecond)
+				if _, err := pw.Write([]byte("chunk")); err != nil {
+					return
+				}
+			}
+			pw.Close()
+		}()
+		resp, err := http.Post(srv.URL+query, "application/octet-stream", pr)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	if code, err := slowUpload("?extend=1"); err != nil || code != http.StatusNoContent {
+		t.Errorf("extended upload: code = %d, err = %v", code, err)
+	}
+	if code, err := slowUpload(""); err == nil && code == http.StatusNoContent {
+		t.Error("upload without extension outlived the read timeout")
+	}
+}
@@ -3,6 +3,7 @@ package middleware
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 	"strings"
@@ -12,7 +13,10 @@ import (
 
 type contextKey string
 
-const userIDKey contextKey = "userID"
+const (
+	userIDKey    contextKey = "userID"
+	sessionIDKey contextKey = "sessionID"
+)
 
 func JWTAuth(authService *service.AuthService) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -31,14 +35,23 @@ func JWTAuth(authService *service.AuthService) func(http.Handler) http.Handler {
 				return
 			}
 
-			userID, err := authService.ValidateAccessToken(token)
-			if err != nil {
+			userID, sessionID, err := authService.ValidateAccessToken(r.Context(), token)
+			if errors.Is(err, service.ErrForbidden) {
 				slog.Debug("auth: token validation failed", "path", r.URL.Path, "error", err)
 				writeAuthError(w, "invalid or expired token")
 				return
 			}
+			if err != nil {
+				// Revocations could not be checked; don't sign the client out.
+				slog.Error("auth: token validation error", "path", r.URL.Path, "error", err)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusInternalServerError)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "internal error"})
+				return
+			}
 
 			ctx := context.WithValue(r.Context(), userIDKey, userID)
+			ctx = context.WithValue(ctx, sessionIDKey, sessionID)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -62,6 +75,13 @@ func UserIDFromContext(ctx context.Context) string {
 	return id
 }
 
+// SessionIDFromContext returns the session the request's access token was
+// issued to, or an empty string for tokens that predate sessions.
+func SessionIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(sessionIDKey).(string)
+	return id
+}
+
 // ContextWithUserID returns a new context with the given userID set.
 // This is useful for testing handlers without going through JWT validation.
 func ContextWithUserID(ctx context.Context, userID string) context.Context {
@@ -9,6 +9,7 @@ import (
 
 	"folio-server/internal/api/handler"
 	"folio-server/internal/api/middleware"
+	"folio-server/internal/inbound"
 	"folio-server/internal/service"
 )
 
@@ -28,6 +29,14 @@ type RouterDeps struct {
 	StatsHandler        *handler.StatsHandler
 	DeviceHandler       *handler.DeviceHandler
 	RelationHandler     *handler.RelationHandler
+	FeedHandler         *handler.FeedHandler
+	CollectionHandler   *handler.CollectionHandler
+	MailHandler         *handler.MailHandler
+	PDFHandler          *handler.PDFHandler
+	ImportHandler       *handler.ImportHandler
+	ExportHandler       *handler.ExportHandler
+	AccountHandler      *handler.AccountHandler
+	SessionHandler      *handler.SessionHandler
 }
 
 func NewRouter(deps RouterDeps) http.Handler {
@@ -37,11 +46,21 @@ func NewRouter(deps RouterDeps) http.Handler {
 	r.Use(chimw.Recoverer)
 	r.Use(chimw.RequestID)
 
-	// Limit request body to 1 MB to prevent memory exhaustion (DoS)
+	// Limit request body to 1 MB to prevent memory exhaustion (DoS).
+	// Endpoints that accept raw documents get a larger, explicit limit.
 	const maxBodyBytes int64 = 1 << 20 // 1 MB
+	bodyLimitOverrides := map[string]int64{
+		"/api/v1/webhook/mail": inbound.MaxMessageBytes,
+		"/api/v1/articles/pdf": service.MaxPDFUploadBytes,
+		"/api/v1/imports":      service.MaxImportFileBytes,
+	}
 	r.Use(func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+			limit := maxBodyBytes
+			if l, ok := bodyLimitOverrides[r.URL.Path]; ok {
+				limit = l
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
 			next.ServeHTTP(w, r)
 		})
 	})
@@ -62,14 +81,19 @@ func NewRouter(deps RouterDeps) http.Handler {
 		// Apple webhook — public endpoint (Apple calls without JWT)
 		r.Post("/webhook/apple", deps.SubscriptionHandler.HandleWebhook)
 
+		// Inbound mail webhook — authenticated by a shared secret header
+		r.Post("/webhook/mail", deps.MailHandler.HandleWebhook)
+
 		// Protected routes
 		r.Group(func(r chi.Router) {
 			r.Use(middleware.JWTAuth(deps.AuthService))
 
 			// Articles — search BEFORE {id} for chi route priority
 			r.Get("/articles/search", deps.SearchHandler.HandleSearch)
+			r.Get("/search", deps.SearchHandler.HandleUnifiedSearch)
 			r.Post("/articles", deps.ArticleHandler.HandleSubmitURL)
 			r.Post("/articles/manual", deps.ArticleHandler.HandleSubmitManual)
+			r.Post("/articles/pdf", deps.PDFHandler.HandleUploadPDF)
 			r.Get("/articles", deps.ArticleHandler.HandleListArticles)
 			r.Get("/articles/{id}", deps.ArticleHandler.HandleGetArticle)
 			r.Put("/articles/{id}", deps.ArticleHandler.HandleUpdateArticle)
@@ -100,9 +124,66 @@ func NewRouter(deps RouterDeps) http.Handler {
 			// Echo (spaced repetition)
 			r.Get("/echo/today", deps.EchoHandler.HandleGetToday)
 			r.Post("/echo/{id}/review", deps.EchoHandler.HandleSubmitReview)
+			r.Get("/echo/cards", deps.EchoHandler.HandleListCards)
+			r.Post("/echo/cards", deps.EchoHandler.HandleCreateCard)
+			r.Get("/echo/cards/{id}", deps.EchoHandler.HandleGetCard)
+			r.Patch("/echo/cards/{id}", deps.EchoHandler.HandleUpdateCard)
+			r.Delete("/echo/cards/{id}", deps.EchoHandler.HandleDeleteCard)
+			r.Post("/echo/cards/{id}/suspend", deps.EchoHandler.HandleSuspendCard)
+			r.Post("/echo/cards/{id}/unsuspend", deps.EchoHandler.HandleUnsuspendCard)
+			r.Post("/echo/cards/{id}/bury", deps.EchoHandler.HandleBuryCard)
 
 			// RAG (question answering over saved articles)
 			r.Post("/rag/query", deps.RAGHandler.HandleQuery)
+			r.Post("/rag/query/stream", deps.RAGHandler.HandleQueryStream)
+			r.Get("/rag/conversations", deps.RAGHandler.HandleListConversations)
+			r.Get("/rag/conversations/{id}", deps.RAGHandler.HandleGetConversation)
+			r.Patch("/rag/conversations/{id}", deps.RAGHandler.HandleRenameConversation)
+			r.Delete("/rag/conversations/{id}", deps.RAGHandler.HandleDeleteConversation)
+			r.Get("/rag/conversations/{id}/export", deps.RAGHandler.HandleExportConversation)
+
+			// Feeds (RSS / Atom / JSON Feed subscriptions)
+			r.Get("/feeds", deps.FeedHandler.HandleListFeeds)
+			r.Post("/feeds", deps.FeedHandler.HandleCreateFeed)
+			r.Get("/feeds/{id}", deps.FeedHandler.HandleGetFeed)
+			r.Put("/feeds/{id}", deps.FeedHandler.HandleUpdateFeed)
+			r.Delete("/feeds/{id}", deps.FeedHandler.HandleDeleteFeed)
+			r.Post("/feeds/{id}/refresh", deps.FeedHandler.HandleRefreshFeed)
+
+			// Collections (saved searches, evaluated live)
+			r.Get("/collections", deps.CollectionHandler.HandleListCollections)
+			r.Post("/collections", deps.CollectionHandler.HandleCreateCollection)
+			r.Get("/collections/{id}", deps.CollectionHandler.HandleGetCollection)
+			r.Patch("/collections/{id}", deps.CollectionHandler.HandleUpdateCollection)
+			r.Delete("/collections/{id}", deps.CollectionHandler.HandleDeleteCollection)
+			r.Get("/collections/{id}/articles", deps.CollectionHandler.HandleListCollectionArticles)
+
+			// Mail ingest (private newsletter address and sender allow-list)
+			r.Get("/mail/inbox", deps.MailHandler.HandleGetInbox)
+			r.Post("/mail/inbox/rotate", deps.MailHandler.HandleRotateInbox)
+			r.Get("/mail/senders", deps.MailHandler.HandleListSenders)
+			r.Post("/mail/senders", deps.MailHandler.HandleAddSender)
+			r.Delete("/mail/senders/{id}", deps.MailHandler.HandleDeleteSender)
+
+			// Imports (Pocket, Instapaper, Omnivore, browser bookmarks)
+			r.Post("/imports", deps.ImportHandler.HandleCreateImport)
+			r.Get("/imports", deps.ImportHandler.HandleListImports)
+			r.Get("/imports/{id}", deps.ImportHandler.HandleGetImport)
+
+			// Exports (Markdown vault zip)
+			r.Post("/exports", deps.ExportHandler.HandleCreateExport)
+			r.Get("/exports", deps.ExportHandler.HandleListExports)
+			r.Get("/exports/{id}", deps.ExportHandler.HandleGetExport)
+			r.Get("/exports/{id}/download", deps.ExportHandler.HandleDownloadExport)
+
+			// Account (deletion and personal data export)
+			r.Delete("/me", deps.AccountHandler.HandleDeleteAccount)
+			r.Post("/me/export", deps.AccountHandler.HandleExportData)
+
+			// Sessions (signed-in devices)
+			r.Get("/sessions", deps.SessionHandler.HandleList)
+			r.Delete("/sessions", deps.SessionHandler.HandleRevokeOthers)
+			r.Delete("/sessions/{id}", deps.SessionHandler.HandleRevoke)
 
 			// Devices (push notification registration)
 			r.Post("/devices", deps.DeviceHandler.HandleRegister)
@@ -0,0 +1,134 @@
+package authcode
+
+import (
+	"context"
+	"crypto/subtle"
+	"sync"
+	"time"
+)
+
+// MemoryStore is an in-process Store. Expired entries are dropped when they
+// are read and swept whenever an entry is written.
+type MemoryStore struct {
+	mu        sync.Mutex
+	codes     map[string]memoryEntry // key: email
+	cooldowns map[string]time.Time   // key: email, value: cooldown end
+	failures  map[string]memoryEntry // key: failure key, code unused
+	now       func() time.Time
+}
+
+type memoryEntry struct {
+	code      string
+	count     int
+	expiresAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		codes:     make(map[string]memoryEntry),
+		cooldowns: make(map[string]time.Time),
+		failures:  make(map[string]memoryEntry),
+		now:       time.Now,
+	}
+}
+
+func (s *MemoryStore) Save(_ context.Context, email, code string, ttl, cooldown time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+	if until, ok := s.cooldowns[email]; ok && now.Before(until) {
+		return ErrCooldown
+	}
+	s.cooldowns[email] = now.Add(cooldown)
+	s.codes[email] = memoryEntry{code: code, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) Verify(_ context.Context, email, code string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.codes[email]
+	if !ok || !s.now().Before(e.expiresAt) {
+		delete(s.codes, email)
+		return false, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(e.code), []byte(code)) != 1 {
+		return false, nil
+	}
+	delete(s.codes, email)
+	return true, nil
+}
+
+func (s *MemoryStore) Discard(_ context.Context, email string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.codes, email)
+	return nil
+}
+
+func (s *MemoryStore) Attempt(_ context.Context, key string, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+	e, ok := s.failures[key]
+	if !ok {
+		e = memoryEntry{expiresAt: now.Add(window)}
+	}
+	e.count++
+	s.failures[key] = e
+	return e.count, nil
+}
+
+func (s *MemoryStore) Forgive(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.failures[key]; ok && e.count > 0 {
+		e.count--
+		s.failures[key] = e
+	}
+	return nil
+}
+
+func (s *MemoryStore) Failures(_ context.Context, key string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.failures[key]
+	if !ok || !s.now().Before(e.expiresAt) {
+		delete(s.failures, key)
+		return 0, nil
+	}
+	return e.count, nil
+}
+
+func (s *MemoryStore) Reset(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.failures, key)
+	return nil
+}
+
+// sweep drops every expired entry. The caller holds s.mu.
+func (s *MemoryStore) sweep(now time.Time) {
+	for k, e := range s.codes {
+		if !now.Before(e.expiresAt) {
+			delete(s.codes, k)
+		}
+	}
+	for k, until := range s.cooldowns {
+		if !now.Before(until) {
+			delete(s.cooldowns, k)
+		}
+	}
+	for k, e := range s.failures {
+		if !now.Before(e.expiresAt) {
+			delete(s.failures, k)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package authcode
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestStore returns a MemoryStore whose clock is advanced by the returned
+// function.
+func newTestStore() (*MemoryStore, func(time.Duration)) {
+	s := NewMemoryStore()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	return s, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestMemoryStore_CodeIsSingleUse(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestStore()
+	if err := s.Save(ctx, "a@example.com", "123456", 5*time.Minute, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, _ := s.Verify(ctx, "a@example.com", "000000"); ok {
+		t.Error("wrong code accepted")
+	}
+	if ok, _ := s.Verify(ctx, "a@example.com", "123456"); !ok {
+		t.Error("correct code rejected after a wrong guess")
+	}
+	if ok, _ := s.Verify(ctx, "a@example.com", "123456"); ok {
+		t.Error("code accepted twice")
+	}
+}
+
+func TestMemoryStore_CodeExpires(t *testing.T) {
+	ctx := context.Background()
+	s, advance := newTestStore()
+	_ = s.Save(ctx, "a@example.com", "123456", 5*time.Minute, time.Minute)
+
+	advance(5 * time.Minute)
+	if ok, _ := s.Verify(ctx, "a@example.com", "123456"); ok {
+		t.Error("expired code accepted")
+	}
+	if len(s.codes) != 0 {
+		t.Errorf("expired code still stored: %v", s.codes)
+	}
+}
+
+func TestMemoryStore_Cooldown(t *testing.T) {
+	ctx := context.Background()
+	s, advance := newTestStore()
+	_ = s.Save(ctx, "a@example.com", "111111", 5*time.Minute, time.Minute)
+
+	advance(30 * time.Second)
+	if err := s.Save(ctx, "a@example.com", "222222", 5*time.Minute, time.Minute); !errors.Is(err, ErrCooldown) {
+		t.Fatalf("err = %v during cooldown, want ErrCooldown", err)
+	}
+	if err := s.Save(ctx, "b@example.com", "333333", 5*time.Minute, time.Minute); err != nil {
+		t.Errorf("cooldown applied to another address: %v", err)
+	}
+
+	advance(30 * time.Second)
+	if err := s.Save(ctx, "a@example.com", "222222", 5*time.Minute, time.Minute); err != nil {
+		t.Fatalf("Save after cooldown: %v", err)
+	}
+	if ok, _ := s.Verify(ctx, "a@example.com", "111111"); ok {
+		t.Error("replaced code still accepted")
+	}
+}
+
+func TestMemoryStore_FailureWindow(t *testing.T) {
+	ctx := context.Background()
+	s, advance := newTestStore()
+
+	for i := 1; i <= 3; i++ {
+		if n, _ := s.Attempt(ctx, "email:a@example.com", 15*time.Minute); n != i {
+			t.Fatalf("failure %d counted as %d", i, n)
+		}
+		advance(time.Minute)
+	}
+	if n, _ := s.Failures(ctx, "email:a@example.com"); n != 3 {
+		t.Errorf("failures = %d, want 3", n)
+	}
+
+	// The window starts at the first failure, not the latest.
+	advance(12 * time.Minute)
+	if n, _ := s.Failures(ctx, "email:a@example.com"); n != 0 {
+		t.Errorf("failures = %d after the window, want 0", n)
+	}
+
+	_, _ = s.Attempt(ctx, "ip:192.0.2.1", 15*time.Minute)
+	_ = s.Reset(ctx, "ip:192.0.2.1")
+	if n, _ := s.Failures(ctx, "ip:192.0.2.1"); n != 0 {
+		t.Errorf("failures = %d after reset, want 0", n)
+	}
+}
+
+func TestMemoryStore_Forgive(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestStore()
+
+	_, _ = s.Attempt(ctx, "ip:192.0.2.1", 15*time.Minute)
+	_, _ = s.Attempt(ctx, "ip:192.0.2.1", 15*time.Minute)
+	_ = s.Forgive(ctx, "ip:192.0.2.1")
+	if n, _ := s.Failures(ctx, "ip:192.0.2.1"); n != 1 {
+		t.Errorf("attempts = %d after forgiving one of two, want 1", n)
+	}
+
+	_ = s.Forgive(ctx, "ip:192.0.2.1")
+	_ = s.Forgive(ctx, "ip:192.0.2.1")
+	if n, _ := s.Attempt(ctx, "ip:192.0.2.1", 15*time.Minute); n != 1 {
+		t.Errorf("attempt counted as %d after over-forgiving, want 1", n)
+	}
+	_ = s.Forgive(ctx, "ip:unknown")
+	if _, ok := s.failures["ip:unknown"]; ok {
+		t.Error("forgiving an unknown key created an entry")
+	}
+}
@@ -0,0 +1,114 @@
+package authcode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const keyPrefix = "folio:authcode:"
+
+// verifyScript deletes the stored code only if it matches, so a code can be
+// used once even when two requests race.
+var verifyScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	redis.call("DEL", KEYS[1])
+	return 1
+end
+return 0`)
+
+// attemptScript increments an attempt counter and starts its window on the
+// first attempt, in one step so concurrent guesses cannot both read a count
+// below the limit.
+var attemptScript = redis.NewScript(`
+local n = redis.call("INCR", KEYS[1])
+if n == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return n`)
+
+// forgiveScript decrements an attempt counter that is above zero. DECR keeps
+// the key's expiry, so the window is unchanged.
+var forgiveScript = redis.NewScript(`
+if tonumber(redis.call("GET", KEYS[1]) or "0") > 0 then
+	redis.call("DECR", KEYS[1])
+end
+return 0`)
+
+// RedisStore is a Store backed by Redis, shared by every API replica.
+type RedisStore struct {
+	rdb redis.UniversalClient
+}
+
+func NewRedisStore(rdb redis.UniversalClient) *RedisStore {
+	return &RedisStore{rdb: rdb}
+}
+
+func codeKey(email string) string     { return keyPrefix + "code:" + email }
+func cooldownKey(email string) string { return keyPrefix + "cooldown:" + email }
+func failureKey(key string) string    { return keyPrefix + "fail:" + key }
+
+func (s *RedisStore) Save(ctx context.Context, email, code string, ttl, cooldown time.Duration) error {
+	ok, err := s.rdb.SetNX(ctx, cooldownKey(email), 1, cooldown).Result()
+	if err != nil {
+		return fmt.Errorf("set code cooldown: %w", err)
+	}
+	if !ok {
+		return ErrCooldown
+	}
+	if err := s.rdb.Set(ctx, codeKey(email), code, ttl).Err(); err != nil {
+		return fmt.Errorf("save code: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) Verify(ctx context.Context, email, code string) (bool, error) {
+	n, err := verifyScript.Run(ctx, s.rdb, []string{codeKey(email)}, code).Int()
+	if err != nil {
+		return false, fmt.Errorf("verify code: %w", err)
+	}
+	return n == 1, nil
+}
+
+func (s *RedisStore) Discard(ctx context.Context, email string) error {
+	if err := s.rdb.Del(ctx, codeKey(email)).Err(); err != nil {
+		return fmt.Errorf("discard code: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) Attempt(ctx context.Context, key string, window time.Duration) (int, error) {
+	n, err := attemptScript.Run(ctx, s.rdb, []string{failureKey(key)}, window.Milliseconds()).Int()
+	if err != nil {
+		return 0, fmt.Errorf("record attempt: %w", err)
+	}
+	return n, nil
+}
+
+func (s *RedisStore) Forgive(ctx context.Context, key string) error {
+	if err := forgiveScript.Run(ctx, s.rdb, []string{failureKey(key)}).Err(); err != nil {
+		return fmt.Errorf("forgive attempt: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) Failures(ctx context.Context, key string) (int, error) {
+	n, err := s.rdb.Get(ctx, failureKey(key)).Int()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("get failures: %w", err)
+	}
+	return n, nil
+}
+
+func (s *RedisStore) Reset(ctx context.Context, key string) error {
+	if err := s.rdb.Del(ctx, failureKey(key)).Err(); err != nil {
+		return fmt.Errorf("reset failures: %w", err)
+	}
+	return nil
+}
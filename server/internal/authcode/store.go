@@ -0,0 +1,51 @@
+// Package authcode stores short-lived email verification codes, the resend
+// cooldown for each address, and counters of guesses used to lock out
+// brute-force attempts.
+//
+// RedisStore shares this state between API replicas and survives restarts;
+// MemoryStore keeps it in process and is meant for tests and single-process
+// development.
+package authcode
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrCooldown is returned by Store.Save when a code was sent to the address
+// too recently.
+var ErrCooldown = errors.New("verification code cooldown active")
+
+// Store keeps verification codes and attempt counters. Every entry expires on
+// its own; nothing needs to be cleaned up by the caller.
+type Store interface {
+	// Save stores code for email for ttl, replacing any earlier code. It
+	// returns ErrCooldown if a code was saved for email less than cooldown
+	// ago.
+	Save(ctx context.Context, email, code string, ttl, cooldown time.Duration) error
+
+	// Verify reports whether code is the current code for email. A correct
+	// code is consumed so it cannot be used twice.
+	Verify(ctx context.Context, email, code string) (bool, error)
+
+	// Discard deletes the current code for email, if any.
+	Discard(ctx context.Context, email string) error
+
+	// Attempt records a guess against key before it is checked and returns
+	// the number of attempts in the current window, this one included. The
+	// increment is atomic, so concurrent guesses each see their own count.
+	// The window starts with the first attempt and lasts window.
+	Attempt(ctx context.Context, key string, window time.Duration) (int, error)
+
+	// Forgive takes back one attempt recorded against key, for a guess that
+	// turned out to be right or was never checked.
+	Forgive(ctx context.Context, key string) error
+
+	// Failures returns the number of attempts recorded against key in its
+	// current window.
+	Failures(ctx context.Context, key string) (int, error)
+
+	// Reset clears the attempts recorded against key.
+	Reset(ctx context.Context, key string) error
+}
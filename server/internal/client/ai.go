@@ -1,12 +1,11 @@
 package client
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
+	"slices"
 	"strings"
 	"time"
 )
@@ -32,10 +31,18 @@ type RAGResult struct {
 type Analyzer interface {
 	Analyze(ctx context.Context, req AnalyzeRequest) (*AnalyzeResponse, error)
 	GenerateEchoCards(ctx context.Context, title string, source string, keyPoints []string) ([]EchoQAPair, error)
+	// GenerateClozeTerms picks key terms in a highlighted passage to blank out.
+	GenerateClozeTerms(ctx context.Context, title, passage string) ([]string, error)
+	// GenerateRelatedEchoCard writes a synthesis question connecting two related articles.
+	GenerateRelatedEchoCard(ctx context.Context, a, b EchoArticleBrief, reason string) (*EchoQAPair, error)
 	GenerateRAGAnswer(ctx context.Context, systemPrompt, userPrompt string) (*RAGResult, error)
+	// StreamRAGAnswer is GenerateRAGAnswer, passing answer text to onToken as it is generated.
+	StreamRAGAnswer(ctx context.Context, systemPrompt, userPrompt string, onToken func(string) error) (*RAGResult, error)
 	ExpandQuery(ctx context.Context, question string) ([]string, error)
 	RerankArticles(ctx context.Context, question string, candidates []RerankCandidate) ([]RerankResult, error)
 	SelectRelatedArticles(ctx context.Context, sourceTitle, sourceSummary string, candidates []RerankCandidate) ([]RelatedResult, error)
+	// GenerateConversationTitle names a RAG conversation from its first turn.
+	GenerateConversationTitle(ctx context.Context, question, answer string) (string, error)
 	// IsRealAI reports whether this analyzer calls a real LLM (vs a mock).
 	IsRealAI() bool
 }
@@ -108,84 +115,41 @@ var validCategories = func() map[string]string {
 	return m
 }()
 
-// DeepSeekAnalyzer calls the DeepSeek (OpenAI-compatible) API directly.
-type DeepSeekAnalyzer struct {
-	apiKey     string
-	baseURL    string
-	httpClient *http.Client
+// LLMAnalyzer implements Analyzer on top of an LLMRegistry, so every task
+// can be routed to its own provider and model with fallbacks.
+type LLMAnalyzer struct {
+	llm *LLMRegistry
 }
 
-// NewDeepSeekAnalyzer creates a DeepSeekAnalyzer.
-// baseURL should be e.g. "https://api.deepseek.com" (no trailing slash).
-func NewDeepSeekAnalyzer(apiKey, baseURL string) *DeepSeekAnalyzer {
-	return &DeepSeekAnalyzer{
-		apiKey:     apiKey,
-		baseURL:    strings.TrimRight(baseURL, "/"),
-		httpClient: &http.Client{Timeout: 60 * time.Second},
-	}
-}
-
-func (d *DeepSeekAnalyzer) IsRealAI() bool { return true }
-
-// --- OpenAI-compatible request/response types ---
-
-type chatRequest struct {
-	Model          string        `json:"model"`
-	Messages       []chatMessage `json:"messages"`
-	Temperature    float64       `json:"temperature"`
-	MaxTokens      int           `json:"max_tokens"`
-	ResponseFormat *respFormat   `json:"response_format,omitempty"`
+// NewLLMAnalyzer creates an LLMAnalyzer.
+func NewLLMAnalyzer(llm *LLMRegistry) *LLMAnalyzer {
+	return &LLMAnalyzer{llm: llm}
 }
 
-type chatMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
-}
+func (a *LLMAnalyzer) IsRealAI() bool { return true }
 
-type respFormat struct {
-	Type string `json:"type"`
-}
-
-type chatResponse struct {
-	Choices []chatChoice `json:"choices"`
-	Error   *chatError   `json:"error,omitempty"`
-}
-
-type chatChoice struct {
-	Message chatMessage `json:"message"`
-}
-
-type chatError struct {
-	Message string `json:"message"`
-	Type    string `json:"type"`
-}
-
-// Analyze sends the article to DeepSeek and returns the structured analysis.
-func (d *DeepSeekAnalyzer) Analyze(ctx context.Context, req AnalyzeRequest) (*AnalyzeResponse, error) {
-	systemPrompt := buildSystemPrompt()
-	userPrompt := buildUserPrompt(req.Title, req.Content, req.Source, req.Author)
-
-	chatReq := chatRequest{
-		Model: "deepseek-chat",
-		Messages: []chatMessage{
-			{Role: "system", Content: systemPrompt},
-			{Role: "user", Content: userPrompt},
-		},
-		Temperature:    0.3,
-		MaxTokens:      1024,
-		ResponseFormat: &respFormat{Type: "json_object"},
+// Analyze sends the article to the analyze model and returns the structured analysis.
+func (a *LLMAnalyzer) Analyze(ctx context.Context, req AnalyzeRequest) (*AnalyzeResponse, error) {
+	chatReq := ChatRequest{
+		System:      buildSystemPrompt(),
+		User:        buildUserPrompt(req.Title, req.Content, req.Source, req.Author),
+		Temperature: 0.3,
+		MaxTokens:   1024,
+		JSON:        true,
 	}
 
-	respBody, err := d.doRequest(ctx, chatReq)
+	var result AnalyzeResponse
+	err := a.llm.Complete(ctx, TaskAnalyze, chatReq, func(reply []byte) error {
+		result = AnalyzeResponse{}
+		if err := json.Unmarshal(reply, &result); err != nil {
+			return fmt.Errorf("decode analysis json: %w (raw: %s)", err, string(reply))
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("analyze: %w", err)
 	}
 
-	var result AnalyzeResponse
-	if err := json.Unmarshal(respBody, &result); err != nil {
-		return nil, fmt.Errorf("decode analysis json: %w (raw: %s)", err, string(respBody))
-	}
-
 	validateResponse(&result)
 	return &result, nil
 }
@@ -306,13 +270,12 @@ type EchoQAPair struct {
 	SourceContext string `json:"source_context"`
 }
 
-// GenerateEchoCards calls DeepSeek to generate 1-2 echo Q&A pairs from article key points.
-// If the analyzer has no API key, it returns a deterministic fallback using key_points directly.
-func (d *DeepSeekAnalyzer) GenerateEchoCards(ctx context.Context, title string, source string, keyPoints []string) ([]EchoQAPair, error) {
-	if d.apiKey == "" {
-		return generateMockEchoCards(title, source, keyPoints), nil
-	}
+// errNoEchoPairs marks an echo card reply that held no usable Q&A pairs.
+var errNoEchoPairs = errors.New("no echo pairs in reply")
 
+// GenerateEchoCards asks the echo_cards model for 1-2 echo Q&A pairs from article key points.
+// If no model returns usable pairs, it falls back to template cards built from key_points.
+func (a *LLMAnalyzer) GenerateEchoCards(ctx context.Context, title string, source string, keyPoints []string) ([]EchoQAPair, error) {
 	systemPrompt := `你是一个回忆测试生成器。基于文章要点，生成 1-2 个回忆测试问答对。
 
 要求：
@@ -329,85 +292,141 @@ func (d *DeepSeekAnalyzer) GenerateEchoCards(ctx context.Context, title string,
 
 	userPrompt := fmt.Sprintf("文章标题：%s\n来源：%s\n要点：\n%s", SanitizeField(title), SanitizeField(source), pointsBuilder.String())
 
-	chatReq := chatRequest{
-		Model: "deepseek-chat",
-		Messages: []chatMessage{
-			{Role: "system", Content: systemPrompt},
-			{Role: "user", Content: userPrompt},
-		},
-		Temperature:    0.3,
-		MaxTokens:      512,
-		ResponseFormat: &respFormat{Type: "json_object"},
+	chatReq := ChatRequest{
+		System:      systemPrompt,
+		User:        userPrompt,
+		Temperature: 0.3,
+		MaxTokens:   512,
+		JSON:        true,
 	}
 
-	respBody, err := d.doRequest(ctx, chatReq)
+	var pairs []EchoQAPair
+	err := a.llm.Complete(ctx, TaskEchoCards, chatReq, func(reply []byte) error {
+		pairs = parseEchoPairs(reply)
+		if len(pairs) == 0 {
+			return errNoEchoPairs
+		}
+		return nil
+	})
+	if errors.Is(err, errNoEchoPairs) {
+		// At least one model answered, just not usefully.
+		return echoFallbackCards(title, source, keyPoints), nil
+	}
 	if err != nil {
 		return nil, fmt.Errorf("generate echo cards: %w", err)
 	}
+	return pairs, nil
+}
 
-	content := string(respBody)
-
-	// Try parsing as array directly
+// parseEchoPairs reads Q&A pairs from a reply that is either a JSON array or
+// an object wrapping one (json_object mode may wrap).
+func parseEchoPairs(reply []byte) []EchoQAPair {
 	var pairs []EchoQAPair
-	if err := json.Unmarshal([]byte(content), &pairs); err == nil && len(pairs) > 0 {
-		return pairs, nil
+	if err := json.Unmarshal(reply, &pairs); err == nil && len(pairs) > 0 {
+		return pairs
 	}
 
-	// Try parsing as object with array field (json_object mode may wrap)
 	var wrapper map[string]json.RawMessage
-	if err := json.Unmarshal([]byte(content), &wrapper); err == nil {
+	if err := json.Unmarshal(reply, &wrapper); err == nil {
 		for _, v := range wrapper {
 			if err := json.Unmarshal(v, &pairs); err == nil && len(pairs) > 0 {
-				return pairs, nil
+				return pairs
 			}
 		}
 	}
-
-	// Fallback: return a template card using the first key point
-	return echoFallbackCards(title, source, keyPoints), nil
+	return nil
 }
 
-// generateMockEchoCards returns deterministic echo cards without calling any API.
-func generateMockEchoCards(title, source string, keyPoints []string) []EchoQAPair {
-	return echoFallbackCards(title, source, keyPoints)
+// clozeMaxTerms caps how many cloze cards one highlight yields.
+const clozeMaxTerms = 3
+
+// GenerateClozeTerms asks the echo_cards model for up to clozeMaxTerms key
+// terms in a highlighted passage to blank out, each copied verbatim from the
+// passage. Terms that do not occur in the passage are dropped.
+func (a *LLMAnalyzer) GenerateClozeTerms(ctx context.Context, title, passage string) ([]string, error) {
+	systemPrompt := fmt.Sprintf(`你是一个填空题生成器。从用户标注的段落中挑出 1-%d 个最值得记住的关键词或短语，用于挖空回忆。
+
+要求：
+1. 每个词必须原样出现在段落中，不要改写
+2. 选择概念、术语、人名、数字等关键信息，不要选虚词或整句
+3. 每个词不超过 15 个字，彼此不重叠
+
+输出 JSON，不要 markdown 代码块：{"terms": ["...", "..."]}`, clozeMaxTerms)
+
+	userPrompt := fmt.Sprintf("文章标题：%s\n段落：%s", SanitizeField(title), SanitizeField(truncate(passage, 1000)))
+
+	chatReq := ChatRequest{
+		System:      systemPrompt,
+		User:        userPrompt,
+		Temperature: 0.2,
+		MaxTokens:   200,
+		JSON:        true,
+	}
+
+	var terms []string
+	err := a.llm.Complete(ctx, TaskEchoCards, chatReq, func(reply []byte) error {
+		var result struct {
+			Terms []string `json:"terms"`
+		}
+		if err := json.Unmarshal(reply, &result); err != nil {
+			return fmt.Errorf("parse cloze terms response: %w (raw: %s)", err, string(reply))
+		}
+		terms = filterClozeTerms(passage, result.Terms)
+		if len(terms) == 0 {
+			return errors.New("no cloze terms in passage")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("generate cloze terms: %w", err)
+	}
+	return terms, nil
 }
 
-// GenerateRAGAnswer calls DeepSeek to produce a RAG answer from a system + user prompt.
-// Uses a 30-second timeout. If no API key is configured, returns a mock fallback.
-func (d *DeepSeekAnalyzer) GenerateRAGAnswer(ctx context.Context, systemPrompt, userPrompt string) (*RAGResult, error) {
-	if d.apiKey == "" {
-		return &RAGResult{
-			Answer:              "这是一个模拟回答。基于你的收藏¹，...",
-			CitedIndices:        []int{1},
-			FollowupSuggestions: []string{"还有什么相关的？"},
-		}, nil
+// filterClozeTerms keeps the distinct terms that occur in passage, up to
+// clozeMaxTerms.
+func filterClozeTerms(passage string, terms []string) []string {
+	kept := make([]string, 0, len(terms))
+	for _, t := range terms {
+		t = strings.TrimSpace(t)
+		if t == "" || t == strings.TrimSpace(passage) || !strings.Contains(passage, t) || slices.Contains(kept, t) {
+			continue
+		}
+		kept = append(kept, t)
+		if len(kept) == clozeMaxTerms {
+			break
+		}
 	}
+	return kept
+}
 
-	chatReq := chatRequest{
-		Model: "deepseek-chat",
-		Messages: []chatMessage{
-			{Role: "system", Content: systemPrompt},
-			{Role: "user", Content: userPrompt},
-		},
-		Temperature:    0.3,
-		MaxTokens:      2048,
-		ResponseFormat: &respFormat{Type: "json_object"},
+// GenerateRAGAnswer asks the rag model to produce an answer from a system + user prompt.
+// The whole call, fallbacks included, is bounded by a 30-second timeout.
+func (a *LLMAnalyzer) GenerateRAGAnswer(ctx context.Context, systemPrompt, userPrompt string) (*RAGResult, error) {
+	chatReq := ChatRequest{
+		System:      systemPrompt,
+		User:        userPrompt,
+		Temperature: 0.3,
+		MaxTokens:   2048,
+		JSON:        true,
 	}
 
 	// Use a 30-second timeout for RAG calls.
 	ragCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	respBody, err := d.doRequest(ragCtx, chatReq)
+	var result RAGResult
+	err := a.llm.Complete(ragCtx, TaskRAG, chatReq, func(reply []byte) error {
+		result = RAGResult{}
+		if err := json.Unmarshal(reply, &result); err != nil {
+			return fmt.Errorf("decode rag json: %w (raw: %s)", err, string(reply))
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("rag: %w", err)
 	}
 
-	var result RAGResult
-	if err := json.Unmarshal(respBody, &result); err != nil {
-		return nil, fmt.Errorf("decode rag json: %w (raw: %s)", err, string(respBody))
-	}
-
 	// Ensure slices are non-nil.
 	if result.CitedIndices == nil {
 		result.CitedIndices = []int{}
@@ -419,51 +438,8 @@ func (d *DeepSeekAnalyzer) GenerateRAGAnswer(ctx context.Context, systemPrompt,
 	return &result, nil
 }
 
-// doRequest sends a chat request and returns the raw content string from the first choice.
-func (d *DeepSeekAnalyzer) doRequest(ctx context.Context, chatReq chatRequest) ([]byte, error) {
-	body, err := json.Marshal(chatReq)
-	if err != nil {
-		return nil, fmt.Errorf("marshal chat request: %w", err)
-	}
-
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", d.baseURL+"/chat/completions", bytes.NewReader(body))
-	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+d.apiKey)
-
-	resp, err := d.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("deepseek request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("read response body: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("deepseek api error: status %d, body: %s", resp.StatusCode, string(respBody))
-	}
-
-	var chatResp chatResponse
-	if err := json.Unmarshal(respBody, &chatResp); err != nil {
-		return nil, fmt.Errorf("decode chat response: %w", err)
-	}
-	if chatResp.Error != nil {
-		return nil, fmt.Errorf("deepseek api error: %s", chatResp.Error.Message)
-	}
-	if len(chatResp.Choices) == 0 {
-		return nil, fmt.Errorf("deepseek returned no choices")
-	}
-
-	return []byte(chatResp.Choices[0].Message.Content), nil
-}
-
 // ExpandQuery generates 10-15 search keywords for a user question via LLM.
-func (d *DeepSeekAnalyzer) ExpandQuery(ctx context.Context, question string) ([]string, error) {
+func (a *LLMAnalyzer) ExpandQuery(ctx context.Context, question string) ([]string, error) {
 	systemPrompt := `给定用户问题，生成 10-15 个搜索关键词，用于在文章库中检索相关内容。
 
 要求：
@@ -478,32 +454,31 @@ func (d *DeepSeekAnalyzer) ExpandQuery(ctx context.Context, question string) ([]
 
 	userPrompt := fmt.Sprintf("用户问题：%s", SanitizeField(question))
 
-	chatReq := chatRequest{
-		Model: "deepseek-chat",
-		Messages: []chatMessage{
-			{Role: "system", Content: systemPrompt},
-			{Role: "user", Content: userPrompt},
-		},
-		Temperature:    0,
-		MaxTokens:      200,
-		ResponseFormat: &respFormat{Type: "json_object"},
-	}
-
-	respBody, err := d.doRequest(ctx, chatReq)
-	if err != nil {
-		return nil, fmt.Errorf("expand query: %w", err)
+	chatReq := ChatRequest{
+		System:      systemPrompt,
+		User:        userPrompt,
+		Temperature: 0,
+		MaxTokens:   200,
+		JSON:        true,
 	}
 
 	var keywords []string
-	if err := json.Unmarshal(respBody, &keywords); err != nil {
-		// Try parsing as {"keywords": [...]} wrapper
-		var wrapper struct {
-			Keywords []string `json:"keywords"`
-		}
-		if err2 := json.Unmarshal(respBody, &wrapper); err2 != nil {
-			return nil, fmt.Errorf("parse expand query response: %w (raw: %s)", err, string(respBody))
+	err := a.llm.Complete(ctx, TaskQueryExpansion, chatReq, func(reply []byte) error {
+		keywords = nil
+		if err := json.Unmarshal(reply, &keywords); err != nil {
+			// Try parsing as {"keywords": [...]} wrapper
+			var wrapper struct {
+				Keywords []string `json:"keywords"`
+			}
+			if err2 := json.Unmarshal(reply, &wrapper); err2 != nil {
+				return fmt.Errorf("parse expand query response: %w (raw: %s)", err, string(reply))
+			}
+			keywords = wrapper.Keywords
 		}
-		keywords = wrapper.Keywords
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("expand query: %w", err)
 	}
 
 	// Ensure lowercase
@@ -515,7 +490,7 @@ func (d *DeepSeekAnalyzer) ExpandQuery(ctx context.Context, question string) ([]
 }
 
 // RerankArticles asks the LLM to judge relevance of candidates to a question.
-func (d *DeepSeekAnalyzer) RerankArticles(ctx context.Context, question string, candidates []RerankCandidate) ([]RerankResult, error) {
+func (a *LLMAnalyzer) RerankArticles(ctx context.Context, question string, candidates []RerankCandidate) ([]RerankResult, error) {
 	var b strings.Builder
 	fmt.Fprintf(&b, "用户问题：%s\n\n以下是候选文章列表。判断每篇与用户问题的相关程度，返回最相关的 Top 10。\n\n候选文章：\n", SanitizeField(question))
 	for _, c := range candidates {
@@ -537,35 +512,37 @@ func (d *DeepSeekAnalyzer) RerankArticles(ctx context.Context, question string,
 3. 按相关程度从高到低排列
 4. 不相关的不要返回`
 
-	chatReq := chatRequest{
-		Model:          "deepseek-chat",
-		Messages:       []chatMessage{{Role: "system", Content: systemPrompt}, {Role: "user", Content: b.String()}},
-		Temperature:    0,
-		MaxTokens:      512,
-		ResponseFormat: &respFormat{Type: "json_object"},
-	}
-
-	respBody, err := d.doRequest(ctx, chatReq)
-	if err != nil {
-		return nil, fmt.Errorf("rerank articles: %w", err)
+	chatReq := ChatRequest{
+		System:      systemPrompt,
+		User:        b.String(),
+		Temperature: 0,
+		MaxTokens:   512,
+		JSON:        true,
 	}
 
 	var results []RerankResult
-	if err := json.Unmarshal(respBody, &results); err != nil {
-		// Try wrapper format
-		var wrapper struct {
-			Results []RerankResult `json:"results"`
-		}
-		if err2 := json.Unmarshal(respBody, &wrapper); err2 != nil {
-			return nil, fmt.Errorf("parse rerank response: %w (raw: %s)", err, string(respBody))
+	err := a.llm.Complete(ctx, TaskRerank, chatReq, func(reply []byte) error {
+		results = nil
+		if err := json.Unmarshal(reply, &results); err != nil {
+			// Try wrapper format
+			var wrapper struct {
+				Results []RerankResult `json:"results"`
+			}
+			if err2 := json.Unmarshal(reply, &wrapper); err2 != nil {
+				return fmt.Errorf("parse rerank response: %w (raw: %s)", err, string(reply))
+			}
+			results = wrapper.Results
 		}
-		results = wrapper.Results
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("rerank articles: %w", err)
 	}
 	return results, nil
 }
 
 // SelectRelatedArticles asks the LLM to pick the most related articles to a source article.
-func (d *DeepSeekAnalyzer) SelectRelatedArticles(ctx context.Context, sourceTitle, sourceSummary string, candidates []RerankCandidate) ([]RelatedResult, error) {
+func (a *LLMAnalyzer) SelectRelatedArticles(ctx context.Context, sourceTitle, sourceSummary string, candidates []RerankCandidate) ([]RelatedResult, error) {
 	var b strings.Builder
 	fmt.Fprintf(&b, "本文：《%s》\n摘要：%s\n\n候选文章：\n", SanitizeField(sourceTitle), SanitizeField(sourceSummary))
 	for _, c := range candidates {
@@ -580,28 +557,31 @@ func (d *DeepSeekAnalyzer) SelectRelatedArticles(ctx context.Context, sourceTitl
 2. 优先选择跨领域的有趣关联，而非简单的主题重复
 3. 没有相关的就少选，不要凑数`
 
-	chatReq := chatRequest{
-		Model:          "deepseek-chat",
-		Messages:       []chatMessage{{Role: "system", Content: systemPrompt}, {Role: "user", Content: b.String()}},
-		Temperature:    0,
-		MaxTokens:      512,
-		ResponseFormat: &respFormat{Type: "json_object"},
-	}
-
-	respBody, err := d.doRequest(ctx, chatReq)
-	if err != nil {
-		return nil, fmt.Errorf("select related articles: %w", err)
+	chatReq := ChatRequest{
+		System:      systemPrompt,
+		User:        b.String(),
+		Temperature: 0,
+		MaxTokens:   512,
+		JSON:        true,
 	}
 
 	var results []RelatedResult
-	if err := json.Unmarshal(respBody, &results); err != nil {
-		var wrapper struct {
-			Results []RelatedResult `json:"results"`
-		}
-		if err2 := json.Unmarshal(respBody, &wrapper); err2 != nil {
-			return nil, fmt.Errorf("parse related response: %w (raw: %s)", err, string(respBody))
+	err := a.llm.Complete(ctx, TaskRelated, chatReq, func(reply []byte) error {
+		results = nil
+		if err := json.Unmarshal(reply, &results); err != nil {
+			// Try wrapper format
+			var wrapper struct {
+				Results []RelatedResult `json:"results"`
+			}
+			if err2 := json.Unmarshal(reply, &wrapper); err2 != nil {
+				return fmt.Errorf("parse related response: %w (raw: %s)", err, string(reply))
+			}
+			results = wrapper.Results
 		}
-		results = wrapper.Results
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("select related articles: %w", err)
 	}
 	return results, nil
 }
@@ -629,3 +609,117 @@ func echoFallbackCards(title, source string, keyPoints []string) []EchoQAPair {
 	}
 	return pairs
 }
+
+// EchoArticleBrief is what GenerateRelatedEchoCard knows about an article.
+type EchoArticleBrief struct {
+	Title     string
+	Summary   string
+	KeyPoints []string
+}
+
+func (b EchoArticleBrief) prompt() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "标题：%s\n", SanitizeField(b.Title))
+	if b.Summary != "" {
+		fmt.Fprintf(&sb, "摘要：%s\n", SanitizeField(truncate(b.Summary, 500)))
+	}
+	for _, kp := range b.KeyPoints {
+		fmt.Fprintf(&sb, "- %s\n", SanitizeField(kp))
+	}
+	return sb.String()
+}
+
+// GenerateRelatedEchoCard asks the echo_cards model for one synthesis
+// question that connects two related articles: how one's claim supports,
+// extends or contrasts with the other's.
+func (a *LLMAnalyzer) GenerateRelatedEchoCard(ctx context.Context, first, second EchoArticleBrief, reason string) (*EchoQAPair, error) {
+	systemPrompt := `你是一个回忆测试生成器。用户读过两篇相关的文章，请生成 1 个综合性的回忆问答对，把两篇文章联系起来。
+
+要求：
+1. question：问两篇文章的观点如何相互印证、补充或对立，例如"《A》关于 X 的观点与《B》有何不同？"，不超过 50 字
+2. answer：简洁地说明两者的联系，不超过 80 字
+3. 只依据给出的内容，不要编造
+
+输出 JSON，不要 markdown 代码块：{"question": "...", "answer": "..."}`
+
+	userPrompt := fmt.Sprintf("文章 A：\n%s\n文章 B：\n%s\n两者的关联：%s",
+		first.prompt(), second.prompt(), SanitizeField(reason))
+
+	chatReq := ChatRequest{
+		System:      systemPrompt,
+		User:        userPrompt,
+		Temperature: 0.4,
+		MaxTokens:   300,
+		JSON:        true,
+	}
+
+	var pair EchoQAPair
+	err := a.llm.Complete(ctx, TaskEchoCards, chatReq, func(reply []byte) error {
+		if err := json.Unmarshal(reply, &pair); err != nil {
+			return fmt.Errorf("parse related echo card response: %w (raw: %s)", err, string(reply))
+		}
+		pair.Question, pair.Answer = strings.TrimSpace(pair.Question), strings.TrimSpace(pair.Answer)
+		if pair.Question == "" || pair.Answer == "" {
+			return errors.New("empty related echo card")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("generate related echo card: %w", err)
+	}
+	pair.SourceContext = fmt.Sprintf("连接《%s》与《%s》", first.Title, second.Title)
+	return &pair, nil
+}
+
+// conversationTitleMaxRunes caps generated conversation titles.
+const conversationTitleMaxRunes = 30
+
+// GenerateConversationTitle asks the conversation_title model for a short
+// title summing up a RAG conversation's first question and answer.
+func (a *LLMAnalyzer) GenerateConversationTitle(ctx context.Context, question, answer string) (string, error) {
+	systemPrompt := `为一段问答对话起一个简短的标题，概括用户关心的主题。
+
+要求：
+1. 不超过 15 个字（英文不超过 8 个词），使用提问所用的语言
+2. 不要加引号、书名号或结尾标点
+
+输出 JSON，不要 markdown 代码块：{"title": "..."}`
+
+	userPrompt := fmt.Sprintf("问题：%s\n回答：%s",
+		SanitizeField(question), SanitizeField(truncate(answer, 500)))
+
+	chatReq := ChatRequest{
+		System:      systemPrompt,
+		User:        userPrompt,
+		Temperature: 0.3,
+		MaxTokens:   60,
+		JSON:        true,
+	}
+
+	var title string
+	err := a.llm.Complete(ctx, TaskConvTitle, chatReq, func(reply []byte) error {
+		var result struct {
+			Title string `json:"title"`
+		}
+		if err := json.Unmarshal(reply, &result); err != nil {
+			return fmt.Errorf("parse conversation title response: %w (raw: %s)", err, string(reply))
+		}
+		title = strings.Trim(strings.TrimSpace(result.Title), `"'“”「」《》。.`)
+		if title == "" {
+			return errors.New("empty conversation title")
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("generate conversation title: %w", err)
+	}
+	return truncateForTitle(title, conversationTitleMaxRunes), nil
+}
+
+// truncateForTitle cuts s to at most n runes.
+func truncateForTitle(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
+	"unicode"
 	"unicode/utf8"
 )
 
@@ -273,6 +275,38 @@ func (m *MockAnalyzer) GenerateEchoCards(_ context.Context, title string, source
 	return pairs, nil
 }
 
+// GenerateClozeTerms returns the passage's longest words, in passage order,
+// without calling any API.
+func (m *MockAnalyzer) GenerateClozeTerms(_ context.Context, _, passage string) ([]string, error) {
+	words := strings.FieldsFunc(passage, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	longest := slices.Clone(words)
+	slices.SortStableFunc(longest, func(a, b string) int {
+		return utf8.RuneCountInString(b) - utf8.RuneCountInString(a)
+	})
+	if len(longest) > clozeMaxTerms {
+		longest = longest[:clozeMaxTerms]
+	}
+	terms := make([]string, 0, len(longest))
+	for _, w := range words {
+		if slices.Contains(longest, w) && utf8.RuneCountInString(w) > 1 {
+			terms = append(terms, w)
+		}
+	}
+	return filterClozeTerms(passage, terms), nil
+}
+
+// GenerateRelatedEchoCard returns a template synthesis question without
+// calling any API.
+func (m *MockAnalyzer) GenerateRelatedEchoCard(_ context.Context, a, b EchoArticleBrief, reason string) (*EchoQAPair, error) {
+	return &EchoQAPair{
+		Question:      fmt.Sprintf("《%s》与《%s》有什么联系？", orDefault(a.Title, "本文"), orDefault(b.Title, "相关文章")),
+		Answer:        orDefault(reason, "两篇文章讨论了相关的主题"),
+		SourceContext: fmt.Sprintf("连接《%s》与《%s》", a.Title, b.Title),
+	}, nil
+}
+
 // GenerateRAGAnswer returns a deterministic mock RAG answer without calling any API.
 func (m *MockAnalyzer) GenerateRAGAnswer(_ context.Context, _, _ string) (*RAGResult, error) {
 	return &RAGResult{
@@ -282,6 +316,18 @@ func (m *MockAnalyzer) GenerateRAGAnswer(_ context.Context, _, _ string) (*RAGRe
 	}, nil
 }
 
+// StreamRAGAnswer streams the mock RAG answer a few runes at a time.
+func (m *MockAnalyzer) StreamRAGAnswer(ctx context.Context, systemPrompt, userPrompt string, onToken func(string) error) (*RAGResult, error) {
+	result, _ := m.GenerateRAGAnswer(ctx, systemPrompt, userPrompt)
+	runes := []rune(result.Answer)
+	for i := 0; i < len(runes); i += 4 {
+		if err := onToken(string(runes[i:min(i+4, len(runes))])); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
 // ExpandQuery returns mock search keywords extracted from the question.
 func (m *MockAnalyzer) ExpandQuery(_ context.Context, question string) ([]string, error) {
 	keywords := []string{}
@@ -330,3 +376,8 @@ func isASCII(s string) bool {
 	}
 	return true
 }
+
+// GenerateConversationTitle returns the question, cut to title length.
+func (m *MockAnalyzer) GenerateConversationTitle(_ context.Context, question, _ string) (string, error) {
+	return truncateForTitle(strings.TrimSpace(question), conversationTitleMaxRunes), nil
+}
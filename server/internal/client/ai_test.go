@@ -253,3 +253,29 @@ func TestMockAnalyzer_CategoryFromURL(t *testing.T) {
 		}
 	}
 }
+
+func TestFilterClozeTerms(t *testing.T) {
+	passage := "费曼学习法要求用简单的语言向别人解释一个概念"
+	got := filterClozeTerms(passage, []string{" 费曼学习法 ", "费曼学习法", "记忆宫殿", "", passage, "简单的语言", "概念", "别人"})
+	want := []string{"费曼学习法", "简单的语言", "概念"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("filterClozeTerms = %q, want %q", got, want)
+	}
+}
+
+func TestMockAnalyzer_GenerateClozeTerms(t *testing.T) {
+	m := &MockAnalyzer{}
+	passage := "Spaced repetition schedules reviews along the forgetting curve"
+	terms, err := m.GenerateClozeTerms(context.Background(), "Title", passage)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(terms) == 0 || len(terms) > clozeMaxTerms {
+		t.Fatalf("got %d terms, want 1-%d", len(terms), clozeMaxTerms)
+	}
+	for _, term := range terms {
+		if !strings.Contains(passage, term) {
+			t.Errorf("term %q not in passage", term)
+		}
+	}
+}
@@ -22,26 +22,58 @@ const (
 	appleStoreKitSandboxURL    = "https://api.storekit-sandbox.itunes.apple.com"
 )
 
+// App Store environments, as reported in signed payloads.
+const (
+	AppleEnvironmentProduction = "Production"
+	AppleEnvironmentSandbox    = "Sandbox"
+)
+
 // TransactionInfo holds parsed App Store transaction data.
 type TransactionInfo struct {
 	TransactionID         string     `json:"transactionId"`
 	OriginalTransactionID string     `json:"originalTransactionId"`
 	ProductID             string     `json:"productId"`
 	BundleID              string     `json:"bundleId"`
+	Environment           string     `json:"environment"`
 	ExpiresDate           *time.Time `json:"expiresDate"`
 	PurchaseDate          *time.Time `json:"purchaseDate"`
 }
 
+// RenewalInfo holds parsed App Store subscription renewal data.
+type RenewalInfo struct {
+	OriginalTransactionID  string     `json:"originalTransactionId"`
+	AutoRenewProductID     string     `json:"autoRenewProductId"`
+	AutoRenewStatus        bool       `json:"autoRenewStatus"`
+	IsInBillingRetry       bool       `json:"isInBillingRetryPeriod"`
+	Environment            string     `json:"environment"`
+	GracePeriodExpiresDate *time.Time `json:"gracePeriodExpiresDate"`
+}
+
 // WebhookEvent represents an App Store Server notification.
 type WebhookEvent struct {
 	NotificationType string      `json:"notificationType"`
 	Subtype          string      `json:"subtype"`
+	NotificationUUID string      `json:"notificationUUID"`
+	SignedDate       int64       `json:"signedDate"` // milliseconds since epoch
 	Data             WebhookData `json:"data"`
 }
 
-// WebhookData contains the signed transaction info from a webhook.
+// SignedAt returns when Apple signed the notification, or the zero time if
+// the payload did not say.
+func (e *WebhookEvent) SignedAt() time.Time {
+	if e.SignedDate == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(e.SignedDate)
+}
+
+// WebhookData contains the signed transaction and renewal info from a
+// webhook.
 type WebhookData struct {
+	BundleID              string `json:"bundleId"`
+	Environment           string `json:"environment"`
 	SignedTransactionInfo string `json:"signedTransactionInfo"`
+	SignedRenewalInfo     string `json:"signedRenewalInfo"`
 }
 
 // AppleStoreClient abstracts App Store Server API operations so callers can
@@ -50,11 +82,14 @@ type AppleStoreClient interface {
 	VerifyTransaction(ctx context.Context, transactionID string) (*TransactionInfo, error)
 	ParseWebhookPayload(signedPayload string) (*WebhookEvent, error)
 	ParseSignedTransaction(signedTxn string) (*TransactionInfo, error)
+	ParseSignedRenewalInfo(signedRenewal string) (*RenewalInfo, error)
 }
 
 // ---------- Real client ----------
 
 // AppleClient communicates with the App Store Server API using ES256 JWTs.
+// Signed payloads it parses are verified against Apple's certificate chain
+// and must match the configured bundle ID and environment.
 type AppleClient struct {
 	keyID      string
 	issuerID   string
@@ -62,6 +97,7 @@ type AppleClient struct {
 	bundleID   string
 	sandbox    bool
 	httpClient *http.Client
+	verifier   *jwsVerifier
 }
 
 // NewAppleClient creates an AppleStoreClient. If keyPath is empty a mock
@@ -76,6 +112,10 @@ func NewAppleClient(keyID, issuerID, keyPath, bundleID string, sandbox bool) (Ap
 	if err != nil {
 		return nil, fmt.Errorf("load apple p8 key: %w", err)
 	}
+	verifier, err := newJWSVerifier(appleRootCAG3SHA256)
+	if err != nil {
+		return nil, err
+	}
 
 	return &AppleClient{
 		keyID:      keyID,
@@ -84,9 +124,18 @@ func NewAppleClient(keyID, issuerID, keyPath, bundleID string, sandbox bool) (Ap
 		bundleID:   bundleID,
 		sandbox:    sandbox,
 		httpClient: &http.Client{Timeout: 30 * time.Second},
+		verifier:   verifier,
 	}, nil
 }
 
+// environment returns the App Store environment payloads must come from.
+func (c *AppleClient) environment() string {
+	if c.sandbox {
+		return AppleEnvironmentSandbox
+	}
+	return AppleEnvironmentProduction
+}
+
 // baseURL returns the appropriate App Store Server API host.
 func (c *AppleClient) baseURL() string {
 	if c.sandbox {
@@ -147,48 +196,113 @@ func (c *AppleClient) VerifyTransaction(ctx context.Context, transactionID strin
 		return nil, fmt.Errorf("decode transaction envelope: %w", err)
 	}
 
-	return parseSignedTransaction(envelope.SignedTransactionInfo)
+	return c.ParseSignedTransaction(envelope.SignedTransactionInfo)
 }
 
-// ParseWebhookPayload decodes an App Store Server notification from its
-// JWS signed payload.
+// ParseWebhookPayload verifies and decodes an App Store Server notification
+// from its JWS signed payload.
 func (c *AppleClient) ParseWebhookPayload(signedPayload string) (*WebhookEvent, error) {
-	return parseWebhookPayload(signedPayload)
+	payload, err := c.verifier.verify(signedPayload)
+	if err != nil {
+		return nil, fmt.Errorf("verify webhook payload: %w", err)
+	}
+	event, err := unmarshalWebhookEvent(payload)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkOrigin(event.Data.BundleID, event.Data.Environment); err != nil {
+		return nil, fmt.Errorf("webhook payload: %w", err)
+	}
+	return event, nil
 }
 
-// ParseSignedTransaction decodes a JWS-signed transaction string into
-// TransactionInfo.
+// ParseSignedTransaction verifies a JWS-signed transaction string and
+// decodes it into TransactionInfo.
 func (c *AppleClient) ParseSignedTransaction(signedTxn string) (*TransactionInfo, error) {
-	return parseSignedTransaction(signedTxn)
+	payload, err := c.verifier.verify(signedTxn)
+	if err != nil {
+		return nil, fmt.Errorf("verify signed transaction: %w", err)
+	}
+	info, err := unmarshalTransactionInfo(payload)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkOrigin(info.BundleID, info.Environment); err != nil {
+		return nil, fmt.Errorf("signed transaction: %w", err)
+	}
+	return info, nil
+}
+
+// ParseSignedRenewalInfo verifies a JWS-signed renewal info string and
+// decodes it into RenewalInfo. Renewal info carries no bundle ID, so only
+// the environment is checked.
+func (c *AppleClient) ParseSignedRenewalInfo(signedRenewal string) (*RenewalInfo, error) {
+	payload, err := c.verifier.verify(signedRenewal)
+	if err != nil {
+		return nil, fmt.Errorf("verify signed renewal info: %w", err)
+	}
+	info, err := unmarshalRenewalInfo(payload)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkOrigin(c.bundleID, info.Environment); err != nil {
+		return nil, fmt.Errorf("signed renewal info: %w", err)
+	}
+	return info, nil
+}
+
+// checkOrigin rejects payloads signed for another app or environment.
+func (c *AppleClient) checkOrigin(bundleID, environment string) error {
+	if bundleID != c.bundleID {
+		return fmt.Errorf("bundle ID %q does not match %q", bundleID, c.bundleID)
+	}
+	if environment != c.environment() {
+		return fmt.Errorf("environment %q does not match %q", environment, c.environment())
+	}
+	return nil
 }
 
 // ---------- Shared JWS helpers ----------
 
 // parseSignedTransaction base64-decodes the payload portion of a JWS and
-// unmarshals it into TransactionInfo. Full JWS signature verification is
-// skipped for the MVP — Apple's TLS guarantees authenticity in transit.
+// unmarshals it into TransactionInfo without verifying the signature. Only
+// MockAppleClient uses it; AppleClient.ParseSignedTransaction verifies.
 func parseSignedTransaction(signed string) (*TransactionInfo, error) {
 	payload, err := decodeJWSPayload(signed)
 	if err != nil {
 		return nil, fmt.Errorf("decode signed transaction: %w", err)
 	}
+	return unmarshalTransactionInfo(payload)
+}
+
+// parseWebhookPayload base64-decodes the payload portion of a JWS and
+// unmarshals it into WebhookEvent without verifying the signature. Only
+// MockAppleClient uses it; AppleClient.ParseWebhookPayload verifies.
+func parseWebhookPayload(signedPayload string) (*WebhookEvent, error) {
+	payload, err := decodeJWSPayload(signedPayload)
+	if err != nil {
+		return nil, fmt.Errorf("decode webhook payload: %w", err)
+	}
+	return unmarshalWebhookEvent(payload)
+}
 
+func unmarshalTransactionInfo(payload []byte) (*TransactionInfo, error) {
 	var raw transactionInfoRaw
 	if err := json.Unmarshal(payload, &raw); err != nil {
 		return nil, fmt.Errorf("unmarshal transaction info: %w", err)
 	}
-
 	return raw.toTransactionInfo(), nil
 }
 
-// parseWebhookPayload base64-decodes the payload portion of a JWS and
-// unmarshals it into WebhookEvent.
-func parseWebhookPayload(signedPayload string) (*WebhookEvent, error) {
-	payload, err := decodeJWSPayload(signedPayload)
-	if err != nil {
-		return nil, fmt.Errorf("decode webhook payload: %w", err)
+func unmarshalRenewalInfo(payload []byte) (*RenewalInfo, error) {
+	var raw renewalInfoRaw
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return nil, fmt.Errorf("unmarshal renewal info: %w", err)
 	}
+	return raw.toRenewalInfo(), nil
+}
 
+func unmarshalWebhookEvent(payload []byte) (*WebhookEvent, error) {
 	var event WebhookEvent
 	if err := json.Unmarshal(payload, &event); err != nil {
 		return nil, fmt.Errorf("unmarshal webhook event: %w", err)
@@ -213,6 +327,7 @@ type transactionInfoRaw struct {
 	OriginalTransactionID string `json:"originalTransactionId"`
 	ProductID             string `json:"productId"`
 	BundleID              string `json:"bundleId"`
+	Environment           string `json:"environment"`
 	ExpiresDate           *int64 `json:"expiresDate"`
 	PurchaseDate          *int64 `json:"purchaseDate"`
 }
@@ -223,6 +338,7 @@ func (r *transactionInfoRaw) toTransactionInfo() *TransactionInfo {
 		OriginalTransactionID: r.OriginalTransactionID,
 		ProductID:             r.ProductID,
 		BundleID:              r.BundleID,
+		Environment:           r.Environment,
 	}
 	if r.ExpiresDate != nil {
 		t := time.UnixMilli(*r.ExpiresDate)
@@ -235,6 +351,32 @@ func (r *transactionInfoRaw) toTransactionInfo() *TransactionInfo {
 	return info
 }
 
+// renewalInfoRaw mirrors Apple's renewal JSON, where autoRenewStatus is 0 or
+// 1 and dates are milliseconds since epoch.
+type renewalInfoRaw struct {
+	OriginalTransactionID  string `json:"originalTransactionId"`
+	AutoRenewProductID     string `json:"autoRenewProductId"`
+	AutoRenewStatus        int    `json:"autoRenewStatus"`
+	IsInBillingRetryPeriod bool   `json:"isInBillingRetryPeriod"`
+	Environment            string `json:"environment"`
+	GracePeriodExpiresDate *int64 `json:"gracePeriodExpiresDate"`
+}
+
+func (r *renewalInfoRaw) toRenewalInfo() *RenewalInfo {
+	info := &RenewalInfo{
+		OriginalTransactionID: r.OriginalTransactionID,
+		AutoRenewProductID:    r.AutoRenewProductID,
+		AutoRenewStatus:       r.AutoRenewStatus == 1,
+		IsInBillingRetry:      r.IsInBillingRetryPeriod,
+		Environment:           r.Environment,
+	}
+	if r.GracePeriodExpiresDate != nil {
+		t := time.UnixMilli(*r.GracePeriodExpiresDate)
+		info.GracePeriodExpiresDate = &t
+	}
+	return info
+}
+
 // ---------- .p8 key loading ----------
 
 // loadP8Key reads an Apple .p8 (PKCS#8 PEM) file and returns the EC private
@@ -317,3 +459,15 @@ func (m *MockAppleClient) ParseSignedTransaction(signedTxn string) (*Transaction
 	}
 	return info, nil
 }
+
+func (m *MockAppleClient) ParseSignedRenewalInfo(signedRenewal string) (*RenewalInfo, error) {
+	payload, err := decodeJWSPayload(signedRenewal)
+	if err != nil {
+		return &RenewalInfo{AutoRenewStatus: true}, nil
+	}
+	info, err := unmarshalRenewalInfo(payload)
+	if err != nil {
+		return &RenewalInfo{AutoRenewStatus: true}, nil
+	}
+	return info, nil
+}
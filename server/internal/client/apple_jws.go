@@ -0,0 +1,126 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// appleRootCAG3SHA256 is the SHA-256 fingerprint of Apple Root CA - G3, the
+// root of every App Store JWS certificate chain
+// (https://www.apple.com/certificateauthority/).
+const appleRootCAG3SHA256 = "63343abfb89a6a03ebb57e9b3f5fa7be7c4f5c756f3017b3a8c488c3653e9179"
+
+// Marker extensions Apple places on the certificates of the App Store
+// signing chain. Checking them stops any other certificate issued under the
+// same root from signing App Store payloads.
+var (
+	oidAppStoreReceiptSigner = asn1.ObjectIdentifier{1, 2, 840, 113635, 100, 6, 11, 1}
+	oidAppleWWDRIntermediate = asn1.ObjectIdentifier{1, 2, 840, 113635, 100, 6, 2, 1}
+)
+
+// ErrInvalidAppleSignature is returned for App Store JWS payloads whose
+// signature or certificate chain does not verify.
+var ErrInvalidAppleSignature = errors.New("invalid apple jws signature")
+
+// jwsVerifier verifies App Store signed payloads: the x5c header must hold
+// leaf, intermediate and root certificates, the root must be pinned, the
+// chain must verify, and the leaf key must carry the ES256 signature.
+type jwsVerifier struct {
+	roots map[[sha256.Size]byte]bool
+	now   func() time.Time
+}
+
+// newJWSVerifier pins the roots with the given hex SHA-256 fingerprints.
+func newJWSVerifier(rootFingerprints ...string) (*jwsVerifier, error) {
+	roots := make(map[[sha256.Size]byte]bool, len(rootFingerprints))
+	for _, fp := range rootFingerprints {
+		b, err := hex.DecodeString(fp)
+		if err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("invalid root fingerprint %q", fp)
+		}
+		roots[[sha256.Size]byte(b)] = true
+	}
+	return &jwsVerifier{roots: roots, now: time.Now}, nil
+}
+
+// verify checks jws and returns its decoded payload.
+func (v *jwsVerifier) verify(jws string) ([]byte, error) {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{jwt.SigningMethodES256.Alg()}),
+		jwt.WithoutClaimsValidation(),
+	)
+	if _, err := parser.Parse(jws, v.signingKey); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAppleSignature, err)
+	}
+	return decodeJWSPayload(jws)
+}
+
+// signingKey validates the token's x5c chain and returns the leaf key.
+func (v *jwsVerifier) signingKey(t *jwt.Token) (any, error) {
+	raw, ok := t.Header["x5c"].([]any)
+	if !ok || len(raw) != 3 {
+		return nil, errors.New("x5c header must contain three certificates")
+	}
+	certs := make([]*x509.Certificate, len(raw))
+	for i, c := range raw {
+		s, ok := c.(string)
+		if !ok {
+			return nil, fmt.Errorf("x5c[%d] is not a string", i)
+		}
+		der, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("decode x5c[%d]: %w", i, err)
+		}
+		if certs[i], err = x509.ParseCertificate(der); err != nil {
+			return nil, fmt.Errorf("parse x5c[%d]: %w", i, err)
+		}
+	}
+	leaf, intermediate, root := certs[0], certs[1], certs[2]
+
+	if !v.roots[sha256.Sum256(root.Raw)] {
+		return nil, errors.New("root certificate is not pinned")
+	}
+	if !hasExtension(leaf, oidAppStoreReceiptSigner) {
+		return nil, errors.New("leaf certificate is not an App Store signer")
+	}
+	if !hasExtension(intermediate, oidAppleWWDRIntermediate) {
+		return nil, errors.New("intermediate certificate is not an Apple WWDR CA")
+	}
+
+	rootPool := x509.NewCertPool()
+	rootPool.AddCert(root)
+	intermediates := x509.NewCertPool()
+	intermediates.AddCert(intermediate)
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         rootPool,
+		Intermediates: intermediates,
+		CurrentTime:   v.now(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return nil, fmt.Errorf("verify certificate chain: %w", err)
+	}
+
+	key, ok := leaf.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("leaf key is %T, want ECDSA", leaf.PublicKey)
+	}
+	return key, nil
+}
+
+func hasExtension(cert *x509.Certificate, oid asn1.ObjectIdentifier) bool {
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oid) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,214 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testChain is a locally generated stand-in for Apple's signing chain.
+type testChain struct {
+	root, intermediate, leaf *x509.Certificate
+	leafKey                  *ecdsa.PrivateKey
+}
+
+func newTestCert(t *testing.T, cn string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, isCA bool, marker asn1.ObjectIdentifier) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	if marker != nil {
+		tmpl.ExtraExtensions = []pkix.Extension{{Id: marker, Value: []byte{0x05, 0x00}}}
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func newTestChain(t *testing.T, leafMarker asn1.ObjectIdentifier) *testChain {
+	t.Helper()
+	root, rootKey := newTestCert(t, "Test Root CA", nil, nil, true, nil)
+	inter, interKey := newTestCert(t, "Test WWDR CA", root, rootKey, true, oidAppleWWDRIntermediate)
+	leaf, leafKey := newTestCert(t, "Test App Store Signer", inter, interKey, false, leafMarker)
+	return &testChain{root: root, intermediate: inter, leaf: leaf, leafKey: leafKey}
+}
+
+// sign produces a JWS over claims with the chain in its x5c header.
+func (c *testChain) sign(t *testing.T, claims jwt.MapClaims, key *ecdsa.PrivateKey) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["x5c"] = []string{
+		base64.StdEncoding.EncodeToString(c.leaf.Raw),
+		base64.StdEncoding.EncodeToString(c.intermediate.Raw),
+		base64.StdEncoding.EncodeToString(c.root.Raw),
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func newTestAppleClient(t *testing.T, root *x509.Certificate) *AppleClient {
+	t.Helper()
+	fp := sha256.Sum256(root.Raw)
+	verifier, err := newJWSVerifier(hex.EncodeToString(fp[:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &AppleClient{bundleID: "com.folio.app", sandbox: true, verifier: verifier}
+}
+
+func transactionClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"transactionId":         "1000",
+		"originalTransactionId": "1000",
+		"productId":             "folio.pro.monthly",
+		"bundleId":              "com.folio.app",
+		"environment":           AppleEnvironmentSandbox,
+		"expiresDate":           time.Now().Add(30 * 24 * time.Hour).UnixMilli(),
+	}
+}
+
+func TestAppleClient_ParseSignedTransaction(t *testing.T) {
+	chain := newTestChain(t, oidAppStoreReceiptSigner)
+	c := newTestAppleClient(t, chain.root)
+
+	info, err := c.ParseSignedTransaction(chain.sign(t, transactionClaims(), chain.leafKey))
+	if err != nil {
+		t.Fatalf("ParseSignedTransaction: %v", err)
+	}
+	if info.TransactionID != "1000" || info.ProductID != "folio.pro.monthly" || info.ExpiresDate == nil {
+		t.Errorf("info = %+v", info)
+	}
+}
+
+func TestAppleClient_ParseWebhookPayload(t *testing.T) {
+	chain := newTestChain(t, oidAppStoreReceiptSigner)
+	c := newTestAppleClient(t, chain.root)
+
+	payload := chain.sign(t, jwt.MapClaims{
+		"notificationType": "DID_RENEW",
+		"notificationUUID": "uuid-1",
+		"data": map[string]any{
+			"bundleId":              "com.folio.app",
+			"environment":           AppleEnvironmentSandbox,
+			"signedTransactionInfo": chain.sign(t, transactionClaims(), chain.leafKey),
+		},
+	}, chain.leafKey)
+
+	event, err := c.ParseWebhookPayload(payload)
+	if err != nil {
+		t.Fatalf("ParseWebhookPayload: %v", err)
+	}
+	if event.NotificationType != "DID_RENEW" {
+		t.Errorf("notification type = %q", event.NotificationType)
+	}
+	if _, err := c.ParseSignedTransaction(event.Data.SignedTransactionInfo); err != nil {
+		t.Errorf("nested transaction: %v", err)
+	}
+}
+
+func TestAppleClient_RejectsInvalidSignatures(t *testing.T) {
+	chain := newTestChain(t, oidAppStoreReceiptSigner)
+	c := newTestAppleClient(t, chain.root)
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	unpinned := newTestChain(t, oidAppStoreReceiptSigner)
+	unmarked := newTestChain(t, nil)
+	unmarkedClient := newTestAppleClient(t, unmarked.root)
+
+	valid := chain.sign(t, transactionClaims(), chain.leafKey)
+	tests := []struct {
+		name   string
+		client *AppleClient
+		jws    string
+	}{
+		{"wrong signing key", c, chain.sign(t, transactionClaims(), otherKey)},
+		{"unpinned root", c, unpinned.sign(t, transactionClaims(), unpinned.leafKey)},
+		{"leaf without signer marker", unmarkedClient, unmarked.sign(t, transactionClaims(), unmarked.leafKey)},
+		{"unsigned", c, strings.Join(strings.Split(valid, ".")[:2], ".")},
+		{"tampered payload", c, tamperPayload(valid)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.client.ParseSignedTransaction(tt.jws); !errors.Is(err, ErrInvalidAppleSignature) {
+				t.Errorf("err = %v, want ErrInvalidAppleSignature", err)
+			}
+		})
+	}
+}
+
+func TestAppleClient_RejectsExpiredChain(t *testing.T) {
+	chain := newTestChain(t, oidAppStoreReceiptSigner)
+	c := newTestAppleClient(t, chain.root)
+	c.verifier.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+
+	if _, err := c.ParseSignedTransaction(chain.sign(t, transactionClaims(), chain.leafKey)); !errors.Is(err, ErrInvalidAppleSignature) {
+		t.Errorf("err = %v, want ErrInvalidAppleSignature", err)
+	}
+}
+
+func TestAppleClient_RejectsForeignOrigin(t *testing.T) {
+	chain := newTestChain(t, oidAppStoreReceiptSigner)
+	c := newTestAppleClient(t, chain.root)
+
+	otherBundle := transactionClaims()
+	otherBundle["bundleId"] = "com.example.other"
+	production := transactionClaims()
+	production["environment"] = AppleEnvironmentProduction
+
+	for name, claims := range map[string]jwt.MapClaims{"bundle": otherBundle, "environment": production} {
+		if _, err := c.ParseSignedTransaction(chain.sign(t, claims, chain.leafKey)); err == nil {
+			t.Errorf("%s mismatch accepted", name)
+		}
+	}
+
+	webhook := chain.sign(t, jwt.MapClaims{
+		"notificationType": "DID_RENEW",
+		"data":             map[string]any{"bundleId": "com.example.other", "environment": AppleEnvironmentSandbox},
+	}, chain.leafKey)
+	if _, err := c.ParseWebhookPayload(webhook); err == nil {
+		t.Error("webhook for another bundle accepted")
+	}
+}
+
+// tamperPayload swaps the payload of jws for a different one, keeping the
+// original header and signature.
+func tamperPayload(jws string) string {
+	parts := strings.Split(jws, ".")
+	parts[1] = base64.RawURLEncoding.EncodeToString([]byte(`{"transactionId":"9999","bundleId":"com.folio.app","environment":"Sandbox"}`))
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,225 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"folio-server/internal/config"
+)
+
+// Embedder turns text into vectors for semantic retrieval. Vectors from
+// different embedders are not comparable, so each is stored with Model.
+type Embedder interface {
+	// Model identifies the vector space, e.g. "local/nomic-embed-text".
+	Model() string
+	// Embed returns one vector per text, in order.
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// NewEmbedderFromConfig returns the embedder named by cfg.EmbeddingModel: a model
+// on one of the configured LLM providers, or the offline hash embedder.
+func NewEmbedderFromConfig(cfg config.Config) (Embedder, error) {
+	if cfg.EmbeddingModel == "" || cfg.EmbeddingModel == hashEmbedderModel {
+		return NewHashEmbedder(), nil
+	}
+	target, err := ParseModelTarget(cfg.EmbeddingModel)
+	if err != nil {
+		return nil, fmt.Errorf("EMBEDDING_MODEL: %w", err)
+	}
+	for _, p := range cfg.LLM.Providers {
+		if p.Name != target.Provider {
+			continue
+		}
+		switch p.Type {
+		case ProviderOpenAI:
+			return NewOpenAIEmbedder(p.BaseURL, p.APIKey, target), nil
+		case ProviderOllama:
+			return NewOllamaEmbedder(p.BaseURL, target), nil
+		default:
+			return nil, fmt.Errorf("EMBEDDING_MODEL: %s providers have no embeddings API", p.Type)
+		}
+	}
+	return nil, fmt.Errorf("EMBEDDING_MODEL: unknown provider %q", target.Provider)
+}
+
+// ---------- OpenAI-compatible ----------
+
+// OpenAIEmbedder calls POST /embeddings on an OpenAI-compatible server.
+type OpenAIEmbedder struct {
+	baseURL    string
+	apiKey     string
+	target     ModelTarget
+	httpClient *http.Client
+}
+
+// NewOpenAIEmbedder creates an OpenAIEmbedder; baseURL is as for NewOpenAIProvider.
+func NewOpenAIEmbedder(baseURL, apiKey string, target ModelTarget) *OpenAIEmbedder {
+	return &OpenAIEmbedder{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		target:     target,
+		httpClient: &http.Client{Timeout: llmHTTPTimeout},
+	}
+}
+
+func (e *OpenAIEmbedder) Model() string { return e.target.String() }
+
+type openAIEmbedResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Error *chatError `json:"error,omitempty"`
+}
+
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	header := http.Header{}
+	if e.apiKey != "" {
+		header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+	body := map[string]any{"model": e.target.Model, "input": texts}
+
+	var resp openAIEmbedResponse
+	if err := postJSON(ctx, e.httpClient, e.baseURL+"/embeddings", header, body, &resp); err != nil {
+		return nil, fmt.Errorf("embed: %w", err)
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("embed: api error: %s", resp.Error.Message)
+	}
+	if len(resp.Data) != len(texts) {
+		return nil, fmt.Errorf("embed: got %d vectors for %d texts", len(resp.Data), len(texts))
+	}
+	vectors := make([][]float32, len(texts))
+	for _, d := range resp.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("embed: vector index %d out of range", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
+
+// ---------- Ollama ----------
+
+// OllamaEmbedder calls Ollama's POST /api/embed.
+type OllamaEmbedder struct {
+	baseURL    string
+	target     ModelTarget
+	httpClient *http.Client
+}
+
+// NewOllamaEmbedder creates an OllamaEmbedder; baseURL is as for NewOllamaProvider.
+func NewOllamaEmbedder(baseURL string, target ModelTarget) *OllamaEmbedder {
+	return &OllamaEmbedder{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		target:     target,
+		httpClient: &http.Client{Timeout: llmHTTPTimeout},
+	}
+}
+
+func (e *OllamaEmbedder) Model() string { return e.target.String() }
+
+func (e *OllamaEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body := map[string]any{"model": e.target.Model, "input": texts}
+
+	var resp struct {
+		Embeddings [][]float32 `json:"embeddings"`
+		Error      string      `json:"error,omitempty"`
+	}
+	if err := postJSON(ctx, e.httpClient, e.baseURL+"/api/embed", nil, body, &resp); err != nil {
+		return nil, fmt.Errorf("embed: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("embed: api error: %s", resp.Error)
+	}
+	if len(resp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("embed: got %d vectors for %d texts", len(resp.Embeddings), len(texts))
+	}
+	return resp.Embeddings, nil
+}
+
+// ---------- Hash (offline) ----------
+
+const (
+	hashEmbedderModel = "hash"
+	hashEmbedderDims  = 256
+)
+
+// HashEmbedder is a deterministic, offline stand-in for a real embedding
+// model. It hashes words (and CJK character bigrams, since CJK text has no
+// spaces) into a fixed number of buckets, so texts sharing vocabulary get
+// similar vectors. It knows nothing of synonyms or meaning.
+type HashEmbedder struct{}
+
+// NewHashEmbedder creates a HashEmbedder.
+func NewHashEmbedder() *HashEmbedder { return &HashEmbedder{} }
+
+func (e *HashEmbedder) Model() string {
+	return fmt.Sprintf("%s/v1-%d", hashEmbedderModel, hashEmbedderDims)
+}
+
+func (e *HashEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, t := range texts {
+		vectors[i] = hashEmbed(t)
+	}
+	return vectors, nil
+}
+
+func hashEmbed(text string) []float32 {
+	v := make([]float32, hashEmbedderDims)
+	add := func(feature string) {
+		h := fnv.New32a()
+		h.Write([]byte(feature))
+		sum := h.Sum32()
+		sign := float32(1)
+		if sum&(1<<31) != 0 {
+			sign = -1 // signed hashing keeps collisions from only ever adding up
+		}
+		v[sum%hashEmbedderDims] += sign
+	}
+
+	var word []rune
+	var prevCJK rune
+	flush := func() {
+		if len(word) > 1 {
+			add(string(word))
+		}
+		word = word[:0]
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r):
+			flush()
+			add(string(r))
+			if prevCJK != 0 {
+				add(string([]rune{prevCJK, r}))
+			}
+			prevCJK = r
+			continue
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word = append(word, r)
+		default:
+			flush()
+		}
+		prevCJK = 0
+	}
+	flush()
+
+	var norm float64
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range v {
+			v[i] *= scale
+		}
+	}
+	return v
+}
@@ -0,0 +1,124 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"folio-server/internal/config"
+)
+
+func cosine(a, b []float32) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+func TestHashEmbedder(t *testing.T) {
+	e := NewHashEmbedder()
+	v, err := e.Embed(context.Background(), []string{
+		"Goroutines and channels in Go",
+		"Go channels and goroutines explained",
+		"Sourdough bread baking at home",
+		"机器学习入门教程",
+		"机器学习的基本概念",
+		"",
+	})
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if len(v[0]) != hashEmbedderDims {
+		t.Fatalf("dims = %d", len(v[0]))
+	}
+	if n := cosine(v[0], v[0]); math.Abs(n-1) > 1e-5 {
+		t.Errorf("self-similarity = %v", n)
+	}
+	if cosine(v[0], v[1]) <= cosine(v[0], v[2]) {
+		t.Errorf("related English texts not closer than unrelated ones")
+	}
+	if cosine(v[3], v[4]) <= cosine(v[3], v[2]) {
+		t.Errorf("related Chinese texts not closer than unrelated ones")
+	}
+	for _, x := range v[5] {
+		if x != 0 {
+			t.Fatal("empty text has a non-zero vector")
+		}
+	}
+
+	again, _ := e.Embed(context.Background(), []string{"Goroutines and channels in Go"})
+	if cosine(v[0], again[0]) < 0.99999 {
+		t.Error("hash embedding is not deterministic")
+	}
+}
+
+func TestOpenAIEmbedder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "text-embedding-3-small" || len(req.Input) != 2 {
+			t.Errorf("request = %+v", req)
+		}
+		// Out of order, as the API allows.
+		_, _ = w.Write([]byte(`{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`))
+	}))
+	defer srv.Close()
+
+	e := NewOpenAIEmbedder(srv.URL+"/v1", "k", ModelTarget{"oa", "text-embedding-3-small"})
+	v, err := e.Embed(context.Background(), []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if v[0][0] != 1 || v[1][1] != 1 || e.Model() != "oa/text-embedding-3-small" {
+		t.Errorf("vectors = %v, model %s", v, e.Model())
+	}
+}
+
+func TestOllamaEmbedder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embed" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		_, _ = w.Write([]byte(`{"model":"nomic-embed-text","embeddings":[[0.5,0.5]]}`))
+	}))
+	defer srv.Close()
+
+	v, err := NewOllamaEmbedder(srv.URL, ModelTarget{"local", "nomic-embed-text"}).Embed(context.Background(), []string{"a"})
+	if err != nil || len(v) != 1 || v[0][0] != 0.5 {
+		t.Errorf("vectors = %v, err %v", v, err)
+	}
+}
+
+func TestNewEmbedderFromConfig(t *testing.T) {
+	providers := []config.LLMProviderConfig{
+		{Name: "local", Type: ProviderOllama, BaseURL: "http://localhost:11434"},
+		{Name: "claude", Type: ProviderAnthropic, BaseURL: "https://api.anthropic.com"},
+	}
+	cfg := config.Config{LLM: config.LLMConfig{Providers: providers}}
+
+	if e, err := NewEmbedderFromConfig(cfg); err != nil || e.Model() != "hash/v1-256" {
+		t.Errorf("default embedder = %v, %v", e, err)
+	}
+	cfg.EmbeddingModel = "local/nomic-embed-text"
+	if e, err := NewEmbedderFromConfig(cfg); err != nil || e.Model() != "local/nomic-embed-text" {
+		t.Errorf("ollama embedder = %v, %v", e, err)
+	}
+	for _, bad := range []string{"claude/some-model", "missing/model", "no-slash"} {
+		cfg.EmbeddingModel = bad
+		if _, err := NewEmbedderFromConfig(cfg); err == nil {
+			t.Errorf("%s accepted", bad)
+		}
+	}
+}
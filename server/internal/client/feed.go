@@ -0,0 +1,432 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html/charset"
+
+	"folio-server/internal/domain"
+)
+
+// maxFeedBytes caps how much of a feed document is read.
+const maxFeedBytes = 10 << 20 // 10 MB
+
+// FeedClient fetches and parses RSS 2.0, RSS 1.0 (RDF), Atom and JSON Feed
+// documents, honoring ETag / Last-Modified for conditional requests.
+type FeedClient struct {
+	httpClient *http.Client
+}
+
+// FeedFetchResult is the outcome of a conditional feed fetch. When
+// NotModified is true, Feed is nil and the cached validators still apply.
+type FeedFetchResult struct {
+	NotModified  bool
+	ETag         string
+	LastModified string
+	Feed         *ParsedFeed
+}
+
+// ParsedFeed is the normalized form of any supported feed format.
+type ParsedFeed struct {
+	Title   string
+	SiteURL string
+	Entries []domain.FeedEntry
+}
+
+// NewFeedClient returns a client for user-supplied feed URLs. It only
+// connects to public addresses, so a subscription can't reach internal
+// services.
+func NewFeedClient() *FeedClient {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = newPublicDialer(10 * time.Second).DialContext
+	return &FeedClient{
+		httpClient: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: transport,
+		},
+	}
+}
+
+// Fetch downloads feedURL, sending If-None-Match / If-Modified-Since when
+// etag / lastModified are non-empty. Relative entry links are resolved
+// against the final response URL.
+func (c *FeedClient) Fetch(ctx context.Context, feedURL, etag, lastModified string) (*FeedFetchResult, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create feed request: %w", err)
+	}
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/feed+json, application/json, application/xml;q=0.9, text/xml;q=0.8, */*;q=0.5")
+	req.Header.Set("User-Agent", "Folio-FeedFetcher/1.0")
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("feed request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	result := &FeedFetchResult{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		result.NotModified = true
+		if result.ETag == "" {
+			result.ETag = etag
+		}
+		if result.LastModified == "" {
+			result.LastModified = lastModified
+		}
+		return result, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("feed error (status %d)", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedBytes))
+	if err != nil {
+		return nil, fmt.Errorf("read feed body: %w", err)
+	}
+
+	feed, err := ParseFeed(data)
+	if err != nil {
+		return nil, err
+	}
+	resolveFeedLinks(feed, resp.Request.URL)
+	result.Feed = feed
+	return result, nil
+}
+
+// ParseFeed detects the feed format and returns its normalized entries.
+// Entries without a GUID fall back to their link, then to a hash of the title.
+func ParseFeed(data []byte) (*ParsedFeed, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("parse feed: empty document")
+	}
+
+	var feed *ParsedFeed
+	var err error
+	if trimmed[0] == '{' {
+		feed, err = parseJSONFeed(trimmed)
+	} else {
+		feed, err = parseXMLFeed(trimmed)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range feed.Entries {
+		e := &feed.Entries[i]
+		if e.GUID == "" {
+			e.GUID = e.URL
+		}
+		if e.GUID == "" && e.Title != "" {
+			sum := sha1.Sum([]byte(e.Title))
+			e.GUID = "title:" + hex.EncodeToString(sum[:])
+		}
+	}
+	return feed, nil
+}
+
+// --- XML formats ---
+
+type rssDoc struct {
+	Channel rssChannel `xml:"channel"`
+}
+
+type rdfDoc struct {
+	Channel rssChannel `xml:"channel"`
+	Items   []rssItem  `xml:"item"`
+}
+
+type rssChannel struct {
+	Title string    `xml:"title"`
+	Links []string  `xml:"link"`
+	Items []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	About   string   `xml:"about,attr"`
+	GUID    string   `xml:"guid"`
+	Title   string   `xml:"title"`
+	Links   []string `xml:"link"`
+	Author  string   `xml:"author"`
+	Creator string   `xml:"creator"`
+	PubDate string   `xml:"pubDate"`
+	Date    string   `xml:"date"`
+}
+
+type atomDoc struct {
+	Title   string      `xml:"title"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+type atomEntry struct {
+	ID      string     `xml:"id"`
+	Title   string     `xml:"title"`
+	Links   []atomLink `xml:"link"`
+	Authors []struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+	Published string `xml:"published"`
+	Updated   string `xml:"updated"`
+}
+
+func newFeedDecoder(data []byte) *xml.Decoder {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.CharsetReader = charset.NewReaderLabel
+	d.Strict = false
+	return d
+}
+
+func parseXMLFeed(data []byte) (*ParsedFeed, error) {
+	root, err := xmlRootName(data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch root {
+	case "rss":
+		var doc rssDoc
+		if err := newFeedDecoder(data).Decode(&doc); err != nil {
+			return nil, fmt.Errorf("parse rss: %w", err)
+		}
+		return rssToFeed(doc.Channel, doc.Channel.Items), nil
+	case "RDF":
+		var doc rdfDoc
+		if err := newFeedDecoder(data).Decode(&doc); err != nil {
+			return nil, fmt.Errorf("parse rdf: %w", err)
+		}
+		return rssToFeed(doc.Channel, doc.Items), nil
+	case "feed":
+		var doc atomDoc
+		if err := newFeedDecoder(data).Decode(&doc); err != nil {
+			return nil, fmt.Errorf("parse atom: %w", err)
+		}
+		return atomToFeed(doc), nil
+	default:
+		return nil, fmt.Errorf("parse feed: unsupported root element %q", root)
+	}
+}
+
+// xmlRootName returns the local name of the document's first element.
+func xmlRootName(data []byte) (string, error) {
+	d := newFeedDecoder(data)
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return "", fmt.Errorf("parse feed: %w", err)
+		}
+		if se, ok := tok.(xml.StartElement); ok {
+			return se.Name.Local, nil
+		}
+	}
+}
+
+func rssToFeed(ch rssChannel, items []rssItem) *ParsedFeed {
+	feed := &ParsedFeed{
+		Title:   strings.TrimSpace(ch.Title),
+		SiteURL: firstNonEmpty(ch.Links...),
+		Entries: make([]domain.FeedEntry, 0, len(items)),
+	}
+	for _, it := range items {
+		link := firstNonEmpty(it.Links...)
+		if link == "" {
+			link = strings.TrimSpace(it.About)
+		}
+		feed.Entries = append(feed.Entries, domain.FeedEntry{
+			GUID:        strings.TrimSpace(it.GUID),
+			URL:         link,
+			Title:       strings.TrimSpace(it.Title),
+			Author:      firstNonEmpty(it.Creator, it.Author),
+			PublishedAt: parseFeedTime(firstNonEmpty(it.PubDate, it.Date)),
+		})
+	}
+	return feed
+}
+
+func atomToFeed(doc atomDoc) *ParsedFeed {
+	feed := &ParsedFeed{
+		Title:   strings.TrimSpace(doc.Title),
+		SiteURL: atomAlternate(doc.Links),
+		Entries: make([]domain.FeedEntry, 0, len(doc.Entries)),
+	}
+	for _, e := range doc.Entries {
+		var author string
+		if len(e.Authors) > 0 {
+			author = strings.TrimSpace(e.Authors[0].Name)
+		}
+		feed.Entries = append(feed.Entries, domain.FeedEntry{
+			GUID:        strings.TrimSpace(e.ID),
+			URL:         atomAlternate(e.Links),
+			Title:       strings.TrimSpace(e.Title),
+			Author:      author,
+			PublishedAt: parseFeedTime(firstNonEmpty(e.Published, e.Updated)),
+		})
+	}
+	return feed
+}
+
+// atomAlternate picks the rel="alternate" link (or a link with no rel).
+func atomAlternate(links []atomLink) string {
+	for _, l := range links {
+		if l.Rel == "" || l.Rel == "alternate" {
+			if href := strings.TrimSpace(l.Href); href != "" {
+				return href
+			}
+		}
+	}
+	return ""
+}
+
+// --- JSON Feed ---
+
+type jsonFeedDoc struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+type jsonFeedItem struct {
+	ID            json.RawMessage  `json:"id"`
+	URL           string           `json:"url"`
+	ExternalURL   string           `json:"external_url"`
+	Title         string           `json:"title"`
+	DatePublished string           `json:"date_published"`
+	DateModified  string           `json:"date_modified"`
+	Author        *jsonFeedAuthor  `json:"author"`
+	Authors       []jsonFeedAuthor `json:"authors"`
+}
+
+func parseJSONFeed(data []byte) (*ParsedFeed, error) {
+	var doc jsonFeedDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse json feed: %w", err)
+	}
+	if !strings.HasPrefix(doc.Version, "https://jsonfeed.org/version/") {
+		return nil, fmt.Errorf("parse json feed: unsupported version %q", doc.Version)
+	}
+
+	feed := &ParsedFeed{
+		Title:   strings.TrimSpace(doc.Title),
+		SiteURL: strings.TrimSpace(doc.HomePageURL),
+		Entries: make([]domain.FeedEntry, 0, len(doc.Items)),
+	}
+	for _, it := range doc.Items {
+		var author string
+		if len(it.Authors) > 0 {
+			author = it.Authors[0].Name
+		} else if it.Author != nil {
+			author = it.Author.Name
+		}
+		feed.Entries = append(feed.Entries, domain.FeedEntry{
+			GUID:        jsonFeedID(it.ID),
+			URL:         firstNonEmpty(it.URL, it.ExternalURL),
+			Title:       strings.TrimSpace(it.Title),
+			Author:      strings.TrimSpace(author),
+			PublishedAt: parseFeedTime(firstNonEmpty(it.DatePublished, it.DateModified)),
+		})
+	}
+	return feed, nil
+}
+
+// jsonFeedID accepts both string and numeric ids (version 1.0 allowed either).
+func jsonFeedID(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return strings.TrimSpace(s)
+	}
+	return strings.TrimSpace(string(raw))
+}
+
+// --- helpers ---
+
+var feedTimeLayouts = []string{
+	time.RFC3339,
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"Mon, 02 Jan 2006 15:04 -0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// parseFeedTime parses the date formats commonly seen in the wild.
+// Returns nil when the value is empty or unrecognized.
+func parseFeedTime(s string) *time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	for _, layout := range feedTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
+
+// resolveFeedLinks makes relative site and entry links absolute.
+func resolveFeedLinks(feed *ParsedFeed, base *url.URL) {
+	if base == nil {
+		return
+	}
+	resolve := func(ref string) string {
+		if ref == "" {
+			return ""
+		}
+		u, err := base.Parse(ref)
+		if err != nil {
+			return ref
+		}
+		return u.String()
+	}
+	feed.SiteURL = resolve(feed.SiteURL)
+	for i := range feed.Entries {
+		feed.Entries[i].URL = resolve(feed.Entries[i].URL)
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,137 @@
+package client
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestParseFeed_RSS2(t *testing.T) {
+	data := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:atom="http://www.w3.org/2005/Atom">
+<channel>
+  <title>Example Blog</title>
+  <atom:link href="https://example.com/feed.xml" rel="self"/>
+  <link>https://example.com/</link>
+  <item>
+    <title>First post</title>
+    <link>https://example.com/first</link>
+    <guid isPermaLink="false">post-1</guid>
+    <dc:creator>Alice</dc:creator>
+    <pubDate>Tue, 03 Jun 2025 09:39:21 +0000</pubDate>
+  </item>
+  <item>
+    <title>No guid</title>
+    <link>https://example.com/second</link>
+  </item>
+</channel>
+</rss>`)
+
+	feed, err := ParseFeed(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if feed.Title != "Example Blog" || feed.SiteURL != "https://example.com/" {
+		t.Errorf("unexpected channel: %+v", feed)
+	}
+	if len(feed.Entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(feed.Entries))
+	}
+	first := feed.Entries[0]
+	if first.GUID != "post-1" || first.URL != "https://example.com/first" || first.Author != "Alice" {
+		t.Errorf("unexpected first entry: %+v", first)
+	}
+	if first.PublishedAt == nil || first.PublishedAt.Year() != 2025 {
+		t.Errorf("expected pubDate to be parsed, got %v", first.PublishedAt)
+	}
+	if feed.Entries[1].GUID != "https://example.com/second" {
+		t.Errorf("expected guid to fall back to link, got %q", feed.Entries[1].GUID)
+	}
+}
+
+func TestParseFeed_Atom(t *testing.T) {
+	data := []byte(`<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Atom Site</title>
+  <link rel="self" href="https://atom.example/feed"/>
+  <link href="https://atom.example/"/>
+  <entry>
+    <id>tag:atom.example,2025:1</id>
+    <title>Entry One</title>
+    <link rel="alternate" href="/posts/1"/>
+    <author><name>Bob</name></author>
+    <updated>2025-05-01T10:00:00Z</updated>
+  </entry>
+</feed>`)
+
+	feed, err := ParseFeed(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	base, _ := url.Parse("https://atom.example/feed")
+	resolveFeedLinks(feed, base)
+
+	if feed.SiteURL != "https://atom.example/" {
+		t.Errorf("expected alternate site link, got %q", feed.SiteURL)
+	}
+	if len(feed.Entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(feed.Entries))
+	}
+	e := feed.Entries[0]
+	if e.GUID != "tag:atom.example,2025:1" || e.URL != "https://atom.example/posts/1" || e.Author != "Bob" {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+	if e.PublishedAt == nil {
+		t.Error("expected updated to be used as published time")
+	}
+}
+
+func TestParseFeed_RDF(t *testing.T) {
+	data := []byte(`<?xml version="1.0"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/">
+  <channel rdf:about="https://rdf.example/"><title>RDF</title><link>https://rdf.example/</link></channel>
+  <item rdf:about="https://rdf.example/a"><title>A</title><link>https://rdf.example/a</link></item>
+</rdf:RDF>`)
+
+	feed, err := ParseFeed(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(feed.Entries) != 1 || feed.Entries[0].URL != "https://rdf.example/a" {
+		t.Errorf("unexpected entries: %+v", feed.Entries)
+	}
+}
+
+func TestParseFeed_JSONFeed(t *testing.T) {
+	data := []byte(`{
+  "version": "https://jsonfeed.org/version/1.1",
+  "title": "JSON Site",
+  "home_page_url": "https://json.example/",
+  "items": [
+    {"id": 42, "url": "https://json.example/42", "title": "Numeric id", "authors": [{"name": "Carol"}], "date_published": "2025-01-02T03:04:05Z"},
+    {"id": "abc", "external_url": "https://other.example/x", "title": "External"}
+  ]
+}`)
+
+	feed, err := ParseFeed(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if feed.Title != "JSON Site" || len(feed.Entries) != 2 {
+		t.Fatalf("unexpected feed: %+v", feed)
+	}
+	if feed.Entries[0].GUID != "42" || feed.Entries[0].Author != "Carol" {
+		t.Errorf("unexpected first entry: %+v", feed.Entries[0])
+	}
+	if feed.Entries[1].URL != "https://other.example/x" {
+		t.Errorf("expected external_url fallback, got %q", feed.Entries[1].URL)
+	}
+}
+
+func TestParseFeed_RejectsUnknownFormat(t *testing.T) {
+	if _, err := ParseFeed([]byte(`<html><body>not a feed</body></html>`)); err == nil {
+		t.Error("expected error for HTML document")
+	}
+	if _, err := ParseFeed([]byte(`{"foo": "bar"}`)); err == nil {
+		t.Error("expected error for JSON without jsonfeed version")
+	}
+}
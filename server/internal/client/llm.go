@@ -0,0 +1,261 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"folio-server/internal/config"
+)
+
+// LLMTask identifies what an LLM call is for. Each task is routed to its own
+// list of models.
+type LLMTask string
+
+const (
+	TaskAnalyze        LLMTask = "analyze"
+	TaskEchoCards      LLMTask = "echo_cards"
+	TaskRAG            LLMTask = "rag"
+	TaskRerank         LLMTask = "rerank"
+	TaskQueryExpansion LLMTask = "query_expansion"
+	TaskRelated        LLMTask = "related"
+	TaskConvTitle      LLMTask = "conversation_title"
+)
+
+// LLMTasks lists every task, in the order they are documented.
+var LLMTasks = []LLMTask{TaskAnalyze, TaskEchoCards, TaskRAG, TaskRerank, TaskQueryExpansion, TaskRelated, TaskConvTitle}
+
+// ChatRequest is a single-turn chat completion, independent of provider.
+type ChatRequest struct {
+	Model       string
+	System      string
+	User        string
+	Temperature float64
+	MaxTokens   int
+	JSON        bool // ask for a JSON response where the provider supports it
+}
+
+// ChatProvider sends chat completions to one LLM backend and returns the
+// text of the reply.
+type ChatProvider interface {
+	Chat(ctx context.Context, req ChatRequest) (string, error)
+	// ChatStream is Chat, calling onDelta with each piece of the reply as
+	// it is generated. An error from onDelta aborts the call.
+	ChatStream(ctx context.Context, req ChatRequest, onDelta func(string) error) (string, error)
+}
+
+// Provider types accepted by NewChatProvider.
+const (
+	ProviderOpenAI    = "openai"    // OpenAI-compatible: DeepSeek, OpenAI, llama.cpp server, vLLM
+	ProviderAnthropic = "anthropic" // Anthropic Messages API
+	ProviderOllama    = "ollama"    // Ollama native API
+)
+
+// NewChatProvider creates a provider of the given type.
+func NewChatProvider(providerType, baseURL, apiKey string) (ChatProvider, error) {
+	switch providerType {
+	case ProviderOpenAI:
+		return NewOpenAIProvider(baseURL, apiKey), nil
+	case ProviderAnthropic:
+		return NewAnthropicProvider(baseURL, apiKey), nil
+	case ProviderOllama:
+		return NewOllamaProvider(baseURL), nil
+	default:
+		return nil, fmt.Errorf("unknown LLM provider type %q", providerType)
+	}
+}
+
+// ModelTarget is one provider/model pair a task can be sent to.
+type ModelTarget struct {
+	Provider string
+	Model    string
+}
+
+func (t ModelTarget) String() string { return t.Provider + "/" + t.Model }
+
+// ParseModelTarget parses "provider/model". Only the first slash separates
+// the two, so model names may contain slashes and colons
+// (e.g. "ollama/qwen2.5:7b", "local/meta-llama/Llama-3.1-8B").
+func ParseModelTarget(s string) (ModelTarget, error) {
+	provider, model, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok || provider == "" || model == "" {
+		return ModelTarget{}, fmt.Errorf("invalid model %q: want provider/model", s)
+	}
+	return ModelTarget{Provider: provider, Model: model}, nil
+}
+
+// TaskRoute lists the models tried for a task, in order, and the
+// temperature to use. A nil Temperature keeps the task's default.
+type TaskRoute struct {
+	Targets     []ModelTarget
+	Temperature *float64
+}
+
+// LLMRegistry routes each task to its models and falls back to the next
+// model when one fails.
+type LLMRegistry struct {
+	providers    map[string]ChatProvider
+	routes       map[LLMTask]TaskRoute
+	defaultRoute TaskRoute
+}
+
+// NewLLMRegistry creates a registry. Tasks without a route of their own use
+// defaultRoute. Every target must name a registered provider.
+func NewLLMRegistry(providers map[string]ChatProvider, defaultRoute TaskRoute, routes map[LLMTask]TaskRoute) (*LLMRegistry, error) {
+	check := func(task string, route TaskRoute) error {
+		if len(route.Targets) == 0 {
+			return fmt.Errorf("llm task %s: no models configured", task)
+		}
+		for _, t := range route.Targets {
+			if _, ok := providers[t.Provider]; !ok {
+				return fmt.Errorf("llm task %s: unknown provider %q", task, t.Provider)
+			}
+		}
+		return nil
+	}
+	for _, task := range LLMTasks {
+		route, ok := routes[task]
+		if !ok {
+			route = defaultRoute
+		}
+		if err := check(string(task), route); err != nil {
+			return nil, err
+		}
+	}
+	return &LLMRegistry{providers: providers, routes: routes, defaultRoute: defaultRoute}, nil
+}
+
+// NewLLMRegistryFromConfig builds the providers and routes described by cfg.
+func NewLLMRegistryFromConfig(cfg config.LLMConfig) (*LLMRegistry, error) {
+	providers := make(map[string]ChatProvider, len(cfg.Providers))
+	for _, p := range cfg.Providers {
+		provider, err := NewChatProvider(p.Type, p.BaseURL, p.APIKey)
+		if err != nil {
+			return nil, fmt.Errorf("llm provider %s: %w", p.Name, err)
+		}
+		providers[p.Name] = provider
+	}
+
+	defaultTargets, err := parseModelTargets(cfg.Models)
+	if err != nil {
+		return nil, err
+	}
+	defaultRoute := TaskRoute{Targets: defaultTargets}
+
+	routes := make(map[LLMTask]TaskRoute, len(cfg.Tasks))
+	for name, tc := range cfg.Tasks {
+		task := LLMTask(name)
+		if !slices.Contains(LLMTasks, task) {
+			return nil, fmt.Errorf("unknown llm task %q", name)
+		}
+		route := TaskRoute{Targets: defaultTargets, Temperature: tc.Temperature}
+		if len(tc.Models) > 0 {
+			if route.Targets, err = parseModelTargets(tc.Models); err != nil {
+				return nil, err
+			}
+		}
+		routes[task] = route
+	}
+	return NewLLMRegistry(providers, defaultRoute, routes)
+}
+
+func parseModelTargets(specs []string) ([]ModelTarget, error) {
+	targets := make([]ModelTarget, 0, len(specs))
+	for _, s := range specs {
+		t, err := ParseModelTarget(s)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, t)
+	}
+	return targets, nil
+}
+
+func (r *LLMRegistry) route(task LLMTask) TaskRoute {
+	if route, ok := r.routes[task]; ok {
+		return route
+	}
+	return r.defaultRoute
+}
+
+// Complete sends req to each model configured for task until one answers
+// with a reply that decode accepts. req.Model is set per target, and
+// req.Temperature is replaced when the route sets one.
+func (r *LLMRegistry) Complete(ctx context.Context, task LLMTask, req ChatRequest, decode func(reply []byte) error) error {
+	route := r.route(task)
+	if route.Temperature != nil {
+		req.Temperature = *route.Temperature
+	}
+
+	var errs []error
+	for i, target := range route.Targets {
+		req.Model = target.Model
+		reply, err := r.providers[target.Provider].Chat(ctx, req)
+		if err == nil {
+			err = decode([]byte(stripCodeFence(reply)))
+		}
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", target, err))
+		if ctx.Err() != nil {
+			break
+		}
+		if i < len(route.Targets)-1 {
+			slog.Warn("llm call failed, falling back", "task", task, "model", target.String(), "error", err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CompleteStream is Complete for streamed replies: onDelta receives the reply
+// as it is generated, and decode the whole reply at the end. A model is only
+// abandoned for the next one while nothing has reached onDelta, since text
+// already passed on cannot be taken back.
+func (r *LLMRegistry) CompleteStream(ctx context.Context, task LLMTask, req ChatRequest, onDelta func(string) error, decode func(reply []byte) error) error {
+	route := r.route(task)
+	if route.Temperature != nil {
+		req.Temperature = *route.Temperature
+	}
+
+	var errs []error
+	for i, target := range route.Targets {
+		req.Model = target.Model
+		streamed := false
+		reply, err := r.providers[target.Provider].ChatStream(ctx, req, func(delta string) error {
+			streamed = true
+			return onDelta(delta)
+		})
+		if err == nil {
+			err = decode([]byte(stripCodeFence(reply)))
+		}
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", target, err))
+		if streamed || ctx.Err() != nil {
+			break
+		}
+		if i < len(route.Targets)-1 {
+			slog.Warn("llm stream failed, falling back", "task", task, "model", target.String(), "error", err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// stripCodeFence removes a Markdown code fence around a reply. Providers
+// without a JSON mode often wrap JSON in one despite being told not to.
+func stripCodeFence(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") {
+		return s
+	}
+	s = strings.TrimPrefix(s, "```")
+	if nl := strings.IndexByte(s, '\n'); nl >= 0 {
+		s = s[nl+1:] // drop the language tag line
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "```"))
+}
@@ -0,0 +1,414 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// llmHTTPTimeout bounds a single LLM call. Local models can be slow, but a
+// hung backend must not hold up the fallback forever.
+const llmHTTPTimeout = 60 * time.Second
+
+// postJSON sends body to url and decodes a 200 response into out.
+func postJSON(ctx context.Context, httpClient *http.Client, url string, header http.Header, body, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshal request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	for k, v := range header {
+		httpReq.Header[k] = v
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("api error: status %d, body: %s", resp.StatusCode, string(respBody))
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// postStream sends body to url and calls line for each line of a 200
+// response body as it arrives.
+func postStream(ctx context.Context, httpClient *http.Client, url string, header http.Header, body any, line func(string) error) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshal request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	for k, v := range header {
+		httpReq.Header[k] = v
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("api error: status %d, body: %s", resp.StatusCode, string(respBody))
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if err := line(scanner.Text()); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read stream: %w", err)
+	}
+	return nil
+}
+
+// sseData returns the payload of a Server-Sent Events "data:" line.
+func sseData(line string) (string, bool) {
+	data, ok := strings.CutPrefix(line, "data:")
+	return strings.TrimSpace(data), ok
+}
+
+// ---------- OpenAI-compatible ----------
+
+// OpenAIProvider talks to any server implementing POST /chat/completions:
+// DeepSeek, OpenAI, llama.cpp's server, vLLM and others.
+type OpenAIProvider struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewOpenAIProvider creates an OpenAIProvider. baseURL should include any
+// version prefix the server expects (e.g. "https://api.openai.com/v1"),
+// without a trailing slash. apiKey may be empty for local servers.
+func NewOpenAIProvider(baseURL, apiKey string) *OpenAIProvider {
+	return &OpenAIProvider{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: llmHTTPTimeout},
+	}
+}
+
+type chatRequest struct {
+	Model          string        `json:"model"`
+	Messages       []chatMessage `json:"messages"`
+	Temperature    float64       `json:"temperature"`
+	MaxTokens      int           `json:"max_tokens"`
+	ResponseFormat *respFormat   `json:"response_format,omitempty"`
+	Stream         bool          `json:"stream,omitempty"`
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type respFormat struct {
+	Type string `json:"type"`
+}
+
+type chatResponse struct {
+	Choices []chatChoice `json:"choices"`
+	Error   *chatError   `json:"error,omitempty"`
+}
+
+type chatChoice struct {
+	Message chatMessage `json:"message"`
+	Delta   chatMessage `json:"delta"` // set on streamed chunks
+}
+
+type chatError struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+}
+
+func (p *OpenAIProvider) request(req ChatRequest) (chatRequest, http.Header) {
+	body := chatRequest{
+		Model: req.Model,
+		Messages: []chatMessage{
+			{Role: "system", Content: req.System},
+			{Role: "user", Content: req.User},
+		},
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+	}
+	if req.JSON {
+		body.ResponseFormat = &respFormat{Type: "json_object"}
+	}
+	header := http.Header{}
+	if p.apiKey != "" {
+		header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	return body, header
+}
+
+func (p *OpenAIProvider) Chat(ctx context.Context, req ChatRequest) (string, error) {
+	body, header := p.request(req)
+	var resp chatResponse
+	if err := postJSON(ctx, p.httpClient, p.baseURL+"/chat/completions", header, body, &resp); err != nil {
+		return "", err
+	}
+	if resp.Error != nil {
+		return "", fmt.Errorf("api error: %s", resp.Error.Message)
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("no choices returned")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+func (p *OpenAIProvider) ChatStream(ctx context.Context, req ChatRequest, onDelta func(string) error) (string, error) {
+	body, header := p.request(req)
+	body.Stream = true
+
+	var text strings.Builder
+	err := postStream(ctx, p.httpClient, p.baseURL+"/chat/completions", header, body, func(line string) error {
+		data, ok := sseData(line)
+		if !ok || data == "" || data == "[DONE]" {
+			return nil
+		}
+		var chunk chatResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("decode stream chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return fmt.Errorf("api error: %s", chunk.Error.Message)
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			return nil
+		}
+		delta := chunk.Choices[0].Delta.Content
+		text.WriteString(delta)
+		return onDelta(delta)
+	})
+	return text.String(), err
+}
+
+// ---------- Anthropic ----------
+
+const anthropicVersion = "2023-06-01"
+
+// AnthropicProvider talks to the Anthropic Messages API, or a server
+// implementing it.
+type AnthropicProvider struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewAnthropicProvider creates an AnthropicProvider. baseURL is e.g.
+// "https://api.anthropic.com" (no trailing slash).
+func NewAnthropicProvider(baseURL, apiKey string) *AnthropicProvider {
+	return &AnthropicProvider{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: llmHTTPTimeout},
+	}
+}
+
+type anthropicRequest struct {
+	Model       string        `json:"model"`
+	System      string        `json:"system,omitempty"`
+	Messages    []chatMessage `json:"messages"`
+	MaxTokens   int           `json:"max_tokens"`
+	Temperature float64       `json:"temperature"`
+	Stream      bool          `json:"stream,omitempty"`
+}
+
+// anthropicStreamEvent covers the stream events we read: content_block_delta
+// carries text, error carries a failure.
+type anthropicStreamEvent struct {
+	Type  string `json:"type"`
+	Delta struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta"`
+	Error *chatError `json:"error,omitempty"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+}
+
+func (p *AnthropicProvider) request(req ChatRequest) (anthropicRequest, http.Header) {
+	system := req.System
+	if req.JSON {
+		// The Messages API has no JSON mode; the prompts already ask for
+		// JSON, this makes it explicit.
+		system += "\n\nRespond with JSON only."
+	}
+	body := anthropicRequest{
+		Model:       req.Model,
+		System:      system,
+		Messages:    []chatMessage{{Role: "user", Content: req.User}},
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+	}
+	header := http.Header{}
+	header.Set("x-api-key", p.apiKey)
+	header.Set("anthropic-version", anthropicVersion)
+	return body, header
+}
+
+func (p *AnthropicProvider) Chat(ctx context.Context, req ChatRequest) (string, error) {
+	body, header := p.request(req)
+	var resp anthropicResponse
+	if err := postJSON(ctx, p.httpClient, p.baseURL+"/v1/messages", header, body, &resp); err != nil {
+		return "", err
+	}
+	var text strings.Builder
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return "", fmt.Errorf("no text content returned")
+	}
+	return text.String(), nil
+}
+
+func (p *AnthropicProvider) ChatStream(ctx context.Context, req ChatRequest, onDelta func(string) error) (string, error) {
+	body, header := p.request(req)
+	body.Stream = true
+
+	var text strings.Builder
+	err := postStream(ctx, p.httpClient, p.baseURL+"/v1/messages", header, body, func(line string) error {
+		data, ok := sseData(line)
+		if !ok || data == "" {
+			return nil // "event:" lines repeat the type carried in data
+		}
+		var ev anthropicStreamEvent
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			return fmt.Errorf("decode stream event: %w", err)
+		}
+		switch {
+		case ev.Type == "error" && ev.Error != nil:
+			return fmt.Errorf("api error: %s", ev.Error.Message)
+		case ev.Type == "content_block_delta" && ev.Delta.Type == "text_delta" && ev.Delta.Text != "":
+			text.WriteString(ev.Delta.Text)
+			return onDelta(ev.Delta.Text)
+		}
+		return nil
+	})
+	return text.String(), err
+}
+
+// ---------- Ollama ----------
+
+// OllamaProvider talks to a local Ollama server through its native chat API.
+type OllamaProvider struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewOllamaProvider creates an OllamaProvider. baseURL is e.g.
+// "http://localhost:11434" (no trailing slash).
+func NewOllamaProvider(baseURL string) *OllamaProvider {
+	return &OllamaProvider{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: llmHTTPTimeout},
+	}
+}
+
+type ollamaRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+	Stream   bool          `json:"stream"`
+	Format   string        `json:"format,omitempty"`
+	Options  ollamaOptions `json:"options"`
+}
+
+type ollamaOptions struct {
+	Temperature float64 `json:"temperature"`
+	NumPredict  int     `json:"num_predict,omitempty"`
+}
+
+type ollamaResponse struct {
+	Message chatMessage `json:"message"`
+	Error   string      `json:"error,omitempty"`
+}
+
+func (p *OllamaProvider) request(req ChatRequest) ollamaRequest {
+	body := ollamaRequest{
+		Model: req.Model,
+		Messages: []chatMessage{
+			{Role: "system", Content: req.System},
+			{Role: "user", Content: req.User},
+		},
+		Options: ollamaOptions{Temperature: req.Temperature, NumPredict: req.MaxTokens},
+	}
+	if req.JSON {
+		body.Format = "json"
+	}
+	return body
+}
+
+func (p *OllamaProvider) Chat(ctx context.Context, req ChatRequest) (string, error) {
+	body := p.request(req)
+	var resp ollamaResponse
+	if err := postJSON(ctx, p.httpClient, p.baseURL+"/api/chat", nil, body, &resp); err != nil {
+		return "", err
+	}
+	if resp.Error != "" {
+		return "", fmt.Errorf("api error: %s", resp.Error)
+	}
+	return resp.Message.Content, nil
+}
+
+// ChatStream reads Ollama's newline-delimited JSON stream.
+func (p *OllamaProvider) ChatStream(ctx context.Context, req ChatRequest, onDelta func(string) error) (string, error) {
+	body := p.request(req)
+	body.Stream = true
+
+	var text strings.Builder
+	err := postStream(ctx, p.httpClient, p.baseURL+"/api/chat", nil, body, func(line string) error {
+		if strings.TrimSpace(line) == "" {
+			return nil
+		}
+		var chunk ollamaResponse
+		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+			return fmt.Errorf("decode stream chunk: %w", err)
+		}
+		if chunk.Error != "" {
+			return fmt.Errorf("api error: %s", chunk.Error)
+		}
+		if chunk.Message.Content == "" {
+			return nil
+		}
+		text.WriteString(chunk.Message.Content)
+		return onDelta(chunk.Message.Content)
+	})
+	return text.String(), err
+}
@@ -0,0 +1,262 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"folio-server/internal/config"
+)
+
+// stubLLM records the requests it receives and answers each with reply.
+type stubLLM struct {
+	t        *testing.T
+	path     string
+	reply    func(req map[string]any) any
+	status   int
+	requests []map[string]any
+	headers  []http.Header
+}
+
+func newStubLLM(t *testing.T, path string, reply func(req map[string]any) any) (*stubLLM, *httptest.Server) {
+	s := &stubLLM{t: t, path: path, reply: reply, status: http.StatusOK}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != s.path {
+			t.Errorf("request to %s, want %s", r.URL.Path, s.path)
+		}
+		var req map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		s.requests = append(s.requests, req)
+		s.headers = append(s.headers, r.Header.Clone())
+		if s.status != http.StatusOK {
+			http.Error(w, "unavailable", s.status)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(s.reply(req))
+	}))
+	t.Cleanup(srv.Close)
+	return s, srv
+}
+
+func openAIReply(content string) func(map[string]any) any {
+	return func(map[string]any) any {
+		return map[string]any{"choices": []any{map[string]any{"message": map[string]any{"role": "assistant", "content": content}}}}
+	}
+}
+
+func anthropicReply(content string) func(map[string]any) any {
+	return func(map[string]any) any {
+		return map[string]any{"content": []any{map[string]any{"type": "text", "text": content}}}
+	}
+}
+
+func ollamaReply(content string) func(map[string]any) any {
+	return func(map[string]any) any {
+		return map[string]any{"message": map[string]any{"role": "assistant", "content": content}, "done": true}
+	}
+}
+
+var testChatRequest = ChatRequest{Model: "m", System: "sys", User: "hi", Temperature: 0.3, MaxTokens: 100, JSON: true}
+
+func TestOpenAIProvider_Chat(t *testing.T) {
+	stub, srv := newStubLLM(t, "/v1/chat/completions", openAIReply(`{"ok":true}`))
+	reply, err := NewOpenAIProvider(srv.URL+"/v1/", "sk-test").Chat(context.Background(), testChatRequest)
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if reply != `{"ok":true}` {
+		t.Errorf("reply = %q", reply)
+	}
+	req := stub.requests[0]
+	if req["model"] != "m" || req["max_tokens"] != float64(100) || req["temperature"] != 0.3 {
+		t.Errorf("request = %v", req)
+	}
+	if rf, _ := req["response_format"].(map[string]any); rf["type"] != "json_object" {
+		t.Errorf("response_format = %v", req["response_format"])
+	}
+	if got := stub.headers[0].Get("Authorization"); got != "Bearer sk-test" {
+		t.Errorf("Authorization = %q", got)
+	}
+}
+
+func TestAnthropicProvider_Chat(t *testing.T) {
+	stub, srv := newStubLLM(t, "/v1/messages", anthropicReply("```json\n{\"ok\":true}\n```"))
+	reply, err := NewAnthropicProvider(srv.URL, "key").Chat(context.Background(), testChatRequest)
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if stripCodeFence(reply) != `{"ok":true}` {
+		t.Errorf("reply = %q", reply)
+	}
+	req := stub.requests[0]
+	if sys, _ := req["system"].(string); !strings.HasPrefix(sys, "sys") {
+		t.Errorf("system = %v", req["system"])
+	}
+	if msgs, _ := req["messages"].([]any); len(msgs) != 1 {
+		t.Errorf("messages = %v, want only the user turn", req["messages"])
+	}
+	h := stub.headers[0]
+	if h.Get("x-api-key") != "key" || h.Get("anthropic-version") != anthropicVersion {
+		t.Errorf("headers = %v", h)
+	}
+}
+
+func TestOllamaProvider_Chat(t *testing.T) {
+	stub, srv := newStubLLM(t, "/api/chat", ollamaReply(`{"ok":true}`))
+	reply, err := NewOllamaProvider(srv.URL).Chat(context.Background(), testChatRequest)
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if reply != `{"ok":true}` {
+		t.Errorf("reply = %q", reply)
+	}
+	req := stub.requests[0]
+	opts, _ := req["options"].(map[string]any)
+	if req["stream"] != false || req["format"] != "json" || opts["temperature"] != 0.3 || opts["num_predict"] != float64(100) {
+		t.Errorf("request = %v", req)
+	}
+}
+
+func TestLLMRegistry_FallsBackInOrder(t *testing.T) {
+	down, downSrv := newStubLLM(t, "/chat/completions", openAIReply(""))
+	down.status = http.StatusServiceUnavailable
+	garbled, garbledSrv := newStubLLM(t, "/v1/messages", anthropicReply("not json"))
+	local, localSrv := newStubLLM(t, "/api/chat", ollamaReply(`{"keywords":["go","golang"]}`))
+
+	llm, err := NewLLMRegistry(map[string]ChatProvider{
+		"remote": NewOpenAIProvider(downSrv.URL, ""),
+		"claude": NewAnthropicProvider(garbledSrv.URL, "key"),
+		"local":  NewOllamaProvider(localSrv.URL),
+	}, TaskRoute{Targets: []ModelTarget{{"remote", "a"}, {"claude", "b"}, {"local", "c"}}}, nil)
+	if err != nil {
+		t.Fatalf("NewLLMRegistry: %v", err)
+	}
+
+	keywords, err := NewLLMAnalyzer(llm).ExpandQuery(context.Background(), "Go?")
+	if err != nil {
+		t.Fatalf("ExpandQuery: %v", err)
+	}
+	if strings.Join(keywords, ",") != "go,golang" {
+		t.Errorf("keywords = %v", keywords)
+	}
+	if len(down.requests) != 1 || len(garbled.requests) != 1 || len(local.requests) != 1 {
+		t.Errorf("requests = %d, %d, %d; want one each", len(down.requests), len(garbled.requests), len(local.requests))
+	}
+	if local.requests[0]["model"] != "c" {
+		t.Errorf("local model = %v", local.requests[0]["model"])
+	}
+}
+
+func TestLLMRegistry_AllFail(t *testing.T) {
+	down, srv := newStubLLM(t, "/chat/completions", openAIReply(""))
+	down.status = http.StatusInternalServerError
+	llm, _ := NewLLMRegistry(map[string]ChatProvider{"p": NewOpenAIProvider(srv.URL, "")},
+		TaskRoute{Targets: []ModelTarget{{"p", "a"}, {"p", "b"}}}, nil)
+
+	err := llm.Complete(context.Background(), TaskAnalyze, testChatRequest, func([]byte) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "p/a") || !strings.Contains(err.Error(), "p/b") {
+		t.Errorf("err = %v, want both models reported", err)
+	}
+}
+
+func TestLLMRegistry_PerTaskModelAndTemperature(t *testing.T) {
+	stub, srv := newStubLLM(t, "/chat/completions", openAIReply(`{"answer":"a","cited_indices":[1]}`))
+	temp := 0.9
+	llm, err := NewLLMRegistryFromConfig(config.LLMConfig{
+		Providers: []config.LLMProviderConfig{{Name: "ds", Type: ProviderOpenAI, BaseURL: srv.URL}},
+		Models:    []string{"ds/deepseek-chat"},
+		Tasks: map[string]config.LLMTaskConfig{
+			"rag": {Models: []string{"ds/deepseek-reasoner"}, Temperature: &temp},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewLLMRegistryFromConfig: %v", err)
+	}
+	a := NewLLMAnalyzer(llm)
+
+	if _, err := a.GenerateRAGAnswer(context.Background(), "sys", "q"); err != nil {
+		t.Fatalf("GenerateRAGAnswer: %v", err)
+	}
+	if _, err := a.Analyze(context.Background(), AnalyzeRequest{Title: "t", Content: "c"}); err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+
+	rag, analyze := stub.requests[0], stub.requests[1]
+	if rag["model"] != "deepseek-reasoner" || rag["temperature"] != 0.9 {
+		t.Errorf("rag request model=%v temperature=%v", rag["model"], rag["temperature"])
+	}
+	if analyze["model"] != "deepseek-chat" || analyze["temperature"] != 0.3 {
+		t.Errorf("analyze request model=%v temperature=%v", analyze["model"], analyze["temperature"])
+	}
+}
+
+func TestLLMAnalyzer_EchoCardsFallBackToTemplate(t *testing.T) {
+	_, srv := newStubLLM(t, "/api/chat", ollamaReply(`{"nothing":"useful"}`))
+	llm, _ := NewLLMRegistry(map[string]ChatProvider{"local": NewOllamaProvider(srv.URL)},
+		TaskRoute{Targets: []ModelTarget{{"local", "m"}}}, nil)
+
+	pairs, err := NewLLMAnalyzer(llm).GenerateEchoCards(context.Background(), "Title", "Source", []string{"point one"})
+	if err != nil {
+		t.Fatalf("GenerateEchoCards: %v", err)
+	}
+	if len(pairs) != 1 || pairs[0].Answer != "point one" {
+		t.Errorf("pairs = %+v, want the template card", pairs)
+	}
+}
+
+func TestNewLLMRegistryFromConfig_Invalid(t *testing.T) {
+	providers := []config.LLMProviderConfig{{Name: "ds", Type: ProviderOpenAI, BaseURL: "http://x"}}
+	tests := map[string]config.LLMConfig{
+		"unknown provider":  {Providers: providers, Models: []string{"other/m"}},
+		"malformed model":   {Providers: providers, Models: []string{"deepseek-chat"}},
+		"no default models": {Providers: providers},
+		"unknown task":      {Providers: providers, Models: []string{"ds/m"}, Tasks: map[string]config.LLMTaskConfig{"summarise": {}}},
+		"unknown type":      {Providers: []config.LLMProviderConfig{{Name: "ds", Type: "grpc"}}, Models: []string{"ds/m"}},
+	}
+	for name, cfg := range tests {
+		if _, err := NewLLMRegistryFromConfig(cfg); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+func TestParseModelTarget(t *testing.T) {
+	got, err := ParseModelTarget("local/meta-llama/Llama-3.1-8B:q4")
+	if err != nil || got.Provider != "local" || got.Model != "meta-llama/Llama-3.1-8B:q4" {
+		t.Errorf("got %+v, %v", got, err)
+	}
+	if _, err := ParseModelTarget("/m"); err == nil {
+		t.Error("empty provider accepted")
+	}
+}
+
+func TestStripCodeFence(t *testing.T) {
+	for in, want := range map[string]string{
+		"```json\n{}\n```": "{}",
+		"```\n[1]\n```":    "[1]",
+		"  {\"a\":1} ":     `{"a":1}`,
+	} {
+		if got := stripCodeFence(in); got != want {
+			t.Errorf("stripCodeFence(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestLLMAnalyzer_GenerateConversationTitle(t *testing.T) {
+	_, srv := newStubLLM(t, "/chat/completions", openAIReply(`{"title":"「Go 并发模型的取舍」。"}`))
+	llm, _ := NewLLMRegistry(map[string]ChatProvider{"p": NewOpenAIProvider(srv.URL, "")},
+		TaskRoute{Targets: []ModelTarget{{"p", "a"}}}, nil)
+
+	title, err := NewLLMAnalyzer(llm).GenerateConversationTitle(context.Background(), "Go 的并发模型好在哪？", "goroutine 很轻量。")
+	if err != nil {
+		t.Fatalf("GenerateConversationTitle: %v", err)
+	}
+	if title != "Go 并发模型的取舍" {
+		t.Errorf("title = %q", title)
+	}
+}
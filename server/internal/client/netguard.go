@@ -0,0 +1,53 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateAddress is returned when a request for a user-supplied URL
+// would connect to a loopback, private, link-local or unspecified address.
+var ErrPrivateAddress = errors.New("address is not publicly routable")
+
+// IsPrivateHost reports whether hostname is localhost or a literal IP that
+// is not publicly routable. It does no DNS lookup; names that resolve to
+// private addresses are caught when dialing (see newPublicDialer).
+func IsPrivateHost(hostname string) bool {
+	h := strings.ToLower(strings.TrimSuffix(hostname, "."))
+	if h == "localhost" || strings.HasSuffix(h, ".localhost") {
+		return true
+	}
+	addr, err := netip.ParseAddr(strings.Trim(h, "[]"))
+	return err == nil && !isPublicAddr(addr)
+}
+
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() && !addr.IsLoopback() && !addr.IsPrivate() &&
+		!addr.IsLinkLocalUnicast() && !addr.IsLinkLocalMulticast() &&
+		!addr.IsInterfaceLocalMulticast() && !addr.IsUnspecified()
+}
+
+// newPublicDialer returns a dialer that refuses non-public addresses. The
+// check runs on the address actually dialed, after DNS resolution, so it
+// also covers redirects and DNS rebinding.
+func newPublicDialer(timeout time.Duration) *net.Dialer {
+	return &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			ap, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("%w: %s", ErrPrivateAddress, address)
+			}
+			if !isPublicAddr(ap.Addr()) {
+				return fmt.Errorf("%w: %s", ErrPrivateAddress, ap.Addr())
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsPrivateHost(t *testing.T) {
+	for host, want := range map[string]bool{
+		"localhost":        true,
+		"api.localhost":    true,
+		"127.0.0.1":        true,
+		"10.1.2.3":         true,
+		"172.16.0.1":       true,
+		"192.168.1.1":      true,
+		"169.254.169.254":  true,
+		"0.0.0.0":          true,
+		"::1":              true,
+		"[::1]":            true,
+		"fe80::1":          true,
+		"fd00::1":          true,
+		"::ffff:127.0.0.1": true,
+		"8.8.8.8":          false,
+		"example.com":      false,
+		"2606:4700::1111":  false,
+	} {
+		if got := IsPrivateHost(host); got != want {
+			t.Errorf("IsPrivateHost(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestFeedClient_RefusesLoopback(t *testing.T) {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+
+	_, err := NewFeedClient().Fetch(context.Background(), srv.URL+"/feed.xml", "", "")
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("err = %v, want ErrPrivateAddress", err)
+	}
+	if called {
+		t.Error("request reached the loopback server")
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
 type R2Client struct {
@@ -57,6 +58,89 @@ func (c *R2Client) Upload(ctx context.Context, key string, body io.Reader, conte
 	return c.publicURL + "/" + key, nil
 }
 
+// UploadFile streams body to key without buffering it in memory. Unlike
+// Upload it returns no public URL: the object is meant to be fetched through
+// a presigned link.
+func (c *R2Client) UploadFile(ctx context.Context, key string, body io.ReadSeeker, contentType string) error {
+	_, err := c.s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(c.bucketName),
+		Key:         aws.String(key),
+		Body:        body,
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return fmt.Errorf("upload to r2: %w", err)
+	}
+	return nil
+}
+
+// PresignGet returns a download URL for key that is valid for ttl. filename
+// is suggested to the browser via Content-Disposition.
+func (c *R2Client) PresignGet(ctx context.Context, key string, ttl time.Duration, filename string) (string, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(c.bucketName),
+		Key:    aws.String(key),
+	}
+	if filename != "" {
+		input.ResponseContentDisposition = aws.String(fmt.Sprintf(`attachment; filename="%s"`, filename))
+	}
+	req, err := s3.NewPresignClient(c.s3Client).PresignGetObject(ctx, input, s3.WithPresignExpires(ttl))
+	if err != nil {
+		return "", fmt.Errorf("presign r2 object: %w", err)
+	}
+	return req.URL, nil
+}
+
+// Delete removes key. Deleting a missing object is not an error.
+func (c *R2Client) Delete(ctx context.Context, key string) error {
+	_, err := c.s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(c.bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("delete from r2: %w", err)
+	}
+	return nil
+}
+
+// DeletePrefix removes every object whose key starts with prefix and returns
+// how many were deleted.
+func (c *R2Client) DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	deleted := 0
+	paginator := s3.NewListObjectsV2Paginator(c.s3Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(c.bucketName),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return deleted, fmt.Errorf("list r2 prefix: %w", err)
+		}
+		if len(page.Contents) == 0 {
+			continue
+		}
+
+		// A listing page holds at most 1000 keys, the DeleteObjects limit.
+		objects := make([]types.ObjectIdentifier, 0, len(page.Contents))
+		for _, obj := range page.Contents {
+			objects = append(objects, types.ObjectIdentifier{Key: obj.Key})
+		}
+		out, err := c.s3Client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(c.bucketName),
+			Delete: &types.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return deleted, fmt.Errorf("delete r2 prefix: %w", err)
+		}
+		if len(out.Errors) > 0 {
+			e := out.Errors[0]
+			return deleted, fmt.Errorf("delete r2 object %s: %s", aws.ToString(e.Key), aws.ToString(e.Message))
+		}
+		deleted += len(objects)
+	}
+	return deleted, nil
+}
+
 func (c *R2Client) DownloadAndUpload(ctx context.Context, sourceURL, keyPrefix string) (string, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", sourceURL, nil)
 	if err != nil {
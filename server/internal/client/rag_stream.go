@@ -0,0 +1,160 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// ragStreamTimeout bounds a streamed RAG answer. It is longer than the
+// blocking call's because the user sees progress while it runs.
+const ragStreamTimeout = 60 * time.Second
+
+// reAnswerField finds the start of the "answer" string in a RAG reply.
+var reAnswerField = regexp.MustCompile(`"answer"\s*:\s*"`)
+
+// answerStream pulls the text of the "answer" field out of a RAG reply as
+// the JSON arrives, so the answer can be shown before the reply is complete.
+// Chunks may split the JSON anywhere, including inside escapes and UTF-8
+// sequences.
+type answerStream struct {
+	buf   []byte
+	pos   int // next undecoded byte of the answer value; 0 until the field is found
+	found bool
+	done  bool
+}
+
+// Write adds a chunk of the reply and returns any newly decoded answer text.
+func (s *answerStream) Write(chunk string) string {
+	if s.done {
+		return ""
+	}
+	s.buf = append(s.buf, chunk...)
+	if !s.found {
+		loc := reAnswerField.FindIndex(s.buf)
+		if loc == nil {
+			return ""
+		}
+		s.found, s.pos = true, loc[1]
+	}
+
+	var out []byte
+	for s.pos < len(s.buf) {
+		c := s.buf[s.pos]
+		switch {
+		case c == '"':
+			s.done = true
+			return string(out)
+		case c == '\\':
+			text, n := decodeEscape(s.buf[s.pos:])
+			if n == 0 {
+				return string(out) // escape incomplete, wait for more
+			}
+			out = append(out, text...)
+			s.pos += n
+		case c < utf8.RuneSelf:
+			out = append(out, c)
+			s.pos++
+		default:
+			if !utf8.FullRune(s.buf[s.pos:]) {
+				return string(out)
+			}
+			_, size := utf8.DecodeRune(s.buf[s.pos:])
+			out = append(out, s.buf[s.pos:s.pos+size]...)
+			s.pos += size
+		}
+	}
+	return string(out)
+}
+
+// decodeEscape decodes the JSON escape at the start of b, returning the text
+// and the number of bytes consumed, or 0 if b ends before the escape does.
+func decodeEscape(b []byte) (string, int) {
+	if len(b) < 2 {
+		return "", 0
+	}
+	if b[1] != 'u' {
+		var s string
+		if err := json.Unmarshal([]byte{'"', b[0], b[1], '"'}, &s); err != nil {
+			return string(b[1]), 2 // invalid escape: keep the character
+		}
+		return s, 2
+	}
+	if len(b) < 6 {
+		return "", 0
+	}
+	r, err := strconv.ParseUint(string(b[2:6]), 16, 32)
+	if err != nil {
+		return string(utf8.RuneError), 6
+	}
+	if r < 0xD800 || r > 0xDBFF {
+		return string(rune(r)), 6
+	}
+	// High surrogate: the low half follows as a second \u escape.
+	if len(b) < 12 {
+		// Wait for the low half, unless what follows cannot be one.
+		if (len(b) > 6 && b[6] != '\\') || (len(b) > 7 && b[7] != 'u') {
+			return string(utf8.RuneError), 6
+		}
+		return "", 0
+	}
+	var s string
+	if err := json.Unmarshal(append(append([]byte{'"'}, b[:12]...), '"'), &s); err != nil {
+		return string(utf8.RuneError), 6
+	}
+	return s, 12
+}
+
+// StreamRAGAnswer is GenerateRAGAnswer with the answer text streamed to
+// onToken as it is generated. Fallback to another model only happens before
+// the first token.
+func (a *LLMAnalyzer) StreamRAGAnswer(ctx context.Context, systemPrompt, userPrompt string, onToken func(string) error) (*RAGResult, error) {
+	chatReq := ChatRequest{
+		System:      systemPrompt,
+		User:        userPrompt,
+		Temperature: 0.3,
+		MaxTokens:   2048,
+		JSON:        true,
+	}
+
+	ragCtx, cancel := context.WithTimeout(ctx, ragStreamTimeout)
+	defer cancel()
+
+	var result RAGResult
+	stream := &answerStream{}
+	streamedAny := false
+	err := a.llm.CompleteStream(ragCtx, TaskRAG, chatReq, func(delta string) error {
+		if text := stream.Write(delta); text != "" {
+			streamedAny = true
+			return onToken(text)
+		}
+		return nil
+	}, func(reply []byte) error {
+		result = RAGResult{}
+		if err := json.Unmarshal(reply, &result); err != nil {
+			return fmt.Errorf("decode rag json: %w (raw: %s)", err, string(reply))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("rag stream: %w", err)
+	}
+	if !streamedAny && result.Answer != "" {
+		// The answer was not where we looked for it; send it whole.
+		if err := onToken(result.Answer); err != nil {
+			return nil, err
+		}
+	}
+
+	if result.CitedIndices == nil {
+		result.CitedIndices = []int{}
+	}
+	if result.FollowupSuggestions == nil {
+		result.FollowupSuggestions = []string{}
+	}
+	return &result, nil
+}
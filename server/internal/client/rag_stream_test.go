@@ -0,0 +1,139 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAnswerStream_SplitAnywhere(t *testing.T) {
+	answer := "Go 的并发¹ uses \"goroutines\"\n\\ and 🚀²."
+	encoded, _ := json.Marshal(answer)
+	reply := `{"answer": ` + string(encoded) + `, "cited_indices": [1, 2]}`
+	// Also exercise \u escapes, including a surrogate pair.
+	escaped := `{"answer":"café 🚀 end","cited_indices":[]}`
+
+	for _, tc := range []struct{ reply, want string }{{reply, answer}, {escaped, "café 🚀 end"}} {
+		for size := 1; size <= 7; size++ {
+			var s answerStream
+			var got strings.Builder
+			for i := 0; i < len(tc.reply); i += size {
+				got.WriteString(s.Write(tc.reply[i:min(i+size, len(tc.reply))]))
+			}
+			if got.String() != tc.want {
+				t.Errorf("chunk size %d: got %q, want %q", size, got.String(), tc.want)
+			}
+		}
+	}
+}
+
+// streamServer answers every request with the given lines.
+func streamServer(t *testing.T, lines ...string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req["stream"] != true {
+			t.Errorf("stream = %v, want true", req["stream"])
+		}
+		for _, l := range lines {
+			fmt.Fprintln(w, l)
+			w.(http.Flusher).Flush()
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestProviders_ChatStream(t *testing.T) {
+	openAI := streamServer(t,
+		`data: {"choices":[{"delta":{"role":"assistant"}}]}`, ``,
+		`data: {"choices":[{"delta":{"content":"{\"answer\":\"Hel"}}]}`, ``,
+		`data: {"choices":[{"delta":{"content":"lo\"}"}}]}`, ``,
+		`data: [DONE]`)
+	anthropic := streamServer(t,
+		`event: message_start`, `data: {"type":"message_start"}`, ``,
+		`event: content_block_delta`, `data: {"type":"content_block_delta","delta":{"type":"text_delta","text":"{\"answer\":\"Hel"}}`, ``,
+		`event: content_block_delta`, `data: {"type":"content_block_delta","delta":{"type":"text_delta","text":"lo\"}"}}`, ``,
+		`event: message_stop`, `data: {"type":"message_stop"}`)
+	ollama := streamServer(t,
+		`{"message":{"role":"assistant","content":"{\"answer\":\"Hel"},"done":false}`,
+		`{"message":{"role":"assistant","content":"lo\"}"},"done":false}`,
+		`{"message":{"role":"assistant","content":""},"done":true}`)
+
+	providers := map[string]ChatProvider{
+		"openai":    NewOpenAIProvider(openAI.URL, "k"),
+		"anthropic": NewAnthropicProvider(anthropic.URL, "k"),
+		"ollama":    NewOllamaProvider(ollama.URL),
+	}
+	for name, p := range providers {
+		var deltas []string
+		reply, err := p.ChatStream(context.Background(), testChatRequest, func(d string) error {
+			deltas = append(deltas, d)
+			return nil
+		})
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if reply != `{"answer":"Hello"}` || len(deltas) != 2 {
+			t.Errorf("%s: reply %q from deltas %q", name, reply, deltas)
+		}
+	}
+}
+
+func TestLLMAnalyzer_StreamRAGAnswer(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(down.Close)
+	up := streamServer(t,
+		`data: {"choices":[{"delta":{"content":"{\"answer\":\"A¹"}}]}`,
+		`data: {"choices":[{"delta":{"content":" B\",\"cited_indices\":[1],"}}]}`,
+		`data: {"choices":[{"delta":{"content":"\"followup_suggestions\":[\"more?\"]}"}}]}`,
+		`data: [DONE]`)
+
+	llm, _ := NewLLMRegistry(map[string]ChatProvider{
+		"down": NewOpenAIProvider(down.URL, ""),
+		"up":   NewOpenAIProvider(up.URL, ""),
+	}, TaskRoute{Targets: []ModelTarget{{"down", "m"}, {"up", "m"}}}, nil)
+
+	var tokens []string
+	result, err := NewLLMAnalyzer(llm).StreamRAGAnswer(context.Background(), "sys", "q", func(s string) error {
+		tokens = append(tokens, s)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamRAGAnswer: %v", err)
+	}
+	if strings.Join(tokens, "") != "A¹ B" || result.Answer != "A¹ B" {
+		t.Errorf("tokens %q, answer %q", tokens, result.Answer)
+	}
+	if len(result.CitedIndices) != 1 || len(result.FollowupSuggestions) != 1 {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestLLMRegistry_StreamNoFallbackAfterOutput(t *testing.T) {
+	broken := streamServer(t, `data: {"choices":[{"delta":{"content":"{\"answer\":\"partial"}}]}`, `data: not json`)
+	backup := streamServer(t, `data: {"choices":[{"delta":{"content":"{}"}}]}`)
+	llm, _ := NewLLMRegistry(map[string]ChatProvider{
+		"broken": NewOpenAIProvider(broken.URL, ""),
+		"backup": NewOpenAIProvider(backup.URL, ""),
+	}, TaskRoute{Targets: []ModelTarget{{"broken", "m"}, {"backup", "m"}}}, nil)
+
+	var out strings.Builder
+	err := llm.CompleteStream(context.Background(), TaskRAG, testChatRequest, func(d string) error {
+		out.WriteString(d)
+		return nil
+	}, func([]byte) error { return nil })
+	if err == nil || strings.Contains(err.Error(), "backup") {
+		t.Errorf("err = %v, want the broken model's error only", err)
+	}
+	if out.String() != `{"answer":"partial` {
+		t.Errorf("streamed %q", out.String())
+	}
+}
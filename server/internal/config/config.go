@@ -13,6 +13,10 @@ type Config struct {
 	ReaderURL    string
 	DeepSeekAPIKey  string
 	DeepSeekBaseURL string
+	LLM          LLMConfig
+	// EmbeddingModel is "provider/model" on an LLM provider, or "hash" (the
+	// default) for the offline hash embedder.
+	EmbeddingModel string
 	JWTSecret    string
 	R2Endpoint   string
 	R2AccessKey  string
@@ -22,7 +26,12 @@ type Config struct {
 	JinaAPIKey     string
 	AppleBundleID  string
 	ResendAPIKey   string
-	AppMode        string // "api" | "worker" | "all" (default "all")
+	AppMode        string // "api" | "worker" | "mail" | "all" (default "all")
+
+	// Inbound mail (newsletter ingest addresses: <token>@MailDomain)
+	MailDomain        string
+	MailSMTPAddr      string
+	MailWebhookSecret string
 
 	// Apple Server API (App Store Server API for subscription verification)
 	AppleAPIKeyID    string
@@ -65,14 +74,26 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("JWT_SECRET must be at least 32 characters")
 	}
 
+	llm, err := loadLLMConfig(cfg.DeepSeekAPIKey, cfg.DeepSeekBaseURL)
+	if err != nil {
+		return nil, err
+	}
+	cfg.LLM = llm
+	cfg.EmbeddingModel = os.Getenv("EMBEDDING_MODEL")
+
 	cfg.AppMode = os.Getenv("APP_MODE")
 	if cfg.AppMode == "" {
 		cfg.AppMode = "all"
 	}
-	if cfg.AppMode != "api" && cfg.AppMode != "worker" && cfg.AppMode != "all" {
-		return nil, fmt.Errorf("invalid APP_MODE %q: must be api, worker, or all", cfg.AppMode)
+	if cfg.AppMode != "api" && cfg.AppMode != "worker" && cfg.AppMode != "mail" && cfg.AppMode != "all" {
+		return nil, fmt.Errorf("invalid APP_MODE %q: must be api, worker, mail, or all", cfg.AppMode)
 	}
 
+	// Inbound mail
+	cfg.MailDomain = strings.ToLower(envOrDefault("MAIL_DOMAIN", "in.echolore.ai"))
+	cfg.MailSMTPAddr = envOrDefault("MAIL_SMTP_ADDR", ":2525")
+	cfg.MailWebhookSecret = os.Getenv("MAIL_WEBHOOK_SECRET")
+
 	// Apple Server API
 	cfg.AppleAPIKeyID = os.Getenv("APPLE_API_KEY_ID")
 	cfg.AppleAPIIssuerID = os.Getenv("APPLE_API_ISSUER_ID")
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// LLMConfig describes the LLM providers and which models serve each task.
+//
+//	LLM_PROVIDERS=deepseek,local
+//	LLM_PROVIDER_DEEPSEEK_TYPE=openai          # openai | anthropic | ollama
+//	LLM_PROVIDER_DEEPSEEK_BASE_URL=https://api.deepseek.com
+//	LLM_PROVIDER_DEEPSEEK_API_KEY=sk-...
+//	LLM_PROVIDER_LOCAL_TYPE=ollama
+//	LLM_MODELS=deepseek/deepseek-chat,local/qwen2.5:7b    # default, in fallback order
+//	LLM_TASK_RAG_MODELS=deepseek/deepseek-reasoner,deepseek/deepseek-chat
+//	LLM_TASK_RAG_TEMPERATURE=0.2
+//
+// Without LLM_PROVIDERS, a DEEPSEEK_API_KEY configures a single "deepseek"
+// provider serving deepseek-chat, as before. With neither, LLM is disabled
+// and the mock analyzer is used.
+type LLMConfig struct {
+	Providers []LLMProviderConfig
+	Models    []string                 // default "provider/model" targets
+	Tasks     map[string]LLMTaskConfig // keyed by lowercase task name
+}
+
+// LLMProviderConfig is one named LLM backend.
+type LLMProviderConfig struct {
+	Name    string
+	Type    string
+	BaseURL string
+	APIKey  string
+}
+
+// LLMTaskConfig overrides the models or temperature for one task.
+type LLMTaskConfig struct {
+	Models      []string
+	Temperature *float64
+}
+
+// Enabled reports whether any LLM provider is configured.
+func (c LLMConfig) Enabled() bool { return len(c.Providers) > 0 }
+
+// defaultLLMBaseURLs are used when a provider has no BASE_URL.
+var defaultLLMBaseURLs = map[string]string{
+	"openai":    "https://api.openai.com/v1",
+	"anthropic": "https://api.anthropic.com",
+	"ollama":    "http://localhost:11434",
+}
+
+func loadLLMConfig(deepSeekAPIKey, deepSeekBaseURL string) (LLMConfig, error) {
+	cfg := LLMConfig{Tasks: map[string]LLMTaskConfig{}}
+
+	names := splitList(os.Getenv("LLM_PROVIDERS"))
+	for _, name := range names {
+		prefix := "LLM_PROVIDER_" + envName(name) + "_"
+		typ := strings.ToLower(os.Getenv(prefix + "TYPE"))
+		defaultURL, ok := defaultLLMBaseURLs[typ]
+		if !ok {
+			return LLMConfig{}, fmt.Errorf("%sTYPE must be openai, anthropic or ollama, got %q", prefix, typ)
+		}
+		cfg.Providers = append(cfg.Providers, LLMProviderConfig{
+			Name:    name,
+			Type:    typ,
+			BaseURL: envOrDefault(prefix+"BASE_URL", defaultURL),
+			APIKey:  os.Getenv(prefix + "API_KEY"),
+		})
+	}
+	cfg.Models = splitList(os.Getenv("LLM_MODELS"))
+
+	if len(names) == 0 && deepSeekAPIKey != "" {
+		cfg.Providers = []LLMProviderConfig{{Name: "deepseek", Type: "openai", BaseURL: deepSeekBaseURL, APIKey: deepSeekAPIKey}}
+		if len(cfg.Models) == 0 {
+			cfg.Models = []string{"deepseek/deepseek-chat"}
+		}
+	}
+	if !cfg.Enabled() {
+		return cfg, nil
+	}
+
+	for _, kv := range os.Environ() {
+		key, value, _ := strings.Cut(kv, "=")
+		rest, ok := strings.CutPrefix(key, "LLM_TASK_")
+		if !ok {
+			continue
+		}
+		if task, ok := strings.CutSuffix(rest, "_MODELS"); ok {
+			tc := cfg.Tasks[strings.ToLower(task)]
+			tc.Models = splitList(value)
+			cfg.Tasks[strings.ToLower(task)] = tc
+		} else if task, ok := strings.CutSuffix(rest, "_TEMPERATURE"); ok {
+			t, err := strconv.ParseFloat(value, 64)
+			if err != nil || t < 0 || t > 2 {
+				return LLMConfig{}, fmt.Errorf("%s must be a number between 0 and 2", key)
+			}
+			tc := cfg.Tasks[strings.ToLower(task)]
+			tc.Temperature = &t
+			cfg.Tasks[strings.ToLower(task)] = tc
+		}
+	}
+	return cfg, nil
+}
+
+// envName turns a provider name into its env var infix ("my-llm" → "MY_LLM").
+func envName(name string) string {
+	return strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// splitList splits a comma-separated env value, dropping empty entries.
+func splitList(s string) []string {
+	out := make([]string, 0)
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
@@ -24,6 +24,7 @@ const (
 	SourceManual     SourceType = "manual"
 	SourceScreenshot SourceType = "screenshot"
 	SourceVoice      SourceType = "voice"
+	SourcePDF        SourceType = "pdf"
 )
 
 type Article struct {
@@ -35,6 +36,7 @@ type Article struct {
 	SiteName        *string       `json:"site_name,omitempty"`
 	FaviconURL      *string       `json:"favicon_url,omitempty"`
 	CoverImageURL   *string       `json:"cover_image_url,omitempty"`
+	FileURL         *string       `json:"file_url,omitempty"` // original upload (PDF)
 	MarkdownContent *string       `json:"markdown_content,omitempty"`
 	RawHTML         *string       `json:"raw_html,omitempty"`
 	WordCount       int           `json:"word_count"`
@@ -61,4 +63,5 @@ type Article struct {
 	// Joined fields (not stored directly)
 	Category *Category `json:"category,omitempty"`
 	Tags     []Tag     `json:"tags,omitempty"`
+	Snippet  *string   `json:"snippet,omitempty"` // search hit context, <mark>-highlighted
 }
@@ -0,0 +1,54 @@
+package domain
+
+import "time"
+
+// ReadState is how far the user has read an article.
+type ReadState string
+
+const (
+	ReadStateUnread  ReadState = "unread"  // not opened: read_progress is 0
+	ReadStateReading ReadState = "reading" // started but not finished
+	ReadStateRead    ReadState = "read"    // read_progress reached ReadThreshold
+)
+
+// ReadThreshold is the read_progress at which an article counts as read.
+// Clients rarely report exactly 1 for the last screen of an article.
+const ReadThreshold = 0.9
+
+// Collection is a saved search: a named ArticleFilter evaluated live each
+// time it is opened, so its articles and count follow the library.
+type Collection struct {
+	ID        string
+	UserID    string
+	Name      string
+	Filter    ArticleFilter
+	Position  int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	ArticleCount int // computed when collections are listed
+}
+
+// ArticleFilter is the persisted filter of a Collection, stored as JSON.
+// Unset fields don't restrict; set fields must all match. Query uses the
+// search syntax (see SearchQuery) and ranks results by relevance; without
+// it, results are listed newest first.
+type ArticleFilter struct {
+	Query        string         `json:"query,omitempty"`
+	Category     *string        `json:"category,omitempty"` // slug
+	Status       *ArticleStatus `json:"status,omitempty"`
+	Favorite     *bool          `json:"favorite,omitempty"`
+	Archived     *bool          `json:"archived,omitempty"`
+	TagIDs       []string       `json:"tag_ids,omitempty"`      // any of
+	SourceTypes  []SourceType   `json:"source_types,omitempty"` // any of
+	ReadState    *ReadState     `json:"read_state,omitempty"`
+	MinWords     *int           `json:"min_words,omitempty"`
+	MaxWords     *int           `json:"max_words,omitempty"`
+	UpdatedSince *time.Time     `json:"updated_since,omitempty"`
+
+	// Saved dates are absolute; CreatedWithinDays keeps a window relative
+	// to when the collection is evaluated ("this month" ≈ 30).
+	CreatedAfter      *time.Time `json:"created_after,omitempty"`  // inclusive
+	CreatedBefore     *time.Time `json:"created_before,omitempty"` // exclusive
+	CreatedWithinDays *int       `json:"created_within_days,omitempty"`
+}
@@ -1,6 +1,10 @@
 package domain
 
-import "time"
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
 
 type EchoCardType string
 
@@ -8,8 +12,12 @@ const (
 	EchoCardInsight   EchoCardType = "insight"
 	EchoCardHighlight EchoCardType = "highlight"
 	EchoCardRelated   EchoCardType = "related"
+	EchoCardManual    EchoCardType = "manual" // written by the user
+	EchoCardCloze     EchoCardType = "cloze"  // a highlight with a key term blanked out
 )
 
+// EchoReviewResult is the binary outcome of a review: whether the card was
+// recalled. Clients that grade reviews send an EchoRating instead.
 type EchoReviewResult string
 
 const (
@@ -17,6 +25,62 @@ const (
 	EchoForgot     EchoReviewResult = "forgot"
 )
 
+// EchoRating is the four-point grade of a review used for scheduling.
+type EchoRating int
+
+const (
+	EchoAgain EchoRating = 1
+	EchoHard  EchoRating = 2
+	EchoGood  EchoRating = 3
+	EchoEasy  EchoRating = 4
+)
+
+var echoRatingNames = map[string]EchoRating{
+	"again": EchoAgain,
+	"hard":  EchoHard,
+	"good":  EchoGood,
+	"easy":  EchoEasy,
+}
+
+// ParseEchoRating parses "again", "hard", "good" or "easy".
+func ParseEchoRating(s string) (EchoRating, bool) {
+	r, ok := echoRatingNames[s]
+	return r, ok
+}
+
+// Result is the binary outcome the rating stands for.
+func (r EchoRating) Result() EchoReviewResult {
+	if r == EchoAgain {
+		return EchoForgot
+	}
+	return EchoRemembered
+}
+
+// Response times that grade a binary "remembered" review. Migration
+// 023_echo_fsrs grades past reviews with the same thresholds.
+const (
+	echoEasyResponseMs = 4000
+	echoHardResponseMs = 15000
+)
+
+// RatingFromResult grades a binary review: "forgot" is Again, and
+// "remembered" is Good, or Easy or Hard when the response time shows the
+// answer came at once or only after a long pause.
+func RatingFromResult(result EchoReviewResult, responseTimeMs *int) EchoRating {
+	if result == EchoForgot {
+		return EchoAgain
+	}
+	switch {
+	case responseTimeMs == nil:
+		return EchoGood
+	case *responseTimeMs <= echoEasyResponseMs:
+		return EchoEasy
+	case *responseTimeMs >= echoHardResponseMs:
+		return EchoHard
+	}
+	return EchoGood
+}
+
 type EchoCard struct {
 	ID               string
 	UserID           string
@@ -27,22 +91,59 @@ type EchoCard struct {
 	SourceContext    *string
 	NextReviewAt     time.Time
 	IntervalDays     int
-	EaseFactor       float64
+	Stability        float64 // FSRS memory state; zero until the first review
+	Difficulty       float64
+	LastReviewedAt   *time.Time
 	ReviewCount      int
 	CorrectCount     int
 	RelatedArticleID *string
 	HighlightID      *string
+	ClozeStart       *int // cloze cards: rune offsets of the blanked Answer in Question
+	ClozeEnd         *int
+	SiblingGroup     *string    // cards from one note, e.g. one highlight's clozes; one is due per day
+	SuspendedAt      *time.Time // suspended cards are never due
+	BuriedUntil      *time.Time // buried cards are not due before this time
 	CreatedAt        time.Time
 	UpdatedAt        time.Time
 	// Joined fields (not stored directly)
 	ArticleTitle string
 }
 
+// echoClozeBlank stands in for the blanked term of a cloze card.
+const echoClozeBlank = "［＿＿＿］"
+
+// ClozeQuestion is the passage of a cloze card with its term blanked out.
+// For other cards, and for cloze cards whose offsets no longer fit the
+// passage, it is Question.
+func (c *EchoCard) ClozeQuestion() string {
+	if c.CardType != EchoCardCloze || c.ClozeStart == nil || c.ClozeEnd == nil {
+		return c.Question
+	}
+	r := []rune(c.Question)
+	start, end := *c.ClozeStart, *c.ClozeEnd
+	if start < 0 || end <= start || end > len(r) {
+		return c.Question
+	}
+	return string(r[:start]) + echoClozeBlank + string(r[end:])
+}
+
+// ClozeOffsets returns the rune offsets of the first occurrence of term in
+// passage, or false if it does not occur.
+func ClozeOffsets(passage, term string) (start, end int, ok bool) {
+	i := strings.Index(passage, term)
+	if term == "" || i < 0 {
+		return 0, 0, false
+	}
+	start = utf8.RuneCountInString(passage[:i])
+	return start, start + utf8.RuneCountInString(term), true
+}
+
 type EchoReview struct {
 	ID             string
 	CardID         string
 	UserID         string
 	Result         EchoReviewResult
+	Rating         EchoRating
 	ResponseTimeMs *int
 	ReviewedAt     time.Time
 }
@@ -52,3 +153,13 @@ type EchoStreak struct {
 	ConsecutiveDays int
 	Display         string // "本周回忆率 85% · 已连续 7 天"
 }
+
+// EchoSchedulerParams are a user's FSRS weights, fitted to their review
+// history.
+type EchoSchedulerParams struct {
+	UserID      string
+	Weights     []float64
+	ReviewCount int     // reviews the weights were fitted to
+	LogLoss     float64 // on those reviews
+	OptimizedAt time.Time
+}
@@ -0,0 +1,62 @@
+package domain
+
+import "testing"
+
+func TestRatingFromResult(t *testing.T) {
+	ms := func(n int) *int { return &n }
+	tests := []struct {
+		result EchoReviewResult
+		ms     *int
+		want   EchoRating
+	}{
+		{EchoForgot, ms(1000), EchoAgain},
+		{EchoRemembered, nil, EchoGood},
+		{EchoRemembered, ms(4000), EchoEasy},
+		{EchoRemembered, ms(8000), EchoGood},
+		{EchoRemembered, ms(15000), EchoHard},
+	}
+	for _, tt := range tests {
+		if got := RatingFromResult(tt.result, tt.ms); got != tt.want {
+			t.Errorf("RatingFromResult(%q, %v) = %d, want %d", tt.result, tt.ms, got, tt.want)
+		}
+	}
+}
+
+func TestParseEchoRating(t *testing.T) {
+	for name, want := range map[string]EchoRating{"again": EchoAgain, "hard": EchoHard, "good": EchoGood, "easy": EchoEasy} {
+		if got, ok := ParseEchoRating(name); !ok || got != want {
+			t.Errorf("ParseEchoRating(%q) = %d, %v", name, got, ok)
+		}
+	}
+	if _, ok := ParseEchoRating("remembered"); ok {
+		t.Error("ParseEchoRating accepted a result")
+	}
+	if EchoAgain.Result() != EchoForgot || EchoHard.Result() != EchoRemembered {
+		t.Error("Result() does not map Again to forgot and the rest to remembered")
+	}
+}
+
+func TestClozeQuestion(t *testing.T) {
+	passage := "间隔重复利用遗忘曲线安排复习"
+	start, end, ok := ClozeOffsets(passage, "遗忘曲线")
+	if !ok || start != 6 || end != 10 {
+		t.Fatalf("ClozeOffsets = %d, %d, %v; want rune offsets 6, 10", start, end, ok)
+	}
+	card := EchoCard{CardType: EchoCardCloze, Question: passage, Answer: "遗忘曲线", ClozeStart: &start, ClozeEnd: &end}
+	if got, want := card.ClozeQuestion(), "间隔重复利用"+echoClozeBlank+"安排复习"; got != want {
+		t.Errorf("ClozeQuestion() = %q, want %q", got, want)
+	}
+
+	stale := 99
+	card.ClozeEnd = &stale
+	if got := card.ClozeQuestion(); got != passage {
+		t.Errorf("ClozeQuestion() with stale offsets = %q, want the passage", got)
+	}
+	if _, _, ok := ClozeOffsets(passage, "艾宾浩斯"); ok {
+		t.Error("ClozeOffsets found a term not in the passage")
+	}
+	insight := EchoCard{CardType: EchoCardInsight, Question: "还记得吗？"}
+	if insight.ClozeQuestion() != insight.Question {
+		t.Error("ClozeQuestion() changed a non-cloze card")
+	}
+}
@@ -0,0 +1,43 @@
+package domain
+
+import "time"
+
+type ExportStatus string
+
+const (
+	ExportStatusPending   ExportStatus = "pending"
+	ExportStatusRunning   ExportStatus = "running"
+	ExportStatusCompleted ExportStatus = "completed"
+	ExportStatusFailed    ExportStatus = "failed"
+	ExportStatusExpired   ExportStatus = "expired" // archive deleted from storage
+)
+
+type ExportFormat string
+
+const (
+	ExportFormatMarkdown ExportFormat = "markdown" // Obsidian-compatible vault
+	ExportFormatJSON     ExportFormat = "json"     // all personal data, machine-readable
+)
+
+// ExportJob builds a zip of the user's whole library, either as Markdown
+// notes or as a JSON bundle. The finished archive lives in object storage
+// until ExpiresAt.
+//
+// A Markdown export may instead cover one collection: Filter is a copy of
+// the collection's filter taken when the export was created.
+type ExportJob struct {
+	ID           string
+	UserID       string
+	Status       ExportStatus
+	Format       ExportFormat
+	CollectionID *string
+	Filter       *ArticleFilter
+	ArticleCount int
+	ObjectKey    *string
+	SizeBytes    int64
+	Error        *string
+	ExpiresAt    *time.Time
+	FinishedAt   *time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
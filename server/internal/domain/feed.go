@@ -0,0 +1,30 @@
+package domain
+
+import "time"
+
+// Feed is an RSS, Atom or JSON Feed subscription. New entries are ingested
+// as articles with SourceNewsletter.
+type Feed struct {
+	ID           string
+	UserID       string
+	URL          string
+	Title        *string
+	SiteURL      *string
+	ETag         *string
+	LastModified *string
+	LastPolledAt *time.Time
+	LastError    *string
+	ErrorCount   int
+	IsActive     bool
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// FeedEntry is a single item parsed from a feed document.
+type FeedEntry struct {
+	GUID        string
+	URL         string
+	Title       string
+	Author      string
+	PublishedAt *time.Time
+}
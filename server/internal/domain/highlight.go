@@ -9,6 +9,7 @@ type Highlight struct {
 	Text        string
 	StartOffset int
 	EndOffset   int
+	Page        *int // 1-based page for highlights on PDF documents
 	Color       string
 	Note        *string
 	CreatedAt   time.Time
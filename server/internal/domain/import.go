@@ -0,0 +1,60 @@
+package domain
+
+import "time"
+
+type ImportStatus string
+
+const (
+	ImportStatusPending   ImportStatus = "pending"
+	ImportStatusRunning   ImportStatus = "running"
+	ImportStatusCompleted ImportStatus = "completed"
+	ImportStatusFailed    ImportStatus = "failed"
+)
+
+type ImportItemStatus string
+
+const (
+	ImportItemPending  ImportItemStatus = "pending"
+	ImportItemImported ImportItemStatus = "imported"
+	ImportItemSkipped  ImportItemStatus = "skipped" // URL already saved
+	ImportItemFailed   ImportItemStatus = "failed"
+)
+
+// ImportJob is a bulk import of links from another read-later service or a
+// bookmarks file. Items are turned into articles in batches by the
+// import:process worker.
+type ImportJob struct {
+	ID            string
+	UserID        string
+	Source        string
+	Status        ImportStatus
+	TotalItems    int
+	ImportedItems int
+	SkippedItems  int
+	FailedItems   int
+	Error         *string
+	FinishedAt    *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// ProcessedItems is how many items have been handled so far.
+func (j *ImportJob) ProcessedItems() int {
+	return j.ImportedItems + j.SkippedItems + j.FailedItems
+}
+
+// ImportItem is one link waiting to be imported.
+type ImportItem struct {
+	JobID      string
+	Position   int
+	URL        string
+	SourceType SourceType
+	Title      *string
+	Tags       []string
+	SavedAt    *time.Time
+	IsFavorite bool
+	IsArchived bool
+	Status     ImportItemStatus
+	ArticleID  *string
+	Error      *string
+}
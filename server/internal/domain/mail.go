@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+// MailInbox holds a user's private ingest address token. The full address
+// is token@MAIL_DOMAIN.
+type MailInbox struct {
+	UserID    string
+	Token     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// MailSender is an allow-list entry: a full address or "@domain".
+type MailSender struct {
+	ID        string
+	UserID    string
+	Pattern   string
+	CreatedAt time.Time
+}
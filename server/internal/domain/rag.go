@@ -1,6 +1,9 @@
 package domain
 
-import "time"
+import (
+	"sort"
+	"time"
+)
 
 type RAGSource struct {
 	ArticleID string
@@ -10,6 +13,39 @@ type RAGSource struct {
 	KeyPoints []string
 	CreatedAt time.Time
 	Relevance float64
+	// Passage is set when the source is a passage of the article rather
+	// than the article as a whole.
+	Passage *RAGPassage
+}
+
+// RAGPassage is an exact stretch of an article's markdown. Text is the
+// markdown as stored; the offsets are reader offsets (UTF-16 code units into
+// the article's displayed text, see package readertext), the same units as
+// highlight offsets.
+type RAGPassage struct {
+	Text        string
+	StartOffset int
+	EndOffset   int
+}
+
+// RAGScope restricts which articles a RAG query draws on. Unset fields
+// don't restrict; set fields must all match, and an article matches a list
+// when it matches any entry.
+type RAGScope struct {
+	TagIDs        []string
+	CategorySlugs []string
+	CreatedAfter  *time.Time // inclusive
+	CreatedBefore *time.Time // exclusive
+	SourceTypes   []SourceType
+	FavoritesOnly bool
+	ArticleIDs    []string
+}
+
+// IsEmpty reports whether the scope restricts nothing. A nil scope is empty.
+func (s *RAGScope) IsEmpty() bool {
+	return s == nil || (len(s.TagIDs) == 0 && len(s.CategorySlugs) == 0 &&
+		s.CreatedAfter == nil && s.CreatedBefore == nil &&
+		len(s.SourceTypes) == 0 && !s.FavoritesOnly && len(s.ArticleIDs) == 0)
 }
 
 type RAGResponse struct {
@@ -28,6 +64,9 @@ type RAGMessage struct {
 	SourceArticleIDs []string
 	SourceCount      int
 	CreatedAt        time.Time
+	// Sources are the cited articles, resolved from SourceArticleIDs when a
+	// conversation is loaded for display.
+	Sources []RAGSource
 }
 
 type RAGConversation struct {
@@ -37,3 +76,37 @@ type RAGConversation struct {
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
+
+// rrfK damps the weight of top ranks in reciprocal rank fusion; 60 is the
+// customary value.
+const rrfK = 60
+
+// FuseRAGSources merges ranked result lists from different retrievers with
+// reciprocal rank fusion: an article scores 1/(rrfK+rank) in each list it
+// appears in, and the merged list is ordered by total score. Each article
+// keeps the first entry seen for it, with Relevance set to its fused score.
+func FuseRAGSources(limit int, lists ...[]RAGSource) []RAGSource {
+	scores := make(map[string]float64)
+	first := make(map[string]RAGSource)
+	order := make([]string, 0)
+	for _, list := range lists {
+		for rank, s := range list {
+			if _, ok := first[s.ArticleID]; !ok {
+				first[s.ArticleID] = s
+				order = append(order, s.ArticleID)
+			}
+			scores[s.ArticleID] += 1.0 / float64(rrfK+rank+1)
+		}
+	}
+
+	sort.SliceStable(order, func(i, j int) bool { return scores[order[i]] > scores[order[j]] })
+	if limit > 0 && len(order) > limit {
+		order = order[:limit]
+	}
+	fused := make([]RAGSource, len(order))
+	for i, id := range order {
+		fused[i] = first[id]
+		fused[i].Relevance = scores[id]
+	}
+	return fused
+}
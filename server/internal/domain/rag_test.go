@@ -0,0 +1,28 @@
+package domain
+
+import "testing"
+
+func TestFuseRAGSources(t *testing.T) {
+	vector := []RAGSource{{ArticleID: "a"}, {ArticleID: "b"}, {ArticleID: "c"}}
+	keyword := []RAGSource{{ArticleID: "c", Title: "from keyword"}, {ArticleID: "d"}, {ArticleID: "a"}}
+
+	fused := FuseRAGSources(3, vector, keyword)
+
+	ids := make([]string, len(fused))
+	for i, s := range fused {
+		ids[i] = s.ArticleID
+	}
+	// a: ranks 1 and 3; c: ranks 3 and 1 — tied, a seen first. Then b (rank 2) over d (rank 2, seen later).
+	if want := []string{"a", "c", "b"}; len(ids) != 3 || ids[0] != want[0] || ids[1] != want[1] || ids[2] != want[2] {
+		t.Errorf("order = %v, want %v", ids, want)
+	}
+	if fused[1].Title != "" {
+		t.Errorf("kept %q, want the first entry seen for c", fused[1].Title)
+	}
+	if fused[0].Relevance <= fused[2].Relevance {
+		t.Errorf("relevance not descending: %v", fused)
+	}
+	if got := FuseRAGSources(10, nil, nil); len(got) != 0 {
+		t.Errorf("fused empty lists = %v", got)
+	}
+}
@@ -0,0 +1,255 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// SearchQuery is a parsed article search. Free text is matched against the
+// full-text index; filters restrict by article metadata. Everything must
+// match: terms and filters are ANDed, and negated ones must not match.
+//
+// Grammar, whitespace-separated:
+//
+//	word            full-text term
+//	"two words"     phrase
+//	key:value       filter; key:"quoted value" for values with spaces
+//	-term           any of the above, excluded
+//
+// Filter keys are tag, site, category, source, is (favorite, archived),
+// has (highlights), before and after (YYYY-MM-DD; before is exclusive,
+// after inclusive). A token with any other key is an ordinary term, so
+// "c++:" or a URL still searches as text.
+type SearchQuery struct {
+	Terms   []SearchTerm
+	Filters []SearchFilter
+}
+
+// SearchTerm is a word or a quoted phrase.
+type SearchTerm struct {
+	Text    string
+	Phrase  bool
+	Negated bool
+}
+
+// SearchField names what a SearchFilter restricts.
+type SearchField string
+
+const (
+	SearchFieldTag      SearchField = "tag"
+	SearchFieldSite     SearchField = "site"
+	SearchFieldCategory SearchField = "category"
+	SearchFieldSource   SearchField = "source"
+	SearchFieldIs       SearchField = "is"
+	SearchFieldHas      SearchField = "has"
+	SearchFieldBefore   SearchField = "before"
+	SearchFieldAfter    SearchField = "after"
+)
+
+// Values accepted by the is: and has: filters.
+const (
+	SearchIsFavorite    = "favorite"
+	SearchIsArchived    = "archived"
+	SearchHasHighlights = "highlights"
+)
+
+// SearchFilter is one key:value filter. Value is lower-cased for every field
+// except tag; Date is set for before and after.
+type SearchFilter struct {
+	Field   SearchField
+	Value   string
+	Date    time.Time
+	Negated bool
+}
+
+// searchSourceTypes are the source: values, which are the stored source types.
+var searchSourceTypes = []SourceType{
+	SourceWeb, SourceWechat, SourceTwitter, SourceWeibo, SourceZhihu,
+	SourceNewsletter, SourceYoutube, SourceManual, SourceScreenshot,
+	SourceVoice, SourcePDF,
+}
+
+// IsKnown reports whether t is one of the stored source types.
+func (t SourceType) IsKnown() bool {
+	return slices.Contains(searchSourceTypes, t)
+}
+
+// IsEmpty reports whether the query has neither terms nor filters.
+func (q *SearchQuery) IsEmpty() bool {
+	return q == nil || (len(q.Terms) == 0 && len(q.Filters) == 0)
+}
+
+// ParseSearchQuery parses s. It fails only for a known filter key with an
+// invalid value, such as an unknown source or a malformed date; the error
+// message is fit to show the user.
+func ParseSearchQuery(s string) (*SearchQuery, error) {
+	q := &SearchQuery{}
+	for _, tok := range splitSearchTokens(s) {
+		if !hasSearchableRune(tok.text) {
+			continue
+		}
+		if tok.quoted {
+			q.Terms = append(q.Terms, SearchTerm{Text: tok.text, Phrase: true, Negated: tok.negated})
+			continue
+		}
+
+		if key, value, ok := strings.Cut(tok.text, ":"); ok {
+			field := SearchField(strings.ToLower(key))
+			if isSearchField(field) {
+				if value == "" {
+					return nil, fmt.Errorf("%s: needs a value", field)
+				}
+				f, err := parseSearchFilter(field, value)
+				if err != nil {
+					return nil, err
+				}
+				f.Negated = tok.negated
+				q.Filters = append(q.Filters, f)
+				continue
+			}
+		}
+		q.Terms = append(q.Terms, SearchTerm{Text: tok.text, Negated: tok.negated})
+	}
+	return q, nil
+}
+
+// hasSearchableRune reports whether s has a letter or digit. Tokens of
+// punctuation alone index to nothing and would match no article.
+func hasSearchableRune(s string) bool {
+	return strings.IndexFunc(s, func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsNumber(r)
+	}) >= 0
+}
+
+func isSearchField(f SearchField) bool {
+	switch f {
+	case SearchFieldTag, SearchFieldSite, SearchFieldCategory, SearchFieldSource,
+		SearchFieldIs, SearchFieldHas, SearchFieldBefore, SearchFieldAfter:
+		return true
+	}
+	return false
+}
+
+func parseSearchFilter(field SearchField, value string) (SearchFilter, error) {
+	f := SearchFilter{Field: field, Value: strings.ToLower(value)}
+	switch field {
+	case SearchFieldTag:
+		f.Value = value
+	case SearchFieldSite:
+		f.Value = strings.TrimPrefix(f.Value, "www.")
+	case SearchFieldSource:
+		if !SourceType(f.Value).IsKnown() {
+			return f, fmt.Errorf("source: unknown source %q", value)
+		}
+	case SearchFieldIs:
+		if f.Value != SearchIsFavorite && f.Value != SearchIsArchived {
+			return f, fmt.Errorf("is: must be %s or %s", SearchIsFavorite, SearchIsArchived)
+		}
+	case SearchFieldHas:
+		if f.Value != SearchHasHighlights {
+			return f, fmt.Errorf("has: must be %s", SearchHasHighlights)
+		}
+	case SearchFieldBefore, SearchFieldAfter:
+		d, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return f, fmt.Errorf("%s: date must be YYYY-MM-DD", field)
+		}
+		f.Date = d
+	}
+	return f, nil
+}
+
+type searchToken struct {
+	text    string
+	quoted  bool // the whole token was a quoted phrase
+	negated bool
+}
+
+// splitSearchTokens splits s on whitespace outside quotes. A leading "-"
+// negates a token; quotes may wrap a whole token or a filter value. An
+// unclosed quote runs to the end of s.
+func splitSearchTokens(s string) []searchToken {
+	var tokens []searchToken
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		tok := searchToken{}
+		if runes[i] == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			tok.negated = true
+			i++
+		}
+		if runes[i] == '"' {
+			tok.quoted = true
+		}
+
+		var b strings.Builder
+		inQuote := false
+		for ; i < len(runes); i++ {
+			r := runes[i]
+			if r == '"' {
+				inQuote = !inQuote
+				continue
+			}
+			if !inQuote && unicode.IsSpace(r) {
+				break
+			}
+			b.WriteRune(r)
+		}
+		tok.text = strings.TrimSpace(b.String())
+		if tok.quoted {
+			tok.text = strings.Join(strings.Fields(tok.text), " ")
+		}
+		tokens = append(tokens, tok)
+	}
+	return tokens
+}
+
+// SearchResultType is the kind of row a unified search result points at.
+type SearchResultType string
+
+const (
+	SearchResultArticle    SearchResultType = "article"
+	SearchResultHighlight  SearchResultType = "highlight"
+	SearchResultEchoCard   SearchResultType = "echo_card"
+	SearchResultRAGMessage SearchResultType = "rag_message"
+)
+
+// SearchResultTypes lists every result type, in the order ties are broken.
+var SearchResultTypes = []SearchResultType{
+	SearchResultArticle, SearchResultHighlight, SearchResultEchoCard, SearchResultRAGMessage,
+}
+
+// SearchResult is one hit of a unified search. Title is the article or
+// conversation it belongs to; for an Echo card it is the card's question.
+// Snippet highlights the matched terms with <mark> tags.
+type SearchResult struct {
+	Type      SearchResultType
+	ID        string
+	Title     *string
+	Snippet   *string
+	Rank      float64
+	CreatedAt time.Time
+	Target    SearchTarget
+
+	Note *string // highlights only
+}
+
+// SearchTarget is where a client opens a result: the article (scrolled to
+// a highlight's offsets), the Echo card or the RAG conversation. Only the
+// fields that apply to the result type are set.
+type SearchTarget struct {
+	ArticleID      *string
+	HighlightID    *string
+	StartOffset    *int
+	EndOffset      *int
+	CardID         *string
+	ConversationID *string
+	MessageID      *string
+}
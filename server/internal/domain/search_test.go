@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	tests := []struct {
+		in      string
+		terms   []SearchTerm
+		filters []SearchFilter
+	}{
+		{
+			in:    `rust  async`,
+			terms: []SearchTerm{{Text: "rust"}, {Text: "async"}},
+		},
+		{
+			in:    `"large language models" -crypto -"web 3"`,
+			terms: []SearchTerm{{Text: "large language models", Phrase: true}, {Text: "crypto", Negated: true}, {Text: "web 3", Phrase: true, Negated: true}},
+		},
+		{
+			in: `Tag:Go tag:"Machine Learning" site:WWW.NYTimes.com category:Tech source:wechat`,
+			filters: []SearchFilter{
+				{Field: SearchFieldTag, Value: "Go"},
+				{Field: SearchFieldTag, Value: "Machine Learning"},
+				{Field: SearchFieldSite, Value: "nytimes.com"},
+				{Field: SearchFieldCategory, Value: "tech"},
+				{Field: SearchFieldSource, Value: "wechat"},
+			},
+		},
+		{
+			in: `is:favorite -is:archived has:highlights before:2026-01-01 after:2025-06-01`,
+			filters: []SearchFilter{
+				{Field: SearchFieldIs, Value: "favorite"},
+				{Field: SearchFieldIs, Value: "archived", Negated: true},
+				{Field: SearchFieldHas, Value: "highlights"},
+				{Field: SearchFieldBefore, Value: "2026-01-01", Date: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+				{Field: SearchFieldAfter, Value: "2025-06-01", Date: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)},
+			},
+		},
+		{
+			// Unknown keys and a quoted filter are plain text; punctuation
+			// alone is dropped.
+			in:    `https://example.com c++: - -- "tag:go"`,
+			terms: []SearchTerm{{Text: "https://example.com"}, {Text: "c++:"}, {Text: "tag:go", Phrase: true}},
+		},
+		{
+			in:    `机器学习 "unclosed phrase`,
+			terms: []SearchTerm{{Text: "机器学习"}, {Text: "unclosed phrase", Phrase: true}},
+		},
+	}
+	for _, tt := range tests {
+		q, err := ParseSearchQuery(tt.in)
+		if err != nil {
+			t.Errorf("ParseSearchQuery(%q) error: %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(q.Terms, tt.terms) {
+			t.Errorf("ParseSearchQuery(%q) terms = %+v, want %+v", tt.in, q.Terms, tt.terms)
+		}
+		if !reflect.DeepEqual(q.Filters, tt.filters) {
+			t.Errorf("ParseSearchQuery(%q) filters = %+v, want %+v", tt.in, q.Filters, tt.filters)
+		}
+	}
+}
+
+func TestParseSearchQuery_Invalid(t *testing.T) {
+	for _, in := range []string{`source:fax`, `is:read`, `has:notes`, `before:2026-13-01`, `after:soon`, `tag:`} {
+		if _, err := ParseSearchQuery(in); err == nil {
+			t.Errorf("ParseSearchQuery(%q) succeeded, want error", in)
+		}
+	}
+	if q, err := ParseSearchQuery(`  "" `); err != nil || !q.IsEmpty() {
+		t.Errorf("empty phrase = %+v, %v; want empty query", q, err)
+	}
+}
@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+// Session is one signed-in device. It owns a family of refresh tokens, of
+// which only the newest is usable.
+type Session struct {
+	ID         string
+	UserID     string
+	DeviceName *string
+	UserAgent  *string
+	ExpiresAt  time.Time
+	RevokedAt  *time.Time
+	LastUsedAt time.Time
+	CreatedAt  time.Time
+}
+
+// RefreshToken is a stored refresh token. The token itself is never stored,
+// only its hash. UsedAt is set once the token has been rotated.
+type RefreshToken struct {
+	ID        string
+	SessionID string
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+// SubscriptionEvent records one App Store notification (or client-side
+// verification) and the state transition it caused. Events that arrive for
+// an unknown transaction, or after a newer event, are stored with Applied
+// false so the history stays complete.
+type SubscriptionEvent struct {
+	ID                    string
+	UserID                *string
+	NotificationUUID      *string // nil for client-side verification
+	NotificationType      string
+	Subtype               string
+	OriginalTransactionID *string
+	TransactionID         *string
+	ProductID             *string
+	FromState             *SubscriptionState
+	ToState               *SubscriptionState // nil when the event does not change state
+	ExpiresAt             *time.Time
+	AutoRenew             *bool
+	Applied               bool
+	SignedAt              time.Time
+	CreatedAt             time.Time
+}
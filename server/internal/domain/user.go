@@ -9,6 +9,30 @@ const (
 	SubscriptionPro  Subscription = "pro"
 )
 
+// SubscriptionState is where the user is in the App Store subscription
+// lifecycle. The Subscription tier follows from it.
+type SubscriptionState string
+
+const (
+	SubscriptionStateNone         SubscriptionState = "none"          // never subscribed
+	SubscriptionStateActive       SubscriptionState = "active"
+	SubscriptionStateGracePeriod  SubscriptionState = "grace_period"  // renewal failed, still entitled while Apple retries
+	SubscriptionStateBillingRetry SubscriptionState = "billing_retry" // renewal failed, not entitled while Apple retries
+	SubscriptionStateExpired      SubscriptionState = "expired"
+	SubscriptionStateRefunded     SubscriptionState = "refunded"
+	SubscriptionStateRevoked      SubscriptionState = "revoked" // Family Sharing access withdrawn
+)
+
+// Tier returns the subscription tier a user in state s is entitled to.
+func (s SubscriptionState) Tier() Subscription {
+	switch s {
+	case SubscriptionStateActive, SubscriptionStateGracePeriod:
+		return SubscriptionPro
+	default:
+		return SubscriptionFree
+	}
+}
+
 type User struct {
 	ID                   string       `json:"id"`
 	AppleID              *string      `json:"apple_id,omitempty"`
@@ -16,6 +40,8 @@ type User struct {
 	Nickname             *string      `json:"nickname,omitempty"`
 	AvatarURL            *string      `json:"avatar_url,omitempty"`
 	Subscription         Subscription `json:"subscription"`
+	SubscriptionState    SubscriptionState `json:"subscription_state"`
+	SubscriptionAutoRenew bool        `json:"subscription_auto_renew"`
 	SubscriptionExpiresAt *time.Time  `json:"subscription_expires_at,omitempty"`
 	OriginalTransactionID *string     `json:"original_transaction_id,omitempty"`
 	MonthlyQuota         int          `json:"monthly_quota"`
@@ -0,0 +1,155 @@
+// Package fsrs implements the FSRS-4.5 spaced repetition scheduler: the
+// memory of a card is modelled by its stability S (days until recall
+// probability falls to 90%) and difficulty D (1–10), and each review grade
+// updates both. Reviews are scheduled when the predicted probability of
+// recall, the retrievability R, falls to the requested retention.
+//
+// See https://github.com/open-spaced-repetition/fsrs4anki/wiki/The-Algorithm.
+package fsrs
+
+import "math"
+
+// Rating is the grade given to a review.
+type Rating int
+
+const (
+	Again Rating = 1 // forgot
+	Hard  Rating = 2 // recalled with serious difficulty
+	Good  Rating = 3 // recalled after some hesitation
+	Easy  Rating = 4 // recalled at once
+)
+
+// NumWeights is the number of model weights.
+const NumWeights = 17
+
+// Weights are the model weights. W[0..3] are the initial stabilities for
+// each rating; the rest shape the difficulty and stability updates.
+type Weights [NumWeights]float64
+
+// DefaultWeights are the published FSRS-4.5 defaults, fitted on a large
+// review corpus. They apply until a user has enough history to fit their own.
+var DefaultWeights = Weights{
+	0.4872, 1.4003, 3.7145, 13.8206,
+	5.1618, 1.2298, 0.8975, 0.031,
+	1.6474, 0.1367, 1.0461,
+	2.1072, 0.0793, 0.3246, 1.587,
+	0.2272, 2.8755,
+}
+
+const (
+	// DefaultRetention is the recall probability reviews are scheduled at.
+	DefaultRetention = 0.9
+	// DefaultMaxInterval caps scheduled intervals, in days.
+	DefaultMaxInterval = 365
+
+	decay  = -0.5
+	factor = 19.0 / 81.0 // makes R(S, S) = 0.9
+
+	minStability = 0.1
+)
+
+// Parameters configure the scheduler.
+type Parameters struct {
+	W           Weights
+	Retention   float64 // requested retention, in (0, 1)
+	MaxInterval int     // days
+}
+
+// DefaultParameters returns the default weights with the default retention
+// and interval cap.
+func DefaultParameters() Parameters {
+	return Parameters{W: DefaultWeights, Retention: DefaultRetention, MaxInterval: DefaultMaxInterval}
+}
+
+// State is the memory state of a card. The zero State is a card that has
+// never been reviewed.
+type State struct {
+	Stability  float64
+	Difficulty float64
+}
+
+// IsNew reports whether the card has never been reviewed.
+func (s State) IsNew() bool {
+	return s.Stability <= 0
+}
+
+// Retrievability is the predicted probability of recall elapsedDays after
+// the last review of a card with the given stability.
+func Retrievability(elapsedDays, stability float64) float64 {
+	if stability <= 0 {
+		return 0
+	}
+	return math.Pow(1+factor*math.Max(elapsedDays, 0)/stability, decay)
+}
+
+// Next returns the memory state after a review graded r, elapsedDays after
+// the previous review. elapsedDays is ignored for a new card.
+func (p Parameters) Next(s State, elapsedDays float64, r Rating) State {
+	return p.W.next(s, elapsedDays, r)
+}
+
+// Interval returns the days until the next review of a card with the given
+// stability: the time for retrievability to fall to the requested retention.
+// It is at least 1.
+func (p Parameters) Interval(stability float64) int {
+	days := stability / factor * (math.Pow(p.Retention, 1/decay) - 1)
+	maxInterval := p.MaxInterval
+	if maxInterval <= 0 {
+		maxInterval = DefaultMaxInterval
+	}
+	return int(math.Max(1, math.Min(math.Round(days), float64(maxInterval))))
+}
+
+func (w *Weights) next(s State, elapsedDays float64, r Rating) State {
+	g := float64(r)
+	if s.IsNew() {
+		return State{
+			Stability:  math.Max(w[r-1], minStability),
+			Difficulty: clampDifficulty(w[4] - (g-3)*w[5]),
+		}
+	}
+
+	retr := Retrievability(elapsedDays, s.Stability)
+	var stability float64
+	if r == Again {
+		stability = w[11] * math.Pow(s.Difficulty, -w[12]) *
+			(math.Pow(s.Stability+1, w[13]) - 1) * math.Exp(w[14]*(1-retr))
+		// Forgetting never makes a memory more stable.
+		stability = math.Min(stability, s.Stability)
+	} else {
+		bonus := 1.0
+		switch r {
+		case Hard:
+			bonus = w[15]
+		case Easy:
+			bonus = w[16]
+		}
+		stability = s.Stability * (1 + math.Exp(w[8])*(11-s.Difficulty)*
+			math.Pow(s.Stability, -w[9])*(math.Exp(w[10]*(1-retr))-1)*bonus)
+	}
+
+	// Difficulty moves with the grade, reverting towards the initial
+	// difficulty of a Good first review.
+	difficulty := s.Difficulty - w[6]*(g-3)
+	difficulty = w[7]*w[4] + (1-w[7])*difficulty
+
+	return State{
+		Stability:  math.Max(stability, minStability),
+		Difficulty: clampDifficulty(difficulty),
+	}
+}
+
+func clampDifficulty(d float64) float64 {
+	return math.Min(math.Max(d, 1), 10)
+}
+
+// WeightsFromSlice converts stored weights, reporting false if there are not
+// exactly NumWeights of them.
+func WeightsFromSlice(s []float64) (Weights, bool) {
+	var w Weights
+	if len(s) != NumWeights {
+		return w, false
+	}
+	copy(w[:], s)
+	return w, true
+}
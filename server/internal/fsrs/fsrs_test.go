@@ -0,0 +1,120 @@
+package fsrs
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestRetrievability(t *testing.T) {
+	if r := Retrievability(0, 5); r != 1 {
+		t.Errorf("R(0) = %v, want 1", r)
+	}
+	if r := Retrievability(5, 5); math.Abs(r-0.9) > 1e-9 {
+		t.Errorf("R(S) = %v, want 0.9", r)
+	}
+	if Retrievability(10, 5) >= Retrievability(5, 5) {
+		t.Error("retrievability should fall with time")
+	}
+}
+
+func TestInterval(t *testing.T) {
+	p := DefaultParameters()
+	if got := p.Interval(12.4); got != 12 {
+		t.Errorf("interval at 90%% retention = %d, want the stability", got)
+	}
+	if got := p.Interval(0.2); got != 1 {
+		t.Errorf("interval = %d, want at least 1", got)
+	}
+	if got := p.Interval(10000); got != DefaultMaxInterval {
+		t.Errorf("interval = %d, want the cap", got)
+	}
+	p.Retention = 0.8
+	if p.Interval(10) <= 10 {
+		t.Error("lower retention should lengthen intervals")
+	}
+}
+
+func TestNext(t *testing.T) {
+	p := DefaultParameters()
+
+	first := p.Next(State{}, 0, Good)
+	if first.Stability != DefaultWeights[2] || math.Abs(first.Difficulty-DefaultWeights[4]) > 1e-9 {
+		t.Errorf("first Good review = %+v", first)
+	}
+	if easy := p.Next(State{}, 0, Easy); easy.Difficulty >= first.Difficulty || easy.Stability <= first.Stability {
+		t.Errorf("first Easy review = %+v, want easier and more stable than Good", easy)
+	}
+
+	var prev float64
+	for _, r := range []Rating{Again, Hard, Good, Easy} {
+		s := p.Next(first, 3, r)
+		if s.Stability <= prev && r != Again {
+			t.Errorf("rating %d: stability %v not above the lower grade's %v", r, s.Stability, prev)
+		}
+		prev = s.Stability
+		if s.Difficulty < 1 || s.Difficulty > 10 {
+			t.Errorf("rating %d: difficulty %v out of range", r, s.Difficulty)
+		}
+	}
+	if forgot := p.Next(first, 3, Again); forgot.Stability > first.Stability || forgot.Difficulty <= first.Difficulty {
+		t.Errorf("Again = %+v, want less stable and harder than %+v", forgot, first)
+	}
+}
+
+// simulate reviews cards on schedule for a learner whose memory follows
+// truth, returning the histories.
+func simulate(truth Weights, cards, reviews int) [][]Review {
+	rng := rand.New(rand.NewSource(1))
+	p := Parameters{W: truth, Retention: 0.9, MaxInterval: 365}
+	histories := make([][]Review, cards)
+	for c := range histories {
+		var s State
+		elapsed := 0.0
+		for i := 0; i < reviews; i++ {
+			rating := Good
+			if i > 0 && rng.Float64() > Retrievability(elapsed, s.Stability) {
+				rating = Again
+			} else if rng.Float64() < 0.2 {
+				rating = Easy
+			}
+			histories[c] = append(histories[c], Review{Rating: rating, ElapsedDays: elapsed})
+			s = p.Next(s, elapsed, rating)
+			// Reviews drift from the schedule, as real ones do.
+			elapsed = float64(p.Interval(s.Stability)) * (0.5 + rng.Float64())
+		}
+	}
+	return histories
+}
+
+func TestOptimize(t *testing.T) {
+	// A learner who forgets faster than the defaults predict.
+	truth := DefaultWeights
+	for i := 0; i < 4; i++ {
+		truth[i] /= 3
+	}
+	truth[8] = 1.0
+	histories := simulate(truth, 150, 6)
+
+	fitted := Optimize(histories, DefaultWeights)
+
+	before, after := LogLoss(DefaultWeights, histories), LogLoss(fitted, histories)
+	if after >= before {
+		t.Fatalf("log loss %v after optimizing, %v before", after, before)
+	}
+	if fitted[2] >= DefaultWeights[2] {
+		t.Errorf("initial Good stability %v, want below the default %v", fitted[2], DefaultWeights[2])
+	}
+	for i, w := range fitted {
+		if w < weightBounds[i][0] || w > weightBounds[i][1] {
+			t.Errorf("w[%d] = %v out of bounds", i, w)
+		}
+	}
+}
+
+func TestOptimize_NoHistory(t *testing.T) {
+	single := [][]Review{{{Rating: Good}}}
+	if got := Optimize(single, DefaultWeights); got != DefaultWeights {
+		t.Errorf("weights changed without any predictions to fit: %v", got)
+	}
+}
@@ -0,0 +1,118 @@
+package fsrs
+
+import "math"
+
+// Review is one review in a card's history.
+type Review struct {
+	Rating Rating
+	// ElapsedDays is the time since the card's previous review; it is
+	// ignored for the first review.
+	ElapsedDays float64
+}
+
+// weightBounds keep optimized weights in the ranges FSRS considers sane.
+var weightBounds = [NumWeights][2]float64{
+	{0.1, 100}, {0.1, 100}, {0.1, 100}, {0.1, 100},
+	{1, 10}, {0.1, 5}, {0.1, 5}, {0, 0.5},
+	{0, 3}, {0.1, 0.8}, {0.01, 2.5},
+	{0.5, 5}, {0.01, 0.2}, {0.01, 0.9}, {0.01, 2},
+	{0, 1}, {1, 4},
+}
+
+const (
+	optimizeSteps = 250
+	learningRate  = 0.01 // per step, as a fraction of each weight's range
+	// regularization pulls weights towards the starting point so that small
+	// histories cannot drag them far; it is per unit of normalized distance.
+	regularization = 0.05
+)
+
+// LogLoss is the mean binary cross-entropy of the recall predicted by w
+// against the recall observed (any rating but Again), over every review that
+// follows an earlier one. It returns 0 when there are no such reviews.
+func LogLoss(w Weights, histories [][]Review) float64 {
+	loss, n := logLoss(&w, histories)
+	if n == 0 {
+		return 0
+	}
+	return loss / float64(n)
+}
+
+func logLoss(w *Weights, histories [][]Review) (float64, int) {
+	var loss float64
+	var n int
+	for _, h := range histories {
+		var s State
+		for i, rv := range h {
+			if i > 0 {
+				p := Retrievability(rv.ElapsedDays, s.Stability)
+				p = math.Min(math.Max(p, 1e-6), 1-1e-6)
+				if rv.Rating == Again {
+					loss -= math.Log(1 - p)
+				} else {
+					loss -= math.Log(p)
+				}
+				n++
+			}
+			s = w.next(s, rv.ElapsedDays, rv.Rating)
+		}
+	}
+	return loss, n
+}
+
+// Optimize fits the weights to a user's review histories, one per card in
+// review order, by minimizing LogLoss with Adam, starting from start. The
+// result is no worse than start on the histories given.
+func Optimize(histories [][]Review, start Weights) Weights {
+	if _, n := logLoss(&start, histories); n == 0 {
+		return start
+	}
+
+	objective := func(w *Weights) float64 {
+		loss, n := logLoss(w, histories)
+		var reg float64
+		for i := range w {
+			d := (w[i] - start[i]) / (weightBounds[i][1] - weightBounds[i][0])
+			reg += d * d
+		}
+		return loss/float64(n) + regularization*reg
+	}
+
+	const beta1, beta2, eps = 0.9, 0.999, 1e-8
+	w := start
+	var m, v [NumWeights]float64
+	best, bestLoss := start, objective(&start)
+	for step := 1; step <= optimizeSteps; step++ {
+		grad := gradient(&w, objective)
+		for i := range w {
+			span := weightBounds[i][1] - weightBounds[i][0]
+			g := grad[i] * span // gradient in normalized units
+			m[i] = beta1*m[i] + (1-beta1)*g
+			v[i] = beta2*v[i] + (1-beta2)*g*g
+			mHat := m[i] / (1 - math.Pow(beta1, float64(step)))
+			vHat := v[i] / (1 - math.Pow(beta2, float64(step)))
+			w[i] -= learningRate * span * mHat / (math.Sqrt(vHat) + eps)
+			w[i] = math.Min(math.Max(w[i], weightBounds[i][0]), weightBounds[i][1])
+		}
+		if loss := objective(&w); loss < bestLoss {
+			best, bestLoss = w, loss
+		}
+	}
+	return best
+}
+
+// gradient estimates the gradient of f at w by central differences.
+func gradient(w *Weights, f func(*Weights) float64) [NumWeights]float64 {
+	var grad [NumWeights]float64
+	for i := range w {
+		h := 1e-4 * (weightBounds[i][1] - weightBounds[i][0])
+		orig := w[i]
+		w[i] = orig + h
+		up := f(w)
+		w[i] = orig - h
+		down := f(w)
+		w[i] = orig
+		grad[i] = (up - down) / (2 * h)
+	}
+	return grad
+}
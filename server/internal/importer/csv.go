@@ -0,0 +1,124 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// csvHeader maps lower-cased column names to their index.
+type csvHeader map[string]int
+
+func (h csvHeader) has(name string) bool {
+	_, ok := h[name]
+	return ok
+}
+
+// get returns the named column of rec, or "" when absent.
+func (h csvHeader) get(rec []string, name string) string {
+	if i, ok := h[name]; ok && i < len(rec) {
+		return rec[i]
+	}
+	return ""
+}
+
+func newCSVReader(data []byte) *csv.Reader {
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	return r
+}
+
+func readCSVHeader(data []byte) (csvHeader, error) {
+	rec, err := newCSVReader(data).Read()
+	if err != nil {
+		return nil, err
+	}
+	h := make(csvHeader, len(rec))
+	for i, name := range rec {
+		h[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if !h.has("url") {
+		return nil, ErrUnknownFormat
+	}
+	return h, nil
+}
+
+// readCSV calls fn for every data row after the header.
+func readCSV(data []byte, fn func(h csvHeader, rec []string)) error {
+	h, err := readCSVHeader(data)
+	if err != nil {
+		return err
+	}
+	r := newCSVReader(data)
+	if _, err := r.Read(); err != nil {
+		return err
+	}
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fn(h, rec)
+	}
+}
+
+// parsePocketCSV reads Pocket's CSV export:
+// title,url,time_added,tags,status with "|"-separated tags and a status of
+// "unread" or "archive".
+func parsePocketCSV(data []byte) ([]Item, error) {
+	var items []Item
+	err := readCSV(data, func(h csvHeader, rec []string) {
+		items = append(items, Item{
+			URL:        h.get(rec, "url"),
+			Title:      h.get(rec, "title"),
+			Tags:       splitTags(h.get(rec, "tags"), "|"),
+			SavedAt:    unixTime(h.get(rec, "time_added")),
+			IsArchived: strings.EqualFold(strings.TrimSpace(h.get(rec, "status")), "archive"),
+		})
+	})
+	return items, err
+}
+
+// parseInstapaperCSV reads Instapaper's CSV export:
+// URL,Title,Selection,Folder,Timestamp[,Tags]. The built-in folders map to
+// flags; any other folder name becomes a tag.
+func parseInstapaperCSV(data []byte) ([]Item, error) {
+	var items []Item
+	err := readCSV(data, func(h csvHeader, rec []string) {
+		it := Item{
+			URL:     h.get(rec, "url"),
+			Title:   h.get(rec, "title"),
+			SavedAt: unixTime(h.get(rec, "timestamp")),
+			Tags:    instapaperTags(h.get(rec, "tags")),
+		}
+		switch folder := strings.TrimSpace(h.get(rec, "folder")); strings.ToLower(folder) {
+		case "", "unread":
+		case "archive":
+			it.IsArchived = true
+		case "starred":
+			it.IsFavorite = true
+		default:
+			it.Tags = append(it.Tags, folder)
+		}
+		items = append(items, it)
+	})
+	return items, err
+}
+
+// instapaperTags accepts the Tags column as a JSON array or a comma list.
+func instapaperTags(s string) []string {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "[") {
+		var tags []string
+		if json.Unmarshal([]byte(s), &tags) == nil {
+			return tags
+		}
+	}
+	return splitTags(s, ",")
+}
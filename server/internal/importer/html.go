@@ -0,0 +1,150 @@
+package importer
+
+import (
+	"bytes"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// bookmarkRootFolders are the browser-provided top-level folders; links
+// directly inside them get no folder tag.
+var bookmarkRootFolders = map[string]bool{
+	"bookmarks":         true,
+	"bookmarks bar":     true,
+	"bookmarks toolbar": true,
+	"bookmarks menu":    true,
+	"favorites bar":     true,
+	"mobile bookmarks":  true,
+	"other bookmarks":   true,
+}
+
+// isPocketHTML reports whether an HTML export is Pocket's ril_export.html,
+// whose links carry time_added attributes instead of Netscape's ADD_DATE.
+func isPocketHTML(data []byte) bool {
+	head := data
+	if len(head) > 64<<10 {
+		head = head[:64<<10]
+	}
+	lower := bytes.ToLower(head)
+	return bytes.Contains(lower, []byte("time_added=")) && !bytes.Contains(lower, []byte("netscape-bookmark-file"))
+}
+
+// parseBookmarksHTML reads a Netscape bookmark file or Pocket HTML export.
+//
+// Netscape files nest <DL> lists under <H3> folder headings; the innermost
+// folder becomes a tag. Pocket exports list links under <h1>Unread</h1> and
+// <h1>Read Archive</h1> sections and keep tags in a comma-separated attribute.
+func parseBookmarksHTML(data []byte, pocket bool) ([]Item, error) {
+	z := html.NewTokenizer(bytes.NewReader(data))
+
+	var (
+		items   []Item
+		folders []string // folder name per open <DL>, "" when untagged
+		heading strings.Builder
+		inHead  atom.Atom // h1 or h3 while reading heading text
+		pending string    // folder name waiting for its <DL>
+		archive bool      // Pocket: inside the Read Archive section
+		current *Item     // link whose title is being read
+	)
+
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return items, nil
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			switch tok.DataAtom {
+			case atom.H1, atom.H3:
+				inHead = tok.DataAtom
+				heading.Reset()
+				if tok.DataAtom == atom.H3 && attr(tok, "personal_toolbar_folder") == "true" {
+					inHead = 0
+					pending = ""
+				}
+			case atom.Dl:
+				folders = append(folders, pending)
+				pending = ""
+			case atom.A:
+				href := attr(tok, "href")
+				if href == "" {
+					continue
+				}
+				it := Item{URL: href}
+				if pocket {
+					it.SavedAt = unixTime(attr(tok, "time_added"))
+					it.Tags = splitTags(attr(tok, "tags"), ",")
+					it.IsArchived = archive
+				} else {
+					it.SavedAt = unixTime(attr(tok, "add_date"))
+					it.Tags = splitTags(attr(tok, "tags"), ",")
+					if f := innermostFolder(folders); f != "" {
+						it.Tags = append(it.Tags, f)
+					}
+				}
+				items = append(items, it)
+				current = &items[len(items)-1]
+			}
+
+		case html.TextToken:
+			switch {
+			case inHead != 0:
+				heading.Write(z.Text())
+			case current != nil:
+				current.Title += string(z.Text())
+			}
+
+		case html.EndTagToken:
+			tok := z.Token()
+			switch tok.DataAtom {
+			case atom.H1:
+				if inHead == atom.H1 {
+					archive = strings.Contains(strings.ToLower(heading.String()), "archive")
+					inHead = 0
+				}
+			case atom.H3:
+				if inHead == atom.H3 {
+					pending = strings.TrimSpace(heading.String())
+					inHead = 0
+				}
+			case atom.Dl:
+				if len(folders) > 0 {
+					folders = folders[:len(folders)-1]
+				}
+			case atom.A:
+				current = nil
+			}
+		}
+	}
+}
+
+func innermostFolder(folders []string) string {
+	for i := len(folders) - 1; i >= 0; i-- {
+		if f := folders[i]; f != "" {
+			if bookmarkRootFolders[strings.ToLower(f)] {
+				return ""
+			}
+			return f
+		}
+	}
+	return ""
+}
+
+func attr(tok html.Token, key string) string {
+	for _, a := range tok.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func splitTags(s, sep string) []string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	return strings.Split(s, sep)
+}
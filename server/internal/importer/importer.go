@@ -0,0 +1,187 @@
+// Package importer parses read-later exports from other services (Pocket,
+// Instapaper, Omnivore) and browser bookmark files into a common item list.
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Source identifies the service an export came from.
+type Source string
+
+const (
+	SourcePocket     Source = "pocket"
+	SourceInstapaper Source = "instapaper"
+	SourceOmnivore   Source = "omnivore"
+	SourceBookmarks  Source = "bookmarks"
+)
+
+// MaxItems caps how many items a single export may contain.
+const MaxItems = 20000
+
+// maxTagLen matches the tags.name column width.
+const maxTagLen = 50
+
+var (
+	ErrUnknownFormat = errors.New("importer: unrecognized export format")
+	ErrNoItems       = errors.New("importer: export contains no links")
+	ErrTooManyItems  = errors.New("importer: export contains too many links")
+)
+
+// Item is one saved link from an export.
+type Item struct {
+	URL        string
+	Title      string
+	Tags       []string
+	SavedAt    *time.Time
+	IsFavorite bool
+	IsArchived bool
+}
+
+// Parse detects the export format and returns its items, oldest first.
+// Items without an http(s) URL are dropped and repeated URLs are merged.
+func Parse(data []byte) (Source, []Item, error) {
+	source, items, err := parse(data)
+	if err != nil {
+		return "", nil, err
+	}
+	items = normalize(items)
+	if len(items) == 0 {
+		return "", nil, ErrNoItems
+	}
+	if len(items) > MaxItems {
+		return "", nil, ErrTooManyItems
+	}
+	return source, items, nil
+}
+
+func parse(data []byte) (Source, []Item, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return "", nil, ErrNoItems
+	}
+
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("PK\x03\x04")):
+		items, err := parseOmnivoreZip(data)
+		return SourceOmnivore, items, err
+	case trimmed[0] == '[' || trimmed[0] == '{':
+		items, err := parseOmnivoreJSON(trimmed)
+		return SourceOmnivore, items, err
+	case trimmed[0] == '<':
+		if isPocketHTML(trimmed) {
+			items, err := parseBookmarksHTML(data, true)
+			return SourcePocket, items, err
+		}
+		items, err := parseBookmarksHTML(data, false)
+		return SourceBookmarks, items, err
+	}
+
+	header, err := readCSVHeader(data)
+	if err != nil {
+		return "", nil, ErrUnknownFormat
+	}
+	switch {
+	case header.has("time_added"):
+		items, err := parsePocketCSV(data)
+		return SourcePocket, items, err
+	case header.has("folder") || header.has("selection"):
+		items, err := parseInstapaperCSV(data)
+		return SourceInstapaper, items, err
+	}
+	return "", nil, ErrUnknownFormat
+}
+
+// normalize drops unusable links, cleans tags, merges duplicate URLs and
+// sorts items oldest first (undated items keep their file order at the end).
+func normalize(items []Item) []Item {
+	out := make([]Item, 0, len(items))
+	index := make(map[string]int, len(items))
+	for _, it := range items {
+		u, ok := cleanURL(it.URL)
+		if !ok {
+			continue
+		}
+		it.URL = u
+		it.Title = strings.TrimSpace(it.Title)
+		it.Tags = cleanTags(it.Tags)
+
+		if i, dup := index[u]; dup {
+			prev := &out[i]
+			prev.Tags = cleanTags(append(prev.Tags, it.Tags...))
+			prev.IsFavorite = prev.IsFavorite || it.IsFavorite
+			prev.IsArchived = prev.IsArchived && it.IsArchived
+			if prev.Title == "" {
+				prev.Title = it.Title
+			}
+			if it.SavedAt != nil && (prev.SavedAt == nil || it.SavedAt.Before(*prev.SavedAt)) {
+				prev.SavedAt = it.SavedAt
+			}
+			continue
+		}
+		index[u] = len(out)
+		out = append(out, it)
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i].SavedAt, out[j].SavedAt
+		return a != nil && (b == nil || a.Before(*b))
+	})
+	return out
+}
+
+func cleanURL(raw string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return "", false
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", false
+	}
+	u.Fragment = ""
+	return u.String(), true
+}
+
+// cleanTags trims, truncates and de-duplicates tag names case-insensitively.
+func cleanTags(tags []string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+	out := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		if utf8.RuneCountInString(t) > maxTagLen {
+			t = string([]rune(t)[:maxTagLen])
+		}
+		key := strings.ToLower(t)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, t)
+	}
+	return out
+}
+
+// unixTime parses a Unix timestamp in seconds; zero and garbage yield nil.
+func unixTime(s string) *time.Time {
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil || n <= 0 || n > 1<<40 {
+		return nil
+	}
+	t := time.Unix(n, 0).UTC()
+	return &t
+}
@@ -0,0 +1,207 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func ts(sec int64) *time.Time {
+	t := time.Unix(sec, 0).UTC()
+	return &t
+}
+
+func TestParse_PocketHTML(t *testing.T) {
+	data := `<!DOCTYPE html>
+<html><head><title>Pocket Export</title></head><body>
+<h1>Unread</h1>
+<ul>
+<li><a href="https://example.com/b" time_added="1600000200" tags="go,backend">Second</a></li>
+<li><a href="https://example.com/a" time_added="1600000100" tags="">First</a></li>
+</ul>
+<h1>Read Archive</h1>
+<ul>
+<li><a href="https://example.com/c#section" time_added="1600000300" tags="reading">Third</a></li>
+<li><a href="javascript:alert(1)" time_added="1600000400">Bad</a></li>
+</ul>
+</body></html>`
+
+	source, items, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if source != SourcePocket {
+		t.Errorf("source = %q, want pocket", source)
+	}
+	want := []Item{
+		{URL: "https://example.com/a", Title: "First", SavedAt: ts(1600000100)},
+		{URL: "https://example.com/b", Title: "Second", Tags: []string{"go", "backend"}, SavedAt: ts(1600000200)},
+		{URL: "https://example.com/c", Title: "Third", Tags: []string{"reading"}, SavedAt: ts(1600000300), IsArchived: true},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("items =\n%+v\nwant\n%+v", items, want)
+	}
+}
+
+func TestParse_PocketCSV(t *testing.T) {
+	data := "title,url,time_added,tags,status\n" +
+		"Hello,https://example.com/1,1700000000,a|b,unread\n" +
+		"\"Quoted, title\",https://example.com/2,1700000100,,archive\n"
+
+	source, items, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if source != SourcePocket || len(items) != 2 {
+		t.Fatalf("source = %q, %d items", source, len(items))
+	}
+	if !reflect.DeepEqual(items[0].Tags, []string{"a", "b"}) || items[0].IsArchived {
+		t.Errorf("item 0 = %+v", items[0])
+	}
+	if items[1].Title != "Quoted, title" || !items[1].IsArchived {
+		t.Errorf("item 1 = %+v", items[1])
+	}
+}
+
+func TestParse_InstapaperCSV(t *testing.T) {
+	data := "URL,Title,Selection,Folder,Timestamp,Tags\n" +
+		"https://example.com/1,One,,Unread,1500000000,\n" +
+		"https://example.com/2,Two,,Starred,1500000100,\"[\"\"ml\"\"]\"\n" +
+		"https://example.com/3,Three,,Archive,1500000200,\n" +
+		"https://example.com/4,Four,,Research,1500000300,\n"
+
+	source, items, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if source != SourceInstapaper || len(items) != 4 {
+		t.Fatalf("source = %q, %d items", source, len(items))
+	}
+	if !items[1].IsFavorite || !reflect.DeepEqual(items[1].Tags, []string{"ml"}) {
+		t.Errorf("starred item = %+v", items[1])
+	}
+	if !items[2].IsArchived {
+		t.Errorf("archived item = %+v", items[2])
+	}
+	if !reflect.DeepEqual(items[3].Tags, []string{"Research"}) {
+		t.Errorf("folder item tags = %v", items[3].Tags)
+	}
+}
+
+const omnivoreJSON = `[
+  {"id":"1","title":"Old","url":"https://example.com/old","state":"ARCHIVED","labels":["Newsletter"],"savedAt":"2023-01-02T03:04:05.000Z"},
+  {"id":"2","title":"New","url":"https://example.com/new","state":"SUCCEEDED","labels":[{"name":"AI"}],"savedAt":"2024-05-06T07:08:09Z"}
+]`
+
+func TestParse_OmnivoreJSON(t *testing.T) {
+	source, items, err := Parse([]byte(omnivoreJSON))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if source != SourceOmnivore || len(items) != 2 {
+		t.Fatalf("source = %q, %d items", source, len(items))
+	}
+	if !items[0].IsArchived || items[0].SavedAt.Year() != 2023 || items[0].Tags[0] != "Newsletter" {
+		t.Errorf("item 0 = %+v", items[0])
+	}
+	if items[1].IsArchived || !reflect.DeepEqual(items[1].Tags, []string{"AI"}) {
+		t.Errorf("item 1 = %+v", items[1])
+	}
+}
+
+func TestParse_OmnivoreZip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("metadata_0_to_2.json")
+	w.Write([]byte(omnivoreJSON))
+	w, _ = zw.Create("content/old.html")
+	w.Write([]byte("<p>ignored</p>"))
+	zw.Close()
+
+	source, items, err := Parse(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if source != SourceOmnivore || len(items) != 2 {
+		t.Errorf("source = %q, %d items", source, len(items))
+	}
+}
+
+func TestParse_NetscapeBookmarks(t *testing.T) {
+	data := `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+<DL><p>
+    <DT><H3 ADD_DATE="1" PERSONAL_TOOLBAR_FOLDER="true">Bookmarks bar</H3>
+    <DL><p>
+        <DT><A HREF="https://example.com/top" ADD_DATE="1400000000">Top</A>
+        <DT><H3>Papers</H3>
+        <DL><p>
+            <DT><A HREF="https://example.com/paper" ADD_DATE="1400000100" TAGS="ml,nlp">Paper</A>
+        </DL><p>
+        <DT><A HREF="https://example.com/after" ADD_DATE="1400000200">After</A>
+    </DL><p>
+</DL><p>`
+
+	source, items, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if source != SourceBookmarks || len(items) != 3 {
+		t.Fatalf("source = %q, %d items", source, len(items))
+	}
+	if items[0].Tags != nil {
+		t.Errorf("toolbar item tags = %v, want none", items[0].Tags)
+	}
+	if !reflect.DeepEqual(items[1].Tags, []string{"ml", "nlp", "Papers"}) {
+		t.Errorf("folder item tags = %v", items[1].Tags)
+	}
+	if items[2].Tags != nil {
+		t.Errorf("item after folder tags = %v, want none", items[2].Tags)
+	}
+}
+
+func TestParse_MergesDuplicates(t *testing.T) {
+	data := "title,url,time_added,tags,status\n" +
+		"A,https://example.com/x,1700000100,one,archive\n" +
+		",https://example.com/x#frag,1700000000,ONE|two,unread\n"
+
+	_, items, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("got %d items, want 1", len(items))
+	}
+	it := items[0]
+	if it.Title != "A" || it.IsArchived || !it.SavedAt.Equal(*ts(1700000000)) {
+		t.Errorf("merged item = %+v", it)
+	}
+	if !reflect.DeepEqual(it.Tags, []string{"one", "two"}) {
+		t.Errorf("merged tags = %v", it.Tags)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want error
+	}{
+		{"empty", "  \n", ErrNoItems},
+		{"plain text", "just some notes\nmore notes", ErrUnknownFormat},
+		{"csv without known columns", "url,title\nhttps://example.com,x\n", ErrUnknownFormat},
+		{"html without links", "<html><body><p>hi</p></body></html>", ErrNoItems},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := Parse([]byte(tt.data)); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
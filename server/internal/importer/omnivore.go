@@ -0,0 +1,118 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// maxZipEntryBytes caps how much of a single metadata file is read from an
+// Omnivore export archive.
+const maxZipEntryBytes = 64 << 20
+
+// omnivoreItem is one entry of an Omnivore metadata_*.json export file.
+type omnivoreItem struct {
+	URL        string          `json:"url"`
+	Title      string          `json:"title"`
+	Labels     json.RawMessage `json:"labels"`
+	State      string          `json:"state"`
+	SavedAt    string          `json:"savedAt"`
+	ArchivedAt *string         `json:"archivedAt"`
+}
+
+// parseOmnivoreJSON reads a single Omnivore metadata file: a JSON array of
+// items, or an object wrapping one under "items".
+func parseOmnivoreJSON(data []byte) ([]Item, error) {
+	var raw []omnivoreItem
+	if err := json.Unmarshal(data, &raw); err != nil {
+		var wrapped struct {
+			Items []omnivoreItem `json:"items"`
+		}
+		if err2 := json.Unmarshal(data, &wrapped); err2 != nil {
+			return nil, ErrUnknownFormat
+		}
+		raw = wrapped.Items
+	}
+
+	items := make([]Item, 0, len(raw))
+	for _, o := range raw {
+		it := Item{
+			URL:        o.URL,
+			Title:      o.Title,
+			Tags:       omnivoreLabels(o.Labels),
+			IsArchived: strings.EqualFold(o.State, "ARCHIVED") || o.ArchivedAt != nil,
+		}
+		if t, err := time.Parse(time.RFC3339, o.SavedAt); err == nil {
+			t = t.UTC()
+			it.SavedAt = &t
+		}
+		items = append(items, it)
+	}
+	return items, nil
+}
+
+// parseOmnivoreZip reads every metadata_*.json file in an Omnivore export
+// archive; the saved article content in the archive is ignored.
+func parseOmnivoreZip(data []byte) ([]Item, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, ErrUnknownFormat
+	}
+
+	var items []Item
+	found := false
+	for _, f := range zr.File {
+		name := path.Base(f.Name)
+		if !strings.HasPrefix(name, "metadata") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		found = true
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		b, err := io.ReadAll(io.LimitReader(rc, maxZipEntryBytes))
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		part, err := parseOmnivoreJSON(b)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, part...)
+		if len(items) > MaxItems {
+			return nil, ErrTooManyItems
+		}
+	}
+	if !found {
+		return nil, ErrUnknownFormat
+	}
+	return items, nil
+}
+
+// omnivoreLabels accepts labels as plain strings or {"name": ...} objects;
+// both shapes appear in exports from different Omnivore versions.
+func omnivoreLabels(raw json.RawMessage) []string {
+	if len(raw) == 0 {
+		return nil
+	}
+	var names []string
+	if json.Unmarshal(raw, &names) == nil {
+		return names
+	}
+	var objs []struct {
+		Name string `json:"name"`
+	}
+	if json.Unmarshal(raw, &objs) != nil {
+		return nil
+	}
+	for _, o := range objs {
+		names = append(names, o.Name)
+	}
+	return names
+}
@@ -0,0 +1,284 @@
+package inbound
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var reBlankLines = regexp.MustCompile(`\n{3,}`)
+
+// HTMLToMarkdown converts an HTML document (typically a newsletter) to
+// markdown. Layout tables are flattened into blocks, tracking pixels and
+// script/style content are dropped. cidURL, when non-nil, rewrites
+// "cid:" image sources to uploaded attachment URLs.
+func HTMLToMarkdown(src string, cidURL func(cid string) string) (string, error) {
+	doc, err := html.Parse(strings.NewReader(src))
+	if err != nil {
+		return "", fmt.Errorf("parse html: %w", err)
+	}
+
+	c := &mdConverter{cidURL: cidURL}
+	c.walk(doc)
+
+	lines := strings.Split(c.sb.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	out := reBlankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(out), nil
+}
+
+type mdConverter struct {
+	sb        strings.Builder
+	cidURL    func(string) string
+	listStack []listState
+	inPre     bool
+	quote     int
+}
+
+type listState struct {
+	ordered bool
+	index   int
+}
+
+func (c *mdConverter) write(s string) {
+	if c.quote > 0 && strings.Contains(s, "\n") {
+		s = strings.ReplaceAll(s, "\n", "\n"+strings.Repeat("> ", c.quote))
+	}
+	c.sb.WriteString(s)
+}
+
+// block ensures the next content starts on a fresh paragraph.
+func (c *mdConverter) block() {
+	str := c.sb.String()
+	if str == "" || strings.HasSuffix(str, "\n\n") {
+		return
+	}
+	if strings.HasSuffix(str, "\n") {
+		c.write("\n")
+		return
+	}
+	c.write("\n\n")
+}
+
+func (c *mdConverter) lineBreak() {
+	if str := c.sb.String(); str != "" && !strings.HasSuffix(str, "\n") {
+		c.write("\n")
+	}
+}
+
+func (c *mdConverter) walkChildren(n *html.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		c.walk(child)
+	}
+}
+
+func (c *mdConverter) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		c.text(n.Data)
+		return
+	case html.DocumentNode:
+		c.walkChildren(n)
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Head, atom.Title, atom.Noscript, atom.Template:
+		return
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		text := strings.TrimSpace(collapseSpace(textContent(n)))
+		if text == "" {
+			return
+		}
+		c.block()
+		c.write(strings.Repeat("#", level) + " " + text)
+		c.block()
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Table, atom.Tr, atom.Center, atom.Header, atom.Footer:
+		c.block()
+		c.walkChildren(n)
+		c.block()
+	case atom.Td, atom.Th:
+		c.lineBreak()
+		c.walkChildren(n)
+		c.lineBreak()
+	case atom.Br:
+		c.write("\n")
+	case atom.Hr:
+		c.block()
+		c.write("---")
+		c.block()
+	case atom.Strong, atom.B:
+		c.wrapInline(n, "**")
+	case atom.Em, atom.I:
+		c.wrapInline(n, "*")
+	case atom.Code:
+		if c.inPre {
+			c.walkChildren(n)
+			return
+		}
+		c.wrapInline(n, "`")
+	case atom.Pre:
+		c.block()
+		c.write("```\n")
+		c.inPre = true
+		c.walkChildren(n)
+		c.inPre = false
+		c.lineBreak()
+		c.write("```")
+		c.block()
+	case atom.Blockquote:
+		c.block()
+		c.quote++
+		c.write("> ")
+		c.walkChildren(n)
+		c.quote--
+		c.block()
+	case atom.Ul, atom.Ol:
+		c.block()
+		c.listStack = append(c.listStack, listState{ordered: n.DataAtom == atom.Ol})
+		c.walkChildren(n)
+		c.listStack = c.listStack[:len(c.listStack)-1]
+		c.block()
+	case atom.Li:
+		c.lineBreak()
+		marker := "- "
+		depth := len(c.listStack)
+		if depth > 0 {
+			top := &c.listStack[depth-1]
+			top.index++
+			if top.ordered {
+				marker = fmt.Sprintf("%d. ", top.index)
+			}
+			c.write(strings.Repeat("  ", depth-1))
+		}
+		c.write(marker)
+		c.walkChildren(n)
+		c.lineBreak()
+	case atom.A:
+		href := strings.TrimSpace(attr(n, "href"))
+		inner := &mdConverter{cidURL: c.cidURL}
+		inner.walkChildren(n)
+		text := strings.TrimSpace(inner.sb.String())
+		switch {
+		case text == "":
+			return
+		case href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:"):
+			c.write(text)
+		default:
+			c.write("[" + text + "](" + href + ")")
+		}
+	case atom.Img:
+		c.image(n)
+	default:
+		c.walkChildren(n)
+	}
+}
+
+func (c *mdConverter) wrapInline(n *html.Node, marker string) {
+	inner := &mdConverter{cidURL: c.cidURL}
+	inner.walkChildren(n)
+	text := inner.sb.String()
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		c.write(text)
+		return
+	}
+	// Keep surrounding spaces outside the markers so "**a** b" stays valid.
+	if strings.HasPrefix(text, " ") {
+		c.write(" ")
+	}
+	c.write(marker + trimmed + marker)
+	if strings.HasSuffix(text, " ") {
+		c.write(" ")
+	}
+}
+
+func (c *mdConverter) image(n *html.Node) {
+	src := strings.TrimSpace(attr(n, "src"))
+	if src == "" {
+		return
+	}
+	// Tracking pixels.
+	if attr(n, "width") == "1" || attr(n, "height") == "1" {
+		return
+	}
+	if strings.HasPrefix(strings.ToLower(src), "cid:") {
+		if c.cidURL == nil {
+			return
+		}
+		if src = c.cidURL(src[len("cid:"):]); src == "" {
+			return
+		}
+	}
+	alt := strings.TrimSpace(attr(n, "alt"))
+	c.write("![" + alt + "](" + src + ")")
+}
+
+func (c *mdConverter) text(s string) {
+	if c.inPre {
+		c.write(s)
+		return
+	}
+	s = collapseSpace(s)
+	if s == "" {
+		return
+	}
+	// Avoid leading spaces at the start of a line.
+	if str := c.sb.String(); str == "" || strings.HasSuffix(str, "\n") || strings.HasSuffix(str, "> ") || strings.HasSuffix(str, " ") {
+		s = strings.TrimLeft(s, " ")
+	}
+	c.write(s)
+}
+
+// collapseSpace folds runs of whitespace into single spaces, keeping one
+// leading/trailing space so words in adjacent inline elements stay apart.
+func collapseSpace(s string) string {
+	fields := strings.FieldsFunc(s, isHTMLSpace)
+	if len(fields) == 0 {
+		if s == "" {
+			return ""
+		}
+		return " "
+	}
+	out := strings.Join(fields, " ")
+	if r := []rune(s); isHTMLSpace(r[0]) {
+		out = " " + out
+	}
+	if r := []rune(s); isHTMLSpace(r[len(r)-1]) {
+		out += " "
+	}
+	return out
+}
+
+func isHTMLSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f' || r == '\u00a0'
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		sb.WriteString(textContent(child))
+	}
+	return sb.String()
+}
@@ -0,0 +1,52 @@
+package inbound
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHTMLToMarkdown(t *testing.T) {
+	src := `<html><head><title>x</title><style>p{}</style></head><body>
+<table><tr><td>
+  <h2>Top   stories</h2>
+  <p>Read <a href="https://example.com/a">the <b>first</b> one</a> today.<br>Second line</p>
+  <ul><li>One</li><li>Two</li></ul>
+  <ol><li>First</li></ol>
+  <blockquote>Quoted</blockquote>
+  <pre><code>x := 1
+y := 2</code></pre>
+  <img src="https://t.example.com/open.gif" width="1" height="1">
+  <img src="cid:hero" alt="Hero">
+</td></tr></table>
+<script>alert(1)</script>
+</body></html>`
+
+	got, err := HTMLToMarkdown(src, func(cid string) string {
+		if cid == "hero" {
+			return "https://cdn.example.com/hero.png"
+		}
+		return ""
+	})
+	if err != nil {
+		t.Fatalf("HTMLToMarkdown: %v", err)
+	}
+
+	for _, want := range []string{
+		"## Top stories",
+		"Read [the **first** one](https://example.com/a) today.\nSecond line",
+		"- One\n- Two",
+		"1. First",
+		"> Quoted",
+		"```\nx := 1\ny := 2\n```",
+		"![Hero](https://cdn.example.com/hero.png)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q\n--- got ---\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"alert", "open.gif", "p{}", "\n\n\n"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("output should not contain %q\n--- got ---\n%s", unwanted, got)
+		}
+	}
+}
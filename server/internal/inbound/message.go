@@ -0,0 +1,260 @@
+// Package inbound turns raw RFC 822 messages (forwarded newsletters) into
+// markdown, and provides the SMTP listener used by APP_MODE=mail.
+package inbound
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	// maxMIMEDepth guards against pathological multipart nesting.
+	maxMIMEDepth = 10
+	// MaxAttachmentBytes is the largest single attachment that is kept.
+	MaxAttachmentBytes = 10 << 20 // 10 MB
+)
+
+// Message is the normalized content of an inbound email.
+type Message struct {
+	MessageID   string
+	Subject     string
+	FromName    string
+	FromAddress string
+	// ForwardedFrom is the original sender when the newsletter arrived as a
+	// message/rfc822 attachment.
+	ForwardedFrom string
+	// Recipients collects Delivered-To, X-Original-To, To and Cc addresses,
+	// used when the envelope recipients are not known (webhook delivery).
+	Recipients  []string
+	Date        *time.Time
+	TextBody    string
+	HTMLBody    string
+	Attachments []Attachment
+}
+
+// Attachment is a non-body MIME part. Inline parts referenced from the HTML
+// body carry a ContentID.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	ContentID   string
+	Inline      bool
+	Data        []byte
+}
+
+var wordDecoder = &mime.WordDecoder{CharsetReader: charset.NewReaderLabel}
+
+// reForwardPrefix matches the "Fwd:" style prefixes mail clients add when forwarding.
+var reForwardPrefix = regexp.MustCompile(`(?i)^\s*((fwd?|fw|转发|轉寄)\s*[:：]\s*)+`)
+
+// ParseMessage parses a raw RFC 822 message, walking multipart bodies and
+// decoding transfer encodings and charsets to UTF-8.
+func ParseMessage(raw []byte) (*Message, error) {
+	m, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("read message: %w", err)
+	}
+
+	msg := &Message{
+		MessageID: strings.Trim(strings.TrimSpace(m.Header.Get("Message-Id")), "<>"),
+		Subject:   decodeHeader(m.Header.Get("Subject")),
+	}
+	msg.Subject = strings.TrimSpace(reForwardPrefix.ReplaceAllString(msg.Subject, ""))
+
+	if from := m.Header.Get("From"); from != "" {
+		parser := &mail.AddressParser{WordDecoder: wordDecoder}
+		if addr, err := parser.Parse(from); err == nil {
+			msg.FromName = addr.Name
+			msg.FromAddress = strings.ToLower(addr.Address)
+		}
+	}
+	if d, err := m.Header.Date(); err == nil {
+		msg.Date = &d
+	}
+	for _, key := range []string{"Delivered-To", "X-Original-To", "To", "Cc"} {
+		for _, v := range m.Header[key] {
+			addrs, err := (&mail.AddressParser{WordDecoder: wordDecoder}).ParseList(v)
+			if err != nil {
+				continue
+			}
+			for _, a := range addrs {
+				msg.Recipients = append(msg.Recipients, strings.ToLower(a.Address))
+			}
+		}
+	}
+
+	if err := msg.walkPart(mailHeader(m.Header), m.Body, 0); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// partHeader is the subset of header access shared by mail.Header and
+// multipart part headers.
+type partHeader interface {
+	Get(key string) string
+}
+
+type mailHeader mail.Header
+
+func (h mailHeader) Get(key string) string { return mail.Header(h).Get(key) }
+
+func (msg *Message) walkPart(h partHeader, body io.Reader, depth int) error {
+	if depth > maxMIMEDepth {
+		return fmt.Errorf("mime nesting too deep")
+	}
+
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		boundary := params["boundary"]
+		if boundary == "" {
+			return fmt.Errorf("multipart without boundary")
+		}
+		mr := multipart.NewReader(body, boundary)
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("read multipart: %w", err)
+			}
+			if err := msg.walkPart(part.Header, part, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	if mediaType == "message/rfc822" {
+		// A forwarded message as attachment: its body is the newsletter, so it
+		// takes precedence over the forwarder's own note.
+		innerMail, err := mail.ReadMessage(decodeTransfer(h, body))
+		if err != nil {
+			return fmt.Errorf("read attached message: %w", err)
+		}
+		inner := &Message{}
+		if err := inner.walkPart(mailHeader(innerMail.Header), innerMail.Body, depth+1); err != nil {
+			return err
+		}
+		if subject := decodeHeader(innerMail.Header.Get("Subject")); subject != "" {
+			msg.Subject = strings.TrimSpace(reForwardPrefix.ReplaceAllString(subject, ""))
+		}
+		if addr, err := (&mail.AddressParser{WordDecoder: wordDecoder}).Parse(innerMail.Header.Get("From")); err == nil {
+			msg.ForwardedFrom = strings.ToLower(addr.Address)
+		}
+		if inner.HTMLBody != "" || inner.TextBody != "" {
+			msg.HTMLBody, msg.TextBody = inner.HTMLBody, inner.TextBody
+		}
+		msg.Attachments = append(msg.Attachments, inner.Attachments...)
+		return nil
+	}
+
+	disposition, dparams, _ := mime.ParseMediaType(h.Get("Content-Disposition"))
+	filename := decodeHeader(dparams["filename"])
+	if filename == "" {
+		filename = decodeHeader(params["name"])
+	}
+	isAttachment := disposition == "attachment" || (filename != "" && !strings.HasPrefix(mediaType, "text/"))
+
+	if !isAttachment && (mediaType == "text/html" || mediaType == "text/plain") {
+		text, err := readText(decodeTransfer(h, body), params["charset"])
+		if err != nil {
+			return err
+		}
+		// First body of each type wins; later ones are usually quoted replies.
+		if mediaType == "text/html" && msg.HTMLBody == "" {
+			msg.HTMLBody = text
+		} else if mediaType == "text/plain" && msg.TextBody == "" {
+			msg.TextBody = text
+		}
+		return nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(decodeTransfer(h, body), MaxAttachmentBytes+1))
+	if err != nil {
+		return fmt.Errorf("read attachment: %w", err)
+	}
+	if len(data) > MaxAttachmentBytes {
+		// Oversized attachments are dropped rather than failing the message.
+		return nil
+	}
+	msg.Attachments = append(msg.Attachments, Attachment{
+		Filename:    filename,
+		ContentType: mediaType,
+		ContentID:   strings.Trim(h.Get("Content-Id"), "<> "),
+		Inline:      disposition == "inline",
+		Data:        data,
+	})
+	return nil
+}
+
+// decodeTransfer wraps body with the reader for its Content-Transfer-Encoding.
+func decodeTransfer(h partHeader, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(h.Get("Content-Transfer-Encoding"))) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &base64Cleaner{r: body})
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	default:
+		return body
+	}
+}
+
+// base64Cleaner strips line breaks and whitespace, which base64.NewDecoder
+// only partially tolerates.
+type base64Cleaner struct {
+	r io.Reader
+}
+
+func (c *base64Cleaner) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	out := 0
+	for i := 0; i < n; i++ {
+		switch p[i] {
+		case '\r', '\n', ' ', '\t':
+			continue
+		}
+		p[out] = p[i]
+		out++
+	}
+	return out, err
+}
+
+func readText(r io.Reader, label string) (string, error) {
+	if label != "" && !strings.EqualFold(label, "utf-8") && !strings.EqualFold(label, "us-ascii") {
+		if cr, err := charset.NewReaderLabel(label, r); err == nil {
+			r = cr
+		}
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("read body: %w", err)
+	}
+	return strings.ToValidUTF8(string(data), "�"), nil
+}
+
+func decodeHeader(s string) string {
+	if s == "" {
+		return ""
+	}
+	decoded, err := wordDecoder.DecodeHeader(s)
+	if err != nil {
+		return s
+	}
+	return decoded
+}
@@ -0,0 +1,147 @@
+package inbound
+
+import (
+	"strings"
+	"testing"
+)
+
+func crlf(s string) []byte {
+	return []byte(strings.ReplaceAll(s, "\n", "\r\n"))
+}
+
+func TestParseMessage_MultipartAlternativeWithAttachment(t *testing.T) {
+	raw := crlf(`From: "Weekly Digest" <News@Example.com>
+To: abc123@in.example.com
+Cc: Someone <other@example.org>
+Subject: =?UTF-8?B?RndkOiDmr4/lkajnsr7pgIk=?=
+Message-ID: <msg-1@example.com>
+Date: Mon, 02 Jan 2006 15:04:05 +0000
+MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="outer"
+
+--outer
+Content-Type: multipart/alternative; boundary="alt"
+
+--alt
+Content-Type: text/plain; charset=utf-8
+
+Plain version
+--alt
+Content-Type: text/html; charset=utf-8
+Content-Transfer-Encoding: quoted-printable
+
+<p>Hello =E4=B8=96=E7=95=8C</p>
+--alt--
+--outer
+Content-Type: application/pdf; name="report.pdf"
+Content-Disposition: attachment; filename="report.pdf"
+Content-Transfer-Encoding: base64
+
+JVBERi0x
+LjQK
+--outer--
+`)
+
+	msg, err := ParseMessage(raw)
+	if err != nil {
+		t.Fatalf("ParseMessage: %v", err)
+	}
+	if msg.MessageID != "msg-1@example.com" {
+		t.Errorf("MessageID = %q", msg.MessageID)
+	}
+	if msg.Subject != "每周精选" {
+		t.Errorf("Subject = %q, want forward prefix stripped", msg.Subject)
+	}
+	if msg.FromAddress != "news@example.com" || msg.FromName != "Weekly Digest" {
+		t.Errorf("From = %q <%s>", msg.FromName, msg.FromAddress)
+	}
+	if len(msg.Recipients) != 2 || msg.Recipients[0] != "abc123@in.example.com" {
+		t.Errorf("Recipients = %v", msg.Recipients)
+	}
+	if msg.Date == nil {
+		t.Error("expected Date to be parsed")
+	}
+	if strings.TrimSpace(msg.TextBody) != "Plain version" {
+		t.Errorf("TextBody = %q", msg.TextBody)
+	}
+	if !strings.Contains(msg.HTMLBody, "Hello 世界") {
+		t.Errorf("HTMLBody = %q, want decoded quoted-printable", msg.HTMLBody)
+	}
+	if len(msg.Attachments) != 1 {
+		t.Fatalf("got %d attachments, want 1", len(msg.Attachments))
+	}
+	a := msg.Attachments[0]
+	if a.Filename != "report.pdf" || a.ContentType != "application/pdf" || string(a.Data) != "%PDF-1.4\n" {
+		t.Errorf("attachment = %+v", a)
+	}
+}
+
+func TestParseMessage_ForwardedAsAttachment(t *testing.T) {
+	raw := crlf(`From: me@example.com
+To: abc123@in.example.com
+Subject: Fwd: ignored
+Content-Type: multipart/mixed; boundary="b"
+
+--b
+Content-Type: text/plain
+
+See below.
+--b
+Content-Type: message/rfc822
+
+From: Newsletter <letters@substack.example>
+Subject: Issue #42
+Content-Type: text/html; charset=iso-8859-1
+Content-Transfer-Encoding: quoted-printable
+
+<h1>Caf=E9</h1>
+--b--
+`)
+
+	msg, err := ParseMessage(raw)
+	if err != nil {
+		t.Fatalf("ParseMessage: %v", err)
+	}
+	if msg.Subject != "Issue #42" {
+		t.Errorf("Subject = %q, want inner subject", msg.Subject)
+	}
+	if msg.ForwardedFrom != "letters@substack.example" {
+		t.Errorf("ForwardedFrom = %q", msg.ForwardedFrom)
+	}
+	if !strings.Contains(msg.HTMLBody, "Café") {
+		t.Errorf("HTMLBody = %q, want charset-decoded inner body", msg.HTMLBody)
+	}
+	if msg.TextBody != "" {
+		t.Errorf("TextBody = %q, want forwarder note replaced", msg.TextBody)
+	}
+}
+
+func TestParseMessage_InlineImage(t *testing.T) {
+	raw := crlf(`From: a@example.com
+Content-Type: multipart/related; boundary="r"
+
+--r
+Content-Type: text/html
+
+<img src="cid:logo@x">
+--r
+Content-Type: image/png
+Content-ID: <logo@x>
+Content-Disposition: inline; filename="logo.png"
+Content-Transfer-Encoding: base64
+
+iVBORw0K
+--r--
+`)
+
+	msg, err := ParseMessage(raw)
+	if err != nil {
+		t.Fatalf("ParseMessage: %v", err)
+	}
+	if len(msg.Attachments) != 1 {
+		t.Fatalf("got %d attachments, want 1", len(msg.Attachments))
+	}
+	if a := msg.Attachments[0]; a.ContentID != "logo@x" || !a.Inline {
+		t.Errorf("attachment = %+v", a)
+	}
+}
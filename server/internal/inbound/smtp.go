@@ -0,0 +1,337 @@
+package inbound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxMessageBytes is the largest message accepted over SMTP or the webhook.
+	MaxMessageBytes    = 25 << 20 // 25 MB
+	smtpMaxRecipients  = 20
+	smtpCommandTimeout = 5 * time.Minute
+	smtpDeliverTimeout = 2 * time.Minute
+)
+
+// Deliverer receives messages accepted by SMTPServer.
+type Deliverer interface {
+	// AcceptRecipient reports whether mail for addr should be accepted at RCPT time.
+	AcceptRecipient(ctx context.Context, addr string) bool
+	// Deliver ingests one message. Returning a *RejectError produces a
+	// permanent 5xx reply; any other error is reported as temporary (4xx).
+	Deliver(ctx context.Context, from string, to []string, raw []byte) error
+}
+
+// RejectError is a permanent delivery failure.
+type RejectError struct {
+	Reason string
+}
+
+func (e *RejectError) Error() string { return e.Reason }
+
+// SMTPServer is a minimal receive-only SMTP server (RFC 5321 subset: no
+// AUTH, no relaying). It is meant to sit behind an MX relay or load balancer
+// that terminates TLS.
+type SMTPServer struct {
+	addr      string
+	hostname  string
+	deliverer Deliverer
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	wg       sync.WaitGroup
+	closed   bool
+}
+
+func NewSMTPServer(addr, hostname string, deliverer Deliverer) *SMTPServer {
+	return &SMTPServer{
+		addr:      addr,
+		hostname:  hostname,
+		deliverer: deliverer,
+		conns:     make(map[net.Conn]struct{}),
+	}
+}
+
+// ListenAndServe listens on the configured address and serves until Shutdown.
+func (s *SMTPServer) ListenAndServe() error {
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("smtp listen: %w", err)
+	}
+	return s.Serve(ln)
+}
+
+// Serve accepts connections on ln until Shutdown is called.
+func (s *SMTPServer) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	s.listener = ln
+	s.mu.Unlock()
+
+	slog.Info("smtp server listening", "addr", ln.Addr().String())
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return nil
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			return fmt.Errorf("smtp accept: %w", err)
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handleConn(conn)
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// Shutdown stops accepting connections and waits for in-flight sessions
+// until ctx expires, after which remaining connections are closed.
+func (s *SMTPServer) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		for c := range s.conns {
+			c.Close()
+		}
+		s.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+type smtpSession struct {
+	server *SMTPServer
+	conn   net.Conn
+	tp     *textproto.Conn
+	remote string
+
+	helo     string
+	mailSeen bool
+	from     string
+	to       []string
+}
+
+func (s *SMTPServer) handleConn(conn net.Conn) {
+	defer conn.Close()
+
+	sess := &smtpSession{
+		server: s,
+		conn:   conn,
+		tp:     textproto.NewConn(conn),
+		remote: conn.RemoteAddr().String(),
+	}
+	sess.reply(220, s.hostname+" ESMTP Folio ready")
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(smtpCommandTimeout))
+		line, err := sess.tp.ReadLine()
+		if err != nil {
+			if err != io.EOF {
+				slog.Debug("smtp read failed", "remote", sess.remote, "error", err)
+			}
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		if quit := sess.handle(strings.ToUpper(verb), strings.TrimSpace(arg)); quit {
+			return
+		}
+	}
+}
+
+func (sess *smtpSession) reply(code int, lines ...string) {
+	for i, l := range lines {
+		sep := " "
+		if i < len(lines)-1 {
+			sep = "-"
+		}
+		if err := sess.tp.PrintfLine("%d%s%s", code, sep, l); err != nil {
+			return
+		}
+	}
+}
+
+func (sess *smtpSession) reset() {
+	sess.mailSeen = false
+	sess.from = ""
+	sess.to = nil
+}
+
+// handle processes one command and reports whether the session should end.
+func (sess *smtpSession) handle(verb, arg string) bool {
+	switch verb {
+	case "HELO":
+		sess.helo = arg
+		sess.reset()
+		sess.reply(250, sess.server.hostname)
+	case "EHLO":
+		sess.helo = arg
+		sess.reset()
+		sess.reply(250,
+			sess.server.hostname,
+			"SIZE "+strconv.Itoa(MaxMessageBytes),
+			"8BITMIME",
+		)
+	case "MAIL":
+		if sess.helo == "" {
+			sess.reply(503, "5.5.1 Send HELO/EHLO first")
+			return false
+		}
+		addr, params, ok := parsePath(arg, "FROM:")
+		if !ok {
+			sess.reply(501, "5.5.4 Syntax: MAIL FROM:<address>")
+			return false
+		}
+		if size, ok := params["SIZE"]; ok {
+			if n, err := strconv.Atoi(size); err == nil && n > MaxMessageBytes {
+				sess.reply(552, "5.3.4 Message too large")
+				return false
+			}
+		}
+		sess.reset()
+		sess.mailSeen = true
+		sess.from = addr
+		sess.reply(250, "2.1.0 OK")
+	case "RCPT":
+		if !sess.mailSeen {
+			sess.reply(503, "5.5.1 Send MAIL first")
+			return false
+		}
+		addr, _, ok := parsePath(arg, "TO:")
+		if !ok || addr == "" {
+			sess.reply(501, "5.5.4 Syntax: RCPT TO:<address>")
+			return false
+		}
+		if len(sess.to) >= smtpMaxRecipients {
+			sess.reply(452, "4.5.3 Too many recipients")
+			return false
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		accepted := sess.server.deliverer.AcceptRecipient(ctx, addr)
+		cancel()
+		if !accepted {
+			sess.reply(550, "5.1.1 No such mailbox")
+			return false
+		}
+		sess.to = append(sess.to, addr)
+		sess.reply(250, "2.1.5 OK")
+	case "DATA":
+		if len(sess.to) == 0 {
+			sess.reply(503, "5.5.1 Send RCPT first")
+			return false
+		}
+		sess.reply(354, "Start mail input; end with <CRLF>.<CRLF>")
+		sess.data()
+		sess.reset()
+	case "RSET":
+		sess.reset()
+		sess.reply(250, "2.0.0 OK")
+	case "NOOP":
+		sess.reply(250, "2.0.0 OK")
+	case "VRFY":
+		sess.reply(252, "2.5.0 Cannot VRFY user")
+	case "QUIT":
+		sess.reply(221, "2.0.0 Bye")
+		return true
+	default:
+		sess.reply(502, "5.5.2 Command not implemented")
+	}
+	return false
+}
+
+func (sess *smtpSession) data() {
+	dr := sess.tp.DotReader()
+	raw, err := io.ReadAll(io.LimitReader(dr, MaxMessageBytes+1))
+	if err != nil {
+		sess.reply(451, "4.3.0 Error reading message")
+		return
+	}
+	if len(raw) > MaxMessageBytes {
+		// Drain the rest so the session stays in sync.
+		io.Copy(io.Discard, dr)
+		sess.reply(552, "5.3.4 Message too large")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), smtpDeliverTimeout)
+	defer cancel()
+
+	err = sess.server.deliverer.Deliver(ctx, sess.from, sess.to, raw)
+	var rej *RejectError
+	switch {
+	case err == nil:
+		sess.reply(250, "2.0.0 Message accepted")
+	case errors.As(err, &rej):
+		slog.Info("smtp message rejected", "remote", sess.remote, "from", sess.from, "reason", rej.Reason)
+		sess.reply(550, "5.7.1 "+rej.Reason)
+	default:
+		slog.Error("smtp delivery failed", "remote", sess.remote, "from", sess.from, "error", err)
+		sess.reply(451, "4.3.0 Temporary failure, try again later")
+	}
+}
+
+// parsePath parses "FROM:<addr> KEY=VAL ..." (prefix is "FROM:" or "TO:").
+// The null reverse-path "<>" yields an empty address.
+func parsePath(arg, prefix string) (addr string, params map[string]string, ok bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", nil, false
+	}
+	rest := strings.TrimSpace(arg[len(prefix):])
+	if !strings.HasPrefix(rest, "<") {
+		return "", nil, false
+	}
+	end := strings.Index(rest, ">")
+	if end < 0 {
+		return "", nil, false
+	}
+	addr = strings.TrimSpace(rest[1:end])
+	// Strip a source route (@a,@b:user@host).
+	if i := strings.LastIndex(addr, ":"); i >= 0 && strings.HasPrefix(addr, "@") {
+		addr = addr[i+1:]
+	}
+
+	params = map[string]string{}
+	for _, p := range strings.Fields(rest[end+1:]) {
+		k, v, _ := strings.Cut(p, "=")
+		params[strings.ToUpper(k)] = v
+	}
+	return addr, params, true
+}
@@ -0,0 +1,108 @@
+package inbound
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeDeliverer struct {
+	mu        sync.Mutex
+	known     map[string]bool
+	rejectAll bool
+	from      string
+	to        []string
+	raw       []byte
+}
+
+func (d *fakeDeliverer) AcceptRecipient(_ context.Context, addr string) bool {
+	return d.known[addr]
+}
+
+func (d *fakeDeliverer) Deliver(_ context.Context, from string, to []string, raw []byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.rejectAll {
+		return &RejectError{Reason: "sender not allowed"}
+	}
+	d.from, d.to, d.raw = from, to, raw
+	return nil
+}
+
+func startTestSMTP(t *testing.T, d Deliverer) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := NewSMTPServer("", "in.example.com", d)
+	go srv.Serve(ln)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	})
+	return ln.Addr().String()
+}
+
+const testMessage = "From: news@example.com\r\nSubject: Hello\r\n\r\nBody line\r\n.leading dot\r\n"
+
+func TestSMTPServer_DeliversMessage(t *testing.T) {
+	d := &fakeDeliverer{known: map[string]bool{"abc@in.example.com": true}}
+	addr := startTestSMTP(t, d)
+
+	err := smtp.SendMail(addr, nil, "news@example.com", []string{"abc@in.example.com"}, []byte(testMessage))
+	if err != nil {
+		t.Fatalf("SendMail: %v", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.from != "news@example.com" || len(d.to) != 1 || d.to[0] != "abc@in.example.com" {
+		t.Errorf("envelope = %q -> %v", d.from, d.to)
+	}
+	if !strings.Contains(string(d.raw), "Body line\n.leading dot") {
+		t.Errorf("raw = %q, want dot-unstuffed body", d.raw)
+	}
+}
+
+func TestSMTPServer_RejectsUnknownRecipient(t *testing.T) {
+	d := &fakeDeliverer{known: map[string]bool{}}
+	addr := startTestSMTP(t, d)
+
+	err := smtp.SendMail(addr, nil, "news@example.com", []string{"nobody@in.example.com"}, []byte(testMessage))
+	var perr *textproto.Error
+	if err == nil || !errors.As(err, &perr) || perr.Code != 550 {
+		t.Fatalf("expected 550 for unknown recipient, got %v", err)
+	}
+}
+
+func TestSMTPServer_PermanentRejection(t *testing.T) {
+	d := &fakeDeliverer{known: map[string]bool{"abc@in.example.com": true}, rejectAll: true}
+	addr := startTestSMTP(t, d)
+
+	err := smtp.SendMail(addr, nil, "spam@example.com", []string{"abc@in.example.com"}, []byte(testMessage))
+	var perr *textproto.Error
+	if err == nil || !errors.As(err, &perr) || perr.Code != 550 {
+		t.Fatalf("expected 550 rejection, got %v", err)
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	addr, params, ok := parsePath("FROM:<a@b.com> SIZE=1000 BODY=8BITMIME", "FROM:")
+	if !ok || addr != "a@b.com" || params["SIZE"] != "1000" {
+		t.Errorf("got %q %v %v", addr, params, ok)
+	}
+	if addr, _, ok := parsePath("from:<>", "FROM:"); !ok || addr != "" {
+		t.Errorf("null path: got %q %v", addr, ok)
+	}
+	if _, _, ok := parsePath("TO:a@b.com", "TO:"); ok {
+		t.Error("expected missing brackets to fail")
+	}
+}
@@ -0,0 +1,556 @@
+// Package pdf extracts plain text from PDF documents, page by page. It is a
+// small pure-Go reader aimed at text-based papers and reports: it recovers
+// objects by scanning the file (so damaged cross-reference tables do not
+// matter), supports object streams, Flate/ASCIIHex/ASCII85 filters and
+// ToUnicode CMaps, and ignores everything that is not text.
+package pdf
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"context"
+	"encoding/ascii85"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+)
+
+var (
+	// ErrNotPDF is returned when the data does not start with a PDF header.
+	ErrNotPDF = errors.New("not a PDF file")
+	// ErrEncrypted is returned for password-protected or encrypted files.
+	ErrEncrypted = errors.New("encrypted PDF")
+	// ErrNoPages is returned when no page tree can be found.
+	ErrNoPages = errors.New("PDF has no pages")
+	// ErrTooComplex is returned when extraction would take more work than
+	// any real document needs, such as forms that draw each other thousands
+	// of times.
+	ErrTooComplex = errors.New("PDF too complex to extract")
+)
+
+const (
+	maxDecodedStreamBytes = 64 << 20 // 64 MB per stream
+	maxPages              = 5000
+
+	// The work budget for one document: bytes decoded from all streams, and
+	// content stream tokens interpreted plus string bytes shown, counting a
+	// form again each time it is drawn. Text-heavy pages use a few thousand
+	// tokens each.
+	maxDecodedBytes  = 512 << 20
+	maxContentTokens = 5_000_000
+
+	// ctxCheckInterval is how many budget charges pass between checks of
+	// the context.
+	ctxCheckInterval = 4096
+)
+
+type document struct {
+	data    []byte
+	objects map[int]object
+	trailer dict
+
+	ctx     context.Context
+	decoded int   // bytes decoded so far
+	tokens  int   // content stream tokens and shown bytes so far
+	charges int   // budget charges since the context was last checked
+	err     error // why extraction stopped early; nil while within budget
+}
+
+var (
+	reObjHeader = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+	reTrailer   = regexp.MustCompile(`trailer\s*<<`)
+)
+
+// IsPDF reports whether data starts with a PDF header (allowing leading
+// garbage, which some generators emit).
+func IsPDF(data []byte) bool {
+	head := data
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	return bytes.Contains(head, []byte("%PDF-"))
+}
+
+func load(ctx context.Context, data []byte) (*document, error) {
+	if !IsPDF(data) {
+		return nil, ErrNotPDF
+	}
+	d := &document{data: data, objects: make(map[int]object), ctx: ctx}
+	d.scanObjects()
+	d.expandObjectStreams()
+	d.findTrailer()
+
+	if d.err != nil {
+		return nil, d.err
+	}
+	if d.trailer == nil {
+		return nil, ErrNoPages
+	}
+	if _, ok := d.trailer["Encrypt"]; ok {
+		return nil, ErrEncrypted
+	}
+	return d, nil
+}
+
+// scanObjects finds every "N G obj" definition in file order, so objects
+// redefined by incremental updates end up with their latest version.
+func (d *document) scanObjects() {
+	pos := 0
+	for pos < len(d.data) {
+		loc := reObjHeader.FindSubmatchIndex(d.data[pos:])
+		if loc == nil {
+			return
+		}
+		start := pos + loc[0]
+		if start > 0 && !isSpace(d.data[start-1]) && !isDelim(d.data[start-1]) {
+			pos = start + 1
+			continue
+		}
+		num, _ := strconv.Atoi(string(d.data[pos+loc[2] : pos+loc[3]]))
+		l := &lexer{data: d.data, pos: pos + loc[1], refs: true}
+		obj, err := l.readObject()
+		if err != nil {
+			pos += loc[1]
+			continue
+		}
+
+		save := l.pos
+		if tok, err := l.next(); err == nil && tok == keyword("stream") {
+			if hdr, ok := obj.(dict); ok {
+				obj, l.pos = d.readStream(hdr, l.pos)
+			}
+		} else {
+			l.pos = save
+		}
+		d.objects[num] = obj
+		pos = l.pos
+	}
+}
+
+// readStream returns the stream starting right after the "stream" keyword
+// and the position after "endstream".
+func (d *document) readStream(hdr dict, pos int) (*stream, int) {
+	if pos < len(d.data) && d.data[pos] == '\r' {
+		pos++
+	}
+	if pos < len(d.data) && d.data[pos] == '\n' {
+		pos++
+	}
+	end := -1
+	if n, ok := hdr["Length"].(int64); ok && n >= 0 && pos+int(n) <= len(d.data) {
+		// Trust a direct /Length only when "endstream" follows it.
+		tail := d.data[pos+int(n):]
+		if len(tail) > 32 {
+			tail = tail[:32]
+		}
+		if bytes.Contains(tail, []byte("endstream")) {
+			end = pos + int(n)
+		}
+	}
+	if end < 0 {
+		i := bytes.Index(d.data[pos:], []byte("endstream"))
+		if i < 0 {
+			return &stream{hdr: hdr, data: d.data[pos:]}, len(d.data)
+		}
+		end = pos + i
+		// Drop the EOL that precedes "endstream".
+		for end > pos && (d.data[end-1] == '\n' || d.data[end-1] == '\r') {
+			end--
+		}
+	}
+	after := end + bytes.Index(d.data[end:], []byte("endstream")) + len("endstream")
+	return &stream{hdr: hdr, data: d.data[pos:end]}, after
+}
+
+// expandObjectStreams adds objects stored inside /Type /ObjStm streams.
+// Objects defined directly in the file take precedence.
+func (d *document) expandObjectStreams() {
+	var objStms []*stream
+	for _, obj := range d.objects {
+		if s, ok := obj.(*stream); ok && s.hdr["Type"] == name("ObjStm") {
+			objStms = append(objStms, s)
+		}
+	}
+	for _, s := range objStms {
+		data, err := d.decodeStream(s)
+		if err != nil {
+			continue
+		}
+		n, _ := d.resolve(s.hdr["N"]).(int64)
+		first, _ := d.resolve(s.hdr["First"]).(int64)
+		if n <= 0 || first <= 0 || int(first) > len(data) {
+			continue
+		}
+		header := &lexer{data: data[:first]}
+		for i := int64(0); i < n; i++ {
+			numTok, err1 := header.next()
+			offTok, err2 := header.next()
+			num, ok1 := numTok.(int64)
+			off, ok2 := offTok.(int64)
+			if err1 != nil || err2 != nil || !ok1 || !ok2 {
+				break
+			}
+			if _, exists := d.objects[int(num)]; exists {
+				continue
+			}
+			l := &lexer{data: data, pos: int(first + off), refs: true}
+			if l.pos > len(data) {
+				continue
+			}
+			if obj, err := l.readObject(); err == nil {
+				d.objects[int(num)] = obj
+			}
+		}
+	}
+}
+
+// findTrailer uses the last classic trailer or cross-reference stream that
+// names a /Root, falling back to any /Type /Catalog object.
+func (d *document) findTrailer() {
+	for _, loc := range reTrailer.FindAllIndex(d.data, -1) {
+		l := &lexer{data: d.data, pos: loc[1] - 2, refs: true}
+		if obj, err := l.readObject(); err == nil {
+			if t, ok := obj.(dict); ok && t["Root"] != nil {
+				d.trailer = t
+			}
+		}
+	}
+	if d.trailer != nil {
+		return
+	}
+	for _, obj := range d.objects {
+		if s, ok := obj.(*stream); ok && s.hdr["Type"] == name("XRef") && s.hdr["Root"] != nil {
+			d.trailer = s.hdr
+			return
+		}
+	}
+	for num, obj := range d.objects {
+		if c, ok := obj.(dict); ok && c["Type"] == name("Catalog") {
+			d.trailer = dict{"Root": ref{num: num}}
+			return
+		}
+	}
+}
+
+func (d *document) resolve(o object) object {
+	for i := 0; i < 32; i++ {
+		r, ok := o.(ref)
+		if !ok {
+			return o
+		}
+		o = d.objects[r.num]
+	}
+	return nil
+}
+
+func (d *document) dict(o object) dict {
+	switch v := d.resolve(o).(type) {
+	case dict:
+		return v
+	case *stream:
+		return v.hdr
+	}
+	return nil
+}
+
+func (d *document) array(o object) array {
+	a, _ := d.resolve(o).(array)
+	return a
+}
+
+func (d *document) number(o object) (float64, bool) {
+	switch v := d.resolve(o).(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// charge counts decoded bytes and interpreted tokens against the work
+// budget. It returns false, with d.err set, once the budget is spent or the
+// context is done; extraction then stops.
+func (d *document) charge(decoded, tokens int) bool {
+	if d.err != nil {
+		return false
+	}
+	d.decoded += decoded
+	d.tokens += tokens
+	if d.decoded > maxDecodedBytes || d.tokens > maxContentTokens {
+		d.err = ErrTooComplex
+		return false
+	}
+	if d.charges++; d.charges >= ctxCheckInterval {
+		d.charges = 0
+		if err := d.ctx.Err(); err != nil {
+			d.err = err
+			return false
+		}
+	}
+	return true
+}
+
+// decodeStream applies the stream's filters.
+func (d *document) decodeStream(s *stream) ([]byte, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	data := s.data
+	filters := d.resolve(s.hdr["Filter"])
+	params := d.resolve(s.hdr["DecodeParms"])
+
+	var names []name
+	var parms []dict
+	switch f := filters.(type) {
+	case name:
+		names = []name{f}
+		parms = []dict{d.dict(params)}
+	case array:
+		pa := d.array(params)
+		for i, v := range f {
+			n, _ := d.resolve(v).(name)
+			names = append(names, n)
+			var p dict
+			if i < len(pa) {
+				p = d.dict(pa[i])
+			}
+			parms = append(parms, p)
+		}
+	}
+
+	for i, f := range names {
+		var err error
+		switch f {
+		case "FlateDecode", "Fl":
+			data, err = inflate(data)
+			if err == nil {
+				data, err = d.unpredict(data, parms[i])
+			}
+		case "ASCIIHexDecode", "AHx":
+			data = asciiHexDecode(data)
+		case "ASCII85Decode", "A85":
+			data, err = ascii85Decode(data)
+		default:
+			return nil, fmt.Errorf("unsupported filter %s", f)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !d.charge(len(data), 0) {
+		return nil, d.err
+	}
+	return data, nil
+}
+
+// inflate decompresses zlib data, falling back to raw deflate and keeping
+// whatever was decoded before a corrupt or truncated tail.
+func inflate(data []byte) ([]byte, error) {
+	var r io.ReadCloser
+	if zr, err := zlib.NewReader(bytes.NewReader(data)); err == nil {
+		r = zr
+	} else {
+		r = flate.NewReader(bytes.NewReader(data))
+	}
+	defer r.Close()
+
+	out, err := io.ReadAll(io.LimitReader(r, maxDecodedStreamBytes))
+	if err != nil && len(out) == 0 {
+		return nil, fmt.Errorf("inflate: %w", err)
+	}
+	return out, nil
+}
+
+// unpredict reverses PNG predictors (Predictor >= 10), used mostly by
+// cross-reference and object streams.
+func (d *document) unpredict(data []byte, p dict) ([]byte, error) {
+	if p == nil {
+		return data, nil
+	}
+	pred, _ := d.number(p["Predictor"])
+	if pred < 10 {
+		return data, nil
+	}
+	colors, bpc, columns := 1.0, 8.0, 1.0
+	if v, ok := d.number(p["Colors"]); ok {
+		colors = v
+	}
+	if v, ok := d.number(p["BitsPerComponent"]); ok {
+		bpc = v
+	}
+	if v, ok := d.number(p["Columns"]); ok {
+		columns = v
+	}
+	bpp := int((colors*bpc + 7) / 8)
+	rowLen := int((colors*bpc*columns + 7) / 8)
+	if bpp < 1 || rowLen < 1 {
+		return nil, fmt.Errorf("invalid predictor parameters")
+	}
+
+	out := make([]byte, 0, len(data))
+	prev := make([]byte, rowLen)
+	for i := 0; i < len(data); i += rowLen + 1 {
+		ft := data[i]
+		row := make([]byte, rowLen)
+		copy(row, data[i+1:min(i+1+rowLen, len(data))])
+		for j := range row {
+			var left, up, upLeft byte
+			if j >= bpp {
+				left = row[j-bpp]
+				upLeft = prev[j-bpp]
+			}
+			up = prev[j]
+			switch ft {
+			case 1:
+				row[j] += left
+			case 2:
+				row[j] += up
+			case 3:
+				row[j] += byte((int(left) + int(up)) / 2)
+			case 4:
+				row[j] += paeth(left, up, upLeft)
+			}
+		}
+		out = append(out, row...)
+		prev = row
+	}
+	return out, nil
+}
+
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	}
+	return c
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func asciiHexDecode(data []byte) []byte {
+	out := make([]byte, 0, len(data)/2)
+	var hi byte
+	half := false
+	for _, c := range data {
+		if c == '>' {
+			break
+		}
+		v, ok := unhex(c)
+		if !ok {
+			continue
+		}
+		if half {
+			out = append(out, hi<<4|v)
+		} else {
+			hi = v
+		}
+		half = !half
+	}
+	if half {
+		out = append(out, hi<<4)
+	}
+	return out
+}
+
+func ascii85Decode(data []byte) ([]byte, error) {
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~"))
+	if i := bytes.Index(data, []byte("~>")); i >= 0 {
+		data = data[:i]
+	}
+	out, err := io.ReadAll(ascii85.NewDecoder(bytes.NewReader(data)))
+	if err != nil {
+		return nil, fmt.Errorf("ascii85: %w", err)
+	}
+	return out, nil
+}
+
+type page struct {
+	dict      dict
+	resources dict
+}
+
+// pages walks the page tree in order, resolving inherited /Resources.
+func (d *document) pages() []page {
+	root := d.dict(d.trailer["Root"])
+	if root == nil {
+		return nil
+	}
+	var out []page
+	visited := make(map[int]bool)
+	var walk func(node object, res dict, depth int)
+	walk = func(node object, res dict, depth int) {
+		if depth > maxObjectDepth || len(out) >= maxPages {
+			return
+		}
+		if r, ok := node.(ref); ok {
+			if visited[r.num] {
+				return
+			}
+			visited[r.num] = true
+		}
+		n := d.dict(node)
+		if n == nil {
+			return
+		}
+		if r := d.dict(n["Resources"]); r != nil {
+			res = r
+		}
+		if kids, ok := d.resolve(n["Kids"]).(array); ok {
+			for _, kid := range kids {
+				walk(kid, res, depth+1)
+			}
+			return
+		}
+		out = append(out, page{dict: n, resources: res})
+	}
+	walk(root["Pages"], nil, 0)
+	return out
+}
+
+// contents returns the page's decoded content streams, concatenated.
+func (d *document) contents(p page) []byte {
+	var parts []object
+	switch c := d.resolve(p.dict["Contents"]).(type) {
+	case *stream:
+		parts = []object{c}
+	case array:
+		parts = c
+	}
+	var buf bytes.Buffer
+	for _, part := range parts {
+		s, ok := d.resolve(part).(*stream)
+		if !ok {
+			continue
+		}
+		data, err := d.decodeStream(s)
+		if err != nil {
+			continue
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// info returns a text string from the document information dictionary.
+func (d *document) info(key name) string {
+	info := d.dict(d.trailer["Info"])
+	if info == nil {
+		return ""
+	}
+	s, _ := d.resolve(info[key]).(pdfString)
+	return decodeTextString(s)
+}
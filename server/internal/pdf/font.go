@@ -0,0 +1,450 @@
+package pdf
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/unicode/norm"
+)
+
+const maxCMapRange = 1 << 16
+
+// font maps character codes in shown strings to text and glyph widths.
+type font struct {
+	codespace []codeRange
+	toUnicode map[uint32]string
+	// encoding is the byte-to-rune table for simple fonts (nil for
+	// composite fonts).
+	encoding *[256]rune
+	// utf16 marks composite fonts whose CMap is UCS-2/UTF-16 (e.g.
+	// UniGB-UCS2-H), where codes are Unicode already.
+	utf16 bool
+
+	widths       map[uint32]float64 // glyph space, 1/1000 em
+	defaultWidth float64
+}
+
+type codeRange struct {
+	lo, hi []byte
+}
+
+type glyph struct {
+	code  uint32
+	text  string
+	width float64 // text space units (em)
+	space bool    // single-byte code 32, which gets word spacing
+}
+
+func (d *document) loadFont(o object) *font {
+	fd := d.dict(o)
+	f := &font{defaultWidth: 500}
+	if fd == nil {
+		f.codespace = []codeRange{{lo: []byte{0}, hi: []byte{0xff}}}
+		f.encoding = &standardTable
+		return f
+	}
+
+	composite := fd["Subtype"] == name("Type0")
+	if composite {
+		f.codespace = []codeRange{{lo: []byte{0, 0}, hi: []byte{0xff, 0xff}}}
+		f.defaultWidth = 1000
+		if enc, ok := d.resolve(fd["Encoding"]).(name); ok {
+			s := string(enc)
+			f.utf16 = strings.HasPrefix(s, "Uni") && (strings.Contains(s, "UCS2") || strings.Contains(s, "UTF16"))
+		}
+		if desc := d.array(fd["DescendantFonts"]); len(desc) > 0 {
+			d.loadCIDWidths(f, d.dict(desc[0]))
+		}
+	} else {
+		f.codespace = []codeRange{{lo: []byte{0}, hi: []byte{0xff}}}
+		f.encoding = d.simpleEncoding(fd)
+		d.loadSimpleWidths(f, fd)
+	}
+
+	if s, ok := d.resolve(fd["ToUnicode"]).(*stream); ok {
+		if data, err := d.decodeStream(s); err == nil {
+			f.parseCMap(data)
+		}
+	}
+	return f
+}
+
+func (d *document) loadSimpleWidths(f *font, fd dict) {
+	first, ok := d.number(fd["FirstChar"])
+	ws := d.array(fd["Widths"])
+	if !ok || ws == nil {
+		return
+	}
+	f.widths = make(map[uint32]float64, len(ws))
+	for i, w := range ws {
+		if v, ok := d.number(w); ok {
+			f.widths[uint32(first)+uint32(i)] = v
+		}
+	}
+}
+
+func (d *document) loadCIDWidths(f *font, cid dict) {
+	if cid == nil {
+		return
+	}
+	if dw, ok := d.number(cid["DW"]); ok {
+		f.defaultWidth = dw
+	}
+	w := d.array(cid["W"])
+	if w == nil {
+		return
+	}
+	f.widths = make(map[uint32]float64)
+	for i := 0; i < len(w); {
+		start, ok := d.number(w[i])
+		if !ok || i+1 >= len(w) {
+			return
+		}
+		if list := d.array(w[i+1]); list != nil {
+			for j, v := range list {
+				if n, ok := d.number(v); ok {
+					f.widths[uint32(start)+uint32(j)] = n
+				}
+			}
+			i += 2
+			continue
+		}
+		if i+2 >= len(w) {
+			return
+		}
+		end, ok1 := d.number(w[i+1])
+		width, ok2 := d.number(w[i+2])
+		if !ok1 || !ok2 || end < start || end-start > maxCMapRange {
+			return
+		}
+		for c := uint32(start); c <= uint32(end); c++ {
+			f.widths[c] = width
+		}
+		i += 3
+	}
+}
+
+// simpleEncoding builds the byte-to-rune table from /Encoding, applying
+// /Differences on top of the base encoding.
+func (d *document) simpleEncoding(fd dict) *[256]rune {
+	table := standardTable
+	switch enc := d.resolve(fd["Encoding"]).(type) {
+	case name:
+		table = *baseEncoding(enc)
+	case dict:
+		if base, ok := d.resolve(enc["BaseEncoding"]).(name); ok {
+			table = *baseEncoding(base)
+		}
+		code := 0
+		for _, v := range d.array(enc["Differences"]) {
+			switch x := d.resolve(v).(type) {
+			case int64:
+				code = int(x)
+			case name:
+				if code >= 0 && code < 256 {
+					if r, ok := glyphRune(string(x)); ok {
+						table[code] = r
+					}
+				}
+				code++
+			}
+		}
+	}
+	return &table
+}
+
+func baseEncoding(n name) *[256]rune {
+	switch n {
+	case "WinAnsiEncoding":
+		return &winAnsiTable
+	case "MacRomanEncoding":
+		return &macRomanTable
+	}
+	return &standardTable
+}
+
+var (
+	winAnsiTable  = charmapTable(charmap.Windows1252)
+	macRomanTable = charmapTable(charmap.Macintosh)
+	standardTable = func() [256]rune {
+		t := winAnsiTable
+		t['\''] = '’'
+		t['`'] = '‘'
+		return t
+	}()
+)
+
+func charmapTable(cm *charmap.Charmap) [256]rune {
+	var t [256]rune
+	for i := range t {
+		t[i] = cm.DecodeByte(byte(i))
+	}
+	return t
+}
+
+// parseCMap reads codespace ranges and bfchar/bfrange mappings from a
+// ToUnicode CMap.
+func (f *font) parseCMap(data []byte) {
+	l := &lexer{data: data}
+	var operands []object
+	for {
+		tok, err := l.next()
+		if err != nil {
+			break
+		}
+		kw, ok := tok.(keyword)
+		if !ok || kw == "[" {
+			if kw == "[" {
+				if v, err := l.parse(tok, 0); err == nil {
+					operands = append(operands, v)
+				}
+				continue
+			}
+			operands = append(operands, tok)
+			continue
+		}
+		switch kw {
+		case "endcodespacerange":
+			var ranges []codeRange
+			for i := 0; i+1 < len(operands); i += 2 {
+				lo, ok1 := operands[i].(pdfString)
+				hi, ok2 := operands[i+1].(pdfString)
+				if ok1 && ok2 && len(lo) == len(hi) && len(lo) > 0 && len(lo) <= 4 {
+					ranges = append(ranges, codeRange{lo: []byte(lo), hi: []byte(hi)})
+				}
+			}
+			if len(ranges) > 0 {
+				f.codespace = ranges
+			}
+		case "endbfchar":
+			f.ensureToUnicode()
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok := operands[i].(pdfString)
+				if !ok {
+					continue
+				}
+				if text, ok := cmapDest(operands[i+1]); ok {
+					f.toUnicode[codeValue([]byte(src))] = text
+				}
+			}
+		case "endbfrange":
+			f.ensureToUnicode()
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].(pdfString)
+				hi, ok2 := operands[i+1].(pdfString)
+				if !ok1 || !ok2 {
+					continue
+				}
+				start, end := codeValue([]byte(lo)), codeValue([]byte(hi))
+				if end < start || end-start > maxCMapRange {
+					continue
+				}
+				switch dst := operands[i+2].(type) {
+				case pdfString:
+					units := utf16BE([]byte(dst))
+					if len(units) == 0 {
+						continue
+					}
+					for c := start; c <= end; c++ {
+						u := append([]uint16(nil), units...)
+						u[len(u)-1] += uint16(c - start)
+						f.toUnicode[c] = string(utf16.Decode(u))
+					}
+				case array:
+					for j, v := range dst {
+						if c := start + uint32(j); c <= end {
+							if text, ok := cmapDest(v); ok {
+								f.toUnicode[c] = text
+							}
+						}
+					}
+				}
+			}
+		}
+		if strings.HasPrefix(string(kw), "end") || strings.HasPrefix(string(kw), "begin") {
+			operands = operands[:0]
+		}
+	}
+}
+
+func (f *font) ensureToUnicode() {
+	if f.toUnicode == nil {
+		f.toUnicode = make(map[uint32]string)
+	}
+}
+
+func cmapDest(o object) (string, bool) {
+	switch v := o.(type) {
+	case pdfString:
+		return string(utf16.Decode(utf16BE([]byte(v)))), true
+	case name:
+		if r, ok := glyphRune(string(v)); ok {
+			return string(r), true
+		}
+	}
+	return "", false
+}
+
+func utf16BE(b []byte) []uint16 {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+	if len(b)%2 == 1 {
+		units = append(units, uint16(b[len(b)-1]))
+	}
+	return units
+}
+
+func codeValue(b []byte) uint32 {
+	var v uint32
+	for _, c := range b {
+		v = v<<8 | uint32(c)
+	}
+	return v
+}
+
+// nextCode splits the next character code off s using the codespace ranges.
+func (f *font) nextCode(s []byte) (code uint32, n int) {
+	for _, r := range f.codespace {
+		n := len(r.lo)
+		if n > len(s) {
+			continue
+		}
+		match := true
+		for i := 0; i < n; i++ {
+			if s[i] < r.lo[i] || s[i] > r.hi[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return codeValue(s[:n]), n
+		}
+	}
+	// No range matched: consume the shortest code length.
+	n = len(f.codespace[0].lo)
+	for _, r := range f.codespace[1:] {
+		n = min(n, len(r.lo))
+	}
+	n = min(n, len(s))
+	return codeValue(s[:n]), n
+}
+
+// decode splits a shown string into glyphs with their text and widths.
+func (f *font) decode(s []byte) []glyph {
+	glyphs := make([]glyph, 0, len(s))
+	for len(s) > 0 {
+		code, n := f.nextCode(s)
+		g := glyph{code: code, space: n == 1 && code == 32}
+		if text, ok := f.toUnicode[code]; ok {
+			g.text = text
+		} else if f.encoding != nil && code < 256 {
+			if r := f.encoding[code]; r != 0 && r != '�' {
+				g.text = string(r)
+			}
+		} else if f.utf16 {
+			g.text = string(utf16.Decode(utf16BE(s[:n])))
+		}
+		w, ok := f.widths[code]
+		if !ok {
+			w = f.defaultWidth
+		}
+		g.width = w / 1000
+		glyphs = append(glyphs, g)
+		s = s[n:]
+	}
+	return glyphs
+}
+
+// decodeTextString decodes a PDF text string (UTF-16BE with BOM, UTF-8
+// with BOM, or PDFDocEncoding, approximated as Windows-1252).
+func decodeTextString(s pdfString) string {
+	b := []byte(s)
+	switch {
+	case bytes.HasPrefix(b, []byte{0xfe, 0xff}):
+		return string(utf16.Decode(utf16BE(b[2:])))
+	case bytes.HasPrefix(b, []byte{0xef, 0xbb, 0xbf}):
+		return string(b[3:])
+	}
+	var sb strings.Builder
+	for _, c := range b {
+		sb.WriteRune(winAnsiTable[c])
+	}
+	return sb.String()
+}
+
+// glyphRune maps an Adobe glyph name to a rune. It covers uniXXXX/uXXXX
+// names, the ASCII and Latin-1 names used by common encodings, and
+// accented letters composed from their base letter.
+func glyphRune(n string) (rune, bool) {
+	if r, ok := glyphNames[n]; ok {
+		return r, true
+	}
+	if len(n) == 1 && (n[0] >= 'a' && n[0] <= 'z' || n[0] >= 'A' && n[0] <= 'Z') {
+		return rune(n[0]), true
+	}
+	if strings.HasPrefix(n, "uni") && len(n) >= 7 {
+		if v, err := strconv.ParseUint(n[3:7], 16, 32); err == nil {
+			return rune(v), true
+		}
+	}
+	if strings.HasPrefix(n, "u") && len(n) >= 5 && len(n) <= 7 {
+		if v, err := strconv.ParseUint(n[1:], 16, 32); err == nil {
+			return rune(v), true
+		}
+	}
+	if base, n := n[:min(1, len(n))], n[min(1, len(n)):]; base != "" {
+		if mark, ok := accentMarks[n]; ok {
+			if composed := norm.NFC.String(base + string(mark)); len([]rune(composed)) == 1 {
+				return []rune(composed)[0], true
+			}
+		}
+	}
+	return 0, false
+}
+
+var accentMarks = map[string]rune{
+	"grave":        '̀',
+	"acute":        '́',
+	"circumflex":   '̂',
+	"tilde":        '̃',
+	"macron":       '̄',
+	"breve":        '̆',
+	"dotaccent":    '̇',
+	"dieresis":     '̈',
+	"ring":         '̊',
+	"hungarumlaut": '̋',
+	"caron":        '̌',
+	"cedilla":      '̧',
+	"ogonek":       '̨',
+}
+
+var glyphNames = map[string]rune{
+	"space": ' ', "exclam": '!', "quotedbl": '"', "numbersign": '#',
+	"dollar": '$', "percent": '%', "ampersand": '&', "quotesingle": '\'',
+	"quoteright": '’', "parenleft": '(', "parenright": ')', "asterisk": '*',
+	"plus": '+', "comma": ',', "hyphen": '-', "period": '.', "slash": '/',
+	"zero": '0', "one": '1', "two": '2', "three": '3', "four": '4',
+	"five": '5', "six": '6', "seven": '7', "eight": '8', "nine": '9',
+	"colon": ':', "semicolon": ';', "less": '<', "equal": '=', "greater": '>',
+	"question": '?', "at": '@', "bracketleft": '[', "backslash": '\\',
+	"bracketright": ']', "asciicircum": '^', "underscore": '_', "grave": '`',
+	"quoteleft": '‘', "braceleft": '{', "bar": '|', "braceright": '}',
+	"asciitilde": '~', "exclamdown": '¡', "cent": '¢', "sterling": '£',
+	"yen": '¥', "section": '§', "copyright": '©', "registered": '®',
+	"degree": '°', "plusminus": '±', "paragraph": '¶', "periodcentered": '·',
+	"guillemotleft": '«', "guillemotright": '»', "questiondown": '¿',
+	"multiply": '×', "divide": '÷', "germandbls": 'ß', "AE": 'Æ', "ae": 'æ',
+	"Oslash": 'Ø', "oslash": 'ø', "OE": 'Œ', "oe": 'œ', "dotlessi": 'ı',
+	"bullet": '•', "endash": '–', "emdash": '—', "ellipsis": '…',
+	"quotedblleft": '“', "quotedblright": '”', "quotesinglbase": '‚',
+	"quotedblbase": '„', "dagger": '†', "daggerdbl": '‡', "perthousand": '‰',
+	"trademark": '™', "Euro": '€', "minus": '−', "fi": 'ﬁ', "fl": 'ﬂ',
+	"ff": 'ﬀ', "ffi": 'ﬃ', "ffl": 'ﬄ', "nbspace": ' ', "mu": 'µ',
+	"fraction": '⁄', "florin": 'ƒ', "guilsinglleft": '‹', "guilsinglright": '›',
+	"onehalf": '½', "onequarter": '¼', "threequarters": '¾',
+}
@@ -0,0 +1,372 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// PDF object model. Strings keep their raw bytes; decoding depends on
+// where they are used (text strings vs. font-encoded content).
+type (
+	object  any
+	name    string
+	keyword string
+	array   []object
+	dict    map[name]object
+)
+
+type ref struct {
+	num, gen int
+}
+
+type stream struct {
+	hdr  dict
+	data []byte // raw, still filtered
+}
+
+type pdfString string
+
+const maxObjectDepth = 64
+
+var errSyntax = errors.New("pdf syntax error")
+
+// lexer tokenizes PDF syntax. With refs enabled, "N G R" sequences are
+// folded into ref values (object bodies); content streams disable it.
+type lexer struct {
+	data []byte
+	pos  int
+	refs bool
+}
+
+func isSpace(c byte) bool {
+	switch c {
+	case 0, '\t', '\n', '\f', '\r', ' ':
+		return true
+	}
+	return false
+}
+
+func isDelim(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+func (l *lexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isSpace(c) {
+			l.pos++
+			continue
+		}
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		return
+	}
+}
+
+// next returns the next token: a number, name, string, or keyword. The
+// delimiters "[", "]", "<<", ">>", "{" and "}" are returned as keywords.
+func (l *lexer) next() (object, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, io.EOF
+	}
+	c := l.data[l.pos]
+	switch c {
+	case '[', ']', '{', '}':
+		l.pos++
+		return keyword(c), nil
+	case '<':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '<' {
+			l.pos += 2
+			return keyword("<<"), nil
+		}
+		return l.hexString()
+	case '>':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '>' {
+			l.pos += 2
+			return keyword(">>"), nil
+		}
+		l.pos++
+		return nil, errSyntax
+	case '(':
+		return l.literalString()
+	case ')':
+		l.pos++
+		return nil, errSyntax
+	case '/':
+		return l.name(), nil
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && !isSpace(l.data[l.pos]) && !isDelim(l.data[l.pos]) {
+		l.pos++
+	}
+	tok := l.data[start:l.pos]
+	if num, ok := parseNumber(tok); ok {
+		return num, nil
+	}
+	return keyword(tok), nil
+}
+
+func parseNumber(tok []byte) (object, bool) {
+	if len(tok) == 0 {
+		return nil, false
+	}
+	for _, c := range tok {
+		if (c < '0' || c > '9') && c != '.' && c != '-' && c != '+' {
+			return nil, false
+		}
+	}
+	if i, err := strconv.ParseInt(string(tok), 10, 64); err == nil {
+		return i, true
+	}
+	if f, err := strconv.ParseFloat(string(tok), 64); err == nil {
+		return f, true
+	}
+	// Malformed numbers such as "--5" or "1.2.3" are read as 0, like most readers.
+	return int64(0), true
+}
+
+func (l *lexer) name() name {
+	l.pos++ // '/'
+	var b []byte
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isSpace(c) || isDelim(c) {
+			break
+		}
+		if c == '#' && l.pos+2 < len(l.data) {
+			if v, err := strconv.ParseUint(string(l.data[l.pos+1:l.pos+3]), 16, 8); err == nil {
+				b = append(b, byte(v))
+				l.pos += 3
+				continue
+			}
+		}
+		b = append(b, c)
+		l.pos++
+	}
+	return name(b)
+}
+
+func (l *lexer) hexString() (object, error) {
+	l.pos++ // '<'
+	var b []byte
+	var hi byte
+	half := false
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		if c == '>' {
+			if half {
+				b = append(b, hi<<4)
+			}
+			return pdfString(b), nil
+		}
+		v, ok := unhex(c)
+		if !ok {
+			continue
+		}
+		if half {
+			b = append(b, hi<<4|v)
+		} else {
+			hi = v
+		}
+		half = !half
+	}
+	return nil, errSyntax
+}
+
+func unhex(c byte) (byte, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0', true
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10, true
+	case c >= 'A' && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+func (l *lexer) literalString() (object, error) {
+	l.pos++ // '('
+	var b []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return pdfString(b), nil
+			}
+		case '\r':
+			// EOL in a literal string is always read as \n.
+			if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+				l.pos++
+			}
+			c = '\n'
+		case '\\':
+			if l.pos >= len(l.data) {
+				return nil, errSyntax
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+		b = append(b, c)
+	}
+	return nil, errSyntax
+}
+
+// readObject reads one complete object (arrays and dictionaries included).
+func (l *lexer) readObject() (object, error) {
+	tok, err := l.next()
+	if err != nil {
+		return nil, err
+	}
+	return l.parse(tok, 0)
+}
+
+func (l *lexer) parse(tok object, depth int) (object, error) {
+	if depth > maxObjectDepth {
+		return nil, fmt.Errorf("%w: nesting too deep", errSyntax)
+	}
+	switch t := tok.(type) {
+	case keyword:
+		switch t {
+		case "[":
+			arr := array{}
+			for {
+				tok, err := l.next()
+				if err != nil {
+					return nil, err
+				}
+				if tok == keyword("]") {
+					return arr, nil
+				}
+				v, err := l.parse(tok, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				arr = append(arr, v)
+			}
+		case "<<":
+			d := dict{}
+			for {
+				tok, err := l.next()
+				if err != nil {
+					return nil, err
+				}
+				if tok == keyword(">>") {
+					return d, nil
+				}
+				key, ok := tok.(name)
+				if !ok {
+					return nil, fmt.Errorf("%w: dictionary key is %T", errSyntax, tok)
+				}
+				vt, err := l.next()
+				if err != nil {
+					return nil, err
+				}
+				if vt == keyword(">>") {
+					// Missing value; treat as null and close.
+					d[key] = nil
+					return d, nil
+				}
+				v, err := l.parse(vt, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				d[key] = v
+			}
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return t, nil
+	case int64:
+		if !l.refs {
+			return t, nil
+		}
+		save := l.pos
+		if gen, err := l.next(); err == nil {
+			if g, ok := gen.(int64); ok {
+				if r, err := l.next(); err == nil && r == keyword("R") {
+					return ref{num: int(t), gen: int(g)}, nil
+				}
+			}
+		}
+		l.pos = save
+		return t, nil
+	}
+	return tok, nil
+}
+
+// skipInlineImage advances past the binary data of an inline image, which
+// starts after the "ID" operator and ends at a whitespace-delimited "EI".
+func (l *lexer) skipInlineImage() {
+	if l.pos < len(l.data) && isSpace(l.data[l.pos]) {
+		l.pos++
+	}
+	for {
+		i := bytes.Index(l.data[l.pos:], []byte("EI"))
+		if i < 0 {
+			l.pos = len(l.data)
+			return
+		}
+		end := l.pos + i
+		before := end == 0 || isSpace(l.data[end-1])
+		after := end+2 >= len(l.data) || isSpace(l.data[end+2]) || isDelim(l.data[end+2])
+		l.pos = end + 2
+		if before && after {
+			return
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pdfBuilder writes a minimal PDF with sequentially numbered objects.
+type pdfBuilder struct {
+	objs []string
+}
+
+func (b *pdfBuilder) add(body string) int {
+	b.objs = append(b.objs, body)
+	return len(b.objs)
+}
+
+func (b *pdfBuilder) addStream(hdr string, data []byte, compress bool) int {
+	if compress {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		zw.Write(data)
+		zw.Close()
+		data = buf.Bytes()
+		hdr += " /Filter /FlateDecode"
+	}
+	return b.add(fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", hdr, len(data), data))
+}
+
+func (b *pdfBuilder) bytes(trailer string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(b.objs))
+	for i, body := range b.objs {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, body)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(b.objs)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d %s >>\nstartxref\n%d\n%%%%EOF\n", len(b.objs)+1, trailer, xref)
+	return buf.Bytes()
+}
+
+const toUnicodeCMap = `/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+1 begincodespacerange
+<0000> <FFFF>
+endcodespacerange
+2 beginbfchar
+<0001> <4F60>
+<0002> <597D>
+endbfchar
+1 beginbfrange
+<0010> <0012> <0041>
+endbfrange
+endcmap
+end end`
+
+func buildTestPDF(t *testing.T) []byte {
+	t.Helper()
+	b := &pdfBuilder{}
+	b.add("<< /Type /Catalog /Pages 2 0 R >>")
+	b.add("<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 /Resources << /Font << /F1 5 0 R /F2 6 0 R >> >> >>")
+	b.add("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 8 0 R >>")
+	b.add("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 9 0 R >>")
+	b.add("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding << /BaseEncoding /WinAnsiEncoding /Differences [150 /endash 2 /fi] >> >>")
+	b.add("<< /Type /Font /Subtype /Type0 /BaseFont /Noto /Encoding /Identity-H /DescendantFonts [] /ToUnicode 7 0 R >>")
+	b.addStream("", []byte(toUnicodeCMap), false)
+
+	page1 := `BT /F1 12 Tf 72 720 Td (Deep Learning \226 A Survey) Tj ET
+BT /F1 10 Tf 72 690 Td [(Neural net)-20(works are ef)] TJ (\002cient) Tj
+0 -12 Td (and hy-) Tj 0 -12 Td (phenated words join.) Tj ET
+BT /F1 10 Tf 72 640 Td (Second) Tj 40 0 Td (paragraph.) Tj ET`
+	b.addStream("", []byte(page1), true)
+
+	page2 := `BT /F2 12 Tf 72 720 Td <00010002> Tj 0 -14 Td <001000110012> Tj ET`
+	b.addStream("", []byte(page2), true)
+	b.add("<< /Title (\xfe\xff\x00R\x00e\x00p\x00o\x00r\x00t) /Author (Ada) >>")
+
+	return b.bytes("/Root 1 0 R /Info 10 0 R")
+}
+
+func TestExtract(t *testing.T) {
+	doc, err := Extract(buildTestPDF(t))
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if doc.Title != "Report" || doc.Author != "Ada" {
+		t.Errorf("info = %q / %q", doc.Title, doc.Author)
+	}
+	if len(doc.Pages) != 2 {
+		t.Fatalf("got %d pages, want 2", len(doc.Pages))
+	}
+
+	want1 := "Deep Learning – A Survey\n\nNeural networks are efficient and hyphenated words join.\n\nSecond paragraph."
+	if doc.Pages[0] != want1 {
+		t.Errorf("page 1:\n got %q\nwant %q", doc.Pages[0], want1)
+	}
+	if want2 := "你好 ABC"; doc.Pages[1] != want2 {
+		t.Errorf("page 2 = %q, want %q", doc.Pages[1], want2)
+	}
+}
+
+func TestExtract_ObjectStreamAndMarkdown(t *testing.T) {
+	b := &pdfBuilder{}
+	b.add("<< /Type /Catalog /Pages 2 0 R >>")
+	b.add("<< /Type /Pages /Kids [3 0 R] /Count 1 >>")
+	b.add("<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 6 0 R >> >> /Contents 4 0 R >>")
+	b.addStream("", []byte("BT /F1 11 Tf 50 700 Td (Hello from an object stream) Tj ET"), true)
+	// Object 6 lives only inside the object stream (object 5).
+	inner := "<< /Type /Font /Subtype /Type1 /BaseFont /Times-Roman >>"
+	header := "6 0 "
+	b.addStream(fmt.Sprintf("/Type /ObjStm /N 1 /First %d", len(header)), []byte(header+inner), true)
+	data := b.bytes("/Root 1 0 R")
+
+	doc, err := Extract(data)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	md := doc.Markdown()
+	want := "<!-- page 1 -->\n\nHello from an object stream"
+	if md != want {
+		t.Errorf("Markdown = %q, want %q", md, want)
+	}
+}
+
+func TestExtract_Errors(t *testing.T) {
+	if _, err := Extract([]byte("hello")); !errors.Is(err, ErrNotPDF) {
+		t.Errorf("expected ErrNotPDF, got %v", err)
+	}
+
+	b := &pdfBuilder{}
+	b.add("<< /Type /Catalog /Pages 2 0 R >>")
+	b.add("<< /Type /Pages /Kids [] /Count 0 >>")
+	b.add("<< /Filter /Standard /V 2 >>")
+	if _, err := Extract(b.bytes("/Root 1 0 R /Encrypt 3 0 R")); !errors.Is(err, ErrEncrypted) {
+		t.Errorf("expected ErrEncrypted, got %v", err)
+	}
+}
+
+func TestPageAt(t *testing.T) {
+	doc := &Document{Pages: []string{"第一页内容", "", "Third page"}}
+	md := doc.Markdown()
+
+	tests := []struct {
+		needle string
+		want   int
+	}{
+		{"第一页", 1},
+		{"Third", 3},
+	}
+	for _, tt := range tests {
+		byteOff := strings.Index(md, tt.needle)
+		runeOff := len([]rune(md[:byteOff]))
+		if got := PageAt(md, runeOff); got != tt.want {
+			t.Errorf("PageAt(%q) = %d, want %d", tt.needle, got, tt.want)
+		}
+	}
+	if got := PageAt("no markers here", 3); got != 0 {
+		t.Errorf("PageAt without markers = %d, want 0", got)
+	}
+}
+
+// buildFormBombPDF returns a page that draws a form XObject fanOut times,
+// each form drawing the next one fanOut times, levels deep.
+func buildFormBombPDF(levels, fanOut int) []byte {
+	b := &pdfBuilder{}
+	b.add("<< /Type /Catalog /Pages 2 0 R >>")
+	b.add("<< /Type /Pages /Kids [3 0 R] /Count 1 >>")
+	// Forms are objects 5 to 4+levels; the page's content stream is 4.
+	b.add("<< /Type /Page /Parent 2 0 R /Resources << /XObject << /X 5 0 R >> >> /Contents 4 0 R >>")
+	draw := []byte(strings.Repeat("/X Do ", fanOut))
+	b.addStream("", draw, false)
+	for i := 0; i < levels; i++ {
+		next := fmt.Sprintf("/Resources << /XObject << /X %d 0 R >> >>", 6+i)
+		body := draw
+		if i == levels-1 {
+			next, body = "", []byte("BT (deep) Tj ET")
+		}
+		b.addStream("/Type /XObject /Subtype /Form "+next, body, true)
+	}
+	return b.bytes("/Root 1 0 R")
+}
+
+func TestExtract_WorkBudget(t *testing.T) {
+	if _, err := Extract(buildFormBombPDF(3, 4)); err != nil {
+		t.Fatalf("small nested forms: %v", err)
+	}
+
+	start := time.Now()
+	_, err := Extract(buildFormBombPDF(maxFormDepth, 64))
+	if !errors.Is(err, ErrTooComplex) {
+		t.Errorf("form bomb: err = %v, want ErrTooComplex", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("form bomb took %v", d)
+	}
+}
+
+func TestExtractContext_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ExtractContext(ctx, buildFormBombPDF(maxFormDepth, 64)); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,494 @@
+package pdf
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const maxFormDepth = 8
+
+// Document is the extracted text of a PDF.
+type Document struct {
+	Title  string
+	Author string
+	// Pages holds the text of each page; paragraphs are separated by a
+	// blank line. Pages without text are empty strings.
+	Pages []string
+}
+
+// Extract parses a PDF and returns the text of every page.
+func Extract(data []byte) (*Document, error) {
+	return ExtractContext(context.Background(), data)
+}
+
+// ExtractContext is like Extract but gives up with the context's error once
+// ctx is done. Either way, extraction stops with ErrTooComplex when the
+// document needs more work than the package allows.
+func ExtractContext(ctx context.Context, data []byte) (*Document, error) {
+	d, err := load(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	pages := d.pages()
+	if len(pages) == 0 {
+		return nil, ErrNoPages
+	}
+
+	doc := &Document{
+		Title:  strings.TrimSpace(d.info("Title")),
+		Author: strings.TrimSpace(d.info("Author")),
+		Pages:  make([]string, 0, len(pages)),
+	}
+	fonts := make(map[int]*font)
+	forms := make(map[int][]byte)
+	for _, p := range pages {
+		w := &textWriter{}
+		in := &interpreter{doc: d, fonts: fonts, forms: forms, out: w}
+		in.run(d.contents(p), p.resources, identity, 0)
+		if d.err != nil {
+			return nil, d.err
+		}
+		doc.Pages = append(doc.Pages, w.String())
+	}
+	return doc, nil
+}
+
+// HasText reports whether any page produced text. Scanned documents
+// (images only) have none.
+func (doc *Document) HasText() bool {
+	for _, p := range doc.Pages {
+		if strings.TrimSpace(p) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Markdown renders the document with a page marker line before each page,
+// so readers and highlights can map offsets back to page numbers.
+func (doc *Document) Markdown() string {
+	var sb strings.Builder
+	for i, p := range doc.Pages {
+		if i > 0 {
+			sb.WriteString("\n\n")
+		}
+		sb.WriteString(PageMarker(i + 1))
+		if p = strings.TrimSpace(p); p != "" {
+			sb.WriteString("\n\n")
+			sb.WriteString(p)
+		}
+	}
+	return sb.String()
+}
+
+// PageMarker returns the marker line that starts page n (1-based) in
+// Markdown output. It is an HTML comment, so markdown renderers hide it.
+func PageMarker(n int) string {
+	return fmt.Sprintf("<!-- page %d -->", n)
+}
+
+var rePageMarker = regexp.MustCompile(`<!-- page (\d+) -->`)
+
+// PageAt returns the page containing the given character (rune) offset of
+// markdown produced by Markdown, or 0 if the text has no page markers.
+func PageAt(markdown string, offset int) int {
+	page := 0
+	for _, loc := range rePageMarker.FindAllStringSubmatchIndex(markdown, -1) {
+		if utf8.RuneCountInString(markdown[:loc[0]]) > offset {
+			break
+		}
+		page, _ = strconv.Atoi(markdown[loc[2]:loc[3]])
+	}
+	return page
+}
+
+// matrix is a PDF transformation matrix [a b c d e f].
+type matrix [6]float64
+
+var identity = matrix{1, 0, 0, 1, 0, 0}
+
+func (m matrix) mul(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+type graphicsState struct {
+	ctm      matrix
+	font     *font
+	fontSize float64
+	charSp   float64
+	wordSp   float64
+	hScale   float64
+	leading  float64
+	rise     float64
+}
+
+type interpreter struct {
+	doc   *document
+	fonts map[int]*font
+	forms map[int][]byte // decoded form XObject content, by object number
+	out   *textWriter
+
+	gs    graphicsState
+	stack []graphicsState
+	tm    matrix
+	tlm   matrix
+}
+
+func (in *interpreter) run(content []byte, res dict, ctm matrix, depth int) {
+	in.gs = graphicsState{ctm: ctm, hScale: 1}
+	in.stack = nil
+	in.tm, in.tlm = identity, identity
+
+	fontRes := in.doc.dict(res["Font"])
+	l := &lexer{data: content}
+	var ops []object
+	for {
+		if !in.doc.charge(0, 1) {
+			return
+		}
+		tok, err := l.next()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			continue
+		}
+		kw, isKw := tok.(keyword)
+		if !isKw || kw == "[" || kw == "<<" {
+			if isKw {
+				v, err := l.parse(tok, 0)
+				if err != nil {
+					return
+				}
+				tok = v
+			}
+			ops = append(ops, tok)
+			continue
+		}
+
+		switch kw {
+		case "q":
+			in.stack = append(in.stack, in.gs)
+		case "Q":
+			if n := len(in.stack); n > 0 {
+				in.gs = in.stack[n-1]
+				in.stack = in.stack[:n-1]
+			}
+		case "cm":
+			if m, ok := in.matrixOperand(ops); ok {
+				in.gs.ctm = m.mul(in.gs.ctm)
+			}
+		case "BT":
+			in.tm, in.tlm = identity, identity
+		case "ET":
+		case "Tf":
+			if len(ops) >= 2 {
+				if n, ok := ops[len(ops)-2].(name); ok {
+					in.gs.font = in.font(fontRes[n])
+				}
+				in.gs.fontSize, _ = toFloat(ops[len(ops)-1])
+			}
+		case "Tc":
+			in.gs.charSp = lastFloat(ops)
+		case "Tw":
+			in.gs.wordSp = lastFloat(ops)
+		case "Tz":
+			in.gs.hScale = lastFloat(ops) / 100
+		case "TL":
+			in.gs.leading = lastFloat(ops)
+		case "Ts":
+			in.gs.rise = lastFloat(ops)
+		case "Td", "TD":
+			if len(ops) >= 2 {
+				tx, _ := toFloat(ops[len(ops)-2])
+				ty, _ := toFloat(ops[len(ops)-1])
+				if kw == "TD" {
+					in.gs.leading = -ty
+				}
+				in.moveLine(tx, ty)
+			}
+		case "Tm":
+			if m, ok := in.matrixOperand(ops); ok {
+				in.tm, in.tlm = m, m
+			}
+		case "T*":
+			in.moveLine(0, -in.gs.leading)
+		case "Tj":
+			if s, ok := lastString(ops); ok {
+				in.show(s)
+			}
+		case "'":
+			in.moveLine(0, -in.gs.leading)
+			if s, ok := lastString(ops); ok {
+				in.show(s)
+			}
+		case "\"":
+			if len(ops) >= 3 {
+				in.gs.wordSp, _ = toFloat(ops[len(ops)-3])
+				in.gs.charSp, _ = toFloat(ops[len(ops)-2])
+			}
+			in.moveLine(0, -in.gs.leading)
+			if s, ok := lastString(ops); ok {
+				in.show(s)
+			}
+		case "TJ":
+			if len(ops) > 0 {
+				if arr, ok := ops[len(ops)-1].(array); ok {
+					in.showArray(arr)
+				}
+			}
+		case "Do":
+			if len(ops) > 0 && depth < maxFormDepth {
+				if n, ok := ops[len(ops)-1].(name); ok {
+					in.doForm(res, n, depth)
+				}
+			}
+		case "ID":
+			l.skipInlineImage()
+		}
+		ops = ops[:0]
+	}
+}
+
+func (in *interpreter) font(o object) *font {
+	r, isRef := o.(ref)
+	if isRef {
+		if f, ok := in.fonts[r.num]; ok {
+			return f
+		}
+	}
+	f := in.doc.loadFont(o)
+	if isRef {
+		in.fonts[r.num] = f
+	}
+	return f
+}
+
+// doForm runs a form XObject's content with its own resources.
+func (in *interpreter) doForm(res dict, n name, depth int) {
+	o := in.doc.dict(res["XObject"])[n]
+	xobj, ok := in.doc.resolve(o).(*stream)
+	if !ok || xobj.hdr["Subtype"] != name("Form") {
+		return
+	}
+	r, isRef := o.(ref)
+	data, cached := in.forms[r.num]
+	if !isRef || !cached {
+		var err error
+		if data, err = in.doc.decodeStream(xobj); err != nil {
+			return
+		}
+		if isRef {
+			in.forms[r.num] = data
+		}
+	}
+	formRes := in.doc.dict(xobj.hdr["Resources"])
+	if formRes == nil {
+		formRes = res
+	}
+	ctm := in.gs.ctm
+	if m, ok := in.matrixOperand(in.doc.array(xobj.hdr["Matrix"])); ok {
+		ctm = m.mul(ctm)
+	}
+
+	saved, savedStack, savedTm, savedTlm := in.gs, in.stack, in.tm, in.tlm
+	in.run(data, formRes, ctm, depth+1)
+	in.gs, in.stack, in.tm, in.tlm = saved, savedStack, savedTm, savedTlm
+}
+
+func (in *interpreter) moveLine(tx, ty float64) {
+	in.tlm = matrix{1, 0, 0, 1, tx, ty}.mul(in.tlm)
+	in.tm = in.tlm
+}
+
+func (in *interpreter) showArray(arr array) {
+	for _, v := range arr {
+		switch x := v.(type) {
+		case pdfString:
+			in.show(x)
+		case int64, float64:
+			adj, _ := toFloat(x)
+			in.tm = matrix{1, 0, 0, 1, -adj / 1000 * in.gs.fontSize * in.gs.hScale, 0}.mul(in.tm)
+		}
+	}
+}
+
+func (in *interpreter) show(s pdfString) {
+	if !in.doc.charge(0, len(s)) {
+		return
+	}
+	f := in.gs.font
+	if f == nil {
+		f = in.doc.loadFont(nil)
+		in.gs.font = f
+	}
+	for _, g := range f.decode([]byte(s)) {
+		trm := matrix{in.gs.fontSize * in.gs.hScale, 0, 0, in.gs.fontSize, 0, in.gs.rise}.mul(in.tm).mul(in.gs.ctm)
+		size := math.Hypot(trm[2], trm[3])
+
+		tx := g.width*in.gs.fontSize + in.gs.charSp
+		if g.space {
+			tx += in.gs.wordSp
+		}
+		tx *= in.gs.hScale
+		end := matrix{1, 0, 0, 1, tx, 0}.mul(in.tm).mul(in.gs.ctm)
+
+		in.out.add(g.text, trm[4], trm[5], end[4], size)
+		in.tm = matrix{1, 0, 0, 1, tx, 0}.mul(in.tm)
+	}
+}
+
+func (in *interpreter) matrixOperand(ops []object) (matrix, bool) {
+	if len(ops) < 6 {
+		return matrix{}, false
+	}
+	var m matrix
+	for i, v := range ops[len(ops)-6:] {
+		f, ok := toFloat(in.doc.resolve(v))
+		if !ok {
+			return matrix{}, false
+		}
+		m[i] = f
+	}
+	return m, true
+}
+
+func toFloat(o object) (float64, bool) {
+	switch v := o.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func lastFloat(ops []object) float64 {
+	if len(ops) == 0 {
+		return 0
+	}
+	f, _ := toFloat(ops[len(ops)-1])
+	return f
+}
+
+func lastString(ops []object) (pdfString, bool) {
+	if len(ops) == 0 {
+		return "", false
+	}
+	s, ok := ops[len(ops)-1].(pdfString)
+	return s, ok
+}
+
+// textWriter assembles positioned glyphs into lines and paragraphs.
+// Lines are joined into paragraphs; a vertical gap noticeably larger than
+// the font size starts a new paragraph.
+type textWriter struct {
+	paragraphs []string
+	para       strings.Builder
+	line       strings.Builder
+
+	started    bool
+	lastX      float64 // end of the previous glyph
+	lastY      float64
+	lastSize   float64
+	lineHeight float64 // most recent baseline-to-baseline distance
+}
+
+var ligatures = strings.NewReplacer("ﬀ", "ff", "ﬁ", "fi", "ﬂ", "fl", "ﬃ", "ffi", "ﬄ", "ffl", "\u00a0", " ")
+
+func (w *textWriter) add(text string, x, y, endX, size float64) {
+	if text == "" {
+		return
+	}
+	text = ligatures.Replace(text)
+	if size <= 0 {
+		size = 1
+	}
+
+	if w.started {
+		dy := math.Abs(y - w.lastY)
+		switch {
+		case dy > 0.5*math.Max(size, w.lastSize):
+			// New line (or a jump elsewhere on the page).
+			gap := dy
+			paragraph := y > w.lastY || gap > 1.8*math.Max(size, w.lastSize) ||
+				(w.lineHeight > 0 && gap > 1.4*w.lineHeight)
+			if y < w.lastY {
+				w.lineHeight = gap
+			}
+			w.endLine(paragraph)
+		case x-w.lastX > 0.15*size || x < w.lastX-size:
+			// Horizontal gap on the same line, or text placed back to the left.
+			if w.line.Len() > 0 && !strings.HasSuffix(w.line.String(), " ") && !strings.HasPrefix(text, " ") {
+				w.line.WriteByte(' ')
+			}
+		}
+	}
+
+	w.line.WriteString(text)
+	w.started = true
+	w.lastX, w.lastY, w.lastSize = endX, y, size
+}
+
+func (w *textWriter) endLine(paragraph bool) {
+	line := strings.Join(strings.Fields(w.line.String()), " ")
+	w.line.Reset()
+	if line != "" {
+		cur := w.para.String()
+		switch {
+		case cur == "":
+		case strings.HasSuffix(cur, "-") && startsLower(line):
+			// Rejoin a word hyphenated across lines.
+			w.para.Reset()
+			w.para.WriteString(strings.TrimSuffix(cur, "-"))
+		case endsCJK(cur) && startsCJK(line):
+		default:
+			w.para.WriteByte(' ')
+		}
+		w.para.WriteString(line)
+	}
+	if paragraph && w.para.Len() > 0 {
+		w.paragraphs = append(w.paragraphs, w.para.String())
+		w.para.Reset()
+	}
+}
+
+func (w *textWriter) String() string {
+	w.endLine(true)
+	return strings.Join(w.paragraphs, "\n\n")
+}
+
+func startsLower(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return unicode.IsLower(r)
+}
+
+func startsCJK(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return isCJK(r)
+}
+
+func endsCJK(s string) bool {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return isCJK(r)
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r) ||
+		(r >= 0x3000 && r <= 0x303f) || (r >= 0xff00 && r <= 0xffef)
+}
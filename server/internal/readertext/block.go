@@ -0,0 +1,728 @@
+package readertext
+
+import (
+	"regexp"
+	"strings"
+)
+
+// blocks renders a sequence of block-level lines. Every block is followed
+// by a line break, as the reader's HTML has one after every closing block
+// tag.
+func (w *writer) blocks(lines []line) {
+	for i := 0; i < len(lines); {
+		l := lines[i]
+		if isBlank(l.span) {
+			i++
+			continue
+		}
+		if col, _ := indent(l.span); col >= 4 {
+			i = w.indentedCode(lines, i)
+			continue
+		}
+		if ch, n, ind, ok := fenceStart(l.span); ok {
+			i = w.fencedCode(lines, i, ch, n, ind)
+			continue
+		}
+		if content, ok := atxHeading(l.span); ok {
+			w.inline(content)
+			w.emit('\n', l.end)
+			i++
+			continue
+		}
+		if isThematicBreak(l.span) {
+			w.emit('\n', l.end)
+			i++
+			continue
+		}
+		if _, ok := blockquoteStart(l.span); ok {
+			i = w.blockquote(lines, i)
+			continue
+		}
+		if kind := htmlBlockStart(l.span); kind > 0 {
+			i = w.htmlBlock(lines, i, kind)
+			continue
+		}
+		if _, ok := listStart(l.span); ok {
+			i = w.list(lines, i)
+			continue
+		}
+		i = w.paragraph(lines, i)
+	}
+}
+
+// paragraph renders the paragraph starting at lines[i], or the setext
+// heading or table it turns out to be, and returns the index of the first
+// line after it.
+func (w *writer) paragraph(lines []line, i int) int {
+	j := i + 1
+	for ; j < len(lines); j++ {
+		l := lines[j]
+		if isBlank(l.span) {
+			break
+		}
+		if isSetextUnderline(l.span) {
+			w.paragraphText(lines[i:j], l.end)
+			return j + 1
+		}
+		if cells, ok := delimiterRow(l.span); ok && hasPipe(lines[j-1].span) && cells == len(splitCells(lines[j-1].span)) {
+			if j-1 > i {
+				w.paragraphText(lines[i:j-1], lines[j-2].end)
+			}
+			return w.table(lines, j-1)
+		}
+		if interrupts(l.span) {
+			break
+		}
+	}
+	w.paragraphText(lines[i:j], lines[j-1].end)
+	return j
+}
+
+// paragraphText renders the inline content of lines, which may start with
+// link reference definitions. Those display nothing.
+func (w *writer) paragraphText(lines []line, end int) {
+	for len(lines) > 0 {
+		label, ok := refDefinition(lines[0].span)
+		if !ok {
+			break
+		}
+		w.refs[label] = true
+		lines = lines[1:]
+	}
+	if len(lines) == 0 {
+		return
+	}
+
+	content := span{}
+	for k, l := range lines {
+		s := trimLeft(l.span)
+		if k == len(lines)-1 {
+			s = trimSpace(s)
+		} else {
+			s = trimRight(s)
+		}
+		if k > 0 {
+			content.r = append(content.r, '\n')
+			content.pos = append(content.pos, lines[k-1].end)
+		}
+		content.r = append(content.r, s.r...)
+		content.pos = append(content.pos, s.pos...)
+	}
+	w.inline(content)
+	w.emit('\n', end)
+}
+
+func (w *writer) fencedCode(lines []line, i int, ch rune, n, ind int) int {
+	body := make([]line, 0)
+	j := i + 1
+	for ; j < len(lines); j++ {
+		if isFenceClose(lines[j].span, ch, n) {
+			break
+		}
+		body = append(body, line{span: stripColumns(lines[j].span, ind), end: lines[j].end})
+	}
+	end := lines[len(lines)-1].end
+	if j < len(lines) {
+		end = lines[j].end
+		j++
+	}
+	w.code(body, end)
+	return j
+}
+
+func (w *writer) indentedCode(lines []line, i int) int {
+	body := make([]line, 0)
+	j := i
+	for ; j < len(lines); j++ {
+		l := lines[j]
+		if col, _ := indent(l.span); col < 4 && !isBlank(l.span) {
+			break
+		}
+		body = append(body, line{span: stripColumns(l.span, 4), end: l.end})
+	}
+	for len(body) > 0 && isBlank(body[len(body)-1].span) {
+		body = body[:len(body)-1]
+	}
+	w.code(body, body[len(body)-1].end)
+	return j
+}
+
+// code renders a code block: its lines verbatim, without the newlines at
+// either end, which the reader trims.
+func (w *writer) code(body []line, end int) {
+	s := span{}
+	for _, l := range body {
+		s.r = append(s.r, l.r...)
+		s.pos = append(s.pos, l.pos...)
+		s.r = append(s.r, '\n')
+		s.pos = append(s.pos, l.end)
+	}
+	a, b := 0, len(s.r)
+	for a < b && isNewline(s.r[a]) {
+		a++
+	}
+	for b > a && isNewline(s.r[b-1]) {
+		b--
+	}
+	w.emitSpan(s.slice(a, b))
+	w.emit('\n', end)
+}
+
+func (w *writer) blockquote(lines []line, i int) int {
+	inner := make([]line, 0)
+	j := i
+	for ; j < len(lines); j++ {
+		l := lines[j]
+		if rest, ok := blockquoteStart(l.span); ok {
+			inner = append(inner, line{span: rest, end: l.end})
+			continue
+		}
+		if isBlank(l.span) || isBlank(inner[len(inner)-1].span) || interrupts(l.span) {
+			break
+		}
+		// Lazy continuation of a quoted paragraph.
+		inner = append(inner, l)
+	}
+	w.blocks(inner)
+	w.emit('\n', lines[j-1].end)
+	return j
+}
+
+func (w *writer) list(lines []line, i int) int {
+	first, _ := listStart(lines[i].span)
+	last := i
+	for i < len(lines) {
+		m, ok := listStart(lines[i].span)
+		if !ok || !m.sameList(first) {
+			break
+		}
+		item := []line{{span: m.content, end: lines[i].end}}
+		j := i + 1
+		for ; j < len(lines); j++ {
+			l := lines[j]
+			if isBlank(l.span) {
+				if m.empty && j == i+1 {
+					break // an item starting with a blank line can't be followed by one
+				}
+				item = append(item, line{end: l.end})
+				continue
+			}
+			if col, _ := indent(l.span); col >= m.width {
+				item = append(item, line{span: stripColumns(l.span, m.width), end: l.end})
+				continue
+			}
+			if _, isItem := listStart(l.span); isItem || isBlank(lines[j-1].span) || interrupts(l.span) {
+				break
+			}
+			// Lazy continuation of the item's paragraph.
+			item = append(item, l)
+		}
+		w.blocks(item)
+		last = j - 1
+		for last > i && isBlank(lines[last].span) {
+			last--
+		}
+		w.emit('\n', lines[last].end)
+		i = j
+	}
+	w.emit('\n', lines[last].end)
+	return i
+}
+
+func (w *writer) htmlBlock(lines []line, i, kind int) int {
+	j := i
+	for ; j < len(lines); j++ {
+		l := lines[j]
+		if kind >= 6 {
+			if isBlank(l.span) {
+				break
+			}
+			continue
+		}
+		if htmlBlockEnded(string(l.r), kind) {
+			j++
+			break
+		}
+	}
+
+	s := span{}
+	for _, l := range lines[i:j] {
+		s.r = append(s.r, l.r...)
+		s.pos = append(s.pos, l.pos...)
+		s.r = append(s.r, '\n')
+		s.pos = append(s.pos, l.end)
+	}
+	w.htmlText(s)
+	return j
+}
+
+// htmlText renders raw HTML as its text: tags and comments display nothing
+// and entities are decoded.
+func (w *writer) htmlText(s span) {
+	r := s.r
+	for k := 0; k < len(r); {
+		switch {
+		case hasPrefixAt(r, k, "<!--"):
+			k = indexAfter(r, k+4, "-->")
+		case r[k] == '<' && k+1 < len(r) && (isASCIILetter(r[k+1]) || r[k+1] == '/' || r[k+1] == '!' || r[k+1] == '?'):
+			k = tagEnd(r, k)
+		case r[k] == '&':
+			if decoded, end, ok := entity(r, k); ok {
+				for _, d := range decoded {
+					w.emit(d, s.pos[k])
+				}
+				k = end
+				continue
+			}
+			w.emit(r[k], s.pos[k])
+			k++
+		default:
+			w.emit(r[k], s.pos[k])
+			k++
+		}
+	}
+}
+
+func (w *writer) table(lines []line, i int) int {
+	n := len(splitCells(lines[i].span))
+	w.cells(splitCells(lines[i].span), n)
+	j := i + 2
+	for ; j < len(lines); j++ {
+		l := lines[j]
+		if isBlank(l.span) || interrupts(l.span) {
+			break
+		}
+		w.cells(splitCells(l.span), n)
+	}
+	w.emit('\n', lines[j-1].end)
+	return j
+}
+
+// cells renders the first n cells of a table row. The reader's table has
+// no text between cells.
+func (w *writer) cells(cells []span, n int) {
+	for k := 0; k < n && k < len(cells); k++ {
+		w.inline(trimSpace(cells[k]))
+	}
+}
+
+// interrupts reports whether s starts a block that ends a paragraph.
+func interrupts(s span) bool {
+	if col, _ := indent(s); col >= 4 {
+		return false
+	}
+	if _, _, _, ok := fenceStart(s); ok {
+		return true
+	}
+	if _, ok := atxHeading(s); ok {
+		return true
+	}
+	if isThematicBreak(s) {
+		return true
+	}
+	if _, ok := blockquoteStart(s); ok {
+		return true
+	}
+	if kind := htmlBlockStart(s); kind > 0 && kind < 7 {
+		return true
+	}
+	if m, ok := listStart(s); ok && !m.empty && (m.bullet != 0 || m.start == 1) {
+		return true
+	}
+	return false
+}
+
+func isBlank(s span) bool {
+	for _, r := range s.r {
+		if r != ' ' && r != '\t' {
+			return false
+		}
+	}
+	return true
+}
+
+func isNewline(r rune) bool {
+	switch r {
+	case '\n', '\v', '\f', '\r', 0x85, 0x2028, 0x2029:
+		return true
+	}
+	return false
+}
+
+// indent returns the column of the first rune of s that is not a space or
+// tab, tabs advancing to the next multiple of 4, and that rune's index.
+func indent(s span) (col, idx int) {
+	for idx < len(s.r) {
+		switch s.r[idx] {
+		case ' ':
+			col++
+		case '\t':
+			col += 4 - col%4
+		default:
+			return col, idx
+		}
+		idx++
+	}
+	return col, idx
+}
+
+// stripColumns removes up to n columns of indentation from s.
+func stripColumns(s span, n int) span {
+	col, idx := 0, 0
+	for idx < len(s.r) && col < n {
+		switch s.r[idx] {
+		case ' ':
+			col++
+		case '\t':
+			col += 4 - col%4
+		default:
+			return s.slice(idx, len(s.r))
+		}
+		idx++
+	}
+	return s.slice(idx, len(s.r))
+}
+
+func trimLeft(s span) span {
+	i := 0
+	for i < len(s.r) && (s.r[i] == ' ' || s.r[i] == '\t') {
+		i++
+	}
+	return s.slice(i, len(s.r))
+}
+
+func trimRight(s span) span {
+	j := len(s.r)
+	for j > 0 && (s.r[j-1] == ' ' || s.r[j-1] == '\t') {
+		j--
+	}
+	return s.slice(0, j)
+}
+
+// fenceStart reports whether s opens a fenced code block, returning the
+// fence character, its length and the fence's indentation.
+func fenceStart(s span) (ch rune, n, ind int, ok bool) {
+	col, idx := indent(s)
+	if col > 3 || idx >= len(s.r) || (s.r[idx] != '`' && s.r[idx] != '~') {
+		return 0, 0, 0, false
+	}
+	ch = s.r[idx]
+	n = runLen(s.r, idx, ch)
+	if n < 3 {
+		return 0, 0, 0, false
+	}
+	if ch == '`' && strings.ContainsRune(string(s.r[idx+n:]), '`') {
+		return 0, 0, 0, false
+	}
+	return ch, n, col, true
+}
+
+func isFenceClose(s span, ch rune, n int) bool {
+	col, idx := indent(s)
+	if col > 3 || idx >= len(s.r) || s.r[idx] != ch {
+		return false
+	}
+	k := runLen(s.r, idx, ch)
+	return k >= n && isBlank(s.slice(idx+k, len(s.r)))
+}
+
+// atxHeading returns the content of an ATX heading line ("## Title").
+func atxHeading(s span) (span, bool) {
+	col, idx := indent(s)
+	if col > 3 || idx >= len(s.r) || s.r[idx] != '#' {
+		return span{}, false
+	}
+	n := runLen(s.r, idx, '#')
+	k := idx + n
+	if n > 6 || (k < len(s.r) && s.r[k] != ' ' && s.r[k] != '\t') {
+		return span{}, false
+	}
+	content := trimSpace(s.slice(k, len(s.r)))
+	// Drop a closing sequence of #s.
+	j := len(content.r)
+	for j > 0 && content.r[j-1] == '#' {
+		j--
+	}
+	if j == 0 || content.r[j-1] == ' ' || content.r[j-1] == '\t' {
+		content = trimSpace(content.slice(0, j))
+	}
+	return content, true
+}
+
+func isThematicBreak(s span) bool {
+	col, idx := indent(s)
+	if col > 3 || idx >= len(s.r) {
+		return false
+	}
+	ch := s.r[idx]
+	if ch != '-' && ch != '*' && ch != '_' {
+		return false
+	}
+	n := 0
+	for _, r := range s.r[idx:] {
+		switch r {
+		case ch:
+			n++
+		case ' ', '\t':
+		default:
+			return false
+		}
+	}
+	return n >= 3
+}
+
+func isSetextUnderline(s span) bool {
+	col, idx := indent(s)
+	if col > 3 || idx >= len(s.r) || (s.r[idx] != '=' && s.r[idx] != '-') {
+		return false
+	}
+	n := runLen(s.r, idx, s.r[idx])
+	return isBlank(s.slice(idx+n, len(s.r)))
+}
+
+// blockquoteStart returns the rest of a line that starts with ">", without
+// the marker and one following space.
+func blockquoteStart(s span) (span, bool) {
+	col, idx := indent(s)
+	if col > 3 || idx >= len(s.r) || s.r[idx] != '>' {
+		return span{}, false
+	}
+	idx++
+	if idx < len(s.r) && (s.r[idx] == ' ' || s.r[idx] == '\t') {
+		idx++
+	}
+	return s.slice(idx, len(s.r)), true
+}
+
+// listMarker describes the start of a list item.
+type listMarker struct {
+	bullet  rune // '-', '+' or '*'; 0 for ordered lists
+	delim   rune // '.' or ')' for ordered lists
+	start   int
+	width   int  // column where the item's content starts
+	empty   bool // nothing follows the marker
+	content span // the rest of the first line
+}
+
+func (m listMarker) sameList(o listMarker) bool {
+	return m.bullet == o.bullet && m.delim == o.delim
+}
+
+func listStart(s span) (listMarker, bool) {
+	var m listMarker
+	col, idx := indent(s)
+	if col > 3 || idx >= len(s.r) || isThematicBreak(s) {
+		return m, false
+	}
+	k := idx
+	switch r := s.r[k]; {
+	case r == '-' || r == '+' || r == '*':
+		m.bullet = r
+		k++
+	case r >= '0' && r <= '9':
+		for k < len(s.r) && k-idx < 9 && s.r[k] >= '0' && s.r[k] <= '9' {
+			m.start = m.start*10 + int(s.r[k]-'0')
+			k++
+		}
+		if k >= len(s.r) || (s.r[k] != '.' && s.r[k] != ')') {
+			return m, false
+		}
+		m.delim = s.r[k]
+		k++
+	default:
+		return m, false
+	}
+	markerEnd := col + k - idx
+
+	if k == len(s.r) || isBlank(s.slice(k, len(s.r))) {
+		m.empty = true
+		m.width = markerEnd + 1
+		return m, true
+	}
+	if s.r[k] != ' ' && s.r[k] != '\t' {
+		return m, false
+	}
+	spaces, p := 0, k
+	for p < len(s.r) && (s.r[p] == ' ' || s.r[p] == '\t') {
+		if s.r[p] == '\t' {
+			spaces += 4 - (markerEnd+spaces)%4
+		} else {
+			spaces++
+		}
+		p++
+	}
+	if spaces > 4 {
+		// The content is indented code; only one space belongs to the marker.
+		m.width = markerEnd + 1
+		m.content = s.slice(k+1, len(s.r))
+		return m, true
+	}
+	m.width = markerEnd + spaces
+	m.content = s.slice(p, len(s.r))
+	return m, true
+}
+
+var (
+	reHTMLType1 = regexp.MustCompile(`(?i)^<(script|pre|style|textarea)(\s|>|$)`)
+	reHTMLType6 = regexp.MustCompile(`(?i)^</?(address|article|aside|base|basefont|blockquote|body|caption|center|col|colgroup|dd|details|dialog|dir|div|dl|dt|fieldset|figcaption|figure|footer|form|frame|frameset|h1|h2|h3|h4|h5|h6|head|header|hr|html|iframe|legend|li|link|main|menu|menuitem|nav|noframes|ol|optgroup|option|p|param|search|section|summary|table|tbody|td|tfoot|th|thead|title|tr|track|ul)(\s|/?>|$)`)
+	reHTMLType7 = regexp.MustCompile(`^(<[A-Za-z][A-Za-z0-9-]*(\s+[A-Za-z_:][A-Za-z0-9_.:-]*(\s*=\s*([^\s"'=<>` + "`" + `]+|'[^']*'|"[^"]*"))?)*\s*/?>|</[A-Za-z][A-Za-z0-9-]*\s*>)\s*$`)
+)
+
+// htmlBlockEnded reports whether a line ends an HTML block of kind 1 to 5.
+func htmlBlockEnded(l string, kind int) bool {
+	switch kind {
+	case 1:
+		l = strings.ToLower(l)
+		return strings.Contains(l, "</script>") || strings.Contains(l, "</pre>") ||
+			strings.Contains(l, "</style>") || strings.Contains(l, "</textarea>")
+	case 2:
+		return strings.Contains(l, "-->")
+	case 3:
+		return strings.Contains(l, "?>")
+	case 4:
+		return strings.Contains(l, ">")
+	}
+	return strings.Contains(l, "]]>")
+}
+
+// htmlBlockStart returns the CommonMark kind (1-7) of the HTML block s
+// starts, or 0.
+func htmlBlockStart(s span) int {
+	col, idx := indent(s)
+	if col > 3 || idx >= len(s.r) || s.r[idx] != '<' {
+		return 0
+	}
+	rest := string(s.r[idx:])
+	switch {
+	case reHTMLType1.MatchString(rest):
+		return 1
+	case strings.HasPrefix(rest, "<!--"):
+		return 2
+	case strings.HasPrefix(rest, "<?"):
+		return 3
+	case strings.HasPrefix(rest, "<![CDATA["):
+		return 5
+	case len(rest) > 2 && rest[1] == '!' && isASCIILetter(rune(rest[2])):
+		return 4
+	case reHTMLType6.MatchString(rest):
+		return 6
+	case reHTMLType7.MatchString(rest):
+		return 7
+	}
+	return 0
+}
+
+var reRefDefinition = regexp.MustCompile(`^ {0,3}\[((?:[^\]\\]|\\.)+)\]:[ \t]*(?:<[^>]*>|\S+)(?:[ \t]+(?:"[^"]*"|'[^']*'|\([^)]*\)))?[ \t]*$`)
+
+// refDefinition returns the normalized label of a link reference
+// definition line.
+func refDefinition(s span) (string, bool) {
+	m := reRefDefinition.FindStringSubmatch(string(s.r))
+	if m == nil || strings.TrimSpace(m[1]) == "" {
+		return "", false
+	}
+	return normalizeLabel(m[1]), true
+}
+
+func normalizeLabel(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
+
+// delimiterRow reports whether s is the delimiter row under a table header
+// ("| --- | :-: |") and returns its number of cells.
+func delimiterRow(s span) (int, bool) {
+	if !strings.ContainsRune(string(s.r), '-') {
+		return 0, false
+	}
+	cells := splitCells(s)
+	for _, c := range cells {
+		t := strings.TrimSpace(string(c.r))
+		t = strings.TrimPrefix(strings.TrimSuffix(t, ":"), ":")
+		if t == "" || strings.Trim(t, "-") != "" {
+			return 0, false
+		}
+	}
+	return len(cells), len(cells) > 0
+}
+
+func hasPipe(s span) bool {
+	for i, r := range s.r {
+		if r == '|' && (i == 0 || s.r[i-1] != '\\') {
+			return true
+		}
+	}
+	return false
+}
+
+// splitCells splits a table row on unescaped pipes, dropping the optional
+// leading and trailing pipe.
+func splitCells(s span) []span {
+	s = trimSpace(s)
+	if len(s.r) > 0 && s.r[0] == '|' {
+		s = s.slice(1, len(s.r))
+	}
+	if n := len(s.r); n > 0 && s.r[n-1] == '|' && (n < 2 || s.r[n-2] != '\\') {
+		s = s.slice(0, n-1)
+	}
+	cells := make([]span, 0)
+	start := 0
+	for i, r := range s.r {
+		if r == '|' && (i == 0 || s.r[i-1] != '\\') {
+			cells = append(cells, s.slice(start, i))
+			start = i + 1
+		}
+	}
+	return append(cells, s.slice(start, len(s.r)))
+}
+
+func runLen(r []rune, i int, ch rune) int {
+	n := 0
+	for i+n < len(r) && r[i+n] == ch {
+		n++
+	}
+	return n
+}
+
+func hasPrefixAt(r []rune, i int, prefix string) bool {
+	for _, p := range prefix {
+		if i >= len(r) || r[i] != p {
+			return false
+		}
+		i++
+	}
+	return true
+}
+
+// indexAfter returns the index just past the first occurrence of sub in r
+// at or after i, or len(r).
+func indexAfter(r []rune, i int, sub string) int {
+	for ; i < len(r); i++ {
+		if hasPrefixAt(r, i, sub) {
+			return i + len([]rune(sub))
+		}
+	}
+	return len(r)
+}
+
+// tagEnd returns the index just past the tag starting at r[i], skipping
+// quoted attribute values.
+func tagEnd(r []rune, i int) int {
+	var quote rune
+	for k := i + 1; k < len(r); k++ {
+		switch {
+		case quote != 0:
+			if r[k] == quote {
+				quote = 0
+			}
+		case r[k] == '"' || r[k] == '\'':
+			quote = r[k]
+		case r[k] == '>':
+			return k + 1
+		}
+	}
+	return len(r)
+}
+
+func isASCIILetter(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
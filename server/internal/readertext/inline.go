@@ -0,0 +1,439 @@
+package readertext
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// tok is a displayed rune, or a run of emphasis delimiters (* _ ~) whose
+// characters display unless they are matched with another run.
+type tok struct {
+	r   rune
+	pos int
+
+	ch          rune  // delimiter character; 0 for a plain rune
+	run         []int // markdown offsets of the run's characters not yet matched
+	origLen     int
+	open, close bool
+	inactive    bool // can no longer be matched: a link or match enclosed it
+}
+
+// inline renders inline content: markup is dropped and the text it wraps
+// kept, as in the reader's HTML.
+func (w *writer) inline(s span) {
+	for _, t := range w.resolve(s) {
+		w.emit(t.r, t.pos)
+	}
+}
+
+// resolve renders s to plain runes, matching emphasis delimiters.
+func (w *writer) resolve(s span) []tok {
+	toks := w.tokens(s)
+	matchEmphasis(toks)
+
+	out := make([]tok, 0, len(toks))
+	for _, t := range toks {
+		if t.ch == 0 {
+			out = append(out, t)
+			continue
+		}
+		for _, p := range t.run {
+			out = append(out, tok{r: t.ch, pos: p})
+		}
+	}
+	return out
+}
+
+func (w *writer) tokens(s span) []tok {
+	r := s.r
+	toks := make([]tok, 0, len(r))
+	text := func(c rune, pos int) { toks = append(toks, tok{r: c, pos: pos}) }
+
+	for i := 0; i < len(r); {
+		c := r[i]
+		switch {
+		case c == '\\' && i+1 < len(r) && isASCIIPunct(r[i+1]):
+			text(r[i+1], s.pos[i+1])
+			i += 2
+
+		case c == '\\' && i+1 < len(r) && r[i+1] == '\n':
+			// Hard line break.
+			text('\n', s.pos[i+1])
+			i += 2
+
+		case c == '`':
+			n := runLen(r, i, '`')
+			end := closingBackticks(r, i+n, n)
+			if end < 0 {
+				for k := 0; k < n; k++ {
+					text('`', s.pos[i+k])
+				}
+				i += n
+				continue
+			}
+			code := s.slice(i+n, end)
+			if len(code.r) > 1 && isCodeSpanPadding(code.r[0]) && isCodeSpanPadding(code.r[len(code.r)-1]) && strings.TrimSpace(string(code.r)) != "" {
+				code = code.slice(1, len(code.r)-1)
+			}
+			for k, cr := range code.r {
+				if cr == '\n' {
+					cr = ' '
+				}
+				text(cr, code.pos[k])
+			}
+			i = end + n
+
+		case c == '*' || c == '_' || c == '~':
+			n := runLen(r, i, c)
+			toks = append(toks, delimiterRun(s, i, n)...)
+			i += n
+
+		case c == '!' && i+1 < len(r) && r[i+1] == '[':
+			if _, end, ok := w.link(s, i+1); ok {
+				// Images display no text; their alt text is an attribute.
+				i = end
+				continue
+			}
+			text('!', s.pos[i])
+			i++
+
+		case c == '[':
+			if inner, end, ok := w.link(s, i); ok {
+				toks = append(toks, w.resolve(inner)...)
+				i = end
+				continue
+			}
+			text('[', s.pos[i])
+			i++
+
+		case c == '<':
+			if from, to, end, ok := autolink(r, i); ok {
+				for k := from; k < to; k++ {
+					text(r[k], s.pos[k])
+				}
+				i = end
+				continue
+			}
+			if end, ok := inlineHTML(r, i); ok {
+				i = end
+				continue
+			}
+			text('<', s.pos[i])
+			i++
+
+		case c == '&':
+			if decoded, end, ok := entity(r, i); ok {
+				for _, d := range decoded {
+					text(d, s.pos[i])
+				}
+				i = end
+				continue
+			}
+			text('&', s.pos[i])
+			i++
+
+		default:
+			text(c, s.pos[i])
+			i++
+		}
+	}
+	return toks
+}
+
+func isCodeSpanPadding(r rune) bool { return r == ' ' || r == '\n' }
+
+// closingBackticks returns the index of the next run of exactly n backticks
+// at or after i, or -1.
+func closingBackticks(r []rune, i, n int) int {
+	for i < len(r) {
+		if r[i] != '`' {
+			i++
+			continue
+		}
+		k := runLen(r, i, '`')
+		if k == n {
+			return i
+		}
+		i += k
+	}
+	return -1
+}
+
+// delimiterRun classifies the run of n delimiter characters at s.r[i].
+func delimiterRun(s span, i, n int) []tok {
+	c := s.r[i]
+	if c == '~' && n > 2 {
+		toks := make([]tok, n)
+		for k := range toks {
+			toks[k] = tok{r: c, pos: s.pos[i+k]}
+		}
+		return toks
+	}
+
+	before, after := ' ', ' '
+	if i > 0 {
+		before = s.r[i-1]
+	}
+	if i+n < len(s.r) {
+		after = s.r[i+n]
+	}
+	left := !unicode.IsSpace(after) && (!isPunct(after) || unicode.IsSpace(before) || isPunct(before))
+	right := !unicode.IsSpace(before) && (!isPunct(before) || unicode.IsSpace(after) || isPunct(after))
+
+	t := tok{ch: c, run: append([]int(nil), s.pos[i:i+n]...), origLen: n, open: left, close: right}
+	if c == '_' {
+		t.open = left && (!right || isPunct(before))
+		t.close = right && (!left || isPunct(after))
+	}
+	return []tok{t}
+}
+
+// matchEmphasis pairs delimiter runs the way CommonMark does, removing the
+// matched characters from both runs. Unmatched characters display as is.
+func matchEmphasis(toks []tok) {
+	for c := range toks {
+		closer := &toks[c]
+		if closer.ch == 0 || !closer.close {
+			continue
+		}
+		for len(closer.run) > 0 {
+			o := -1
+			for k := c - 1; k >= 0; k-- {
+				opener := &toks[k]
+				if opener.ch != closer.ch || !opener.open || opener.inactive || len(opener.run) == 0 {
+					continue
+				}
+				if closer.ch == '~' {
+					if len(opener.run) != len(closer.run) {
+						continue
+					}
+				} else if (opener.close || closer.open) && (opener.origLen+closer.origLen)%3 == 0 &&
+					(opener.origLen%3 != 0 || closer.origLen%3 != 0) {
+					continue
+				}
+				o = k
+				break
+			}
+			if o < 0 {
+				break
+			}
+			opener := &toks[o]
+			n := 1
+			switch {
+			case closer.ch == '~':
+				n = len(closer.run)
+			case len(opener.run) >= 2 && len(closer.run) >= 2:
+				n = 2
+			}
+			opener.run = opener.run[:len(opener.run)-n]
+			closer.run = closer.run[n:]
+			for k := o + 1; k < c; k++ {
+				toks[k].inactive = true
+			}
+		}
+	}
+}
+
+// link parses a link or image starting with the "[" at s.r[i]: inline
+// ("[text](url)") or by reference to a definition. It returns the link
+// text and the index just past the link.
+func (w *writer) link(s span, i int) (span, int, bool) {
+	r := s.r
+	closeIdx := matchBracket(r, i)
+	if closeIdx < 0 {
+		return span{}, 0, false
+	}
+	inner := s.slice(i+1, closeIdx)
+	k := closeIdx + 1
+	if k < len(r) && r[k] == '(' {
+		if end, ok := linkTail(r, k); ok {
+			return inner, end, true
+		}
+	}
+	if k < len(r) && r[k] == '[' {
+		if c2 := matchBracket(r, k); c2 >= 0 {
+			label := string(r[k+1 : c2])
+			if strings.TrimSpace(label) == "" {
+				label = string(inner.r)
+			}
+			if w.refs[normalizeLabel(label)] {
+				return inner, c2 + 1, true
+			}
+		}
+	}
+	if w.refs[normalizeLabel(string(inner.r))] {
+		return inner, closeIdx + 1, true
+	}
+	return span{}, 0, false
+}
+
+// matchBracket returns the index of the "]" closing the "[" at r[i], or -1.
+func matchBracket(r []rune, i int) int {
+	depth := 0
+	for k := i; k < len(r); k++ {
+		switch r[k] {
+		case '\\':
+			k++
+		case '`':
+			n := runLen(r, k, '`')
+			if end := closingBackticks(r, k+n, n); end >= 0 {
+				k = end + n - 1
+			} else {
+				k += n - 1
+			}
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return k
+			}
+		}
+	}
+	return -1
+}
+
+// linkTail parses "(destination "title")" starting at the "(" at r[i] and
+// returns the index just past the ")".
+func linkTail(r []rune, i int) (int, bool) {
+	p := skipSpace(r, i+1)
+	if p < len(r) && r[p] == '<' {
+		for p++; p < len(r) && r[p] != '>'; p++ {
+			if r[p] == '\n' || r[p] == '<' {
+				return 0, false
+			}
+			if r[p] == '\\' {
+				p++
+			}
+		}
+		if p >= len(r) {
+			return 0, false
+		}
+		p++
+	} else {
+		depth := 0
+	dest:
+		for ; p < len(r); p++ {
+			switch c := r[p]; {
+			case c == '\\':
+				p++
+			case c == '(':
+				depth++
+			case c == ')':
+				if depth == 0 {
+					break dest
+				}
+				depth--
+			case unicode.IsSpace(c) || unicode.IsControl(c):
+				break dest
+			}
+		}
+	}
+	p = skipSpace(r, p)
+	if p < len(r) && (r[p] == '"' || r[p] == '\'' || r[p] == '(') {
+		closing := r[p]
+		if closing == '(' {
+			closing = ')'
+		}
+		for p++; p < len(r) && r[p] != closing; p++ {
+			if r[p] == '\\' {
+				p++
+			}
+		}
+		if p >= len(r) {
+			return 0, false
+		}
+		p = skipSpace(r, p+1)
+	}
+	if p >= len(r) || r[p] != ')' {
+		return 0, false
+	}
+	return p + 1, true
+}
+
+func skipSpace(r []rune, i int) int {
+	for i < len(r) && unicode.IsSpace(r[i]) {
+		i++
+	}
+	return i
+}
+
+var (
+	reAutolinkURI   = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.\-]{1,31}:[^\s<>]*$`)
+	reAutolinkEmail = regexp.MustCompile(`^[a-zA-Z0-9.!#$%&'*+/=?^_` + "`" + `{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`)
+	reInlineTag     = regexp.MustCompile(`^(?:<[A-Za-z][A-Za-z0-9-]*(?:\s+[A-Za-z_:][A-Za-z0-9_.:-]*(?:\s*=\s*(?:[^\s"'=<>` + "`" + `]+|'[^']*'|"[^"]*"))?)*\s*/?>|</[A-Za-z][A-Za-z0-9-]*\s*>)`)
+	reEntity        = regexp.MustCompile(`^&(?:#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[A-Za-z][A-Za-z0-9]{1,31});`)
+)
+
+// autolink parses "<https://example.com>" at r[i]. The link displays its
+// address, r[from:to].
+func autolink(r []rune, i int) (from, to, end int, ok bool) {
+	for k := i + 1; k < len(r); k++ {
+		switch {
+		case r[k] == '>':
+			s := string(r[i+1 : k])
+			if reAutolinkURI.MatchString(s) || reAutolinkEmail.MatchString(s) {
+				return i + 1, k, k + 1, true
+			}
+			return 0, 0, 0, false
+		case r[k] == '<' || unicode.IsSpace(r[k]):
+			return 0, 0, 0, false
+		}
+	}
+	return 0, 0, 0, false
+}
+
+// inlineHTML parses a raw HTML tag or comment at r[i], which displays no
+// text of its own.
+func inlineHTML(r []rune, i int) (int, bool) {
+	switch {
+	case hasPrefixAt(r, i, "<!--"):
+		if end := indexAfter(r, i+4, "-->"); end <= len(r) && hasSuffixBefore(r, end, "-->") {
+			return end, true
+		}
+	case hasPrefixAt(r, i, "<?"):
+		if end := indexAfter(r, i+2, "?>"); hasSuffixBefore(r, end, "?>") {
+			return end, true
+		}
+	case hasPrefixAt(r, i, "<![CDATA["):
+		if end := indexAfter(r, i+9, "]]>"); hasSuffixBefore(r, end, "]]>") {
+			return end, true
+		}
+	case i+2 < len(r) && r[i+1] == '!' && isASCIILetter(r[i+2]):
+		if end := indexAfter(r, i+2, ">"); hasSuffixBefore(r, end, ">") {
+			return end, true
+		}
+	default:
+		if m := reInlineTag.FindString(string(r[i:])); m != "" {
+			return i + len([]rune(m)), true
+		}
+	}
+	return 0, false
+}
+
+func hasSuffixBefore(r []rune, end int, suffix string) bool {
+	n := len([]rune(suffix))
+	return end >= n && hasPrefixAt(r, end-n, suffix)
+}
+
+// entity decodes an HTML entity or numeric character reference at r[i].
+func entity(r []rune, i int) ([]rune, int, bool) {
+	end := min(len(r), i+40)
+	m := reEntity.FindString(string(r[i:end]))
+	if m == "" {
+		return nil, 0, false
+	}
+	decoded := html.UnescapeString(m)
+	if decoded == m {
+		return nil, 0, false
+	}
+	return []rune(decoded), i + len([]rune(m)), true
+}
+
+func isASCIIPunct(r rune) bool {
+	return r < 0x80 && strings.ContainsRune("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", r)
+}
+
+func isPunct(r rune) bool { return unicode.IsPunct(r) || unicode.IsSymbol(r) }
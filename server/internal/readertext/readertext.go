@@ -0,0 +1,201 @@
+// Package readertext reproduces the text the iOS reader displays for an
+// article, so that offsets computed on the server are in the same units as
+// the highlight offsets the reader reports: UTF-16 code units into the
+// concatenated text nodes of the rendered article, with markdown syntax,
+// images and HTML comments (such as PDF page markers) gone and a line break
+// after every block.
+//
+// Rendering follows the reader's MarkdownToHTML for the markdown articles
+// are stored as: headings, paragraphs, block quotes, lists, code, tables,
+// HTML blocks, emphasis, links, images, code spans, escapes and entities.
+// It is not a complete CommonMark implementation; unusual constructs may
+// shift offsets by a few characters.
+package readertext
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Text is the text the reader displays for an article, with a map back to
+// the markdown it was rendered from.
+type Text struct {
+	runes []rune
+	src   []int // src[i] is the markdown rune offset runes[i] was rendered from; non-decreasing
+	units []int // units[i] is the UTF-16 offset runes[i] starts at; the extra last entry is the length
+	mdLen int   // markdown length in runes
+}
+
+// Render renders markdown the way the reader does for an article with the
+// given title.
+func Render(markdown, title string) *Text {
+	runes := []rune(markdown)
+	pos := make([]int, len(runes))
+	for i := range pos {
+		pos[i] = i
+	}
+	s := preprocess(span{r: runes, pos: pos}, title)
+	lines := splitLines(s, len(runes))
+
+	w := &writer{refs: map[string]bool{}}
+	w.blocks(lines)
+	if len(w.refs) > 0 {
+		// Reference definitions can follow the links that use them, so
+		// render again now that all of them are known.
+		w.runes, w.src = nil, nil
+		w.blocks(lines)
+	}
+
+	t := &Text{runes: w.runes, src: w.src, units: make([]int, len(w.runes)+1), mdLen: len(runes)}
+	for i, r := range w.runes {
+		t.units[i+1] = t.units[i] + utf16Len(r)
+	}
+	return t
+}
+
+// String returns the displayed text.
+func (t *Text) String() string { return string(t.runes) }
+
+// Len returns the length of the displayed text in UTF-16 code units.
+func (t *Text) Len() int { return t.units[len(t.units)-1] }
+
+// FromMarkdown converts a rune offset into the markdown to a reader offset:
+// that of the first displayed character rendered from at or after offset.
+// An offset inside markdown syntax moves forward to the text that follows.
+func (t *Text) FromMarkdown(offset int) int {
+	i := sort.Search(len(t.src), func(i int) bool { return t.src[i] >= offset })
+	return t.units[i]
+}
+
+// ToMarkdown converts a reader offset to the rune offset in the markdown of
+// the character displayed there. Offsets at or past the end of the text map
+// to the end of the markdown.
+func (t *Text) ToMarkdown(offset int) int {
+	i := sort.Search(len(t.runes), func(i int) bool { return t.units[i+1] > offset })
+	if i == len(t.runes) {
+		return t.mdLen
+	}
+	return t.src[i]
+}
+
+func utf16Len(r rune) int {
+	if r >= 0x10000 && r <= utf8.MaxRune {
+		return 2
+	}
+	return 1
+}
+
+// span is a piece of the markdown together with the markdown rune offset of
+// each of its runes.
+type span struct {
+	r   []rune
+	pos []int
+}
+
+func (s span) slice(i, j int) span { return span{r: s.r[i:j], pos: s.pos[i:j]} }
+
+// trimSpace trims white space, as Swift's whitespacesAndNewlines does.
+func trimSpace(s span) span {
+	i, j := 0, len(s.r)
+	for i < j && unicode.IsSpace(s.r[i]) {
+		i++
+	}
+	for j > i && unicode.IsSpace(s.r[j-1]) {
+		j--
+	}
+	return s.slice(i, j)
+}
+
+// metadataPatterns match the tweet view counts and timestamps the reader
+// strips before rendering.
+var metadataPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\[[\d,]+ Views?\]\([^\)]+/analytics\)`),
+	regexp.MustCompile(`\[\d{1,2}:\d{2} [AP]M · .+?\]\([^\)]+\)`),
+}
+
+// preprocess mirrors MarkdownRenderer.preprocessed: a leading copy of the
+// title is dropped, metadata links are removed and the text is trimmed.
+func preprocess(s span, title string) span {
+	if title = strings.TrimSpace(title); title != "" {
+		t := trimSpace(s)
+		tr := []rune(title)
+		if len(t.r) >= len(tr) && string(t.r[:len(tr)]) == title {
+			s = trimSpace(t.slice(len(tr), len(t.r)))
+		}
+	}
+	for _, re := range metadataPatterns {
+		str := string(s.r)
+		locs := re.FindAllStringIndex(str, -1)
+		if len(locs) == 0 {
+			continue
+		}
+		out := span{r: make([]rune, 0, len(s.r)), pos: make([]int, 0, len(s.r))}
+		from, byteOff, runeOff := 0, 0, 0
+		for _, loc := range locs {
+			start := runeOff + utf8.RuneCountInString(str[byteOff:loc[0]])
+			end := start + utf8.RuneCountInString(str[loc[0]:loc[1]])
+			out.r = append(out.r, s.r[from:start]...)
+			out.pos = append(out.pos, s.pos[from:start]...)
+			from, byteOff, runeOff = end, loc[1], end
+		}
+		out.r = append(out.r, s.r[from:]...)
+		out.pos = append(out.pos, s.pos[from:]...)
+		s = out
+	}
+	return trimSpace(s)
+}
+
+// line is one line of markdown without its line ending. end is the markdown
+// offset of the line ending, where the break after a block ending on this
+// line is attributed.
+type line struct {
+	span
+	end int
+}
+
+func splitLines(s span, mdLen int) []line {
+	lines := make([]line, 0)
+	start := 0
+	for i, r := range s.r {
+		if r == '\n' {
+			lines = append(lines, newLine(s.slice(start, i), s.pos[i]))
+			start = i + 1
+		}
+	}
+	end := mdLen
+	if start < len(s.r) {
+		end = s.pos[len(s.r)-1] + 1
+	}
+	return append(lines, newLine(s.slice(start, len(s.r)), end))
+}
+
+func newLine(s span, end int) line {
+	if n := len(s.r); n > 0 && s.r[n-1] == '\r' {
+		s = s.slice(0, n-1)
+	}
+	return line{span: s, end: end}
+}
+
+// writer collects the displayed text.
+type writer struct {
+	runes []rune
+	src   []int
+	refs  map[string]bool // normalized labels of link reference definitions
+}
+
+func (w *writer) emit(r rune, pos int) {
+	if n := len(w.src); n > 0 && pos < w.src[n-1] {
+		pos = w.src[n-1]
+	}
+	w.runes = append(w.runes, r)
+	w.src = append(w.src, pos)
+}
+
+func (w *writer) emitSpan(s span) {
+	for i, r := range s.r {
+		w.emit(r, s.pos[i])
+	}
+}
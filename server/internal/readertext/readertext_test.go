@@ -0,0 +1,62 @@
+package readertext
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name, markdown, title, want string
+	}{
+		{"title and metadata", "My Title\n\nBody [1,234 Views](https://x.com/a/status/1/analytics) text.", "My Title", "Body  text.\n"},
+		{"emphasis", "*em* **strong** ***both*** _under_score_ snake_case ~~del~~ 2 * 3", "", "em strong both under_score snake_case del 2 * 3\n"},
+		{"links and images", "[link](http://x \"t\") ![alt](a.png) <https://x.y> [ref]\n\n[ref]: http://example.com", "", "link  https://x.y ref\n"},
+		{"escapes and entities", `\*not em\* &amp; &copy; &nope; <b>bold</b>`, "", "*not em* & © &nope; bold\n"},
+		{"code", "Use `a  *b*` here.\n\n```go\nx := *p\n```", "", "Use a  *b* here.\nx := *p\n"},
+		{"headings", "# One #\n\nTwo\n---", "", "One\nTwo\n"},
+		{"lists", "- one\n- two\n  more\n\n1. a", "", "one\n\ntwo\nmore\n\n\na\n\n\n"},
+		{"quote and break", "> quoted\nlazy\n\n***", "", "quoted\nlazy\n\n\n"},
+		{"table", "| a | b |\n|---|:-:|\n| 1 | 2 |", "", "ab12\n"},
+		{"page markers", "<!-- page 1 -->\n\nOne.\n\n<!-- page 2 -->\n\nTwo.", "", "\nOne.\n\nTwo.\n"},
+	}
+	for _, tt := range tests {
+		if got := Render(tt.markdown, tt.title).String(); got != tt.want {
+			t.Errorf("%s: Render = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestOffsets(t *testing.T) {
+	// Markdown syntax and characters outside the BMP (two UTF-16 units
+	// each) come before the passage.
+	markdown := "# 标题 😀\n\n**Bold** [link](https://example.com) 𝒜 text\n\nThe passage here."
+	text := Render(markdown, "")
+
+	start := strings.Index(markdown, "The passage")
+	runeStart := len([]rune(markdown[:start]))
+	runeEnd := len([]rune(markdown))
+
+	prefix := "标题 😀\nBold link 𝒜 text\n"
+	wantStart := len(utf16.Encode([]rune(prefix)))
+	if got := text.FromMarkdown(runeStart); got != wantStart {
+		t.Errorf("FromMarkdown(%d) = %d, want %d", runeStart, got, wantStart)
+	}
+	wantEnd := wantStart + len("The passage here.")
+	if got := text.FromMarkdown(runeEnd); got != wantEnd {
+		t.Errorf("FromMarkdown(end) = %d, want %d", got, wantEnd)
+	}
+	if got := text.ToMarkdown(wantStart); got != runeStart {
+		t.Errorf("ToMarkdown(%d) = %d, want %d", wantStart, got, runeStart)
+	}
+
+	// An offset inside syntax moves to the text it wraps.
+	bold := len([]rune(markdown[:strings.Index(markdown, "**Bold")]))
+	if got, want := text.FromMarkdown(bold), len(utf16.Encode([]rune("标题 😀\n"))); got != want {
+		t.Errorf("FromMarkdown(**) = %d, want %d", got, want)
+	}
+	if got := text.ToMarkdown(text.Len() + 5); got != runeEnd {
+		t.Errorf("ToMarkdown past the end = %d, want %d", got, runeEnd)
+	}
+}
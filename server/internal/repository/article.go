@@ -22,6 +22,10 @@ func NewArticleRepo(pool *pgxpool.Pool) *ArticleRepo {
 	return &ArticleRepo{pool: pool}
 }
 
+// MaxClientIDLength is the size of articles.client_id (VARCHAR(36)): a
+// client's local UUID, or a key derived by the server for content it ingests.
+const MaxClientIDLength = 36
+
 type CreateArticleParams struct {
 	UserID          string
 	URL             *string
@@ -31,7 +35,11 @@ type CreateArticleParams struct {
 	SiteName        *string
 	MarkdownContent *string
 	WordCount       *int
-	ClientID        *string
+	ClientID        *string // at most MaxClientIDLength characters
+	// Imported articles keep their original saved date and flags.
+	CreatedAt  *time.Time
+	IsFavorite bool
+	IsArchived bool
 }
 
 func (r *ArticleRepo) Create(ctx context.Context, p CreateArticleParams) (*domain.Article, error) {
@@ -42,11 +50,13 @@ func (r *ArticleRepo) Create(ctx context.Context, p CreateArticleParams) (*domai
 
 	var a domain.Article
 	err := r.pool.QueryRow(ctx, `
-		INSERT INTO articles (user_id, url, source_type, title, author, site_name, markdown_content, word_count, client_id)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-		RETURNING id, user_id, url, status, source_type, created_at, updated_at`,
+		INSERT INTO articles (user_id, url, source_type, title, author, site_name, markdown_content, word_count, client_id,
+			created_at, is_favorite, is_archived)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, COALESCE($10, NOW()), $11, $12)
+		RETURNING id, user_id, url, status, source_type, is_favorite, is_archived, created_at, updated_at`,
 		p.UserID, p.URL, p.SourceType, p.Title, p.Author, p.SiteName, p.MarkdownContent, wordCount, p.ClientID,
-	).Scan(&a.ID, &a.UserID, &a.URL, &a.Status, &a.SourceType, &a.CreatedAt, &a.UpdatedAt)
+		p.CreatedAt, p.IsFavorite, p.IsArchived,
+	).Scan(&a.ID, &a.UserID, &a.URL, &a.Status, &a.SourceType, &a.IsFavorite, &a.IsArchived, &a.CreatedAt, &a.UpdatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("insert article: %w", err)
 	}
@@ -54,6 +64,18 @@ func (r *ArticleRepo) Create(ctx context.Context, p CreateArticleParams) (*domai
 	return &a, nil
 }
 
+// SetFileURL records where the original uploaded file is stored.
+func (r *ArticleRepo) SetFileURL(ctx context.Context, id string, fileURL string) error {
+	_, err := r.pool.Exec(ctx,
+		`UPDATE articles SET file_url = $2, updated_at = NOW() WHERE id = $1`,
+		id, fileURL,
+	)
+	if err != nil {
+		return fmt.Errorf("set file url: %w", err)
+	}
+	return nil
+}
+
 func (r *ArticleRepo) ExistsByUserAndClientID(ctx context.Context, userID, clientID string) (bool, error) {
 	var exists bool
 	err := r.pool.QueryRow(ctx,
@@ -67,7 +89,7 @@ func (r *ArticleRepo) GetByID(ctx context.Context, id string) (*domain.Article,
 	var a domain.Article
 	var keyPointsJSON []byte
 	err := r.pool.QueryRow(ctx, `
-		SELECT id, user_id, url, title, author, site_name, favicon_url, cover_image_url,
+		SELECT id, user_id, url, title, author, site_name, favicon_url, cover_image_url, file_url,
 		       markdown_content, word_count, language, category_id, summary, key_points,
 		       ai_confidence, status, source_type, fetch_error, retry_count,
 		       is_favorite, is_archived, read_progress, highlight_count, last_read_at, published_at,
@@ -75,7 +97,7 @@ func (r *ArticleRepo) GetByID(ctx context.Context, id string) (*domain.Article,
 		FROM articles WHERE id = $1`, id,
 	).Scan(
 		&a.ID, &a.UserID, &a.URL, &a.Title, &a.Author, &a.SiteName,
-		&a.FaviconURL, &a.CoverImageURL, &a.MarkdownContent, &a.WordCount,
+		&a.FaviconURL, &a.CoverImageURL, &a.FileURL, &a.MarkdownContent, &a.WordCount,
 		&a.Language, &a.CategoryID, &a.Summary, &keyPointsJSON,
 		&a.AIConfidence, &a.Status, &a.SourceType, &a.FetchError, &a.RetryCount,
 		&a.IsFavorite, &a.IsArchived, &a.ReadProgress, &a.HighlightCount, &a.LastReadAt, &a.PublishedAt,
@@ -109,6 +131,17 @@ type ListArticlesParams struct {
 	UpdatedSince *time.Time
 	Page         int
 	PerPage      int
+
+	// Collection filters (see domain.ArticleFilter).
+	Archived      *bool
+	TagIDs        []string
+	SourceTypes   []domain.SourceType
+	ReadState     *domain.ReadState
+	MinWords      *int
+	MaxWords      *int
+	CreatedAfter  *time.Time // inclusive
+	CreatedBefore *time.Time // exclusive
+	UpdatedAfter  *time.Time // like UpdatedSince, without deleted articles
 }
 
 type ListArticlesResult struct {
@@ -116,104 +149,325 @@ type ListArticlesResult struct {
 	Total    int              `json:"total"`
 }
 
-func (r *ArticleRepo) ListByUser(ctx context.Context, p ListArticlesParams) (*ListArticlesResult, error) {
-	offset := (p.Page - 1) * p.PerPage
-
+// articleListSQL returns the conditions of p for the articles aliased a,
+// each prefixed with AND, after a.user_id = $1. Soft-deleted articles are
+// excluded unless UpdatedSince is set.
+func articleListSQL(a string, p ListArticlesParams, args *searchArgs) string {
+	var b strings.Builder
 	// When using updated_since (incremental sync), include soft-deleted articles
 	// so the client can learn about deletions. Otherwise, filter them out.
-	includeDeleted := p.UpdatedSince != nil
-
-	// Count
-	countQuery := `SELECT COUNT(*) FROM articles WHERE user_id = $1`
-	args := []any{p.UserID}
-	argIdx := 2
-
-	if !includeDeleted {
-		countQuery += ` AND deleted_at IS NULL`
+	if p.UpdatedSince == nil {
+		fmt.Fprintf(&b, ` AND %s.deleted_at IS NULL`, a)
 	}
 	if p.Category != nil {
-		countQuery += fmt.Sprintf(` AND category_id = (SELECT id FROM categories WHERE slug = $%d)`, argIdx)
-		args = append(args, *p.Category)
-		argIdx++
+		fmt.Fprintf(&b, ` AND %s.category_id = (SELECT id FROM categories WHERE slug = %s)`, a, args.add(*p.Category))
 	}
 	if p.Status != nil {
-		countQuery += fmt.Sprintf(` AND status = $%d`, argIdx)
-		args = append(args, *p.Status)
-		argIdx++
+		fmt.Fprintf(&b, ` AND %s.status = %s`, a, args.add(*p.Status))
 	}
 	if p.Favorite != nil {
-		countQuery += fmt.Sprintf(` AND is_favorite = $%d`, argIdx)
-		args = append(args, *p.Favorite)
-		argIdx++
+		fmt.Fprintf(&b, ` AND %s.is_favorite = %s`, a, args.add(*p.Favorite))
 	}
 	if p.UpdatedSince != nil {
-		countQuery += fmt.Sprintf(` AND updated_at > $%d`, argIdx)
-		args = append(args, *p.UpdatedSince)
-		argIdx++
+		fmt.Fprintf(&b, ` AND %s.updated_at > %s`, a, args.add(*p.UpdatedSince))
+	}
+	if p.Archived != nil {
+		fmt.Fprintf(&b, ` AND %s.is_archived = %s`, a, args.add(*p.Archived))
 	}
+	if len(p.TagIDs) > 0 {
+		fmt.Fprintf(&b, ` AND EXISTS (SELECT 1 FROM article_tags ft WHERE ft.article_id = %s.id AND ft.tag_id = ANY(%s::uuid[]))`, a, args.add(p.TagIDs))
+	}
+	if len(p.SourceTypes) > 0 {
+		types := make([]string, len(p.SourceTypes))
+		for i, t := range p.SourceTypes {
+			types[i] = string(t)
+		}
+		fmt.Fprintf(&b, ` AND %s.source_type = ANY(%s::text[])`, a, args.add(types))
+	}
+	if p.ReadState != nil {
+		switch *p.ReadState {
+		case domain.ReadStateUnread:
+			fmt.Fprintf(&b, ` AND %s.read_progress = 0`, a)
+		case domain.ReadStateReading:
+			fmt.Fprintf(&b, ` AND %s.read_progress > 0 AND %s.read_progress < %s`, a, a, args.add(domain.ReadThreshold))
+		case domain.ReadStateRead:
+			fmt.Fprintf(&b, ` AND %s.read_progress >= %s`, a, args.add(domain.ReadThreshold))
+		}
+	}
+	if p.MinWords != nil {
+		fmt.Fprintf(&b, ` AND %s.word_count >= %s`, a, args.add(*p.MinWords))
+	}
+	if p.MaxWords != nil {
+		fmt.Fprintf(&b, ` AND %s.word_count <= %s`, a, args.add(*p.MaxWords))
+	}
+	if p.CreatedAfter != nil {
+		fmt.Fprintf(&b, ` AND %s.created_at >= %s`, a, args.add(*p.CreatedAfter))
+	}
+	if p.CreatedBefore != nil {
+		fmt.Fprintf(&b, ` AND %s.created_at < %s`, a, args.add(*p.CreatedBefore))
+	}
+	if p.UpdatedAfter != nil {
+		fmt.Fprintf(&b, ` AND %s.updated_at > %s`, a, args.add(*p.UpdatedAfter))
+	}
+	return b.String()
+}
+
+// articleListColumns are the columns of an article in a listing, read by
+// scanArticleListRow. Content and AI detail are loaded per article.
+const articleListColumns = `a.id, a.user_id, a.url, a.title, a.summary, a.cover_image_url, a.site_name,
+	a.source_type, a.category_id, a.word_count, a.is_favorite, a.is_archived,
+	a.read_progress, a.status, a.created_at, a.updated_at, a.deleted_at`
+
+func scanArticleListRow(row pgx.Row) (domain.Article, error) {
+	var a domain.Article
+	err := row.Scan(
+		&a.ID, &a.UserID, &a.URL, &a.Title, &a.Summary, &a.CoverImageURL,
+		&a.SiteName, &a.SourceType, &a.CategoryID, &a.WordCount,
+		&a.IsFavorite, &a.IsArchived, &a.ReadProgress, &a.Status, &a.CreatedAt,
+		&a.UpdatedAt, &a.DeletedAt,
+	)
+	a.KeyPoints = []string{}
+	return a, err
+}
+
+func (r *ArticleRepo) ListByUser(ctx context.Context, p ListArticlesParams) (*ListArticlesResult, error) {
+	offset := (p.Page - 1) * p.PerPage
+
+	args := &searchArgs{next: 2}
+	where := `a.user_id = $1` + articleListSQL("a", p, args)
+	queryArgs := append([]any{p.UserID}, args.args...)
 
+	// Count
 	var total int
-	if err := r.pool.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM articles a WHERE `+where, queryArgs...).Scan(&total); err != nil {
 		return nil, fmt.Errorf("count articles: %w", err)
 	}
 
 	// Query
-	query := `SELECT id, user_id, url, title, summary, cover_image_url, site_name,
-	                 source_type, category_id, word_count, is_favorite, is_archived,
-	                 read_progress, status, created_at, updated_at, deleted_at
-	          FROM articles WHERE user_id = $1`
-	queryArgs := []any{p.UserID}
-	qArgIdx := 2
+	queryArgs = append(queryArgs, p.PerPage, offset)
+	rows, err := r.pool.Query(ctx, fmt.Sprintf(`
+		SELECT `+articleListColumns+`
+		FROM articles a WHERE %s
+		ORDER BY a.created_at DESC, a.id DESC LIMIT $%d OFFSET $%d`,
+		where, len(queryArgs)-1, len(queryArgs)),
+		queryArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("list articles: %w", err)
+	}
+	defer rows.Close()
 
-	if !includeDeleted {
-		query += ` AND deleted_at IS NULL`
+	articles := make([]domain.Article, 0)
+	for rows.Next() {
+		a, err := scanArticleListRow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan article: %w", err)
+		}
+		articles = append(articles, a)
 	}
-	if p.Category != nil {
-		query += fmt.Sprintf(` AND category_id = (SELECT id FROM categories WHERE slug = $%d)`, qArgIdx)
-		queryArgs = append(queryArgs, *p.Category)
-		qArgIdx++
+
+	return &ListArticlesResult{Articles: articles, Total: total}, nil
+}
+
+// ArticlePage is one page of a keyset-paginated listing. Next is nil on the
+// last page; Total is set only when it was asked for.
+type ArticlePage struct {
+	Articles []domain.Article
+	Next     *ArticleCursor
+	Total    *int
+}
+
+// syncCursorOverlap is how long a write transaction may run and still be
+// seen by a sync that has paged past it; see ListPage.
+const syncCursorOverlap = time.Minute
+
+// ListPage lists the articles matching p after the cursor, or from the
+// start when after is nil; p.Page is ignored. Browsing runs newest first by
+// (created_at, id). With UpdatedSince it is a sync and runs oldest change
+// first by (updated_at, id), so an article changed mid-sync moves to a
+// later page instead of being skipped. Both orders are total: rows added or
+// changed while paging never shift the pages still to come.
+//
+// updated_at is the start time of the writing transaction, so a change can
+// commit behind a sync cursor after the page that passed it was read. Each
+// later sync page therefore starts with the articles behind the cursor
+// changed less than syncCursorOverlap before that read, which covers
+// transactions shorter than syncCursorOverlap. Those articles may have been
+// sent already; syncing clients must treat every article as an upsert.
+func (r *ArticleRepo) ListPage(ctx context.Context, p ListArticlesParams, after *ArticleCursor, withTotal bool) (*ArticlePage, error) {
+	args := &searchArgs{next: 2}
+	where := `a.user_id = $1` + articleListSQL("a", p, args)
+	queryArgs := append([]any{p.UserID}, args.args...)
+
+	page := &ArticlePage{Articles: make([]domain.Article, 0)}
+	if withTotal {
+		var total int
+		if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM articles a WHERE `+where, queryArgs...).Scan(&total); err != nil {
+			return nil, fmt.Errorf("count articles: %w", err)
+		}
+		page.Total = &total
+	}
+
+	sync := p.UpdatedSince != nil
+	order := `a.created_at DESC, a.id DESC`
+	var readAt time.Time
+	if sync {
+		order = `a.updated_at, a.id`
+		// Read before the page, so that any transaction still open when
+		// the page is read started after readAt - syncCursorOverlap.
+		if err := r.pool.QueryRow(ctx, `SELECT NOW()`).Scan(&readAt); err != nil {
+			return nil, fmt.Errorf("read sync time: %w", err)
+		}
+		if after != nil {
+			if err := r.listSyncOverlap(ctx, page, where, queryArgs, after); err != nil {
+				return nil, err
+			}
+		}
 	}
-	if p.Status != nil {
-		query += fmt.Sprintf(` AND status = $%d`, qArgIdx)
-		queryArgs = append(queryArgs, *p.Status)
-		qArgIdx++
+	if after != nil {
+		n := len(queryArgs)
+		if sync {
+			where += fmt.Sprintf(` AND (a.updated_at, a.id) > ($%d, $%d::uuid)`, n+1, n+2)
+		} else {
+			where += fmt.Sprintf(` AND (a.created_at, a.id) < ($%d, $%d::uuid)`, n+1, n+2)
+		}
+		queryArgs = append(queryArgs, after.At, after.ID)
 	}
-	if p.Favorite != nil {
-		query += fmt.Sprintf(` AND is_favorite = $%d`, qArgIdx)
-		queryArgs = append(queryArgs, *p.Favorite)
-		qArgIdx++
+
+	// One row past the page tells whether there is a next one.
+	queryArgs = append(queryArgs, p.PerPage+1)
+	rows, err := r.pool.Query(ctx, fmt.Sprintf(`
+		SELECT `+articleListColumns+`
+		FROM articles a WHERE %s
+		ORDER BY %s LIMIT $%d`,
+		where, order, len(queryArgs)),
+		queryArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("list articles: %w", err)
 	}
-	if p.UpdatedSince != nil {
-		query += fmt.Sprintf(` AND updated_at > $%d`, qArgIdx)
-		queryArgs = append(queryArgs, *p.UpdatedSince)
-		qArgIdx++
+	defer rows.Close()
+
+	overlap := len(page.Articles)
+	for rows.Next() {
+		a, err := scanArticleListRow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan article: %w", err)
+		}
+		page.Articles = append(page.Articles, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate articles: %w", err)
 	}
 
-	query += fmt.Sprintf(` ORDER BY created_at DESC LIMIT $%d OFFSET $%d`, qArgIdx, qArgIdx+1)
-	queryArgs = append(queryArgs, p.PerPage, offset)
+	if len(page.Articles)-overlap > p.PerPage {
+		page.Articles = page.Articles[:overlap+p.PerPage]
+		last := page.Articles[len(page.Articles)-1]
+		page.Next = &ArticleCursor{Sync: sync, At: last.CreatedAt, ID: last.ID}
+		if sync {
+			page.Next.At = last.UpdatedAt
+			page.Next.ReadAt = readAt
+		}
+	}
+	return page, nil
+}
 
-	rows, err := r.pool.Query(ctx, query, queryArgs...)
+// listSyncOverlap adds to page the articles behind the sync cursor after
+// that changed within syncCursorOverlap of when the previous page was read.
+func (r *ArticleRepo) listSyncOverlap(ctx context.Context, page *ArticlePage, where string, queryArgs []any, after *ArticleCursor) error {
+	n := len(queryArgs)
+	rows, err := r.pool.Query(ctx, fmt.Sprintf(`
+		SELECT `+articleListColumns+`
+		FROM articles a
+		WHERE %s AND (a.updated_at, a.id) < ($%d, $%d::uuid) AND a.updated_at > $%d
+		ORDER BY a.updated_at, a.id`,
+		where, n+1, n+2, n+3),
+		append(queryArgs[:n:n], after.At, after.ID, after.ReadAt.Add(-syncCursorOverlap))...)
 	if err != nil {
-		return nil, fmt.Errorf("list articles: %w", err)
+		return fmt.Errorf("list sync overlap: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		a, err := scanArticleListRow(rows)
+		if err != nil {
+			return fmt.Errorf("scan article: %w", err)
+		}
+		page.Articles = append(page.Articles, a)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate articles: %w", err)
+	}
+	return nil
+}
+
+// ListForExport returns up to limit of the user's articles created after the
+// (afterCreatedAt, afterID) cursor, oldest first, with content, category and
+// tag names loaded. Pass the zero time and an empty ID for the first page.
+// A non-nil articleIDs restricts the listing to those articles.
+func (r *ArticleRepo) ListForExport(ctx context.Context, userID string, articleIDs []string, afterCreatedAt time.Time, afterID string, limit int) ([]domain.Article, error) {
+	if afterID == "" {
+		afterID = "00000000-0000-0000-0000-000000000000"
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT a.id, a.user_id, a.url, a.title, a.author, a.site_name, a.markdown_content,
+		       a.summary, a.key_points, a.source_type, a.is_favorite, a.is_archived,
+		       a.created_at, a.updated_at,
+		       c.id, c.slug, c.name_zh, c.name_en,
+		       COALESCE((
+		           SELECT array_agg(t.name ORDER BY t.name)
+		           FROM article_tags at JOIN tags t ON t.id = at.tag_id
+		           WHERE at.article_id = a.id
+		       ), '{}')
+		FROM articles a
+		LEFT JOIN categories c ON c.id = a.category_id
+		WHERE a.user_id = $1
+		  AND a.deleted_at IS NULL
+		  AND ($5::uuid[] IS NULL OR a.id = ANY($5))
+		  AND (a.created_at, a.id) > ($2, $3::uuid)
+		ORDER BY a.created_at, a.id
+		LIMIT $4`,
+		userID, afterCreatedAt, afterID, limit, articleIDs,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list articles for export: %w", err)
 	}
 	defer rows.Close()
 
 	articles := make([]domain.Article, 0)
 	for rows.Next() {
 		var a domain.Article
+		var keyPointsJSON []byte
+		var catID, catSlug, catZH, catEN *string
+		var tagNames []string
 		if err := rows.Scan(
-			&a.ID, &a.UserID, &a.URL, &a.Title, &a.Summary, &a.CoverImageURL,
-			&a.SiteName, &a.SourceType, &a.CategoryID, &a.WordCount,
-			&a.IsFavorite, &a.IsArchived, &a.ReadProgress, &a.Status, &a.CreatedAt,
-			&a.UpdatedAt, &a.DeletedAt,
+			&a.ID, &a.UserID, &a.URL, &a.Title, &a.Author, &a.SiteName, &a.MarkdownContent,
+			&a.Summary, &keyPointsJSON, &a.SourceType, &a.IsFavorite, &a.IsArchived,
+			&a.CreatedAt, &a.UpdatedAt,
+			&catID, &catSlug, &catZH, &catEN,
+			&tagNames,
 		); err != nil {
-			return nil, fmt.Errorf("scan article: %w", err)
+			return nil, fmt.Errorf("scan export article: %w", err)
+		}
+		if keyPointsJSON != nil {
+			if err := json.Unmarshal(keyPointsJSON, &a.KeyPoints); err != nil {
+				return nil, fmt.Errorf("unmarshal key_points: %w", err)
+			}
+		}
+		if a.KeyPoints == nil {
+			a.KeyPoints = []string{}
+		}
+		if catID != nil {
+			a.CategoryID = catID
+			a.Category = &domain.Category{ID: *catID, Slug: derefStr(catSlug), NameZH: derefStr(catZH), NameEN: derefStr(catEN)}
+		}
+		for _, name := range tagNames {
+			a.Tags = append(a.Tags, domain.Tag{Name: name})
 		}
-		a.KeyPoints = []string{}
 		articles = append(articles, a)
 	}
-
-	return &ListArticlesResult{Articles: articles, Total: total}, nil
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate export articles: %w", err)
+	}
+	return articles, nil
 }
 
 func (r *ArticleRepo) UpdateStatus(ctx context.Context, id string, status domain.ArticleStatus) error {
@@ -429,6 +683,29 @@ func (r *ArticleRepo) Delete(ctx context.Context, id string, userID string) erro
 	return nil
 }
 
+// ListIDsByUser returns the IDs of all of the user's articles, including
+// soft-deleted ones.
+func (r *ArticleRepo) ListIDsByUser(ctx context.Context, userID string) ([]string, error) {
+	rows, err := r.pool.Query(ctx, `SELECT id FROM articles WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list article ids: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan article id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate article ids: %w", err)
+	}
+	return ids, nil
+}
+
 // BroadRecallArticles does multi-path keyword recall returning full Article objects for semantic search.
 func (r *ArticleRepo) BroadRecallArticles(ctx context.Context, userID string, keywords []string, limit int) ([]domain.Article, error) {
 	cleaned := make([]string, len(keywords))
@@ -487,32 +764,63 @@ func (r *ArticleRepo) BroadRecallArticles(ctx context.Context, userID string, ke
 	return articles, rows.Err()
 }
 
-func (r *ArticleRepo) Search(ctx context.Context, userID, query string, page, perPage int) (*ListArticlesResult, error) {
-	offset := (page - 1) * perPage
-	escaped := strings.NewReplacer("%", "\\%", "_", "\\_").Replace(query)
-	pattern := "%" + escaped + "%"
+// Search runs a parsed search query over the user's articles. Free-text
+// terms use the search_vector full-text index (see migration 026): results
+// are ranked with ts_rank_cd and carry a ts_headline snippet of their body
+// (or summary, for articles without one) with matches wrapped in <mark>
+// tags. A query of filters alone lists matches newest first, without
+// snippets.
+func (r *ArticleRepo) Search(ctx context.Context, userID string, q *domain.SearchQuery, page, perPage int) (*ListArticlesResult, error) {
+	return r.SearchFiltered(ctx, q, ListArticlesParams{UserID: userID, Page: page, PerPage: perPage})
+}
+
+// filteredSearchSQL returns the WHERE clause and arguments matching both q
+// (which may be nil) and the filters of p, for the articles aliased a.
+func filteredSearchSQL(q *domain.SearchQuery, p ListArticlesParams) (string, searchSQL, []any) {
+	args := &searchArgs{next: 2}
+	where := `a.user_id = $1` + articleListSQL("a", p, args)
+	sq := searchQuerySQL("a", q, args.next+len(args.args))
+	return where + sq.where, sq, append(append([]any{p.UserID}, args.args...), sq.args...)
+}
+
+// SearchFiltered is Search restricted to articles that also match the
+// filters of p, as ListByUser applies them. It evaluates collections.
+func (r *ArticleRepo) SearchFiltered(ctx context.Context, q *domain.SearchQuery, p ListArticlesParams) (*ListArticlesResult, error) {
+	offset := (p.Page - 1) * p.PerPage
+	where, sq, args := filteredSearchSQL(q, p)
 
 	var total int
-	err := r.pool.QueryRow(ctx,
-		`SELECT COUNT(*) FROM articles WHERE user_id = $1 AND deleted_at IS NULL AND (
-			title ILIKE $2 OR summary ILIKE $2 OR author ILIKE $2 OR site_name ILIKE $2
-		)`,
-		userID, pattern,
-	).Scan(&total)
+	err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM articles a WHERE `+where, args...).Scan(&total)
 	if err != nil {
 		return nil, fmt.Errorf("count search: %w", err)
 	}
 
-	rows, err := r.pool.Query(ctx, `
-		SELECT id, user_id, url, title, summary, site_name, source_type, created_at
-		FROM articles WHERE user_id = $1 AND deleted_at IS NULL AND (
-			title ILIKE $2 OR summary ILIKE $2 OR author ILIKE $2 OR site_name ILIKE $2
-		)
-		ORDER BY
-			CASE WHEN title ILIKE $2 THEN 0 ELSE 1 END,
-			created_at DESC
-		LIMIT $3 OFFSET $4`,
-		userID, pattern, perPage, offset)
+	rank, headline := "0", "NULL::text"
+	if sq.match != "" {
+		args = append(args, searchHeadlineOptions)
+		rank = fmt.Sprintf(`ts_rank_cd(a.search_vector, %s)`, sq.match)
+		headline = fmt.Sprintf(`ts_headline('simple',
+				folio_cjk_unigrams(COALESCE(NULLIF(left(a.markdown_content, 100000), ''), a.summary, '')),
+				%s, $%d)`, sq.headline, len(args))
+	}
+	args = append(args, p.PerPage, offset)
+
+	// The headline is computed over the page only: ts_headline re-parses
+	// the document, which is too costly to run on every match.
+	rows, err := r.pool.Query(ctx, fmt.Sprintf(`
+		SELECT a.id, a.user_id, a.url, a.title, a.summary, a.site_name, a.source_type, a.created_at,
+			%s
+		FROM (
+			SELECT a.id, a.user_id, a.url, a.title, a.summary, a.site_name, a.source_type, a.created_at,
+				a.markdown_content, %s AS rank
+			FROM articles a
+			WHERE %s
+			ORDER BY rank DESC, a.created_at DESC, a.id
+			LIMIT $%d OFFSET $%d
+		) a
+		ORDER BY a.rank DESC, a.created_at DESC, a.id`,
+		headline, rank, where, len(args)-1, len(args)),
+		args...)
 	if err != nil {
 		return nil, fmt.Errorf("search articles: %w", err)
 	}
@@ -521,13 +829,99 @@ func (r *ArticleRepo) Search(ctx context.Context, userID, query string, page, pe
 	articles := make([]domain.Article, 0)
 	for rows.Next() {
 		var a domain.Article
+		var headline *string
 		if err := rows.Scan(&a.ID, &a.UserID, &a.URL, &a.Title, &a.Summary,
-			&a.SiteName, &a.SourceType, &a.CreatedAt); err != nil {
+			&a.SiteName, &a.SourceType, &a.CreatedAt, &headline); err != nil {
 			return nil, fmt.Errorf("scan search result: %w", err)
 		}
 		a.KeyPoints = []string{}
+		if headline != nil {
+			if snippet := searchSnippet(*headline); snippet != "" {
+				a.Snippet = &snippet
+			}
+		}
 		articles = append(articles, a)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate search results: %w", err)
+	}
 
 	return &ListArticlesResult{Articles: articles, Total: total}, nil
 }
+
+// CountFiltered counts the articles SearchFiltered would return.
+func (r *ArticleRepo) CountFiltered(ctx context.Context, q *domain.SearchQuery, p ListArticlesParams) (int, error) {
+	where, _, args := filteredSearchSQL(q, p)
+	var total int
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM articles a WHERE `+where, args...).Scan(&total); err != nil {
+		return 0, fmt.Errorf("count filtered articles: %w", err)
+	}
+	return total, nil
+}
+
+// ListFilteredIDs returns the IDs of up to limit articles SearchFiltered
+// would return, best match (or newest) first. A limit of 0 returns all.
+func (r *ArticleRepo) ListFilteredIDs(ctx context.Context, q *domain.SearchQuery, p ListArticlesParams, limit int) ([]string, error) {
+	where, sq, args := filteredSearchSQL(q, p)
+	order := `a.created_at DESC, a.id`
+	if sq.match != "" {
+		order = fmt.Sprintf(`ts_rank_cd(a.search_vector, %s) DESC, %s`, sq.match, order)
+	}
+	query := `SELECT a.id FROM articles a WHERE ` + where + ` ORDER BY ` + order
+	if limit > 0 {
+		args = append(args, limit)
+		query += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list filtered article ids: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan filtered article id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// BackfillSearchVectors computes search_vector for up to batchSize articles
+// that predate the full-text index, returning how many it updated. Callers
+// loop until it returns 0; the trigger keeps rows current afterwards.
+//
+// Filling in the index is not an edit, so the batch runs with triggers
+// disabled: tr_articles_updated_at would otherwise bump updated_at and every
+// article would be sent to every device again on the next sync. Setting
+// session_replication_role needs a superuser or, on PostgreSQL 15 and later,
+// GRANT SET ON PARAMETER session_replication_role.
+func (r *ArticleRepo) BackfillSearchVectors(ctx context.Context, batchSize int) (int64, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SET LOCAL session_replication_role = replica`); err != nil {
+		return 0, fmt.Errorf("disable triggers: %w", err)
+	}
+	tag, err := tx.Exec(ctx, `
+		UPDATE articles
+		SET search_vector = folio_article_search_vector(title, summary, author, site_name, markdown_content)
+		WHERE id IN (
+			SELECT id FROM articles WHERE search_vector IS NULL
+			LIMIT $1
+		)`,
+		batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("backfill search vectors: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commit tx: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
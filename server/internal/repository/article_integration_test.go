@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestArticleRepo_ListPageSeesChangeCommittedBehindCursor(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	repo := NewArticleRepo(pool)
+
+	userID := testUser(t, pool)
+	a := testArticle(t, pool, userID, "https://example.com/a")
+	b := testArticle(t, pool, userID, "https://example.com/b")
+	c := testArticle(t, pool, userID, "https://example.com/c")
+	touch := func(id string) {
+		t.Helper()
+		if _, err := pool.Exec(ctx, `UPDATE articles SET title = 'changed' WHERE id = $1`, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The change to a gets an updated_at older than the changes to b and c
+	// but commits only after the first page has passed them.
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `UPDATE articles SET title = 'late' WHERE id = $1`, a); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	touch(b)
+	time.Sleep(10 * time.Millisecond)
+	touch(c)
+
+	since := time.Now().Add(-time.Hour)
+	params := ListArticlesParams{UserID: userID, UpdatedSince: &since, PerPage: 2}
+	first, err := repo.ListPage(ctx, params, nil, false)
+	if err != nil {
+		t.Fatalf("first page: %v", err)
+	}
+	if len(first.Articles) != 2 || first.Articles[1].ID != b || first.Next == nil {
+		t.Fatalf("first page = %+v, want a (unchanged) and b with a next cursor", first)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := repo.ListPage(ctx, params, first.Next, false)
+	if err != nil {
+		t.Fatalf("second page: %v", err)
+	}
+	var late, sawC bool
+	for _, art := range second.Articles {
+		switch art.ID {
+		case a:
+			late = art.Title != nil && *art.Title == "late"
+		case c:
+			sawC = true
+		}
+	}
+	if !late || !sawC {
+		t.Errorf("second page = %+v, want the late change to a and c", second.Articles)
+	}
+	if second.Next != nil {
+		t.Error("second page should be the last")
+	}
+}
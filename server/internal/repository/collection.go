@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"folio-server/internal/domain"
+)
+
+type CollectionRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewCollectionRepo(pool *pgxpool.Pool) *CollectionRepo {
+	return &CollectionRepo{pool: pool}
+}
+
+const collectionColumns = `id, user_id, name, filter, position, created_at, updated_at`
+
+func scanCollection(row pgx.Row) (*domain.Collection, error) {
+	var c domain.Collection
+	err := row.Scan(&c.ID, &c.UserID, &c.Name, &c.Filter, &c.Position, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Create appends a collection after the user's existing ones.
+func (r *CollectionRepo) Create(ctx context.Context, userID, name string, filter domain.ArticleFilter) (*domain.Collection, error) {
+	c, err := scanCollection(r.pool.QueryRow(ctx, `
+		INSERT INTO collections (user_id, name, filter, position)
+		VALUES ($1, $2, $3, (SELECT COALESCE(MAX(position) + 1, 0) FROM collections WHERE user_id = $1))
+		RETURNING `+collectionColumns,
+		userID, name, filter,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("insert collection: %w", err)
+	}
+	return c, nil
+}
+
+func (r *CollectionRepo) GetByID(ctx context.Context, id string) (*domain.Collection, error) {
+	c, err := scanCollection(r.pool.QueryRow(ctx,
+		`SELECT `+collectionColumns+` FROM collections WHERE id = $1`, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get collection: %w", err)
+	}
+	return c, nil
+}
+
+// ListByUser returns the user's collections in sidebar order.
+func (r *CollectionRepo) ListByUser(ctx context.Context, userID string) ([]domain.Collection, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+collectionColumns+` FROM collections
+		WHERE user_id = $1
+		ORDER BY position, created_at`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list collections: %w", err)
+	}
+	defer rows.Close()
+
+	collections := make([]domain.Collection, 0)
+	for rows.Next() {
+		c, err := scanCollection(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan collection: %w", err)
+		}
+		collections = append(collections, *c)
+	}
+	return collections, rows.Err()
+}
+
+type UpdateCollectionParams struct {
+	Name     *string
+	Filter   *domain.ArticleFilter
+	Position *int
+}
+
+func (r *CollectionRepo) Update(ctx context.Context, id, userID string, p UpdateCollectionParams) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE collections SET
+			name = COALESCE($3, name),
+			filter = COALESCE($4, filter),
+			position = COALESCE($5, position)
+		WHERE id = $1 AND user_id = $2`,
+		id, userID, p.Name, p.Filter, p.Position,
+	)
+	if err != nil {
+		return fmt.Errorf("update collection: %w", err)
+	}
+	return nil
+}
+
+func (r *CollectionRepo) Delete(ctx context.Context, id, userID string) error {
+	_, err := r.pool.Exec(ctx, `DELETE FROM collections WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("delete collection: %w", err)
+	}
+	return nil
+}
+
+// ArticleFilterParams turns a collection filter into the arguments of
+// ArticleRepo.SearchFiltered, CountFiltered and ListFilteredIDs for the
+// user, resolving CreatedWithinDays against now. The query is nil when the
+// filter has none.
+func ArticleFilterParams(userID string, f domain.ArticleFilter, now time.Time) (ListArticlesParams, *domain.SearchQuery, error) {
+	p := ListArticlesParams{
+		UserID:        userID,
+		Category:      f.Category,
+		Status:        f.Status,
+		Favorite:      f.Favorite,
+		UpdatedAfter:  f.UpdatedSince,
+		Archived:      f.Archived,
+		TagIDs:        f.TagIDs,
+		SourceTypes:   f.SourceTypes,
+		ReadState:     f.ReadState,
+		MinWords:      f.MinWords,
+		MaxWords:      f.MaxWords,
+		CreatedAfter:  f.CreatedAfter,
+		CreatedBefore: f.CreatedBefore,
+	}
+	if f.CreatedWithinDays != nil {
+		since := now.AddDate(0, 0, -*f.CreatedWithinDays)
+		if p.CreatedAfter == nil || since.After(*p.CreatedAfter) {
+			p.CreatedAfter = &since
+		}
+	}
+
+	var q *domain.SearchQuery
+	if f.Query != "" {
+		var err error
+		if q, err = domain.ParseSearchQuery(f.Query); err != nil {
+			return p, nil, err
+		}
+	}
+	return p, q, nil
+}
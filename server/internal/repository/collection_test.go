@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"folio-server/internal/domain"
+)
+
+func TestArticleFilterParams(t *testing.T) {
+	now := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+	longAgo := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	since := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	days, read := 30, domain.ReadStateRead
+
+	p, q, err := ArticleFilterParams("user-1", domain.ArticleFilter{
+		ReadState:         &read,
+		UpdatedSince:      &since,
+		CreatedAfter:      &longAgo,
+		CreatedWithinDays: &days,
+	}, now)
+	if err != nil || q != nil {
+		t.Fatalf("q = %v, err = %v", q, err)
+	}
+	if p.UserID != "user-1" || p.ReadState != &read {
+		t.Errorf("params = %+v", p)
+	}
+	// Collections never show deleted articles, so the sync filter is not used.
+	if p.UpdatedSince != nil || p.UpdatedAfter != &since {
+		t.Errorf("updated since = %v, after = %v", p.UpdatedSince, p.UpdatedAfter)
+	}
+	// The later of the saved date and the relative window applies.
+	if want := now.AddDate(0, 0, -30); !p.CreatedAfter.Equal(want) {
+		t.Errorf("created after = %v, want %v", p.CreatedAfter, want)
+	}
+
+	_, q, err = ArticleFilterParams("user-1", domain.ArticleFilter{Query: "rust is:favorite"}, now)
+	if err != nil || len(q.Terms) != 1 || len(q.Filters) != 1 {
+		t.Errorf("q = %+v, err = %v", q, err)
+	}
+	if _, _, err := ArticleFilterParams("user-1", domain.ArticleFilter{Query: "after:soon"}, now); err == nil {
+		t.Error("invalid query should fail")
+	}
+}
+
+func TestArticleListSQL(t *testing.T) {
+	args := &searchArgs{next: 2}
+	if sql := articleListSQL("a", ListArticlesParams{}, args); sql != " AND a.deleted_at IS NULL" || len(args.args) != 0 {
+		t.Errorf("empty params = %q, %v", sql, args.args)
+	}
+
+	unread, minWords := domain.ReadStateUnread, 500
+	args = &searchArgs{next: 2}
+	sql := articleListSQL("a", ListArticlesParams{
+		TagIDs:      []string{"t1"},
+		SourceTypes: []domain.SourceType{domain.SourceWeb},
+		ReadState:   &unread,
+		MinWords:    &minWords,
+	}, args)
+	for _, want := range []string{
+		"a.deleted_at IS NULL",
+		"ft.tag_id = ANY($2::uuid[])",
+		"a.source_type = ANY($3::text[])",
+		"a.read_progress = 0",
+		"a.word_count >= $4",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("missing %q in %q", want, sql)
+		}
+	}
+	if len(args.args) != 3 {
+		t.Errorf("args = %v", args.args)
+	}
+
+	// Incremental sync includes deleted articles.
+	since := time.Now()
+	if sql := articleListSQL("a", ListArticlesParams{UpdatedSince: &since}, &searchArgs{next: 2}); strings.Contains(sql, "deleted_at") {
+		t.Errorf("sync params = %q", sql)
+	}
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func TestArticleCursor_RoundTrip(t *testing.T) {
+	at := time.Date(2026, 5, 1, 8, 30, 0, 123456000, time.UTC)
+	readAt := at.Add(time.Hour)
+	id := "0b6f5a52-3c1e-4f7e-9d0a-5f2b8c7d9e10"
+
+	for _, sync := range []bool{false, true} {
+		in := ArticleCursor{Sync: sync, At: at, ID: id}
+		if sync {
+			in.ReadAt = readAt
+		}
+		c, err := DecodeArticleCursor(in.Encode())
+		if err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if c.Sync != sync || !c.At.Equal(at) || c.ID != id || !c.ReadAt.Equal(in.ReadAt) {
+			t.Errorf("sync %v: got %+v", sync, c)
+		}
+	}
+}
+
+func TestDecodeArticleCursor_Invalid(t *testing.T) {
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	for _, s := range []string{
+		"",
+		"not base64!",
+		encode(`not json`),
+		encode(`{"o":"created","t":"2026-05-01T00:00:00Z"}`),
+		encode(`{"o":"created","t":"2026-05-01T00:00:00Z","id":"1' OR 1=1"}`),
+		encode(`{"o":"title","t":"2026-05-01T00:00:00Z","id":"0b6f5a52-3c1e-4f7e-9d0a-5f2b8c7d9e10"}`),
+		encode(`{"o":"updated","id":"0b6f5a52-3c1e-4f7e-9d0a-5f2b8c7d9e10"}`),
+		encode(`{"o":"updated","t":"2026-05-01T00:00:00Z","id":"0b6f5a52-3c1e-4f7e-9d0a-5f2b8c7d9e10"}`),
+	} {
+		if c, err := DecodeArticleCursor(s); err == nil {
+			t.Errorf("DecodeArticleCursor(%q) = %+v, want error", s, c)
+		}
+	}
+}
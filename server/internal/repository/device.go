@@ -64,15 +64,23 @@ func (r *DeviceRepo) GetByUserID(ctx context.Context, userID string) ([]domain.D
 	return devices, nil
 }
 
-// GetPushableDevices returns one device per user that has due echo cards,
-// has not been pushed today, and has not reviewed today. The returned
-// question is the earliest due card's question for that user.
+// GetPushableDevices returns one device per user that has due echo cards
+// (neither suspended nor buried), has not been pushed today, and has not
+// reviewed today. The returned
+// question is the earliest due card's question for that user, with the term
+// of a cloze card blanked out as EchoCard.ClozeQuestion does.
 func (r *DeviceRepo) GetPushableDevices(ctx context.Context) ([]domain.PushTarget, error) {
 	rows, err := r.db.Query(ctx, `
-		SELECT DISTINCT ON (d.user_id) d.user_id, d.token, ec.question
+		SELECT DISTINCT ON (d.user_id) d.user_id, d.token,
+			CASE WHEN ec.card_type = 'cloze'
+				THEN overlay(ec.question PLACING '［＿＿＿］' FROM ec.cloze_start + 1 FOR ec.cloze_end - ec.cloze_start)
+				ELSE ec.question
+			END
 		FROM devices d
 		JOIN echo_cards ec ON ec.user_id = d.user_id
 		WHERE ec.next_review_at <= NOW()
+		AND ec.suspended_at IS NULL
+		AND (ec.buried_until IS NULL OR ec.buried_until <= NOW())
 		AND (d.last_push_at IS NULL OR d.last_push_at < CURRENT_DATE)
 		AND NOT EXISTS (
 			SELECT 1 FROM echo_reviews er
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -21,41 +22,154 @@ func NewEchoRepo(db *pgxpool.Pool) *EchoRepo {
 
 // CreateCard inserts a new echo card.
 func (r *EchoRepo) CreateCard(ctx context.Context, card *domain.EchoCard) error {
-	err := r.db.QueryRow(ctx, `
+	if err := insertEchoCard(ctx, r.db, card, ""); err != nil {
+		return fmt.Errorf("create echo card: %w", err)
+	}
+	return nil
+}
+
+// CreateRelatedCard inserts a related card unless the user already has limit
+// related cards created since the given time or a related card for the same
+// two articles, in either direction. It reports whether the card was created.
+// The check and insert hold a per-user advisory lock, so relate runs for
+// different articles of one user cannot both take the last slot.
+func (r *EchoRepo) CreateRelatedCard(ctx context.Context, card *domain.EchoCard, limit int, since time.Time) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('echo:related:' || $1))`, card.UserID); err != nil {
+		return false, fmt.Errorf("lock related cards: %w", err)
+	}
+	var count int
+	err = tx.QueryRow(ctx,
+		`SELECT COUNT(*) FROM echo_cards WHERE user_id = $1 AND card_type = 'related' AND created_at >= $2`,
+		card.UserID, since,
+	).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("count related cards: %w", err)
+	}
+	if count >= limit {
+		return false, nil
+	}
+
+	// idx_echo_cards_related_pair rejects a second card for the pair.
+	err = insertEchoCard(ctx, tx, card, "ON CONFLICT DO NOTHING")
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("create related echo card: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("commit tx: %w", err)
+	}
+	return true, nil
+}
+
+// rowQuerier is satisfied by both the pool and a transaction.
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// insertEchoCard inserts card with the given ON CONFLICT clause, filling in
+// its ID and timestamps. It returns pgx.ErrNoRows when the clause skipped it.
+func insertEchoCard(ctx context.Context, q rowQuerier, card *domain.EchoCard, onConflict string) error {
+	return q.QueryRow(ctx, `
 		INSERT INTO echo_cards (
 			id, user_id, article_id, card_type, question, answer, source_context,
-			next_review_at, interval_days, ease_factor, review_count, correct_count,
-			related_article_id, highlight_id, created_at, updated_at
+			next_review_at, interval_days, stability, difficulty, last_reviewed_at,
+			review_count, correct_count,
+			related_article_id, highlight_id, cloze_start, cloze_end, sibling_group,
+			created_at, updated_at
 		) VALUES (
 			COALESCE(NULLIF($1, '')::uuid, uuid_generate_v4()),
 			$2::uuid, $3::uuid, $4, $5, $6, $7,
-			$8, $9, $10, $11, $12,
-			$13, $14, NOW(), NOW()
+			$8, $9, NULLIF($10::float8, 0), NULLIF($11::float8, 0), $12,
+			$13, $14,
+			$15, $16, $17, $18, $19,
+			NOW(), NOW()
 		)
+		`+onConflict+`
 		RETURNING id, created_at, updated_at`,
 		card.ID, card.UserID, card.ArticleID, card.CardType, card.Question, card.Answer, card.SourceContext,
-		card.NextReviewAt, card.IntervalDays, card.EaseFactor, card.ReviewCount, card.CorrectCount,
-		card.RelatedArticleID, card.HighlightID,
+		card.NextReviewAt, card.IntervalDays, card.Stability, card.Difficulty, card.LastReviewedAt,
+		card.ReviewCount, card.CorrectCount,
+		card.RelatedArticleID, card.HighlightID, card.ClozeStart, card.ClozeEnd, card.SiblingGroup,
 	).Scan(&card.ID, &card.CreatedAt, &card.UpdatedAt)
+}
+
+// echoCardColumns selects a card joined with its article (alias a) for the
+// article title. Stability and difficulty are NULL until the first review.
+const echoCardColumns = `
+	ec.id, ec.user_id, ec.article_id, ec.card_type, ec.question, ec.answer, ec.source_context,
+	ec.next_review_at, ec.interval_days, COALESCE(ec.stability, 0), COALESCE(ec.difficulty, 0), ec.last_reviewed_at,
+	ec.review_count, ec.correct_count,
+	ec.related_article_id, ec.highlight_id, ec.cloze_start, ec.cloze_end, ec.sibling_group,
+	ec.suspended_at, ec.buried_until, ec.created_at, ec.updated_at,
+	COALESCE(a.title, '') AS article_title`
+
+func scanEchoCard(row pgx.Row) (*domain.EchoCard, error) {
+	var c domain.EchoCard
+	err := row.Scan(
+		&c.ID, &c.UserID, &c.ArticleID, &c.CardType, &c.Question, &c.Answer, &c.SourceContext,
+		&c.NextReviewAt, &c.IntervalDays, &c.Stability, &c.Difficulty, &c.LastReviewedAt,
+		&c.ReviewCount, &c.CorrectCount,
+		&c.RelatedArticleID, &c.HighlightID, &c.ClozeStart, &c.ClozeEnd, &c.SiblingGroup,
+		&c.SuspendedAt, &c.BuriedUntil, &c.CreatedAt, &c.UpdatedAt,
+		&c.ArticleTitle,
+	)
 	if err != nil {
-		return fmt.Errorf("create echo card: %w", err)
+		return nil, err
 	}
-	return nil
+	return &c, nil
+}
+
+func collectEchoCards(rows pgx.Rows) ([]domain.EchoCard, error) {
+	defer rows.Close()
+	cards := make([]domain.EchoCard, 0)
+	for rows.Next() {
+		c, err := scanEchoCard(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan echo card: %w", err)
+		}
+		cards = append(cards, *c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate echo cards: %w", err)
+	}
+	return cards, nil
 }
 
 // GetDueCards returns cards where next_review_at <= now for a user,
 // joined with articles to get article title. Ordered by next_review_at ASC.
+// Suspended cards and cards buried until later are left out, as are all but
+// the earliest due card of a sibling group and siblings of a card reviewed
+// today, so siblings are never shown on the same day.
 func (r *EchoRepo) GetDueCards(ctx context.Context, userID string, limit int) ([]domain.EchoCard, error) {
 	rows, err := r.db.Query(ctx, `
-		SELECT
-			ec.id, ec.user_id, ec.article_id, ec.card_type, ec.question, ec.answer, ec.source_context,
-			ec.next_review_at, ec.interval_days, ec.ease_factor, ec.review_count, ec.correct_count,
-			ec.related_article_id, ec.highlight_id, ec.created_at, ec.updated_at,
-			COALESCE(a.title, '') AS article_title
-		FROM echo_cards ec
+		SELECT `+echoCardColumns+`
+		FROM (
+			SELECT c.*, ROW_NUMBER() OVER (
+				PARTITION BY COALESCE(c.sibling_group, c.id)
+				ORDER BY c.next_review_at, c.id
+			) AS sibling_rank
+			FROM echo_cards c
+			WHERE c.user_id = $1
+			  AND c.next_review_at <= NOW()
+			  AND c.suspended_at IS NULL
+			  AND (c.buried_until IS NULL OR c.buried_until <= NOW())
+		) ec
 		LEFT JOIN articles a ON ec.article_id = a.id
-		WHERE ec.user_id = $1
-		  AND ec.next_review_at <= NOW()
+		WHERE ec.sibling_rank = 1
+		  AND NOT EXISTS (
+			SELECT 1 FROM echo_cards s
+			WHERE s.sibling_group = ec.sibling_group
+			  AND s.id <> ec.id
+			  AND s.last_reviewed_at >= CURRENT_DATE
+		  )
 		ORDER BY ec.next_review_at ASC
 		LIMIT $2`,
 		userID, limit,
@@ -63,68 +177,166 @@ func (r *EchoRepo) GetDueCards(ctx context.Context, userID string, limit int) ([
 	if err != nil {
 		return nil, fmt.Errorf("query due cards: %w", err)
 	}
-	defer rows.Close()
+	return collectEchoCards(rows)
+}
 
-	cards := make([]domain.EchoCard, 0)
-	for rows.Next() {
-		var c domain.EchoCard
-		if err := rows.Scan(
-			&c.ID, &c.UserID, &c.ArticleID, &c.CardType, &c.Question, &c.Answer, &c.SourceContext,
-			&c.NextReviewAt, &c.IntervalDays, &c.EaseFactor, &c.ReviewCount, &c.CorrectCount,
-			&c.RelatedArticleID, &c.HighlightID, &c.CreatedAt, &c.UpdatedAt,
-			&c.ArticleTitle,
-		); err != nil {
-			return nil, fmt.Errorf("scan due card: %w", err)
-		}
-		cards = append(cards, c)
+// ListByUser returns all of the user's cards with their article titles,
+// grouped by article and oldest first within each article.
+func (r *EchoRepo) ListByUser(ctx context.Context, userID string) ([]domain.EchoCard, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+echoCardColumns+`
+		FROM echo_cards ec
+		LEFT JOIN articles a ON ec.article_id = a.id
+		WHERE ec.user_id = $1
+		ORDER BY ec.article_id, ec.created_at ASC`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query cards by user: %w", err)
 	}
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterate due cards: %w", err)
+	return collectEchoCards(rows)
+}
+
+// ListEchoCardsParams filters a page of a user's cards.
+type ListEchoCardsParams struct {
+	UserID    string
+	ArticleID *string
+	Suspended *bool // nil lists both
+	Page      int
+	PerPage   int
+}
+
+type ListEchoCardsResult struct {
+	Cards []domain.EchoCard
+	Total int
+}
+
+// ListCards returns a page of the user's cards, newest first.
+func (r *EchoRepo) ListCards(ctx context.Context, p ListEchoCardsParams) (*ListEchoCardsResult, error) {
+	where := `ec.user_id = $1
+		  AND ($2::uuid IS NULL OR ec.article_id = $2::uuid)
+		  AND ($3::boolean IS NULL OR (ec.suspended_at IS NOT NULL) = $3::boolean)`
+
+	var total int
+	if err := r.db.QueryRow(ctx,
+		`SELECT COUNT(*) FROM echo_cards ec WHERE `+where,
+		p.UserID, p.ArticleID, p.Suspended,
+	).Scan(&total); err != nil {
+		return nil, fmt.Errorf("count echo cards: %w", err)
 	}
-	return cards, nil
+
+	rows, err := r.db.Query(ctx, `
+		SELECT `+echoCardColumns+`
+		FROM echo_cards ec
+		LEFT JOIN articles a ON ec.article_id = a.id
+		WHERE `+where+`
+		ORDER BY ec.created_at DESC, ec.id
+		LIMIT $4 OFFSET $5`,
+		p.UserID, p.ArticleID, p.Suspended, p.PerPage, (p.Page-1)*p.PerPage,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list echo cards: %w", err)
+	}
+	cards, err := collectEchoCards(rows)
+	if err != nil {
+		return nil, err
+	}
+	return &ListEchoCardsResult{Cards: cards, Total: total}, nil
 }
 
 // GetCardByID returns a single card, verifying user ownership.
 func (r *EchoRepo) GetCardByID(ctx context.Context, cardID, userID string) (*domain.EchoCard, error) {
-	var c domain.EchoCard
-	err := r.db.QueryRow(ctx, `
-		SELECT
-			ec.id, ec.user_id, ec.article_id, ec.card_type, ec.question, ec.answer, ec.source_context,
-			ec.next_review_at, ec.interval_days, ec.ease_factor, ec.review_count, ec.correct_count,
-			ec.related_article_id, ec.highlight_id, ec.created_at, ec.updated_at,
-			COALESCE(a.title, '') AS article_title
+	c, err := scanEchoCard(r.db.QueryRow(ctx, `
+		SELECT `+echoCardColumns+`
 		FROM echo_cards ec
 		LEFT JOIN articles a ON ec.article_id = a.id
 		WHERE ec.id = $1 AND ec.user_id = $2`,
 		cardID, userID,
-	).Scan(
-		&c.ID, &c.UserID, &c.ArticleID, &c.CardType, &c.Question, &c.Answer, &c.SourceContext,
-		&c.NextReviewAt, &c.IntervalDays, &c.EaseFactor, &c.ReviewCount, &c.CorrectCount,
-		&c.RelatedArticleID, &c.HighlightID, &c.CreatedAt, &c.UpdatedAt,
-		&c.ArticleTitle,
-	)
+	))
 	if err == pgx.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, fmt.Errorf("get echo card: %w", err)
 	}
-	return &c, nil
+	return c, nil
 }
 
-// UpdateCard updates SM-2 fields: interval_days, ease_factor, next_review_at,
-// review_count, correct_count, updated_at.
+// UpdateCardContent sets the question, answer and cloze offsets of one of
+// the user's cards.
+func (r *EchoRepo) UpdateCardContent(ctx context.Context, card *domain.EchoCard) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE echo_cards SET
+			question    = $3,
+			answer      = $4,
+			cloze_start = $5,
+			cloze_end   = $6,
+			updated_at  = NOW()
+		WHERE id = $1 AND user_id = $2`,
+		card.ID, card.UserID, card.Question, card.Answer, card.ClozeStart, card.ClozeEnd,
+	)
+	if err != nil {
+		return fmt.Errorf("update echo card content: %w", err)
+	}
+	return nil
+}
+
+// SetSuspended suspends one of the user's cards, keeping its schedule, or
+// unsuspends it.
+func (r *EchoRepo) SetSuspended(ctx context.Context, cardID, userID string, suspended bool) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE echo_cards SET
+			suspended_at = CASE WHEN $3 THEN COALESCE(suspended_at, NOW()) END,
+			updated_at   = NOW()
+		WHERE id = $1 AND user_id = $2`,
+		cardID, userID, suspended,
+	)
+	if err != nil {
+		return fmt.Errorf("set echo card suspended: %w", err)
+	}
+	return nil
+}
+
+// SetBuriedUntil hides one of the user's cards from due cards until the
+// given time.
+func (r *EchoRepo) SetBuriedUntil(ctx context.Context, cardID, userID string, until time.Time) error {
+	_, err := r.db.Exec(ctx,
+		`UPDATE echo_cards SET buried_until = $3, updated_at = NOW() WHERE id = $1 AND user_id = $2`,
+		cardID, userID, until,
+	)
+	if err != nil {
+		return fmt.Errorf("bury echo card: %w", err)
+	}
+	return nil
+}
+
+// DeleteCard deletes one of the user's cards with its reviews.
+func (r *EchoRepo) DeleteCard(ctx context.Context, cardID, userID string) error {
+	_, err := r.db.Exec(ctx,
+		`DELETE FROM echo_cards WHERE id = $1 AND user_id = $2`,
+		cardID, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("delete echo card: %w", err)
+	}
+	return nil
+}
+
+// UpdateCard updates scheduling fields: interval_days, stability, difficulty,
+// last_reviewed_at, next_review_at, review_count, correct_count, updated_at.
 func (r *EchoRepo) UpdateCard(ctx context.Context, card *domain.EchoCard) error {
 	_, err := r.db.Exec(ctx, `
 		UPDATE echo_cards SET
 			interval_days    = $1,
-			ease_factor      = $2,
-			next_review_at   = $3,
-			review_count     = $4,
-			correct_count    = $5,
+			stability        = NULLIF($2::float8, 0),
+			difficulty       = NULLIF($3::float8, 0),
+			last_reviewed_at = $4,
+			next_review_at   = $5,
+			review_count     = $6,
+			correct_count    = $7,
 			updated_at       = NOW()
-		WHERE id = $6 AND user_id = $7`,
-		card.IntervalDays, card.EaseFactor, card.NextReviewAt,
+		WHERE id = $8 AND user_id = $9`,
+		card.IntervalDays, card.Stability, card.Difficulty, card.LastReviewedAt, card.NextReviewAt,
 		card.ReviewCount, card.CorrectCount,
 		card.ID, card.UserID,
 	)
@@ -137,13 +349,13 @@ func (r *EchoRepo) UpdateCard(ctx context.Context, card *domain.EchoCard) error
 // CreateReview inserts an echo_reviews record.
 func (r *EchoRepo) CreateReview(ctx context.Context, review *domain.EchoReview) error {
 	err := r.db.QueryRow(ctx, `
-		INSERT INTO echo_reviews (id, card_id, user_id, result, response_time_ms, reviewed_at)
+		INSERT INTO echo_reviews (id, card_id, user_id, result, rating, response_time_ms, reviewed_at)
 		VALUES (
 			COALESCE(NULLIF($1, '')::uuid, uuid_generate_v4()),
-			$2::uuid, $3::uuid, $4, $5, COALESCE($6, NOW())
+			$2::uuid, $3::uuid, $4, $5, $6, COALESCE($7, NOW())
 		)
 		RETURNING id, reviewed_at`,
-		review.ID, review.CardID, review.UserID, review.Result, review.ResponseTimeMs, review.ReviewedAt,
+		review.ID, review.CardID, review.UserID, review.Result, review.Rating, review.ResponseTimeMs, review.ReviewedAt,
 	).Scan(&review.ID, &review.ReviewedAt)
 	if err != nil {
 		return fmt.Errorf("create echo review: %w", err)
@@ -255,6 +467,39 @@ func (r *EchoRepo) CountCardsByArticle(ctx context.Context, articleID string) (i
 	return count, nil
 }
 
+// CountCardsByTypeSince returns how many cards of the given type were
+// created for the user since the given time.
+func (r *EchoRepo) CountCardsByTypeSince(ctx context.Context, userID string, cardType domain.EchoCardType, since time.Time) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx,
+		`SELECT COUNT(*) FROM echo_cards WHERE user_id = $1 AND card_type = $2 AND created_at >= $3`,
+		userID, cardType, since,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count cards by type: %w", err)
+	}
+	return count, nil
+}
+
+// HasRelatedCard reports whether the user has a related card connecting the
+// two articles, in either direction.
+func (r *EchoRepo) HasRelatedCard(ctx context.Context, userID, articleID, relatedArticleID string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM echo_cards
+			WHERE user_id = $1 AND card_type = 'related'
+			  AND ((article_id = $2 AND related_article_id = $3)
+			    OR (article_id = $3 AND related_article_id = $2))
+		)`,
+		userID, articleID, relatedArticleID,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("check related card: %w", err)
+	}
+	return exists, nil
+}
+
 // GetUserEchoQuota returns echo_count_this_week and echo_week_reset_at for a user.
 func (r *EchoRepo) GetUserEchoQuota(ctx context.Context, userID string) (count int, resetAt *time.Time, err error) {
 	err = r.db.QueryRow(ctx,
@@ -290,3 +535,107 @@ func (r *EchoRepo) IncrementEchoWeekCount(ctx context.Context, userID string) er
 	}
 	return nil
 }
+
+// GetSchedulerParams returns the user's fitted FSRS weights, or nil if they
+// have none yet.
+func (r *EchoRepo) GetSchedulerParams(ctx context.Context, userID string) (*domain.EchoSchedulerParams, error) {
+	p := domain.EchoSchedulerParams{UserID: userID}
+	err := r.db.QueryRow(ctx, `
+		SELECT weights, review_count, log_loss, optimized_at
+		FROM echo_scheduler_params
+		WHERE user_id = $1`,
+		userID,
+	).Scan(&p.Weights, &p.ReviewCount, &p.LogLoss, &p.OptimizedAt)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get echo scheduler params: %w", err)
+	}
+	return &p, nil
+}
+
+// SaveSchedulerParams inserts or replaces the user's fitted FSRS weights.
+func (r *EchoRepo) SaveSchedulerParams(ctx context.Context, p *domain.EchoSchedulerParams) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO echo_scheduler_params (user_id, weights, review_count, log_loss, optimized_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (user_id) DO UPDATE SET
+			weights      = EXCLUDED.weights,
+			review_count = EXCLUDED.review_count,
+			log_loss     = EXCLUDED.log_loss,
+			optimized_at = EXCLUDED.optimized_at
+		RETURNING optimized_at`,
+		p.UserID, p.Weights, p.ReviewCount, p.LogLoss,
+	).Scan(&p.OptimizedAt)
+	if err != nil {
+		return fmt.Errorf("save echo scheduler params: %w", err)
+	}
+	return nil
+}
+
+// ListReviewHistory returns all of the user's reviews ordered by card and,
+// within each card, oldest first.
+func (r *EchoRepo) ListReviewHistory(ctx context.Context, userID string) ([]domain.EchoReview, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, card_id, user_id, result, rating, response_time_ms, reviewed_at
+		FROM echo_reviews
+		WHERE user_id = $1
+		ORDER BY card_id, reviewed_at ASC`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query review history: %w", err)
+	}
+	defer rows.Close()
+
+	reviews := make([]domain.EchoReview, 0)
+	for rows.Next() {
+		var rv domain.EchoReview
+		if err := rows.Scan(
+			&rv.ID, &rv.CardID, &rv.UserID, &rv.Result, &rv.Rating, &rv.ResponseTimeMs, &rv.ReviewedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan review: %w", err)
+		}
+		reviews = append(reviews, rv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate reviews: %w", err)
+	}
+	return reviews, nil
+}
+
+// ListUsersDueForOptimization returns users with at least minReviews reviews
+// whose FSRS weights were never fitted or were fitted before staleBefore and
+// who have reviewed since.
+func (r *EchoRepo) ListUsersDueForOptimization(ctx context.Context, minReviews int, staleBefore time.Time, limit int) ([]string, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT er.user_id
+		FROM echo_reviews er
+		LEFT JOIN echo_scheduler_params p ON p.user_id = er.user_id
+		WHERE p.user_id IS NULL OR p.optimized_at < $2
+		GROUP BY er.user_id, p.optimized_at
+		HAVING COUNT(*) >= $1
+		   AND (p.optimized_at IS NULL OR MAX(er.reviewed_at) > p.optimized_at)
+		ORDER BY p.optimized_at ASC NULLS FIRST
+		LIMIT $3`,
+		minReviews, staleBefore, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query users due for optimization: %w", err)
+	}
+	defer rows.Close()
+
+	userIDs := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan user id: %w", err)
+		}
+		userIDs = append(userIDs, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate user ids: %w", err)
+	}
+	return userIDs, nil
+}
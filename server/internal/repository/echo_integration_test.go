@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"folio-server/internal/domain"
+)
+
+func TestEchoRepo_CreateRelatedCard(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	repo := NewEchoRepo(pool)
+
+	userID := testUser(t, pool)
+	a := testArticle(t, pool, userID, "https://example.com/a")
+	b := testArticle(t, pool, userID, "https://example.com/b")
+	c := testArticle(t, pool, userID, "https://example.com/c")
+
+	related := func(from, to string) bool {
+		t.Helper()
+		card := &domain.EchoCard{
+			UserID: userID, ArticleID: from, CardType: domain.EchoCardRelated,
+			Question: "q", Answer: "a", RelatedArticleID: &to,
+			NextReviewAt: time.Now(), IntervalDays: 1,
+		}
+		ok, err := repo.CreateRelatedCard(ctx, card, 5, time.Now().Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("CreateRelatedCard(%s, %s): %v", from, to, err)
+		}
+		return ok
+	}
+	if !related(a, b) || !related(a, c) {
+		t.Fatal("related cards for new pairs were not created")
+	}
+	if related(b, a) {
+		t.Error("created a second card for the same pair in the other direction")
+	}
+
+	// Deleting both related articles orphans both of a's cards; they must
+	// not collide in idx_echo_cards_related_pair.
+	if _, err := pool.Exec(ctx, `DELETE FROM articles WHERE id = ANY($1)`, []string{b, c}); err != nil {
+		t.Fatalf("delete related articles: %v", err)
+	}
+	var orphaned int
+	if err := pool.QueryRow(ctx,
+		`SELECT COUNT(*) FROM echo_cards WHERE article_id = $1 AND related_article_id IS NULL`, a,
+	).Scan(&orphaned); err != nil {
+		t.Fatal(err)
+	}
+	if orphaned != 2 {
+		t.Errorf("orphaned cards = %d, want 2", orphaned)
+	}
+}
@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"folio-server/internal/domain"
+	"folio-server/internal/readertext"
+)
+
+type EmbeddingRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewEmbeddingRepo(pool *pgxpool.Pool) *EmbeddingRepo {
+	return &EmbeddingRepo{pool: pool}
+}
+
+// EmbeddingChunk is one embedded piece of an article. Passages of the body
+// carry their rune offsets in the article markdown.
+type EmbeddingChunk struct {
+	Index       int
+	Text        string
+	Vector      []float32
+	StartOffset *int
+	EndOffset   *int
+}
+
+// vectorLiteral formats v as a pgvector text literal, e.g. "[0.1,-0.2]".
+// It is passed as text and cast with ::vector, so no vector codec is needed.
+func vectorLiteral(v []float32) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, x := range v {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(x), 'g', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// Replace stores chunks as the article's embeddings, removing any it had
+// before (including those of another model).
+func (r *EmbeddingRepo) Replace(ctx context.Context, articleID, userID, model string, chunks []EmbeddingChunk) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM article_embeddings WHERE article_id = $1`, articleID); err != nil {
+		return fmt.Errorf("delete article embeddings: %w", err)
+	}
+
+	batch := &pgx.Batch{}
+	for _, c := range chunks {
+		batch.Queue(`
+			INSERT INTO article_embeddings (article_id, user_id, model, chunk_index, chunk_text, embedding, start_offset, end_offset)
+			VALUES ($1, $2, $3, $4, $5, $6::vector, $7, $8)`,
+			articleID, userID, model, c.Index, c.Text, vectorLiteral(c.Vector), c.StartOffset, c.EndOffset,
+		)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("insert article embeddings: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
+
+// nearestArticlesSQL ranks a user's ready articles by their closest chunk to
+// the query vector in CTE "query" (columns embedding, model). Relevance is
+// cosine similarity. $1 = user ID, $2 = limit, $3 = article to exclude;
+// scoped holds further conditions on the articles, aliased a.
+func nearestArticlesSQL(scoped string) string {
+	return `
+	nearest AS (
+		SELECT e.article_id, MIN(e.embedding <=> q.embedding) AS distance
+		FROM article_embeddings e, query q
+		WHERE e.user_id = $1
+			AND e.model = q.model
+			AND ($3::uuid IS NULL OR e.article_id != $3)
+		GROUP BY e.article_id
+	)
+	SELECT a.id, COALESCE(a.title, ''), a.summary, a.key_points, a.site_name, a.created_at, 1 - n.distance
+	FROM nearest n
+	JOIN articles a ON a.id = n.article_id
+	WHERE a.status = 'ready' AND a.deleted_at IS NULL` + scoped + `
+	ORDER BY n.distance
+	LIMIT $2`
+}
+
+// SearchSummaries returns the user's articles within scope closest to
+// vector, which must come from model.
+func (r *EmbeddingRepo) SearchSummaries(ctx context.Context, userID, model string, vector []float32, limit int, excludeID string, scope *domain.RAGScope) ([]domain.RAGSource, error) {
+	scoped, scopeArgs := ragScopeSQL("a", scope, 6)
+	rows, err := r.pool.Query(ctx, `
+		WITH query AS (SELECT $4::vector AS embedding, $5::text AS model),`+nearestArticlesSQL(scoped),
+		append([]any{userID, limit, nullableUUID(excludeID), vectorLiteral(vector), model}, scopeArgs...)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("search embeddings: %w", err)
+	}
+	return scanNearestSources(rows)
+}
+
+// SimilarSummaries returns the user's articles closest to articleID, using
+// its first chunk (title and summary) as the query. It returns none if the
+// article has not been embedded.
+func (r *EmbeddingRepo) SimilarSummaries(ctx context.Context, userID, articleID string, limit int) ([]domain.RAGSource, error) {
+	rows, err := r.pool.Query(ctx, `
+		WITH query AS (
+			SELECT embedding, model FROM article_embeddings
+			WHERE article_id = $3 AND chunk_index = 0
+		),`+nearestArticlesSQL(""),
+		userID, limit, articleID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("similar embeddings: %w", err)
+	}
+	return scanNearestSources(rows)
+}
+
+// SearchPassages returns the passages of the user's articles within scope
+// closest to vector, which must come from model. Passages whose text no
+// longer matches the article at their offsets (the article changed since it
+// was embedded) are left out. Chunks are stored with rune offsets into the
+// markdown; the passages returned carry reader offsets instead.
+func (r *EmbeddingRepo) SearchPassages(ctx context.Context, userID, model string, vector []float32, limit int, scope *domain.RAGScope) ([]domain.RAGSource, error) {
+	scoped, scopeArgs := ragScopeSQL("a", scope, 5)
+	rows, err := r.pool.Query(ctx, `
+		WITH nearest AS (
+			SELECT e.article_id, e.chunk_text, e.start_offset, e.end_offset,
+				e.embedding <=> $3::vector AS distance
+			FROM article_embeddings e
+			JOIN articles a ON a.id = e.article_id
+			WHERE e.user_id = $1 AND e.model = $2 AND e.start_offset IS NOT NULL`+scoped+`
+			ORDER BY distance
+			LIMIT $4 * 2
+		)
+		SELECT a.id, COALESCE(a.title, ''), a.summary, a.key_points, a.site_name, a.created_at,
+			1 - n.distance, n.chunk_text, n.start_offset, n.end_offset, a.markdown_content
+		FROM nearest n
+		JOIN articles a ON a.id = n.article_id
+		WHERE a.status = 'ready' AND a.deleted_at IS NULL
+			AND substr(a.markdown_content, n.start_offset + 1, n.end_offset - n.start_offset) = n.chunk_text
+		ORDER BY n.distance
+		LIMIT $4`,
+		append([]any{userID, model, vectorLiteral(vector), limit}, scopeArgs...)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("search passages: %w", err)
+	}
+	defer rows.Close()
+
+	sources := make([]domain.RAGSource, 0)
+	rendered := make(map[string]*readertext.Text)
+	for rows.Next() {
+		var s domain.RAGSource
+		var p domain.RAGPassage
+		var kpJSON []byte
+		var markdown string
+		if err := rows.Scan(&s.ArticleID, &s.Title, &s.Summary, &kpJSON, &s.SiteName, &s.CreatedAt,
+			&s.Relevance, &p.Text, &p.StartOffset, &p.EndOffset, &markdown); err != nil {
+			return nil, fmt.Errorf("scan passage: %w", err)
+		}
+		if len(kpJSON) > 0 {
+			json.Unmarshal(kpJSON, &s.KeyPoints)
+		}
+		text, ok := rendered[s.ArticleID]
+		if !ok {
+			text = readertext.Render(markdown, s.Title)
+			rendered[s.ArticleID] = text
+		}
+		p.StartOffset, p.EndOffset = text.FromMarkdown(p.StartOffset), text.FromMarkdown(p.EndOffset)
+		s.Passage = &p
+		sources = append(sources, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate passages: %w", err)
+	}
+	return sources, nil
+}
+
+func scanNearestSources(rows pgx.Rows) ([]domain.RAGSource, error) {
+	defer rows.Close()
+
+	sources := make([]domain.RAGSource, 0)
+	for rows.Next() {
+		var s domain.RAGSource
+		var kpJSON []byte
+		if err := rows.Scan(&s.ArticleID, &s.Title, &s.Summary, &kpJSON, &s.SiteName, &s.CreatedAt, &s.Relevance); err != nil {
+			return nil, fmt.Errorf("scan embedding match: %w", err)
+		}
+		if len(kpJSON) > 0 {
+			json.Unmarshal(kpJSON, &s.KeyPoints)
+		}
+		sources = append(sources, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate embedding matches: %w", err)
+	}
+	return sources, nil
+}
+
+// nullableUUID turns an empty ID into SQL NULL.
+func nullableUUID(id string) any {
+	if id == "" {
+		return nil
+	}
+	return id
+}
@@ -0,0 +1,12 @@
+package repository
+
+import "testing"
+
+func TestVectorLiteral(t *testing.T) {
+	if got := vectorLiteral([]float32{0.25, -1, 3e-7}); got != "[0.25,-1,3e-07]" {
+		t.Errorf("vectorLiteral = %q", got)
+	}
+	if got := vectorLiteral(nil); got != "[]" {
+		t.Errorf("vectorLiteral(nil) = %q", got)
+	}
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"folio-server/internal/domain"
+)
+
+type ExportRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewExportRepo(pool *pgxpool.Pool) *ExportRepo {
+	return &ExportRepo{pool: pool}
+}
+
+const exportJobColumns = `id, user_id, status, format, collection_id, article_filter, article_count,
+	object_key, size_bytes, error, expires_at, finished_at, created_at, updated_at`
+
+func scanExportJob(row pgx.Row) (*domain.ExportJob, error) {
+	var j domain.ExportJob
+	err := row.Scan(
+		&j.ID, &j.UserID, &j.Status, &j.Format, &j.CollectionID, &j.Filter, &j.ArticleCount,
+		&j.ObjectKey, &j.SizeBytes, &j.Error, &j.ExpiresAt, &j.FinishedAt, &j.CreatedAt, &j.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// Create inserts a pending export job. collectionID and filter are nil for
+// an export of the whole library.
+func (r *ExportRepo) Create(ctx context.Context, userID string, format domain.ExportFormat, collectionID *string, filter *domain.ArticleFilter) (*domain.ExportJob, error) {
+	j, err := scanExportJob(r.pool.QueryRow(ctx, `
+		INSERT INTO export_jobs (user_id, format, collection_id, article_filter)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+exportJobColumns,
+		userID, string(format), collectionID, filter,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("insert export job: %w", err)
+	}
+	return j, nil
+}
+
+func (r *ExportRepo) GetByID(ctx context.Context, id string) (*domain.ExportJob, error) {
+	j, err := scanExportJob(r.pool.QueryRow(ctx,
+		`SELECT `+exportJobColumns+` FROM export_jobs WHERE id = $1`, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get export job: %w", err)
+	}
+	return j, nil
+}
+
+// ListByUser returns the user's most recent export jobs, newest first.
+func (r *ExportRepo) ListByUser(ctx context.Context, userID string, limit int) ([]domain.ExportJob, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+exportJobColumns+` FROM export_jobs
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2`,
+		userID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list export jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := make([]domain.ExportJob, 0)
+	for rows.Next() {
+		j, err := scanExportJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan export job: %w", err)
+		}
+		jobs = append(jobs, *j)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate export jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// HasActive reports whether the user has an export that is still pending or running.
+func (r *ExportRepo) HasActive(ctx context.Context, userID string) (bool, error) {
+	var exists bool
+	err := r.pool.QueryRow(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM export_jobs
+			WHERE user_id = $1 AND status IN ($2, $3)
+		)`,
+		userID, string(domain.ExportStatusPending), string(domain.ExportStatusRunning),
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("check active export: %w", err)
+	}
+	return exists, nil
+}
+
+func (r *ExportRepo) SetRunning(ctx context.Context, id string) error {
+	_, err := r.pool.Exec(ctx,
+		`UPDATE export_jobs SET status = $2 WHERE id = $1`,
+		id, string(domain.ExportStatusRunning))
+	if err != nil {
+		return fmt.Errorf("set export running: %w", err)
+	}
+	return nil
+}
+
+// Complete records the stored archive and when it will be deleted.
+func (r *ExportRepo) Complete(ctx context.Context, id, objectKey string, sizeBytes int64, articleCount int, expiresAt time.Time) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE export_jobs SET
+			status = $2, object_key = $3, size_bytes = $4, article_count = $5,
+			expires_at = $6, finished_at = NOW()
+		WHERE id = $1`,
+		id, string(domain.ExportStatusCompleted), objectKey, sizeBytes, articleCount, expiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("complete export job: %w", err)
+	}
+	return nil
+}
+
+func (r *ExportRepo) Fail(ctx context.Context, id, errMsg string) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE export_jobs SET status = $2, error = $3, finished_at = NOW()
+		WHERE id = $1`,
+		id, string(domain.ExportStatusFailed), errMsg,
+	)
+	if err != nil {
+		return fmt.Errorf("fail export job: %w", err)
+	}
+	return nil
+}
+
+// Expire marks a completed job's archive as deleted.
+func (r *ExportRepo) Expire(ctx context.Context, id string) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE export_jobs SET status = $2, object_key = NULL
+		WHERE id = $1 AND status = $3`,
+		id, string(domain.ExportStatusExpired), string(domain.ExportStatusCompleted),
+	)
+	if err != nil {
+		return fmt.Errorf("expire export job: %w", err)
+	}
+	return nil
+}
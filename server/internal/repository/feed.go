@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"folio-server/internal/domain"
+)
+
+type FeedRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewFeedRepo(pool *pgxpool.Pool) *FeedRepo {
+	return &FeedRepo{pool: pool}
+}
+
+const feedColumns = `id, user_id, url, title, site_url, etag, last_modified,
+	last_polled_at, last_error, error_count, is_active, created_at, updated_at`
+
+func scanFeed(row pgx.Row) (*domain.Feed, error) {
+	var f domain.Feed
+	err := row.Scan(
+		&f.ID, &f.UserID, &f.URL, &f.Title, &f.SiteURL, &f.ETag, &f.LastModified,
+		&f.LastPolledAt, &f.LastError, &f.ErrorCount, &f.IsActive, &f.CreatedAt, &f.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (r *FeedRepo) Create(ctx context.Context, userID, url string, title *string) (*domain.Feed, error) {
+	f, err := scanFeed(r.pool.QueryRow(ctx, `
+		INSERT INTO feeds (user_id, url, title)
+		VALUES ($1, $2, $3)
+		RETURNING `+feedColumns,
+		userID, url, title,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("insert feed: %w", err)
+	}
+	return f, nil
+}
+
+func (r *FeedRepo) ExistsByUserAndURL(ctx context.Context, userID, url string) (bool, error) {
+	var exists bool
+	err := r.pool.QueryRow(ctx,
+		`SELECT EXISTS(SELECT 1 FROM feeds WHERE user_id = $1 AND url = $2)`,
+		userID, url,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("check feed exists: %w", err)
+	}
+	return exists, nil
+}
+
+func (r *FeedRepo) GetByID(ctx context.Context, id string) (*domain.Feed, error) {
+	f, err := scanFeed(r.pool.QueryRow(ctx,
+		`SELECT `+feedColumns+` FROM feeds WHERE id = $1`, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get feed: %w", err)
+	}
+	return f, nil
+}
+
+func (r *FeedRepo) ListByUser(ctx context.Context, userID string) ([]domain.Feed, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT `+feedColumns+` FROM feeds WHERE user_id = $1 ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list feeds: %w", err)
+	}
+	defer rows.Close()
+	return collectFeeds(rows)
+}
+
+// ListDueForPoll returns active feeds that have never been polled or were
+// last polled before cutoff, oldest first.
+func (r *FeedRepo) ListDueForPoll(ctx context.Context, cutoff time.Time, limit int) ([]domain.Feed, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+feedColumns+` FROM feeds
+		WHERE is_active AND (last_polled_at IS NULL OR last_polled_at < $1)
+		ORDER BY last_polled_at NULLS FIRST
+		LIMIT $2`, cutoff, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list due feeds: %w", err)
+	}
+	defer rows.Close()
+	return collectFeeds(rows)
+}
+
+func collectFeeds(rows pgx.Rows) ([]domain.Feed, error) {
+	feeds := make([]domain.Feed, 0)
+	for rows.Next() {
+		f, err := scanFeed(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan feed: %w", err)
+		}
+		feeds = append(feeds, *f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate feeds: %w", err)
+	}
+	return feeds, nil
+}
+
+type UpdateFeedParams struct {
+	Title    *string
+	IsActive *bool
+}
+
+func (r *FeedRepo) Update(ctx context.Context, id, userID string, p UpdateFeedParams) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE feeds SET
+			title = COALESCE($3, title),
+			is_active = COALESCE($4, is_active),
+			error_count = CASE WHEN $4 THEN 0 ELSE error_count END
+		WHERE id = $1 AND user_id = $2`,
+		id, userID, p.Title, p.IsActive,
+	)
+	if err != nil {
+		return fmt.Errorf("update feed: %w", err)
+	}
+	return nil
+}
+
+func (r *FeedRepo) Delete(ctx context.Context, id, userID string) error {
+	_, err := r.pool.Exec(ctx, `DELETE FROM feeds WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("delete feed: %w", err)
+	}
+	return nil
+}
+
+// FeedPollResult carries the validators and metadata from a successful poll.
+type FeedPollResult struct {
+	ETag         *string
+	LastModified *string
+	Title        *string
+	SiteURL      *string
+}
+
+// RecordPollSuccess stores the conditional-request validators and clears
+// the error state. The title is only filled in when the user has not set one.
+func (r *FeedRepo) RecordPollSuccess(ctx context.Context, id string, p FeedPollResult) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE feeds SET
+			etag = $2,
+			last_modified = $3,
+			title = COALESCE(title, $4),
+			site_url = COALESCE($5, site_url),
+			last_polled_at = NOW(),
+			last_error = NULL,
+			error_count = 0
+		WHERE id = $1`,
+		id, p.ETag, p.LastModified, p.Title, p.SiteURL,
+	)
+	if err != nil {
+		return fmt.Errorf("record feed poll: %w", err)
+	}
+	return nil
+}
+
+// RecordPollError stores the error and deactivates the feed once it has
+// failed maxErrors times in a row.
+func (r *FeedRepo) RecordPollError(ctx context.Context, id, errMsg string, maxErrors int) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE feeds SET
+			last_error = $2,
+			error_count = error_count + 1,
+			is_active = (error_count + 1) < $3,
+			last_polled_at = NOW()
+		WHERE id = $1`,
+		id, truncateUTF8(errMsg, 1000), maxErrors,
+	)
+	if err != nil {
+		return fmt.Errorf("record feed error: %w", err)
+	}
+	return nil
+}
+
+// HasEntry reports whether the feed has already seen an entry with this GUID.
+func (r *FeedRepo) HasEntry(ctx context.Context, feedID, guid string) (bool, error) {
+	var exists bool
+	err := r.pool.QueryRow(ctx,
+		`SELECT EXISTS(SELECT 1 FROM feed_entries WHERE feed_id = $1 AND guid = $2)`,
+		feedID, guid,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("check feed entry: %w", err)
+	}
+	return exists, nil
+}
+
+// RecordEntry marks a GUID as seen. articleID is nil for entries that were
+// skipped (no link, or the URL was already saved).
+func (r *FeedRepo) RecordEntry(ctx context.Context, feedID, guid string, articleID *string) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO feed_entries (feed_id, guid, article_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (feed_id, guid) DO NOTHING`,
+		feedID, guid, articleID,
+	)
+	if err != nil {
+		return fmt.Errorf("record feed entry: %w", err)
+	}
+	return nil
+}
@@ -22,13 +22,13 @@ func NewHighlightRepo(db *pgxpool.Pool) *HighlightRepo {
 func (r *HighlightRepo) CreateHighlight(ctx context.Context, h *domain.Highlight) error {
 	err := r.db.QueryRow(ctx, `
 		INSERT INTO highlights (
-			id, article_id, user_id, text, start_offset, end_offset, color, note
+			id, article_id, user_id, text, start_offset, end_offset, page, color, note
 		) VALUES (
 			COALESCE(NULLIF($1, '')::uuid, uuid_generate_v4()),
-			$2::uuid, $3::uuid, $4, $5, $6, $7, $8
+			$2::uuid, $3::uuid, $4, $5, $6, $7, $8, $9
 		)
 		RETURNING id, created_at`,
-		h.ID, h.ArticleID, h.UserID, h.Text, h.StartOffset, h.EndOffset, h.Color, h.Note,
+		h.ID, h.ArticleID, h.UserID, h.Text, h.StartOffset, h.EndOffset, h.Page, h.Color, h.Note,
 	).Scan(&h.ID, &h.CreatedAt)
 	if err != nil {
 		return fmt.Errorf("create highlight: %w", err)
@@ -40,7 +40,7 @@ func (r *HighlightRepo) CreateHighlight(ctx context.Context, h *domain.Highlight
 // ordered by start_offset ascending.
 func (r *HighlightRepo) GetByArticle(ctx context.Context, articleID, userID string) ([]domain.Highlight, error) {
 	rows, err := r.db.Query(ctx, `
-		SELECT id, article_id, user_id, text, start_offset, end_offset, color, note, created_at
+		SELECT id, article_id, user_id, text, start_offset, end_offset, page, color, note, created_at
 		FROM highlights
 		WHERE article_id = $1::uuid AND user_id = $2::uuid
 		ORDER BY start_offset ASC`,
@@ -56,7 +56,39 @@ func (r *HighlightRepo) GetByArticle(ctx context.Context, articleID, userID stri
 		var h domain.Highlight
 		if err := rows.Scan(
 			&h.ID, &h.ArticleID, &h.UserID, &h.Text,
-			&h.StartOffset, &h.EndOffset, &h.Color, &h.Note, &h.CreatedAt,
+			&h.StartOffset, &h.EndOffset, &h.Page, &h.Color, &h.Note, &h.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan highlight: %w", err)
+		}
+		highlights = append(highlights, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate highlights: %w", err)
+	}
+	return highlights, nil
+}
+
+// ListByUser returns all of the user's highlights grouped by article and
+// ordered by start_offset within each article.
+func (r *HighlightRepo) ListByUser(ctx context.Context, userID string) ([]domain.Highlight, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, article_id, user_id, text, start_offset, end_offset, page, color, note, created_at
+		FROM highlights
+		WHERE user_id = $1::uuid
+		ORDER BY article_id, start_offset ASC`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query highlights by user: %w", err)
+	}
+	defer rows.Close()
+
+	highlights := make([]domain.Highlight, 0)
+	for rows.Next() {
+		var h domain.Highlight
+		if err := rows.Scan(
+			&h.ID, &h.ArticleID, &h.UserID, &h.Text,
+			&h.StartOffset, &h.EndOffset, &h.Page, &h.Color, &h.Note, &h.CreatedAt,
 		); err != nil {
 			return nil, fmt.Errorf("scan highlight: %w", err)
 		}
@@ -73,13 +105,13 @@ func (r *HighlightRepo) GetByArticle(ctx context.Context, articleID, userID stri
 func (r *HighlightRepo) GetByID(ctx context.Context, id, userID string) (*domain.Highlight, error) {
 	var h domain.Highlight
 	err := r.db.QueryRow(ctx, `
-		SELECT id, article_id, user_id, text, start_offset, end_offset, color, note, created_at
+		SELECT id, article_id, user_id, text, start_offset, end_offset, page, color, note, created_at
 		FROM highlights
 		WHERE id = $1::uuid AND user_id = $2::uuid`,
 		id, userID,
 	).Scan(
 		&h.ID, &h.ArticleID, &h.UserID, &h.Text,
-		&h.StartOffset, &h.EndOffset, &h.Color, &h.Note, &h.CreatedAt,
+		&h.StartOffset, &h.EndOffset, &h.Page, &h.Color, &h.Note, &h.CreatedAt,
 	)
 	if err == pgx.ErrNoRows {
 		return nil, nil
@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"folio-server/internal/domain"
+)
+
+type ImportRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewImportRepo(pool *pgxpool.Pool) *ImportRepo {
+	return &ImportRepo{pool: pool}
+}
+
+const importJobColumns = `id, user_id, source, status, total_items,
+	imported_items, skipped_items, failed_items, error, finished_at, created_at, updated_at`
+
+func scanImportJob(row pgx.Row) (*domain.ImportJob, error) {
+	var j domain.ImportJob
+	err := row.Scan(
+		&j.ID, &j.UserID, &j.Source, &j.Status, &j.TotalItems,
+		&j.ImportedItems, &j.SkippedItems, &j.FailedItems, &j.Error, &j.FinishedAt, &j.CreatedAt, &j.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// Create stores a job and all of its items in one transaction. Item
+// positions are assigned from the slice order.
+func (r *ImportRepo) Create(ctx context.Context, userID, source string, items []domain.ImportItem) (*domain.ImportJob, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	job, err := scanImportJob(tx.QueryRow(ctx, `
+		INSERT INTO import_jobs (user_id, source, total_items)
+		VALUES ($1, $2, $3)
+		RETURNING `+importJobColumns,
+		userID, source, len(items),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("insert import job: %w", err)
+	}
+
+	_, err = tx.CopyFrom(ctx,
+		pgx.Identifier{"import_items"},
+		[]string{"job_id", "position", "url", "source_type", "title", "tags", "saved_at", "is_favorite", "is_archived"},
+		pgx.CopyFromSlice(len(items), func(i int) ([]any, error) {
+			it := items[i]
+			tags := it.Tags
+			if tags == nil {
+				tags = []string{}
+			}
+			return []any{job.ID, i, it.URL, string(it.SourceType), it.Title, tags, it.SavedAt, it.IsFavorite, it.IsArchived}, nil
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("copy import items: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit import job: %w", err)
+	}
+	return job, nil
+}
+
+func (r *ImportRepo) GetByID(ctx context.Context, id string) (*domain.ImportJob, error) {
+	j, err := scanImportJob(r.pool.QueryRow(ctx,
+		`SELECT `+importJobColumns+` FROM import_jobs WHERE id = $1`, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get import job: %w", err)
+	}
+	return j, nil
+}
+
+// ListByUser returns a page of the user's import jobs, newest first, and the
+// total job count.
+func (r *ImportRepo) ListByUser(ctx context.Context, userID string, page, perPage int) ([]domain.ImportJob, int, error) {
+	var total int
+	if err := r.pool.QueryRow(ctx,
+		`SELECT COUNT(*) FROM import_jobs WHERE user_id = $1`, userID,
+	).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count import jobs: %w", err)
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+importJobColumns+` FROM import_jobs
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3`,
+		userID, perPage, (page-1)*perPage,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list import jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := make([]domain.ImportJob, 0)
+	for rows.Next() {
+		j, err := scanImportJob(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("scan import job: %w", err)
+		}
+		jobs = append(jobs, *j)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("iterate import jobs: %w", err)
+	}
+	return jobs, total, nil
+}
+
+// HasActive reports whether the user has a job that is still pending or running.
+func (r *ImportRepo) HasActive(ctx context.Context, userID string) (bool, error) {
+	var exists bool
+	err := r.pool.QueryRow(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM import_jobs
+			WHERE user_id = $1 AND status IN ($2, $3)
+		)`,
+		userID, string(domain.ImportStatusPending), string(domain.ImportStatusRunning),
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("check active import: %w", err)
+	}
+	return exists, nil
+}
+
+// ListPendingItems returns up to limit unprocessed items in position order.
+func (r *ImportRepo) ListPendingItems(ctx context.Context, jobID string, limit int) ([]domain.ImportItem, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT job_id, position, url, source_type, title, tags, saved_at, is_favorite, is_archived, status
+		FROM import_items
+		WHERE job_id = $1 AND status = $2
+		ORDER BY position
+		LIMIT $3`,
+		jobID, string(domain.ImportItemPending), limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list pending import items: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]domain.ImportItem, 0)
+	for rows.Next() {
+		var it domain.ImportItem
+		if err := rows.Scan(
+			&it.JobID, &it.Position, &it.URL, &it.SourceType, &it.Title, &it.Tags,
+			&it.SavedAt, &it.IsFavorite, &it.IsArchived, &it.Status,
+		); err != nil {
+			return nil, fmt.Errorf("scan import item: %w", err)
+		}
+		items = append(items, it)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate import items: %w", err)
+	}
+	return items, nil
+}
+
+// FinishItem records an item's outcome, including whether its article was
+// sent on to AI processing, and bumps the matching job counter. Items that
+// were already finished are left untouched, so retried batches don't double
+// count.
+func (r *ImportRepo) FinishItem(ctx context.Context, jobID string, position int, status domain.ImportItemStatus, withAI bool, articleID, errMsg *string) error {
+	_, err := r.pool.Exec(ctx, `
+		WITH item AS (
+			UPDATE import_items SET status = $3, article_id = $4, error = $5, ai = $10, finished_at = NOW()
+			WHERE job_id = $1 AND position = $2 AND status = $6
+			RETURNING status
+		)
+		UPDATE import_jobs SET
+			imported_items = imported_items + (SELECT COUNT(*) FROM item WHERE status = $7),
+			skipped_items  = skipped_items  + (SELECT COUNT(*) FROM item WHERE status = $8),
+			failed_items   = failed_items   + (SELECT COUNT(*) FROM item WHERE status = $9)
+		WHERE id = $1 AND EXISTS (SELECT 1 FROM item)`,
+		jobID, position, string(status), articleID, errMsg,
+		string(domain.ImportItemPending),
+		string(domain.ImportItemImported), string(domain.ImportItemSkipped), string(domain.ImportItemFailed),
+		withAI,
+	)
+	if err != nil {
+		return fmt.Errorf("finish import item: %w", err)
+	}
+	return nil
+}
+
+// CountAIItemsThisMonth returns how many of the user's imported items were
+// sent on to AI processing since the start of the current month.
+func (r *ImportRepo) CountAIItemsThisMonth(ctx context.Context, userID string) (int, error) {
+	var count int
+	err := r.pool.QueryRow(ctx, `
+		SELECT COUNT(*)
+		FROM import_items i
+		JOIN import_jobs j ON j.id = i.job_id
+		WHERE j.user_id = $1 AND i.ai
+		  AND i.finished_at >= date_trunc('month', NOW())`,
+		userID,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count import AI items: %w", err)
+	}
+	return count, nil
+}
+
+// SetStatus moves a job to status. Terminal states also record finished_at.
+func (r *ImportRepo) SetStatus(ctx context.Context, id string, status domain.ImportStatus, errMsg *string) error {
+	terminal := status == domain.ImportStatusCompleted || status == domain.ImportStatusFailed
+	_, err := r.pool.Exec(ctx, `
+		UPDATE import_jobs SET
+			status = $2,
+			error = COALESCE($3, error),
+			finished_at = CASE WHEN $4 THEN NOW() ELSE finished_at END
+		WHERE id = $1`,
+		id, string(status), errMsg, terminal,
+	)
+	if err != nil {
+		return fmt.Errorf("set import status: %w", err)
+	}
+	return nil
+}
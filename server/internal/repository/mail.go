@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"folio-server/internal/domain"
+)
+
+type MailRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewMailRepo(pool *pgxpool.Pool) *MailRepo {
+	return &MailRepo{pool: pool}
+}
+
+func (r *MailRepo) GetInboxByUser(ctx context.Context, userID string) (*domain.MailInbox, error) {
+	var in domain.MailInbox
+	err := r.pool.QueryRow(ctx,
+		`SELECT user_id, token, created_at, updated_at FROM mail_inboxes WHERE user_id = $1`, userID,
+	).Scan(&in.UserID, &in.Token, &in.CreatedAt, &in.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get inbox by user: %w", err)
+	}
+	return &in, nil
+}
+
+func (r *MailRepo) GetInboxByToken(ctx context.Context, token string) (*domain.MailInbox, error) {
+	var in domain.MailInbox
+	err := r.pool.QueryRow(ctx,
+		`SELECT user_id, token, created_at, updated_at FROM mail_inboxes WHERE token = $1`, token,
+	).Scan(&in.UserID, &in.Token, &in.CreatedAt, &in.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get inbox by token: %w", err)
+	}
+	return &in, nil
+}
+
+// UpsertInbox sets the user's inbox token, creating the inbox if needed.
+// Used both for first-time creation and for rotating the address.
+func (r *MailRepo) UpsertInbox(ctx context.Context, userID, token string) (*domain.MailInbox, error) {
+	var in domain.MailInbox
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO mail_inboxes (user_id, token)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET token = EXCLUDED.token
+		RETURNING user_id, token, created_at, updated_at`,
+		userID, token,
+	).Scan(&in.UserID, &in.Token, &in.CreatedAt, &in.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("upsert inbox: %w", err)
+	}
+	return &in, nil
+}
+
+func (r *MailRepo) ListSenders(ctx context.Context, userID string) ([]domain.MailSender, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT id, user_id, pattern, created_at FROM mail_allowed_senders
+		 WHERE user_id = $1 ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list senders: %w", err)
+	}
+	defer rows.Close()
+
+	senders := make([]domain.MailSender, 0)
+	for rows.Next() {
+		var s domain.MailSender
+		if err := rows.Scan(&s.ID, &s.UserID, &s.Pattern, &s.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan sender: %w", err)
+		}
+		senders = append(senders, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate senders: %w", err)
+	}
+	return senders, nil
+}
+
+func (r *MailRepo) AddSender(ctx context.Context, userID, pattern string) (*domain.MailSender, error) {
+	var s domain.MailSender
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO mail_allowed_senders (user_id, pattern)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, pattern) DO UPDATE SET pattern = EXCLUDED.pattern
+		RETURNING id, user_id, pattern, created_at`,
+		userID, pattern,
+	).Scan(&s.ID, &s.UserID, &s.Pattern, &s.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("add sender: %w", err)
+	}
+	return &s, nil
+}
+
+// DeleteSender removes an allow-list entry and reports whether it existed.
+func (r *MailRepo) DeleteSender(ctx context.Context, id, userID string) (bool, error) {
+	ct, err := r.pool.Exec(ctx,
+		`DELETE FROM mail_allowed_senders WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("delete sender: %w", err)
+	}
+	return ct.RowsAffected() > 0, nil
+}
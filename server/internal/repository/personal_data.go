@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PersonalDataRepo reads every row stored about a user, table by table, as
+// raw JSON. It backs the personal data export and deliberately selects whole
+// rows so that new columns are included without changes here.
+type PersonalDataRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewPersonalDataRepo(pool *pgxpool.Pool) *PersonalDataRepo {
+	return &PersonalDataRepo{pool: pool}
+}
+
+// PersonalDataSection is one table (or join) in the export. Query takes the
+// user ID as $1 and returns a single JSON column.
+type PersonalDataSection struct {
+	Name  string
+	Query string
+}
+
+// PersonalDataSections lists everything exported, in bundle order. Derived
+// data that is rebuilt from the rows below (embeddings, article relations,
+// the search index), refresh token hashes and the shared content cache are
+// not included.
+var PersonalDataSections = []PersonalDataSection{
+	{"profile", `SELECT to_jsonb(u) FROM users u WHERE u.id = $1`},
+	{"articles", `SELECT to_jsonb(a) - 'search_vector' FROM articles a WHERE a.user_id = $1 ORDER BY a.created_at, a.id`},
+	{"tags", `SELECT to_jsonb(t) FROM tags t WHERE t.user_id = $1 ORDER BY t.name`},
+	{"article_tags", `
+		SELECT to_jsonb(at) FROM article_tags at
+		JOIN articles a ON a.id = at.article_id
+		WHERE a.user_id = $1 ORDER BY at.article_id, at.tag_id`},
+	{"collections", `SELECT to_jsonb(c) FROM collections c WHERE c.user_id = $1 ORDER BY c.position, c.created_at`},
+	{"highlights", `SELECT to_jsonb(h) FROM highlights h WHERE h.user_id = $1 ORDER BY h.created_at, h.id`},
+	{"echo_cards", `SELECT to_jsonb(c) FROM echo_cards c WHERE c.user_id = $1 ORDER BY c.created_at, c.id`},
+	{"echo_reviews", `SELECT to_jsonb(r) FROM echo_reviews r WHERE r.user_id = $1 ORDER BY r.reviewed_at, r.id`},
+	{"rag_conversations", `SELECT to_jsonb(c) FROM rag_conversations c WHERE c.user_id = $1 ORDER BY c.created_at, c.id`},
+	{"rag_messages", `
+		SELECT to_jsonb(m) FROM rag_messages m
+		JOIN rag_conversations c ON c.id = m.conversation_id
+		WHERE c.user_id = $1 ORDER BY m.created_at, m.id`},
+	{"feeds", `SELECT to_jsonb(f) FROM feeds f WHERE f.user_id = $1 ORDER BY f.created_at, f.id`},
+	{"feed_entries", `
+		SELECT to_jsonb(e) FROM feed_entries e
+		JOIN feeds f ON f.id = e.feed_id
+		WHERE f.user_id = $1 ORDER BY e.created_at, e.id`},
+	{"mail_inboxes", `SELECT to_jsonb(i) FROM mail_inboxes i WHERE i.user_id = $1`},
+	{"mail_allowed_senders", `SELECT to_jsonb(s) FROM mail_allowed_senders s WHERE s.user_id = $1 ORDER BY s.created_at, s.id`},
+	{"crawl_tasks", `SELECT to_jsonb(t) FROM crawl_tasks t WHERE t.user_id = $1 ORDER BY t.created_at, t.id`},
+	{"import_jobs", `SELECT to_jsonb(j) FROM import_jobs j WHERE j.user_id = $1 ORDER BY j.created_at, j.id`},
+	{"import_items", `
+		SELECT to_jsonb(i) FROM import_items i
+		JOIN import_jobs j ON j.id = i.job_id
+		WHERE j.user_id = $1 ORDER BY i.job_id, i.position`},
+	{"export_jobs", `SELECT to_jsonb(j) FROM export_jobs j WHERE j.user_id = $1 ORDER BY j.created_at, j.id`},
+	{"subscription_events", `SELECT to_jsonb(e) FROM subscription_events e WHERE e.user_id = $1 ORDER BY e.signed_at, e.id`},
+	{"sessions", `SELECT to_jsonb(s) FROM auth_sessions s WHERE s.user_id = $1 ORDER BY s.created_at, s.id`},
+	{"devices", `SELECT to_jsonb(d) FROM devices d WHERE d.user_id = $1 ORDER BY d.created_at, d.id`},
+	{"milestones", `SELECT to_jsonb(m) FROM user_milestones m WHERE m.user_id = $1 ORDER BY m.achieved_at, m.id`},
+	{"activity_logs", `SELECT to_jsonb(l) FROM activity_logs l WHERE l.user_id = $1 ORDER BY l.created_at, l.id`},
+}
+
+// Each streams the rows of one section to fn without loading the whole
+// section into memory.
+func (r *PersonalDataRepo) Each(ctx context.Context, userID string, section PersonalDataSection, fn func(row json.RawMessage) error) error {
+	rows, err := r.pool.Query(ctx, section.Query, userID)
+	if err != nil {
+		return fmt.Errorf("query %s: %w", section.Name, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row []byte
+		if err := rows.Scan(&row); err != nil {
+			return fmt.Errorf("scan %s: %w", section.Name, err)
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate %s: %w", section.Name, err)
+	}
+	return nil
+}
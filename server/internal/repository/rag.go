@@ -21,14 +21,16 @@ func NewRAGRepo(db *pgxpool.Pool) *RAGRepo {
 	return &RAGRepo{db: db}
 }
 
-// LoadArticleSummaries returns all ready articles for a user with their summaries.
-func (r *RAGRepo) LoadArticleSummaries(ctx context.Context, userID string) ([]domain.RAGSource, error) {
+// LoadArticleSummaries returns all ready articles for a user within scope
+// with their summaries.
+func (r *RAGRepo) LoadArticleSummaries(ctx context.Context, userID string, scope *domain.RAGScope) ([]domain.RAGSource, error) {
+	scoped, scopeArgs := ragScopeSQL("a", scope, 2)
 	rows, err := r.db.Query(ctx, `
-		SELECT id, title, summary, site_name, created_at
-		FROM articles
-		WHERE user_id = $1 AND status = 'ready'
-		ORDER BY created_at DESC`,
-		userID,
+		SELECT a.id, a.title, a.summary, a.site_name, a.created_at
+		FROM articles a
+		WHERE a.user_id = $1 AND a.status = 'ready'`+scoped+`
+		ORDER BY a.created_at DESC`,
+		append([]any{userID}, scopeArgs...)...,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("load article summaries: %w", err)
@@ -49,25 +51,27 @@ func (r *RAGRepo) LoadArticleSummaries(ctx context.Context, userID string) ([]do
 	return sources, nil
 }
 
-// SearchArticleSummaries uses pg_trgm similarity search on title + summary.
-func (r *RAGRepo) SearchArticleSummaries(ctx context.Context, userID, query string, limit int) ([]domain.RAGSource, error) {
+// SearchArticleSummaries uses pg_trgm similarity search on title + summary,
+// within scope.
+func (r *RAGRepo) SearchArticleSummaries(ctx context.Context, userID, query string, limit int, scope *domain.RAGScope) ([]domain.RAGSource, error) {
+	scoped, scopeArgs := ragScopeSQL("a", scope, 4)
 	rows, err := r.db.Query(ctx, `
 		SELECT
-			id, title, summary, site_name, created_at,
+			a.id, a.title, a.summary, a.site_name, a.created_at,
 			GREATEST(
-				similarity(title, $2),
-				COALESCE(similarity(summary, $2), 0)
+				similarity(a.title, $2),
+				COALESCE(similarity(a.summary, $2), 0)
 			) AS relevance
-		FROM articles
-		WHERE user_id = $1
-		  AND status = 'ready'
+		FROM articles a
+		WHERE a.user_id = $1
+		  AND a.status = 'ready'
 		  AND (
-			similarity(title, $2) > 0.1
-			OR similarity(summary, $2) > 0.1
-		  )
+			similarity(a.title, $2) > 0.1
+			OR similarity(a.summary, $2) > 0.1
+		  )`+scoped+`
 		ORDER BY relevance DESC
 		LIMIT $3`,
-		userID, query, limit,
+		append([]any{userID, query, limit}, scopeArgs...)...,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("search article summaries: %w", err)
@@ -88,8 +92,9 @@ func (r *RAGRepo) SearchArticleSummaries(ctx context.Context, userID, query stri
 	return sources, nil
 }
 
-// BroadRecallSummaries does multi-path keyword recall for RAG and related articles.
-func (r *RAGRepo) BroadRecallSummaries(ctx context.Context, userID string, keywords []string, limit int, excludeID string) ([]domain.RAGSource, error) {
+// BroadRecallSummaries does multi-path keyword recall for RAG and related
+// articles, within scope.
+func (r *RAGRepo) BroadRecallSummaries(ctx context.Context, userID string, keywords []string, limit int, excludeID string, scope *domain.RAGScope) ([]domain.RAGSource, error) {
 	cleaned := make([]string, len(keywords))
 	escaped := make([]string, len(keywords))
 	for i, kw := range keywords {
@@ -106,6 +111,7 @@ func (r *RAGRepo) BroadRecallSummaries(ctx context.Context, userID string, keywo
 		excludeUUID = excludeID
 	}
 
+	scoped, scopeArgs := ragScopeSQL("a", scope, 6)
 	rows, err := r.db.Query(ctx, `
 		WITH keyword_matches AS (
 			SELECT DISTINCT ON (a.id)
@@ -119,7 +125,7 @@ func (r *RAGRepo) BroadRecallSummaries(ctx context.Context, userID string, keywo
 			WHERE a.user_id = $1
 				AND a.status = 'ready'
 				AND a.deleted_at IS NULL
-				AND ($5::uuid IS NULL OR a.id != $5)
+				AND ($5::uuid IS NULL OR a.id != $5)`+scoped+`
 				AND (
 					a.semantic_keywords && $2::text[]
 					OR EXISTS (SELECT 1 FROM unnest($2::text[]) kw WHERE a.title % kw)
@@ -132,7 +138,7 @@ func (r *RAGRepo) BroadRecallSummaries(ctx context.Context, userID string, keywo
 		FROM keyword_matches
 		ORDER BY score DESC
 		LIMIT $4`,
-		userID, cleaned, escaped, limit, excludeUUID,
+		append([]any{userID, cleaned, escaped, limit, excludeUUID}, scopeArgs...)...,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("broad recall summaries: %w", err)
@@ -179,7 +185,12 @@ func (r *RAGRepo) AddMessage(ctx context.Context, msg *domain.RAGMessage) error
 		return fmt.Errorf("marshal source_article_ids: %w", err)
 	}
 
+	// Adding a message counts as activity on the conversation, which orders
+	// the conversation list.
 	err = r.db.QueryRow(ctx, `
+		WITH touched AS (
+			UPDATE rag_conversations SET updated_at = NOW() WHERE id = $2::uuid
+		)
 		INSERT INTO rag_messages (id, conversation_id, role, content, source_article_ids, source_count)
 		VALUES (
 			COALESCE(NULLIF($1, '')::uuid, uuid_generate_v4()),
@@ -194,15 +205,25 @@ func (r *RAGRepo) AddMessage(ctx context.Context, msg *domain.RAGMessage) error
 	return nil
 }
 
-// GetConversationMessages returns messages for a conversation ordered by created_at ASC.
-func (r *RAGRepo) GetConversationMessages(ctx context.Context, conversationID string, limit int) ([]domain.RAGMessage, error) {
+// GetConversationMessages returns the latest limit messages of one of the
+// user's conversations, oldest first. A limit of 0 returns them all.
+func (r *RAGRepo) GetConversationMessages(ctx context.Context, userID, conversationID string, limit int) ([]domain.RAGMessage, error) {
+	var limitArg any
+	if limit > 0 {
+		limitArg = limit
+	}
 	rows, err := r.db.Query(ctx, `
 		SELECT id, conversation_id, role, content, source_article_ids, source_count, created_at
-		FROM rag_messages
-		WHERE conversation_id = $1
-		ORDER BY created_at ASC
-		LIMIT $2`,
-		conversationID, limit,
+		FROM (
+			SELECT m.*
+			FROM rag_messages m
+			JOIN rag_conversations c ON c.id = m.conversation_id
+			WHERE m.conversation_id = $1 AND c.user_id = $2
+			ORDER BY m.created_at DESC, m.role DESC
+			LIMIT $3
+		) latest
+		ORDER BY created_at ASC, role DESC`,
+		conversationID, userID, limitArg,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("query conversation messages: %w", err)
@@ -235,6 +256,143 @@ func (r *RAGRepo) GetConversationMessages(ctx context.Context, conversationID st
 	return msgs, nil
 }
 
+// GetConversation returns a conversation by ID, or nil if it doesn't exist.
+func (r *RAGRepo) GetConversation(ctx context.Context, conversationID string) (*domain.RAGConversation, error) {
+	var c domain.RAGConversation
+	err := r.db.QueryRow(ctx, `
+		SELECT id, user_id, title, created_at, updated_at
+		FROM rag_conversations
+		WHERE id = $1`,
+		conversationID,
+	).Scan(&c.ID, &c.UserID, &c.Title, &c.CreatedAt, &c.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get rag conversation: %w", err)
+	}
+	return &c, nil
+}
+
+// ListRAGConversationsResult is a page of conversations with the total count.
+type ListRAGConversationsResult struct {
+	Conversations []domain.RAGConversation
+	Total         int
+}
+
+// ListConversations returns a page of the user's conversations, most
+// recently active first.
+func (r *RAGRepo) ListConversations(ctx context.Context, userID string, page, perPage int) (*ListRAGConversationsResult, error) {
+	var total int
+	if err := r.db.QueryRow(ctx,
+		`SELECT COUNT(*) FROM rag_conversations WHERE user_id = $1`,
+		userID,
+	).Scan(&total); err != nil {
+		return nil, fmt.Errorf("count rag conversations: %w", err)
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT id, user_id, title, created_at, updated_at
+		FROM rag_conversations
+		WHERE user_id = $1
+		ORDER BY updated_at DESC, id
+		LIMIT $2 OFFSET $3`,
+		userID, perPage, (page-1)*perPage,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list rag conversations: %w", err)
+	}
+	defer rows.Close()
+
+	convs := make([]domain.RAGConversation, 0)
+	for rows.Next() {
+		var c domain.RAGConversation
+		if err := rows.Scan(&c.ID, &c.UserID, &c.Title, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan rag conversation: %w", err)
+		}
+		convs = append(convs, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rag conversations: %w", err)
+	}
+	return &ListRAGConversationsResult{Conversations: convs, Total: total}, nil
+}
+
+// UpdateConversationTitle renames one of the user's conversations.
+func (r *RAGRepo) UpdateConversationTitle(ctx context.Context, conversationID, userID, title string) error {
+	_, err := r.db.Exec(ctx,
+		`UPDATE rag_conversations SET title = $3 WHERE id = $1 AND user_id = $2`,
+		conversationID, userID, title,
+	)
+	if err != nil {
+		return fmt.Errorf("update rag conversation title: %w", err)
+	}
+	return nil
+}
+
+// ReplaceProvisionalTitle sets a generated title on a conversation whose
+// title is still provisional, so that it never overwrites a rename. It
+// reports whether the title was replaced.
+func (r *RAGRepo) ReplaceProvisionalTitle(ctx context.Context, conversationID, userID, provisional, title string) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE rag_conversations SET title = $4
+		WHERE id = $1 AND user_id = $2 AND title IS NOT DISTINCT FROM NULLIF($3, '')`,
+		conversationID, userID, provisional, title,
+	)
+	if err != nil {
+		return false, fmt.Errorf("replace rag conversation title: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// DeleteConversation deletes one of the user's conversations with its
+// messages.
+func (r *RAGRepo) DeleteConversation(ctx context.Context, conversationID, userID string) error {
+	_, err := r.db.Exec(ctx,
+		`DELETE FROM rag_conversations WHERE id = $1 AND user_id = $2`,
+		conversationID, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("delete rag conversation: %w", err)
+	}
+	return nil
+}
+
+// LoadSourcesByIDs returns the user's articles among articleIDs, in no
+// particular order. Deleted articles are left out.
+func (r *RAGRepo) LoadSourcesByIDs(ctx context.Context, userID string, articleIDs []string) ([]domain.RAGSource, error) {
+	sources := make([]domain.RAGSource, 0, len(articleIDs))
+	if len(articleIDs) == 0 {
+		return sources, nil
+	}
+	rows, err := r.db.Query(ctx, `
+		SELECT id, COALESCE(title, ''), summary, key_points, site_name, created_at
+		FROM articles
+		WHERE user_id = $1 AND id = ANY($2::uuid[]) AND deleted_at IS NULL`,
+		userID, articleIDs,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("load rag sources: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s domain.RAGSource
+		var kpJSON []byte
+		if err := rows.Scan(&s.ArticleID, &s.Title, &s.Summary, &kpJSON, &s.SiteName, &s.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan rag source: %w", err)
+		}
+		if len(kpJSON) > 0 {
+			json.Unmarshal(kpJSON, &s.KeyPoints)
+		}
+		sources = append(sources, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rag sources: %w", err)
+	}
+	return sources, nil
+}
+
 // GetUserRAGQuota returns rag_count_this_month and rag_month_reset_at for a user.
 func (r *RAGRepo) GetUserRAGQuota(ctx context.Context, userID string) (count int, resetAt *time.Time, err error) {
 	err = r.db.QueryRow(ctx,
@@ -273,3 +431,45 @@ func (r *RAGRepo) IncrementRAGMonthCount(ctx context.Context, userID string) err
 	}
 	return nil
 }
+
+// ragScopeSQL returns conditions restricting the articles aliased a to
+// scope, each prefixed with AND, with their arguments numbered from next.
+// An empty scope gives no conditions.
+func ragScopeSQL(a string, scope *domain.RAGScope, next int) (string, []any) {
+	if scope.IsEmpty() {
+		return "", nil
+	}
+	var b strings.Builder
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", next+len(args)-1)
+	}
+
+	if len(scope.TagIDs) > 0 {
+		fmt.Fprintf(&b, ` AND EXISTS (SELECT 1 FROM article_tags st WHERE st.article_id = %s.id AND st.tag_id = ANY(%s::uuid[]))`, a, arg(scope.TagIDs))
+	}
+	if len(scope.CategorySlugs) > 0 {
+		fmt.Fprintf(&b, ` AND %s.category_id IN (SELECT id FROM categories WHERE slug = ANY(%s::text[]))`, a, arg(scope.CategorySlugs))
+	}
+	if scope.CreatedAfter != nil {
+		fmt.Fprintf(&b, ` AND %s.created_at >= %s`, a, arg(*scope.CreatedAfter))
+	}
+	if scope.CreatedBefore != nil {
+		fmt.Fprintf(&b, ` AND %s.created_at < %s`, a, arg(*scope.CreatedBefore))
+	}
+	if len(scope.SourceTypes) > 0 {
+		types := make([]string, len(scope.SourceTypes))
+		for i, t := range scope.SourceTypes {
+			types[i] = string(t)
+		}
+		fmt.Fprintf(&b, ` AND %s.source_type = ANY(%s::text[])`, a, arg(types))
+	}
+	if scope.FavoritesOnly {
+		fmt.Fprintf(&b, ` AND %s.is_favorite`, a)
+	}
+	if len(scope.ArticleIDs) > 0 {
+		fmt.Fprintf(&b, ` AND %s.id = ANY(%s::uuid[])`, a, arg(scope.ArticleIDs))
+	}
+	return b.String(), args
+}
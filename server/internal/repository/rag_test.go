@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"folio-server/internal/domain"
+)
+
+func TestRAGScopeSQL(t *testing.T) {
+	if sql, args := ragScopeSQL("a", nil, 2); sql != "" || args != nil {
+		t.Errorf("nil scope = %q, %v", sql, args)
+	}
+	if sql, _ := ragScopeSQL("a", &domain.RAGScope{}, 2); sql != "" {
+		t.Errorf("empty scope = %q", sql)
+	}
+
+	after := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	sql, args := ragScopeSQL("a", &domain.RAGScope{
+		TagIDs:        []string{"t1"},
+		CreatedAfter:  &after,
+		SourceTypes:   []domain.SourceType{domain.SourcePDF},
+		FavoritesOnly: true,
+	}, 4)
+
+	for _, want := range []string{
+		"st.article_id = a.id AND st.tag_id = ANY($4::uuid[])",
+		"a.created_at >= $5",
+		"a.source_type = ANY($6::text[])",
+		"AND a.is_favorite",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("missing %q in %q", want, sql)
+		}
+	}
+	if !strings.HasPrefix(sql, " AND ") || strings.Contains(sql, "$7") {
+		t.Errorf("sql = %q", sql)
+	}
+	if len(args) != 3 || args[1] != after || args[2].([]string)[0] != "pdf" {
+		t.Errorf("args = %v", args)
+	}
+}
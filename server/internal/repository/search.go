@@ -0,0 +1,370 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"folio-server/internal/domain"
+)
+
+// searchHeadlineOptions configures ts_headline. Snippets are cut from
+// unigram-spaced text, so MaxWords counts single CJK characters.
+const searchHeadlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxWords=40, MinWords=15, MaxFragments=2, FragmentDelimiter=\" … \""
+
+// searchArgs collects query arguments, numbering placeholders from next.
+type searchArgs struct {
+	next int
+	args []any
+}
+
+func (s *searchArgs) add(v any) string {
+	s.args = append(s.args, v)
+	return fmt.Sprintf("$%d", s.next+len(s.args)-1)
+}
+
+// searchTermsSQL is the free text of a SearchQuery as tsquery expressions.
+type searchTermsSQL struct {
+	match    string   // tsquery of the positive terms for search vectors; "" if none
+	headline string   // the same terms as a tsquery over folio_cjk_unigrams text
+	exclude  []string // tsqueries of the negated terms
+}
+
+// where returns conditions, each prefixed with AND, matching the terms
+// against the tsvector expression vector.
+func (t searchTermsSQL) where(vector string) string {
+	var b strings.Builder
+	if t.match != "" {
+		fmt.Fprintf(&b, ` AND %s @@ %s`, vector, t.match)
+	}
+	for _, x := range t.exclude {
+		fmt.Fprintf(&b, ` AND NOT COALESCE(%s @@ %s, false)`, vector, x)
+	}
+	return b.String()
+}
+
+// compileSearchTerms builds the tsqueries for terms. Bare words go through
+// folio_fts_query together, which also handles single CJK characters; a
+// phrase is matched as a whole.
+func compileSearchTerms(terms []domain.SearchTerm, args *searchArgs) searchTermsSQL {
+	tsquery := func(t domain.SearchTerm) (string, string) {
+		p := args.add(t.Text)
+		if t.Phrase {
+			return fmt.Sprintf(`phraseto_tsquery('simple', folio_cjk_bigrams(%s))`, p),
+				fmt.Sprintf(`phraseto_tsquery('simple', folio_cjk_unigrams(%s))`, p)
+		}
+		return fmt.Sprintf(`folio_fts_query(%s)`, p), fmt.Sprintf(`folio_fts_query(%s, false)`, p)
+	}
+
+	var out searchTermsSQL
+	var words, match, headline []string
+	for _, t := range terms {
+		switch {
+		case t.Negated:
+			m, _ := tsquery(t)
+			out.exclude = append(out.exclude, m)
+		case t.Phrase:
+			m, h := tsquery(t)
+			match, headline = append(match, m), append(headline, h)
+		default:
+			words = append(words, t.Text)
+		}
+	}
+	if len(words) > 0 {
+		m, h := tsquery(domain.SearchTerm{Text: strings.Join(words, " ")})
+		match, headline = append([]string{m}, match...), append([]string{h}, headline...)
+	}
+	if len(match) > 0 {
+		out.match = "(" + strings.Join(match, " && ") + ")"
+		out.headline = "(" + strings.Join(headline, " && ") + ")"
+	}
+	return out
+}
+
+// compileSearchFilters returns conditions, each prefixed with AND,
+// restricting the articles aliased a to filters.
+func compileSearchFilters(a string, filters []domain.SearchFilter, args *searchArgs) string {
+	var b strings.Builder
+	for _, f := range filters {
+		var cond string
+		switch f.Field {
+		case domain.SearchFieldTag:
+			cond = fmt.Sprintf(`EXISTS (SELECT 1 FROM article_tags qt JOIN tags t ON t.id = qt.tag_id
+				WHERE qt.article_id = %s.id AND lower(t.name) = lower(%s))`, a, args.add(f.Value))
+		case domain.SearchFieldSite:
+			// Matches the site name, or the URL host and its subdomains.
+			cond = fmt.Sprintf(`lower(%[1]s.site_name) = %[2]s OR EXISTS (
+				SELECT 1 FROM lower(substring(%[1]s.url from '^[^:/]+://(?:[^@/]*@)?([^/:?#]+)')) AS u(host)
+				WHERE u.host = %[2]s OR right(u.host, length(%[2]s) + 1) = '.' || %[2]s)`, a, args.add(f.Value))
+		case domain.SearchFieldCategory:
+			cond = fmt.Sprintf(`%s.category_id IN (SELECT id FROM categories WHERE slug = %s)`, a, args.add(f.Value))
+		case domain.SearchFieldSource:
+			cond = fmt.Sprintf(`%s.source_type = %s`, a, args.add(f.Value))
+		case domain.SearchFieldIs:
+			if f.Value == domain.SearchIsArchived {
+				cond = a + `.is_archived`
+			} else {
+				cond = a + `.is_favorite`
+			}
+		case domain.SearchFieldHas:
+			cond = a + `.highlight_count > 0`
+		case domain.SearchFieldBefore:
+			cond = fmt.Sprintf(`%s.created_at < %s`, a, args.add(f.Date))
+		case domain.SearchFieldAfter:
+			cond = fmt.Sprintf(`%s.created_at >= %s`, a, args.add(f.Date))
+		default:
+			continue
+		}
+		if f.Negated {
+			fmt.Fprintf(&b, ` AND NOT COALESCE(%s, false)`, cond)
+		} else {
+			fmt.Fprintf(&b, ` AND (%s)`, cond)
+		}
+	}
+	return b.String()
+}
+
+// searchSQL is a SearchQuery compiled for the articles aliased a.
+type searchSQL struct {
+	where    string // conditions, each prefixed with AND
+	match    string // tsquery of the positive terms for search_vector; "" if none
+	headline string // the same terms as a tsquery over folio_cjk_unigrams text
+	args     []any
+}
+
+// searchQuerySQL compiles q for the articles aliased a, numbering its
+// arguments from next. Every value is passed as an argument.
+func searchQuerySQL(a string, q *domain.SearchQuery, next int) searchSQL {
+	if q == nil {
+		return searchSQL{}
+	}
+	args := &searchArgs{next: next}
+	terms := compileSearchTerms(q.Terms, args)
+	filters := compileSearchFilters(a, q.Filters, args)
+	return searchSQL{
+		where:    terms.where(a+".search_vector") + filters,
+		match:    terms.match,
+		headline: terms.headline,
+		args:     args.args,
+	}
+}
+
+// SearchRepo searches across the user's articles, highlights, Echo cards
+// and RAG messages at once. Article-only search lives in ArticleRepo.Search.
+type SearchRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewSearchRepo(pool *pgxpool.Pool) *SearchRepo {
+	return &SearchRepo{pool: pool}
+}
+
+type UnifiedSearchParams struct {
+	UserID  string
+	Query   *domain.SearchQuery
+	Types   []domain.SearchResultType // empty means all
+	Page    int
+	PerPage int
+}
+
+type UnifiedSearchResult struct {
+	Results []domain.SearchResult
+	Total   int
+}
+
+// Search ranks matches of every requested type together by ts_rank_cd.
+// Query must have a positive term. Its filters apply to the article a
+// result belongs to, so they leave out RAG messages, which belong to none.
+// Rows of soft-deleted articles are never returned.
+func (r *SearchRepo) Search(ctx context.Context, p UnifiedSearchParams) (*UnifiedSearchResult, error) {
+	offset := (p.Page - 1) * p.PerPage
+
+	args := &searchArgs{next: 2}
+	terms := compileSearchTerms(p.Query.Terms, args)
+	filters := compileSearchFilters("a", p.Query.Filters, args)
+	if terms.match == "" {
+		return &UnifiedSearchResult{Results: make([]domain.SearchResult, 0)}, nil
+	}
+	hits := unifiedSearchHitsSQL(terms, filters, len(p.Query.Filters) > 0, p.Types)
+	if hits == "" {
+		return &UnifiedSearchResult{Results: make([]domain.SearchResult, 0)}, nil
+	}
+	queryArgs := append([]any{p.UserID}, args.args...)
+
+	var total int
+	err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM (`+hits+`) hits`, queryArgs...).Scan(&total)
+	if err != nil {
+		return nil, fmt.Errorf("count unified search: %w", err)
+	}
+
+	n := len(queryArgs)
+	queryArgs = append(queryArgs, searchHeadlineOptions, p.PerPage, offset)
+
+	// Hits carry only keys so the sort stays narrow; titles and headlines
+	// are fetched for the page alone.
+	rows, err := r.pool.Query(ctx, fmt.Sprintf(`
+		WITH hits AS (%s),
+		page AS (
+			SELECT * FROM hits ORDER BY rank DESC, created_at DESC, id LIMIT $%d OFFSET $%d
+		)
+		SELECT p.kind, p.id, p.rank, p.created_at,
+			CASE p.kind
+				WHEN 'article' THEN a.title
+				WHEN 'echo_card' THEN c.question
+				WHEN 'rag_message' THEN rc.title
+				ELSE ha.title
+			END,
+			ts_headline('simple', folio_cjk_unigrams(CASE p.kind
+				WHEN 'article' THEN COALESCE(NULLIF(left(a.markdown_content, 100000), ''), a.summary, '')
+				WHEN 'highlight' THEN concat_ws(E'\n', h.text, h.note)
+				WHEN 'echo_card' THEN concat_ws(E'\n', c.question, c.answer)
+				ELSE left(m.content, 100000)
+			END), %s, $%d),
+			COALESCE(a.id, h.article_id, c.article_id), h.start_offset, h.end_offset, h.note,
+			m.conversation_id
+		FROM page p
+		LEFT JOIN articles a ON p.kind = 'article' AND a.id = p.id
+		LEFT JOIN highlights h ON p.kind = 'highlight' AND h.id = p.id
+		LEFT JOIN echo_cards c ON p.kind = 'echo_card' AND c.id = p.id
+		LEFT JOIN articles ha ON ha.id = COALESCE(h.article_id, c.article_id)
+		LEFT JOIN rag_messages m ON p.kind = 'rag_message' AND m.id = p.id
+		LEFT JOIN rag_conversations rc ON rc.id = m.conversation_id
+		ORDER BY p.rank DESC, p.created_at DESC, p.id`,
+		hits, n+2, n+3, terms.headline, n+1),
+		queryArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("unified search: %w", err)
+	}
+	defer rows.Close()
+
+	results := make([]domain.SearchResult, 0)
+	for rows.Next() {
+		var res domain.SearchResult
+		var headline, articleID, conversationID *string
+		var start, end *int
+		if err := rows.Scan(&res.Type, &res.ID, &res.Rank, &res.CreatedAt, &res.Title, &headline,
+			&articleID, &start, &end, &res.Note, &conversationID); err != nil {
+			return nil, fmt.Errorf("scan unified search result: %w", err)
+		}
+		if headline != nil {
+			if snippet := searchSnippet(*headline); snippet != "" {
+				res.Snippet = &snippet
+			}
+		}
+		id := res.ID
+		res.Target.ArticleID = articleID
+		switch res.Type {
+		case domain.SearchResultHighlight:
+			res.Target.HighlightID = &id
+			res.Target.StartOffset, res.Target.EndOffset = start, end
+		case domain.SearchResultEchoCard:
+			res.Target.CardID = &id
+		case domain.SearchResultRAGMessage:
+			res.Target.ConversationID = conversationID
+			res.Target.MessageID = &id
+		}
+		results = append(results, res)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate unified search results: %w", err)
+	}
+
+	return &UnifiedSearchResult{Results: results, Total: total}, nil
+}
+
+// unifiedSearchHitsSQL returns a UNION of (kind, id, rank, created_at) for
+// every matching row of the requested types, or "" if none can match. $1
+// is the user ID. Each branch matches the expression its search index is
+// built on (migrations 026 and 027).
+func unifiedSearchHitsSQL(terms searchTermsSQL, filters string, filtered bool, types []domain.SearchResultType) string {
+	want := func(t domain.SearchResultType) bool {
+		return len(types) == 0 || slices.Contains(types, t)
+	}
+	// branch selects hits from rows aliased x, whose search vector is vector.
+	branch := func(kind domain.SearchResultType, x, vector, from, where string) string {
+		return fmt.Sprintf(`SELECT '%[1]s'::text AS kind, %[2]s.id, ts_rank_cd(%[3]s, %[4]s) AS rank, %[2]s.created_at
+			FROM %[5]s WHERE %[6]s%[7]s`,
+			kind, x, vector, terms.match, from, where, terms.where(vector))
+	}
+
+	var branches []string
+	if want(domain.SearchResultArticle) {
+		branches = append(branches, branch(domain.SearchResultArticle, "a", "a.search_vector",
+			"articles a", "a.user_id = $1 AND a.deleted_at IS NULL")+filters)
+	}
+	if want(domain.SearchResultHighlight) {
+		branches = append(branches, branch(domain.SearchResultHighlight, "h", "folio_highlight_search_vector(h.text, h.note)",
+			"highlights h JOIN articles a ON a.id = h.article_id", "h.user_id = $1 AND a.deleted_at IS NULL")+filters)
+	}
+	if want(domain.SearchResultEchoCard) {
+		branches = append(branches, branch(domain.SearchResultEchoCard, "c", "folio_echo_card_search_vector(c.question, c.answer)",
+			"echo_cards c JOIN articles a ON a.id = c.article_id", "c.user_id = $1 AND a.deleted_at IS NULL")+filters)
+	}
+	if want(domain.SearchResultRAGMessage) && !filtered {
+		branches = append(branches, branch(domain.SearchResultRAGMessage, "m", "folio_rag_message_search_vector(m.content)",
+			"rag_messages m JOIN rag_conversations rc ON rc.id = m.conversation_id", "rc.user_id = $1"))
+	}
+	return strings.Join(branches, "\n\t\tUNION ALL\n\t\t")
+}
+
+// searchSnippet turns a ts_headline over folio_cjk_unigrams text back into
+// readable text: spaces next to CJK characters were inserted for the
+// tokenizer and are dropped, adjacent highlights are merged, and the
+// remaining whitespace is collapsed.
+func searchSnippet(headline string) string {
+	const open, closing = "<mark>", "</mark>"
+
+	type piece struct {
+		tag string
+		r   rune
+	}
+	var pieces []piece
+	for i := 0; i < len(headline); {
+		switch {
+		case strings.HasPrefix(headline[i:], open):
+			pieces = append(pieces, piece{tag: open})
+			i += len(open)
+		case strings.HasPrefix(headline[i:], closing):
+			pieces = append(pieces, piece{tag: closing})
+			i += len(closing)
+		default:
+			r, size := utf8.DecodeRuneInString(headline[i:])
+			pieces = append(pieces, piece{r: r})
+			i += size
+		}
+	}
+
+	// neighbour returns the nearest non-space rune before (step -1) or
+	// after (step 1) position i, skipping tags.
+	neighbour := func(i, step int) rune {
+		for j := i + step; j >= 0 && j < len(pieces); j += step {
+			if pieces[j].tag == "" && !unicode.IsSpace(pieces[j].r) {
+				return pieces[j].r
+			}
+		}
+		return 0
+	}
+
+	var b strings.Builder
+	for i, p := range pieces {
+		switch {
+		case p.tag != "":
+			b.WriteString(p.tag)
+		case unicode.IsSpace(p.r):
+			if isCJK(neighbour(i, -1)) || isCJK(neighbour(i, 1)) {
+				continue
+			}
+			b.WriteRune(' ')
+		default:
+			b.WriteRune(p.r)
+		}
+	}
+
+	out := strings.ReplaceAll(b.String(), closing+open, "")
+	return strings.Join(strings.Fields(out), " ")
+}
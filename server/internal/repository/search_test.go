@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+
+	"folio-server/internal/domain"
+)
+
+func TestSearchSnippet(t *testing.T) {
+	tests := []struct {
+		name     string
+		headline string
+		want     string
+	}{
+		{"latin", "the <mark>quick</mark> brown fox", "the <mark>quick</mark> brown fox"},
+		{"latin adjacent hits", "<mark>quick</mark> <mark>brown</mark>", "<mark>quick</mark> <mark>brown</mark>"},
+		{"cjk merged", " 深 <mark>机</mark> <mark>器</mark> <mark>学</mark> <mark>习</mark> 入 门 ", "深<mark>机器学习</mark>入门"},
+		{"mixed", " 用 <mark>Go</mark>  写 服 务 ", "用<mark>Go</mark>写服务"},
+		{"whitespace collapsed", "one\n\ntwo   three", "one two three"},
+		{"fragments", " 甲 <mark>乙</mark>  …  <mark>丙</mark> 丁 ", "甲<mark>乙</mark>…<mark>丙</mark>丁"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := searchSnippet(tt.headline); got != tt.want {
+				t.Errorf("searchSnippet(%q) = %q, want %q", tt.headline, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchQuerySQL(t *testing.T) {
+	if sq := searchQuerySQL("a", nil, 2); sq.where != "" || sq.match != "" || sq.args != nil {
+		t.Errorf("nil query = %+v", sq)
+	}
+
+	q, err := domain.ParseSearchQuery(`agents "tool use" -crypto memory tag:go -is:archived before:2026-01-01`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sq := searchQuerySQL("a", q, 2)
+
+	for _, want := range []string{
+		"AND NOT COALESCE(a.search_vector @@ folio_fts_query($3), false)",
+		"AND a.search_vector @@ (folio_fts_query($4) && phraseto_tsquery('simple', folio_cjk_bigrams($2)))",
+		"lower(t.name) = lower($5)",
+		"AND NOT COALESCE(a.is_archived, false)",
+		"AND (a.created_at < $6)",
+	} {
+		if !strings.Contains(sq.where, want) {
+			t.Errorf("missing %q in %q", want, sq.where)
+		}
+	}
+	if sq.headline != "(folio_fts_query($4, false) && phraseto_tsquery('simple', folio_cjk_unigrams($2)))" {
+		t.Errorf("headline = %q", sq.headline)
+	}
+	if len(sq.args) != 5 || sq.args[0] != "tool use" || sq.args[1] != "crypto" ||
+		sq.args[2] != "agents memory" || sq.args[3] != "go" {
+		t.Errorf("args = %v", sq.args)
+	}
+
+	// Filters alone match nothing against the index.
+	q, _ = domain.ParseSearchQuery(`site:example.com`)
+	sq = searchQuerySQL("a", q, 2)
+	if sq.match != "" || strings.Contains(sq.where, "search_vector") || len(sq.args) != 1 {
+		t.Errorf("filter-only query = %+v", sq)
+	}
+}
+
+func TestUnifiedSearchHitsSQL(t *testing.T) {
+	q, _ := domain.ParseSearchQuery(`transformer -crypto`)
+	terms := compileSearchTerms(q.Terms, &searchArgs{next: 2})
+
+	sql := unifiedSearchHitsSQL(terms, "", false, nil)
+	for _, want := range []string{
+		"'article'::text AS kind",
+		"folio_highlight_search_vector(h.text, h.note) @@ (folio_fts_query($3))",
+		"NOT COALESCE(folio_echo_card_search_vector(c.question, c.answer) @@ folio_fts_query($2), false)",
+		"ts_rank_cd(folio_rag_message_search_vector(m.content), (folio_fts_query($3)))",
+		"rc.user_id = $1",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("missing %q in %q", want, sql)
+		}
+	}
+	if n := strings.Count(sql, "UNION ALL"); n != 3 {
+		t.Errorf("got %d UNION ALLs, want 3", n)
+	}
+
+	// Article filters leave out RAG messages, which have no article.
+	sql = unifiedSearchHitsSQL(terms, " AND (a.is_favorite)", true, nil)
+	if strings.Contains(sql, "rag_messages") || strings.Count(sql, "AND (a.is_favorite)") != 3 {
+		t.Errorf("filtered = %q", sql)
+	}
+
+	sql = unifiedSearchHitsSQL(terms, "", false, []domain.SearchResultType{domain.SearchResultHighlight})
+	if strings.Contains(sql, "UNION") || !strings.Contains(sql, "FROM highlights h") {
+		t.Errorf("highlights only = %q", sql)
+	}
+	if sql := unifiedSearchHitsSQL(terms, " AND (a.is_favorite)", true, []domain.SearchResultType{domain.SearchResultRAGMessage}); sql != "" {
+		t.Errorf("filtered RAG only = %q, want empty", sql)
+	}
+}
@@ -0,0 +1,264 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"folio-server/internal/domain"
+)
+
+type SessionRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewSessionRepo(pool *pgxpool.Pool) *SessionRepo {
+	return &SessionRepo{pool: pool}
+}
+
+const sessionColumns = `id, user_id, device_name, user_agent,
+	expires_at, revoked_at, last_used_at, created_at`
+
+func scanSession(row pgx.Row) (*domain.Session, error) {
+	var s domain.Session
+	err := row.Scan(
+		&s.ID, &s.UserID, &s.DeviceName, &s.UserAgent,
+		&s.ExpiresAt, &s.RevokedAt, &s.LastUsedAt, &s.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+type CreateSessionParams struct {
+	UserID     string
+	DeviceName *string
+	UserAgent  *string
+	TokenHash  []byte
+	ExpiresAt  time.Time
+}
+
+// Create starts a session together with its first refresh token.
+func (r *SessionRepo) Create(ctx context.Context, p CreateSessionParams) (*domain.Session, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	s, err := scanSession(tx.QueryRow(ctx, `
+		INSERT INTO auth_sessions (user_id, device_name, user_agent, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+sessionColumns,
+		p.UserID, p.DeviceName, p.UserAgent, p.ExpiresAt,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("insert session: %w", err)
+	}
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO refresh_tokens (session_id, token_hash) VALUES ($1, $2)`,
+		s.ID, p.TokenHash,
+	); err != nil {
+		return nil, fmt.Errorf("insert refresh token: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit session: %w", err)
+	}
+	return s, nil
+}
+
+// AdoptLegacyToken starts a session for a refresh token issued at issuedAt,
+// before sessions existed. The legacy token is recorded in
+// legacy_refresh_tokens, so it is adopted only once, and stored already used,
+// under p.TokenHash, next to its replacement newHash, so presenting it again
+// while the session keeps its used tokens looks like reuse of a rotated
+// token. It returns nil, nil if the legacy token was adopted before,
+// including by a concurrent request, or was issued before the user last
+// signed out their other devices.
+func (r *SessionRepo) AdoptLegacyToken(ctx context.Context, p CreateSessionParams, issuedAt time.Time, newHash []byte) (*domain.Session, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO legacy_refresh_tokens (token_hash, user_id)
+		SELECT $1, id FROM users
+		WHERE id = $2 AND (sessions_revoked_at IS NULL OR sessions_revoked_at < $3)
+		ON CONFLICT (token_hash) DO NOTHING`,
+		p.TokenHash, p.UserID, issuedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("record legacy refresh token: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, nil
+	}
+
+	s, err := scanSession(tx.QueryRow(ctx, `
+		INSERT INTO auth_sessions (user_id, device_name, user_agent, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+sessionColumns,
+		p.UserID, p.DeviceName, p.UserAgent, p.ExpiresAt,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("insert session: %w", err)
+	}
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO refresh_tokens (session_id, token_hash, used_at) VALUES ($1, $2, NOW())`,
+		s.ID, p.TokenHash,
+	); err != nil {
+		return nil, fmt.Errorf("insert legacy refresh token: %w", err)
+	}
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO refresh_tokens (session_id, token_hash) VALUES ($1, $2)`,
+		s.ID, newHash,
+	); err != nil {
+		return nil, fmt.Errorf("insert refresh token: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit session: %w", err)
+	}
+	return s, nil
+}
+
+// GetByTokenHash returns the refresh token with the given hash and the
+// session it belongs to, or nil, nil if no such token exists.
+func (r *SessionRepo) GetByTokenHash(ctx context.Context, hash []byte) (*domain.RefreshToken, *domain.Session, error) {
+	var t domain.RefreshToken
+	var s domain.Session
+	err := r.pool.QueryRow(ctx, `
+		SELECT t.id, t.session_id, t.used_at, t.created_at,
+		       s.id, s.user_id, s.device_name, s.user_agent,
+		       s.expires_at, s.revoked_at, s.last_used_at, s.created_at
+		FROM refresh_tokens t
+		JOIN auth_sessions s ON s.id = t.session_id
+		WHERE t.token_hash = $1`, hash,
+	).Scan(
+		&t.ID, &t.SessionID, &t.UsedAt, &t.CreatedAt,
+		&s.ID, &s.UserID, &s.DeviceName, &s.UserAgent,
+		&s.ExpiresAt, &s.RevokedAt, &s.LastUsedAt, &s.CreatedAt,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("get refresh token: %w", err)
+	}
+	return &t, &s, nil
+}
+
+// Rotate marks the token used and stores its replacement, extending the
+// session to expiresAt. It returns false if the token had already been used,
+// which happens when two refreshes race with the same token. Used tokens of
+// the session older than keepUsed are pruned.
+func (r *SessionRepo) Rotate(ctx context.Context, tokenID, sessionID string, newHash []byte, expiresAt time.Time, keepUsed time.Duration) (bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx,
+		`UPDATE refresh_tokens SET used_at = NOW() WHERE id = $1 AND used_at IS NULL`, tokenID)
+	if err != nil {
+		return false, fmt.Errorf("mark refresh token used: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO refresh_tokens (session_id, token_hash) VALUES ($1, $2)`,
+		sessionID, newHash,
+	); err != nil {
+		return false, fmt.Errorf("insert refresh token: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE auth_sessions SET last_used_at = NOW(), expires_at = $2
+		WHERE id = $1`, sessionID, expiresAt,
+	); err != nil {
+		return false, fmt.Errorf("touch session: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM refresh_tokens
+		WHERE session_id = $1 AND used_at < NOW() - make_interval(secs => $2)`,
+		sessionID, keepUsed.Seconds(),
+	); err != nil {
+		return false, fmt.Errorf("prune refresh tokens: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("commit rotation: %w", err)
+	}
+	return true, nil
+}
+
+// ListActive returns the user's sessions that are neither revoked nor
+// expired, most recently used first.
+func (r *SessionRepo) ListActive(ctx context.Context, userID string) ([]domain.Session, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+sessionColumns+` FROM auth_sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_used_at DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := make([]domain.Session, 0)
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan session: %w", err)
+		}
+		sessions = append(sessions, *s)
+	}
+	return sessions, rows.Err()
+}
+
+// Revoke ends one of the user's sessions. It returns false if the session
+// does not exist, belongs to someone else or was already revoked.
+func (r *SessionRepo) Revoke(ctx context.Context, userID, sessionID string) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE auth_sessions SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`, sessionID, userID)
+	if err != nil {
+		return false, fmt.Errorf("revoke session: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// RevokeOthers ends every session of the user except keepID and returns the
+// IDs of the sessions it revoked. Legacy refresh tokens issued before now
+// are no longer adopted.
+func (r *SessionRepo) RevokeOthers(ctx context.Context, userID, keepID string) ([]string, error) {
+	rows, err := r.pool.Query(ctx, `
+		WITH cutoff AS (
+			UPDATE users SET sessions_revoked_at = NOW() WHERE id = $1
+		)
+		UPDATE auth_sessions SET revoked_at = NOW()
+		WHERE user_id = $1 AND id::text <> $2 AND revoked_at IS NULL
+		RETURNING id`, userID, keepID)
+	if err != nil {
+		return nil, fmt.Errorf("revoke sessions: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan session id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
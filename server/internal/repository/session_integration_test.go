@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSessionRepo_AdoptLegacyToken(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	repo := NewSessionRepo(pool)
+
+	userID := testUser(t, pool)
+	issuedAt := time.Now().Add(-24 * time.Hour)
+	adopt := func(legacy, next string) bool {
+		t.Helper()
+		sess, err := repo.AdoptLegacyToken(ctx, CreateSessionParams{
+			UserID:    userID,
+			TokenHash: []byte(legacy),
+			ExpiresAt: time.Now().Add(time.Hour),
+		}, issuedAt, []byte(next))
+		if err != nil {
+			t.Fatalf("AdoptLegacyToken(%s): %v", legacy, err)
+		}
+		return sess != nil
+	}
+
+	if !adopt("legacy-1", "next-1") {
+		t.Fatal("legacy token not adopted")
+	}
+	if adopt("legacy-1", "next-2") {
+		t.Error("legacy token adopted twice")
+	}
+	// Pruning the session's used tokens must not make it adoptable again.
+	if _, err := pool.Exec(ctx, `DELETE FROM refresh_tokens WHERE token_hash = $1`, []byte("legacy-1")); err != nil {
+		t.Fatal(err)
+	}
+	if adopt("legacy-1", "next-3") {
+		t.Error("legacy token adopted again after its refresh token was pruned")
+	}
+
+	if _, err := repo.RevokeOthers(ctx, userID, ""); err != nil {
+		t.Fatal(err)
+	}
+	if adopt("legacy-2", "next-4") {
+		t.Error("adopted a legacy token issued before the user signed out other devices")
+	}
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"folio-server/internal/domain"
+)
+
+// Monthly article quotas per subscription tier.
+const (
+	freeMonthlyQuota = 30
+	proMonthlyQuota  = 9999
+)
+
+type SubscriptionRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewSubscriptionRepo(pool *pgxpool.Pool) *SubscriptionRepo {
+	return &SubscriptionRepo{pool: pool}
+}
+
+// Record stores ev and, when it belongs to a user and is not older than the
+// last applied event, applies it to the user's subscription in the same
+// transaction. It fills in ev.ID, ev.FromState and ev.Applied.
+//
+// Events are idempotent on NotificationUUID: a redelivered notification is
+// not stored again and Record returns false.
+func (r *SubscriptionRepo) Record(ctx context.Context, ev *domain.SubscriptionEvent) (bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	ev.FromState, ev.Applied = nil, false
+	if ev.UserID != nil {
+		var state domain.SubscriptionState
+		var lastEventAt *time.Time
+		err := tx.QueryRow(ctx, `
+			SELECT subscription_state, subscription_event_at
+			FROM users WHERE id = $1 FOR UPDATE`, *ev.UserID,
+		).Scan(&state, &lastEventAt)
+		if err != nil && err != pgx.ErrNoRows {
+			return false, fmt.Errorf("lock user subscription: %w", err)
+		}
+		if err == nil {
+			ev.FromState = &state
+			ev.Applied = lastEventAt == nil || !ev.SignedAt.Before(*lastEventAt)
+		}
+	}
+
+	var toState *string
+	if ev.ToState != nil {
+		s := string(*ev.ToState)
+		toState = &s
+	}
+	var fromState *string
+	if ev.FromState != nil {
+		s := string(*ev.FromState)
+		fromState = &s
+	}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO subscription_events (
+			user_id, notification_uuid, notification_type, subtype,
+			original_transaction_id, transaction_id, product_id,
+			from_state, to_state, expires_at, auto_renew, applied, signed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (notification_uuid) DO NOTHING
+		RETURNING id, created_at`,
+		ev.UserID, ev.NotificationUUID, ev.NotificationType, ev.Subtype,
+		ev.OriginalTransactionID, ev.TransactionID, ev.ProductID,
+		fromState, toState, ev.ExpiresAt, ev.AutoRenew, ev.Applied, ev.SignedAt,
+	).Scan(&ev.ID, &ev.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("insert subscription event: %w", err)
+	}
+
+	if ev.Applied {
+		state := *ev.FromState
+		if ev.ToState != nil {
+			state = *ev.ToState
+		}
+		quota := freeMonthlyQuota
+		if state.Tier() == domain.SubscriptionPro {
+			quota = proMonthlyQuota
+		}
+		_, err := tx.Exec(ctx, `
+			UPDATE users
+			SET subscription_state = $2,
+			    subscription = $3,
+			    monthly_quota = $4,
+			    subscription_expires_at = COALESCE($5, subscription_expires_at),
+			    subscription_auto_renew = COALESCE($6, subscription_auto_renew),
+			    original_transaction_id = COALESCE($7, original_transaction_id),
+			    subscription_event_at = $8
+			WHERE id = $1`,
+			*ev.UserID, string(state), string(state.Tier()), quota,
+			ev.ExpiresAt, ev.AutoRenew, ev.OriginalTransactionID, ev.SignedAt,
+		)
+		if err != nil {
+			return false, fmt.Errorf("apply subscription event: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("commit subscription event: %w", err)
+	}
+	return true, nil
+}
@@ -3,7 +3,6 @@ package repository
 import (
 	"context"
 	"fmt"
-	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -21,7 +20,8 @@ func NewUserRepo(pool *pgxpool.Pool) *UserRepo {
 
 // userColumns is the canonical SELECT column list for users.
 const userColumns = `id, apple_id, email, nickname, avatar_url,
-	subscription, subscription_expires_at, original_transaction_id,
+	subscription, subscription_state, subscription_auto_renew,
+	subscription_expires_at, original_transaction_id,
 	monthly_quota, current_month_count, quota_reset_at, preferred_language,
 	created_at, updated_at, sync_epoch,
 	echo_count_this_week, echo_week_reset_at`
@@ -31,7 +31,8 @@ func scanUser(row pgx.Row) (*domain.User, error) {
 	var u domain.User
 	err := row.Scan(
 		&u.ID, &u.AppleID, &u.Email, &u.Nickname, &u.AvatarURL,
-		&u.Subscription, &u.SubscriptionExpiresAt, &u.OriginalTransactionID,
+		&u.Subscription, &u.SubscriptionState, &u.SubscriptionAutoRenew,
+		&u.SubscriptionExpiresAt, &u.OriginalTransactionID,
 		&u.MonthlyQuota, &u.CurrentMonthCount, &u.QuotaResetAt, &u.PreferredLanguage,
 		&u.CreatedAt, &u.UpdatedAt, &u.SyncEpoch,
 		&u.EchoCountThisWeek, &u.EchoWeekResetAt,
@@ -139,28 +140,6 @@ func (r *UserRepo) AtomicResetAndIncrement(ctx context.Context, id string) (int,
 	return newCount, nil
 }
 
-// UpdateSubscription sets the user's subscription tier, expiry, and stores the
-// Apple original_transaction_id for webhook lookup. Pro users get 9999 quota.
-func (r *UserRepo) UpdateSubscription(ctx context.Context, userID string, subscription domain.Subscription, expiresAt *time.Time, originalTxnID *string) error {
-	quota := 30 // free tier default
-	if subscription == domain.SubscriptionPro {
-		quota = 9999
-	}
-	_, err := r.pool.Exec(ctx, `
-		UPDATE users
-		SET subscription = $2,
-		    subscription_expires_at = $3,
-		    original_transaction_id = COALESCE($4, original_transaction_id),
-		    monthly_quota = $5
-		WHERE id = $1`,
-		userID, string(subscription), expiresAt, originalTxnID, quota,
-	)
-	if err != nil {
-		return fmt.Errorf("update subscription: %w", err)
-	}
-	return nil
-}
-
 // GetByOriginalTransactionID finds a user by their Apple original transaction ID.
 func (r *UserRepo) GetByOriginalTransactionID(ctx context.Context, txnID string) (*domain.User, error) {
 	u, err := scanUser(r.pool.QueryRow(ctx,
@@ -173,3 +152,13 @@ func (r *UserRepo) GetByOriginalTransactionID(ctx context.Context, txnID string)
 	}
 	return u, nil
 }
+
+// Delete removes the user row. Everything the user owns is removed with it
+// through ON DELETE CASCADE foreign keys.
+func (r *UserRepo) Delete(ctx context.Context, id string) error {
+	_, err := r.pool.Exec(ctx, `DELETE FROM users WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete user: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package revocation
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore is an in-process Store. Expired entries are dropped when they
+// are read and swept whenever an entry is written.
+type MemoryStore struct {
+	mu      sync.Mutex
+	revoked map[string]time.Time // value: end of the revocation
+	now     func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		revoked: make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) Revoke(_ context.Context, key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for k, until := range s.revoked {
+		if !now.Before(until) {
+			delete(s.revoked, k)
+		}
+	}
+	s.revoked[key] = now.Add(ttl)
+	return nil
+}
+
+func (s *MemoryStore) IsRevoked(_ context.Context, keys ...string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for _, key := range keys {
+		until, ok := s.revoked[key]
+		if !ok {
+			continue
+		}
+		if now.Before(until) {
+			return true, nil
+		}
+		delete(s.revoked, key)
+	}
+	return false, nil
+}
@@ -0,0 +1,32 @@
+package revocation
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore_RevocationExpires(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	if err := s.Revoke(ctx, "session:s1", 2*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := s.IsRevoked(ctx, "user:u1", "session:s1"); !ok {
+		t.Error("revoked session not reported")
+	}
+	if ok, _ := s.IsRevoked(ctx, "user:u1", "session:s2"); ok {
+		t.Error("unrevoked keys reported as revoked")
+	}
+
+	now = now.Add(2 * time.Hour)
+	if ok, _ := s.IsRevoked(ctx, "session:s1"); ok {
+		t.Error("revocation outlived its ttl")
+	}
+	if len(s.revoked) != 0 {
+		t.Errorf("expired revocation still stored: %v", s.revoked)
+	}
+}
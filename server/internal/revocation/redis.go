@@ -0,0 +1,42 @@
+package revocation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const keyPrefix = "folio:revoked:"
+
+// RedisStore is a Store backed by Redis, shared by every API replica.
+type RedisStore struct {
+	rdb redis.UniversalClient
+}
+
+func NewRedisStore(rdb redis.UniversalClient) *RedisStore {
+	return &RedisStore{rdb: rdb}
+}
+
+func (s *RedisStore) Revoke(ctx context.Context, key string, ttl time.Duration) error {
+	if err := s.rdb.Set(ctx, keyPrefix+key, 1, ttl).Err(); err != nil {
+		return fmt.Errorf("revoke: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) IsRevoked(ctx context.Context, keys ...string) (bool, error) {
+	if len(keys) == 0 {
+		return false, nil
+	}
+	prefixed := make([]string, len(keys))
+	for i, k := range keys {
+		prefixed[i] = keyPrefix + k
+	}
+	n, err := s.rdb.Exists(ctx, prefixed...).Result()
+	if err != nil {
+		return false, fmt.Errorf("check revocation: %w", err)
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,24 @@
+// Package revocation remembers revoked access tokens until they would have
+// expired anyway. Access tokens are stateless JWTs, so signing a session or
+// a user out only takes effect once every API replica rejects the tokens
+// already issued to it.
+//
+// RedisStore shares revocations between API replicas and survives restarts;
+// MemoryStore keeps them in process and is meant for tests and
+// single-process development.
+package revocation
+
+import (
+	"context"
+	"time"
+)
+
+// Store keeps revoked keys, such as a user or session ID. Every entry
+// expires on its own; nothing needs to be cleaned up by the caller.
+type Store interface {
+	// Revoke marks key revoked for ttl.
+	Revoke(ctx context.Context, key string, ttl time.Duration) error
+
+	// IsRevoked reports whether any of keys is currently revoked.
+	IsRevoked(ctx context.Context, keys ...string) (bool, error)
+}
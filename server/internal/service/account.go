@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/hibiken/asynq"
+
+	"folio-server/internal/client"
+	"folio-server/internal/domain"
+	"folio-server/internal/repository"
+	"folio-server/internal/worker"
+)
+
+type accountUserStore interface {
+	GetByID(ctx context.Context, id string) (*domain.User, error)
+	Delete(ctx context.Context, id string) error
+}
+
+type accountArticleLister interface {
+	ListIDsByUser(ctx context.Context, userID string) ([]string, error)
+}
+
+type tokenRevoker interface {
+	RevokeUser(ctx context.Context, userID string) error
+}
+
+// AccountService deletes accounts. Database rows are removed through
+// cascading foreign keys; objects in R2 are purged by the account:purge
+// worker afterwards.
+type AccountService struct {
+	userRepo    accountUserStore
+	articleRepo accountArticleLister
+	auth        tokenRevoker
+	asynqClient taskEnqueuer
+	hasStorage  bool
+}
+
+func NewAccountService(
+	userRepo *repository.UserRepo,
+	articleRepo *repository.ArticleRepo,
+	authService *AuthService,
+	r2Client *client.R2Client,
+	asynqClient *asynq.Client,
+) *AccountService {
+	return &AccountService{
+		userRepo:    userRepo,
+		articleRepo: articleRepo,
+		auth:        authService,
+		asynqClient: asynqClient,
+		hasStorage:  r2Client != nil,
+	}
+}
+
+// storagePrefixes lists every R2 prefix holding the user's objects.
+func storagePrefixes(userID string, articleIDs []string) []string {
+	prefixes := []string{
+		"mail/" + userID + "/",
+		"pdf/" + userID + "/",
+		"exports/" + userID + "/",
+	}
+	for _, id := range articleIDs {
+		prefixes = append(prefixes, "articles/"+id+"/images/")
+	}
+	return prefixes
+}
+
+// Delete permanently deletes the user's account and everything stored
+// about them, and revokes their tokens.
+func (s *AccountService) Delete(ctx context.Context, userID string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrNotFound
+	}
+
+	// Article IDs are needed for the image prefixes and are gone after the delete.
+	articleIDs, err := s.articleRepo.ListIDsByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userRepo.Delete(ctx, userID); err != nil {
+		return fmt.Errorf("delete account: %w", err)
+	}
+	if err := s.auth.RevokeUser(ctx, userID); err != nil {
+		// The account is already gone and its refresh tokens with it; the
+		// access tokens it still holds run out within two hours.
+		slog.Error("failed to revoke deleted user's tokens", "user_id", userID, "error", err)
+	}
+
+	if s.hasStorage {
+		task := worker.NewAccountPurgeTask(userID, storagePrefixes(userID, articleIDs))
+		if _, err := s.asynqClient.EnqueueContext(ctx, task); err != nil {
+			// The account is already gone; the orphaned objects are only
+			// reachable through their unguessable URLs.
+			slog.Error("failed to enqueue account purge", "user_id", userID, "articles", len(articleIDs), "error", err)
+		}
+	}
+
+	slog.Info("account deleted", "user_id", userID, "articles", len(articleIDs))
+	return nil
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"folio-server/internal/domain"
+	"folio-server/internal/revocation"
+	"folio-server/internal/worker"
+)
+
+type mockAccountUsers struct {
+	user    *domain.User
+	deleted bool
+}
+
+func (m *mockAccountUsers) GetByID(_ context.Context, _ string) (*domain.User, error) {
+	return m.user, nil
+}
+
+func (m *mockAccountUsers) Delete(_ context.Context, _ string) error {
+	m.deleted = true
+	return nil
+}
+
+type mockArticleIDs struct{ ids []string }
+
+func (m *mockArticleIDs) ListIDsByUser(_ context.Context, _ string) ([]string, error) {
+	return m.ids, nil
+}
+
+type mockRevoker struct{ revoked []string }
+
+func (m *mockRevoker) RevokeUser(_ context.Context, userID string) error {
+	m.revoked = append(m.revoked, userID)
+	return nil
+}
+
+func TestAccountService_Delete(t *testing.T) {
+	users := &mockAccountUsers{user: &domain.User{ID: "user-1"}}
+	revoker := &mockRevoker{}
+	enq := &mockEnqueuer{}
+	s := &AccountService{
+		userRepo:    users,
+		articleRepo: &mockArticleIDs{ids: []string{"a1", "a2"}},
+		auth:        revoker,
+		asynqClient: enq,
+		hasStorage:  true,
+	}
+
+	if err := s.Delete(context.Background(), "user-1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if !users.deleted || len(revoker.revoked) != 1 {
+		t.Errorf("deleted = %v, revoked = %v", users.deleted, revoker.revoked)
+	}
+	if len(enq.enqueuedTasks) != 1 || enq.enqueuedTasks[0].Type() != worker.TypeAccountPurge {
+		t.Fatalf("enqueued %v, want one purge task", enq.enqueuedTasks)
+	}
+
+	var p worker.AccountPurgePayload
+	if err := json.Unmarshal(enq.enqueuedTasks[0].Payload(), &p); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"mail/user-1/", "pdf/user-1/", "exports/user-1/", "articles/a1/images/", "articles/a2/images/"}
+	if len(p.Prefixes) != len(want) {
+		t.Fatalf("prefixes = %v, want %v", p.Prefixes, want)
+	}
+	for i := range want {
+		if p.Prefixes[i] != want[i] {
+			t.Errorf("prefix %d = %q, want %q", i, p.Prefixes[i], want[i])
+		}
+	}
+}
+
+func TestAccountService_Delete_WithoutStorage(t *testing.T) {
+	enq := &mockEnqueuer{}
+	s := &AccountService{
+		userRepo:    &mockAccountUsers{user: &domain.User{ID: "user-1"}},
+		articleRepo: &mockArticleIDs{},
+		auth:        &mockRevoker{},
+		asynqClient: enq,
+	}
+	if err := s.Delete(context.Background(), "user-1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if len(enq.enqueuedTasks) != 0 {
+		t.Errorf("enqueued %d tasks without storage configured", len(enq.enqueuedTasks))
+	}
+}
+
+func TestAccountService_Delete_UnknownUser(t *testing.T) {
+	users := &mockAccountUsers{}
+	s := &AccountService{userRepo: users, articleRepo: &mockArticleIDs{}, auth: &mockRevoker{}, asynqClient: &mockEnqueuer{}}
+	if err := s.Delete(context.Background(), "user-1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+	if users.deleted {
+		t.Error("deleted a user that does not exist")
+	}
+}
+
+func TestAuthService_RevokeUser(t *testing.T) {
+	s := &AuthService{jwtSecret: []byte("secret"), sessions: newMockSessions(), revocations: revocation.NewMemoryStore()}
+	pair, err := s.issueTokenPair(context.Background(), &domain.User{ID: "user-1"}, SessionInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.ValidateAccessToken(context.Background(), pair.AccessToken); err != nil {
+		t.Fatalf("token rejected before revocation: %v", err)
+	}
+	if err := s.RevokeUser(context.Background(), "user-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.ValidateAccessToken(context.Background(), pair.AccessToken); !errors.Is(err, ErrForbidden) {
+		t.Errorf("err = %v after revocation, want ErrForbidden", err)
+	}
+}
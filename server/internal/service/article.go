@@ -75,6 +75,7 @@ type SubmitManualContentRequest struct {
 	TagIDs     []string `json:"tag_ids,omitempty"`
 	ClientID   *string  `json:"client_id,omitempty"`
 	SourceType string   `json:"source_type,omitempty"`
+	Author     *string  `json:"author,omitempty"`
 }
 
 func (s *ArticleService) SubmitURL(ctx context.Context, userID string, req SubmitURLRequest) (*SubmitURLResponse, error) {
@@ -177,6 +178,7 @@ func (s *ArticleService) SubmitManualContent(ctx context.Context, userID string,
 		URL:             nil,
 		SourceType:      sourceType,
 		Title:           req.Title,
+		Author:          req.Author,
 		MarkdownContent: &req.Content,
 		WordCount:       &wordCount,
 		ClientID:        req.ClientID,
@@ -211,7 +213,11 @@ func (s *ArticleService) SubmitManualContent(ctx context.Context, userID string,
 	if req.Title != nil {
 		title = *req.Title
 	}
-	aiTask := worker.NewAIProcessTask(article.ID, task.ID, userID, title, req.Content, string(sourceType), "")
+	author := ""
+	if req.Author != nil {
+		author = *req.Author
+	}
+	aiTask := worker.NewAIProcessTask(article.ID, task.ID, userID, title, req.Content, string(sourceType), author)
 	if _, err := s.asynqClient.EnqueueContext(ctx, aiTask); err != nil {
 		_ = s.quotaService.DecrementQuota(ctx, userID)
 		return nil, fmt.Errorf("enqueue ai process: %w", err)
@@ -258,6 +264,24 @@ func (s *ArticleService) ListByUser(ctx context.Context, params repository.ListA
 	return s.articleRepo.ListByUser(ctx, params)
 }
 
+// ListPage lists articles with keyset pagination, continuing after cursor,
+// or from the start when it is "". The total is counted only on request.
+// A cursor from a browse listing is rejected on a sync and vice versa.
+func (s *ArticleService) ListPage(ctx context.Context, params repository.ListArticlesParams, cursor string, withTotal bool) (*repository.ArticlePage, error) {
+	var after *repository.ArticleCursor
+	if cursor != "" {
+		c, err := repository.DecodeArticleCursor(cursor)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		if c.Sync != (params.UpdatedSince != nil) {
+			return nil, ErrInvalidCursor
+		}
+		after = c
+	}
+	return s.articleRepo.ListPage(ctx, params, after, withTotal)
+}
+
 func (s *ArticleService) Update(ctx context.Context, userID, articleID string, params repository.UpdateArticleParams) error {
 	article, err := s.articleRepo.GetByID(ctx, articleID)
 	if err != nil {
@@ -286,8 +310,17 @@ func (s *ArticleService) Delete(ctx context.Context, userID, articleID string) e
 	return s.articleRepo.Delete(ctx, articleID, userID)
 }
 
+// Search parses query (see domain.SearchQuery) and runs it. A malformed
+// filter returns ErrInvalidSearchQuery wrapping the reason.
 func (s *ArticleService) Search(ctx context.Context, userID, query string, page, perPage int) (*repository.ListArticlesResult, error) {
-	return s.articleRepo.Search(ctx, userID, query, page, perPage)
+	q, err := domain.ParseSearchQuery(query)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSearchQuery, err)
+	}
+	if q.IsEmpty() {
+		return nil, fmt.Errorf("%w: nothing to search for", ErrInvalidSearchQuery)
+	}
+	return s.articleRepo.Search(ctx, userID, q, page, perPage)
 }
 
 // SemanticSearch does LLM-powered search: expand query → broad recall → LLM rerank.
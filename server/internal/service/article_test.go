@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/hibiken/asynq"
 
@@ -22,7 +23,9 @@ type mockArticleRepo struct {
 	listByUserFn func(ctx context.Context, p repository.ListArticlesParams) (*repository.ListArticlesResult, error)
 	updateFn     func(ctx context.Context, id string, userID string, p repository.UpdateArticleParams) error
 	deleteFn     func(ctx context.Context, id string, userID string) error
-	searchFn     func(ctx context.Context, userID, query string, page, perPage int) (*repository.ListArticlesResult, error)
+	searchFn     func(ctx context.Context, userID string, q *domain.SearchQuery, page, perPage int) (*repository.ListArticlesResult, error)
+
+	listPageAfter *repository.ArticleCursor
 }
 
 func (m *mockArticleRepo) Create(ctx context.Context, p repository.CreateArticleParams) (*domain.Article, error) {
@@ -40,6 +43,11 @@ func (m *mockArticleRepo) GetByID(ctx context.Context, id string) (*domain.Artic
 	return nil, nil
 }
 
+func (m *mockArticleRepo) ListPage(ctx context.Context, p repository.ListArticlesParams, after *repository.ArticleCursor, withTotal bool) (*repository.ArticlePage, error) {
+	m.listPageAfter = after
+	return &repository.ArticlePage{Articles: []domain.Article{}}, nil
+}
+
 func (m *mockArticleRepo) ListByUser(ctx context.Context, p repository.ListArticlesParams) (*repository.ListArticlesResult, error) {
 	if m.listByUserFn != nil {
 		return m.listByUserFn(ctx, p)
@@ -61,9 +69,9 @@ func (m *mockArticleRepo) Delete(ctx context.Context, id string, userID string)
 	return nil
 }
 
-func (m *mockArticleRepo) Search(ctx context.Context, userID, query string, page, perPage int) (*repository.ListArticlesResult, error) {
+func (m *mockArticleRepo) Search(ctx context.Context, userID string, q *domain.SearchQuery, page, perPage int) (*repository.ListArticlesResult, error) {
 	if m.searchFn != nil {
-		return m.searchFn(ctx, userID, query, page, perPage)
+		return m.searchFn(ctx, userID, q, page, perPage)
 	}
 	return &repository.ListArticlesResult{}, nil
 }
@@ -1038,3 +1046,55 @@ func TestSubmitURL_CrawlTaskAlwaysEnqueued_WithoutContent(t *testing.T) {
 		t.Errorf("crawl payload URL = %q, want %q", crawlPayload.URL, "https://example.com/article-url-only")
 	}
 }
+
+func TestSearch_ParsesQuery(t *testing.T) {
+	var got *domain.SearchQuery
+	artRepo := &mockArticleRepo{
+		searchFn: func(ctx context.Context, userID string, q *domain.SearchQuery, page, perPage int) (*repository.ListArticlesResult, error) {
+			got = q
+			return &repository.ListArticlesResult{}, nil
+		},
+	}
+	svc := newTestArticleService(artRepo, &mockTaskRepo{}, &mockTagRepo{}, &mockCategoryRepo{}, &mockQuotaService{}, &mockEnqueuer{})
+
+	if _, err := svc.Search(context.Background(), "user-1", `agents tag:go -is:archived`, 1, 20); err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if got == nil || len(got.Terms) != 1 || len(got.Filters) != 2 {
+		t.Fatalf("query passed to repo = %+v", got)
+	}
+
+	for _, q := range []string{`source:fax`, `before:yesterday`, `""`} {
+		got = nil
+		_, err := svc.Search(context.Background(), "user-1", q, 1, 20)
+		if !errors.Is(err, ErrInvalidSearchQuery) {
+			t.Errorf("Search(%q) error = %v, want ErrInvalidSearchQuery", q, err)
+		}
+		if got != nil {
+			t.Errorf("Search(%q) reached the repo", q)
+		}
+	}
+}
+
+func TestListPage_Cursor(t *testing.T) {
+	repo := &mockArticleRepo{}
+	svc := &ArticleService{articleRepo: repo}
+	ctx := context.Background()
+	since := time.Now()
+	browse := repository.ArticleCursor{At: since, ID: "0b6f5a52-3c1e-4f7e-9d0a-5f2b8c7d9e10"}.Encode()
+
+	if _, err := svc.ListPage(ctx, repository.ListArticlesParams{UserID: "user-1", PerPage: 20}, "", false); err != nil || repo.listPageAfter != nil {
+		t.Fatalf("first page: err = %v, after = %+v", err, repo.listPageAfter)
+	}
+	if _, err := svc.ListPage(ctx, repository.ListArticlesParams{UserID: "user-1", PerPage: 20}, browse, false); err != nil || repo.listPageAfter == nil {
+		t.Fatalf("next page: err = %v, after = %+v", err, repo.listPageAfter)
+	}
+
+	// A browse cursor can't continue a sync, and garbage is rejected.
+	sync := repository.ListArticlesParams{UserID: "user-1", PerPage: 20, UpdatedSince: &since}
+	for _, cursor := range []string{browse, "garbage"} {
+		if _, err := svc.ListPage(ctx, sync, cursor, false); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("cursor %q: err = %v, want ErrInvalidCursor", cursor, err)
+		}
+	}
+}
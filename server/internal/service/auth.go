@@ -4,9 +4,11 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"math/big"
@@ -17,21 +19,70 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 
+	"folio-server/internal/authcode"
 	"folio-server/internal/client"
 	"folio-server/internal/domain"
 	"folio-server/internal/repository"
+	"folio-server/internal/revocation"
 )
 
+type authUserStore interface {
+	GetByID(ctx context.Context, id string) (*domain.User, error)
+	GetByAppleID(ctx context.Context, appleID string) (*domain.User, error)
+	GetByEmail(ctx context.Context, email string) (*domain.User, error)
+	Create(ctx context.Context, p repository.CreateUserParams) (*domain.User, error)
+}
+
+type sessionStore interface {
+	Create(ctx context.Context, p repository.CreateSessionParams) (*domain.Session, error)
+	AdoptLegacyToken(ctx context.Context, p repository.CreateSessionParams, issuedAt time.Time, newHash []byte) (*domain.Session, error)
+	GetByTokenHash(ctx context.Context, hash []byte) (*domain.RefreshToken, *domain.Session, error)
+	Rotate(ctx context.Context, tokenID, sessionID string, newHash []byte, expiresAt time.Time, keepUsed time.Duration) (bool, error)
+	ListActive(ctx context.Context, userID string) ([]domain.Session, error)
+	Revoke(ctx context.Context, userID, sessionID string) (bool, error)
+	RevokeOthers(ctx context.Context, userID, keepID string) ([]string, error)
+}
+
 type AuthService struct {
-	userRepo      *repository.UserRepo
+	userRepo      authUserStore
+	sessions      sessionStore
+	codes         authcode.Store
+	revocations   revocation.Store // keys: "user:<id>", "session:<id>"
 	jwtSecret     []byte
 	appleBundleID string
 	resend        *client.ResendClient
 }
 
-func NewAuthService(userRepo *repository.UserRepo, jwtSecret string, appleBundleID string, resend *client.ResendClient) *AuthService {
+const (
+	// accessTokenTTL is the lifetime of an access token, and so how long a
+	// revocation has to be remembered.
+	accessTokenTTL = 2 * time.Hour
+	// refreshTokenTTL is how long a session survives without being used.
+	refreshTokenTTL = 90 * 24 * time.Hour
+
+	maxDeviceNameRunes = 100
+	maxUserAgentRunes  = 300
+)
+
+// Email verification codes. Guesses are counted per address and per client
+// IP before the code is checked, so parallel requests cannot slip past the
+// limits; a correct guess gives its attempt back. Once either counter is over
+// its limit, verification is refused until the lockout window (which starts
+// at the first guess) has passed.
+const (
+	codeTTL          = 5 * time.Minute
+	codeCooldown     = 60 * time.Second
+	maxEmailFailures = 5
+	maxIPFailures    = 20
+	lockoutWindow    = 15 * time.Minute
+)
+
+func NewAuthService(userRepo *repository.UserRepo, sessionRepo *repository.SessionRepo, codes authcode.Store, revocations revocation.Store, jwtSecret string, appleBundleID string, resend *client.ResendClient) *AuthService {
 	return &AuthService{
 		userRepo:      userRepo,
+		sessions:      sessionRepo,
+		codes:         codes,
+		revocations:   revocations,
 		jwtSecret:     []byte(jwtSecret),
 		appleBundleID: appleBundleID,
 		resend:        resend,
@@ -41,13 +92,22 @@ func NewAuthService(userRepo *repository.UserRepo, jwtSecret string, appleBundle
 type TokenClaims struct {
 	jwt.RegisteredClaims
 	UserID    string `json:"uid"`
+	SessionID string `json:"sid,omitempty"`
 	TokenType string `json:"type"`
 }
 
+// SessionInfo describes the device a session is started from.
+type SessionInfo struct {
+	DeviceName string
+	UserAgent  string
+	IP         string // used for verification code lockouts, not stored
+}
+
 type AppleAuthRequest struct {
 	IdentityToken string  `json:"identity_token"`
 	Email         *string `json:"email,omitempty"`
 	Nickname      *string `json:"nickname,omitempty"`
+	DeviceName    string  `json:"device_name,omitempty"`
 }
 
 type AuthResponse struct {
@@ -57,25 +117,14 @@ type AuthResponse struct {
 	User         *domain.User `json:"user"`
 }
 
-// Verification code in-memory storage
-type emailCode struct {
-	code      string
-	expiresAt time.Time
-	attempts  int
-}
-
-var (
-	emailCodes   sync.Map // key: email, value: *emailCode
-	codeCooldown sync.Map // key: email, value: time.Time
-)
-
 type SendCodeRequest struct {
 	Email string `json:"email"`
 }
 
 type VerifyCodeRequest struct {
-	Email string `json:"email"`
-	Code  string `json:"code"`
+	Email      string `json:"email"`
+	Code       string `json:"code"`
+	DeviceName string `json:"device_name,omitempty"`
 }
 
 // Apple JWKS cache
@@ -98,7 +147,7 @@ type AppleJWK struct {
 	E   string `json:"e"`
 }
 
-func (s *AuthService) LoginWithApple(ctx context.Context, req AppleAuthRequest) (*AuthResponse, error) {
+func (s *AuthService) LoginWithApple(ctx context.Context, req AppleAuthRequest, info SessionInfo) (*AuthResponse, error) {
 	// Parse and verify the Apple identity token
 	appleUserID, err := s.verifyAppleToken(req.IdentityToken)
 	if err != nil {
@@ -125,7 +174,7 @@ func (s *AuthService) LoginWithApple(ctx context.Context, req AppleAuthRequest)
 	}
 
 	slog.Info("apple login succeeded", "user_id", user.ID, "new_user", isNew)
-	return s.issueTokenPair(user)
+	return s.issueTokenPair(ctx, user, info)
 }
 
 func (s *AuthService) SendEmailCode(ctx context.Context, req SendCodeRequest) error {
@@ -134,25 +183,24 @@ func (s *AuthService) SendEmailCode(ctx context.Context, req SendCodeRequest) er
 		return fmt.Errorf("invalid email address")
 	}
 
-	// Check 60s cooldown
-	if lastSent, ok := codeCooldown.Load(email); ok {
-		if time.Since(lastSent.(time.Time)) < 60*time.Second {
-			return ErrCodeRateLimit
-		}
+	// A locked-out address could not use the code anyway.
+	locked, err := s.lockedOut(ctx, email)
+	if err != nil {
+		return err
+	}
+	if locked {
+		return ErrTooManyAttempts
 	}
 
 	// Generate 6-digit code
 	code := fmt.Sprintf("%06d", cryptoRandInt(1000000))
 
-	// Store code with 5min TTL
-	emailCodes.Store(email, &emailCode{
-		code:      code,
-		expiresAt: time.Now().Add(5 * time.Minute),
-		attempts:  0,
-	})
-
-	// Store send time for cooldown
-	codeCooldown.Store(email, time.Now())
+	if err := s.codes.Save(ctx, email, code, codeTTL, codeCooldown); err != nil {
+		if errors.Is(err, authcode.ErrCooldown) {
+			return ErrCodeRateLimit
+		}
+		return err
+	}
 
 	// Send email via Resend (falls back to logging if no API key)
 	if err := s.resend.SendVerificationCode(email, code); err != nil {
@@ -164,35 +212,37 @@ func (s *AuthService) SendEmailCode(ctx context.Context, req SendCodeRequest) er
 	return nil
 }
 
-func (s *AuthService) VerifyEmailCode(ctx context.Context, req VerifyCodeRequest) (*AuthResponse, error) {
+func (s *AuthService) VerifyEmailCode(ctx context.Context, req VerifyCodeRequest, info SessionInfo) (*AuthResponse, error) {
 	email := strings.TrimSpace(strings.ToLower(req.Email))
 
-	val, ok := emailCodes.Load(email)
-	if !ok {
-		return nil, ErrInvalidCode
+	attempts, err := s.recordCodeAttempt(ctx, email, info.IP)
+	if err != nil {
+		return nil, err
 	}
-	ec := val.(*emailCode)
 
-	// Check expiry
-	if time.Now().After(ec.expiresAt) {
-		emailCodes.Delete(email)
-		return nil, ErrInvalidCode
+	ok, err := s.codes.Verify(ctx, email, req.Code)
+	if err != nil {
+		return nil, err
 	}
-
-	// Check max attempts
-	if ec.attempts >= 5 {
-		emailCodes.Delete(email)
+	if !ok {
+		if attempts >= maxEmailFailures {
+			// The last allowed guess was wrong: drop the code so a new one
+			// has to be requested after the lockout.
+			slog.Warn("email login: too many wrong codes, locking address", "email", email)
+			if err := s.codes.Discard(ctx, email); err != nil {
+				return nil, err
+			}
+		}
 		return nil, ErrInvalidCode
 	}
-
-	// Compare code
-	if ec.code != req.Code {
-		ec.attempts++
-		return nil, ErrInvalidCode
+	if err := s.codes.Reset(ctx, emailFailureKey(email)); err != nil {
+		slog.Warn("failed to reset verification failures", "email", email, "error", err)
+	}
+	if info.IP != "" {
+		if err := s.codes.Forgive(ctx, ipFailureKey(info.IP)); err != nil {
+			slog.Warn("failed to forgive verification attempt", "ip", info.IP, "error", err)
+		}
 	}
-
-	// Success — delete code
-	emailCodes.Delete(email)
 
 	// Find or create user
 	user, err := s.userRepo.GetByEmail(ctx, email)
@@ -212,7 +262,52 @@ func (s *AuthService) VerifyEmailCode(ctx context.Context, req VerifyCodeRequest
 	}
 
 	slog.Info("email login succeeded", "user_id", user.ID, "email", email, "new_user", isNew)
-	return s.issueTokenPair(user)
+	return s.issueTokenPair(ctx, user, info)
+}
+
+func emailFailureKey(email string) string { return "email:" + email }
+func ipFailureKey(ip string) string       { return "ip:" + ip }
+
+// lockedOut reports whether verification is locked for the address.
+func (s *AuthService) lockedOut(ctx context.Context, email string) (bool, error) {
+	n, err := s.codes.Failures(ctx, emailFailureKey(email))
+	if err != nil {
+		return false, err
+	}
+	return n >= maxEmailFailures, nil
+}
+
+// recordCodeAttempt counts a guess against the address and the client IP
+// before the code is checked, and returns the address's attempt count. Each
+// increment is atomic, so of any number of concurrent guesses at most the
+// limit get through; the rest fail with ErrTooManyAttempts. An empty ip is
+// not counted.
+func (s *AuthService) recordCodeAttempt(ctx context.Context, email, ip string) (int, error) {
+	n, err := s.codes.Attempt(ctx, emailFailureKey(email), lockoutWindow)
+	if err != nil {
+		return 0, err
+	}
+	if n > maxEmailFailures {
+		slog.Info("email login: locked out", "email", email, "ip", ip)
+		return 0, ErrTooManyAttempts
+	}
+	if ip == "" {
+		return n, nil
+	}
+	ipN, err := s.codes.Attempt(ctx, ipFailureKey(ip), lockoutWindow)
+	if err != nil {
+		return 0, err
+	}
+	if ipN > maxIPFailures {
+		// The code is never checked, so this must not count against the
+		// address: a locked-out IP could otherwise lock out anyone.
+		if err := s.codes.Forgive(ctx, emailFailureKey(email)); err != nil {
+			slog.Warn("failed to forgive verification attempt", "email", email, "error", err)
+		}
+		slog.Info("email login: locked out", "email", email, "ip", ip)
+		return 0, ErrTooManyAttempts
+	}
+	return n, nil
 }
 
 func cryptoRandInt(max int) int {
@@ -221,20 +316,70 @@ func cryptoRandInt(max int) int {
 	return int(binary.BigEndian.Uint32(b)) % max
 }
 
+// RefreshToken exchanges a refresh token for a new token pair, rotating the
+// refresh token. Presenting a token that was already rotated means it was
+// copied: the whole session is revoked, signing out both the thief and the
+// legitimate device.
 func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (*AuthResponse, error) {
+	token, sess, err := s.sessions.GetByTokenHash(ctx, hashRefreshToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return s.adoptLegacyRefreshToken(ctx, refreshToken)
+	}
+	if sess.RevokedAt != nil || time.Now().After(sess.ExpiresAt) {
+		slog.Debug("token refresh: session ended", "session_id", sess.ID)
+		return nil, ErrForbidden
+	}
+	if token.UsedAt != nil {
+		s.revokeReusedSession(ctx, sess)
+		return nil, ErrForbidden
+	}
+
+	user, err := s.userRepo.GetByID(ctx, sess.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		slog.Info("token refresh: user not found", "user_id", sess.UserID)
+		return nil, ErrNotFound
+	}
+
+	newToken, newHash, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	rotated, err := s.sessions.Rotate(ctx, token.ID, sess.ID, newHash, time.Now().Add(refreshTokenTTL), refreshTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// Another request used the token between the lookup and now.
+		s.revokeReusedSession(ctx, sess)
+		return nil, ErrForbidden
+	}
+
+	slog.Debug("token refreshed", "user_id", user.ID, "session_id", sess.ID)
+	return s.tokenResponse(user, sess.ID, newToken)
+}
+
+// adoptLegacyRefreshToken accepts a refresh JWT issued before sessions
+// existed, once, and moves it into a new session so that devices signed in
+// before the switch stay signed in. The JWT is stored as the session's first,
+// already rotated token: presenting it a second time is treated as reuse.
+// JWTs issued before the user last signed out their other devices are
+// rejected.
+func (s *AuthService) adoptLegacyRefreshToken(ctx context.Context, refreshToken string) (*AuthResponse, error) {
 	claims := &TokenClaims{}
-	token, err := jwt.ParseWithClaims(refreshToken, claims, func(t *jwt.Token) (any, error) {
+	parsed, err := jwt.ParseWithClaims(refreshToken, claims, func(t *jwt.Token) (any, error) {
 		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
 		}
 		return s.jwtSecret, nil
 	})
-	if err != nil || !token.Valid {
-		slog.Debug("token refresh: invalid token", "error", err)
-		return nil, ErrForbidden
-	}
-	if claims.TokenType != "refresh" {
-		slog.Debug("token refresh: wrong token type", "type", claims.TokenType)
+	if err != nil || !parsed.Valid || claims.TokenType != "refresh" || claims.UserID == "" {
+		slog.Debug("token refresh: unknown token")
 		return nil, ErrForbidden
 	}
 
@@ -247,11 +392,46 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (*A
 		return nil, ErrNotFound
 	}
 
-	slog.Debug("token refreshed", "user_id", user.ID)
-	return s.issueTokenPair(user)
+	newToken, newHash, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+	sess, err := s.sessions.AdoptLegacyToken(ctx, repository.CreateSessionParams{
+		UserID:    user.ID,
+		TokenHash: hashRefreshToken(refreshToken),
+		ExpiresAt: time.Now().Add(refreshTokenTTL),
+	}, issuedAt, newHash)
+	if err != nil {
+		return nil, fmt.Errorf("adopt legacy refresh token: %w", err)
+	}
+	if sess == nil {
+		slog.Warn("token refresh: legacy refresh token reused or revoked", "user_id", user.ID)
+		return nil, ErrForbidden
+	}
+
+	slog.Info("legacy refresh token moved to a session", "user_id", user.ID, "session_id", sess.ID)
+	return s.tokenResponse(user, sess.ID, newToken)
 }
 
-func (s *AuthService) ValidateAccessToken(tokenString string) (string, error) {
+// revokeReusedSession ends a session whose refresh token was presented twice.
+func (s *AuthService) revokeReusedSession(ctx context.Context, sess *domain.Session) {
+	slog.Warn("token refresh: refresh token reused, revoking session",
+		"user_id", sess.UserID, "session_id", sess.ID)
+	if _, err := s.sessions.Revoke(ctx, sess.UserID, sess.ID); err != nil {
+		slog.Error("failed to revoke reused session", "session_id", sess.ID, "error", err)
+	}
+	s.revokeSessionTokens(ctx, sess.ID)
+}
+
+// ValidateAccessToken checks an access token and returns the user and
+// session it was issued to. Tokens issued before sessions existed carry no
+// session ID. A token that is invalid or revoked yields ErrForbidden; any
+// other error means revocations could not be checked.
+func (s *AuthService) ValidateAccessToken(ctx context.Context, tokenString string) (userID, sessionID string, err error) {
 	claims := &TokenClaims{}
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (any, error) {
 		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -260,24 +440,111 @@ func (s *AuthService) ValidateAccessToken(tokenString string) (string, error) {
 		return s.jwtSecret, nil
 	})
 	if err != nil || !token.Valid {
-		return "", ErrForbidden
+		return "", "", ErrForbidden
 	}
 	if claims.TokenType != "access" {
-		return "", ErrForbidden
+		return "", "", ErrForbidden
+	}
+	keys := []string{userRevocationKey(claims.UserID)}
+	if claims.SessionID != "" {
+		keys = append(keys, sessionRevocationKey(claims.SessionID))
+	}
+	revoked, err := s.revocations.IsRevoked(ctx, keys...)
+	if err != nil {
+		return "", "", err
+	}
+	if revoked {
+		return "", "", ErrForbidden
+	}
+	return claims.UserID, claims.SessionID, nil
+}
+
+func userRevocationKey(userID string) string       { return "user:" + userID }
+func sessionRevocationKey(sessionID string) string { return "session:" + sessionID }
+
+// RevokeUser rejects every access token already issued to the user. Refresh
+// tokens need no entry: they are only honoured while the user row exists.
+// The revocation lasts as long as the longest-lived access token.
+func (s *AuthService) RevokeUser(ctx context.Context, userID string) error {
+	return s.revocations.Revoke(ctx, userRevocationKey(userID), accessTokenTTL)
+}
+
+// revokeSessionTokens rejects the access tokens already issued to a session.
+// Its refresh tokens are rejected through the session's revoked_at, so a
+// failure here is logged rather than returned: the session is already over
+// and its access tokens run out within accessTokenTTL.
+func (s *AuthService) revokeSessionTokens(ctx context.Context, sessionID string) {
+	if err := s.revocations.Revoke(ctx, sessionRevocationKey(sessionID), accessTokenTTL); err != nil {
+		slog.Error("failed to revoke session access tokens", "session_id", sessionID, "error", err)
 	}
-	return claims.UserID, nil
 }
 
-func (s *AuthService) issueTokenPair(user *domain.User) (*AuthResponse, error) {
+// ListSessions returns the user's signed-in devices.
+func (s *AuthService) ListSessions(ctx context.Context, userID string) ([]domain.Session, error) {
+	return s.sessions.ListActive(ctx, userID)
+}
+
+// RevokeSession signs one of the user's devices out.
+func (s *AuthService) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	ok, err := s.sessions.Revoke(ctx, userID, sessionID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotFound
+	}
+	s.revokeSessionTokens(ctx, sessionID)
+	slog.Info("session revoked", "user_id", userID, "session_id", sessionID)
+	return nil
+}
+
+// RevokeOtherSessions signs out every device of the user except the one
+// with session currentID, and returns how many were signed out.
+func (s *AuthService) RevokeOtherSessions(ctx context.Context, userID, currentID string) (int, error) {
+	ids, err := s.sessions.RevokeOthers(ctx, userID, currentID)
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		s.revokeSessionTokens(ctx, id)
+	}
+	slog.Info("other sessions revoked", "user_id", userID, "count", len(ids))
+	return len(ids), nil
+}
+
+// issueTokenPair starts a new session for the user and returns its first
+// token pair.
+func (s *AuthService) issueTokenPair(ctx context.Context, user *domain.User, info SessionInfo) (*AuthResponse, error) {
+	refreshToken, hash, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	sess, err := s.sessions.Create(ctx, repository.CreateSessionParams{
+		UserID:     user.ID,
+		DeviceName: nonEmpty(truncateRunes(strings.TrimSpace(info.DeviceName), maxDeviceNameRunes)),
+		UserAgent:  nonEmpty(truncateRunes(info.UserAgent, maxUserAgentRunes)),
+		TokenHash:  hash,
+		ExpiresAt:  time.Now().Add(refreshTokenTTL),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create session: %w", err)
+	}
+	return s.tokenResponse(user, sess.ID, refreshToken)
+}
+
+// tokenResponse signs an access token for the session and pairs it with
+// refreshToken.
+func (s *AuthService) tokenResponse(user *domain.User, sessionID, refreshToken string) (*AuthResponse, error) {
 	now := time.Now()
 
 	accessClaims := TokenClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(2 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(accessTokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			Issuer:    "folio",
 		},
 		UserID:    user.ID,
+		SessionID: sessionID,
 		TokenType: "access",
 	}
 	accessToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims).SignedString(s.jwtSecret)
@@ -285,28 +552,30 @@ func (s *AuthService) issueTokenPair(user *domain.User) (*AuthResponse, error) {
 		return nil, fmt.Errorf("sign access token: %w", err)
 	}
 
-	refreshClaims := TokenClaims{
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(90 * 24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(now),
-			Issuer:    "folio",
-		},
-		UserID:    user.ID,
-		TokenType: "refresh",
-	}
-	refreshTokenStr, err := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims).SignedString(s.jwtSecret)
-	if err != nil {
-		return nil, fmt.Errorf("sign refresh token: %w", err)
-	}
-
 	return &AuthResponse{
 		AccessToken:  accessToken,
-		RefreshToken: refreshTokenStr,
-		ExpiresIn:    7200,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(accessTokenTTL.Seconds()),
 		User:         user,
 	}, nil
 }
 
+// newRefreshToken returns a random opaque refresh token and the hash under
+// which it is stored.
+func newRefreshToken() (string, []byte, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, fmt.Errorf("generate refresh token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, hashRefreshToken(token), nil
+}
+
+func hashRefreshToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
 func (s *AuthService) verifyAppleToken(tokenString string) (string, error) {
 	// Parse the token header to get the kid
 	parser := jwt.NewParser()
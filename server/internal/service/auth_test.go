@@ -0,0 +1,480 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"folio-server/internal/authcode"
+	"folio-server/internal/client"
+	"folio-server/internal/domain"
+	"folio-server/internal/repository"
+	"folio-server/internal/revocation"
+)
+
+// mockSessions is an in-memory session store keyed by token hash.
+type mockSessions struct {
+	sessions map[string]*domain.Session
+	tokens   map[string]*domain.RefreshToken // key: string(hash)
+	legacy   map[string]bool                 // adopted legacy token hashes
+	cutoffs  map[string]time.Time            // key: user ID
+	nextID   int
+}
+
+func newMockSessions() *mockSessions {
+	return &mockSessions{sessions: map[string]*domain.Session{}, tokens: map[string]*domain.RefreshToken{},
+		legacy: map[string]bool{}, cutoffs: map[string]time.Time{}}
+}
+
+func (m *mockSessions) id(prefix string) string {
+	m.nextID++
+	return fmt.Sprintf("%s-%d", prefix, m.nextID)
+}
+
+func (m *mockSessions) Create(_ context.Context, p repository.CreateSessionParams) (*domain.Session, error) {
+	s := &domain.Session{ID: m.id("session"), UserID: p.UserID, DeviceName: p.DeviceName, UserAgent: p.UserAgent,
+		ExpiresAt: p.ExpiresAt, LastUsedAt: time.Now(), CreatedAt: time.Now()}
+	m.sessions[s.ID] = s
+	m.tokens[string(p.TokenHash)] = &domain.RefreshToken{ID: m.id("token"), SessionID: s.ID}
+	return s, nil
+}
+
+func (m *mockSessions) AdoptLegacyToken(_ context.Context, p repository.CreateSessionParams, issuedAt time.Time, newHash []byte) (*domain.Session, error) {
+	if m.legacy[string(p.TokenHash)] {
+		return nil, nil
+	}
+	if cutoff, ok := m.cutoffs[p.UserID]; ok && !issuedAt.After(cutoff) {
+		return nil, nil
+	}
+	m.legacy[string(p.TokenHash)] = true
+	s := &domain.Session{ID: m.id("session"), UserID: p.UserID, ExpiresAt: p.ExpiresAt, LastUsedAt: time.Now(), CreatedAt: time.Now()}
+	m.sessions[s.ID] = s
+	now := time.Now()
+	m.tokens[string(p.TokenHash)] = &domain.RefreshToken{ID: m.id("token"), SessionID: s.ID, UsedAt: &now}
+	m.tokens[string(newHash)] = &domain.RefreshToken{ID: m.id("token"), SessionID: s.ID}
+	return s, nil
+}
+
+func (m *mockSessions) GetByTokenHash(_ context.Context, hash []byte) (*domain.RefreshToken, *domain.Session, error) {
+	t, ok := m.tokens[string(hash)]
+	if !ok {
+		return nil, nil, nil
+	}
+	tc, sc := *t, *m.sessions[t.SessionID]
+	return &tc, &sc, nil
+}
+
+func (m *mockSessions) Rotate(_ context.Context, tokenID, sessionID string, newHash []byte, expiresAt time.Time, _ time.Duration) (bool, error) {
+	for _, t := range m.tokens {
+		if t.ID == tokenID {
+			if t.UsedAt != nil {
+				return false, nil
+			}
+			now := time.Now()
+			t.UsedAt = &now
+		}
+	}
+	m.tokens[string(newHash)] = &domain.RefreshToken{ID: m.id("token"), SessionID: sessionID}
+	m.sessions[sessionID].ExpiresAt = expiresAt
+	return true, nil
+}
+
+func (m *mockSessions) ListActive(_ context.Context, userID string) ([]domain.Session, error) {
+	out := make([]domain.Session, 0)
+	for _, s := range m.sessions {
+		if s.UserID == userID && s.RevokedAt == nil {
+			out = append(out, *s)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockSessions) Revoke(_ context.Context, userID, sessionID string) (bool, error) {
+	s, ok := m.sessions[sessionID]
+	if !ok || s.UserID != userID || s.RevokedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	s.RevokedAt = &now
+	return true, nil
+}
+
+func (m *mockSessions) RevokeOthers(ctx context.Context, userID, keepID string) ([]string, error) {
+	m.cutoffs[userID] = time.Now()
+	ids := make([]string, 0)
+	for id := range m.sessions {
+		if id == keepID {
+			continue
+		}
+		if ok, _ := m.Revoke(ctx, userID, id); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+type mockAuthUsers struct{}
+
+func (m *mockAuthUsers) GetByID(_ context.Context, id string) (*domain.User, error) {
+	return &domain.User{ID: id}, nil
+}
+
+func (m *mockAuthUsers) GetByAppleID(_ context.Context, _ string) (*domain.User, error) {
+	return nil, nil
+}
+
+func (m *mockAuthUsers) GetByEmail(_ context.Context, _ string) (*domain.User, error) {
+	return nil, nil
+}
+
+func (m *mockAuthUsers) Create(_ context.Context, _ repository.CreateUserParams) (*domain.User, error) {
+	return &domain.User{ID: "user-new"}, nil
+}
+
+// newSessionAuthService returns an AuthService with one session for user-1.
+func newSessionAuthService(t *testing.T) (*AuthService, *mockSessions, *AuthResponse) {
+	t.Helper()
+	sessions := newMockSessions()
+	s := &AuthService{userRepo: &mockAuthUsers{}, jwtSecret: []byte("secret"), sessions: sessions, revocations: revocation.NewMemoryStore()}
+	pair, err := s.issueTokenPair(context.Background(), &domain.User{ID: "user-1"}, SessionInfo{DeviceName: "Ada's iPhone", UserAgent: "Folio/3.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, sessions, pair
+}
+
+func TestAuthService_IssueTokenPairStartsSession(t *testing.T) {
+	s, sessions, pair := newSessionAuthService(t)
+
+	userID, sessionID, err := s.ValidateAccessToken(context.Background(), pair.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken: %v", err)
+	}
+	sess, ok := sessions.sessions[sessionID]
+	if userID != "user-1" || !ok || *sess.DeviceName != "Ada's iPhone" {
+		t.Fatalf("user %q session %q = %+v", userID, sessionID, sess)
+	}
+	if _, stored := sessions.tokens[string(hashRefreshToken(pair.RefreshToken))]; !stored {
+		t.Error("refresh token hash not stored")
+	}
+	if _, stored := sessions.tokens[pair.RefreshToken]; stored {
+		t.Error("refresh token stored in plain text")
+	}
+}
+
+func TestAuthService_ReusedRefreshTokenRevokesSession(t *testing.T) {
+	s, sessions, pair := newSessionAuthService(t)
+	_, sessionID, _ := s.ValidateAccessToken(context.Background(), pair.AccessToken)
+
+	// A stolen copy of the token is used first; the rotated token it returns
+	// must stop working once the original device presents the old token.
+	stolen, err := s.RefreshToken(context.Background(), pair.RefreshToken)
+	if err != nil {
+		t.Fatalf("first refresh: %v", err)
+	}
+	if _, err := s.RefreshToken(context.Background(), pair.RefreshToken); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("reused token: err = %v, want ErrForbidden", err)
+	}
+	if sessions.sessions[sessionID].RevokedAt == nil {
+		t.Error("session not revoked after token reuse")
+	}
+	if _, err := s.RefreshToken(context.Background(), stolen.RefreshToken); !errors.Is(err, ErrForbidden) {
+		t.Errorf("rotated token of revoked session: err = %v, want ErrForbidden", err)
+	}
+	if _, _, err := s.ValidateAccessToken(context.Background(), stolen.AccessToken); !errors.Is(err, ErrForbidden) {
+		t.Errorf("access token of revoked session: err = %v, want ErrForbidden", err)
+	}
+}
+
+func TestAuthService_RefreshRotatesToken(t *testing.T) {
+	s, _, pair := newSessionAuthService(t)
+	_, sessionID, _ := s.ValidateAccessToken(context.Background(), pair.AccessToken)
+
+	next, err := s.RefreshToken(context.Background(), pair.RefreshToken)
+	if err != nil {
+		t.Fatalf("RefreshToken: %v", err)
+	}
+	if next.RefreshToken == pair.RefreshToken {
+		t.Error("refresh token not rotated")
+	}
+	if _, sid, err := s.ValidateAccessToken(context.Background(), next.AccessToken); err != nil || sid != sessionID {
+		t.Errorf("new access token session = %q, %v; want %q", sid, err, sessionID)
+	}
+	if _, err := s.RefreshToken(context.Background(), next.RefreshToken); err != nil {
+		t.Errorf("rotated token rejected: %v", err)
+	}
+}
+
+func TestAuthService_RevokeSession(t *testing.T) {
+	s, sessions, pair := newSessionAuthService(t)
+	other, err := s.issueTokenPair(context.Background(), &domain.User{ID: "user-1"}, SessionInfo{DeviceName: "iPad"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, currentID, _ := s.ValidateAccessToken(context.Background(), pair.AccessToken)
+	_, otherID, _ := s.ValidateAccessToken(context.Background(), other.AccessToken)
+
+	if err := s.RevokeSession(context.Background(), "user-2", otherID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("revoking another user's session: err = %v, want ErrNotFound", err)
+	}
+
+	n, err := s.RevokeOtherSessions(context.Background(), "user-1", currentID)
+	if err != nil || n != 1 {
+		t.Fatalf("RevokeOtherSessions = %d, %v", n, err)
+	}
+	if _, _, err := s.ValidateAccessToken(context.Background(), other.AccessToken); !errors.Is(err, ErrForbidden) {
+		t.Errorf("signed-out device still accepted: %v", err)
+	}
+	if _, _, err := s.ValidateAccessToken(context.Background(), pair.AccessToken); err != nil {
+		t.Errorf("current device signed out: %v", err)
+	}
+	if active, _ := sessions.ListActive(context.Background(), "user-1"); len(active) != 1 || active[0].ID != currentID {
+		t.Errorf("active sessions = %+v", active)
+	}
+}
+
+func TestAuthService_RevocationSeenByOtherReplicas(t *testing.T) {
+	s, sessions, pair := newSessionAuthService(t)
+	// A second API replica shares the database and the revocation store,
+	// but nothing in memory.
+	replica := &AuthService{userRepo: &mockAuthUsers{}, jwtSecret: []byte("secret"), sessions: sessions, revocations: s.revocations}
+	_, sessionID, _ := s.ValidateAccessToken(context.Background(), pair.AccessToken)
+
+	if err := s.RevokeSession(context.Background(), "user-1", sessionID); err != nil {
+		t.Fatalf("RevokeSession: %v", err)
+	}
+	if _, _, err := replica.ValidateAccessToken(context.Background(), pair.AccessToken); !errors.Is(err, ErrForbidden) {
+		t.Errorf("other replica: err = %v, want ErrForbidden", err)
+	}
+}
+
+func TestAuthService_RefreshUnknownToken(t *testing.T) {
+	s, _, _ := newSessionAuthService(t)
+	if _, err := s.RefreshToken(context.Background(), "not-a-token"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("err = %v, want ErrForbidden", err)
+	}
+}
+
+// legacyRefreshToken signs a refresh JWT the way tokens were issued before
+// sessions existed.
+func legacyRefreshToken(t *testing.T, secret, userID string, expiresAt time.Time) string {
+	t.Helper()
+	claims := TokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now().Add(-24 * time.Hour)),
+			Issuer:    "folio",
+		},
+		UserID:    userID,
+		TokenType: "refresh",
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestAuthService_RefreshAdoptsLegacyTokenOnce(t *testing.T) {
+	s, sessions, _ := newSessionAuthService(t)
+	legacy := legacyRefreshToken(t, "secret", "user-7", time.Now().Add(30*24*time.Hour))
+
+	resp, err := s.RefreshToken(context.Background(), legacy)
+	if err != nil {
+		t.Fatalf("legacy refresh: %v", err)
+	}
+	userID, sessionID, err := s.ValidateAccessToken(context.Background(), resp.AccessToken)
+	if err != nil || userID != "user-7" || sessionID == "" {
+		t.Fatalf("access token = %q, %q, %v; want user-7 with a session", userID, sessionID, err)
+	}
+	if _, err := s.RefreshToken(context.Background(), resp.RefreshToken); err != nil {
+		t.Errorf("rotated token of adopted session rejected: %v", err)
+	}
+
+	// The legacy token was used up: presenting it again is reuse.
+	if _, err := s.RefreshToken(context.Background(), legacy); !errors.Is(err, ErrForbidden) {
+		t.Errorf("second legacy refresh: err = %v, want ErrForbidden", err)
+	}
+	if sessions.sessions[sessionID].RevokedAt == nil {
+		t.Error("adopted session not revoked after legacy token reuse")
+	}
+}
+
+func TestAuthService_LegacyTokenAcceptedOnceAfterPruning(t *testing.T) {
+	s, sessions, _ := newSessionAuthService(t)
+	legacy := legacyRefreshToken(t, "secret", "user-7", time.Now().Add(30*24*time.Hour))
+
+	if _, err := s.RefreshToken(context.Background(), legacy); err != nil {
+		t.Fatalf("legacy refresh: %v", err)
+	}
+	// Rotation prunes the session's used tokens, including the legacy one.
+	delete(sessions.tokens, string(hashRefreshToken(legacy)))
+
+	if _, err := s.RefreshToken(context.Background(), legacy); !errors.Is(err, ErrForbidden) {
+		t.Errorf("legacy token presented twice: err = %v, want ErrForbidden", err)
+	}
+	if n := len(sessions.sessions); n != 2 {
+		t.Errorf("%d sessions, want 2 (no second adoption)", n)
+	}
+}
+
+func TestAuthService_LegacyTokenRejectedAfterRevokeOthers(t *testing.T) {
+	s, _, _ := newSessionAuthService(t)
+	legacy := legacyRefreshToken(t, "secret", "user-1", time.Now().Add(30*24*time.Hour))
+
+	if _, err := s.RevokeOtherSessions(context.Background(), "user-1", "session-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.RefreshToken(context.Background(), legacy); !errors.Is(err, ErrForbidden) {
+		t.Errorf("legacy token issued before sign-out: err = %v, want ErrForbidden", err)
+	}
+}
+
+func TestAuthService_RefreshRejectsBadLegacyTokens(t *testing.T) {
+	s, _, pair := newSessionAuthService(t)
+	tests := map[string]string{
+		"expired":      legacyRefreshToken(t, "secret", "user-7", time.Now().Add(-time.Minute)),
+		"wrong secret": legacyRefreshToken(t, "other", "user-7", time.Now().Add(time.Hour)),
+		"access token": pair.AccessToken,
+	}
+	for name, token := range tests {
+		if _, err := s.RefreshToken(context.Background(), token); !errors.Is(err, ErrForbidden) {
+			t.Errorf("%s: err = %v, want ErrForbidden", name, err)
+		}
+	}
+}
+
+func newCodeAuthService() (*AuthService, *authcode.MemoryStore) {
+	codes := authcode.NewMemoryStore()
+	return &AuthService{
+		userRepo:    &mockAuthUsers{},
+		sessions:    newMockSessions(),
+		codes:       codes,
+		revocations: revocation.NewMemoryStore(),
+		jwtSecret:   []byte("secret"),
+		resend:      client.NewResendClient("", "test@example.com"),
+	}, codes
+}
+
+func TestAuthService_EmailCodeLockout(t *testing.T) {
+	ctx := context.Background()
+	s, codes := newCodeAuthService()
+	_ = codes.Save(ctx, "a@example.com", "123456", codeTTL, codeCooldown)
+	info := SessionInfo{IP: "192.0.2.1"}
+
+	for i := 0; i < maxEmailFailures; i++ {
+		_, err := s.VerifyEmailCode(ctx, VerifyCodeRequest{Email: "a@example.com", Code: "000000"}, info)
+		if !errors.Is(err, ErrInvalidCode) {
+			t.Fatalf("guess %d: err = %v, want ErrInvalidCode", i, err)
+		}
+	}
+
+	// The right code no longer helps: the address is locked and the code
+	// has been discarded.
+	_, err := s.VerifyEmailCode(ctx, VerifyCodeRequest{Email: "a@example.com", Code: "123456"}, info)
+	if !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("err = %v after lockout, want ErrTooManyAttempts", err)
+	}
+	if err := s.SendEmailCode(ctx, SendCodeRequest{Email: "a@example.com"}); !errors.Is(err, ErrTooManyAttempts) {
+		t.Errorf("SendEmailCode while locked: err = %v, want ErrTooManyAttempts", err)
+	}
+	if ok, _ := codes.Verify(ctx, "a@example.com", "123456"); ok {
+		t.Error("code survived the lockout")
+	}
+}
+
+func TestAuthService_EmailCodeIPLockout(t *testing.T) {
+	ctx := context.Background()
+	s, codes := newCodeAuthService()
+	info := SessionInfo{IP: "192.0.2.1"}
+
+	// Spread guesses over many addresses so no single address locks.
+	for i := 0; i < maxIPFailures; i++ {
+		req := VerifyCodeRequest{Email: fmt.Sprintf("user%d@example.com", i), Code: "000000"}
+		if _, err := s.VerifyEmailCode(ctx, req, info); !errors.Is(err, ErrInvalidCode) {
+			t.Fatalf("guess %d: err = %v", i, err)
+		}
+	}
+
+	_ = codes.Save(ctx, "victim@example.com", "123456", codeTTL, codeCooldown)
+	_, err := s.VerifyEmailCode(ctx, VerifyCodeRequest{Email: "victim@example.com", Code: "123456"}, info)
+	if !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("err = %v from locked IP, want ErrTooManyAttempts", err)
+	}
+	resp, err := s.VerifyEmailCode(ctx, VerifyCodeRequest{Email: "victim@example.com", Code: "123456"}, SessionInfo{IP: "198.51.100.7"})
+	if err != nil || resp.AccessToken == "" {
+		t.Errorf("other IP: resp = %v, err = %v", resp, err)
+	}
+}
+
+func TestAuthService_EmailCodeConcurrentGuesses(t *testing.T) {
+	ctx := context.Background()
+	s, codes := newCodeAuthService()
+	_ = codes.Save(ctx, "a@example.com", "123456", codeTTL, codeCooldown)
+
+	// Fire many wrong guesses at once, each from its own IP so only the
+	// address limit applies. Checked before counting, they would all see
+	// fewer than maxEmailFailures failures and get a guess in.
+	const guesses = 50
+	var invalid, locked atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < guesses; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			info := SessionInfo{IP: fmt.Sprintf("192.0.2.%d", i)}
+			_, err := s.VerifyEmailCode(ctx, VerifyCodeRequest{Email: "a@example.com", Code: fmt.Sprintf("%06d", i)}, info)
+			switch {
+			case errors.Is(err, ErrInvalidCode):
+				invalid.Add(1)
+			case errors.Is(err, ErrTooManyAttempts):
+				locked.Add(1)
+			default:
+				t.Errorf("guess %d: err = %v", i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if invalid.Load() != maxEmailFailures {
+		t.Errorf("%d guesses were checked, want %d", invalid.Load(), maxEmailFailures)
+	}
+	if locked.Load() != guesses-maxEmailFailures {
+		t.Errorf("%d guesses were refused, want %d", locked.Load(), guesses-maxEmailFailures)
+	}
+	if ok, _ := codes.Verify(ctx, "a@example.com", "123456"); ok {
+		t.Error("code survived the lockout")
+	}
+}
+
+func TestAuthService_EmailCodeSuccessDoesNotCountAgainstIP(t *testing.T) {
+	ctx := context.Background()
+	s, codes := newCodeAuthService()
+	info := SessionInfo{IP: "192.0.2.1"}
+
+	// A shared IP whose users all sign in correctly never locks.
+	for i := 0; i < maxIPFailures+5; i++ {
+		email := fmt.Sprintf("user%d@example.com", i)
+		_ = codes.Save(ctx, email, "123456", codeTTL, codeCooldown)
+		if _, err := s.VerifyEmailCode(ctx, VerifyCodeRequest{Email: email, Code: "123456"}, info); err != nil {
+			t.Fatalf("login %d: %v", i, err)
+		}
+	}
+}
+
+func TestAuthService_SendEmailCodeCooldown(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newCodeAuthService()
+	if err := s.SendEmailCode(ctx, SendCodeRequest{Email: "A@Example.com "}); err != nil {
+		t.Fatalf("SendEmailCode: %v", err)
+	}
+	if err := s.SendEmailCode(ctx, SendCodeRequest{Email: "a@example.com"}); !errors.Is(err, ErrCodeRateLimit) {
+		t.Errorf("second send: err = %v, want ErrCodeRateLimit", err)
+	}
+}
@@ -0,0 +1,302 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"folio-server/internal/domain"
+	"folio-server/internal/repository"
+)
+
+const (
+	// maxCollectionsPerUser caps how many collections one user can save.
+	maxCollectionsPerUser = 50
+	// maxCollectionNameLen is the longest collection name, in characters.
+	maxCollectionNameLen = 100
+	// maxCollectionTags caps the tags one collection filter can name.
+	maxCollectionTags = 50
+)
+
+type collectionStore interface {
+	Create(ctx context.Context, userID, name string, filter domain.ArticleFilter) (*domain.Collection, error)
+	GetByID(ctx context.Context, id string) (*domain.Collection, error)
+	ListByUser(ctx context.Context, userID string) ([]domain.Collection, error)
+	Update(ctx context.Context, id, userID string, p repository.UpdateCollectionParams) error
+	Delete(ctx context.Context, id, userID string) error
+}
+
+// collectionArticles evaluates collection filters against the library.
+type collectionArticles interface {
+	ListByUser(ctx context.Context, p repository.ListArticlesParams) (*repository.ListArticlesResult, error)
+	SearchFiltered(ctx context.Context, q *domain.SearchQuery, p repository.ListArticlesParams) (*repository.ListArticlesResult, error)
+	CountFiltered(ctx context.Context, q *domain.SearchQuery, p repository.ListArticlesParams) (int, error)
+	ListFilteredIDs(ctx context.Context, q *domain.SearchQuery, p repository.ListArticlesParams, limit int) ([]string, error)
+}
+
+type collectionTagLister interface {
+	ListByUser(ctx context.Context, userID string) ([]domain.Tag, error)
+}
+
+// CollectionService manages smart collections: saved article filters that
+// are evaluated live whenever a collection is listed or opened.
+type CollectionService struct {
+	collectionRepo collectionStore
+	articleRepo    collectionArticles
+	tagRepo        collectionTagLister
+}
+
+func NewCollectionService(collectionRepo *repository.CollectionRepo, articleRepo *repository.ArticleRepo, tagRepo *repository.TagRepo) *CollectionService {
+	return &CollectionService{
+		collectionRepo: collectionRepo,
+		articleRepo:    articleRepo,
+		tagRepo:        tagRepo,
+	}
+}
+
+// List returns the user's collections in sidebar order, each with the
+// number of articles it currently matches.
+func (s *CollectionService) List(ctx context.Context, userID string) ([]domain.Collection, error) {
+	collections, err := s.collectionRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range collections {
+		p, q, err := s.filterParams(&collections[i])
+		if err != nil {
+			// A filter that no longer parses matches nothing rather than
+			// hiding the rest of the sidebar.
+			slog.Warn("collection filter invalid", "collection_id", collections[i].ID, "error", err)
+			continue
+		}
+		if collections[i].ArticleCount, err = s.articleRepo.CountFiltered(ctx, q, p); err != nil {
+			return nil, err
+		}
+	}
+	return collections, nil
+}
+
+func (s *CollectionService) Get(ctx context.Context, userID, collectionID string) (*domain.Collection, error) {
+	c, err := s.collectionRepo.GetByID(ctx, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, ErrNotFound
+	}
+	if c.UserID != userID {
+		return nil, ErrForbidden
+	}
+	return c, nil
+}
+
+func (s *CollectionService) Create(ctx context.Context, userID, name string, filter domain.ArticleFilter) (*domain.Collection, error) {
+	name, err := normalizeCollectionName(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateFilter(ctx, userID, &filter); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.collectionRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list collections: %w", err)
+	}
+	if len(existing) >= maxCollectionsPerUser {
+		return nil, ErrCollectionLimit
+	}
+	if collectionNameTaken(existing, name, "") {
+		return nil, ErrDuplicateCollection
+	}
+
+	c, err := s.collectionRepo.Create(ctx, userID, name, filter)
+	if err != nil {
+		return nil, fmt.Errorf("create collection: %w", err)
+	}
+	slog.Info("collection created", "collection_id", c.ID, "user_id", userID)
+	return c, nil
+}
+
+// Update renames, re-filters or moves a collection. A new filter replaces
+// the old one entirely.
+func (s *CollectionService) Update(ctx context.Context, userID, collectionID string, p repository.UpdateCollectionParams) (*domain.Collection, error) {
+	if _, err := s.Get(ctx, userID, collectionID); err != nil {
+		return nil, err
+	}
+	if p.Name != nil {
+		name, err := normalizeCollectionName(*p.Name)
+		if err != nil {
+			return nil, err
+		}
+		existing, err := s.collectionRepo.ListByUser(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("list collections: %w", err)
+		}
+		if collectionNameTaken(existing, name, collectionID) {
+			return nil, ErrDuplicateCollection
+		}
+		p.Name = &name
+	}
+	if p.Filter != nil {
+		if err := s.validateFilter(ctx, userID, p.Filter); err != nil {
+			return nil, err
+		}
+	}
+	if p.Position != nil && *p.Position < 0 {
+		return nil, fmt.Errorf("%w: position must not be negative", ErrInvalidCollection)
+	}
+
+	if err := s.collectionRepo.Update(ctx, collectionID, userID, p); err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, userID, collectionID)
+}
+
+func (s *CollectionService) Delete(ctx context.Context, userID, collectionID string) error {
+	if _, err := s.Get(ctx, userID, collectionID); err != nil {
+		return err
+	}
+	return s.collectionRepo.Delete(ctx, collectionID, userID)
+}
+
+// Articles returns a page of the articles a collection matches: ranked by
+// relevance when its filter has a search query, newest first otherwise.
+func (s *CollectionService) Articles(ctx context.Context, userID, collectionID string, page, perPage int) (*repository.ListArticlesResult, error) {
+	c, err := s.Get(ctx, userID, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	p, q, err := s.filterParams(c)
+	if err != nil {
+		return nil, err
+	}
+	p.Page, p.PerPage = page, perPage
+	if q == nil {
+		return s.articleRepo.ListByUser(ctx, p)
+	}
+	return s.articleRepo.SearchFiltered(ctx, q, p)
+}
+
+// ArticleIDs returns the IDs of up to limit articles a collection matches,
+// best match first. It lets a collection scope RAG and exports.
+func (s *CollectionService) ArticleIDs(ctx context.Context, userID, collectionID string, limit int) ([]string, error) {
+	c, err := s.Get(ctx, userID, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	p, q, err := s.filterParams(c)
+	if err != nil {
+		return nil, err
+	}
+	return s.articleRepo.ListFilteredIDs(ctx, q, p, limit)
+}
+
+func (s *CollectionService) filterParams(c *domain.Collection) (repository.ListArticlesParams, *domain.SearchQuery, error) {
+	p, q, err := repository.ArticleFilterParams(c.UserID, c.Filter, time.Now())
+	if err != nil {
+		return p, nil, fmt.Errorf("%w: query: %v", ErrInvalidCollection, err)
+	}
+	return p, q, nil
+}
+
+// validateFilter checks f and normalizes it in place: the query is trimmed
+// and repeated tags or source types are dropped.
+func (s *CollectionService) validateFilter(ctx context.Context, userID string, f *domain.ArticleFilter) error {
+	f.Query = strings.TrimSpace(f.Query)
+	if f.Query != "" {
+		if _, err := domain.ParseSearchQuery(f.Query); err != nil {
+			return fmt.Errorf("%w: query: %v", ErrInvalidCollection, err)
+		}
+	}
+	if f.Status != nil {
+		switch *f.Status {
+		case domain.ArticleStatusPending, domain.ArticleStatusProcessing, domain.ArticleStatusReady, domain.ArticleStatusFailed:
+		default:
+			return fmt.Errorf("%w: unknown status %q", ErrInvalidCollection, *f.Status)
+		}
+	}
+	if f.ReadState != nil {
+		switch *f.ReadState {
+		case domain.ReadStateUnread, domain.ReadStateReading, domain.ReadStateRead:
+		default:
+			return fmt.Errorf("%w: unknown read state %q", ErrInvalidCollection, *f.ReadState)
+		}
+	}
+	for _, t := range f.SourceTypes {
+		if !t.IsKnown() {
+			return fmt.Errorf("%w: unknown source type %q", ErrInvalidCollection, t)
+		}
+	}
+	f.SourceTypes = compactUnique(f.SourceTypes)
+
+	if (f.MinWords != nil && *f.MinWords < 0) || (f.MaxWords != nil && *f.MaxWords < 0) {
+		return fmt.Errorf("%w: word counts must not be negative", ErrInvalidCollection)
+	}
+	if f.MinWords != nil && f.MaxWords != nil && *f.MinWords > *f.MaxWords {
+		return fmt.Errorf("%w: min_words is greater than max_words", ErrInvalidCollection)
+	}
+	if f.CreatedAfter != nil && f.CreatedBefore != nil && !f.CreatedAfter.Before(*f.CreatedBefore) {
+		return fmt.Errorf("%w: created_after must be before created_before", ErrInvalidCollection)
+	}
+	if f.CreatedWithinDays != nil && *f.CreatedWithinDays <= 0 {
+		return fmt.Errorf("%w: created_within_days must be positive", ErrInvalidCollection)
+	}
+
+	f.TagIDs = compactUnique(f.TagIDs)
+	if len(f.TagIDs) > maxCollectionTags {
+		return fmt.Errorf("%w: at most %d tags", ErrInvalidCollection, maxCollectionTags)
+	}
+	if len(f.TagIDs) > 0 {
+		tags, err := s.tagRepo.ListByUser(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("list tags: %w", err)
+		}
+		for _, id := range f.TagIDs {
+			if !slices.ContainsFunc(tags, func(t domain.Tag) bool { return t.ID == id }) {
+				return fmt.Errorf("%w: unknown tag %q", ErrInvalidCollection, id)
+			}
+		}
+	}
+	return nil
+}
+
+func normalizeCollectionName(name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" {
+		return "", fmt.Errorf("%w: name is required", ErrInvalidCollection)
+	}
+	if utf8.RuneCountInString(name) > maxCollectionNameLen {
+		return "", fmt.Errorf("%w: name is longer than %d characters", ErrInvalidCollection, maxCollectionNameLen)
+	}
+	return name, nil
+}
+
+// collectionNameTaken reports whether another collection than exceptID is
+// already called name, ignoring case.
+func collectionNameTaken(collections []domain.Collection, name, exceptID string) bool {
+	return slices.ContainsFunc(collections, func(c domain.Collection) bool {
+		return c.ID != exceptID && strings.EqualFold(c.Name, name)
+	})
+}
+
+// compactUnique returns s without repeated elements, keeping first
+// occurrences in order.
+func compactUnique[T comparable](s []T) []T {
+	if len(s) == 0 {
+		return s
+	}
+	seen := make(map[T]bool, len(s))
+	out := make([]T, 0, len(s))
+	for _, v := range s {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
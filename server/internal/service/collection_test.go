@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"folio-server/internal/domain"
+	"folio-server/internal/repository"
+)
+
+type mockCollectionStore struct {
+	collections []domain.Collection
+	created     *domain.Collection
+	updated     *repository.UpdateCollectionParams
+}
+
+func (m *mockCollectionStore) Create(_ context.Context, userID, name string, filter domain.ArticleFilter) (*domain.Collection, error) {
+	m.created = &domain.Collection{ID: "col-new", UserID: userID, Name: name, Filter: filter}
+	return m.created, nil
+}
+
+func (m *mockCollectionStore) GetByID(_ context.Context, id string) (*domain.Collection, error) {
+	for i := range m.collections {
+		if m.collections[i].ID == id {
+			c := m.collections[i]
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockCollectionStore) ListByUser(_ context.Context, userID string) ([]domain.Collection, error) {
+	out := make([]domain.Collection, 0)
+	for _, c := range m.collections {
+		if c.UserID == userID {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockCollectionStore) Update(_ context.Context, _, _ string, p repository.UpdateCollectionParams) error {
+	m.updated = &p
+	return nil
+}
+
+func (m *mockCollectionStore) Delete(_ context.Context, _, _ string) error {
+	return nil
+}
+
+// mockCollectionArticles records how a collection was evaluated.
+type mockCollectionArticles struct {
+	listed   *repository.ListArticlesParams
+	searched *domain.SearchQuery
+	count    int
+}
+
+func (m *mockCollectionArticles) ListByUser(_ context.Context, p repository.ListArticlesParams) (*repository.ListArticlesResult, error) {
+	m.listed = &p
+	return &repository.ListArticlesResult{Articles: []domain.Article{}}, nil
+}
+
+func (m *mockCollectionArticles) SearchFiltered(_ context.Context, q *domain.SearchQuery, p repository.ListArticlesParams) (*repository.ListArticlesResult, error) {
+	m.searched, m.listed = q, &p
+	return &repository.ListArticlesResult{Articles: []domain.Article{}}, nil
+}
+
+func (m *mockCollectionArticles) CountFiltered(_ context.Context, _ *domain.SearchQuery, _ repository.ListArticlesParams) (int, error) {
+	return m.count, nil
+}
+
+func (m *mockCollectionArticles) ListFilteredIDs(_ context.Context, _ *domain.SearchQuery, _ repository.ListArticlesParams, _ int) ([]string, error) {
+	return []string{"a1"}, nil
+}
+
+type mockCollectionTags struct{}
+
+func (mockCollectionTags) ListByUser(_ context.Context, _ string) ([]domain.Tag, error) {
+	return []domain.Tag{{ID: "tag-1"}}, nil
+}
+
+func newTestCollectionService(collections ...domain.Collection) (*CollectionService, *mockCollectionStore, *mockCollectionArticles) {
+	store := &mockCollectionStore{collections: collections}
+	articles := &mockCollectionArticles{count: 7}
+	return &CollectionService{collectionRepo: store, articleRepo: articles, tagRepo: mockCollectionTags{}}, store, articles
+}
+
+func TestCollectionService_Create(t *testing.T) {
+	svc, store, _ := newTestCollectionService(domain.Collection{ID: "col-1", UserID: "user-1", Name: "Reading list"})
+
+	filter := domain.ArticleFilter{
+		Query:       "  golang tag:backend ",
+		TagIDs:      []string{"tag-1", "tag-1"},
+		SourceTypes: []domain.SourceType{domain.SourcePDF},
+	}
+	c, err := svc.Create(context.Background(), "user-1", "  Go   papers ", filter)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if c.Name != "Go papers" || store.created.Filter.Query != "golang tag:backend" || len(store.created.Filter.TagIDs) != 1 {
+		t.Errorf("created = %+v", store.created)
+	}
+
+	neg, days, status := -1, 0, domain.ArticleStatus("gone")
+	tests := []struct {
+		name   string
+		cname  string
+		filter domain.ArticleFilter
+		want   error
+	}{
+		{"empty name", " ", domain.ArticleFilter{}, ErrInvalidCollection},
+		{"long name", strings.Repeat("名", maxCollectionNameLen+1), domain.ArticleFilter{}, ErrInvalidCollection},
+		{"duplicate name", "reading LIST", domain.ArticleFilter{}, ErrDuplicateCollection},
+		{"bad query", "q", domain.ArticleFilter{Query: "source:fax"}, ErrInvalidCollection},
+		{"bad status", "q", domain.ArticleFilter{Status: &status}, ErrInvalidCollection},
+		{"bad source", "q", domain.ArticleFilter{SourceTypes: []domain.SourceType{"fax"}}, ErrInvalidCollection},
+		{"negative words", "q", domain.ArticleFilter{MinWords: &neg}, ErrInvalidCollection},
+		{"zero days", "q", domain.ArticleFilter{CreatedWithinDays: &days}, ErrInvalidCollection},
+		{"foreign tag", "q", domain.ArticleFilter{TagIDs: []string{"tag-2"}}, ErrInvalidCollection},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := svc.Create(context.Background(), "user-1", tt.cname, tt.filter); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollectionService_Create_Limit(t *testing.T) {
+	existing := make([]domain.Collection, maxCollectionsPerUser)
+	for i := range existing {
+		existing[i] = domain.Collection{ID: fmt.Sprint(i), UserID: "user-1", Name: fmt.Sprint("c", i)}
+	}
+	svc, _, _ := newTestCollectionService(existing...)
+	if _, err := svc.Create(context.Background(), "user-1", "one more", domain.ArticleFilter{}); !errors.Is(err, ErrCollectionLimit) {
+		t.Errorf("err = %v, want ErrCollectionLimit", err)
+	}
+}
+
+func TestCollectionService_Update(t *testing.T) {
+	svc, store, _ := newTestCollectionService(
+		domain.Collection{ID: "col-1", UserID: "user-1", Name: "Papers"},
+		domain.Collection{ID: "col-2", UserID: "user-1", Name: "Videos"},
+	)
+
+	// Renaming a collection to its own name in another case is allowed.
+	name := "PAPERS"
+	if _, err := svc.Update(context.Background(), "user-1", "col-1", repository.UpdateCollectionParams{Name: &name}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if store.updated == nil || *store.updated.Name != "PAPERS" {
+		t.Errorf("updated = %+v", store.updated)
+	}
+
+	name = "videos"
+	if _, err := svc.Update(context.Background(), "user-1", "col-1", repository.UpdateCollectionParams{Name: &name}); !errors.Is(err, ErrDuplicateCollection) {
+		t.Errorf("taken name: err = %v, want ErrDuplicateCollection", err)
+	}
+	if _, err := svc.Update(context.Background(), "user-2", "col-1", repository.UpdateCollectionParams{}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("other user: err = %v, want ErrForbidden", err)
+	}
+	if _, err := svc.Update(context.Background(), "user-1", "col-9", repository.UpdateCollectionParams{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestCollectionService_ListAndArticles(t *testing.T) {
+	fav := true
+	svc, _, articles := newTestCollectionService(
+		domain.Collection{ID: "col-1", UserID: "user-1", Name: "Favorites", Filter: domain.ArticleFilter{Favorite: &fav}},
+		domain.Collection{ID: "col-2", UserID: "user-1", Name: "Go", Filter: domain.ArticleFilter{Query: "golang"}},
+	)
+
+	collections, err := svc.List(context.Background(), "user-1")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(collections) != 2 || collections[0].ArticleCount != 7 {
+		t.Errorf("collections = %+v", collections)
+	}
+
+	// Without a query the collection lists newest first.
+	if _, err := svc.Articles(context.Background(), "user-1", "col-1", 2, 10); err != nil {
+		t.Fatalf("Articles failed: %v", err)
+	}
+	if articles.searched != nil || articles.listed.Favorite == nil || articles.listed.Page != 2 || articles.listed.UserID != "user-1" {
+		t.Errorf("listed %+v, searched %+v", articles.listed, articles.searched)
+	}
+
+	// With one it is ranked by the search.
+	if _, err := svc.Articles(context.Background(), "user-1", "col-2", 1, 20); err != nil {
+		t.Fatalf("Articles failed: %v", err)
+	}
+	if articles.searched == nil || articles.searched.Terms[0].Text != "golang" {
+		t.Errorf("searched = %+v", articles.searched)
+	}
+
+	if _, err := svc.ArticleIDs(context.Background(), "user-2", "col-1", 10); !errors.Is(err, ErrForbidden) {
+		t.Errorf("other user: err = %v, want ErrForbidden", err)
+	}
+}
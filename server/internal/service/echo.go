@@ -3,24 +3,33 @@ package service
 import (
 	"context"
 	"fmt"
-	"math"
 	"time"
 
 	"folio-server/internal/domain"
+	"folio-server/internal/fsrs"
 	"folio-server/internal/repository"
 )
 
 const echoFreeWeeklyLimit = 3
 
 type EchoService struct {
-	echoRepo *repository.EchoRepo
-	userRepo *repository.UserRepo
+	echoRepo      *repository.EchoRepo
+	userRepo      *repository.UserRepo
+	articleRepo   *repository.ArticleRepo
+	highlightRepo *repository.HighlightRepo
 }
 
-func NewEchoService(echoRepo *repository.EchoRepo, userRepo *repository.UserRepo) *EchoService {
+func NewEchoService(
+	echoRepo *repository.EchoRepo,
+	userRepo *repository.UserRepo,
+	articleRepo *repository.ArticleRepo,
+	highlightRepo *repository.HighlightRepo,
+) *EchoService {
 	return &EchoService{
-		echoRepo: echoRepo,
-		userRepo: userRepo,
+		echoRepo:      echoRepo,
+		userRepo:      userRepo,
+		articleRepo:   articleRepo,
+		highlightRepo: highlightRepo,
 	}
 }
 
@@ -71,19 +80,21 @@ func (s *EchoService) GetTodayCards(ctx context.Context, userID string, limit in
 	return cards, len(cards), 0, nil, nil
 }
 
-// ReviewResult holds the outcome of a single SM-2 review submission.
+// ReviewResult holds the outcome of a single review submission.
 type ReviewResult struct {
 	NextReviewAt time.Time
 	IntervalDays int
+	Stability    float64
+	Difficulty   float64
 	ReviewCount  int
 	CorrectCount int
 	Streak       domain.EchoStreak
 }
 
-// SubmitReview applies SM-2 to the card, records the review, increments weekly quota,
+// SubmitReview applies FSRS to the card, records the review, increments weekly quota,
 // and returns the updated review result with streak info.
 func (s *EchoService) SubmitReview(ctx context.Context, userID, cardID string,
-	result domain.EchoReviewResult, responseTimeMs *int) (*ReviewResult, error) {
+	rating domain.EchoRating, responseTimeMs *int) (*ReviewResult, error) {
 
 	// 1. Get card (verifies ownership)
 	card, err := s.echoRepo.GetCardByID(ctx, cardID, userID)
@@ -93,9 +104,16 @@ func (s *EchoService) SubmitReview(ctx context.Context, userID, cardID string,
 	if card == nil {
 		return nil, ErrNotFound
 	}
+	if card.SuspendedAt != nil {
+		return nil, ErrEchoCardSuspended
+	}
 
-	// 2. Apply SM-2 algorithm
-	updateSM2(card, result)
+	// 2. Apply FSRS with the user's fitted weights, if any
+	params, err := s.schedulerParams(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	updateFSRS(card, params, rating, time.Now())
 
 	// 3. Persist updated card
 	if err := s.echoRepo.UpdateCard(ctx, card); err != nil {
@@ -106,7 +124,8 @@ func (s *EchoService) SubmitReview(ctx context.Context, userID, cardID string,
 	review := &domain.EchoReview{
 		CardID:         cardID,
 		UserID:         userID,
-		Result:         result,
+		Result:         rating.Result(),
+		Rating:         rating,
 		ResponseTimeMs: responseTimeMs,
 	}
 	if err := s.echoRepo.CreateReview(ctx, review); err != nil {
@@ -129,6 +148,8 @@ func (s *EchoService) SubmitReview(ctx context.Context, userID, cardID string,
 	return &ReviewResult{
 		NextReviewAt: card.NextReviewAt,
 		IntervalDays: card.IntervalDays,
+		Stability:    card.Stability,
+		Difficulty:   card.Difficulty,
 		ReviewCount:  card.ReviewCount,
 		CorrectCount: card.CorrectCount,
 		Streak:       streak,
@@ -161,25 +182,40 @@ func (s *EchoService) buildStreak(ctx context.Context, userID string) (domain.Ec
 	}, nil
 }
 
-// updateSM2 applies the SM-2 spaced repetition algorithm to a card in place.
-func updateSM2(card *domain.EchoCard, result domain.EchoReviewResult) {
+// schedulerParams returns the FSRS parameters for the user: their fitted
+// weights, or the defaults until the echo:optimize job has fitted some.
+func (s *EchoService) schedulerParams(ctx context.Context, userID string) (fsrs.Parameters, error) {
+	params := fsrs.DefaultParameters()
+	saved, err := s.echoRepo.GetSchedulerParams(ctx, userID)
+	if err != nil {
+		return params, fmt.Errorf("get scheduler params: %w", err)
+	}
+	if saved != nil {
+		if w, ok := fsrs.WeightsFromSlice(saved.Weights); ok {
+			params.W = w
+		}
+	}
+	return params, nil
+}
+
+// updateFSRS applies an FSRS review graded rating at now to a card in place.
+func updateFSRS(card *domain.EchoCard, params fsrs.Parameters, rating domain.EchoRating, now time.Time) {
+	var elapsed float64
+	if card.LastReviewedAt != nil {
+		elapsed = now.Sub(*card.LastReviewedAt).Hours() / 24
+	}
+	state := params.Next(fsrs.State{Stability: card.Stability, Difficulty: card.Difficulty},
+		elapsed, fsrs.Rating(rating))
+
+	card.Stability = state.Stability
+	card.Difficulty = state.Difficulty
 	card.ReviewCount++
-	if result == domain.EchoRemembered {
+	if rating != domain.EchoAgain {
 		card.CorrectCount++
-		switch card.ReviewCount {
-		case 1:
-			card.IntervalDays = 1
-		case 2:
-			card.IntervalDays = 3
-		default:
-			card.IntervalDays = int(math.Round(float64(card.IntervalDays) * card.EaseFactor))
-		}
-		card.EaseFactor = math.Min(3.0, card.EaseFactor+0.1)
-	} else {
-		card.IntervalDays = 1
-		card.EaseFactor = math.Max(1.3, card.EaseFactor-0.2)
 	}
-	card.NextReviewAt = time.Now().Add(time.Duration(card.IntervalDays) * 24 * time.Hour)
+	card.IntervalDays = params.Interval(state.Stability)
+	card.LastReviewedAt = &now
+	card.NextReviewAt = now.Add(time.Duration(card.IntervalDays) * 24 * time.Hour)
 }
 
 // thisWeekMonday returns Monday 00:00:00 UTC of the current week.
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"folio-server/internal/domain"
+	"folio-server/internal/repository"
+)
+
+// echoCardMaxRunes caps the question and answer of a user-written card.
+const echoCardMaxRunes = 2000
+
+// CreateEchoCardInput is a user-written card. It is tied to an article, or
+// to a highlight and through it to the highlight's article.
+type CreateEchoCardInput struct {
+	ArticleID     string
+	HighlightID   *string
+	Question      string
+	Answer        string
+	SourceContext *string
+}
+
+// ListCards returns a page of the user's cards.
+func (s *EchoService) ListCards(ctx context.Context, p repository.ListEchoCardsParams) (*repository.ListEchoCardsResult, error) {
+	return s.echoRepo.ListCards(ctx, p)
+}
+
+// GetCard returns one of the user's cards.
+func (s *EchoService) GetCard(ctx context.Context, userID, cardID string) (*domain.EchoCard, error) {
+	card, err := s.echoRepo.GetCardByID(ctx, cardID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get card: %w", err)
+	}
+	if card == nil {
+		return nil, ErrNotFound
+	}
+	return card, nil
+}
+
+// CreateCard creates a user-written card, due for its first review tomorrow
+// like generated cards.
+func (s *EchoService) CreateCard(ctx context.Context, userID string, in CreateEchoCardInput) (*domain.EchoCard, error) {
+	question, answer, err := normalizeEchoCardText(in.Question, in.Answer)
+	if err != nil {
+		return nil, err
+	}
+
+	articleID := in.ArticleID
+	if in.HighlightID != nil {
+		h, err := s.highlightRepo.GetByID(ctx, *in.HighlightID, userID)
+		if err != nil {
+			return nil, fmt.Errorf("get highlight: %w", err)
+		}
+		if h == nil {
+			return nil, ErrNotFound
+		}
+		if articleID != "" && articleID != h.ArticleID {
+			return nil, ErrInvalidEchoCard
+		}
+		articleID = h.ArticleID
+	}
+	if articleID == "" {
+		return nil, ErrInvalidEchoCard
+	}
+	article, err := s.articleRepo.GetByID(ctx, articleID)
+	if err != nil {
+		return nil, fmt.Errorf("get article: %w", err)
+	}
+	if article == nil {
+		return nil, ErrNotFound
+	}
+	if article.UserID != userID {
+		return nil, ErrForbidden
+	}
+
+	card := &domain.EchoCard{
+		UserID:        userID,
+		ArticleID:     articleID,
+		CardType:      domain.EchoCardManual,
+		Question:      question,
+		Answer:        answer,
+		SourceContext: in.SourceContext,
+		HighlightID:   in.HighlightID,
+		NextReviewAt:  time.Now().Add(24 * time.Hour),
+		IntervalDays:  1,
+	}
+	if err := s.echoRepo.CreateCard(ctx, card); err != nil {
+		return nil, fmt.Errorf("create card: %w", err)
+	}
+	return s.GetCard(ctx, userID, card.ID)
+}
+
+// UpdateCard edits the question and answer of one of the user's cards.
+// Nil values keep the current text.
+func (s *EchoService) UpdateCard(ctx context.Context, userID, cardID string, question, answer *string) (*domain.EchoCard, error) {
+	card, err := s.GetCard(ctx, userID, cardID)
+	if err != nil {
+		return nil, err
+	}
+	q, a := card.Question, card.Answer
+	if question != nil {
+		q = *question
+	}
+	if answer != nil {
+		a = *answer
+	}
+	card.Question, card.Answer, err = normalizeEchoCardText(q, a)
+	if err != nil {
+		return nil, err
+	}
+	if card.CardType == domain.EchoCardCloze {
+		// The answer of a cloze card is the blanked term, so it must still
+		// occur in the passage.
+		start, end, ok := domain.ClozeOffsets(card.Question, card.Answer)
+		if !ok {
+			return nil, ErrInvalidEchoCard
+		}
+		card.ClozeStart, card.ClozeEnd = &start, &end
+	}
+	if err := s.echoRepo.UpdateCardContent(ctx, card); err != nil {
+		return nil, err
+	}
+	return s.GetCard(ctx, userID, cardID)
+}
+
+// SuspendCard suspends or unsuspends one of the user's cards. A suspended
+// card keeps its schedule but is never due.
+func (s *EchoService) SuspendCard(ctx context.Context, userID, cardID string, suspended bool) (*domain.EchoCard, error) {
+	if _, err := s.GetCard(ctx, userID, cardID); err != nil {
+		return nil, err
+	}
+	if err := s.echoRepo.SetSuspended(ctx, cardID, userID, suspended); err != nil {
+		return nil, err
+	}
+	return s.GetCard(ctx, userID, cardID)
+}
+
+// BuryCard hides one of the user's cards from due cards until tomorrow
+// (00:00 UTC, the day boundary streaks use).
+func (s *EchoService) BuryCard(ctx context.Context, userID, cardID string) (*domain.EchoCard, error) {
+	if _, err := s.GetCard(ctx, userID, cardID); err != nil {
+		return nil, err
+	}
+	tomorrow := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+	if err := s.echoRepo.SetBuriedUntil(ctx, cardID, userID, tomorrow); err != nil {
+		return nil, err
+	}
+	return s.GetCard(ctx, userID, cardID)
+}
+
+// DeleteCard deletes one of the user's cards and its review history.
+func (s *EchoService) DeleteCard(ctx context.Context, userID, cardID string) error {
+	if _, err := s.GetCard(ctx, userID, cardID); err != nil {
+		return err
+	}
+	return s.echoRepo.DeleteCard(ctx, cardID, userID)
+}
+
+// normalizeEchoCardText trims a card's question and answer and checks that
+// both are present and within echoCardMaxRunes.
+func normalizeEchoCardText(question, answer string) (string, string, error) {
+	question, answer = strings.TrimSpace(question), strings.TrimSpace(answer)
+	for _, s := range []string{question, answer} {
+		if s == "" || utf8.RuneCountInString(s) > echoCardMaxRunes {
+			return "", "", ErrInvalidEchoCard
+		}
+	}
+	return question, answer, nil
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"folio-server/internal/domain"
+	"folio-server/internal/fsrs"
+)
+
+func TestUpdateFSRS(t *testing.T) {
+	params := fsrs.DefaultParameters()
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	card := &domain.EchoCard{IntervalDays: 1, NextReviewAt: now}
+	updateFSRS(card, params, domain.EchoGood, now)
+	if card.ReviewCount != 1 || card.CorrectCount != 1 {
+		t.Errorf("counts = %d/%d, want 1/1", card.CorrectCount, card.ReviewCount)
+	}
+	if card.Stability != fsrs.DefaultWeights[2] {
+		t.Errorf("stability = %v, want the initial Good stability", card.Stability)
+	}
+	if card.LastReviewedAt == nil || !card.LastReviewedAt.Equal(now) {
+		t.Errorf("last reviewed at = %v, want %v", card.LastReviewedAt, now)
+	}
+	if want := now.AddDate(0, 0, card.IntervalDays); !card.NextReviewAt.Equal(want) {
+		t.Errorf("next review at = %v, want %v", card.NextReviewAt, want)
+	}
+
+	// Reviewed on schedule and recalled, the interval grows.
+	first := card.IntervalDays
+	later := now.AddDate(0, 0, first)
+	updateFSRS(card, params, domain.EchoGood, later)
+	if card.IntervalDays <= first {
+		t.Errorf("interval after second Good = %d, want more than %d", card.IntervalDays, first)
+	}
+
+	// Forgotten, it shrinks and the card gets harder.
+	before := *card
+	updateFSRS(card, params, domain.EchoAgain, later.AddDate(0, 0, card.IntervalDays))
+	if card.IntervalDays >= before.IntervalDays || card.Difficulty <= before.Difficulty {
+		t.Errorf("after Again: interval %d, difficulty %v; before: %d, %v",
+			card.IntervalDays, card.Difficulty, before.IntervalDays, before.Difficulty)
+	}
+	if card.CorrectCount != 2 || card.ReviewCount != 3 {
+		t.Errorf("counts = %d/%d, want 2/3", card.CorrectCount, card.ReviewCount)
+	}
+}
+
+func TestNormalizeEchoCardText(t *testing.T) {
+	q, a, err := normalizeEchoCardText("  什么是间隔重复？\n", " 按遗忘曲线安排复习 ")
+	if err != nil || q != "什么是间隔重复？" || a != "按遗忘曲线安排复习" {
+		t.Errorf("got %q, %q, %v", q, a, err)
+	}
+
+	long := strings.Repeat("字", echoCardMaxRunes+1)
+	for _, tt := range [][2]string{{"", "answer"}, {"question", "   "}, {long, "answer"}} {
+		if _, _, err := normalizeEchoCardText(tt[0], tt[1]); !errors.Is(err, ErrInvalidEchoCard) {
+			t.Errorf("normalizeEchoCardText(%.10q, %.10q) error = %v, want ErrInvalidEchoCard", tt[0], tt[1], err)
+		}
+	}
+	if _, _, err := normalizeEchoCardText(strings.Repeat("字", echoCardMaxRunes), "answer"); err != nil {
+		t.Errorf("question at the limit rejected: %v", err)
+	}
+}
@@ -8,8 +8,61 @@ var (
 	ErrNotFound         = errors.New("not found")
 	ErrForbidden        = errors.New("forbidden")
 	ErrDuplicateURL     = errors.New("url already saved")
+	ErrInvalidCursor    = errors.New("invalid cursor")
 	ErrInvalidCode      = errors.New("invalid verification code")
 	ErrCodeRateLimit    = errors.New("verification code rate limit")
+	ErrTooManyAttempts  = errors.New("too many verification attempts")
+
+	// RAG conversation errors
+	ErrInvalidConversationTitle = errors.New("invalid conversation title")
+
+	// Feed errors
+	ErrInvalidFeedURL = errors.New("invalid feed URL")
+	ErrDuplicateFeed  = errors.New("feed already subscribed")
+	ErrFeedLimit      = errors.New("feed subscription limit reached")
+	ErrFeedInactive   = errors.New("feed is paused")
+
+	// Mail ingest errors
+	ErrInvalidSenderPattern = errors.New("invalid sender pattern")
+	ErrMailRecipientUnknown = errors.New("unknown mail recipient")
+	ErrMailSenderRejected   = errors.New("sender not in allow-list")
+	ErrMailMalformed        = errors.New("malformed message")
+	ErrMailEmpty            = errors.New("message has no readable content")
+
+	// PDF upload errors
+	ErrInvalidPDF    = errors.New("invalid PDF file")
+	ErrPDFEncrypted  = errors.New("encrypted PDF")
+	ErrPDFNoText     = errors.New("PDF has no extractable text")
+	ErrPDFTooLarge   = errors.New("PDF too large")
+	ErrPDFTooComplex = errors.New("PDF too complex")
+
+	// Search errors
+	ErrInvalidSearchQuery = errors.New("invalid search query")
+
+	// Collection errors
+	ErrInvalidCollection   = errors.New("invalid collection")
+	ErrDuplicateCollection = errors.New("collection name already in use")
+	ErrCollectionLimit     = errors.New("collection limit reached")
+
+	// Highlight errors
+	ErrInvalidHighlightPage = errors.New("invalid highlight page")
+
+	// Echo card errors
+	ErrInvalidEchoCard   = errors.New("invalid echo card")
+	ErrEchoCardSuspended = errors.New("echo card is suspended")
+
+	// Import errors
+	ErrImportFormat     = errors.New("unrecognized import file")
+	ErrImportEmpty      = errors.New("import file contains no links")
+	ErrImportTooLarge   = errors.New("import file contains too many links")
+	ErrImportInProgress = errors.New("an import is already in progress")
+
+	// Export errors
+	ErrExportInProgress    = errors.New("an export is already in progress")
+	ErrExportNotReady      = errors.New("export is not finished")
+	ErrExportExpired       = errors.New("export has expired")
+	ErrExportUnavailable   = errors.New("exports are not available")
+	ErrInvalidExportFormat = errors.New("invalid export format")
 
 	// Subscription errors
 	ErrInvalidProduct       = errors.New("invalid product ID")
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/hibiken/asynq"
+
+	"folio-server/internal/client"
+	"folio-server/internal/domain"
+	"folio-server/internal/repository"
+	"folio-server/internal/worker"
+)
+
+const (
+	// exportListLimit is how many recent exports GET /exports returns.
+	exportListLimit = 20
+	// exportLinkTTL is how long a presigned download link stays valid. Each
+	// request for the download link signs a fresh one.
+	exportLinkTTL = 15 * time.Minute
+)
+
+type exportJobStore interface {
+	Create(ctx context.Context, userID string, format domain.ExportFormat, collectionID *string, filter *domain.ArticleFilter) (*domain.ExportJob, error)
+	GetByID(ctx context.Context, id string) (*domain.ExportJob, error)
+	ListByUser(ctx context.Context, userID string, limit int) ([]domain.ExportJob, error)
+	HasActive(ctx context.Context, userID string) (bool, error)
+	Fail(ctx context.Context, id, errMsg string) error
+}
+
+type collectionGetter interface {
+	GetByID(ctx context.Context, id string) (*domain.Collection, error)
+}
+
+type objectPresigner interface {
+	PresignGet(ctx context.Context, key string, ttl time.Duration, filename string) (string, error)
+}
+
+// ExportService creates Markdown vault exports of a user's library and hands
+// out download links for finished archives. The archive itself is built by
+// the export:build worker.
+type ExportService struct {
+	exportRepo     exportJobStore
+	collectionRepo collectionGetter
+	presigner      objectPresigner // nil when R2 is not configured
+	asynqClient    taskEnqueuer
+}
+
+func NewExportService(exportRepo *repository.ExportRepo, collectionRepo *repository.CollectionRepo, r2Client *client.R2Client, asynqClient *asynq.Client) *ExportService {
+	s := &ExportService{
+		exportRepo:     exportRepo,
+		collectionRepo: collectionRepo,
+		asynqClient:    asynqClient,
+	}
+	if r2Client != nil {
+		s.presigner = r2Client
+	}
+	return s
+}
+
+// Create starts an export of the user's whole library in the given format,
+// or, when collectionID is set, a Markdown export of that collection's
+// articles. Only one export per user runs at a time.
+func (s *ExportService) Create(ctx context.Context, userID string, format domain.ExportFormat, collectionID *string) (*domain.ExportJob, error) {
+	if format != domain.ExportFormatMarkdown && format != domain.ExportFormatJSON {
+		return nil, ErrInvalidExportFormat
+	}
+	// The JSON format is the personal data export, which is always complete.
+	if collectionID != nil && format != domain.ExportFormatMarkdown {
+		return nil, ErrInvalidExportFormat
+	}
+	if s.presigner == nil {
+		return nil, ErrExportUnavailable
+	}
+
+	var filter *domain.ArticleFilter
+	if collectionID != nil {
+		c, err := s.collectionRepo.GetByID(ctx, *collectionID)
+		if err != nil {
+			return nil, err
+		}
+		if c == nil {
+			return nil, ErrNotFound
+		}
+		if c.UserID != userID {
+			return nil, ErrForbidden
+		}
+		filter = &c.Filter
+	}
+
+	if active, err := s.exportRepo.HasActive(ctx, userID); err != nil {
+		return nil, err
+	} else if active {
+		return nil, ErrExportInProgress
+	}
+
+	job, err := s.exportRepo.Create(ctx, userID, format, collectionID, filter)
+	if err != nil {
+		return nil, fmt.Errorf("create export job: %w", err)
+	}
+
+	if _, err := s.asynqClient.EnqueueContext(ctx, worker.NewExportBuildTask(job.ID)); err != nil {
+		// Fail the job so it doesn't block the user's next attempt.
+		_ = s.exportRepo.Fail(ctx, job.ID, "could not be scheduled")
+		return nil, fmt.Errorf("enqueue export: %w", err)
+	}
+
+	slog.Info("export created", "job_id", job.ID, "user_id", userID, "format", format)
+	return job, nil
+}
+
+func (s *ExportService) Get(ctx context.Context, userID, jobID string) (*domain.ExportJob, error) {
+	job, err := s.exportRepo.GetByID(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, ErrNotFound
+	}
+	if job.UserID != userID {
+		return nil, ErrForbidden
+	}
+	return job, nil
+}
+
+// ListByUser returns the user's most recent exports, newest first.
+func (s *ExportService) ListByUser(ctx context.Context, userID string) ([]domain.ExportJob, error) {
+	return s.exportRepo.ListByUser(ctx, userID, exportListLimit)
+}
+
+// DownloadURL returns a short-lived link to a finished export's archive.
+func (s *ExportService) DownloadURL(ctx context.Context, userID, jobID string) (string, error) {
+	job, err := s.Get(ctx, userID, jobID)
+	if err != nil {
+		return "", err
+	}
+	if s.presigner == nil {
+		return "", ErrExportUnavailable
+	}
+
+	switch job.Status {
+	case domain.ExportStatusCompleted:
+	case domain.ExportStatusExpired:
+		return "", ErrExportExpired
+	default:
+		return "", ErrExportNotReady
+	}
+	if job.ObjectKey == nil || (job.ExpiresAt != nil && time.Now().After(*job.ExpiresAt)) {
+		return "", ErrExportExpired
+	}
+
+	name := "folio-export"
+	if job.Format == domain.ExportFormatJSON {
+		name = "folio-data"
+	}
+	filename := fmt.Sprintf("%s-%s.zip", name, job.CreatedAt.UTC().Format("2006-01-02"))
+	url, err := s.presigner.PresignGet(ctx, *job.ObjectKey, exportLinkTTL, filename)
+	if err != nil {
+		return "", fmt.Errorf("presign export: %w", err)
+	}
+	return url, nil
+}
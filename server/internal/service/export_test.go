@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+
+	"folio-server/internal/domain"
+)
+
+type mockExportStore struct {
+	active bool
+	job    *domain.ExportJob
+	failed bool
+}
+
+func (m *mockExportStore) Create(_ context.Context, userID string, format domain.ExportFormat, collectionID *string, filter *domain.ArticleFilter) (*domain.ExportJob, error) {
+	return &domain.ExportJob{ID: "job-1", UserID: userID, Status: domain.ExportStatusPending, Format: format,
+		CollectionID: collectionID, Filter: filter}, nil
+}
+
+type mockCollectionGetter struct{ collection *domain.Collection }
+
+func (m *mockCollectionGetter) GetByID(_ context.Context, _ string) (*domain.Collection, error) {
+	return m.collection, nil
+}
+
+func (m *mockExportStore) GetByID(_ context.Context, _ string) (*domain.ExportJob, error) {
+	return m.job, nil
+}
+
+func (m *mockExportStore) ListByUser(_ context.Context, _ string, _ int) ([]domain.ExportJob, error) {
+	return nil, nil
+}
+
+func (m *mockExportStore) HasActive(_ context.Context, _ string) (bool, error) {
+	return m.active, nil
+}
+
+func (m *mockExportStore) Fail(_ context.Context, _, _ string) error {
+	m.failed = true
+	return nil
+}
+
+type mockPresigner struct {
+	key string
+}
+
+func (m *mockPresigner) PresignGet(_ context.Context, key string, _ time.Duration, _ string) (string, error) {
+	m.key = key
+	return "https://r2.example.com/signed", nil
+}
+
+func TestExportService_Create(t *testing.T) {
+	enq := &mockEnqueuer{}
+	s := &ExportService{exportRepo: &mockExportStore{}, presigner: &mockPresigner{}, asynqClient: enq}
+
+	job, err := s.Create(context.Background(), "user-1", domain.ExportFormatMarkdown, nil)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if job.Status != domain.ExportStatusPending || job.Format != domain.ExportFormatMarkdown {
+		t.Errorf("job = %+v", job)
+	}
+	if len(enq.enqueuedTasks) != 1 || enq.enqueuedTasks[0].Type() != "export:build" {
+		t.Errorf("enqueued %v", enq.enqueuedTasks)
+	}
+}
+
+func TestExportService_Create_Errors(t *testing.T) {
+	if _, err := (&ExportService{exportRepo: &mockExportStore{}, asynqClient: &mockEnqueuer{}}).Create(context.Background(), "user-1", domain.ExportFormatMarkdown, nil); !errors.Is(err, ErrExportUnavailable) {
+		t.Errorf("without storage: err = %v, want ErrExportUnavailable", err)
+	}
+
+	s := &ExportService{exportRepo: &mockExportStore{active: true}, presigner: &mockPresigner{}, asynqClient: &mockEnqueuer{}}
+	if _, err := s.Create(context.Background(), "user-1", domain.ExportFormatMarkdown, nil); !errors.Is(err, ErrExportInProgress) {
+		t.Errorf("active export: err = %v, want ErrExportInProgress", err)
+	}
+
+	s = &ExportService{exportRepo: &mockExportStore{}, presigner: &mockPresigner{}, asynqClient: &mockEnqueuer{}}
+	if _, err := s.Create(context.Background(), "user-1", "pdf", nil); !errors.Is(err, ErrInvalidExportFormat) {
+		t.Errorf("unknown format: err = %v, want ErrInvalidExportFormat", err)
+	}
+
+	store := &mockExportStore{}
+	enq := &mockEnqueuer{enqueueFn: func(context.Context, *asynq.Task, ...asynq.Option) (*asynq.TaskInfo, error) {
+		return nil, errors.New("redis down")
+	}}
+	s = &ExportService{exportRepo: store, presigner: &mockPresigner{}, asynqClient: enq}
+	if _, err := s.Create(context.Background(), "user-1", domain.ExportFormatMarkdown, nil); err == nil || !store.failed {
+		t.Errorf("enqueue failure: err = %v, failed = %v; want error and failed job", err, store.failed)
+	}
+}
+
+func TestExportService_CreateCollection(t *testing.T) {
+	query := "golang"
+	collections := &mockCollectionGetter{collection: &domain.Collection{ID: "col-1", UserID: "user-1", Filter: domain.ArticleFilter{Query: query}}}
+	s := &ExportService{exportRepo: &mockExportStore{}, collectionRepo: collections, presigner: &mockPresigner{}, asynqClient: &mockEnqueuer{}}
+	id := "col-1"
+
+	job, err := s.Create(context.Background(), "user-1", domain.ExportFormatMarkdown, &id)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if job.CollectionID == nil || *job.CollectionID != id || job.Filter == nil || job.Filter.Query != query {
+		t.Errorf("job = %+v, want the collection's filter copied", job)
+	}
+
+	if _, err := s.Create(context.Background(), "user-1", domain.ExportFormatJSON, &id); !errors.Is(err, ErrInvalidExportFormat) {
+		t.Errorf("JSON collection export: err = %v, want ErrInvalidExportFormat", err)
+	}
+	if _, err := s.Create(context.Background(), "user-2", domain.ExportFormatMarkdown, &id); !errors.Is(err, ErrForbidden) {
+		t.Errorf("other user's collection: err = %v, want ErrForbidden", err)
+	}
+	collections.collection = nil
+	if _, err := s.Create(context.Background(), "user-1", domain.ExportFormatMarkdown, &id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing collection: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestExportService_DownloadURL(t *testing.T) {
+	key := "exports/user-1/job-1.zip"
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name string
+		job  *domain.ExportJob
+		want error
+	}{
+		{"completed", &domain.ExportJob{UserID: "user-1", Status: domain.ExportStatusCompleted, ObjectKey: &key, ExpiresAt: &future}, nil},
+		{"running", &domain.ExportJob{UserID: "user-1", Status: domain.ExportStatusRunning}, ErrExportNotReady},
+		{"expired status", &domain.ExportJob{UserID: "user-1", Status: domain.ExportStatusExpired}, ErrExportExpired},
+		{"past expiry", &domain.ExportJob{UserID: "user-1", Status: domain.ExportStatusCompleted, ObjectKey: &key, ExpiresAt: &past}, ErrExportExpired},
+		{"other user", &domain.ExportJob{UserID: "user-2", Status: domain.ExportStatusCompleted, ObjectKey: &key}, ErrForbidden},
+		{"missing", nil, ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			presigner := &mockPresigner{}
+			s := &ExportService{exportRepo: &mockExportStore{job: tt.job}, presigner: presigner}
+			url, err := s.DownloadURL(context.Background(), "user-1", "job-1")
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			if tt.want == nil && (url == "" || presigner.key != key) {
+				t.Errorf("url = %q, presigned key = %q", url, presigner.key)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"github.com/hibiken/asynq"
+
+	"folio-server/internal/client"
+	"folio-server/internal/domain"
+	"folio-server/internal/repository"
+	"folio-server/internal/worker"
+)
+
+// maxFeedsPerUser caps how many feed subscriptions one user can hold.
+const maxFeedsPerUser = 200
+
+type FeedService struct {
+	feedRepo    *repository.FeedRepo
+	asynqClient *asynq.Client
+}
+
+func NewFeedService(feedRepo *repository.FeedRepo, asynqClient *asynq.Client) *FeedService {
+	return &FeedService{
+		feedRepo:    feedRepo,
+		asynqClient: asynqClient,
+	}
+}
+
+// Subscribe creates a feed subscription and enqueues an immediate first poll.
+func (s *FeedService) Subscribe(ctx context.Context, userID, rawURL string, title *string) (*domain.Feed, error) {
+	feedURL, err := normalizeFeedURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if exists, err := s.feedRepo.ExistsByUserAndURL(ctx, userID, feedURL); err != nil {
+		return nil, fmt.Errorf("check duplicate feed: %w", err)
+	} else if exists {
+		return nil, ErrDuplicateFeed
+	}
+
+	feeds, err := s.feedRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list feeds: %w", err)
+	}
+	if len(feeds) >= maxFeedsPerUser {
+		return nil, ErrFeedLimit
+	}
+
+	feed, err := s.feedRepo.Create(ctx, userID, feedURL, title)
+	if err != nil {
+		return nil, fmt.Errorf("create feed: %w", err)
+	}
+
+	s.enqueuePoll(ctx, feed.ID)
+	slog.Info("feed subscribed", "feed_id", feed.ID, "user_id", userID, "url", feedURL)
+	return feed, nil
+}
+
+func (s *FeedService) ListByUser(ctx context.Context, userID string) ([]domain.Feed, error) {
+	return s.feedRepo.ListByUser(ctx, userID)
+}
+
+func (s *FeedService) Get(ctx context.Context, userID, feedID string) (*domain.Feed, error) {
+	feed, err := s.feedRepo.GetByID(ctx, feedID)
+	if err != nil {
+		return nil, err
+	}
+	if feed == nil {
+		return nil, ErrNotFound
+	}
+	if feed.UserID != userID {
+		return nil, ErrForbidden
+	}
+	return feed, nil
+}
+
+// Update renames or pauses/resumes a feed. Resuming a feed clears its error
+// count and polls it right away.
+func (s *FeedService) Update(ctx context.Context, userID, feedID string, p repository.UpdateFeedParams) (*domain.Feed, error) {
+	if _, err := s.Get(ctx, userID, feedID); err != nil {
+		return nil, err
+	}
+	if err := s.feedRepo.Update(ctx, feedID, userID, p); err != nil {
+		return nil, err
+	}
+	if p.IsActive != nil && *p.IsActive {
+		s.enqueuePoll(ctx, feedID)
+	}
+	return s.Get(ctx, userID, feedID)
+}
+
+func (s *FeedService) Delete(ctx context.Context, userID, feedID string) error {
+	if _, err := s.Get(ctx, userID, feedID); err != nil {
+		return err
+	}
+	return s.feedRepo.Delete(ctx, feedID, userID)
+}
+
+// Refresh enqueues an out-of-schedule poll for a feed.
+func (s *FeedService) Refresh(ctx context.Context, userID, feedID string) error {
+	feed, err := s.Get(ctx, userID, feedID)
+	if err != nil {
+		return err
+	}
+	if !feed.IsActive {
+		return ErrFeedInactive
+	}
+	s.enqueuePoll(ctx, feedID)
+	return nil
+}
+
+func (s *FeedService) enqueuePoll(ctx context.Context, feedID string) {
+	if _, err := s.asynqClient.EnqueueContext(ctx, worker.NewFeedPollTask(feedID)); err != nil {
+		// Non-fatal: the scheduler will pick the feed up on its next run.
+		slog.Warn("failed to enqueue feed poll", "feed_id", feedID, "error", err)
+	}
+}
+
+// normalizeFeedURL requires an absolute http(s) URL and strips the fragment.
+func normalizeFeedURL(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", ErrInvalidFeedURL
+	}
+	// Caught early for a clear error; the fetcher also refuses hosts that
+	// only resolve to private addresses.
+	if client.IsPrivateHost(u.Hostname()) {
+		return "", ErrInvalidFeedURL
+	}
+	u.Fragment = ""
+	return u.String(), nil
+}
@@ -0,0 +1,22 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestFeedService_Subscribe_RejectsInternalHosts(t *testing.T) {
+	s := &FeedService{}
+	for _, u := range []string{
+		"http://127.0.0.1:8080/feed.xml",
+		"http://localhost/rss",
+		"http://169.254.169.254/latest/meta-data/",
+		"https://10.0.0.5/atom.xml",
+		"http://[::1]/feed",
+	} {
+		if _, err := s.Subscribe(context.Background(), "user-1", u, nil); !errors.Is(err, ErrInvalidFeedURL) {
+			t.Errorf("Subscribe(%q) err = %v, want ErrInvalidFeedURL", u, err)
+		}
+	}
+}
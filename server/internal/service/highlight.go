@@ -4,10 +4,13 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"unicode/utf8"
 
 	"github.com/hibiken/asynq"
 
 	"folio-server/internal/domain"
+	"folio-server/internal/pdf"
+	"folio-server/internal/readertext"
 	"folio-server/internal/repository"
 	"folio-server/internal/worker"
 )
@@ -31,11 +34,14 @@ func NewHighlightService(
 }
 
 // CreateHighlight creates a highlight, increments the article's highlight count,
-// and enqueues an echo:generate task for the highlight.
+// and enqueues an echo:generate task for the highlight. page is optional; for
+// PDF articles it is derived from the page markers around startOffset when
+// the client does not send one.
 func (s *HighlightService) CreateHighlight(
 	ctx context.Context,
 	userID, articleID, text string,
 	startOffset, endOffset int,
+	page *int,
 ) (*domain.Highlight, error) {
 	// Verify article belongs to user
 	article, err := s.articleRepo.GetByID(ctx, articleID)
@@ -49,6 +55,20 @@ func (s *HighlightService) CreateHighlight(
 		return nil, ErrForbidden
 	}
 
+	if article.SourceType == domain.SourcePDF && article.MarkdownContent != nil {
+		pages := pdf.PageAt(*article.MarkdownContent, utf8.RuneCountInString(*article.MarkdownContent))
+		if page == nil {
+			if p := highlightPage(*article.MarkdownContent, article.Title, startOffset); p > 0 {
+				page = &p
+			}
+		} else if pages > 0 && *page > pages {
+			return nil, ErrInvalidHighlightPage
+		}
+	}
+	if page != nil && *page < 1 {
+		return nil, ErrInvalidHighlightPage
+	}
+
 	// Create highlight
 	h := &domain.Highlight{
 		ArticleID:   articleID,
@@ -56,6 +76,7 @@ func (s *HighlightService) CreateHighlight(
 		Text:        text,
 		StartOffset: startOffset,
 		EndOffset:   endOffset,
+		Page:        page,
 		Color:       "yellow",
 	}
 	if err := s.highlightRepo.CreateHighlight(ctx, h); err != nil {
@@ -87,6 +108,18 @@ func (s *HighlightService) CreateHighlight(
 	return h, nil
 }
 
+// highlightPage returns the page of a PDF article's markdown that a highlight
+// starting at startOffset falls on. The reader reports offsets in UTF-16 units
+// of the displayed text, where page markers and markdown syntax are hidden, so
+// the offset is mapped back into the markdown first.
+func highlightPage(markdown string, title *string, startOffset int) int {
+	t := ""
+	if title != nil {
+		t = *title
+	}
+	return pdf.PageAt(markdown, readertext.Render(markdown, t).ToMarkdown(startOffset))
+}
+
 // GetArticleHighlights returns all highlights for a given article owned by the user.
 func (s *HighlightService) GetArticleHighlights(
 	ctx context.Context,
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"folio-server/internal/pdf"
+	"folio-server/internal/readertext"
+)
+
+func TestHighlightPage_ReaderOffsets(t *testing.T) {
+	title := "Report"
+	markdown := "Report\n\n" + pdf.PageMarker(1) + "\n\n**Summary** 📈 of [results](https://example.com).\n\n" +
+		pdf.PageMarker(2) + "\n\nSecond page 𝒳 text.\n\n" +
+		pdf.PageMarker(3) + "\n\nThird page."
+	text := readertext.Render(markdown, title).String()
+
+	for _, tt := range []struct {
+		phrase string
+		want   int
+	}{
+		{"Summary", 1},
+		{"results", 1},
+		{"Second page", 2},
+		{"text.", 2},
+		{"Third page", 3},
+	} {
+		i := strings.Index(text, tt.phrase)
+		if i < 0 {
+			t.Fatalf("%q not in reader text %q", tt.phrase, text)
+		}
+		offset := len(utf16.Encode([]rune(text[:i])))
+		if got := highlightPage(markdown, &title, offset); got != tt.want {
+			t.Errorf("highlightPage(%q at %d) = %d, want %d", tt.phrase, offset, got, tt.want)
+		}
+	}
+
+	if got := highlightPage("No markers here.", nil, 3); got != 0 {
+		t.Errorf("highlightPage without markers = %d, want 0", got)
+	}
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/hibiken/asynq"
+
+	"folio-server/internal/domain"
+	"folio-server/internal/importer"
+	"folio-server/internal/repository"
+	"folio-server/internal/worker"
+)
+
+// MaxImportFileBytes is the largest export file accepted by the import endpoint.
+const MaxImportFileBytes = 50 << 20 // 50 MB
+
+type importJobStore interface {
+	Create(ctx context.Context, userID, source string, items []domain.ImportItem) (*domain.ImportJob, error)
+	GetByID(ctx context.Context, id string) (*domain.ImportJob, error)
+	ListByUser(ctx context.Context, userID string, page, perPage int) ([]domain.ImportJob, int, error)
+	HasActive(ctx context.Context, userID string) (bool, error)
+	SetStatus(ctx context.Context, id string, status domain.ImportStatus, errMsg *string) error
+}
+
+// ImportService creates bulk import jobs from Pocket, Instapaper and
+// Omnivore exports and browser bookmark files. The items themselves are
+// processed by the import:process worker, which charges each imported
+// article to the user's monthly quota.
+type ImportService struct {
+	importRepo  importJobStore
+	asynqClient taskEnqueuer
+}
+
+func NewImportService(importRepo *repository.ImportRepo, asynqClient *asynq.Client) *ImportService {
+	return &ImportService{
+		importRepo:  importRepo,
+		asynqClient: asynqClient,
+	}
+}
+
+// Create parses an export file and starts the job. Only one import per user
+// runs at a time.
+func (s *ImportService) Create(ctx context.Context, userID string, data []byte) (*domain.ImportJob, error) {
+	source, parsed, err := importer.Parse(data)
+	switch {
+	case errors.Is(err, importer.ErrNoItems):
+		return nil, ErrImportEmpty
+	case errors.Is(err, importer.ErrTooManyItems):
+		return nil, ErrImportTooLarge
+	case err != nil:
+		slog.Info("import parse failed", "user_id", userID, "error", err)
+		return nil, ErrImportFormat
+	}
+
+	if active, err := s.importRepo.HasActive(ctx, userID); err != nil {
+		return nil, err
+	} else if active {
+		return nil, ErrImportInProgress
+	}
+
+	items := make([]domain.ImportItem, len(parsed))
+	for i, it := range parsed {
+		items[i] = domain.ImportItem{
+			URL:        it.URL,
+			SourceType: DetectSource(it.URL),
+			Tags:       it.Tags,
+			SavedAt:    it.SavedAt,
+			IsFavorite: it.IsFavorite,
+			IsArchived: it.IsArchived,
+		}
+		if it.Title != "" {
+			title := it.Title
+			items[i].Title = &title
+		}
+	}
+
+	job, err := s.importRepo.Create(ctx, userID, string(source), items)
+	if err != nil {
+		return nil, fmt.Errorf("create import job: %w", err)
+	}
+
+	if _, err := s.asynqClient.EnqueueContext(ctx, worker.NewImportTask(job.ID)); err != nil {
+		// Fail the job so it doesn't block the user's next attempt.
+		msg := "could not be scheduled"
+		_ = s.importRepo.SetStatus(ctx, job.ID, domain.ImportStatusFailed, &msg)
+		return nil, fmt.Errorf("enqueue import: %w", err)
+	}
+
+	slog.Info("import created", "job_id", job.ID, "user_id", userID, "source", source, "items", len(items))
+	return job, nil
+}
+
+func (s *ImportService) Get(ctx context.Context, userID, jobID string) (*domain.ImportJob, error) {
+	job, err := s.importRepo.GetByID(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, ErrNotFound
+	}
+	if job.UserID != userID {
+		return nil, ErrForbidden
+	}
+	return job, nil
+}
+
+func (s *ImportService) ListByUser(ctx context.Context, userID string, page, perPage int) ([]domain.ImportJob, int, error) {
+	return s.importRepo.ListByUser(ctx, userID, page, perPage)
+}
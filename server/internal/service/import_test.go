@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hibiken/asynq"
+
+	"folio-server/internal/domain"
+)
+
+type mockImportStore struct {
+	active  bool
+	created []domain.ImportItem
+	status  domain.ImportStatus
+}
+
+func (m *mockImportStore) Create(_ context.Context, userID, source string, items []domain.ImportItem) (*domain.ImportJob, error) {
+	m.created = items
+	return &domain.ImportJob{ID: "job-1", UserID: userID, Source: source, Status: domain.ImportStatusPending, TotalItems: len(items)}, nil
+}
+
+func (m *mockImportStore) GetByID(_ context.Context, _ string) (*domain.ImportJob, error) {
+	return nil, nil
+}
+
+func (m *mockImportStore) ListByUser(_ context.Context, _ string, _, _ int) ([]domain.ImportJob, int, error) {
+	return nil, 0, nil
+}
+
+func (m *mockImportStore) HasActive(_ context.Context, _ string) (bool, error) {
+	return m.active, nil
+}
+
+func (m *mockImportStore) SetStatus(_ context.Context, _ string, status domain.ImportStatus, _ *string) error {
+	m.status = status
+	return nil
+}
+
+const testPocketCSV = "title,url,time_added,tags,status\n" +
+	"One,https://mp.weixin.qq.com/s/abc,1700000000,wechat,unread\n" +
+	"Two,https://example.com/two,1700000100,,archive\n"
+
+func TestImportService_Create(t *testing.T) {
+	store := &mockImportStore{}
+	enq := &mockEnqueuer{}
+	s := &ImportService{importRepo: store, asynqClient: enq}
+
+	job, err := s.Create(context.Background(), "user-1", []byte(testPocketCSV))
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if job.Source != "pocket" || job.TotalItems != 2 {
+		t.Errorf("job = %+v", job)
+	}
+	if store.created[0].SourceType != domain.SourceWechat || !store.created[1].IsArchived {
+		t.Errorf("items = %+v", store.created)
+	}
+	if len(enq.enqueuedTasks) != 1 || enq.enqueuedTasks[0].Type() != "import:process" {
+		t.Errorf("enqueued %v", enq.enqueuedTasks)
+	}
+}
+
+func TestImportService_Create_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		active bool
+		want   error
+	}{
+		{"unknown format", "hello world", false, ErrImportFormat},
+		{"no links", "<html><body>nothing</body></html>", false, ErrImportEmpty},
+		{"already running", testPocketCSV, true, ErrImportInProgress},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &ImportService{importRepo: &mockImportStore{active: tt.active}, asynqClient: &mockEnqueuer{}}
+			if _, err := s.Create(context.Background(), "user-1", []byte(tt.data)); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportService_Create_EnqueueFailureFailsJob(t *testing.T) {
+	store := &mockImportStore{}
+	enq := &mockEnqueuer{enqueueFn: func(context.Context, *asynq.Task, ...asynq.Option) (*asynq.TaskInfo, error) {
+		return nil, errors.New("redis down")
+	}}
+	s := &ImportService{importRepo: store, asynqClient: enq}
+
+	if _, err := s.Create(context.Background(), "user-1", []byte(testPocketCSV)); err == nil {
+		t.Fatal("expected error")
+	}
+	if store.status != domain.ImportStatusFailed {
+		t.Errorf("status = %q, want failed so the next import is not blocked", store.status)
+	}
+}
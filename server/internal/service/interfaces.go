@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"io"
 
 	"github.com/hibiken/asynq"
 
@@ -14,9 +15,10 @@ type articleCreator interface {
 	Create(ctx context.Context, p repository.CreateArticleParams) (*domain.Article, error)
 	GetByID(ctx context.Context, id string) (*domain.Article, error)
 	ListByUser(ctx context.Context, p repository.ListArticlesParams) (*repository.ListArticlesResult, error)
+	ListPage(ctx context.Context, p repository.ListArticlesParams, after *repository.ArticleCursor, withTotal bool) (*repository.ArticlePage, error)
 	Update(ctx context.Context, id string, userID string, p repository.UpdateArticleParams) error
 	Delete(ctx context.Context, id string, userID string) error
-	Search(ctx context.Context, userID, query string, page, perPage int) (*repository.ListArticlesResult, error)
+	Search(ctx context.Context, userID string, q *domain.SearchQuery, page, perPage int) (*repository.ListArticlesResult, error)
 	ExistsByUserAndURL(ctx context.Context, userID, url string) (bool, error)
 	ExistsByUserAndClientID(ctx context.Context, userID, clientID string) (bool, error)
 }
@@ -47,3 +49,14 @@ type quotaChecker interface {
 type taskEnqueuer interface {
 	EnqueueContext(ctx context.Context, task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error)
 }
+
+// manualContentSubmitter is the subset of ArticleService used by services
+// that turn other inputs (mail, documents) into articles.
+type manualContentSubmitter interface {
+	SubmitManualContent(ctx context.Context, userID string, req SubmitManualContentRequest) (*SubmitURLResponse, error)
+}
+
+// objectUploader is the subset of R2Client used to store files.
+type objectUploader interface {
+	Upload(ctx context.Context, key string, body io.Reader, contentType string) (string, error)
+}
@@ -0,0 +1,426 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"regexp"
+	"strings"
+
+	"folio-server/internal/client"
+	"folio-server/internal/domain"
+	"folio-server/internal/inbound"
+	"folio-server/internal/repository"
+)
+
+// mailStore is the subset of MailRepo used by MailService.
+type mailStore interface {
+	GetInboxByUser(ctx context.Context, userID string) (*domain.MailInbox, error)
+	GetInboxByToken(ctx context.Context, token string) (*domain.MailInbox, error)
+	UpsertInbox(ctx context.Context, userID, token string) (*domain.MailInbox, error)
+	ListSenders(ctx context.Context, userID string) ([]domain.MailSender, error)
+	AddSender(ctx context.Context, userID, pattern string) (*domain.MailSender, error)
+	DeleteSender(ctx context.Context, id, userID string) (bool, error)
+}
+
+// MailService ingests forwarded newsletters sent to a user's private
+// address (token@domain) and saves them as SourceNewsletter articles.
+type MailService struct {
+	mailRepo mailStore
+	articles manualContentSubmitter
+	uploader objectUploader // nil when R2 is not configured
+	domain   string
+}
+
+func NewMailService(
+	mailRepo *repository.MailRepo,
+	articles *ArticleService,
+	r2Client *client.R2Client,
+	mailDomain string,
+) *MailService {
+	s := &MailService{
+		mailRepo: mailRepo,
+		articles: articles,
+		domain:   strings.ToLower(mailDomain),
+	}
+	if r2Client != nil {
+		s.uploader = r2Client
+	}
+	return s
+}
+
+// MailInboxInfo is the user-facing view of the ingest inbox.
+type MailInboxInfo struct {
+	Address string
+	Senders []domain.MailSender
+}
+
+// MailIngestResult lists the articles created from one message.
+type MailIngestResult struct {
+	Articles []SubmitURLResponse
+}
+
+// GetInbox returns the user's ingest address, creating it on first use.
+func (s *MailService) GetInbox(ctx context.Context, userID string) (*MailInboxInfo, error) {
+	inbox, err := s.mailRepo.GetInboxByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if inbox == nil {
+		if inbox, err = s.mailRepo.UpsertInbox(ctx, userID, newInboxToken()); err != nil {
+			return nil, err
+		}
+	}
+	senders, err := s.mailRepo.ListSenders(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &MailInboxInfo{Address: s.address(inbox.Token), Senders: senders}, nil
+}
+
+// RotateAddress replaces the ingest address; the old one stops working.
+func (s *MailService) RotateAddress(ctx context.Context, userID string) (*MailInboxInfo, error) {
+	if _, err := s.mailRepo.UpsertInbox(ctx, userID, newInboxToken()); err != nil {
+		return nil, err
+	}
+	return s.GetInbox(ctx, userID)
+}
+
+func (s *MailService) ListSenders(ctx context.Context, userID string) ([]domain.MailSender, error) {
+	return s.mailRepo.ListSenders(ctx, userID)
+}
+
+// AddSender adds an allow-list entry. Once the list is non-empty, mail from
+// other senders is rejected.
+func (s *MailService) AddSender(ctx context.Context, userID, pattern string) (*domain.MailSender, error) {
+	normalized, ok := normalizeSenderPattern(pattern)
+	if !ok {
+		return nil, ErrInvalidSenderPattern
+	}
+	return s.mailRepo.AddSender(ctx, userID, normalized)
+}
+
+func (s *MailService) RemoveSender(ctx context.Context, userID, senderID string) error {
+	deleted, err := s.mailRepo.DeleteSender(ctx, senderID, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Ingest parses a raw RFC 822 message and saves it for every recipient that
+// maps to an inbox. When rcpts is empty the recipients are taken from the
+// message headers. envelopeFrom may be empty.
+func (s *MailService) Ingest(ctx context.Context, envelopeFrom string, rcpts []string, raw []byte) (*MailIngestResult, error) {
+	msg, err := inbound.ParseMessage(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMailMalformed, err)
+	}
+	if len(rcpts) == 0 {
+		rcpts = msg.Recipients
+	}
+
+	clientID := mailClientID(msg.MessageID, raw)
+
+	result := &MailIngestResult{Articles: []SubmitURLResponse{}}
+	var firstErr error
+	seen := make(map[string]bool)
+	for _, rcpt := range rcpts {
+		inbox, err := s.lookupInbox(ctx, rcpt)
+		if err != nil {
+			return nil, err
+		}
+		if inbox == nil || seen[inbox.UserID] {
+			continue
+		}
+		seen[inbox.UserID] = true
+
+		resp, err := s.ingestForUser(ctx, inbox.UserID, envelopeFrom, clientID, msg)
+		if err != nil {
+			slog.Info("mail ingest rejected", "user_id", inbox.UserID, "message_id", msg.MessageID, "error", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if resp != nil {
+			result.Articles = append(result.Articles, *resp)
+		}
+	}
+
+	if len(seen) == 0 {
+		return nil, ErrMailRecipientUnknown
+	}
+	if len(result.Articles) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	return result, nil
+}
+
+func (s *MailService) ingestForUser(ctx context.Context, userID, envelopeFrom, clientID string, msg *inbound.Message) (*SubmitURLResponse, error) {
+	senders, err := s.mailRepo.ListSenders(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !senderAllowed(senders, envelopeFrom, msg.FromAddress, msg.ForwardedFrom) {
+		return nil, ErrMailSenderRejected
+	}
+
+	content, err := s.renderMessage(ctx, userID, clientID, msg)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(content) == "" {
+		return nil, ErrMailEmpty
+	}
+
+	title := msg.Subject
+	resp, err := s.articles.SubmitManualContent(ctx, userID, SubmitManualContentRequest{
+		Content:    content,
+		Title:      &title,
+		ClientID:   &clientID,
+		SourceType: string(domain.SourceNewsletter),
+	})
+	if errors.Is(err, ErrDuplicateURL) {
+		// Redelivery of a message we already saved.
+		slog.Debug("duplicate mail ignored", "user_id", userID, "client_id", clientID)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("mail ingested", "user_id", userID, "article_id", resp.ArticleID, "attachments", len(msg.Attachments))
+	return resp, nil
+}
+
+// renderMessage converts the message body to markdown. Inline images are
+// uploaded and linked; other attachments are listed at the end.
+func (s *MailService) renderMessage(ctx context.Context, userID, clientID string, msg *inbound.Message) (string, error) {
+	sum := sha256.Sum256([]byte(clientID))
+	keyPrefix := path.Join("mail", userID, hex.EncodeToString(sum[:8]))
+
+	uploaded := make(map[int]string)
+	upload := func(i int) string {
+		if u, ok := uploaded[i]; ok {
+			return u
+		}
+		u := s.uploadAttachment(ctx, keyPrefix, i, msg.Attachments[i])
+		uploaded[i] = u
+		return u
+	}
+
+	referenced := make(map[int]bool)
+	var body string
+	switch {
+	case msg.HTMLBody != "":
+		md, err := inbound.HTMLToMarkdown(msg.HTMLBody, func(cid string) string {
+			for i, a := range msg.Attachments {
+				if a.ContentID != "" && strings.EqualFold(a.ContentID, cid) {
+					referenced[i] = true
+					return upload(i)
+				}
+			}
+			return ""
+		})
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrMailMalformed, err)
+		}
+		body = md
+	case msg.TextBody != "":
+		body = strings.TrimSpace(msg.TextBody)
+	}
+
+	var list []string
+	for i, a := range msg.Attachments {
+		if referenced[i] {
+			continue
+		}
+		// A bare text/markdown attachment stands in for a missing body.
+		if body == "" && isTextAttachment(a) {
+			body = strings.TrimSpace(string(a.Data))
+			continue
+		}
+		name := attachmentName(i, a)
+		if u := upload(i); u != "" {
+			if strings.HasPrefix(a.ContentType, "image/") {
+				list = append(list, fmt.Sprintf("- ![%s](%s)", name, u))
+			} else {
+				list = append(list, fmt.Sprintf("- [%s](%s)", name, u))
+			}
+		} else {
+			list = append(list, "- "+name)
+		}
+	}
+
+	if len(list) > 0 {
+		body = strings.TrimSpace(body + "\n\n## Attachments\n\n" + strings.Join(list, "\n"))
+	}
+	return body, nil
+}
+
+func (s *MailService) uploadAttachment(ctx context.Context, keyPrefix string, i int, a inbound.Attachment) string {
+	if s.uploader == nil {
+		return ""
+	}
+	key := path.Join(keyPrefix, fmt.Sprintf("%d-%s", i, sanitizeFilename(attachmentName(i, a))))
+	u, err := s.uploader.Upload(ctx, key, bytes.NewReader(a.Data), a.ContentType)
+	if err != nil {
+		slog.Warn("mail attachment upload failed", "key", key, "error", err)
+		return ""
+	}
+	return u
+}
+
+// AcceptRecipient implements inbound.Deliverer.
+func (s *MailService) AcceptRecipient(ctx context.Context, addr string) bool {
+	inbox, err := s.lookupInbox(ctx, addr)
+	if err != nil {
+		slog.Error("mail recipient lookup failed", "rcpt", addr, "error", err)
+		// Accept and let delivery produce a temporary failure instead of a bounce.
+		return true
+	}
+	return inbox != nil
+}
+
+// Deliver implements inbound.Deliverer, mapping permanent failures to
+// *inbound.RejectError so the SMTP client gets a 5xx instead of retrying.
+func (s *MailService) Deliver(ctx context.Context, from string, to []string, raw []byte) error {
+	_, err := s.Ingest(ctx, from, to, raw)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrMailRecipientUnknown),
+		errors.Is(err, ErrMailSenderRejected),
+		errors.Is(err, ErrMailMalformed),
+		errors.Is(err, ErrMailEmpty),
+		errors.Is(err, ErrQuotaExceeded):
+		return &inbound.RejectError{Reason: err.Error()}
+	default:
+		return err
+	}
+}
+
+// mailClientID returns the key that makes redelivered mail idempotent: a
+// hash of the Message-ID, or of the raw message when it has none. Message-IDs
+// are routinely longer than articles.client_id, so they are never stored as is.
+func mailClientID(messageID string, raw []byte) string {
+	key := raw
+	if messageID != "" {
+		key = []byte(messageID)
+	}
+	sum := sha256.Sum256(key)
+	return "mail:" + hex.EncodeToString(sum[:15]) // 35 characters
+}
+
+func (s *MailService) lookupInbox(ctx context.Context, addr string) (*domain.MailInbox, error) {
+	token, ok := inboxToken(addr, s.domain)
+	if !ok {
+		return nil, nil
+	}
+	return s.mailRepo.GetInboxByToken(ctx, token)
+}
+
+func (s *MailService) address(token string) string {
+	return token + "@" + s.domain
+}
+
+// --- helpers ---
+
+var inboxTokenEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newInboxToken returns 16 lowercase base32 characters (80 bits).
+func newInboxToken() string {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("crypto/rand failed: %v", err))
+	}
+	return strings.ToLower(inboxTokenEncoding.EncodeToString(b))
+}
+
+// inboxToken extracts the token from "token+tag@domain". The domain must
+// match mailDomain (case-insensitive).
+func inboxToken(addr, mailDomain string) (string, bool) {
+	addr = strings.ToLower(strings.Trim(strings.TrimSpace(addr), "<>"))
+	local, host, ok := strings.Cut(addr, "@")
+	if !ok || local == "" || host != mailDomain {
+		return "", false
+	}
+	local, _, _ = strings.Cut(local, "+")
+	return local, local != ""
+}
+
+var reSenderDomain = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)+$`)
+
+// normalizeSenderPattern accepts "user@example.com", "@example.com" or
+// "example.com" and returns the stored form (address or "@domain").
+func normalizeSenderPattern(pattern string) (string, bool) {
+	p := strings.ToLower(strings.TrimSpace(pattern))
+	local, host, hasAt := strings.Cut(p, "@")
+	if !hasAt {
+		host, local = p, ""
+	}
+	if !reSenderDomain.MatchString(host) {
+		return "", false
+	}
+	if local == "" {
+		return "@" + host, true
+	}
+	if strings.ContainsAny(local, " <>,") {
+		return "", false
+	}
+	return local + "@" + host, true
+}
+
+// senderAllowed reports whether any candidate address matches the
+// allow-list. An empty allow-list accepts everyone.
+func senderAllowed(senders []domain.MailSender, candidates ...string) bool {
+	if len(senders) == 0 {
+		return true
+	}
+	for _, c := range candidates {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if c == "" {
+			continue
+		}
+		_, host, _ := strings.Cut(c, "@")
+		for _, s := range senders {
+			if s.Pattern == c || s.Pattern == "@"+host {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isTextAttachment(a inbound.Attachment) bool {
+	ext := strings.ToLower(path.Ext(a.Filename))
+	return a.ContentType == "text/plain" || a.ContentType == "text/markdown" || ext == ".md" || ext == ".txt"
+}
+
+func attachmentName(i int, a inbound.Attachment) string {
+	if a.Filename != "" {
+		return a.Filename
+	}
+	return fmt.Sprintf("attachment-%d", i+1)
+}
+
+var reUnsafeFilename = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func sanitizeFilename(name string) string {
+	name = reUnsafeFilename.ReplaceAllString(path.Base(name), "_")
+	if len(name) > 100 {
+		name = name[len(name)-100:]
+	}
+	return name
+}
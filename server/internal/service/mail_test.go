@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"folio-server/internal/domain"
+	"folio-server/internal/inbound"
+	"folio-server/internal/repository"
+)
+
+type mockUploader struct {
+	keys []string
+}
+
+func (m *mockUploader) Upload(_ context.Context, key string, _ io.Reader, _ string) (string, error) {
+	m.keys = append(m.keys, key)
+	return "https://cdn.example.com/" + key, nil
+}
+
+func TestInboxToken(t *testing.T) {
+	tests := []struct {
+		addr  string
+		token string
+		ok    bool
+	}{
+		{"abc123@in.example.com", "abc123", true},
+		{"<ABC123+news@In.Example.com>", "abc123", true},
+		{"abc123@other.com", "", false},
+		{"+tag@in.example.com", "", false},
+		{"not-an-address", "", false},
+	}
+	for _, tt := range tests {
+		token, ok := inboxToken(tt.addr, "in.example.com")
+		if token != tt.token || ok != tt.ok {
+			t.Errorf("inboxToken(%q) = %q, %v; want %q, %v", tt.addr, token, ok, tt.token, tt.ok)
+		}
+	}
+}
+
+func TestNormalizeSenderPattern(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"News@Example.com", "news@example.com", true},
+		{"@substack.com", "@substack.com", true},
+		{"substack.com", "@substack.com", true},
+		{"bad pattern", "", false},
+		{"user@localhost", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := normalizeSenderPattern(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("normalizeSenderPattern(%q) = %q, %v; want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSenderAllowed(t *testing.T) {
+	if !senderAllowed(nil, "anyone@example.com") {
+		t.Error("empty allow-list should accept all senders")
+	}
+	senders := []domain.MailSender{{Pattern: "@substack.com"}, {Pattern: "me@example.com"}}
+	if !senderAllowed(senders, "", "Writer@Substack.com") {
+		t.Error("expected domain pattern to match")
+	}
+	if !senderAllowed(senders, "me@example.com") {
+		t.Error("expected exact address to match")
+	}
+	if senderAllowed(senders, "spam@example.com", "") {
+		t.Error("expected unlisted sender to be rejected")
+	}
+}
+
+func TestMailRenderMessage_UploadsAttachments(t *testing.T) {
+	up := &mockUploader{}
+	s := &MailService{uploader: up, domain: "in.example.com"}
+	msg := &inbound.Message{
+		HTMLBody: `<p>Hello</p><img src="cid:hero">`,
+		Attachments: []inbound.Attachment{
+			{Filename: "hero.png", ContentType: "image/png", ContentID: "hero", Data: []byte("png")},
+			{Filename: "my report.pdf", ContentType: "application/pdf", Data: []byte("pdf")},
+		},
+	}
+
+	got, err := s.renderMessage(context.Background(), "user-1", "mail:msg-1", msg)
+	if err != nil {
+		t.Fatalf("renderMessage: %v", err)
+	}
+	if len(up.keys) != 2 {
+		t.Fatalf("uploaded %d attachments, want 2", len(up.keys))
+	}
+	for _, key := range up.keys {
+		if !strings.HasPrefix(key, "mail/user-1/") {
+			t.Errorf("key %q not under the user's mail prefix", key)
+		}
+	}
+	if !strings.Contains(got, "![](https://cdn.example.com/"+up.keys[0]+")") {
+		t.Errorf("inline image not rewritten:\n%s", got)
+	}
+	if !strings.Contains(got, "## Attachments\n\n- [my report.pdf](https://cdn.example.com/"+up.keys[1]+")") {
+		t.Errorf("attachment not listed:\n%s", got)
+	}
+}
+
+func TestMailRenderMessage_TextAttachmentWithoutBody(t *testing.T) {
+	s := &MailService{domain: "in.example.com"}
+	msg := &inbound.Message{
+		Attachments: []inbound.Attachment{
+			{Filename: "notes.md", ContentType: "application/octet-stream", Data: []byte("# Notes\n")},
+			{Filename: "data.csv", ContentType: "text/csv", Data: []byte("a,b")},
+		},
+	}
+
+	got, err := s.renderMessage(context.Background(), "user-1", "mail:msg-2", msg)
+	if err != nil {
+		t.Fatalf("renderMessage: %v", err)
+	}
+	if !strings.HasPrefix(got, "# Notes") {
+		t.Errorf("expected markdown attachment to become the body:\n%s", got)
+	}
+	if !strings.Contains(got, "- data.csv") {
+		t.Errorf("expected attachment name without R2:\n%s", got)
+	}
+}
+
+type mockMailStore struct {
+	inbox *domain.MailInbox
+}
+
+func (m *mockMailStore) GetInboxByUser(_ context.Context, _ string) (*domain.MailInbox, error) {
+	return m.inbox, nil
+}
+
+func (m *mockMailStore) GetInboxByToken(_ context.Context, token string) (*domain.MailInbox, error) {
+	if m.inbox == nil || m.inbox.Token != token {
+		return nil, nil
+	}
+	return m.inbox, nil
+}
+
+func (m *mockMailStore) UpsertInbox(_ context.Context, userID, token string) (*domain.MailInbox, error) {
+	m.inbox = &domain.MailInbox{UserID: userID, Token: token}
+	return m.inbox, nil
+}
+
+func (m *mockMailStore) ListSenders(_ context.Context, _ string) ([]domain.MailSender, error) {
+	return nil, nil
+}
+
+func (m *mockMailStore) AddSender(_ context.Context, _, _ string) (*domain.MailSender, error) {
+	return nil, nil
+}
+
+func (m *mockMailStore) DeleteSender(_ context.Context, _, _ string) (bool, error) {
+	return false, nil
+}
+
+func TestMailService_Ingest_ClientIDFitsColumn(t *testing.T) {
+	// The article repository rejects client IDs longer than the column, as
+	// Postgres does with "value too long".
+	repo := &mockArticleRepo{createFn: func(_ context.Context, p repository.CreateArticleParams) (*domain.Article, error) {
+		if p.ClientID != nil && len(*p.ClientID) > repository.MaxClientIDLength {
+			return nil, fmt.Errorf("value too long for type character varying(%d)", repository.MaxClientIDLength)
+		}
+		return &domain.Article{ID: "article-1", UserID: p.UserID}, nil
+	}}
+	articles := newTestArticleService(repo, &mockTaskRepo{}, &mockTagRepo{}, &mockCategoryRepo{}, &mockQuotaService{}, &mockEnqueuer{})
+	s := &MailService{
+		mailRepo: &mockMailStore{inbox: &domain.MailInbox{UserID: "user-1", Token: "abc123"}},
+		articles: articles,
+		domain:   "in.example.com",
+	}
+
+	for _, messageID := range []string{
+		"<CAH+8ZqT1vN3xQ7s9pLk2Wm0yR5eJ4dGh6uBfCn=Xo_aVt-PzKw@mail.gmail.com>",
+		"<0100018f3c2a7b1e-5d4c3b2a-1f0e-4d9c-8b7a-6f5e4d3c2b1a-000000@email.amazonses.com>",
+		"",
+	} {
+		raw := "From: Weekly <news@example.com>\r\n" +
+			"To: abc123@in.example.com\r\n" +
+			"Subject: This week\r\n"
+		if messageID != "" {
+			raw += "Message-ID: " + messageID + "\r\n"
+		}
+		raw += "Content-Type: text/plain; charset=utf-8\r\n\r\nHello from the newsletter.\r\n"
+
+		result, err := s.Ingest(context.Background(), "news@example.com", nil, []byte(raw))
+		if err != nil {
+			t.Fatalf("Ingest(%q): %v", messageID, err)
+		}
+		if len(result.Articles) != 1 {
+			t.Errorf("Ingest(%q) created %d articles, want 1", messageID, len(result.Articles))
+		}
+		if id := *repo.lastCreateP.ClientID; !strings.HasPrefix(id, "mail:") {
+			t.Errorf("client_id = %q", id)
+		}
+	}
+
+	if a, b := mailClientID("<a@example.com>", nil), mailClientID("<b@example.com>", nil); a == b {
+		t.Error("different Message-IDs share a client_id")
+	}
+	if a, b := mailClientID("", []byte("one")), mailClientID("", []byte("two")); a == b {
+		t.Error("different messages without a Message-ID share a client_id")
+	}
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"folio-server/internal/client"
+	"folio-server/internal/domain"
+	"folio-server/internal/pdf"
+	"folio-server/internal/repository"
+)
+
+const (
+	// MaxPDFUploadBytes is the largest PDF accepted by the upload endpoint.
+	MaxPDFUploadBytes = 50 << 20 // 50 MB
+	// maxPDFContentBytes caps the extracted markdown stored on the article.
+	maxPDFContentBytes = 2 << 20 // 2 MB
+	// pdfExtractTimeout bounds text extraction, on top of the pdf package's
+	// own work budget, so a crafted file cannot hold a request for long.
+	pdfExtractTimeout = 20 * time.Second
+)
+
+// fileURLSetter is the subset of ArticleRepo used to link the stored original.
+type fileURLSetter interface {
+	SetFileURL(ctx context.Context, id string, fileURL string) error
+}
+
+// PDFService turns uploaded PDF documents into articles: the text is
+// extracted with page markers and analyzed like manual content, and the
+// original file is kept in R2 for the reader.
+type PDFService struct {
+	articles    manualContentSubmitter
+	articleRepo fileURLSetter
+	uploader    objectUploader // nil when R2 is not configured
+}
+
+func NewPDFService(
+	articleService *ArticleService,
+	articleRepo *repository.ArticleRepo,
+	r2Client *client.R2Client,
+) *PDFService {
+	s := &PDFService{
+		articles:    articleService,
+		articleRepo: articleRepo,
+	}
+	if r2Client != nil {
+		s.uploader = r2Client
+	}
+	return s
+}
+
+type UploadPDFRequest struct {
+	Data     []byte
+	Filename string
+	Title    *string
+	TagIDs   []string
+}
+
+// Upload extracts the PDF's text and submits it as a SourcePDF article.
+// Uploading the same file twice returns ErrDuplicateURL.
+func (s *PDFService) Upload(ctx context.Context, userID string, req UploadPDFRequest) (*SubmitURLResponse, error) {
+	if len(req.Data) > MaxPDFUploadBytes {
+		return nil, ErrPDFTooLarge
+	}
+	extractCtx, cancel := context.WithTimeout(ctx, pdfExtractTimeout)
+	doc, err := pdf.ExtractContext(extractCtx, req.Data)
+	cancel()
+	switch {
+	case err != nil && ctx.Err() != nil:
+		return nil, ctx.Err()
+	case errors.Is(err, pdf.ErrEncrypted):
+		return nil, ErrPDFEncrypted
+	case errors.Is(err, pdf.ErrTooComplex), errors.Is(err, context.DeadlineExceeded):
+		slog.Warn("pdf extraction gave up", "user_id", userID, "filename", req.Filename, "error", err)
+		return nil, ErrPDFTooComplex
+	case err != nil:
+		slog.Info("pdf extraction failed", "user_id", userID, "filename", req.Filename, "error", err)
+		return nil, ErrInvalidPDF
+	case !doc.HasText():
+		return nil, ErrPDFNoText
+	}
+
+	content := truncateMarkdown(doc.Markdown(), maxPDFContentBytes)
+	title := pdfTitle(req.Title, doc.Title, req.Filename)
+	sum := sha256.Sum256(req.Data)
+	clientID := "pdf:" + hex.EncodeToString(sum[:16])
+
+	submitReq := SubmitManualContentRequest{
+		Content:    content,
+		Title:      &title,
+		TagIDs:     req.TagIDs,
+		ClientID:   &clientID,
+		SourceType: string(domain.SourcePDF),
+	}
+	if doc.Author != "" {
+		submitReq.Author = &doc.Author
+	}
+	resp, err := s.articles.SubmitManualContent(ctx, userID, submitReq)
+	if err != nil {
+		return nil, err
+	}
+
+	// The text is already saved; a failed upload only loses the original.
+	if s.uploader != nil {
+		key := path.Join("pdf", userID, resp.ArticleID+".pdf")
+		fileURL, err := s.uploader.Upload(ctx, key, bytes.NewReader(req.Data), "application/pdf")
+		if err != nil {
+			slog.Error("failed to store pdf original", "article_id", resp.ArticleID, "error", err)
+		} else if err := s.articleRepo.SetFileURL(ctx, resp.ArticleID, fileURL); err != nil {
+			slog.Error("failed to record pdf file url", "article_id", resp.ArticleID, "error", err)
+		}
+	}
+
+	slog.Info("pdf uploaded",
+		"article_id", resp.ArticleID,
+		"pages", len(doc.Pages),
+		"bytes", len(req.Data),
+	)
+	return resp, nil
+}
+
+// pdfTitle prefers the caller's title, then the document's own metadata,
+// then the file name.
+func pdfTitle(requested *string, metadata, filename string) string {
+	if requested != nil && strings.TrimSpace(*requested) != "" {
+		return strings.TrimSpace(*requested)
+	}
+	if metadata != "" && !strings.EqualFold(metadata, "untitled") && !strings.HasPrefix(metadata, "Microsoft Word - ") {
+		return metadata
+	}
+	name := strings.TrimSuffix(path.Base(filename), path.Ext(filename))
+	if name == "" || name == "." || name == "/" {
+		return "Untitled PDF"
+	}
+	return name
+}
+
+// truncateMarkdown cuts s to at most maxBytes on a UTF-8 boundary.
+func truncateMarkdown(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+	s = s[:maxBytes]
+	for len(s) > 0 && !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s + "\n\n*(content truncated)*"
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+type mockManualSubmitter struct {
+	req SubmitManualContentRequest
+	err error
+}
+
+func (m *mockManualSubmitter) SubmitManualContent(_ context.Context, _ string, req SubmitManualContentRequest) (*SubmitURLResponse, error) {
+	m.req = req
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &SubmitURLResponse{ArticleID: "art-1", TaskID: "task-1"}, nil
+}
+
+type mockFileURLSetter struct {
+	urls map[string]string
+}
+
+func (m *mockFileURLSetter) SetFileURL(_ context.Context, id string, fileURL string) error {
+	m.urls[id] = fileURL
+	return nil
+}
+
+// minimalPDF returns a single-page PDF whose page shows text.
+func minimalPDF(text string) []byte {
+	content := fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+	return []byte(fmt.Sprintf(`%%PDF-1.4
+1 0 obj
+<< /Type /Catalog /Pages 2 0 R >>
+endobj
+2 0 obj
+<< /Type /Pages /Kids [3 0 R] /Count 1 >>
+endobj
+3 0 obj
+<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>
+endobj
+4 0 obj
+<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>
+endobj
+5 0 obj
+<< /Length %d >>
+stream
+%s
+endstream
+endobj
+trailer
+<< /Size 6 /Root 1 0 R >>
+%%%%EOF
+`, len(content), content))
+}
+
+func TestPDFService_Upload(t *testing.T) {
+	submitter := &mockManualSubmitter{}
+	setter := &mockFileURLSetter{urls: map[string]string{}}
+	up := &mockUploader{}
+	s := &PDFService{articles: submitter, articleRepo: setter, uploader: up}
+
+	resp, err := s.Upload(context.Background(), "user-1", UploadPDFRequest{
+		Data:     minimalPDF("Attention is all you need"),
+		Filename: "attention.pdf",
+		TagIDs:   []string{"tag-1"},
+	})
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if resp.ArticleID != "art-1" {
+		t.Errorf("ArticleID = %q", resp.ArticleID)
+	}
+
+	req := submitter.req
+	if req.SourceType != "pdf" {
+		t.Errorf("SourceType = %q, want pdf", req.SourceType)
+	}
+	if req.Title == nil || *req.Title != "attention" {
+		t.Errorf("Title = %v, want attention", req.Title)
+	}
+	if want := "<!-- page 1 -->\n\nAttention is all you need"; req.Content != want {
+		t.Errorf("Content = %q, want %q", req.Content, want)
+	}
+	if req.ClientID == nil || !strings.HasPrefix(*req.ClientID, "pdf:") {
+		t.Errorf("ClientID = %v, want pdf: prefix", req.ClientID)
+	}
+
+	if len(up.keys) != 1 || up.keys[0] != "pdf/user-1/art-1.pdf" {
+		t.Errorf("uploaded keys = %v", up.keys)
+	}
+	if setter.urls["art-1"] != "https://cdn.example.com/pdf/user-1/art-1.pdf" {
+		t.Errorf("file url = %q", setter.urls["art-1"])
+	}
+}
+
+func TestPDFService_Upload_Errors(t *testing.T) {
+	s := &PDFService{articles: &mockManualSubmitter{}}
+
+	if _, err := s.Upload(context.Background(), "user-1", UploadPDFRequest{Data: []byte("%PDF-1.4 garbage")}); !errors.Is(err, ErrInvalidPDF) {
+		t.Errorf("garbage: expected ErrInvalidPDF, got %v", err)
+	}
+	if _, err := s.Upload(context.Background(), "user-1", UploadPDFRequest{Data: minimalPDF("   ")}); !errors.Is(err, ErrPDFNoText) {
+		t.Errorf("blank page: expected ErrPDFNoText, got %v", err)
+	}
+
+	// Quota and duplicate errors from the article service pass through unchanged.
+	s.articles = &mockManualSubmitter{err: ErrQuotaExceeded}
+	if _, err := s.Upload(context.Background(), "user-1", UploadPDFRequest{Data: minimalPDF("text")}); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("expected ErrQuotaExceeded, got %v", err)
+	}
+}
+
+func TestPDFTitle(t *testing.T) {
+	str := func(s string) *string { return &s }
+	tests := []struct {
+		requested *string
+		metadata  string
+		filename  string
+		want      string
+	}{
+		{str("  Mine "), "Meta", "file.pdf", "Mine"},
+		{nil, "Meta", "file.pdf", "Meta"},
+		{str(""), "untitled", "dir/paper.v2.pdf", "paper.v2"},
+		{nil, "Microsoft Word - draft.docx", "draft.pdf", "draft"},
+		{nil, "", "", "Untitled PDF"},
+	}
+	for _, tt := range tests {
+		if got := pdfTitle(tt.requested, tt.metadata, tt.filename); got != tt.want {
+			t.Errorf("pdfTitle(%v, %q, %q) = %q, want %q", tt.requested, tt.metadata, tt.filename, got, tt.want)
+		}
+	}
+}
+
+func TestTruncateMarkdown(t *testing.T) {
+	if got := truncateMarkdown("short", 10); got != "short" {
+		t.Errorf("got %q", got)
+	}
+	got := truncateMarkdown("中文内容", 7) // cut falls inside the third rune
+	if !utf8.ValidString(got) || !strings.HasPrefix(got, "中文\n\n") {
+		t.Errorf("got %q", got)
+	}
+}
@@ -8,13 +8,17 @@ import (
 )
 
 type QuotaService struct {
-	userRepo *repository.UserRepo
+	userRepo   *repository.UserRepo
+	importRepo *repository.ImportRepo
 }
 
-func NewQuotaService(userRepo *repository.UserRepo) *QuotaService {
-	return &QuotaService{userRepo: userRepo}
+func NewQuotaService(userRepo *repository.UserRepo, importRepo *repository.ImportRepo) *QuotaService {
+	return &QuotaService{userRepo: userRepo, importRepo: importRepo}
 }
 
+// CheckAndIncrement consumes one unit of the regular monthly quota for an
+// article the user saves directly. Articles created by an import job are
+// not charged here; see ImportAIBudget.
 func (s *QuotaService) CheckAndIncrement(ctx context.Context, userID string) error {
 	newCount, err := s.userRepo.AtomicResetAndIncrement(ctx, userID)
 	if err != nil {
@@ -33,3 +37,23 @@ func (s *QuotaService) CheckAndIncrement(ctx context.Context, userID string) err
 func (s *QuotaService) DecrementQuota(ctx context.Context, userID string) error {
 	return s.userRepo.DecrementMonthCount(ctx, userID)
 }
+
+// ImportAIBudget returns how many more imported articles may be sent to AI
+// processing this month. Imports never use the regular save quota and are
+// never rejected: each month up to monthly_quota imported articles get AI
+// processing, counted from the import items already sent to it, and the rest
+// are imported and crawled without AI.
+func (s *QuotaService) ImportAIBudget(ctx context.Context, userID string) (int, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if user == nil {
+		return 0, ErrNotFound
+	}
+	used, err := s.importRepo.CountAIItemsThisMonth(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	return max(user.MonthlyQuota-used, 0), nil
+}
@@ -5,12 +5,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"slices"
 	"strings"
 	"time"
 
 	"folio-server/internal/client"
 	"folio-server/internal/domain"
 	"folio-server/internal/repository"
+	"folio-server/internal/worker"
 )
 
 const (
@@ -20,51 +22,180 @@ const (
 	ragArticleFallbackCap = 500
 	ragSearchFallbackSize = 50
 	ragHistoryLimit       = 10
+	ragPassageLimit       = 8
 )
 
+// ragVectorSearcher finds a user's articles and passages nearest to a query
+// embedding.
+type ragVectorSearcher interface {
+	SearchSummaries(ctx context.Context, userID, model string, vector []float32, limit int, excludeID string, scope *domain.RAGScope) ([]domain.RAGSource, error)
+	SearchPassages(ctx context.Context, userID, model string, vector []float32, limit int, scope *domain.RAGScope) ([]domain.RAGSource, error)
+}
+
 // RAGService orchestrates question-answering over a user's saved articles.
 type RAGService struct {
 	ragRepo  *repository.RAGRepo
 	userRepo *repository.UserRepo
 	aiClient client.Analyzer
+	embedder client.Embedder
+	vectors  ragVectorSearcher
+	enqueuer taskEnqueuer
 }
 
 // NewRAGService creates a new RAGService.
-func NewRAGService(ragRepo *repository.RAGRepo, userRepo *repository.UserRepo, aiClient client.Analyzer) *RAGService {
+func NewRAGService(ragRepo *repository.RAGRepo, userRepo *repository.UserRepo, aiClient client.Analyzer, embedder client.Embedder, vectors ragVectorSearcher, enqueuer taskEnqueuer) *RAGService {
 	return &RAGService{
 		ragRepo:  ragRepo,
 		userRepo: userRepo,
 		aiClient: aiClient,
+		embedder: embedder,
+		vectors:  vectors,
+		enqueuer: enqueuer,
 	}
 }
 
-// Query answers a user question using their saved article summaries as context.
-func (s *RAGService) Query(ctx context.Context, userID, question, conversationID string) (*domain.RAGResponse, error) {
-	// 1. Quota check
-	if err := s.checkQuota(ctx, userID); err != nil {
+// ragPrompt is a question ready to send to the LLM, with the articles and
+// passages it was built from.
+type ragPrompt struct {
+	question     string
+	articles     []domain.RAGSource
+	passages     []domain.RAGSource
+	systemPrompt string
+	userPrompt   string
+}
+
+// sources lists what the prompt numbers for citation: the articles, then the
+// passages, so that citation n is sources()[n-1].
+func (p *ragPrompt) sources() []domain.RAGSource {
+	return append(slices.Clip(p.articles), p.passages...)
+}
+
+// RAGStreamEvent is one piece of a streamed answer: either answer text, or
+// a source the answer has just cited with the marker number used for it.
+type RAGStreamEvent struct {
+	Text     string
+	Citation *domain.RAGSource
+	Index    int // 1-based citation marker, set with Citation
+}
+
+// Query answers a user question using their saved article summaries as
+// context. A non-empty scope limits which articles are drawn on.
+func (s *RAGService) Query(ctx context.Context, userID, question, conversationID string, scope *domain.RAGScope) (*domain.RAGResponse, error) {
+	prompt, resp, err := s.prepare(ctx, userID, question, conversationID, scope)
+	if prompt == nil {
+		return resp, err
+	}
+
+	// 7. Call AI.
+	ragResult, err := s.aiClient.GenerateRAGAnswer(ctx, prompt.systemPrompt, prompt.userPrompt)
+	if err != nil {
+		slog.Error("rag ai call failed", "user_id", userID, "error", err)
+		return ragFailedResponse(), nil
+	}
+
+	return s.finish(ctx, userID, conversationID, prompt, ragResult), nil
+}
+
+// QueryStream is Query with the answer passed to emit as it is generated,
+// followed by a citation event the first time each source is cited. The
+// returned response carries the complete answer, sources and follow-ups.
+// Errors from emit abort the query.
+func (s *RAGService) QueryStream(ctx context.Context, userID, question, conversationID string, scope *domain.RAGScope, emit func(RAGStreamEvent) error) (*domain.RAGResponse, error) {
+	prompt, resp, err := s.prepare(ctx, userID, question, conversationID, scope)
+	if prompt == nil {
+		if err == nil {
+			err = emit(RAGStreamEvent{Text: resp.Answer})
+		}
+		return resp, err
+	}
+
+	var citations citationScanner
+	sources := prompt.sources()
+	cited := make([]int, 0)
+	emitCitations := func(indices []int) error {
+		for _, idx := range indices {
+			if slices.Contains(cited, idx) || idx < 1 || idx > len(sources) {
+				continue
+			}
+			cited = append(cited, idx)
+			if err := emit(RAGStreamEvent{Citation: &sources[idx-1], Index: idx}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	streamed := false
+	ragResult, err := s.aiClient.StreamRAGAnswer(ctx, prompt.systemPrompt, prompt.userPrompt, func(text string) error {
+		streamed = true
+		if err := emit(RAGStreamEvent{Text: text}); err != nil {
+			return err
+		}
+		return emitCitations(citations.Write(text))
+	})
+	if err != nil {
+		if streamed || ctx.Err() != nil {
+			return nil, fmt.Errorf("stream rag answer: %w", err)
+		}
+		slog.Error("rag ai stream failed", "user_id", userID, "error", err)
+		resp := ragFailedResponse()
+		return resp, emit(RAGStreamEvent{Text: resp.Answer})
+	}
+	if err := emitCitations(citations.Flush()); err != nil {
 		return nil, err
 	}
 
+	// Markers in the text stand in for a missing cited_indices list.
+	if len(ragResult.CitedIndices) == 0 {
+		ragResult.CitedIndices = cited
+	}
+	return s.finish(ctx, userID, conversationID, prompt, ragResult), nil
+}
+
+// prepare runs the steps Query and QueryStream share before the AI call.
+// When there is nothing to ask the AI, it returns a nil prompt and the
+// response to give instead.
+func (s *RAGService) prepare(ctx context.Context, userID, question, conversationID string, scope *domain.RAGScope) (*ragPrompt, *domain.RAGResponse, error) {
+	// 1. Quota check, and that a continued conversation is the user's.
+	if err := s.checkQuota(ctx, userID); err != nil {
+		return nil, nil, err
+	}
+	if conversationID != "" {
+		if _, err := s.getConversation(ctx, userID, conversationID); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	// 2. Load articles
-	articles, err := s.ragRepo.LoadArticleSummaries(ctx, userID)
+	articles, err := s.ragRepo.LoadArticleSummaries(ctx, userID, scope)
 	if err != nil {
-		return nil, fmt.Errorf("load articles: %w", err)
+		return nil, nil, fmt.Errorf("load articles: %w", err)
+	}
+	if len(articles) == 0 && !scope.IsEmpty() {
+		return nil, &domain.RAGResponse{
+			Answer:      "所选范围内没有可用的文章，换个范围再试试吧。",
+			Sources:     nil,
+			SourceCount: 0,
+		}, nil
 	}
 	if len(articles) == 0 {
-		return &domain.RAGResponse{
+		return nil, &domain.RAGResponse{
 			Answer:      "先收藏一些文章再来提问吧。",
 			Sources:     nil,
 			SourceCount: 0,
 		}, nil
 	}
 
-	// 3. Token budget — decide which articles to include in the prompt.
-	articles = s.applyTokenBudget(ctx, userID, question, articles)
+	// 3. Token budget — decide which articles to include in the prompt, and
+	// recall the passages closest to the question.
+	vector := s.embedQuestion(ctx, question)
+	articles = s.applyTokenBudget(ctx, userID, question, vector, articles, scope)
+	passages := s.passageRecall(ctx, userID, vector, scope)
 
 	// 4. Load conversation history (if continuing a conversation).
 	var history []domain.RAGMessage
 	if conversationID != "" {
-		history, err = s.ragRepo.GetConversationMessages(ctx, conversationID, ragHistoryLimit)
+		history, err = s.ragRepo.GetConversationMessages(ctx, userID, conversationID, ragHistoryLimit)
 		if err != nil {
 			slog.Warn("failed to load conversation history", "conversation_id", conversationID, "error", err)
 			history = nil
@@ -75,25 +206,22 @@ func (s *RAGService) Query(ctx context.Context, userID, question, conversationID
 	question = strings.TrimSpace(client.SanitizeField(question))
 
 	// 6. Build prompts.
-	systemPrompt := buildRAGSystemPrompt()
-	userPrompt := buildRAGUserPrompt(articles, history, question)
-
-	// 7. Call AI.
-	ragResult, err := s.aiClient.GenerateRAGAnswer(ctx, systemPrompt, userPrompt)
-	if err != nil {
-		slog.Error("rag ai call failed", "user_id", userID, "error", err)
-		return &domain.RAGResponse{
-			Answer:      "抱歉，回答生成失败，请重试。",
-			Sources:     nil,
-			SourceCount: 0,
-		}, nil
-	}
+	return &ragPrompt{
+		question:     question,
+		articles:     articles,
+		passages:     passages,
+		systemPrompt: buildRAGSystemPrompt(),
+		userPrompt:   buildRAGUserPrompt(articles, passages, history, question),
+	}, nil, nil
+}
 
-	// 8. Map cited_indices to actual article sources.
-	sources := mapCitedSources(ragResult.CitedIndices, articles)
+// finish runs the steps Query and QueryStream share after a successful AI call.
+func (s *RAGService) finish(ctx context.Context, userID, conversationID string, prompt *ragPrompt, ragResult *client.RAGResult) *domain.RAGResponse {
+	// 8. Map cited_indices to actual article and passage sources.
+	sources := mapCitedSources(ragResult.CitedIndices, prompt.sources())
 
 	// 9. Save conversation.
-	conversationID, err = s.saveConversation(ctx, userID, conversationID, question, ragResult, sources)
+	conversationID, err := s.saveConversation(ctx, userID, conversationID, prompt.question, ragResult, sources)
 	if err != nil {
 		slog.Error("failed to save rag conversation", "user_id", userID, "error", err)
 		// Non-fatal: still return the answer.
@@ -107,10 +235,19 @@ func (s *RAGService) Query(ctx context.Context, userID, question, conversationID
 	return &domain.RAGResponse{
 		Answer:              ragResult.Answer,
 		Sources:             sources,
-		SourceCount:         len(articles),
+		SourceCount:         len(prompt.articles),
 		FollowupSuggestions: ragResult.FollowupSuggestions,
 		ConversationID:      conversationID,
-	}, nil
+	}
+}
+
+// ragFailedResponse is returned in place of an answer when the AI call fails.
+func ragFailedResponse() *domain.RAGResponse {
+	return &domain.RAGResponse{
+		Answer:      "抱歉，回答生成失败，请重试。",
+		Sources:     nil,
+		SourceCount: 0,
+	}
 }
 
 // checkQuota verifies the user hasn't exceeded their monthly RAG quota.
@@ -151,21 +288,27 @@ func (s *RAGService) checkQuota(ctx context.Context, userID string) error {
 }
 
 // applyTokenBudget selects articles that fit within the token budget.
-// If the user has >500 articles, use smart retrieval (LLM query expansion → broad recall).
-// Otherwise, use the original token budget logic.
-func (s *RAGService) applyTokenBudget(ctx context.Context, userID, question string, articles []domain.RAGSource) []domain.RAGSource {
+// If the user has >500 articles, use smart retrieval: a hybrid of embedding kNN on the
+// question and LLM query expansion → broad keyword recall.
+// Otherwise, use the original token budget logic. Retrieval stays within
+// scope, as articles already do.
+func (s *RAGService) applyTokenBudget(ctx context.Context, userID, question string, vector []float32, articles []domain.RAGSource, scope *domain.RAGScope) []domain.RAGSource {
 	if len(articles) > ragArticleFallbackCap {
-		// Smart retrieval: LLM query expansion → multi-keyword broad recall
+		byVector := s.vectorRecall(ctx, userID, vector, scope)
+
+		var byKeyword []domain.RAGSource
 		keywords, err := s.aiClient.ExpandQuery(ctx, question)
 		if err != nil {
-			slog.Warn("query expansion failed, falling back to pg_trgm", "error", err)
-			return s.fallbackSearch(ctx, userID, question, articles)
+			slog.Warn("query expansion failed", "error", err)
+		} else if byKeyword, err = s.ragRepo.BroadRecallSummaries(ctx, userID, keywords, ragSearchFallbackSize, "", scope); err != nil {
+			slog.Warn("broad recall failed", "error", err)
 		}
-		recalled, err := s.ragRepo.BroadRecallSummaries(ctx, userID, keywords, ragSearchFallbackSize, "")
-		if err != nil || len(recalled) == 0 {
-			slog.Warn("broad recall failed or empty, falling back to pg_trgm",
-				"error", err, "recalled", len(recalled))
-			return s.fallbackSearch(ctx, userID, question, articles)
+
+		recalled := domain.FuseRAGSources(ragSearchFallbackSize, byVector, byKeyword)
+		if len(recalled) == 0 {
+			slog.Warn("hybrid recall empty, falling back to pg_trgm",
+				"by_vector", len(byVector), "by_keyword", len(byKeyword))
+			return s.fallbackSearch(ctx, userID, question, articles, scope)
 		}
 		return recalled
 	}
@@ -182,12 +325,12 @@ func (s *RAGService) applyTokenBudget(ctx context.Context, userID, question stri
 		tokens := estimateTokens(title) + estimateTokens(summary)
 
 		if estimatedTokens+tokens > ragTokenBudget {
-			searched, err := s.ragRepo.SearchArticleSummaries(ctx, userID, question, ragSearchFallbackSize)
+			searched, err := s.ragRepo.SearchArticleSummaries(ctx, userID, question, ragSearchFallbackSize, scope)
 			if err != nil {
 				slog.Warn("search fallback failed after budget exceeded", "error", err)
 				break
 			}
-			return searched
+			return domain.FuseRAGSources(ragSearchFallbackSize, s.vectorRecall(ctx, userID, vector, scope), searched)
 		}
 
 		estimatedTokens += tokens
@@ -200,9 +343,53 @@ func (s *RAGService) applyTokenBudget(ctx context.Context, userID, question stri
 	return selected
 }
 
+// embedQuestion returns the question's embedding, or nil when vector recall
+// is unavailable.
+func (s *RAGService) embedQuestion(ctx context.Context, question string) []float32 {
+	if s.embedder == nil || s.vectors == nil {
+		return nil
+	}
+	vectors, err := s.embedder.Embed(ctx, []string{question})
+	if err != nil || len(vectors) != 1 {
+		slog.Warn("embed question failed", "error", err)
+		return nil
+	}
+	if !slices.ContainsFunc(vectors[0], func(x float32) bool { return x != 0 }) {
+		return nil // nothing embeddable in the question; cosine distance is undefined
+	}
+	return vectors[0]
+}
+
+// vectorRecall returns the user's articles nearest to the question vector.
+func (s *RAGService) vectorRecall(ctx context.Context, userID string, vector []float32, scope *domain.RAGScope) []domain.RAGSource {
+	if vector == nil {
+		return nil
+	}
+	found, err := s.vectors.SearchSummaries(ctx, userID, s.embedder.Model(), vector, ragSearchFallbackSize, "", scope)
+	if err != nil {
+		slog.Warn("vector recall failed", "error", err)
+		return nil
+	}
+	return found
+}
+
+// passageRecall returns the passages nearest to the question vector, to be
+// quoted in the prompt alongside the article summaries.
+func (s *RAGService) passageRecall(ctx context.Context, userID string, vector []float32, scope *domain.RAGScope) []domain.RAGSource {
+	if vector == nil {
+		return nil
+	}
+	found, err := s.vectors.SearchPassages(ctx, userID, s.embedder.Model(), vector, ragPassageLimit, scope)
+	if err != nil {
+		slog.Warn("passage recall failed", "error", err)
+		return nil
+	}
+	return found
+}
+
 // fallbackSearch is the degradation path when smart retrieval fails.
-func (s *RAGService) fallbackSearch(ctx context.Context, userID, question string, articles []domain.RAGSource) []domain.RAGSource {
-	searched, err := s.ragRepo.SearchArticleSummaries(ctx, userID, question, ragSearchFallbackSize)
+func (s *RAGService) fallbackSearch(ctx context.Context, userID, question string, articles []domain.RAGSource, scope *domain.RAGScope) []domain.RAGSource {
+	searched, err := s.ragRepo.SearchArticleSummaries(ctx, userID, question, ragSearchFallbackSize, scope)
 	if err != nil || len(searched) == 0 {
 		if len(articles) > ragSearchFallbackSize {
 			return articles[:ragSearchFallbackSize]
@@ -231,8 +418,8 @@ func estimateTokens(s string) int {
 
 // buildRAGSystemPrompt returns the system prompt for RAG queries.
 func buildRAGSystemPrompt() string {
-	return `你是用户的个人知识助手。以下是用户收藏的文章摘要列表。
-基于且仅基于这些文章回答用户的问题。
+	return `你是用户的个人知识助手。以下是用户收藏的文章摘要列表，以及可能附带的原文段落。
+基于且仅基于这些内容回答用户的问题。
 
 输出 JSON 格式（不要 markdown 代码块）：
 {
@@ -243,14 +430,15 @@ func buildRAGSystemPrompt() string {
 
 规则：
 1. 只基于用户的收藏回答，不编造内容
-2. 引用标注必须对应下方文章列表的编号
+2. 引用标注必须对应下方文章或原文段落的编号；观点出自某段原文时，优先引用该段落的编号
 3. 回答风格：简洁、有洞察力、直击核心。对核心观点用加粗强调
 4. 给出 2 个建议的跟进问题
 5. 如果收藏中没有相关内容，answer 写 "你的收藏中没有找到与此相关的内容。"，cited_indices 为空`
 }
 
-// buildRAGUserPrompt constructs the user prompt with articles, history, and question.
-func buildRAGUserPrompt(articles []domain.RAGSource, history []domain.RAGMessage, question string) string {
+// buildRAGUserPrompt constructs the user prompt with articles, passages,
+// history, and question. Passages are numbered after the articles.
+func buildRAGUserPrompt(articles, passages []domain.RAGSource, history []domain.RAGMessage, question string) string {
 	var b strings.Builder
 
 	fmt.Fprintf(&b, "用户收藏（共 %d 篇）：\n\n", len(articles))
@@ -268,6 +456,13 @@ func buildRAGUserPrompt(articles []domain.RAGSource, history []domain.RAGMessage
 		fmt.Fprintf(&b, "[%d] 《%s》(%s, %s): %s\n", i+1, title, siteName, date, summary)
 	}
 
+	if len(passages) > 0 {
+		b.WriteString("\n相关原文段落：\n\n")
+		for i, p := range passages {
+			fmt.Fprintf(&b, "[%d] 《%s》原文：「%s」\n", len(articles)+i+1, p.Title, p.Passage.Text)
+		}
+	}
+
 	// Append conversation history if present.
 	if len(history) > 0 {
 		b.WriteString("\n")
@@ -300,6 +495,44 @@ func extractAnswerFromContent(content string) string {
 	return content
 }
 
+// superscriptDigits maps the superscript numerals used as citation markers
+// to their values.
+var superscriptDigits = map[rune]int{
+	'⁰': 0, '¹': 1, '²': 2, '³': 3, '⁴': 4, '⁵': 5, '⁶': 6, '⁷': 7, '⁸': 8, '⁹': 9,
+}
+
+// citationScanner finds superscript citation markers (¹, ¹²) in answer text
+// that arrives in pieces. A marker is complete once a character other than a
+// superscript digit follows it, or at Flush.
+type citationScanner struct {
+	value    int
+	inMarker bool
+}
+
+// Write scans the next piece of text and returns the markers it completed.
+func (c *citationScanner) Write(text string) []int {
+	var done []int
+	for _, r := range text {
+		if d, ok := superscriptDigits[r]; ok {
+			c.value = c.value*10 + d
+			c.inMarker = true
+			continue
+		}
+		done = append(done, c.Flush()...)
+	}
+	return done
+}
+
+// Flush returns the marker at the end of the text, if any.
+func (c *citationScanner) Flush() []int {
+	if !c.inMarker {
+		return nil
+	}
+	v := c.value
+	c.value, c.inMarker = 0, false
+	return []int{v}
+}
+
 // mapCitedSources converts 1-based cited indices to actual RAGSource entries.
 // Invalid indices (out of range) are silently filtered.
 func mapCitedSources(citedIndices []int, articles []domain.RAGSource) []domain.RAGSource {
@@ -330,7 +563,8 @@ func (s *RAGService) saveConversation(
 	sources []domain.RAGSource,
 ) (string, error) {
 	// Create new conversation if none provided.
-	if conversationID == "" {
+	created := conversationID == ""
+	if created {
 		conv := &domain.RAGConversation{
 			UserID: userID,
 			Title:  truncateStringPtr(question, 50),
@@ -356,7 +590,10 @@ func (s *RAGService) saveConversation(
 	// Save assistant message with cited sources.
 	sourceIDs := make([]string, 0, len(sources))
 	for _, src := range sources {
-		sourceIDs = append(sourceIDs, src.ArticleID)
+		// A passage and its article can both be cited.
+		if !slices.Contains(sourceIDs, src.ArticleID) {
+			sourceIDs = append(sourceIDs, src.ArticleID)
+		}
 	}
 
 	// Store the full RAG result JSON as the assistant content.
@@ -373,6 +610,13 @@ func (s *RAGService) saveConversation(
 		return conversationID, fmt.Errorf("save assistant message: %w", err)
 	}
 
+	// The question stands in as the title until one is generated.
+	if created {
+		if _, err := s.enqueuer.EnqueueContext(ctx, worker.NewRAGTitleTask(conversationID, userID)); err != nil {
+			slog.Error("failed to enqueue rag title", "conversation_id", conversationID, "error", err)
+		}
+	}
+
 	return conversationID, nil
 }
 
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"folio-server/internal/domain"
+	"folio-server/internal/repository"
+	"folio-server/internal/vault"
+)
+
+// ragTitleMaxRunes matches the rag_conversations.title column.
+const ragTitleMaxRunes = 200
+
+// ListConversations returns a page of the user's RAG conversations, most
+// recently active first.
+func (s *RAGService) ListConversations(ctx context.Context, userID string, page, perPage int) (*repository.ListRAGConversationsResult, error) {
+	return s.ragRepo.ListConversations(ctx, userID, page, perPage)
+}
+
+// GetConversation returns one of the user's conversations with all its
+// messages. Assistant messages carry the answer text as Content and their
+// cited articles as Sources; deleted articles are left out.
+func (s *RAGService) GetConversation(ctx context.Context, userID, conversationID string) (*domain.RAGConversation, []domain.RAGMessage, error) {
+	conv, err := s.getConversation(ctx, userID, conversationID)
+	if err != nil {
+		return nil, nil, err
+	}
+	msgs, err := s.ragRepo.GetConversationMessages(ctx, userID, conversationID, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var ids []string
+	for _, m := range msgs {
+		for _, id := range m.SourceArticleIDs {
+			if !slices.Contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	found, err := s.ragRepo.LoadSourcesByIDs(ctx, userID, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	byID := make(map[string]domain.RAGSource, len(found))
+	for _, src := range found {
+		byID[src.ArticleID] = src
+	}
+
+	for i := range msgs {
+		m := &msgs[i]
+		if m.Role != "assistant" {
+			continue
+		}
+		m.Content = extractAnswerFromContent(m.Content)
+		m.Sources = make([]domain.RAGSource, 0, len(m.SourceArticleIDs))
+		for _, id := range m.SourceArticleIDs {
+			if src, ok := byID[id]; ok {
+				m.Sources = append(m.Sources, src)
+			}
+		}
+	}
+	return conv, msgs, nil
+}
+
+// RenameConversation sets the title of one of the user's conversations.
+func (s *RAGService) RenameConversation(ctx context.Context, userID, conversationID, title string) (*domain.RAGConversation, error) {
+	title = strings.Join(strings.Fields(title), " ")
+	if title == "" || utf8.RuneCountInString(title) > ragTitleMaxRunes {
+		return nil, ErrInvalidConversationTitle
+	}
+	conv, err := s.getConversation(ctx, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ragRepo.UpdateConversationTitle(ctx, conversationID, userID, title); err != nil {
+		return nil, err
+	}
+	return s.getConversation(ctx, userID, conv.ID)
+}
+
+// DeleteConversation deletes one of the user's conversations.
+func (s *RAGService) DeleteConversation(ctx context.Context, userID, conversationID string) error {
+	if _, err := s.getConversation(ctx, userID, conversationID); err != nil {
+		return err
+	}
+	return s.ragRepo.DeleteConversation(ctx, conversationID, userID)
+}
+
+// ExportConversation renders one of the user's conversations as Markdown,
+// returning a file name for it and the content.
+func (s *RAGService) ExportConversation(ctx context.Context, userID, conversationID string) (string, []byte, error) {
+	conv, msgs, err := s.GetConversation(ctx, userID, conversationID)
+	if err != nil {
+		return "", nil, err
+	}
+	var buf bytes.Buffer
+	if err := vault.WriteConversation(&buf, conv, msgs); err != nil {
+		return "", nil, err
+	}
+	return vault.ConversationFileName(conv), buf.Bytes(), nil
+}
+
+// getConversation returns the conversation if it exists and belongs to the
+// user.
+func (s *RAGService) getConversation(ctx context.Context, userID, conversationID string) (*domain.RAGConversation, error) {
+	conv, err := s.ragRepo.GetConversation(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if conv == nil {
+		return nil, ErrNotFound
+	}
+	if conv.UserID != userID {
+		return nil, ErrForbidden
+	}
+	return conv, nil
+}
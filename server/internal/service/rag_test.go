@@ -0,0 +1,44 @@
+package service
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"folio-server/internal/domain"
+)
+
+func TestCitationScanner(t *testing.T) {
+	var c citationScanner
+	var got []int
+	for _, piece := range []string{"Go¹ and Rust", "²", "³ differ", "¹", "²"} {
+		got = append(got, c.Write(piece)...)
+	}
+	got = append(got, c.Flush()...)
+
+	if want := []int{1, 23, 12}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if c.Flush() != nil {
+		t.Error("second Flush returned a marker")
+	}
+}
+
+func TestRAGPromptNumbersPassagesAfterArticles(t *testing.T) {
+	prompt := &ragPrompt{
+		articles: []domain.RAGSource{{ArticleID: "a1", Title: "Go"}, {ArticleID: "a2", Title: "Rust"}},
+		passages: []domain.RAGSource{{ArticleID: "a1", Title: "Go", Passage: &domain.RAGPassage{Text: "Goroutines are cheap.", StartOffset: 10, EndOffset: 31}}},
+	}
+	user := buildRAGUserPrompt(prompt.articles, prompt.passages, nil, "why?")
+	if !strings.Contains(user, "[3] 《Go》原文：「Goroutines are cheap.」") {
+		t.Errorf("passage not numbered after articles:\n%s", user)
+	}
+
+	sources := mapCitedSources([]int{3, 2, 4}, prompt.sources())
+	if len(sources) != 2 || sources[0].Passage == nil || sources[0].Passage.StartOffset != 10 || sources[1].ArticleID != "a2" {
+		t.Errorf("mapped sources = %+v", sources)
+	}
+	if len(prompt.articles) != 2 {
+		t.Error("sources() modified the articles")
+	}
+}
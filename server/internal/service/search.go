@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"folio-server/internal/domain"
+	"folio-server/internal/repository"
+)
+
+// unifiedSearcher is the subset of SearchRepo used by SearchService.
+type unifiedSearcher interface {
+	Search(ctx context.Context, p repository.UnifiedSearchParams) (*repository.UnifiedSearchResult, error)
+}
+
+// SearchService searches everything a user has saved or written:
+// articles, highlights and their notes, Echo cards and RAG messages.
+type SearchService struct {
+	searchRepo unifiedSearcher
+}
+
+func NewSearchService(searchRepo *repository.SearchRepo) *SearchService {
+	return &SearchService{searchRepo: searchRepo}
+}
+
+// Search runs query (see domain.SearchQuery) over the given result types,
+// or all of them when types is empty. The query needs at least one word or
+// phrase to rank by; filters restrict results to those of matching articles.
+func (s *SearchService) Search(ctx context.Context, userID, query string, types []domain.SearchResultType, page, perPage int) (*repository.UnifiedSearchResult, error) {
+	q, err := domain.ParseSearchQuery(query)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSearchQuery, err)
+	}
+	if !hasPositiveTerm(q) {
+		return nil, fmt.Errorf("%w: needs a word or phrase to search for", ErrInvalidSearchQuery)
+	}
+	return s.searchRepo.Search(ctx, repository.UnifiedSearchParams{
+		UserID:  userID,
+		Query:   q,
+		Types:   types,
+		Page:    page,
+		PerPage: perPage,
+	})
+}
+
+func hasPositiveTerm(q *domain.SearchQuery) bool {
+	for _, t := range q.Terms {
+		if !t.Negated {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"folio-server/internal/domain"
+	"folio-server/internal/repository"
+)
+
+type mockUnifiedSearcher struct {
+	params *repository.UnifiedSearchParams
+}
+
+func (m *mockUnifiedSearcher) Search(ctx context.Context, p repository.UnifiedSearchParams) (*repository.UnifiedSearchResult, error) {
+	m.params = &p
+	return &repository.UnifiedSearchResult{Results: []domain.SearchResult{}}, nil
+}
+
+func TestSearchService_Search(t *testing.T) {
+	repo := &mockUnifiedSearcher{}
+	svc := &SearchService{searchRepo: repo}
+
+	types := []domain.SearchResultType{domain.SearchResultHighlight}
+	if _, err := svc.Search(context.Background(), "user-1", `"attention is all" tag:ml`, types, 2, 10); err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	p := repo.params
+	if p == nil || p.UserID != "user-1" || p.Page != 2 || p.PerPage != 10 || len(p.Types) != 1 {
+		t.Fatalf("params = %+v", p)
+	}
+	if len(p.Query.Terms) != 1 || !p.Query.Terms[0].Phrase || len(p.Query.Filters) != 1 {
+		t.Errorf("query = %+v", p.Query)
+	}
+
+	// Filters or exclusions alone give nothing to rank by.
+	for _, q := range []string{`tag:ml`, `-crypto`, `before:someday`} {
+		repo.params = nil
+		_, err := svc.Search(context.Background(), "user-1", q, nil, 1, 20)
+		if !errors.Is(err, ErrInvalidSearchQuery) {
+			t.Errorf("Search(%q) error = %v, want ErrInvalidSearchQuery", q, err)
+		}
+		if repo.params != nil {
+			t.Errorf("Search(%q) reached the repo", q)
+		}
+	}
+}
@@ -107,7 +107,7 @@ func (s *StatsService) GetMonthlyStats(ctx context.Context, userID string, year,
 	// 5. trend_insight: compare this month vs last month (AI only)
 	var trendInsight *string
 	if s.aiClient.IsRealAI() {
-		// Only call AI when a real analyzer is wired (i.e. DeepSeek key is present).
+		// Only call AI when a real analyzer is wired (i.e. an LLM provider is configured).
 		prevYear, prevMonth := year, month-1
 		if prevMonth == 0 {
 			prevMonth = 12
@@ -213,7 +213,7 @@ func (s *StatsService) getTopicDistribution(ctx context.Context, userID string,
 	return stats, nil
 }
 
-// generateTrendInsight calls DeepSeek to produce a one-sentence trend summary.
+// generateTrendInsight asks the LLM to produce a one-sentence trend summary.
 // Returns nil on any error so callers can treat it as optional.
 func (s *StatsService) generateTrendInsight(ctx context.Context, current, prev []TopicStat) *string {
 	currentMap := topicStatMap(current)
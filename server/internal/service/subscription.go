@@ -17,19 +17,34 @@ var validProductIDs = map[string]bool{
 	"com.folio.app.pro.monthly": true,
 }
 
+// subscriptionEventClientVerify is the notification type recorded when the
+// app reports a purchase through VerifyAndActivate.
+const subscriptionEventClientVerify = "CLIENT_VERIFY"
+
+type subscriptionUserFinder interface {
+	GetByOriginalTransactionID(ctx context.Context, txnID string) (*domain.User, error)
+}
+
+type subscriptionEventRecorder interface {
+	Record(ctx context.Context, ev *domain.SubscriptionEvent) (bool, error)
+}
+
 // SubscriptionService handles App Store subscription verification and webhook
-// processing.
+// processing. Every change to a user's subscription goes through the
+// subscription_events history.
 type SubscriptionService struct {
 	appleClient client.AppleStoreClient
-	userRepo    *repository.UserRepo
+	userRepo    subscriptionUserFinder
+	eventRepo   subscriptionEventRecorder
 	bundleID    string
 }
 
 // NewSubscriptionService creates a SubscriptionService.
-func NewSubscriptionService(appleClient client.AppleStoreClient, userRepo *repository.UserRepo, bundleID string) *SubscriptionService {
+func NewSubscriptionService(appleClient client.AppleStoreClient, userRepo *repository.UserRepo, eventRepo *repository.SubscriptionRepo, bundleID string) *SubscriptionService {
 	return &SubscriptionService{
 		appleClient: appleClient,
 		userRepo:    userRepo,
+		eventRepo:   eventRepo,
 		bundleID:    bundleID,
 	}
 }
@@ -47,7 +62,7 @@ type VerifyAndActivateResult struct {
 //  2. Validate bundleID (skip for mock)
 //  3. Validate productID is a known Folio Pro product
 //  4. Check expiresDate is in the future
-//  5. Update user: subscription = "pro", expiry, original_transaction_id
+//  5. Record the activation: state = active, expiry, original_transaction_id
 func (s *SubscriptionService) VerifyAndActivate(ctx context.Context, userID, transactionID, productID string) (*VerifyAndActivateResult, error) {
 	txnInfo, err := s.appleClient.VerifyTransaction(ctx, transactionID)
 	if err != nil {
@@ -78,8 +93,12 @@ func (s *SubscriptionService) VerifyAndActivate(ctx context.Context, userID, tra
 
 	// Activate.
 	origTxnID := txnInfo.OriginalTransactionID
-	if err := s.userRepo.UpdateSubscription(ctx, userID,
-		domain.SubscriptionPro, txnInfo.ExpiresDate, &origTxnID); err != nil {
+	active := domain.SubscriptionStateActive
+	ev := newSubscriptionEvent(subscriptionEventClientVerify, "", txnInfo, time.Now())
+	ev.UserID = &userID
+	ev.ToState = &active
+	ev.ExpiresAt = txnInfo.ExpiresDate
+	if _, err := s.eventRepo.Record(ctx, ev); err != nil {
 		return nil, fmt.Errorf("activate subscription: %w", err)
 	}
 
@@ -95,11 +114,9 @@ func (s *SubscriptionService) VerifyAndActivate(ctx context.Context, userID, tra
 	}, nil
 }
 
-// HandleWebhookEvent processes an App Store Server notification.
-//
-// For MVP, the webhook logs all events and only acts on REFUND (downgrades the
-// user). Renewals and expirations are handled client-side via StoreKit 2
-// Transaction.currentEntitlements.
+// HandleWebhookEvent processes an App Store Server notification: it works
+// out the subscription state the notification moves the user into and
+// records it. Redelivered notifications are ignored.
 func (s *SubscriptionService) HandleWebhookEvent(ctx context.Context, signedPayload string) error {
 	event, err := s.appleClient.ParseWebhookPayload(signedPayload)
 	if err != nil {
@@ -108,7 +125,8 @@ func (s *SubscriptionService) HandleWebhookEvent(ctx context.Context, signedPayl
 
 	slog.Info("apple webhook received",
 		"notification_type", event.NotificationType,
-		"subtype", event.Subtype)
+		"subtype", event.Subtype,
+		"notification_uuid", event.NotificationUUID)
 
 	// Parse the signed transaction inside the event to get user-linking info.
 	var txnInfo *client.TransactionInfo
@@ -121,111 +139,133 @@ func (s *SubscriptionService) HandleWebhookEvent(ctx context.Context, signedPayl
 			// Non-fatal — we still return 200 to Apple.
 			return nil
 		}
-		slog.Info("webhook transaction parsed",
-			"original_txn_id", txnInfo.OriginalTransactionID,
-			"product_id", txnInfo.ProductID,
-			"expires", txnInfo.ExpiresDate)
 	}
-
-	switch event.NotificationType {
-	case "DID_RENEW":
-		slog.Info("webhook: subscription renewed",
-			"original_txn_id", safeOrigTxnID(txnInfo))
-		// Update expiry if we can find the user.
-		if txnInfo != nil {
-			s.tryUpdateExpiry(ctx, txnInfo)
+	var renewal *client.RenewalInfo
+	if event.Data.SignedRenewalInfo != "" {
+		renewal, err = s.appleClient.ParseSignedRenewalInfo(event.Data.SignedRenewalInfo)
+		if err != nil {
+			slog.Warn("webhook: failed to parse signed renewal info",
+				"error", err,
+				"notification_type", event.NotificationType)
+			return nil
 		}
+	}
 
-	case "EXPIRED":
-		slog.Info("webhook: subscription expired",
-			"original_txn_id", safeOrigTxnID(txnInfo))
-		// Downgrade if we can find the user.
-		if txnInfo != nil {
-			s.tryDowngrade(ctx, txnInfo)
-		}
+	signedAt := event.SignedAt()
+	if signedAt.IsZero() {
+		signedAt = time.Now()
+	}
+	ev := newSubscriptionEvent(event.NotificationType, event.Subtype, txnInfo, signedAt)
+	if event.NotificationUUID != "" {
+		ev.NotificationUUID = &event.NotificationUUID
+	}
+	applyWebhookTransition(ev, event, txnInfo, renewal)
 
-	case "REFUND":
-		slog.Info("webhook: refund issued",
-			"original_txn_id", safeOrigTxnID(txnInfo))
-		if txnInfo != nil {
-			s.tryDowngrade(ctx, txnInfo)
+	if ev.OriginalTransactionID != nil {
+		user, err := s.userRepo.GetByOriginalTransactionID(ctx, *ev.OriginalTransactionID)
+		if err != nil {
+			return fmt.Errorf("find subscription user: %w", err)
 		}
+		if user != nil {
+			ev.UserID = &user.ID
+		} else {
+			slog.Warn("webhook: no user found for original_txn_id",
+				"original_txn_id", *ev.OriginalTransactionID)
+		}
+	}
 
-	case "DID_CHANGE_RENEWAL_STATUS":
-		slog.Info("webhook: renewal status changed",
-			"subtype", event.Subtype,
-			"original_txn_id", safeOrigTxnID(txnInfo))
-
-	default:
-		slog.Info("webhook: unhandled notification type",
-			"notification_type", event.NotificationType,
-			"subtype", event.Subtype)
+	recorded, err := s.eventRepo.Record(ctx, ev)
+	if err != nil {
+		return fmt.Errorf("record subscription event: %w", err)
+	}
+	if !recorded {
+		slog.Info("webhook: duplicate notification ignored",
+			"notification_uuid", event.NotificationUUID)
+		return nil
 	}
 
+	slog.Info("webhook: subscription event recorded",
+		"notification_type", event.NotificationType,
+		"user_id", derefString(ev.UserID),
+		"from_state", safeState(ev.FromState),
+		"to_state", safeState(ev.ToState),
+		"applied", ev.Applied)
 	return nil
 }
 
-// tryUpdateExpiry attempts to find the user by original_transaction_id and
-// update their subscription expiry. Errors are logged but not returned (webhook
-// must always return 200).
-func (s *SubscriptionService) tryUpdateExpiry(ctx context.Context, txnInfo *client.TransactionInfo) {
-	if txnInfo.OriginalTransactionID == "" || txnInfo.ExpiresDate == nil {
-		return
-	}
-
-	user, err := s.userRepo.GetByOriginalTransactionID(ctx, txnInfo.OriginalTransactionID)
-	if err != nil {
-		slog.Error("webhook: failed to find user by txn ID",
-			"original_txn_id", txnInfo.OriginalTransactionID, "error", err)
-		return
+// applyWebhookTransition sets the state, expiry and auto-renew status that
+// the notification implies on ev. Notification types that do not affect
+// entitlement (e.g. PRICE_INCREASE, TEST) leave ToState nil.
+func applyWebhookTransition(ev *domain.SubscriptionEvent, event *client.WebhookEvent, txn *client.TransactionInfo, renewal *client.RenewalInfo) {
+	var to domain.SubscriptionState
+	switch event.NotificationType {
+	case "SUBSCRIBED", "DID_RENEW", "OFFER_REDEEMED", "REFUND_REVERSED":
+		to = domain.SubscriptionStateActive
+		if txn != nil {
+			ev.ExpiresAt = txn.ExpiresDate
+		}
+	case "DID_FAIL_TO_RENEW":
+		// The GRACE_PERIOD subtype means billing grace is enabled: the user
+		// keeps access until it ends while Apple retries the payment.
+		if event.Subtype == "GRACE_PERIOD" {
+			to = domain.SubscriptionStateGracePeriod
+			if renewal != nil {
+				ev.ExpiresAt = renewal.GracePeriodExpiresDate
+			}
+		} else {
+			to = domain.SubscriptionStateBillingRetry
+		}
+	case "GRACE_PERIOD_EXPIRED":
+		// Apple keeps retrying after the grace period, but access ends.
+		to = domain.SubscriptionStateBillingRetry
+	case "EXPIRED":
+		to = domain.SubscriptionStateExpired
+	case "REFUND":
+		to = domain.SubscriptionStateRefunded
+	case "REVOKE":
+		to = domain.SubscriptionStateRevoked
+	case "DID_CHANGE_RENEWAL_STATUS":
+		autoRenew := event.Subtype == "AUTO_RENEW_ENABLED"
+		ev.AutoRenew = &autoRenew
 	}
-	if user == nil {
-		slog.Warn("webhook: no user found for original_txn_id",
-			"original_txn_id", txnInfo.OriginalTransactionID)
-		return
+	if to != "" {
+		ev.ToState = &to
 	}
 
-	if err := s.userRepo.UpdateSubscription(ctx, user.ID,
-		domain.SubscriptionPro, txnInfo.ExpiresDate, nil); err != nil {
-		slog.Error("webhook: failed to update subscription expiry",
-			"user_id", user.ID, "error", err)
-	} else {
-		slog.Info("webhook: subscription expiry updated",
-			"user_id", user.ID, "expires_at", txnInfo.ExpiresDate)
+	if renewal != nil && ev.AutoRenew == nil {
+		ev.AutoRenew = &renewal.AutoRenewStatus
+	}
+	if ev.OriginalTransactionID == nil && renewal != nil && renewal.OriginalTransactionID != "" {
+		ev.OriginalTransactionID = &renewal.OriginalTransactionID
 	}
 }
 
-// tryDowngrade attempts to find the user and revert them to the free tier.
-func (s *SubscriptionService) tryDowngrade(ctx context.Context, txnInfo *client.TransactionInfo) {
-	if txnInfo.OriginalTransactionID == "" {
-		return
+// newSubscriptionEvent starts an event for a notification, copying the
+// transaction identifiers from txn when present.
+func newSubscriptionEvent(notificationType, subtype string, txn *client.TransactionInfo, signedAt time.Time) *domain.SubscriptionEvent {
+	ev := &domain.SubscriptionEvent{
+		NotificationType: notificationType,
+		Subtype:          subtype,
+		SignedAt:         signedAt,
 	}
-
-	user, err := s.userRepo.GetByOriginalTransactionID(ctx, txnInfo.OriginalTransactionID)
-	if err != nil {
-		slog.Error("webhook: failed to find user for downgrade",
-			"original_txn_id", txnInfo.OriginalTransactionID, "error", err)
-		return
-	}
-	if user == nil {
-		slog.Warn("webhook: no user found for downgrade",
-			"original_txn_id", txnInfo.OriginalTransactionID)
-		return
+	if txn != nil {
+		ev.OriginalTransactionID = nonEmpty(txn.OriginalTransactionID)
+		ev.TransactionID = nonEmpty(txn.TransactionID)
+		ev.ProductID = nonEmpty(txn.ProductID)
 	}
+	return ev
+}
 
-	if err := s.userRepo.UpdateSubscription(ctx, user.ID,
-		domain.SubscriptionFree, nil, nil); err != nil {
-		slog.Error("webhook: failed to downgrade user",
-			"user_id", user.ID, "error", err)
-	} else {
-		slog.Info("webhook: user downgraded to free",
-			"user_id", user.ID)
+func nonEmpty(s string) *string {
+	if s == "" {
+		return nil
 	}
+	return &s
 }
 
-func safeOrigTxnID(txnInfo *client.TransactionInfo) string {
-	if txnInfo == nil {
-		return "<unknown>"
+func safeState(state *domain.SubscriptionState) string {
+	if state == nil {
+		return "<none>"
 	}
-	return txnInfo.OriginalTransactionID
+	return string(*state)
 }
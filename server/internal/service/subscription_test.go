@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"folio-server/internal/client"
+	"folio-server/internal/domain"
+)
+
+// fakeAppleClient returns canned parse results regardless of input.
+type fakeAppleClient struct {
+	event   *client.WebhookEvent
+	txn     *client.TransactionInfo
+	renewal *client.RenewalInfo
+}
+
+func (f *fakeAppleClient) VerifyTransaction(_ context.Context, _ string) (*client.TransactionInfo, error) {
+	return f.txn, nil
+}
+
+func (f *fakeAppleClient) ParseWebhookPayload(_ string) (*client.WebhookEvent, error) {
+	return f.event, nil
+}
+
+func (f *fakeAppleClient) ParseSignedTransaction(_ string) (*client.TransactionInfo, error) {
+	return f.txn, nil
+}
+
+func (f *fakeAppleClient) ParseSignedRenewalInfo(_ string) (*client.RenewalInfo, error) {
+	return f.renewal, nil
+}
+
+type mockSubscriptionUsers struct{ user *domain.User }
+
+func (m *mockSubscriptionUsers) GetByOriginalTransactionID(_ context.Context, _ string) (*domain.User, error) {
+	return m.user, nil
+}
+
+// mockSubscriptionEvents keeps recorded events and rejects repeated
+// notification UUIDs like the unique index does.
+type mockSubscriptionEvents struct {
+	events []*domain.SubscriptionEvent
+	seen   map[string]bool
+}
+
+func (m *mockSubscriptionEvents) Record(_ context.Context, ev *domain.SubscriptionEvent) (bool, error) {
+	if ev.NotificationUUID != nil {
+		if m.seen[*ev.NotificationUUID] {
+			return false, nil
+		}
+		if m.seen == nil {
+			m.seen = map[string]bool{}
+		}
+		m.seen[*ev.NotificationUUID] = true
+	}
+	ev.Applied = ev.UserID != nil
+	m.events = append(m.events, ev)
+	return true, nil
+}
+
+func newSubscriptionFixture(notificationType, subtype string) (*SubscriptionService, *fakeAppleClient, *mockSubscriptionEvents) {
+	expires := time.Now().Add(30 * 24 * time.Hour)
+	apple := &fakeAppleClient{
+		event: &client.WebhookEvent{
+			NotificationType: notificationType,
+			Subtype:          subtype,
+			NotificationUUID: "uuid-" + notificationType + subtype,
+			SignedDate:       time.Now().UnixMilli(),
+			Data:             client.WebhookData{SignedTransactionInfo: "txn", SignedRenewalInfo: "renewal"},
+		},
+		txn: &client.TransactionInfo{
+			TransactionID:         "2000",
+			OriginalTransactionID: "1000",
+			ProductID:             "com.folio.app.pro.monthly",
+			ExpiresDate:           &expires,
+		},
+		renewal: &client.RenewalInfo{OriginalTransactionID: "1000", AutoRenewStatus: true},
+	}
+	events := &mockSubscriptionEvents{}
+	s := &SubscriptionService{
+		appleClient: apple,
+		userRepo:    &mockSubscriptionUsers{user: &domain.User{ID: "user-1"}},
+		eventRepo:   events,
+	}
+	return s, apple, events
+}
+
+func TestSubscriptionService_WebhookTransitions(t *testing.T) {
+	tests := []struct {
+		notificationType, subtype string
+		want                      domain.SubscriptionState
+		wantTier                  domain.Subscription
+	}{
+		{"SUBSCRIBED", "INITIAL_BUY", domain.SubscriptionStateActive, domain.SubscriptionPro},
+		{"DID_RENEW", "", domain.SubscriptionStateActive, domain.SubscriptionPro},
+		{"DID_RENEW", "BILLING_RECOVERY", domain.SubscriptionStateActive, domain.SubscriptionPro},
+		{"OFFER_REDEEMED", "UPGRADE", domain.SubscriptionStateActive, domain.SubscriptionPro},
+		{"DID_FAIL_TO_RENEW", "GRACE_PERIOD", domain.SubscriptionStateGracePeriod, domain.SubscriptionPro},
+		{"DID_FAIL_TO_RENEW", "", domain.SubscriptionStateBillingRetry, domain.SubscriptionFree},
+		{"GRACE_PERIOD_EXPIRED", "", domain.SubscriptionStateBillingRetry, domain.SubscriptionFree},
+		{"EXPIRED", "VOLUNTARY", domain.SubscriptionStateExpired, domain.SubscriptionFree},
+		{"REFUND", "", domain.SubscriptionStateRefunded, domain.SubscriptionFree},
+		{"REVOKE", "", domain.SubscriptionStateRevoked, domain.SubscriptionFree},
+	}
+	for _, tt := range tests {
+		t.Run(tt.notificationType+"/"+tt.subtype, func(t *testing.T) {
+			s, _, events := newSubscriptionFixture(tt.notificationType, tt.subtype)
+			if err := s.HandleWebhookEvent(context.Background(), "payload"); err != nil {
+				t.Fatalf("HandleWebhookEvent: %v", err)
+			}
+			if len(events.events) != 1 {
+				t.Fatalf("recorded %d events, want 1", len(events.events))
+			}
+			ev := events.events[0]
+			if ev.ToState == nil || *ev.ToState != tt.want {
+				t.Fatalf("to state = %v, want %q", ev.ToState, tt.want)
+			}
+			if ev.ToState.Tier() != tt.wantTier {
+				t.Errorf("tier = %q, want %q", ev.ToState.Tier(), tt.wantTier)
+			}
+			if ev.UserID == nil || *ev.UserID != "user-1" || *ev.OriginalTransactionID != "1000" {
+				t.Errorf("event not linked to user: %+v", ev)
+			}
+		})
+	}
+}
+
+func TestSubscriptionService_GracePeriodExpiry(t *testing.T) {
+	s, apple, events := newSubscriptionFixture("DID_FAIL_TO_RENEW", "GRACE_PERIOD")
+	graceEnds := time.Now().Add(16 * 24 * time.Hour)
+	apple.renewal.GracePeriodExpiresDate = &graceEnds
+
+	if err := s.HandleWebhookEvent(context.Background(), "payload"); err != nil {
+		t.Fatal(err)
+	}
+	if ev := events.events[0]; ev.ExpiresAt == nil || !ev.ExpiresAt.Equal(graceEnds) {
+		t.Errorf("expires at = %v, want end of grace period %v", ev.ExpiresAt, graceEnds)
+	}
+}
+
+func TestSubscriptionService_RenewalStatusChange(t *testing.T) {
+	s, _, events := newSubscriptionFixture("DID_CHANGE_RENEWAL_STATUS", "AUTO_RENEW_DISABLED")
+	if err := s.HandleWebhookEvent(context.Background(), "payload"); err != nil {
+		t.Fatal(err)
+	}
+	ev := events.events[0]
+	if ev.ToState != nil {
+		t.Errorf("renewal status change moved state to %q", *ev.ToState)
+	}
+	if ev.AutoRenew == nil || *ev.AutoRenew {
+		t.Errorf("auto renew = %v, want false", ev.AutoRenew)
+	}
+}
+
+func TestSubscriptionService_DuplicateNotification(t *testing.T) {
+	s, _, events := newSubscriptionFixture("REFUND", "")
+	for range 2 {
+		if err := s.HandleWebhookEvent(context.Background(), "payload"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(events.events) != 1 {
+		t.Errorf("recorded %d events for one notification, want 1", len(events.events))
+	}
+}
+
+func TestSubscriptionService_UnknownUserStillRecorded(t *testing.T) {
+	s, _, events := newSubscriptionFixture("REFUND", "")
+	s.userRepo = &mockSubscriptionUsers{}
+
+	if err := s.HandleWebhookEvent(context.Background(), "payload"); err != nil {
+		t.Fatal(err)
+	}
+	if len(events.events) != 1 || events.events[0].UserID != nil || events.events[0].Applied {
+		t.Errorf("events = %+v, want one unapplied event without user", events.events)
+	}
+}
+
+func TestSubscriptionService_VerifyAndActivate(t *testing.T) {
+	s, _, events := newSubscriptionFixture("", "")
+	res, err := s.VerifyAndActivate(context.Background(), "user-1", "2000", "com.folio.app.pro.monthly")
+	if err != nil {
+		t.Fatalf("VerifyAndActivate: %v", err)
+	}
+	if res.Subscription != string(domain.SubscriptionPro) {
+		t.Errorf("subscription = %q", res.Subscription)
+	}
+	ev := events.events[0]
+	if ev.NotificationType != subscriptionEventClientVerify || ev.NotificationUUID != nil ||
+		*ev.UserID != "user-1" || *ev.ToState != domain.SubscriptionStateActive {
+		t.Errorf("event = %+v", ev)
+	}
+}
@@ -0,0 +1,54 @@
+package vault
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"folio-server/internal/domain"
+)
+
+// ConversationFileName returns the file name for a conversation exported
+// with WriteConversation.
+func ConversationFileName(c *domain.RAGConversation) string {
+	title := ""
+	if c.Title != nil {
+		title = *c.Title
+	}
+	return safeName(title) + ".md"
+}
+
+// WriteConversation writes a RAG conversation as one Markdown note: each
+// question as a heading, followed by the answer and the articles it cited.
+// msgs carry the answer text as Content and their resolved Sources.
+func WriteConversation(w io.Writer, c *domain.RAGConversation, msgs []domain.RAGMessage) error {
+	var b bytes.Buffer
+	b.WriteString("---\n")
+	writeYAMLField(&b, "title", c.Title)
+	fmt.Fprintf(&b, "created_at: %s\n", c.CreatedAt.UTC().Format(time.RFC3339))
+	b.WriteString("---\n")
+
+	for _, m := range msgs {
+		switch m.Role {
+		case "user":
+			fmt.Fprintf(&b, "\n## %s\n", strings.Join(strings.Fields(m.Content), " "))
+		case "assistant":
+			fmt.Fprintf(&b, "\n%s\n", strings.TrimSpace(m.Content))
+			if len(m.Sources) > 0 {
+				b.WriteString("\nSources:\n\n")
+				for i, s := range m.Sources {
+					fmt.Fprintf(&b, "%d. %s", i+1, s.Title)
+					if s.SiteName != nil && *s.SiteName != "" {
+						fmt.Fprintf(&b, " — %s", *s.SiteName)
+					}
+					b.WriteString("\n")
+				}
+			}
+		}
+	}
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
@@ -0,0 +1,55 @@
+package vault
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"folio-server/internal/domain"
+)
+
+func TestWriteConversation(t *testing.T) {
+	conv := &domain.RAGConversation{
+		Title:     strPtr("Go: concurrency?"),
+		CreatedAt: time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC),
+	}
+	msgs := []domain.RAGMessage{
+		{Role: "user", Content: "Why are\ngoroutines cheap?"},
+		{Role: "assistant", Content: "Small stacks.¹", Sources: []domain.RAGSource{
+			{Title: "Go Scheduler", SiteName: strPtr("go.dev")},
+		}},
+		{Role: "user", Content: "Thanks"},
+		{Role: "assistant", Content: "Welcome."},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteConversation(&buf, conv, msgs); err != nil {
+		t.Fatalf("WriteConversation: %v", err)
+	}
+	want := `---
+title: "Go: concurrency?"
+created_at: 2026-03-01T08:00:00Z
+---
+
+## Why are goroutines cheap?
+
+Small stacks.¹
+
+Sources:
+
+1. Go Scheduler — go.dev
+
+## Thanks
+
+Welcome.
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if name := ConversationFileName(conv); name != "Go concurrency.md" {
+		t.Errorf("file name = %q", name)
+	}
+	if name := ConversationFileName(&domain.RAGConversation{}); name != "Untitled.md" {
+		t.Errorf("untitled file name = %q", name)
+	}
+}
@@ -0,0 +1,313 @@
+// Package vault writes a user's library as a zip of Markdown files that can
+// be opened directly as an Obsidian vault, or read by any Markdown tool.
+//
+// Layout:
+//
+//	Articles/<title>.md   one note per article, YAML front matter + content
+//	Echo Cards.md         all Echo cards as a question/answer deck
+package vault
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"folio-server/internal/domain"
+)
+
+const (
+	articlesDir  = "Articles/"
+	deckFileName = "Echo Cards.md"
+	// maxNameRunes keeps file names well under common 255-byte limits.
+	maxNameRunes = 100
+)
+
+// Writer streams notes into a zip archive. Notes must be added before the
+// deck so that cards can link to their article notes.
+type Writer struct {
+	zw       *zip.Writer
+	lang     string
+	used     map[string]bool   // lower-cased note names already taken
+	notes    map[string]string // article ID → note name
+	articles int
+}
+
+// NewWriter returns a Writer that writes the archive to w. lang selects the
+// category name language ("zh" or anything else for English).
+func NewWriter(w io.Writer, lang string) *Writer {
+	return &Writer{
+		zw:    zip.NewWriter(w),
+		lang:  lang,
+		used:  make(map[string]bool),
+		notes: make(map[string]string),
+	}
+}
+
+// Articles returns how many article notes have been written.
+func (w *Writer) Articles() int {
+	return w.articles
+}
+
+// AddArticle writes one article note. highlights are placed inline as
+// callouts after the paragraph they end in.
+func (w *Writer) AddArticle(a *domain.Article, highlights []domain.Highlight) error {
+	name := w.uniqueName(noteName(a))
+	w.notes[a.ID] = name
+
+	var b bytes.Buffer
+	w.writeFrontMatter(&b, a)
+
+	body := ""
+	if a.MarkdownContent != nil {
+		body = strings.TrimSpace(*a.MarkdownContent)
+	}
+	if body == "" && a.Summary != nil {
+		body = strings.TrimSpace(*a.Summary)
+	}
+	if body == "" && a.URL != nil {
+		body = fmt.Sprintf("<%s>", *a.URL)
+	}
+	b.WriteString(withHighlights(body, highlights))
+	b.WriteString("\n")
+
+	if err := w.writeFile(articlesDir+name+".md", b.Bytes(), a.CreatedAt); err != nil {
+		return err
+	}
+	w.articles++
+	return nil
+}
+
+// AddEchoDeck writes all cards to a single deck note, grouped by article.
+// Cards use the "question / ? / answer" layout understood by Obsidian
+// spaced-repetition plugins and readable as plain Markdown elsewhere; cloze
+// cards ask for their blanked term.
+func (w *Writer) AddEchoDeck(cards []domain.EchoCard) error {
+	if len(cards) == 0 {
+		return nil
+	}
+
+	var order []string
+	groups := make(map[string][]domain.EchoCard)
+	for _, c := range cards {
+		if _, ok := groups[c.ArticleID]; !ok {
+			order = append(order, c.ArticleID)
+		}
+		groups[c.ArticleID] = append(groups[c.ArticleID], c)
+	}
+
+	var b bytes.Buffer
+	b.WriteString("---\ntags:\n  - flashcards\n---\n\n# Echo Cards\n")
+	for _, articleID := range order {
+		group := groups[articleID]
+		if name, ok := w.notes[articleID]; ok {
+			fmt.Fprintf(&b, "\n## [[%s]]\n", name)
+		} else {
+			title := strings.TrimSpace(group[0].ArticleTitle)
+			if title == "" {
+				title = "Untitled"
+			}
+			fmt.Fprintf(&b, "\n## %s\n", title)
+		}
+		for i := range group {
+			c := &group[i]
+			fmt.Fprintf(&b, "\n%s\n?\n%s\n", strings.TrimSpace(c.ClozeQuestion()), strings.TrimSpace(c.Answer))
+		}
+	}
+	return w.writeFile(deckFileName, b.Bytes(), time.Now())
+}
+
+// Close finishes the archive. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	return w.zw.Close()
+}
+
+func (w *Writer) writeFile(name string, data []byte, modified time.Time) error {
+	f, err := w.zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modified,
+	})
+	if err != nil {
+		return fmt.Errorf("create %s: %w", name, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	return nil
+}
+
+func (w *Writer) writeFrontMatter(b *bytes.Buffer, a *domain.Article) {
+	b.WriteString("---\n")
+	writeYAMLField(b, "title", a.Title)
+	writeYAMLField(b, "url", a.URL)
+	writeYAMLField(b, "site", a.SiteName)
+	writeYAMLField(b, "author", a.Author)
+
+	if len(a.Tags) > 0 {
+		b.WriteString("tags:\n")
+		for _, t := range a.Tags {
+			fmt.Fprintf(b, "  - %s\n", yamlString(tagName(t.Name)))
+		}
+	}
+	if a.Category != nil {
+		name := a.Category.NameEN
+		if w.lang == "zh" && a.Category.NameZH != "" {
+			name = a.Category.NameZH
+		}
+		writeYAMLField(b, "category", &name)
+	}
+	writeYAMLField(b, "summary", a.Summary)
+	if len(a.KeyPoints) > 0 {
+		b.WriteString("key_points:\n")
+		for _, kp := range a.KeyPoints {
+			fmt.Fprintf(b, "  - %s\n", yamlString(kp))
+		}
+	}
+	fmt.Fprintf(b, "created_at: %s\n", a.CreatedAt.UTC().Format(time.RFC3339))
+	b.WriteString("---\n\n")
+}
+
+func writeYAMLField(b *bytes.Buffer, key string, v *string) {
+	if v == nil || strings.TrimSpace(*v) == "" {
+		return
+	}
+	fmt.Fprintf(b, "%s: %s\n", key, yamlString(strings.TrimSpace(*v)))
+}
+
+// yamlString quotes s as a YAML double-quoted scalar. JSON string escapes
+// are a subset of YAML's, so a JSON encoding is always valid.
+func yamlString(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// tagName makes a tag usable as an Obsidian tag, which cannot contain spaces.
+func tagName(s string) string {
+	return strings.Join(strings.Fields(s), "-")
+}
+
+// noteName derives a file-system-safe note name from the article title.
+func noteName(a *domain.Article) string {
+	title := ""
+	if a.Title != nil {
+		title = *a.Title
+	}
+	return safeName(title)
+}
+
+// safeName makes title usable as a file name on common file systems.
+func safeName(title string) string {
+	var b strings.Builder
+	for _, r := range title {
+		switch {
+		case strings.ContainsRune(`\/:*?"<>|#^[]`, r):
+			b.WriteRune(' ')
+		case unicode.IsControl(r) || r == utf8.RuneError:
+		default:
+			b.WriteRune(r)
+		}
+	}
+	name := strings.Join(strings.Fields(b.String()), " ")
+	if utf8.RuneCountInString(name) > maxNameRunes {
+		name = strings.TrimSpace(string([]rune(name)[:maxNameRunes]))
+	}
+	name = strings.Trim(name, ". ")
+	if name == "" {
+		name = "Untitled"
+	}
+	return name
+}
+
+// uniqueName suffixes name with " (2)", " (3)", ... until it is unused.
+// Comparison is case-insensitive for case-insensitive file systems.
+func (w *Writer) uniqueName(name string) string {
+	candidate := name
+	for i := 2; w.used[strings.ToLower(candidate)]; i++ {
+		candidate = fmt.Sprintf("%s (%d)", name, i)
+	}
+	w.used[strings.ToLower(candidate)] = true
+	return candidate
+}
+
+// withHighlights inserts a callout for each highlight after the block
+// (paragraph) its end offset falls in. Offsets are rune offsets into md;
+// highlights whose offsets no longer fit are appended at the end.
+func withHighlights(md string, highlights []domain.Highlight) string {
+	if len(highlights) == 0 {
+		return md
+	}
+	hs := append([]domain.Highlight(nil), highlights...)
+	sort.SliceStable(hs, func(i, j int) bool { return hs[i].StartOffset < hs[j].StartOffset })
+
+	runes := []rune(md)
+	inserts := make(map[int][]string)
+	var positions []int
+	for _, h := range hs {
+		pos := len(runes)
+		if h.EndOffset >= 0 && h.EndOffset <= len(runes) {
+			pos = blockEnd(runes, h.EndOffset)
+		}
+		if _, ok := inserts[pos]; !ok {
+			positions = append(positions, pos)
+		}
+		inserts[pos] = append(inserts[pos], callout(h))
+	}
+	sort.Ints(positions)
+
+	var b strings.Builder
+	prev := 0
+	for _, pos := range positions {
+		b.WriteString(string(runes[prev:pos]))
+		for _, c := range inserts[pos] {
+			b.WriteString("\n\n")
+			b.WriteString(c)
+		}
+		prev = pos
+	}
+	b.WriteString(string(runes[prev:]))
+	return b.String()
+}
+
+// blockEnd returns the offset of the blank line ending the block that
+// contains offset, or len(runes) for the last block.
+func blockEnd(runes []rune, offset int) int {
+	for i := offset; i+1 < len(runes); i++ {
+		if runes[i] == '\n' && runes[i+1] == '\n' {
+			return i
+		}
+	}
+	return len(runes)
+}
+
+func callout(h domain.Highlight) string {
+	var b strings.Builder
+	b.WriteString("> [!quote] Highlight")
+	if h.Page != nil {
+		fmt.Fprintf(&b, " (p. %d)", *h.Page)
+	}
+	b.WriteString("\n")
+	writeQuoted(&b, h.Text)
+	if h.Note != nil && strings.TrimSpace(*h.Note) != "" {
+		b.WriteString(">\n")
+		writeQuoted(&b, "**Note:** "+strings.TrimSpace(*h.Note))
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func writeQuoted(b *strings.Builder, text string) {
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		b.WriteString("> ")
+		b.WriteString(strings.TrimRight(line, " \t\r"))
+		b.WriteString("\n")
+	}
+}
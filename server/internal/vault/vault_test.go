@@ -0,0 +1,164 @@
+package vault
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"folio-server/internal/domain"
+)
+
+func strPtr(s string) *string { return &s }
+
+func readZip(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(b)
+	}
+	return files
+}
+
+func TestWriter(t *testing.T) {
+	created := time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC)
+	article := &domain.Article{
+		ID:              "a1",
+		Title:           strPtr(`Go: "Effective" patterns`),
+		URL:             strPtr("https://example.com/go"),
+		SiteName:        strPtr("Example"),
+		Summary:         strPtr("A summary."),
+		KeyPoints:       []string{"one", "two: three"},
+		MarkdownContent: strPtr("First paragraph here.\n\nSecond paragraph.\n\nThird."),
+		CreatedAt:       created,
+		Tags:            []domain.Tag{{Name: "go"}, {Name: "best practices"}},
+		Category:        &domain.Category{NameEN: "Technology", NameZH: "科技"},
+	}
+	page := 2
+	highlights := []domain.Highlight{
+		{Text: "Second", StartOffset: 23, EndOffset: 29, Note: strPtr("remember this")},
+		{Text: "First", StartOffset: 0, EndOffset: 5, Page: &page},
+	}
+	cards := []domain.EchoCard{
+		{ArticleID: "a1", Question: "What?", Answer: "That."},
+		{ArticleID: "gone", ArticleTitle: "Deleted article", Question: "Q2", Answer: "A2"},
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf, "en")
+	if err := w.AddArticle(article, highlights); err != nil {
+		t.Fatalf("AddArticle: %v", err)
+	}
+	// Same title again gets a distinct note name.
+	dup := *article
+	dup.ID = "a2"
+	dup.Tags, dup.Category, dup.KeyPoints = nil, nil, nil
+	if err := w.AddArticle(&dup, nil); err != nil {
+		t.Fatalf("AddArticle dup: %v", err)
+	}
+	if err := w.AddEchoDeck(cards); err != nil {
+		t.Fatalf("AddEchoDeck: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	files := readZip(t, buf.Bytes())
+	note, ok := files["Articles/Go Effective patterns.md"]
+	if !ok {
+		t.Fatalf("missing note; files = %v", keys(files))
+	}
+	if _, ok := files["Articles/Go Effective patterns (2).md"]; !ok {
+		t.Errorf("missing deduplicated note; files = %v", keys(files))
+	}
+
+	wantFront := `---
+title: "Go: \"Effective\" patterns"
+url: "https://example.com/go"
+site: "Example"
+tags:
+  - "go"
+  - "best-practices"
+category: "Technology"
+summary: "A summary."
+key_points:
+  - "one"
+  - "two: three"
+created_at: 2024-02-03T04:05:06Z
+---
+
+`
+	if !strings.HasPrefix(note, wantFront) {
+		t.Errorf("front matter:\n%s", note)
+	}
+
+	wantBody := `First paragraph here.
+
+> [!quote] Highlight (p. 2)
+> First
+
+Second paragraph.
+
+> [!quote] Highlight
+> Second
+>
+> **Note:** remember this
+
+Third.
+`
+	if body := strings.TrimPrefix(note, wantFront); body != wantBody {
+		t.Errorf("body:\n%s\nwant:\n%s", body, wantBody)
+	}
+
+	deck := files["Echo Cards.md"]
+	for _, want := range []string{"## [[Go Effective patterns]]\n\nWhat?\n?\nThat.\n", "## Deleted article\n\nQ2\n?\nA2\n"} {
+		if !strings.Contains(deck, want) {
+			t.Errorf("deck missing %q:\n%s", want, deck)
+		}
+	}
+}
+
+func TestWithHighlights_OutOfRange(t *testing.T) {
+	got := withHighlights("Short.", []domain.Highlight{{Text: "gone", StartOffset: 50, EndOffset: 60}})
+	want := "Short.\n\n> [!quote] Highlight\n> gone"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestNoteName(t *testing.T) {
+	tests := []struct {
+		title *string
+		want  string
+	}{
+		{nil, "Untitled"},
+		{strPtr("  ...  "), "Untitled"},
+		{strPtr("a/b\\c#d"), "a b c d"},
+		{strPtr(strings.Repeat("长", 150)), strings.Repeat("长", maxNameRunes)},
+	}
+	for _, tt := range tests {
+		if got := noteName(&domain.Article{Title: tt.title}); got != tt.want {
+			t.Errorf("noteName(%v) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func keys(m map[string]string) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
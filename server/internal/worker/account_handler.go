@@ -0,0 +1,46 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/hibiken/asynq"
+)
+
+// prefixDeleter removes every stored object under a key prefix.
+type prefixDeleter interface {
+	DeletePrefix(ctx context.Context, prefix string) (int, error)
+}
+
+// AccountPurgeHandler processes account:purge tasks, removing a deleted
+// user's images, mail attachments, PDF originals and exports from storage.
+type AccountPurgeHandler struct {
+	storage prefixDeleter
+}
+
+func NewAccountPurgeHandler(storage prefixDeleter) *AccountPurgeHandler {
+	return &AccountPurgeHandler{storage: storage}
+}
+
+// ProcessTask handles account:purge. Deleting is idempotent, so a retry
+// simply starts over from the first prefix.
+func (h *AccountPurgeHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
+	var p AccountPurgePayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("unmarshal payload: %w", err)
+	}
+
+	total := 0
+	for _, prefix := range p.Prefixes {
+		n, err := h.storage.DeletePrefix(ctx, prefix)
+		total += n
+		if err != nil {
+			return fmt.Errorf("purge %s: %w", prefix, err)
+		}
+	}
+
+	slog.Info("account storage purged", "user_id", p.UserID, "prefixes", len(p.Prefixes), "objects", total)
+	return nil
+}
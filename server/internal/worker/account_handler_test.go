@@ -0,0 +1,44 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/hibiken/asynq"
+)
+
+type mockPrefixDeleter struct {
+	purged []string
+	failOn string
+}
+
+func (m *mockPrefixDeleter) DeletePrefix(_ context.Context, prefix string) (int, error) {
+	if prefix == m.failOn {
+		return 0, errors.New("r2 unavailable")
+	}
+	m.purged = append(m.purged, prefix)
+	return 3, nil
+}
+
+func TestAccountPurgeHandler(t *testing.T) {
+	prefixes := []string{"mail/user-1/", "pdf/user-1/", "articles/a1/images/"}
+	payload, _ := json.Marshal(AccountPurgePayload{UserID: "user-1", Prefixes: prefixes})
+	task := asynq.NewTask(TypeAccountPurge, payload)
+
+	storage := &mockPrefixDeleter{failOn: "pdf/user-1/"}
+	h := NewAccountPurgeHandler(storage)
+	if err := h.ProcessTask(context.Background(), task); err == nil {
+		t.Fatal("expected error so the task is retried")
+	}
+
+	storage.failOn = ""
+	storage.purged = nil
+	if err := h.ProcessTask(context.Background(), task); err != nil {
+		t.Fatalf("ProcessTask: %v", err)
+	}
+	if len(storage.purged) != len(prefixes) {
+		t.Errorf("purged %v, want %v", storage.purged, prefixes)
+	}
+}
@@ -200,10 +200,10 @@ func (h *AIHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
 		}
 	}
 
-	// Enqueue related article computation (non-blocking)
-	relateTask := NewRelateTask(p.ArticleID, p.UserID)
-	if _, err := h.asynqClient.EnqueueContext(ctx, relateTask); err != nil {
-		slog.Error("[RELATE] failed to enqueue for article",
+	// Enqueue embedding (non-blocking); it enqueues related article computation
+	embedTask := NewEmbedTask(p.ArticleID, p.UserID)
+	if _, err := h.asynqClient.EnqueueContext(ctx, embedTask); err != nil {
+		slog.Error("[EMBED] failed to enqueue for article",
 			"article_id", p.ArticleID,
 			"error", err,
 		)
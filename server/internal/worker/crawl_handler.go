@@ -104,7 +104,7 @@ func (h *CrawlHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
 				title, *preCheckArticle.MarkdownContent,
 				string(preCheckArticle.SourceType), derefOrEmpty(preCheckArticle.Author),
 			)
-			if _, enqErr := h.asynqClient.EnqueueContext(ctx, aiTask); enqErr != nil {
+			if enqErr := h.enqueueAI(ctx, p, aiTask); enqErr != nil {
 				return fmt.Errorf("enqueue ai task (screenshot/voice): %w", enqErr)
 			}
 			return nil
@@ -154,7 +154,7 @@ func (h *CrawlHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
 				derefOrEmpty(cached.Title), derefOrEmpty(cached.MarkdownContent),
 				source, derefOrEmpty(cached.Author),
 			)
-			if _, enqErr := h.asynqClient.EnqueueContext(ctx, aiTask); enqErr != nil {
+			if enqErr := h.enqueueAI(ctx, p, aiTask); enqErr != nil {
 				return fmt.Errorf("enqueue ai task (cache partial): %w", enqErr)
 			}
 			slog.Info("crawl task using cached content (partial, needs AI)",
@@ -181,7 +181,7 @@ func (h *CrawlHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
 			derefOrEmpty(article.Title), *article.MarkdownContent,
 			source, derefOrEmpty(article.Author),
 		)
-		if _, enqErr := h.asynqClient.EnqueueContext(ctx, aiTask); enqErr != nil {
+		if enqErr := h.enqueueAI(ctx, p, aiTask); enqErr != nil {
 			return fmt.Errorf("enqueue ai task (client content): %w", enqErr)
 		}
 		return nil
@@ -249,7 +249,7 @@ func (h *CrawlHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
 		title, markdown,
 		source, result.Metadata.Author,
 	)
-	if _, err := h.asynqClient.EnqueueContext(ctx, aiTask); err != nil {
+	if err := h.enqueueAI(ctx, p, aiTask); err != nil {
 		return fmt.Errorf("enqueue ai task: %w", err)
 	}
 
@@ -271,6 +271,23 @@ func (h *CrawlHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
 	return nil
 }
 
+// enqueueAI hands the crawled article to article:ai. Articles crawled with
+// SkipAI are marked ready and their task done here instead.
+func (h *CrawlHandler) enqueueAI(ctx context.Context, p CrawlPayload, aiTask *asynq.Task) error {
+	if !p.SkipAI {
+		_, err := h.asynqClient.EnqueueContext(ctx, aiTask)
+		return err
+	}
+	if err := h.articleRepo.UpdateStatus(ctx, p.ArticleID, domain.ArticleStatusReady); err != nil {
+		return fmt.Errorf("update article status: %w", err)
+	}
+	if err := h.taskRepo.SetAIFinished(ctx, p.TaskID); err != nil {
+		return fmt.Errorf("set task done: %w", err)
+	}
+	slog.Info("crawl task finished without AI", "article_id", p.ArticleID)
+	return nil
+}
+
 // applyCacheHit handles the full cache hit: copies content + AI results to the article,
 // creates user-specific tags, and marks the task as done.
 func (h *CrawlHandler) applyCacheHit(ctx context.Context, p CrawlPayload, cached *domain.ContentCache, start time.Time) error {
@@ -602,6 +602,39 @@ func newCrawlAsynqTask(articleID, taskID, url, userID string) *asynq.Task {
 
 // --- ProcessTask integration tests ---
 
+func TestProcessTask_SkipAI_FinishesAfterCrawl(t *testing.T) {
+	mockReader := &mockScraper{
+		scrapeFn: func(ctx context.Context, url string) (*client.ScrapeResponse, error) {
+			return &client.ScrapeResponse{Markdown: "Body text.", Metadata: client.ReaderMetadata{Title: "Title"}}, nil
+		},
+	}
+	mockArtRepo := &mockCrawlArticleRepo{}
+	mockTaskRepo := &mockCrawlTaskRepo{}
+	mockEnq := &mockCrawlEnqueuer{}
+	h := newTestCrawlHandler(mockReader, mockArtRepo, mockTaskRepo, mockEnq, false)
+
+	task := NewCrawlTaskWithoutAI("art-1", "task-1", "https://example.com/article", "user-1")
+	if err := h.ProcessTask(context.Background(), task); err != nil {
+		t.Fatalf("ProcessTask returned error: %v", err)
+	}
+
+	if len(mockArtRepo.updateCrawlCalls) != 1 {
+		t.Errorf("UpdateCrawlResult calls = %d, want 1", len(mockArtRepo.updateCrawlCalls))
+	}
+	for _, task := range mockEnq.enqueuedTasks {
+		if task.Type() == TypeAIProcess {
+			t.Error("AI task enqueued for an article crawled without AI")
+		}
+	}
+	last := mockArtRepo.updateStatusCalls[len(mockArtRepo.updateStatusCalls)-1]
+	if last.Status != domain.ArticleStatusReady {
+		t.Errorf("final article status = %q, want ready", last.Status)
+	}
+	if len(mockTaskRepo.setAIFinishedCalls) != 1 || mockTaskRepo.setAIFinishedCalls[0] != "task-1" {
+		t.Errorf("SetAIFinished calls = %v, want [task-1]", mockTaskRepo.setAIFinishedCalls)
+	}
+}
+
 func TestProcessTask_ScrapeSuccess_NormalFlow(t *testing.T) {
 	scrapeResp := &client.ScrapeResponse{
 		Markdown: "# Scraped Content\n\nBody text here.",
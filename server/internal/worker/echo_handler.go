@@ -6,35 +6,52 @@ import (
 	"fmt"
 	"log/slog"
 	"time"
+	"unicode/utf8"
 
 	"github.com/hibiken/asynq"
 
 	"folio-server/internal/client"
 	"folio-server/internal/domain"
+	"folio-server/internal/repository"
 )
 
 // echoCardRepo abstracts the echo repository methods used by EchoHandler.
 type echoCardRepo interface {
 	CountCardsByArticle(ctx context.Context, articleID string) (int, error)
 	CreateCard(ctx context.Context, card *domain.EchoCard) error
+	CountCardsByTypeSince(ctx context.Context, userID string, cardType domain.EchoCardType, since time.Time) (int, error)
+	HasRelatedCard(ctx context.Context, userID, articleID, relatedArticleID string) (bool, error)
+	CreateRelatedCard(ctx context.Context, card *domain.EchoCard, limit int, since time.Time) (bool, error)
 }
 
-// echoCardGenerator abstracts the AI method for generating echo cards.
+// echoCardGenerator abstracts the AI methods for generating echo cards.
 type echoCardGenerator interface {
 	GenerateEchoCards(ctx context.Context, title string, source string, keyPoints []string) ([]client.EchoQAPair, error)
+	GenerateClozeTerms(ctx context.Context, title, passage string) ([]string, error)
+	GenerateRelatedEchoCard(ctx context.Context, a, b client.EchoArticleBrief, reason string) (*client.EchoQAPair, error)
 }
 
+// clozeMinPassageRunes is the shortest highlight worth making cloze cards
+// from; shorter ones get a single highlight card.
+const clozeMinPassageRunes = 12
+
 // echoHighlightRepo abstracts the highlight repository methods used by EchoHandler.
 type echoHighlightRepo interface {
 	GetByID(ctx context.Context, id, userID string) (*domain.Highlight, error)
 }
 
-// EchoHandler processes echo:generate tasks.
+// echoRelationRepo abstracts the relation repository methods used by EchoHandler.
+type echoRelationRepo interface {
+	ListBySource(ctx context.Context, userID, sourceID string) ([]repository.RelatedArticleRow, error)
+}
+
+// EchoHandler processes echo:generate and echo:related tasks.
 type EchoHandler struct {
 	aiClient      echoCardGenerator
 	articleRepo   ArticleGetter
 	echoRepo      echoCardRepo
 	highlightRepo echoHighlightRepo
+	relationRepo  echoRelationRepo
 }
 
 // NewEchoHandler creates an EchoHandler.
@@ -43,12 +60,14 @@ func NewEchoHandler(
 	articleRepo ArticleGetter,
 	echoRepo echoCardRepo,
 	highlightRepo echoHighlightRepo,
+	relationRepo echoRelationRepo,
 ) *EchoHandler {
 	return &EchoHandler{
 		aiClient:      aiClient,
 		articleRepo:   articleRepo,
 		echoRepo:      echoRepo,
 		highlightRepo: highlightRepo,
+		relationRepo:  relationRepo,
 	}
 }
 
@@ -61,8 +80,8 @@ func (h *EchoHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
 
 	start := time.Now()
 
-	// Highlight card path: when a highlightID is provided, generate a single
-	// cloze-recall card for that specific highlight instead of insight cards.
+	// Highlight card path: when a highlightID is provided, generate cloze
+	// cards (or a single recall card) for that highlight instead of insight cards.
 	if p.HighlightID != "" {
 		return h.processHighlightCard(ctx, p, start)
 	}
@@ -71,7 +90,9 @@ func (h *EchoHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
 	return h.processInsightCards(ctx, p, start)
 }
 
-// processHighlightCard generates a single highlight-type Echo card.
+// processHighlightCard generates cloze cards for a highlight, one per key
+// term the AI picks, falling back to a single highlight-type card that asks
+// for the whole passage.
 func (h *EchoHandler) processHighlightCard(ctx context.Context, p EchoPayload, start time.Time) error {
 	// Fetch the highlight (verifies ownership).
 	highlight, err := h.highlightRepo.GetByID(ctx, p.HighlightID, p.UserID)
@@ -97,10 +118,23 @@ func (h *EchoHandler) processHighlightCard(ctx context.Context, p EchoPayload, s
 	}
 
 	articleTitle := derefOrEmpty(article.Title)
+	sourceContext := fmt.Sprintf("你标注了这段文字 · 来自《%s》", articleTitle)
+
+	if utf8.RuneCountInString(highlight.Text) >= clozeMinPassageRunes {
+		terms, err := h.aiClient.GenerateClozeTerms(ctx, articleTitle, highlight.Text)
+		if err != nil {
+			slog.Warn("echo task: cloze terms failed, creating highlight card",
+				"highlight_id", p.HighlightID,
+				"error", err,
+			)
+		}
+		if len(terms) > 0 {
+			return h.createClozeCards(ctx, p, highlight, sourceContext, terms, start)
+		}
+	}
 
 	question := fmt.Sprintf("你在《%s》中标注了一句话。还记得你标注的原文是什么吗？", articleTitle)
 	answer := highlight.Text
-	sourceContext := fmt.Sprintf("你标注了这段文字 · 来自《%s》", articleTitle)
 
 	card := &domain.EchoCard{
 		UserID:        p.UserID,
@@ -112,7 +146,6 @@ func (h *EchoHandler) processHighlightCard(ctx context.Context, p EchoPayload, s
 		HighlightID:   &highlight.ID,
 		NextReviewAt:  time.Now().Add(24 * time.Hour),
 		IntervalDays:  1,
-		EaseFactor:    2.50,
 	}
 
 	if err := h.echoRepo.CreateCard(ctx, card); err != nil {
@@ -128,6 +161,46 @@ func (h *EchoHandler) processHighlightCard(ctx context.Context, p EchoPayload, s
 	return nil
 }
 
+// createClozeCards creates one cloze card per term as siblings: their first
+// reviews are a day apart, and GetDueCards never shows two of them on the
+// same day.
+func (h *EchoHandler) createClozeCards(ctx context.Context, p EchoPayload, highlight *domain.Highlight,
+	sourceContext string, terms []string, start time.Time) error {
+	created := 0
+	for _, term := range terms {
+		clozeStart, clozeEnd, ok := domain.ClozeOffsets(highlight.Text, term)
+		if !ok {
+			continue
+		}
+		card := &domain.EchoCard{
+			UserID:        p.UserID,
+			ArticleID:     p.ArticleID,
+			CardType:      domain.EchoCardCloze,
+			Question:      highlight.Text,
+			Answer:        term,
+			SourceContext: &sourceContext,
+			HighlightID:   &highlight.ID,
+			ClozeStart:    &clozeStart,
+			ClozeEnd:      &clozeEnd,
+			SiblingGroup:  &highlight.ID,
+			NextReviewAt:  time.Now().Add(time.Duration(created+1) * 24 * time.Hour),
+			IntervalDays:  1,
+		}
+		if err := h.echoRepo.CreateCard(ctx, card); err != nil {
+			return fmt.Errorf("create cloze echo card: %w", err)
+		}
+		created++
+	}
+
+	slog.Info("echo cloze cards created",
+		"article_id", p.ArticleID,
+		"highlight_id", p.HighlightID,
+		"count", created,
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+	return nil
+}
+
 // processInsightCards generates insight-type Echo cards from article key points.
 func (h *EchoHandler) processInsightCards(ctx context.Context, p EchoPayload, start time.Time) error {
 	// Fetch article
@@ -163,7 +236,7 @@ func (h *EchoHandler) processInsightCards(ctx context.Context, p EchoPayload, st
 	title := derefOrEmpty(article.Title)
 	source := derefOrDefault(article.SiteName, "web")
 
-	// Generate Q&A pairs via DeepSeek (or mock)
+	// Generate Q&A pairs via the LLM (or mock)
 	pairs, err := h.aiClient.GenerateEchoCards(ctx, title, source, article.KeyPoints)
 	if err != nil {
 		slog.Error("echo task: generate cards failed",
@@ -189,7 +262,6 @@ func (h *EchoHandler) processInsightCards(ctx context.Context, p EchoPayload, st
 			SourceContext: &qa.SourceContext,
 			NextReviewAt:  time.Now().Add(24 * time.Hour),
 			IntervalDays:  1,
-			EaseFactor:    2.50,
 		}
 		if err := h.echoRepo.CreateCard(ctx, card); err != nil {
 			slog.Error("echo task: create card failed",
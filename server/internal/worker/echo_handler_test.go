@@ -0,0 +1,148 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"folio-server/internal/client"
+	"folio-server/internal/domain"
+)
+
+type mockEchoCardRepo struct {
+	mu           sync.Mutex
+	created      []*domain.EchoCard
+	relatedSince int             // related cards already made this week
+	related      map[string]bool // "articleID|relatedArticleID" pairs with a card
+}
+
+func (m *mockEchoCardRepo) CountCardsByArticle(_ context.Context, _ string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.created), nil
+}
+
+func (m *mockEchoCardRepo) CreateCard(_ context.Context, card *domain.EchoCard) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.created = append(m.created, card)
+	return nil
+}
+
+func (m *mockEchoCardRepo) CountCardsByTypeSince(_ context.Context, _ string, _ domain.EchoCardType, _ time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.relatedSince, nil
+}
+
+func (m *mockEchoCardRepo) HasRelatedCard(_ context.Context, _, a, b string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.related[a+"|"+b] || m.related[b+"|"+a], nil
+}
+
+func (m *mockEchoCardRepo) CreateRelatedCard(_ context.Context, card *domain.EchoCard, limit int, _ time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	a, b := card.ArticleID, *card.RelatedArticleID
+	if m.relatedSince+len(m.created) >= limit || m.related[a+"|"+b] || m.related[b+"|"+a] {
+		return false, nil
+	}
+	if m.related == nil {
+		m.related = map[string]bool{}
+	}
+	m.related[a+"|"+b] = true
+	m.created = append(m.created, card)
+	return true, nil
+}
+
+type mockEchoGenerator struct {
+	mu       sync.Mutex
+	terms    []string
+	termsErr error
+	pairs    int // related cards generated
+}
+
+func (m *mockEchoGenerator) GenerateEchoCards(_ context.Context, _, _ string, _ []string) ([]client.EchoQAPair, error) {
+	return nil, nil
+}
+
+func (m *mockEchoGenerator) GenerateClozeTerms(_ context.Context, _, _ string) ([]string, error) {
+	return m.terms, m.termsErr
+}
+
+func (m *mockEchoGenerator) GenerateRelatedEchoCard(_ context.Context, a, b client.EchoArticleBrief, _ string) (*client.EchoQAPair, error) {
+	m.mu.Lock()
+	m.pairs++
+	m.mu.Unlock()
+	return &client.EchoQAPair{Question: a.Title + " vs " + b.Title, Answer: "answer"}, nil
+}
+
+type mockEchoHighlights struct{ highlight *domain.Highlight }
+
+func (m *mockEchoHighlights) GetByID(_ context.Context, _, _ string) (*domain.Highlight, error) {
+	return m.highlight, nil
+}
+
+type mockEchoArticles struct{}
+
+func (mockEchoArticles) GetByID(_ context.Context, id string) (*domain.Article, error) {
+	title := "学习之道 " + id
+	return &domain.Article{ID: id, UserID: "user-1", Title: &title}, nil
+}
+
+func runHighlightCard(t *testing.T, gen *mockEchoGenerator, text string) []*domain.EchoCard {
+	t.Helper()
+	repo := &mockEchoCardRepo{}
+	h := NewEchoHandler(gen, mockEchoArticles{}, repo,
+		&mockEchoHighlights{highlight: &domain.Highlight{ID: "hl-1", ArticleID: "article-1", Text: text}}, nil)
+
+	task, err := NewEchoTask("article-1", "user-1", "hl-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.ProcessTask(context.Background(), task); err != nil {
+		t.Fatalf("ProcessTask: %v", err)
+	}
+	return repo.created
+}
+
+func TestEchoHandler_HighlightClozeSiblings(t *testing.T) {
+	text := "费曼学习法要求用简单的语言向别人解释一个概念"
+	cards := runHighlightCard(t, &mockEchoGenerator{terms: []string{"费曼学习法", "简单的语言"}}, text)
+
+	if len(cards) != 2 {
+		t.Fatalf("created %d cards, want 2", len(cards))
+	}
+	for i, c := range cards {
+		if c.CardType != domain.EchoCardCloze || c.Question != text || c.SiblingGroup == nil || *c.SiblingGroup != "hl-1" {
+			t.Errorf("card %d = %+v", i, c)
+		}
+		if got := []rune(c.Question)[*c.ClozeStart:*c.ClozeEnd]; string(got) != c.Answer {
+			t.Errorf("card %d offsets cover %q, want %q", i, string(got), c.Answer)
+		}
+	}
+	if gap := cards[1].NextReviewAt.Sub(cards[0].NextReviewAt); gap < 23*time.Hour {
+		t.Errorf("siblings first due %v apart, want a day", gap)
+	}
+}
+
+func TestEchoHandler_HighlightFallsBackToRecallCard(t *testing.T) {
+	text := "费曼学习法要求用简单的语言向别人解释一个概念"
+	for name, gen := range map[string]*mockEchoGenerator{
+		"no terms":  {},
+		"ai failed": {termsErr: errors.New("llm down")},
+	} {
+		cards := runHighlightCard(t, gen, text)
+		if len(cards) != 1 || cards[0].CardType != domain.EchoCardHighlight || cards[0].Answer != text {
+			t.Errorf("%s: created %+v, want one highlight card", name, cards)
+		}
+	}
+
+	cards := runHighlightCard(t, &mockEchoGenerator{terms: []string{"短句"}}, "一个短句")
+	if len(cards) != 1 || cards[0].CardType != domain.EchoCardHighlight {
+		t.Errorf("short highlight: created %+v, want one highlight card", cards)
+	}
+}
@@ -0,0 +1,144 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/hibiken/asynq"
+
+	"folio-server/internal/domain"
+	"folio-server/internal/fsrs"
+)
+
+const (
+	// echoOptimizeMinReviews is how many reviews a user needs before their
+	// weights are fitted; with fewer the defaults predict better.
+	echoOptimizeMinReviews = 100
+	// echoOptimizeInterval is how often a user's weights are refitted.
+	echoOptimizeInterval = 7 * 24 * time.Hour
+	// echoOptimizeBatch caps how many users one schedule run fans out.
+	echoOptimizeBatch = 500
+)
+
+// echoOptimizeRepo abstracts the echo repository methods used by
+// EchoOptimizeHandler.
+type echoOptimizeRepo interface {
+	ListUsersDueForOptimization(ctx context.Context, minReviews int, staleBefore time.Time, limit int) ([]string, error)
+	ListReviewHistory(ctx context.Context, userID string) ([]domain.EchoReview, error)
+	GetSchedulerParams(ctx context.Context, userID string) (*domain.EchoSchedulerParams, error)
+	SaveSchedulerParams(ctx context.Context, p *domain.EchoSchedulerParams) error
+}
+
+// EchoOptimizeHandler fits each user's FSRS weights to their Echo review
+// history so that intervals follow how fast they actually forget.
+type EchoOptimizeHandler struct {
+	echoRepo echoOptimizeRepo
+	enqueuer Enqueuer
+}
+
+func NewEchoOptimizeHandler(echoRepo echoOptimizeRepo, enqueuer Enqueuer) *EchoOptimizeHandler {
+	return &EchoOptimizeHandler{echoRepo: echoRepo, enqueuer: enqueuer}
+}
+
+// ProcessSchedule handles the periodic echo:optimize-schedule task by
+// enqueueing an echo:optimize task for every user that is due.
+func (h *EchoOptimizeHandler) ProcessSchedule(ctx context.Context, _ *asynq.Task) error {
+	userIDs, err := h.echoRepo.ListUsersDueForOptimization(ctx, echoOptimizeMinReviews,
+		time.Now().Add(-echoOptimizeInterval), echoOptimizeBatch)
+	if err != nil {
+		return fmt.Errorf("list users due for optimization: %w", err)
+	}
+
+	enqueued := 0
+	for _, id := range userIDs {
+		if _, err := h.enqueuer.EnqueueContext(ctx, NewEchoOptimizeTask(id)); err != nil {
+			// ErrDuplicateTask means an optimization for this user is already pending.
+			slog.Debug("echo:optimize-schedule — enqueue skipped", "user_id", id, "error", err)
+			continue
+		}
+		enqueued++
+	}
+
+	slog.Info("echo:optimize-schedule completed", "due", len(userIDs), "enqueued", enqueued)
+	return nil
+}
+
+// ProcessTask handles echo:optimize for a single user. The fit starts from
+// the user's current weights and is only saved if it predicts their history
+// better than the defaults do.
+func (h *EchoOptimizeHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
+	var p EchoOptimizePayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("unmarshal payload: %w", err)
+	}
+	start := time.Now()
+
+	reviews, err := h.echoRepo.ListReviewHistory(ctx, p.UserID)
+	if err != nil {
+		return fmt.Errorf("list review history: %w", err)
+	}
+	if len(reviews) < echoOptimizeMinReviews {
+		slog.Debug("echo:optimize — not enough reviews, skipping", "user_id", p.UserID, "reviews", len(reviews))
+		return nil
+	}
+
+	initial := fsrs.DefaultWeights
+	current, err := h.echoRepo.GetSchedulerParams(ctx, p.UserID)
+	if err != nil {
+		return fmt.Errorf("get scheduler params: %w", err)
+	}
+	if current != nil {
+		if w, ok := fsrs.WeightsFromSlice(current.Weights); ok {
+			initial = w
+		}
+	}
+
+	histories := reviewHistories(reviews)
+	fitted := fsrs.Optimize(histories, initial)
+	loss := fsrs.LogLoss(fitted, histories)
+	if defaultLoss := fsrs.LogLoss(fsrs.DefaultWeights, histories); defaultLoss <= loss {
+		fitted, loss = fsrs.DefaultWeights, defaultLoss
+	}
+
+	if err := h.echoRepo.SaveSchedulerParams(ctx, &domain.EchoSchedulerParams{
+		UserID:      p.UserID,
+		Weights:     fitted[:],
+		ReviewCount: len(reviews),
+		LogLoss:     loss,
+	}); err != nil {
+		return fmt.Errorf("save scheduler params: %w", err)
+	}
+
+	slog.Info("echo:optimize completed",
+		"user_id", p.UserID,
+		"reviews", len(reviews),
+		"log_loss", loss,
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+	return nil
+}
+
+// reviewHistories groups reviews, which must be ordered by card and then by
+// time, into one FSRS history per card.
+func reviewHistories(reviews []domain.EchoReview) [][]fsrs.Review {
+	var histories [][]fsrs.Review
+	var cardID string
+	var last time.Time
+	for _, rv := range reviews {
+		if len(histories) == 0 || rv.CardID != cardID {
+			histories = append(histories, nil)
+			cardID = rv.CardID
+			last = rv.ReviewedAt
+		}
+		h := &histories[len(histories)-1]
+		*h = append(*h, fsrs.Review{
+			Rating:      fsrs.Rating(rv.Rating),
+			ElapsedDays: rv.ReviewedAt.Sub(last).Hours() / 24,
+		})
+		last = rv.ReviewedAt
+	}
+	return histories
+}
@@ -0,0 +1,134 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+
+	"folio-server/internal/domain"
+	"folio-server/internal/fsrs"
+)
+
+type mockEchoOptimizeRepo struct {
+	due     []string
+	reviews []domain.EchoReview
+	current *domain.EchoSchedulerParams
+	saved   *domain.EchoSchedulerParams
+}
+
+func (m *mockEchoOptimizeRepo) ListUsersDueForOptimization(_ context.Context, _ int, _ time.Time, _ int) ([]string, error) {
+	return m.due, nil
+}
+
+func (m *mockEchoOptimizeRepo) ListReviewHistory(_ context.Context, _ string) ([]domain.EchoReview, error) {
+	return m.reviews, nil
+}
+
+func (m *mockEchoOptimizeRepo) GetSchedulerParams(_ context.Context, _ string) (*domain.EchoSchedulerParams, error) {
+	return m.current, nil
+}
+
+func (m *mockEchoOptimizeRepo) SaveSchedulerParams(_ context.Context, p *domain.EchoSchedulerParams) error {
+	m.saved = p
+	return nil
+}
+
+// forgetfulReviews returns reviews of cards that are recalled on the first
+// review and forgotten on every review after, three days apart.
+func forgetfulReviews(cards, perCard int) []domain.EchoReview {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var reviews []domain.EchoReview
+	for c := 0; c < cards; c++ {
+		for i := 0; i < perCard; i++ {
+			rating := domain.EchoAgain
+			if i == 0 {
+				rating = domain.EchoGood
+			}
+			reviews = append(reviews, domain.EchoReview{
+				CardID:     fmt.Sprintf("card-%d", c),
+				Rating:     rating,
+				ReviewedAt: start.AddDate(0, 0, 3*i),
+			})
+		}
+	}
+	return reviews
+}
+
+func optimizeTask(t *testing.T, userID string) *asynq.Task {
+	t.Helper()
+	payload, err := json.Marshal(EchoOptimizePayload{UserID: userID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return asynq.NewTask(TypeEchoOptimize, payload)
+}
+
+func TestEchoOptimizeHandler_FitsHistory(t *testing.T) {
+	repo := &mockEchoOptimizeRepo{reviews: forgetfulReviews(40, 3)}
+	h := NewEchoOptimizeHandler(repo, &mockImportEnqueuer{})
+
+	if err := h.ProcessTask(context.Background(), optimizeTask(t, "user-1")); err != nil {
+		t.Fatalf("ProcessTask: %v", err)
+	}
+	if repo.saved == nil {
+		t.Fatal("weights not saved")
+	}
+	if repo.saved.UserID != "user-1" || repo.saved.ReviewCount != 120 {
+		t.Errorf("saved %+v", repo.saved)
+	}
+	histories := reviewHistories(repo.reviews)
+	if repo.saved.LogLoss >= fsrs.LogLoss(fsrs.DefaultWeights, histories) {
+		t.Errorf("log loss %v not below the defaults'", repo.saved.LogLoss)
+	}
+	if _, ok := fsrs.WeightsFromSlice(repo.saved.Weights); !ok {
+		t.Errorf("saved %d weights", len(repo.saved.Weights))
+	}
+}
+
+func TestEchoOptimizeHandler_SkipsShortHistory(t *testing.T) {
+	repo := &mockEchoOptimizeRepo{reviews: forgetfulReviews(10, 3)}
+	h := NewEchoOptimizeHandler(repo, &mockImportEnqueuer{})
+
+	if err := h.ProcessTask(context.Background(), optimizeTask(t, "user-1")); err != nil {
+		t.Fatalf("ProcessTask: %v", err)
+	}
+	if repo.saved != nil {
+		t.Errorf("weights saved from %d reviews", len(repo.reviews))
+	}
+}
+
+func TestEchoOptimizeHandler_ProcessSchedule(t *testing.T) {
+	enq := &mockImportEnqueuer{}
+	h := NewEchoOptimizeHandler(&mockEchoOptimizeRepo{due: []string{"user-1", "user-2"}}, enq)
+
+	if err := h.ProcessSchedule(context.Background(), NewEchoOptimizeScheduleTask()); err != nil {
+		t.Fatalf("ProcessSchedule: %v", err)
+	}
+	if len(enq.tasks) != 2 {
+		t.Fatalf("enqueued %d tasks, want 2", len(enq.tasks))
+	}
+	var p EchoOptimizePayload
+	if err := json.Unmarshal(enq.tasks[1].Payload(), &p); err != nil || p.UserID != "user-2" {
+		t.Errorf("payload %s", enq.tasks[1].Payload())
+	}
+}
+
+func TestReviewHistories(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC) }
+	got := reviewHistories([]domain.EchoReview{
+		{CardID: "a", Rating: domain.EchoGood, ReviewedAt: day(1)},
+		{CardID: "a", Rating: domain.EchoHard, ReviewedAt: day(4)},
+		{CardID: "b", Rating: domain.EchoAgain, ReviewedAt: day(2)},
+	})
+	want := [][]fsrs.Review{
+		{{Rating: fsrs.Good}, {Rating: fsrs.Hard, ElapsedDays: 3}},
+		{{Rating: fsrs.Again}},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("histories = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,134 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/hibiken/asynq"
+
+	"folio-server/internal/client"
+	"folio-server/internal/domain"
+)
+
+const (
+	// echoRelatedWeeklyLimit caps how many related cards a user gets per week
+	// (from Monday 00:00 UTC).
+	echoRelatedWeeklyLimit = 5
+	// echoRelatedPerArticle caps how many related cards one relate run makes.
+	echoRelatedPerArticle = 2
+)
+
+// ProcessRelated handles echo:related: it turns an article's strongest
+// relations into related cards with a synthesis question connecting the two
+// articles. Pairs that already have a card, in either direction, are skipped.
+// The checks below avoid needless generation; the weekly limit and pair
+// uniqueness are enforced when the card is stored.
+func (h *EchoHandler) ProcessRelated(ctx context.Context, t *asynq.Task) error {
+	var p RelatePayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("unmarshal echo related payload: %w", err)
+	}
+	start := time.Now()
+
+	made, err := h.echoRepo.CountCardsByTypeSince(ctx, p.UserID, domain.EchoCardRelated, weekStart(start))
+	if err != nil {
+		return fmt.Errorf("count related cards: %w", err)
+	}
+	budget := min(echoRelatedWeeklyLimit-made, echoRelatedPerArticle)
+	if budget <= 0 {
+		slog.Debug("echo:related — weekly limit reached, skipping", "user_id", p.UserID)
+		return nil
+	}
+
+	article, err := h.articleRepo.GetByID(ctx, p.ArticleID)
+	if err != nil {
+		return fmt.Errorf("get article for related echo: %w", err)
+	}
+	if article == nil || article.UserID != p.UserID {
+		slog.Warn("echo:related — article not found, skipping", "article_id", p.ArticleID)
+		return nil
+	}
+	relations, err := h.relationRepo.ListBySource(ctx, p.UserID, p.ArticleID)
+	if err != nil {
+		return fmt.Errorf("list relations: %w", err)
+	}
+
+	source := articleBrief(article)
+	created := 0
+	for _, rel := range relations {
+		if created == budget {
+			break
+		}
+		exists, err := h.echoRepo.HasRelatedCard(ctx, p.UserID, p.ArticleID, rel.ID)
+		if err != nil {
+			return fmt.Errorf("check related card: %w", err)
+		}
+		if exists {
+			continue
+		}
+		related, err := h.articleRepo.GetByID(ctx, rel.ID)
+		if err != nil {
+			return fmt.Errorf("get related article: %w", err)
+		}
+		if related == nil {
+			continue
+		}
+
+		pair, err := h.aiClient.GenerateRelatedEchoCard(ctx, source, articleBrief(related), rel.RelevanceReason)
+		if err != nil {
+			// Non-fatal: the next relate run can try this pair again.
+			slog.Warn("echo:related — generation failed",
+				"article_id", p.ArticleID,
+				"related_article_id", rel.ID,
+				"error", err,
+			)
+			continue
+		}
+
+		relatedID := rel.ID
+		card := &domain.EchoCard{
+			UserID:           p.UserID,
+			ArticleID:        p.ArticleID,
+			CardType:         domain.EchoCardRelated,
+			Question:         pair.Question,
+			Answer:           pair.Answer,
+			SourceContext:    nilIfEmpty(pair.SourceContext),
+			RelatedArticleID: &relatedID,
+			NextReviewAt:     time.Now().Add(24 * time.Hour),
+			IntervalDays:     1,
+		}
+		ok, err := h.echoRepo.CreateRelatedCard(ctx, card, echoRelatedWeeklyLimit, weekStart(start))
+		if err != nil {
+			return fmt.Errorf("create related echo card: %w", err)
+		}
+		if !ok {
+			// A concurrent run for another of the user's articles took the
+			// week's last slot or this pair first.
+			slog.Debug("echo:related — card not created, stopping",
+				"article_id", p.ArticleID,
+				"related_article_id", rel.ID,
+			)
+			break
+		}
+		created++
+	}
+
+	slog.Info("echo:related completed",
+		"article_id", p.ArticleID,
+		"relations", len(relations),
+		"created", created,
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+	return nil
+}
+
+func articleBrief(a *domain.Article) client.EchoArticleBrief {
+	return client.EchoArticleBrief{
+		Title:     derefOrEmpty(a.Title),
+		Summary:   derefOrEmpty(a.Summary),
+		KeyPoints: a.KeyPoints,
+	}
+}
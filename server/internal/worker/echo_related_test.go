@@ -0,0 +1,119 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"folio-server/internal/domain"
+	"folio-server/internal/repository"
+)
+
+type mockEchoRelations struct {
+	rows []repository.RelatedArticleRow
+}
+
+func (m *mockEchoRelations) ListBySource(_ context.Context, _, _ string) ([]repository.RelatedArticleRow, error) {
+	return m.rows, nil
+}
+
+func relatedRows(ids ...string) *mockEchoRelations {
+	rows := make([]repository.RelatedArticleRow, len(ids))
+	for i, id := range ids {
+		rows[i] = repository.RelatedArticleRow{ID: id, RelevanceReason: "同一主题"}
+	}
+	return &mockEchoRelations{rows: rows}
+}
+
+func runRelated(t *testing.T, repo *mockEchoCardRepo, gen *mockEchoGenerator, relations *mockEchoRelations) {
+	t.Helper()
+	h := NewEchoHandler(gen, mockEchoArticles{}, repo, &mockEchoHighlights{}, relations)
+	if err := h.ProcessRelated(context.Background(), NewEchoRelatedTask("article-1", "user-1")); err != nil {
+		t.Fatalf("ProcessRelated: %v", err)
+	}
+}
+
+func TestEchoHandler_ProcessRelated(t *testing.T) {
+	repo := &mockEchoCardRepo{related: map[string]bool{"article-2|article-1": true}}
+	gen := &mockEchoGenerator{}
+	runRelated(t, repo, gen, relatedRows("article-2", "article-3", "article-4", "article-5"))
+
+	// article-2 already has a card (made from its side); the per-run cap
+	// stops after two new pairs.
+	if len(repo.created) != echoRelatedPerArticle {
+		t.Fatalf("created %d cards, want %d", len(repo.created), echoRelatedPerArticle)
+	}
+	for i, want := range []string{"article-3", "article-4"} {
+		c := repo.created[i]
+		if c.CardType != domain.EchoCardRelated || c.ArticleID != "article-1" || c.RelatedArticleID == nil || *c.RelatedArticleID != want {
+			t.Errorf("card %d = %+v, want related to %s", i, c, want)
+		}
+	}
+}
+
+func TestEchoHandler_ProcessRelated_WeeklyLimit(t *testing.T) {
+	repo := &mockEchoCardRepo{relatedSince: echoRelatedWeeklyLimit - 1}
+	runRelated(t, repo, &mockEchoGenerator{}, relatedRows("article-2", "article-3"))
+	if len(repo.created) != 1 {
+		t.Errorf("created %d cards with one left this week", len(repo.created))
+	}
+
+	repo = &mockEchoCardRepo{relatedSince: echoRelatedWeeklyLimit}
+	gen := &mockEchoGenerator{}
+	runRelated(t, repo, gen, relatedRows("article-2"))
+	if len(repo.created) != 0 || gen.pairs != 0 {
+		t.Errorf("created %d cards over the weekly limit", len(repo.created))
+	}
+}
+
+func TestEchoHandler_ProcessRelated_Concurrent(t *testing.T) {
+	// One slot left this week: relate runs for different articles all see
+	// it before generating, but only one of them may use it.
+	repo := &mockEchoCardRepo{relatedSince: echoRelatedWeeklyLimit - 1}
+	gen := &mockEchoGenerator{}
+	h := NewEchoHandler(gen, mockEchoArticles{}, repo, &mockEchoHighlights{}, relatedRows("article-9"))
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			task := NewEchoRelatedTask(fmt.Sprintf("article-%d", i), "user-1")
+			if err := h.ProcessRelated(context.Background(), task); err != nil {
+				t.Errorf("ProcessRelated: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if len(repo.created) != 1 {
+		t.Errorf("created %d cards with one left this week", len(repo.created))
+	}
+
+	// Two runs relating the same pair from either side make one card.
+	repo = &mockEchoCardRepo{}
+	for _, ids := range [][2]string{{"article-1", "article-2"}, {"article-2", "article-1"}} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h := NewEchoHandler(&mockEchoGenerator{}, mockEchoArticles{}, repo, &mockEchoHighlights{}, relatedRows(ids[1]))
+			if err := h.ProcessRelated(context.Background(), NewEchoRelatedTask(ids[0], "user-1")); err != nil {
+				t.Errorf("ProcessRelated: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if len(repo.created) != 1 {
+		t.Errorf("created %d cards for one pair", len(repo.created))
+	}
+}
+
+func TestWeekStart(t *testing.T) {
+	monday := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	for _, d := range []time.Time{monday, monday.Add(50 * time.Hour), time.Date(2026, 3, 8, 23, 59, 0, 0, time.UTC)} {
+		if got := weekStart(d); !got.Equal(monday) {
+			t.Errorf("weekStart(%v) = %v, want %v", d, got, monday)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/hibiken/asynq"
+
+	"folio-server/internal/client"
+	"folio-server/internal/repository"
+)
+
+const (
+	embedChunkRunes = 800 // target chunk size
+	embedMaxChunks  = 64  // content chunks per article, after the title/summary chunk
+	embedBatchSize  = 16  // texts per embedder call
+)
+
+type embeddingStore interface {
+	Replace(ctx context.Context, articleID, userID, model string, chunks []repository.EmbeddingChunk) error
+}
+
+// EmbedHandler splits an article into chunks, embeds them and stores the
+// vectors, then enqueues the relate task.
+type EmbedHandler struct {
+	articleRepo ArticleGetter
+	embedder    client.Embedder
+	store       embeddingStore
+	enqueuer    Enqueuer
+}
+
+func NewEmbedHandler(articleRepo ArticleGetter, embedder client.Embedder, store embeddingStore, enqueuer Enqueuer) *EmbedHandler {
+	return &EmbedHandler{
+		articleRepo: articleRepo,
+		embedder:    embedder,
+		store:       store,
+		enqueuer:    enqueuer,
+	}
+}
+
+func (h *EmbedHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
+	var p EmbedPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("unmarshal embed payload: %w", err)
+	}
+
+	start := time.Now()
+
+	article, err := h.articleRepo.GetByID(ctx, p.ArticleID)
+	if err != nil {
+		return fmt.Errorf("get article %s: %w", p.ArticleID, err)
+	}
+	if article == nil {
+		slog.Info("[EMBED] article gone, skipping", "article_id", p.ArticleID)
+		return nil
+	}
+
+	texts := embedChunks(derefOrEmpty(article.Title), derefOrEmpty(article.Summary), derefOrEmpty(article.MarkdownContent))
+	if len(texts) > 0 {
+		chunks, err := h.embed(ctx, texts)
+		if err != nil {
+			// Relations can still be found by keyword; don't hold them up
+			// once retries are exhausted.
+			if retried, _ := asynq.GetRetryCount(ctx); retried >= maxRetry(ctx) {
+				h.enqueueRelate(ctx, p)
+			}
+			return err
+		}
+		if err := h.store.Replace(ctx, p.ArticleID, p.UserID, h.embedder.Model(), chunks); err != nil {
+			return fmt.Errorf("store embeddings: %w", err)
+		}
+	}
+
+	slog.Info("[EMBED] completed",
+		"article_id", p.ArticleID,
+		"chunks", len(texts),
+		"model", h.embedder.Model(),
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+	h.enqueueRelate(ctx, p)
+	return nil
+}
+
+func (h *EmbedHandler) embed(ctx context.Context, texts []articleChunk) ([]repository.EmbeddingChunk, error) {
+	chunks := make([]repository.EmbeddingChunk, 0, len(texts))
+	for i := 0; i < len(texts); i += embedBatchSize {
+		batch := texts[i:min(i+embedBatchSize, len(texts))]
+		inputs := make([]string, len(batch))
+		for j, c := range batch {
+			inputs[j] = c.Embed
+		}
+		vectors, err := h.embedder.Embed(ctx, inputs)
+		if err != nil {
+			return nil, fmt.Errorf("embed chunks: %w", err)
+		}
+		for j, v := range vectors {
+			chunks = append(chunks, repository.EmbeddingChunk{
+				Index:       i + j,
+				Text:        batch[j].Text,
+				Vector:      v,
+				StartOffset: batch[j].Start,
+				EndOffset:   batch[j].End,
+			})
+		}
+	}
+	return chunks, nil
+}
+
+func (h *EmbedHandler) enqueueRelate(ctx context.Context, p EmbedPayload) {
+	if _, err := h.enqueuer.EnqueueContext(ctx, NewRelateTask(p.ArticleID, p.UserID)); err != nil {
+		slog.Error("[RELATE] failed to enqueue for article",
+			"article_id", p.ArticleID,
+			"error", err,
+		)
+	}
+}
+
+// maxRetry returns the task's retry limit, or 0 outside a task context.
+func maxRetry(ctx context.Context) int {
+	n, _ := asynq.GetMaxRetry(ctx)
+	return n
+}
+
+var (
+	reEmbedImage = regexp.MustCompile(`!\[[^\]]*\]\([^)]*\)`)
+	reEmbedLink  = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+)
+
+// articleChunk is one piece of an article to embed.
+type articleChunk struct {
+	Text string // stored and quoted: the exact passage, or title and summary
+	// Embed is what the embedder sees: Text without image and link markup.
+	Embed string
+	// Start and End are the rune offsets of Text in the article markdown;
+	// nil for the title chunk. They are converted to reader offsets when a
+	// passage is returned.
+	Start, End *int
+}
+
+// embedChunks returns the chunks to embed for an article: a first chunk of
+// title and summary, which stands for the whole article when finding
+// related ones, then passages of the body of about embedChunkRunes, split
+// on paragraph boundaries.
+func embedChunks(title, summary, markdown string) []articleChunk {
+	chunks := make([]articleChunk, 0)
+	if head := strings.TrimSpace(title + "\n" + summary); head != "" {
+		chunks = append(chunks, articleChunk{Text: head, Embed: head})
+	}
+
+	runes := []rune(markdown)
+	body := 0
+	add := func(start, end int) {
+		if body >= embedMaxChunks || start >= end {
+			return
+		}
+		passage := string(runes[start:end])
+		embed := strings.TrimSpace(reEmbedLink.ReplaceAllString(reEmbedImage.ReplaceAllString(passage, ""), "$1"))
+		if embed == "" {
+			return
+		}
+		chunks = append(chunks, articleChunk{Text: passage, Embed: embed, Start: &start, End: &end})
+		body++
+	}
+
+	// Group paragraphs into passages, splitting paragraphs that are too
+	// long on their own.
+	start, end := -1, -1
+	for _, para := range paragraphSpans(runes) {
+		if start >= 0 && para[1]-start > embedChunkRunes {
+			add(start, end)
+			start = -1
+		}
+		for para[1]-para[0] > embedChunkRunes {
+			add(para[0], para[0]+embedChunkRunes)
+			para[0] += embedChunkRunes
+		}
+		if start < 0 {
+			start = para[0]
+		}
+		end = para[1]
+	}
+	if start >= 0 {
+		add(start, end)
+	}
+	return chunks
+}
+
+// paragraphSpans returns the [start, end) rune offsets of the paragraphs of
+// runes: runs of text separated by blank lines, trimmed of whitespace.
+func paragraphSpans(runes []rune) [][2]int {
+	spans := make([][2]int, 0)
+	start := -1
+	lastText := -1
+	blank := true // current line has no text so far
+	for i, r := range runes {
+		switch {
+		case r == '\n':
+			if blank && start >= 0 {
+				spans = append(spans, [2]int{start, lastText + 1})
+				start = -1
+			}
+			blank = true
+		case unicode.IsSpace(r):
+		default:
+			if start < 0 {
+				start = i
+			}
+			lastText = i
+			blank = false
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, [2]int{start, lastText + 1})
+	}
+	return spans
+}
@@ -0,0 +1,153 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/hibiken/asynq"
+
+	"folio-server/internal/client"
+	"folio-server/internal/domain"
+	"folio-server/internal/readertext"
+	"folio-server/internal/repository"
+)
+
+type mockEmbedArticles struct{ article *domain.Article }
+
+func (m *mockEmbedArticles) GetByID(_ context.Context, _ string) (*domain.Article, error) {
+	return m.article, nil
+}
+
+type mockEmbeddingStore struct {
+	model  string
+	chunks []repository.EmbeddingChunk
+}
+
+func (m *mockEmbeddingStore) Replace(_ context.Context, _, _, model string, chunks []repository.EmbeddingChunk) error {
+	m.model, m.chunks = model, chunks
+	return nil
+}
+
+type failingEmbedder struct{}
+
+func (failingEmbedder) Model() string { return "down/model" }
+func (failingEmbedder) Embed(context.Context, []string) ([][]float32, error) {
+	return nil, errors.New("embedding server down")
+}
+
+func newEmbedTask() *asynq.Task {
+	payload, _ := json.Marshal(EmbedPayload{ArticleID: "art-1", UserID: "user-1"})
+	return asynq.NewTask(TypeEmbedArticle, payload)
+}
+
+func TestEmbedChunks(t *testing.T) {
+	long := strings.Repeat("长", embedChunkRunes+10)
+	md := "Intro with a [link](https://x.y) and ![img](https://x.y/a.png).\n\n" +
+		strings.Repeat("word ", 100) + "\n\n" + long
+
+	chunks := embedChunks("Title", "Summary", md)
+
+	if chunks[0].Text != "Title\nSummary" || chunks[0].Start != nil {
+		t.Errorf("first chunk = %+v", chunks[0])
+	}
+	if strings.Contains(chunks[1].Embed, "https://") || !strings.Contains(chunks[1].Embed, "link") {
+		t.Errorf("markdown not cleaned: %q", chunks[1].Embed)
+	}
+	runes := []rune(md)
+	for i, c := range chunks[1:] {
+		if n := len([]rune(c.Text)); n > embedChunkRunes {
+			t.Errorf("chunk %d has %d runes", i+1, n)
+		}
+		if got := string(runes[*c.Start:*c.End]); got != c.Text {
+			t.Errorf("chunk %d offsets [%d, %d) give %q, want %q", i+1, *c.Start, *c.End, got, c.Text)
+		}
+	}
+	if got := embedChunks("", "", ""); len(got) != 0 {
+		t.Errorf("empty article chunks = %+v", got)
+	}
+
+	many := strings.Repeat(strings.Repeat("x", embedChunkRunes)+"\n\n", embedMaxChunks+5)
+	if got := embedChunks("T", "", many); len(got) != embedMaxChunks+1 {
+		t.Errorf("got %d chunks, want cap of %d plus the title chunk", len(got), embedMaxChunks)
+	}
+}
+
+func TestEmbedChunks_ReaderOffsets(t *testing.T) {
+	// Markdown syntax and characters outside the BMP precede the passage,
+	// so its rune offsets in the markdown differ from the UTF-16 offsets
+	// the reader reports for the same text.
+	md := "# 𝐓𝐢𝐭𝐥𝐞 😀\n\n**Bold** and [a link](https://x.y) 🎉.\n\n" +
+		strings.Repeat("filler ", 113) + "\n\nThe cited passage."
+
+	chunks := embedChunks("", "", md)
+	last := chunks[len(chunks)-1]
+	if last.Text != "The cited passage." {
+		t.Fatalf("last chunk = %q", last.Text)
+	}
+
+	text := readertext.Render(md, "")
+	start, end := text.FromMarkdown(*last.Start), text.FromMarkdown(*last.End)
+	shown := utf16.Encode([]rune(text.String()))
+	if got := string(utf16.Decode(shown[start:end])); got != "The cited passage." {
+		t.Errorf("reader offsets [%d, %d) give %q", start, end, got)
+	}
+	if start == *last.Start {
+		t.Errorf("reader offset equals the rune offset %d; the test needs them to differ", start)
+	}
+}
+
+func TestParagraphSpans(t *testing.T) {
+	text := []rune("  第一段\n续行  \n \n\nSecond para.\n\n\t\n")
+	spans := paragraphSpans(text)
+
+	want := []string{"第一段\n续行", "Second para."}
+	if len(spans) != len(want) {
+		t.Fatalf("got %d spans, want %d", len(spans), len(want))
+	}
+	for i, span := range spans {
+		if got := string(text[span[0]:span[1]]); got != want[i] {
+			t.Errorf("span %d = %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestEmbedHandler_StoresVectorsThenRelates(t *testing.T) {
+	title, summary, md := "Go", "About Go", "Para one.\n\nPara two."
+	store := &mockEmbeddingStore{}
+	enq := &mockImportEnqueuer{}
+	h := NewEmbedHandler(&mockEmbedArticles{article: &domain.Article{ID: "art-1", Title: &title, Summary: &summary, MarkdownContent: &md}},
+		client.NewHashEmbedder(), store, enq)
+
+	if err := h.ProcessTask(context.Background(), newEmbedTask()); err != nil {
+		t.Fatalf("ProcessTask: %v", err)
+	}
+	if store.model != "hash/v1-256" || len(store.chunks) != 2 {
+		t.Fatalf("stored %d chunks of %q", len(store.chunks), store.model)
+	}
+	if c := store.chunks[1]; c.Index != 1 || len(c.Vector) == 0 || c.Text != "Para one.\n\nPara two." ||
+		c.StartOffset == nil || *c.StartOffset != 0 || *c.EndOffset != len([]rune(md)) {
+		t.Errorf("chunk = %+v", c)
+	}
+	if len(enq.tasks) != 1 || enq.tasks[0].Type() != TypeRelateArticle {
+		t.Errorf("enqueued %v, want the relate task", enq.tasks)
+	}
+}
+
+func TestEmbedHandler_FailureStillRelatesOnLastAttempt(t *testing.T) {
+	title := "Go"
+	enq := &mockImportEnqueuer{}
+	h := NewEmbedHandler(&mockEmbedArticles{article: &domain.Article{ID: "art-1", Title: &title}},
+		failingEmbedder{}, &mockEmbeddingStore{}, enq)
+
+	// Outside a worker context there are no retries left.
+	if err := h.ProcessTask(context.Background(), newEmbedTask()); err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(enq.tasks) != 1 || enq.tasks[0].Type() != TypeRelateArticle {
+		t.Errorf("enqueued %v, want the relate task", enq.tasks)
+	}
+}
@@ -0,0 +1,361 @@
+package worker
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/hibiken/asynq"
+
+	"folio-server/internal/domain"
+	"folio-server/internal/repository"
+	"folio-server/internal/vault"
+)
+
+const (
+	// exportBatchSize is how many articles are loaded per query while building.
+	exportBatchSize = 200
+	// exportTTL is how long a finished archive is kept in object storage.
+	exportTTL = 7 * 24 * time.Hour
+	// personalDataFileName is the JSON document inside a personal data export.
+	personalDataFileName = "folio-data.json"
+)
+
+// exportRepo abstracts the export repository methods used by ExportHandler.
+type exportRepo interface {
+	GetByID(ctx context.Context, id string) (*domain.ExportJob, error)
+	SetRunning(ctx context.Context, id string) error
+	Complete(ctx context.Context, id, objectKey string, sizeBytes int64, articleCount int, expiresAt time.Time) error
+	Fail(ctx context.Context, id, errMsg string) error
+	Expire(ctx context.Context, id string) error
+}
+
+// exportArticleLister pages through a user's library in creation order and
+// resolves a collection's filter to the articles it matches.
+type exportArticleLister interface {
+	ListForExport(ctx context.Context, userID string, articleIDs []string, afterCreatedAt time.Time, afterID string, limit int) ([]domain.Article, error)
+	ListFilteredIDs(ctx context.Context, q *domain.SearchQuery, p repository.ListArticlesParams, limit int) ([]string, error)
+}
+
+// exportHighlightLister lists all of a user's highlights.
+type exportHighlightLister interface {
+	ListByUser(ctx context.Context, userID string) ([]domain.Highlight, error)
+}
+
+// exportCardLister lists all of a user's Echo cards.
+type exportCardLister interface {
+	ListByUser(ctx context.Context, userID string) ([]domain.EchoCard, error)
+}
+
+// exportUserGetter reads the user's preferred language.
+type exportUserGetter interface {
+	GetByID(ctx context.Context, id string) (*domain.User, error)
+}
+
+// exportPersonalData streams every row stored about a user, per section.
+type exportPersonalData interface {
+	Each(ctx context.Context, userID string, section repository.PersonalDataSection, fn func(row json.RawMessage) error) error
+}
+
+// exportStorage stores and deletes export archives.
+type exportStorage interface {
+	UploadFile(ctx context.Context, key string, body io.ReadSeeker, contentType string) error
+	Delete(ctx context.Context, key string) error
+}
+
+// ExportHandler processes export:build and export:expire tasks. The archive
+// is written to a temporary file (a vault.Writer Markdown vault, or a JSON
+// bundle of all personal data), uploaded to object storage, and deleted
+// again by a delayed export:expire task.
+type ExportHandler struct {
+	exportRepo    exportRepo
+	articleRepo   exportArticleLister
+	highlightRepo exportHighlightLister
+	echoRepo      exportCardLister
+	userRepo      exportUserGetter
+	personalData  exportPersonalData
+	storage       exportStorage
+	enqueuer      Enqueuer
+}
+
+func NewExportHandler(
+	exportRepo exportRepo,
+	articleRepo exportArticleLister,
+	highlightRepo exportHighlightLister,
+	echoRepo exportCardLister,
+	userRepo exportUserGetter,
+	personalData exportPersonalData,
+	storage exportStorage,
+	enqueuer Enqueuer,
+) *ExportHandler {
+	return &ExportHandler{
+		exportRepo:    exportRepo,
+		articleRepo:   articleRepo,
+		highlightRepo: highlightRepo,
+		echoRepo:      echoRepo,
+		userRepo:      userRepo,
+		personalData:  personalData,
+		storage:       storage,
+		enqueuer:      enqueuer,
+	}
+}
+
+// exportObjectKey is where a job's archive is stored.
+func exportObjectKey(userID, jobID string) string {
+	return fmt.Sprintf("exports/%s/%s.zip", userID, jobID)
+}
+
+// ProcessTask handles export:build.
+func (h *ExportHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
+	var p ExportPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("unmarshal payload: %w", err)
+	}
+
+	job, err := h.exportRepo.GetByID(ctx, p.JobID)
+	if err != nil {
+		return fmt.Errorf("get export job: %w", err)
+	}
+	if job == nil || (job.Status != domain.ExportStatusPending && job.Status != domain.ExportStatusRunning) {
+		slog.Debug("export:build — job gone or finished, skipping", "job_id", p.JobID)
+		return nil
+	}
+	if err := h.exportRepo.SetRunning(ctx, job.ID); err != nil {
+		return fmt.Errorf("start export job: %w", err)
+	}
+
+	key := exportObjectKey(job.UserID, job.ID)
+	size, articles, err := h.build(ctx, job, key)
+	if err != nil {
+		if failErr := h.exportRepo.Fail(ctx, job.ID, err.Error()); failErr != nil {
+			slog.Error("export:build — mark failed", "job_id", job.ID, "error", failErr)
+		}
+		// The user can start a new export; retrying a failed job would only
+		// repeat the same work against a job row that is already failed.
+		return fmt.Errorf("build export: %v: %w", err, asynq.SkipRetry)
+	}
+
+	expiresAt := time.Now().Add(exportTTL)
+	if err := h.exportRepo.Complete(ctx, job.ID, key, size, articles, expiresAt); err != nil {
+		return fmt.Errorf("complete export job: %w", err)
+	}
+	if _, err := h.enqueuer.EnqueueContext(ctx, NewExportExpireTask(job.ID), asynq.ProcessIn(exportTTL)); err != nil {
+		// The archive then stays in storage, but the download link still
+		// stops working once expires_at has passed.
+		slog.Error("export:build — enqueue expiry failed", "job_id", job.ID, "error", err)
+	}
+
+	slog.Info("export completed", "job_id", job.ID, "user_id", job.UserID, "articles", articles, "bytes", size)
+	return nil
+}
+
+// build writes the job's archive to a temporary zip and uploads it to key.
+// It returns the archive size and the number of articles included.
+func (h *ExportHandler) build(ctx context.Context, job *domain.ExportJob, key string) (int64, int, error) {
+	f, err := os.CreateTemp("", "folio-export-*.zip")
+	if err != nil {
+		return 0, 0, fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	var articles int
+	if job.Format == domain.ExportFormatJSON {
+		articles, err = h.writePersonalData(ctx, f, job.UserID)
+	} else {
+		articles, err = h.writeVault(ctx, f, job)
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	size, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, 0, fmt.Errorf("archive size: %w", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return 0, 0, fmt.Errorf("rewind archive: %w", err)
+	}
+	if err := h.storage.UploadFile(ctx, key, f, "application/zip"); err != nil {
+		return 0, 0, fmt.Errorf("upload archive: %w", err)
+	}
+	return size, articles, nil
+}
+
+// writeVault writes the user's library as a Markdown vault. For a
+// collection export only the articles matching the job's filter are
+// written, with their highlights and Echo cards.
+func (h *ExportHandler) writeVault(ctx context.Context, out io.Writer, job *domain.ExportJob) (int, error) {
+	userID := job.UserID
+	articleIDs, err := h.exportArticleIDs(ctx, job)
+	if err != nil {
+		return 0, err
+	}
+	var selected map[string]bool
+	if articleIDs != nil {
+		selected = make(map[string]bool, len(articleIDs))
+		for _, id := range articleIDs {
+			selected[id] = true
+		}
+	}
+	inExport := func(articleID string) bool {
+		return selected == nil || selected[articleID]
+	}
+
+	lang := "zh"
+	if user, err := h.userRepo.GetByID(ctx, userID); err != nil {
+		return 0, fmt.Errorf("get user: %w", err)
+	} else if user != nil && user.PreferredLanguage != "" {
+		lang = user.PreferredLanguage
+	}
+
+	highlights, err := h.highlightRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("list highlights: %w", err)
+	}
+	byArticle := make(map[string][]domain.Highlight)
+	for _, hl := range highlights {
+		if inExport(hl.ArticleID) {
+			byArticle[hl.ArticleID] = append(byArticle[hl.ArticleID], hl)
+		}
+	}
+
+	w := vault.NewWriter(out, lang)
+	var afterCreatedAt time.Time
+	var afterID string
+	for {
+		batch, err := h.articleRepo.ListForExport(ctx, userID, articleIDs, afterCreatedAt, afterID, exportBatchSize)
+		if err != nil {
+			return 0, fmt.Errorf("list articles: %w", err)
+		}
+		for i := range batch {
+			if err := w.AddArticle(&batch[i], byArticle[batch[i].ID]); err != nil {
+				return 0, err
+			}
+		}
+		if len(batch) < exportBatchSize {
+			break
+		}
+		last := batch[len(batch)-1]
+		afterCreatedAt, afterID = last.CreatedAt, last.ID
+	}
+
+	cards, err := h.echoRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("list echo cards: %w", err)
+	}
+	if selected != nil {
+		cards = slices.DeleteFunc(cards, func(c domain.EchoCard) bool { return !inExport(c.ArticleID) })
+	}
+	if err := w.AddEchoDeck(cards); err != nil {
+		return 0, err
+	}
+	if err := w.Close(); err != nil {
+		return 0, fmt.Errorf("close archive: %w", err)
+	}
+	return w.Articles(), nil
+}
+
+// exportArticleIDs returns the articles a collection export covers, or nil
+// for an export of the whole library. The filter is evaluated as of the
+// job's creation, so relative date windows match what the user saw.
+func (h *ExportHandler) exportArticleIDs(ctx context.Context, job *domain.ExportJob) ([]string, error) {
+	if job.Filter == nil {
+		return nil, nil
+	}
+	p, q, err := repository.ArticleFilterParams(job.UserID, *job.Filter, job.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("collection filter: %w", err)
+	}
+	ids, err := h.articleRepo.ListFilteredIDs(ctx, q, p, 0)
+	if err != nil {
+		return nil, fmt.Errorf("list collection articles: %w", err)
+	}
+	return ids, nil
+}
+
+// writePersonalData writes a zip holding a single JSON document with every
+// row stored about the user, keyed by section:
+//
+//	{"exported_at": "...", "user_id": "...", "profile": [...], "articles": [...], ...}
+func (h *ExportHandler) writePersonalData(ctx context.Context, out io.Writer, userID string) (int, error) {
+	zw := zip.NewWriter(out)
+	f, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     personalDataFileName,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("create %s: %w", personalDataFileName, err)
+	}
+	w := bufio.NewWriter(f)
+
+	exportedAt, _ := json.Marshal(time.Now().UTC().Format(time.RFC3339))
+	userJSON, _ := json.Marshal(userID)
+	fmt.Fprintf(w, "{\n  \"exported_at\": %s,\n  \"user_id\": %s", exportedAt, userJSON)
+
+	articles := 0
+	for _, section := range repository.PersonalDataSections {
+		fmt.Fprintf(w, ",\n  %q: [", section.Name)
+		n := 0
+		err := h.personalData.Each(ctx, userID, section, func(row json.RawMessage) error {
+			if n > 0 {
+				w.WriteString(",")
+			}
+			w.WriteString("\n    ")
+			n++
+			_, err := w.Write(row)
+			return err
+		})
+		if err != nil {
+			return 0, err
+		}
+		if n > 0 {
+			w.WriteString("\n  ")
+		}
+		w.WriteString("]")
+		if section.Name == "articles" {
+			articles = n
+		}
+	}
+	w.WriteString("\n}\n")
+
+	if err := w.Flush(); err != nil {
+		return 0, fmt.Errorf("write %s: %w", personalDataFileName, err)
+	}
+	if err := zw.Close(); err != nil {
+		return 0, fmt.Errorf("close archive: %w", err)
+	}
+	return articles, nil
+}
+
+// ProcessExpire handles export:expire by deleting the archive.
+func (h *ExportHandler) ProcessExpire(ctx context.Context, t *asynq.Task) error {
+	var p ExportPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("unmarshal payload: %w", err)
+	}
+
+	job, err := h.exportRepo.GetByID(ctx, p.JobID)
+	if err != nil {
+		return fmt.Errorf("get export job: %w", err)
+	}
+	if job == nil || job.Status != domain.ExportStatusCompleted || job.ObjectKey == nil {
+		return nil
+	}
+	if err := h.storage.Delete(ctx, *job.ObjectKey); err != nil {
+		return fmt.Errorf("delete archive: %w", err)
+	}
+	if err := h.exportRepo.Expire(ctx, job.ID); err != nil {
+		return fmt.Errorf("expire export job: %w", err)
+	}
+	slog.Info("export expired", "job_id", job.ID, "user_id", job.UserID)
+	return nil
+}
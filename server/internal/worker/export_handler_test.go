@@ -0,0 +1,311 @@
+package worker
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+
+	"folio-server/internal/domain"
+	"folio-server/internal/repository"
+)
+
+type mockExportRepo struct {
+	job *domain.ExportJob
+}
+
+func (m *mockExportRepo) GetByID(_ context.Context, _ string) (*domain.ExportJob, error) {
+	if m.job == nil {
+		return nil, nil
+	}
+	j := *m.job
+	return &j, nil
+}
+
+func (m *mockExportRepo) SetRunning(_ context.Context, _ string) error {
+	m.job.Status = domain.ExportStatusRunning
+	return nil
+}
+
+func (m *mockExportRepo) Complete(_ context.Context, _, objectKey string, sizeBytes int64, articleCount int, expiresAt time.Time) error {
+	m.job.Status = domain.ExportStatusCompleted
+	m.job.ObjectKey = &objectKey
+	m.job.SizeBytes = sizeBytes
+	m.job.ArticleCount = articleCount
+	m.job.ExpiresAt = &expiresAt
+	return nil
+}
+
+func (m *mockExportRepo) Fail(_ context.Context, _, errMsg string) error {
+	m.job.Status = domain.ExportStatusFailed
+	m.job.Error = &errMsg
+	return nil
+}
+
+func (m *mockExportRepo) Expire(_ context.Context, _ string) error {
+	m.job.Status = domain.ExportStatusExpired
+	m.job.ObjectKey = nil
+	return nil
+}
+
+// mockExportArticles serves n articles through the keyset cursor. A
+// collection filter matches the articles in filtered.
+type mockExportArticles struct {
+	articles []domain.Article
+	filtered []string
+	calls    int
+}
+
+func (m *mockExportArticles) ListForExport(_ context.Context, _ string, articleIDs []string, afterCreatedAt time.Time, afterID string, limit int) ([]domain.Article, error) {
+	m.calls++
+	out := make([]domain.Article, 0)
+	for _, a := range m.articles {
+		if a.CreatedAt.Before(afterCreatedAt) || (a.CreatedAt.Equal(afterCreatedAt) && a.ID <= afterID) {
+			continue
+		}
+		if articleIDs != nil && !slices.Contains(articleIDs, a.ID) {
+			continue
+		}
+		out = append(out, a)
+		if len(out) == limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+func (m *mockExportArticles) ListFilteredIDs(_ context.Context, _ *domain.SearchQuery, _ repository.ListArticlesParams, _ int) ([]string, error) {
+	return append([]string{}, m.filtered...), nil
+}
+
+type mockExportHighlights struct{ highlights []domain.Highlight }
+
+func (m *mockExportHighlights) ListByUser(_ context.Context, _ string) ([]domain.Highlight, error) {
+	return m.highlights, nil
+}
+
+type mockExportCards struct{ cards []domain.EchoCard }
+
+func (m *mockExportCards) ListByUser(_ context.Context, _ string) ([]domain.EchoCard, error) {
+	return m.cards, nil
+}
+
+type mockExportUsers struct{}
+
+func (m *mockExportUsers) GetByID(_ context.Context, id string) (*domain.User, error) {
+	return &domain.User{ID: id, PreferredLanguage: "en"}, nil
+}
+
+// mockPersonalData returns the rows in sections, keyed by section name.
+type mockPersonalData struct {
+	sections map[string][]string
+}
+
+func (m *mockPersonalData) Each(_ context.Context, _ string, section repository.PersonalDataSection, fn func(json.RawMessage) error) error {
+	for _, row := range m.sections[section.Name] {
+		if err := fn(json.RawMessage(row)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type mockExportStorage struct {
+	objects   map[string][]byte
+	uploadErr error
+}
+
+func (m *mockExportStorage) UploadFile(_ context.Context, key string, body io.ReadSeeker, _ string) error {
+	if m.uploadErr != nil {
+		return m.uploadErr
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	m.objects[key] = data
+	return nil
+}
+
+func (m *mockExportStorage) Delete(_ context.Context, key string) error {
+	delete(m.objects, key)
+	return nil
+}
+
+func exportArticles(n int) []domain.Article {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	articles := make([]domain.Article, n)
+	for i := range articles {
+		title := fmt.Sprintf("Article %d", i)
+		content := fmt.Sprintf("Body of article %d.", i)
+		articles[i] = domain.Article{
+			ID:              fmt.Sprintf("a%04d", i),
+			Title:           &title,
+			MarkdownContent: &content,
+			CreatedAt:       base.Add(time.Duration(i/2) * time.Minute), // pairs share a timestamp
+		}
+	}
+	return articles
+}
+
+func newExportTask(typ, jobID string) *asynq.Task {
+	payload, _ := json.Marshal(ExportPayload{JobID: jobID})
+	return asynq.NewTask(typ, payload)
+}
+
+func TestExportHandler_BuildsAndSchedulesExpiry(t *testing.T) {
+	repo := &mockExportRepo{job: &domain.ExportJob{ID: "job-1", UserID: "user-1", Status: domain.ExportStatusPending}}
+	articles := &mockExportArticles{articles: exportArticles(exportBatchSize + 3)}
+	highlights := &mockExportHighlights{highlights: []domain.Highlight{{ArticleID: "a0000", Text: "Body", StartOffset: 0, EndOffset: 4}}}
+	cards := &mockExportCards{cards: []domain.EchoCard{{ArticleID: "a0001", Question: "Q", Answer: "A"}}}
+	storage := &mockExportStorage{objects: map[string][]byte{}}
+	enq := &mockImportEnqueuer{}
+	h := NewExportHandler(repo, articles, highlights, cards, &mockExportUsers{}, &mockPersonalData{}, storage, enq)
+
+	if err := h.ProcessTask(context.Background(), newExportTask(TypeExportBuild, "job-1")); err != nil {
+		t.Fatalf("ProcessTask: %v", err)
+	}
+
+	if repo.job.Status != domain.ExportStatusCompleted || repo.job.ArticleCount != exportBatchSize+3 {
+		t.Fatalf("job = %+v", repo.job)
+	}
+	if articles.calls != 2 {
+		t.Errorf("ListForExport called %d times, want 2", articles.calls)
+	}
+	key := "exports/user-1/job-1.zip"
+	data, ok := storage.objects[key]
+	if !ok || *repo.job.ObjectKey != key || repo.job.SizeBytes != int64(len(data)) {
+		t.Fatalf("stored %v, job key %v size %d", ok, repo.job.ObjectKey, repo.job.SizeBytes)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	if len(zr.File) != exportBatchSize+3+1 {
+		t.Errorf("archive has %d files, want articles + deck", len(zr.File))
+	}
+
+	if len(enq.tasks) != 1 || enq.tasks[0].Type() != TypeExportExpire {
+		t.Fatalf("enqueued %v, want one expiry task", enq.tasks)
+	}
+	if d, _ := findOption(enq.opts[0], asynq.ProcessInOpt); d != exportTTL {
+		t.Errorf("expiry delay = %v, want %v", d, exportTTL)
+	}
+
+	if err := h.ProcessExpire(context.Background(), newExportTask(TypeExportExpire, "job-1")); err != nil {
+		t.Fatalf("ProcessExpire: %v", err)
+	}
+	if _, ok := storage.objects[key]; ok || repo.job.Status != domain.ExportStatusExpired {
+		t.Errorf("after expiry: stored %v, status %q", ok, repo.job.Status)
+	}
+}
+
+func TestExportHandler_CollectionExport(t *testing.T) {
+	repo := &mockExportRepo{job: &domain.ExportJob{ID: "job-1", UserID: "user-1", Status: domain.ExportStatusPending,
+		Format: domain.ExportFormatMarkdown, Filter: &domain.ArticleFilter{Query: "body"}}}
+	articles := &mockExportArticles{articles: exportArticles(5), filtered: []string{"a0001", "a0003"}}
+	cards := &mockExportCards{cards: []domain.EchoCard{
+		{ArticleID: "a0001", Question: "Kept", Answer: "A"},
+		{ArticleID: "a0002", Question: "Dropped", Answer: "A"},
+	}}
+	storage := &mockExportStorage{objects: map[string][]byte{}}
+	h := NewExportHandler(repo, articles, &mockExportHighlights{}, cards, &mockExportUsers{}, &mockPersonalData{}, storage, &mockImportEnqueuer{})
+
+	if err := h.ProcessTask(context.Background(), newExportTask(TypeExportBuild, "job-1")); err != nil {
+		t.Fatalf("ProcessTask: %v", err)
+	}
+	if repo.job.ArticleCount != 2 {
+		t.Errorf("article count = %d, want 2", repo.job.ArticleCount)
+	}
+
+	data := storage.objects["exports/user-1/job-1.zip"]
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	var deck string
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		if strings.Contains(string(b), "# Echo Cards") {
+			deck = string(b)
+		}
+	}
+	if !strings.Contains(deck, "Kept") || strings.Contains(deck, "Dropped") {
+		t.Errorf("deck should hold only the collection's cards:\n%s", deck)
+	}
+}
+
+func TestExportHandler_UploadFailureFailsJob(t *testing.T) {
+	repo := &mockExportRepo{job: &domain.ExportJob{ID: "job-1", UserID: "user-1", Status: domain.ExportStatusPending}}
+	storage := &mockExportStorage{objects: map[string][]byte{}, uploadErr: errors.New("bucket gone")}
+	h := NewExportHandler(repo, &mockExportArticles{articles: exportArticles(2)}, &mockExportHighlights{},
+		&mockExportCards{}, &mockExportUsers{}, &mockPersonalData{}, storage, &mockImportEnqueuer{})
+
+	err := h.ProcessTask(context.Background(), newExportTask(TypeExportBuild, "job-1"))
+	if !errors.Is(err, asynq.SkipRetry) {
+		t.Errorf("err = %v, want SkipRetry", err)
+	}
+	if repo.job.Status != domain.ExportStatusFailed {
+		t.Errorf("status = %q, want failed", repo.job.Status)
+	}
+}
+
+func TestExportHandler_PersonalDataBundle(t *testing.T) {
+	repo := &mockExportRepo{job: &domain.ExportJob{ID: "job-2", UserID: "user-1", Status: domain.ExportStatusPending, Format: domain.ExportFormatJSON}}
+	data := &mockPersonalData{sections: map[string][]string{
+		"profile":    {`{"id":"user-1","email":"a@example.com"}`},
+		"articles":   {`{"id":"a1"}`, `{"id":"a2"}`},
+		"highlights": {`{"id":"h1","text":"quote"}`},
+	}}
+	storage := &mockExportStorage{objects: map[string][]byte{}}
+	h := NewExportHandler(repo, &mockExportArticles{}, &mockExportHighlights{}, &mockExportCards{},
+		&mockExportUsers{}, data, storage, &mockImportEnqueuer{})
+
+	if err := h.ProcessTask(context.Background(), newExportTask(TypeExportBuild, "job-2")); err != nil {
+		t.Fatalf("ProcessTask: %v", err)
+	}
+	if repo.job.ArticleCount != 2 {
+		t.Errorf("article count = %d, want 2", repo.job.ArticleCount)
+	}
+
+	archive := storage.objects["exports/user-1/job-2.zip"]
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	if len(zr.File) != 1 || zr.File[0].Name != personalDataFileName {
+		t.Fatalf("archive files = %v", zr.File)
+	}
+	rc, _ := zr.File[0].Open()
+	defer rc.Close()
+
+	var bundle map[string]json.RawMessage
+	if err := json.NewDecoder(rc).Decode(&bundle); err != nil {
+		t.Fatalf("bundle is not valid JSON: %v", err)
+	}
+	for _, section := range repository.PersonalDataSections {
+		var rows []map[string]any
+		if err := json.Unmarshal(bundle[section.Name], &rows); err != nil {
+			t.Errorf("section %s: %v", section.Name, err)
+		}
+		if want := len(data.sections[section.Name]); len(rows) != want {
+			t.Errorf("section %s has %d rows, want %d", section.Name, len(rows), want)
+		}
+	}
+	var userID string
+	if err := json.Unmarshal(bundle["user_id"], &userID); err != nil || userID != "user-1" {
+		t.Errorf("user_id = %s", bundle["user_id"])
+	}
+}
@@ -0,0 +1,271 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/hibiken/asynq"
+
+	"folio-server/internal/client"
+	"folio-server/internal/domain"
+	"folio-server/internal/repository"
+)
+
+const (
+	// feedPollInterval is how stale a feed must be before the scheduler polls it again.
+	feedPollInterval = 1 * time.Hour
+	// feedScheduleBatch caps how many feeds one feed:schedule run fans out.
+	feedScheduleBatch = 500
+	// feedMaxEntriesPerPoll caps how many of the newest entries one poll ingests.
+	feedMaxEntriesPerPoll = 30
+	// feedInitialBackfill is how many of the newest entries are ingested on the
+	// first poll; older entries are only marked as seen.
+	feedInitialBackfill = 5
+	// feedMaxErrors deactivates a feed after this many consecutive failures.
+	feedMaxErrors = 10
+)
+
+// feedRepo abstracts the feed repository methods used by FeedHandler.
+type feedRepo interface {
+	GetByID(ctx context.Context, id string) (*domain.Feed, error)
+	ListDueForPoll(ctx context.Context, cutoff time.Time, limit int) ([]domain.Feed, error)
+	RecordPollSuccess(ctx context.Context, id string, p repository.FeedPollResult) error
+	RecordPollError(ctx context.Context, id, errMsg string, maxErrors int) error
+	HasEntry(ctx context.Context, feedID, guid string) (bool, error)
+	RecordEntry(ctx context.Context, feedID, guid string, articleID *string) error
+}
+
+// feedFetcher abstracts the feed client.
+type feedFetcher interface {
+	Fetch(ctx context.Context, feedURL, etag, lastModified string) (*client.FeedFetchResult, error)
+}
+
+// feedArticleCreator creates articles for new feed entries.
+type feedArticleCreator interface {
+	Create(ctx context.Context, p repository.CreateArticleParams) (*domain.Article, error)
+	ExistsByUserAndURL(ctx context.Context, userID, url string) (bool, error)
+}
+
+// feedTaskCreator creates crawl tasks for new feed entries.
+type feedTaskCreator interface {
+	Create(ctx context.Context, p repository.CreateTaskParams) (*domain.CrawlTask, error)
+}
+
+// feedQuotaChecker consumes the user's monthly article quota.
+type feedQuotaChecker interface {
+	CheckAndIncrement(ctx context.Context, userID string) error
+	DecrementQuota(ctx context.Context, userID string) error
+}
+
+// FeedHandler processes feed:schedule and feed:poll tasks. New entries are
+// saved as SourceNewsletter articles and go through the regular
+// article:crawl → article:ai pipeline.
+type FeedHandler struct {
+	feedRepo    feedRepo
+	fetcher     feedFetcher
+	articleRepo feedArticleCreator
+	taskRepo    feedTaskCreator
+	quota       feedQuotaChecker
+	enqueuer    Enqueuer
+}
+
+func NewFeedHandler(
+	feedRepo feedRepo,
+	fetcher feedFetcher,
+	articleRepo feedArticleCreator,
+	taskRepo feedTaskCreator,
+	quota feedQuotaChecker,
+	enqueuer Enqueuer,
+) *FeedHandler {
+	return &FeedHandler{
+		feedRepo:    feedRepo,
+		fetcher:     fetcher,
+		articleRepo: articleRepo,
+		taskRepo:    taskRepo,
+		quota:       quota,
+		enqueuer:    enqueuer,
+	}
+}
+
+// ProcessSchedule handles the periodic feed:schedule task by enqueueing a
+// feed:poll task for every active feed that is due.
+func (h *FeedHandler) ProcessSchedule(ctx context.Context, _ *asynq.Task) error {
+	feeds, err := h.feedRepo.ListDueForPoll(ctx, time.Now().Add(-feedPollInterval), feedScheduleBatch)
+	if err != nil {
+		return fmt.Errorf("list due feeds: %w", err)
+	}
+
+	enqueued := 0
+	for _, f := range feeds {
+		if _, err := h.enqueuer.EnqueueContext(ctx, NewFeedPollTask(f.ID)); err != nil {
+			// ErrDuplicateTask means a poll for this feed is already pending.
+			slog.Debug("feed:schedule — enqueue skipped", "feed_id", f.ID, "error", err)
+			continue
+		}
+		enqueued++
+	}
+
+	slog.Info("feed:schedule completed", "due", len(feeds), "enqueued", enqueued)
+	return nil
+}
+
+// ProcessTask handles feed:poll for a single feed.
+func (h *FeedHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
+	var p FeedPollPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("unmarshal payload: %w", err)
+	}
+
+	feed, err := h.feedRepo.GetByID(ctx, p.FeedID)
+	if err != nil {
+		return fmt.Errorf("get feed: %w", err)
+	}
+	if feed == nil || !feed.IsActive {
+		slog.Debug("feed:poll — feed gone or inactive, skipping", "feed_id", p.FeedID)
+		return nil
+	}
+
+	result, err := h.fetcher.Fetch(ctx, feed.URL, derefOrEmpty(feed.ETag), derefOrEmpty(feed.LastModified))
+	if err != nil {
+		slog.Warn("feed:poll — fetch failed", "feed_id", feed.ID, "url", feed.URL, "error", err)
+		if recErr := h.feedRepo.RecordPollError(ctx, feed.ID, err.Error(), feedMaxErrors); recErr != nil {
+			return fmt.Errorf("record poll error: %w", recErr)
+		}
+		// Not retried: the next scheduled poll will try again.
+		return nil
+	}
+
+	pollResult := repository.FeedPollResult{
+		ETag:         nilIfEmpty(result.ETag),
+		LastModified: nilIfEmpty(result.LastModified),
+	}
+
+	ingested := 0
+	if !result.NotModified {
+		pollResult.Title = nilIfEmpty(result.Feed.Title)
+		pollResult.SiteURL = nilIfEmpty(result.Feed.SiteURL)
+		var complete bool
+		ingested, complete = h.ingestEntries(ctx, feed, result.Feed)
+		if !complete {
+			// Drop the validators so the next poll refetches the unseen entries.
+			pollResult.ETag, pollResult.LastModified = nil, nil
+		}
+	}
+
+	if err := h.feedRepo.RecordPollSuccess(ctx, feed.ID, pollResult); err != nil {
+		return fmt.Errorf("record poll success: %w", err)
+	}
+
+	slog.Info("feed:poll completed",
+		"feed_id", feed.ID,
+		"not_modified", result.NotModified,
+		"ingested", ingested,
+	)
+	return nil
+}
+
+// ingestEntries saves unseen entries as articles, oldest first, and returns
+// how many were ingested. It stops early (complete=false) when the user's
+// quota runs out; those entries are left unseen so a later poll picks them up.
+func (h *FeedHandler) ingestEntries(ctx context.Context, feed *domain.Feed, parsed *client.ParsedFeed) (ingested int, complete bool) {
+	entries := parsed.Entries
+	limit := feedMaxEntriesPerPoll
+	if feed.LastPolledAt == nil {
+		limit = feedInitialBackfill
+	}
+
+	// Feeds list newest first; ingest oldest first so created_at order matches.
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if e.GUID == "" {
+			continue
+		}
+
+		seen, err := h.feedRepo.HasEntry(ctx, feed.ID, e.GUID)
+		if err != nil {
+			slog.Error("feed:poll — check entry failed", "feed_id", feed.ID, "guid", e.GUID, "error", err)
+			continue
+		}
+		if seen {
+			continue
+		}
+
+		// Only the newest entries become articles; the backlog is marked as seen.
+		if i >= limit || e.URL == "" {
+			h.recordEntry(ctx, feed.ID, e.GUID, nil)
+			continue
+		}
+
+		articleID, err := h.ingestEntry(ctx, feed, e)
+		if err != nil {
+			slog.Warn("feed:poll — ingest stopped",
+				"feed_id", feed.ID,
+				"user_id", feed.UserID,
+				"guid", e.GUID,
+				"error", err,
+			)
+			return ingested, false
+		}
+		h.recordEntry(ctx, feed.ID, e.GUID, articleID)
+		if articleID != nil {
+			ingested++
+		}
+	}
+	return ingested, true
+}
+
+// ingestEntry creates the article and crawl task for one entry, mirroring
+// ArticleService.SubmitURL. A nil article ID means the URL was already saved.
+func (h *FeedHandler) ingestEntry(ctx context.Context, feed *domain.Feed, e domain.FeedEntry) (*string, error) {
+	if exists, err := h.articleRepo.ExistsByUserAndURL(ctx, feed.UserID, e.URL); err != nil {
+		return nil, fmt.Errorf("check duplicate: %w", err)
+	} else if exists {
+		return nil, nil
+	}
+
+	if err := h.quota.CheckAndIncrement(ctx, feed.UserID); err != nil {
+		return nil, err
+	}
+
+	url := e.URL
+	article, err := h.articleRepo.Create(ctx, repository.CreateArticleParams{
+		UserID:     feed.UserID,
+		URL:        &url,
+		SourceType: domain.SourceNewsletter,
+		Title:      nilIfEmpty(e.Title),
+		Author:     nilIfEmpty(e.Author),
+		SiteName:   feed.Title,
+	})
+	if err != nil {
+		_ = h.quota.DecrementQuota(ctx, feed.UserID)
+		return nil, fmt.Errorf("create article: %w", err)
+	}
+
+	task, err := h.taskRepo.Create(ctx, repository.CreateTaskParams{
+		ArticleID:  article.ID,
+		UserID:     feed.UserID,
+		URL:        &url,
+		SourceType: string(domain.SourceNewsletter),
+	})
+	if err != nil {
+		_ = h.quota.DecrementQuota(ctx, feed.UserID)
+		return nil, fmt.Errorf("create task: %w", err)
+	}
+
+	if _, err := h.enqueuer.EnqueueContext(ctx, NewCrawlTask(article.ID, task.ID, url, feed.UserID)); err != nil {
+		_ = h.quota.DecrementQuota(ctx, feed.UserID)
+		return nil, fmt.Errorf("enqueue crawl: %w", err)
+	}
+
+	slog.Info("feed entry ingested", "feed_id", feed.ID, "article_id", article.ID, "url", url)
+	return &article.ID, nil
+}
+
+func (h *FeedHandler) recordEntry(ctx context.Context, feedID, guid string, articleID *string) {
+	if err := h.feedRepo.RecordEntry(ctx, feedID, guid, articleID); err != nil {
+		slog.Error("feed:poll — record entry failed", "feed_id", feedID, "guid", guid, "error", err)
+	}
+}
@@ -0,0 +1,234 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+
+	"folio-server/internal/client"
+	"folio-server/internal/domain"
+	"folio-server/internal/repository"
+)
+
+type mockFeedRepo struct {
+	feed        *domain.Feed
+	seen        map[string]*string
+	pollResult  *repository.FeedPollResult
+	pollErrored bool
+}
+
+func (m *mockFeedRepo) GetByID(_ context.Context, _ string) (*domain.Feed, error) {
+	return m.feed, nil
+}
+
+func (m *mockFeedRepo) ListDueForPoll(_ context.Context, _ time.Time, _ int) ([]domain.Feed, error) {
+	return []domain.Feed{*m.feed}, nil
+}
+
+func (m *mockFeedRepo) RecordPollSuccess(_ context.Context, _ string, p repository.FeedPollResult) error {
+	m.pollResult = &p
+	return nil
+}
+
+func (m *mockFeedRepo) RecordPollError(_ context.Context, _, _ string, _ int) error {
+	m.pollErrored = true
+	return nil
+}
+
+func (m *mockFeedRepo) HasEntry(_ context.Context, _, guid string) (bool, error) {
+	_, ok := m.seen[guid]
+	return ok, nil
+}
+
+func (m *mockFeedRepo) RecordEntry(_ context.Context, _, guid string, articleID *string) error {
+	m.seen[guid] = articleID
+	return nil
+}
+
+type mockFeedFetcher struct {
+	result *client.FeedFetchResult
+	err    error
+}
+
+func (m *mockFeedFetcher) Fetch(_ context.Context, _, _, _ string) (*client.FeedFetchResult, error) {
+	return m.result, m.err
+}
+
+type mockFeedArticleRepo struct {
+	existing map[string]bool
+	created  []repository.CreateArticleParams
+}
+
+func (m *mockFeedArticleRepo) Create(_ context.Context, p repository.CreateArticleParams) (*domain.Article, error) {
+	m.created = append(m.created, p)
+	return &domain.Article{ID: "article-" + *p.URL}, nil
+}
+
+func (m *mockFeedArticleRepo) ExistsByUserAndURL(_ context.Context, _, url string) (bool, error) {
+	return m.existing[url], nil
+}
+
+type mockFeedTaskRepo struct{}
+
+func (m *mockFeedTaskRepo) Create(_ context.Context, p repository.CreateTaskParams) (*domain.CrawlTask, error) {
+	return &domain.CrawlTask{ID: "task-" + p.ArticleID}, nil
+}
+
+type mockFeedQuota struct {
+	remaining int
+}
+
+func (m *mockFeedQuota) CheckAndIncrement(_ context.Context, _ string) error {
+	if m.remaining <= 0 {
+		return errors.New("quota exceeded")
+	}
+	m.remaining--
+	return nil
+}
+
+func (m *mockFeedQuota) DecrementQuota(_ context.Context, _ string) error {
+	m.remaining++
+	return nil
+}
+
+type mockFeedEnqueuer struct {
+	tasks []*asynq.Task
+}
+
+func (m *mockFeedEnqueuer) EnqueueContext(_ context.Context, task *asynq.Task, _ ...asynq.Option) (*asynq.TaskInfo, error) {
+	m.tasks = append(m.tasks, task)
+	return &asynq.TaskInfo{}, nil
+}
+
+func newFeedPollTask(t *testing.T, feedID string) *asynq.Task {
+	t.Helper()
+	payload, _ := json.Marshal(FeedPollPayload{FeedID: feedID})
+	return asynq.NewTask(TypeFeedPoll, payload)
+}
+
+func feedEntries(n int) []domain.FeedEntry {
+	// Newest first, as feeds usually list them.
+	entries := make([]domain.FeedEntry, 0, n)
+	for i := n; i > 0; i-- {
+		id := string(rune('a' + i - 1))
+		entries = append(entries, domain.FeedEntry{GUID: id, URL: "https://example.com/" + id, Title: "Post " + id})
+	}
+	return entries
+}
+
+func TestFeedPoll_FirstPollBackfillsNewestOnly(t *testing.T) {
+	repo := &mockFeedRepo{
+		feed: &domain.Feed{ID: "feed-1", UserID: "user-1", URL: "https://example.com/feed", IsActive: true},
+		seen: map[string]*string{},
+	}
+	fetcher := &mockFeedFetcher{result: &client.FeedFetchResult{
+		ETag: `"v1"`,
+		Feed: &client.ParsedFeed{Title: "Example", Entries: feedEntries(8)},
+	}}
+	articles := &mockFeedArticleRepo{existing: map[string]bool{}}
+	enq := &mockFeedEnqueuer{}
+	h := NewFeedHandler(repo, fetcher, articles, &mockFeedTaskRepo{}, &mockFeedQuota{remaining: 100}, enq)
+
+	if err := h.ProcessTask(context.Background(), newFeedPollTask(t, "feed-1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(articles.created) != feedInitialBackfill {
+		t.Fatalf("created %d articles, want %d", len(articles.created), feedInitialBackfill)
+	}
+	if len(repo.seen) != 8 {
+		t.Errorf("expected all 8 entries marked seen, got %d", len(repo.seen))
+	}
+	// Oldest of the backfilled entries is created first.
+	if got := *articles.created[0].URL; got != "https://example.com/d" {
+		t.Errorf("first created URL = %q, want oldest backfilled entry", got)
+	}
+	for _, p := range articles.created {
+		if p.SourceType != domain.SourceNewsletter {
+			t.Errorf("source type = %q, want %q", p.SourceType, domain.SourceNewsletter)
+		}
+	}
+	for _, task := range enq.tasks {
+		if task.Type() != TypeCrawlArticle {
+			t.Errorf("enqueued %q, want %q", task.Type(), TypeCrawlArticle)
+		}
+	}
+	if repo.pollResult == nil || repo.pollResult.ETag == nil || *repo.pollResult.ETag != `"v1"` {
+		t.Error("expected ETag to be recorded")
+	}
+}
+
+func TestFeedPoll_SkipsSeenAndDuplicateURLs(t *testing.T) {
+	polled := time.Now().Add(-2 * time.Hour)
+	repo := &mockFeedRepo{
+		feed: &domain.Feed{ID: "feed-1", UserID: "user-1", IsActive: true, LastPolledAt: &polled},
+		seen: map[string]*string{"a": nil},
+	}
+	fetcher := &mockFeedFetcher{result: &client.FeedFetchResult{
+		Feed: &client.ParsedFeed{Entries: feedEntries(3)},
+	}}
+	articles := &mockFeedArticleRepo{existing: map[string]bool{"https://example.com/b": true}}
+	h := NewFeedHandler(repo, fetcher, articles, &mockFeedTaskRepo{}, &mockFeedQuota{remaining: 100}, &mockFeedEnqueuer{})
+
+	if err := h.ProcessTask(context.Background(), newFeedPollTask(t, "feed-1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(articles.created) != 1 || *articles.created[0].URL != "https://example.com/c" {
+		t.Fatalf("expected only entry c to be created, got %+v", articles.created)
+	}
+	if id, ok := repo.seen["b"]; !ok || id != nil {
+		t.Error("expected duplicate URL entry to be marked seen without an article")
+	}
+}
+
+func TestFeedPoll_QuotaExhaustedLeavesEntriesUnseen(t *testing.T) {
+	polled := time.Now().Add(-2 * time.Hour)
+	repo := &mockFeedRepo{
+		feed: &domain.Feed{ID: "feed-1", UserID: "user-1", IsActive: true, LastPolledAt: &polled},
+		seen: map[string]*string{},
+	}
+	fetcher := &mockFeedFetcher{result: &client.FeedFetchResult{
+		ETag: `"v2"`,
+		Feed: &client.ParsedFeed{Entries: feedEntries(3)},
+	}}
+	articles := &mockFeedArticleRepo{existing: map[string]bool{}}
+	h := NewFeedHandler(repo, fetcher, articles, &mockFeedTaskRepo{}, &mockFeedQuota{remaining: 1}, &mockFeedEnqueuer{})
+
+	if err := h.ProcessTask(context.Background(), newFeedPollTask(t, "feed-1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(articles.created) != 1 {
+		t.Fatalf("created %d articles, want 1", len(articles.created))
+	}
+	if len(repo.seen) != 1 {
+		t.Errorf("expected only the ingested entry to be marked seen, got %d", len(repo.seen))
+	}
+	if repo.pollResult == nil || repo.pollResult.ETag != nil {
+		t.Error("expected validators to be dropped so the next poll refetches")
+	}
+}
+
+func TestFeedPoll_FetchErrorIsRecorded(t *testing.T) {
+	repo := &mockFeedRepo{
+		feed: &domain.Feed{ID: "feed-1", UserID: "user-1", IsActive: true},
+		seen: map[string]*string{},
+	}
+	fetcher := &mockFeedFetcher{err: errors.New("feed error (status 500)")}
+	h := NewFeedHandler(repo, fetcher, &mockFeedArticleRepo{}, &mockFeedTaskRepo{}, &mockFeedQuota{}, &mockFeedEnqueuer{})
+
+	if err := h.ProcessTask(context.Background(), newFeedPollTask(t, "feed-1")); err != nil {
+		t.Fatalf("fetch errors should not be retried, got %v", err)
+	}
+	if !repo.pollErrored {
+		t.Error("expected poll error to be recorded")
+	}
+	if repo.pollResult != nil {
+		t.Error("expected no success record on fetch error")
+	}
+}
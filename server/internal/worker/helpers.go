@@ -1,5 +1,7 @@
 package worker
 
+import "time"
+
 // derefFloat returns the dereferenced float64 or 0 if the pointer is nil.
 func derefFloat(f *float64) float64 {
 	if f != nil {
@@ -23,3 +25,18 @@ func derefOrDefault(s *string, fallback string) string {
 	}
 	return fallback
 }
+
+// nilIfEmpty returns nil for an empty string, otherwise a pointer to it.
+func nilIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// weekStart returns Monday 00:00 UTC of the week containing t.
+func weekStart(t time.Time) time.Time {
+	t = t.UTC()
+	daysBack := (int(t.Weekday()) + 6) % 7 // Monday is 0
+	return time.Date(t.Year(), t.Month(), t.Day()-daysBack, 0, 0, 0, 0, time.UTC)
+}
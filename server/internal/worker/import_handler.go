@@ -0,0 +1,233 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/hibiken/asynq"
+
+	"folio-server/internal/domain"
+	"folio-server/internal/repository"
+)
+
+const (
+	// importBatchSize is how many items one import:process run handles.
+	importBatchSize = 100
+	// importCrawlSpacing spreads an import's crawls out on the low queue so a
+	// large migration doesn't starve interactive saves or hammer one site.
+	importCrawlSpacing = 3 * time.Second
+)
+
+// importRepo abstracts the import repository methods used by ImportHandler.
+type importRepo interface {
+	GetByID(ctx context.Context, id string) (*domain.ImportJob, error)
+	ListPendingItems(ctx context.Context, jobID string, limit int) ([]domain.ImportItem, error)
+	FinishItem(ctx context.Context, jobID string, position int, status domain.ImportItemStatus, withAI bool, articleID, errMsg *string) error
+	SetStatus(ctx context.Context, id string, status domain.ImportStatus, errMsg *string) error
+}
+
+// importArticleCreator creates articles for imported items.
+type importArticleCreator interface {
+	Create(ctx context.Context, p repository.CreateArticleParams) (*domain.Article, error)
+	ExistsByUserAndURL(ctx context.Context, userID, url string) (bool, error)
+}
+
+// importTaskCreator creates crawl tasks for imported items.
+type importTaskCreator interface {
+	Create(ctx context.Context, p repository.CreateTaskParams) (*domain.CrawlTask, error)
+}
+
+// importQuota limits how many imported articles get AI processing.
+type importQuota interface {
+	ImportAIBudget(ctx context.Context, userID string) (int, error)
+}
+
+// ImportHandler processes import:process tasks. Every item becomes an
+// article with its original saved date, flags and tags, and is crawled
+// through the regular article:crawl → article:ai pipeline on QueueLow.
+//
+// Imports never use the regular save quota or fail for quota. Each batch
+// sends as many articles to AI processing as the user's monthly import AI
+// budget has left; the others are crawled but skip AI.
+type ImportHandler struct {
+	importRepo  importRepo
+	articleRepo importArticleCreator
+	taskRepo    importTaskCreator
+	tagRepo     TagCreator
+	quota       importQuota
+	enqueuer    Enqueuer
+}
+
+func NewImportHandler(
+	importRepo importRepo,
+	articleRepo importArticleCreator,
+	taskRepo importTaskCreator,
+	tagRepo TagCreator,
+	quota importQuota,
+	enqueuer Enqueuer,
+) *ImportHandler {
+	return &ImportHandler{
+		importRepo:  importRepo,
+		articleRepo: articleRepo,
+		taskRepo:    taskRepo,
+		tagRepo:     tagRepo,
+		quota:       quota,
+		enqueuer:    enqueuer,
+	}
+}
+
+// ProcessTask handles import:process for one batch of a job.
+func (h *ImportHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
+	var p ImportPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("unmarshal payload: %w", err)
+	}
+
+	job, err := h.importRepo.GetByID(ctx, p.JobID)
+	if err != nil {
+		return fmt.Errorf("get import job: %w", err)
+	}
+	if job == nil || job.Status == domain.ImportStatusCompleted || job.Status == domain.ImportStatusFailed {
+		slog.Debug("import:process — job gone or finished, skipping", "job_id", p.JobID)
+		return nil
+	}
+	if job.Status == domain.ImportStatusPending {
+		if err := h.importRepo.SetStatus(ctx, job.ID, domain.ImportStatusRunning, nil); err != nil {
+			return fmt.Errorf("start import job: %w", err)
+		}
+	}
+
+	items, err := h.importRepo.ListPendingItems(ctx, job.ID, importBatchSize)
+	if err != nil {
+		return fmt.Errorf("list import items: %w", err)
+	}
+
+	// An error here is retried with the task rather than importing the
+	// batch without AI.
+	budget, err := h.quota.ImportAIBudget(ctx, job.UserID)
+	if err != nil {
+		return fmt.Errorf("get import AI budget: %w", err)
+	}
+
+	tagIDs := make(map[string]string)
+	crawls := 0
+	for _, it := range items {
+		withAI := budget > 0
+		status, articleID, itemErr := h.importItem(ctx, job.UserID, it, tagIDs, time.Duration(crawls)*importCrawlSpacing, withAI)
+		var errMsg *string
+		if itemErr != nil {
+			slog.Warn("import:process — item failed", "job_id", job.ID, "url", it.URL, "error", itemErr)
+			msg := itemErr.Error()
+			errMsg = &msg
+		}
+		withAI = withAI && status == domain.ImportItemImported
+		if status == domain.ImportItemImported {
+			crawls++
+		}
+		if withAI {
+			budget--
+		}
+		if err := h.importRepo.FinishItem(ctx, job.ID, it.Position, status, withAI, articleID, errMsg); err != nil {
+			return fmt.Errorf("finish import item: %w", err)
+		}
+	}
+
+	if len(items) == importBatchSize {
+		// Start the next batch once this batch's crawls have been released.
+		next := NewImportTask(job.ID)
+		if _, err := h.enqueuer.EnqueueContext(ctx, next, asynq.ProcessIn(time.Duration(crawls)*importCrawlSpacing)); err != nil {
+			return fmt.Errorf("enqueue next import batch: %w", err)
+		}
+		slog.Info("import:process batch done", "job_id", job.ID, "items", len(items), "crawls", crawls)
+		return nil
+	}
+
+	return h.finish(ctx, job.ID)
+}
+
+// importItem creates the article, tags and crawl task for one item. The
+// crawl is delayed by crawlDelay to throttle the job, and skips AI unless
+// withAI is set.
+func (h *ImportHandler) importItem(ctx context.Context, userID string, it domain.ImportItem, tagIDs map[string]string, crawlDelay time.Duration, withAI bool) (domain.ImportItemStatus, *string, error) {
+	if exists, err := h.articleRepo.ExistsByUserAndURL(ctx, userID, it.URL); err != nil {
+		return domain.ImportItemFailed, nil, fmt.Errorf("check duplicate: %w", err)
+	} else if exists {
+		return domain.ImportItemSkipped, nil, nil
+	}
+
+	url := it.URL
+	article, err := h.articleRepo.Create(ctx, repository.CreateArticleParams{
+		UserID:     userID,
+		URL:        &url,
+		SourceType: it.SourceType,
+		Title:      it.Title,
+		CreatedAt:  it.SavedAt,
+		IsFavorite: it.IsFavorite,
+		IsArchived: it.IsArchived,
+	})
+	if err != nil {
+		return domain.ImportItemFailed, nil, fmt.Errorf("create article: %w", err)
+	}
+
+	for _, name := range it.Tags {
+		key := strings.ToLower(name)
+		tagID, ok := tagIDs[key]
+		if !ok {
+			tag, err := h.tagRepo.Create(ctx, userID, name, false)
+			if err != nil {
+				slog.Error("import:process — create tag failed", "user_id", userID, "tag", name, "error", err)
+				continue
+			}
+			tagID = tag.ID
+			tagIDs[key] = tagID
+		}
+		if err := h.tagRepo.AttachToArticle(ctx, article.ID, tagID); err != nil {
+			slog.Error("import:process — attach tag failed", "article_id", article.ID, "tag_id", tagID, "error", err)
+		}
+	}
+
+	task, err := h.taskRepo.Create(ctx, repository.CreateTaskParams{
+		ArticleID:  article.ID,
+		UserID:     userID,
+		URL:        &url,
+		SourceType: string(it.SourceType),
+	})
+	if err != nil {
+		return domain.ImportItemFailed, &article.ID, fmt.Errorf("create task: %w", err)
+	}
+
+	crawl := NewCrawlTask(article.ID, task.ID, url, userID)
+	if !withAI {
+		crawl = NewCrawlTaskWithoutAI(article.ID, task.ID, url, userID)
+	}
+	if _, err := h.enqueuer.EnqueueContext(ctx, crawl, asynq.Queue(QueueLow), asynq.ProcessIn(crawlDelay)); err != nil {
+		return domain.ImportItemFailed, &article.ID, fmt.Errorf("enqueue crawl: %w", err)
+	}
+	return domain.ImportItemImported, &article.ID, nil
+}
+
+// finish marks the job completed.
+func (h *ImportHandler) finish(ctx context.Context, jobID string) error {
+	job, err := h.importRepo.GetByID(ctx, jobID)
+	if err != nil {
+		return fmt.Errorf("get import job: %w", err)
+	}
+	if job == nil {
+		return nil
+	}
+	if err := h.importRepo.SetStatus(ctx, job.ID, domain.ImportStatusCompleted, nil); err != nil {
+		return fmt.Errorf("complete import job: %w", err)
+	}
+	slog.Info("import completed",
+		"job_id", job.ID,
+		"user_id", job.UserID,
+		"imported", job.ImportedItems,
+		"skipped", job.SkippedItems,
+		"failed", job.FailedItems,
+	)
+	return nil
+}
@@ -0,0 +1,312 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+
+	"folio-server/internal/domain"
+)
+
+type mockImportRepo struct {
+	job      *domain.ImportJob
+	items    []domain.ImportItem
+	finished map[int]domain.ImportItemStatus
+	ai       map[int]bool
+}
+
+func (m *mockImportRepo) GetByID(_ context.Context, _ string) (*domain.ImportJob, error) {
+	if m.job == nil {
+		return nil, nil
+	}
+	j := *m.job
+	return &j, nil
+}
+
+func (m *mockImportRepo) ListPendingItems(_ context.Context, _ string, limit int) ([]domain.ImportItem, error) {
+	out := make([]domain.ImportItem, 0)
+	for _, it := range m.items {
+		if _, done := m.finished[it.Position]; done {
+			continue
+		}
+		out = append(out, it)
+		if len(out) == limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+func (m *mockImportRepo) FinishItem(_ context.Context, _ string, position int, status domain.ImportItemStatus, withAI bool, _, _ *string) error {
+	m.finished[position] = status
+	if withAI {
+		if m.ai == nil {
+			m.ai = map[int]bool{}
+		}
+		m.ai[position] = true
+	}
+	switch status {
+	case domain.ImportItemImported:
+		m.job.ImportedItems++
+	case domain.ImportItemSkipped:
+		m.job.SkippedItems++
+	case domain.ImportItemFailed:
+		m.job.FailedItems++
+	}
+	return nil
+}
+
+func (m *mockImportRepo) SetStatus(_ context.Context, _ string, status domain.ImportStatus, _ *string) error {
+	m.job.Status = status
+	return nil
+}
+
+type mockImportTagRepo struct {
+	created  map[string]int
+	attached map[string][]string
+}
+
+func (m *mockImportTagRepo) Create(_ context.Context, _, name string, _ bool) (*domain.Tag, error) {
+	m.created[name]++
+	return &domain.Tag{ID: "tag-" + name, Name: name}, nil
+}
+
+func (m *mockImportTagRepo) AttachToArticle(_ context.Context, articleID, tagID string) error {
+	m.attached[articleID] = append(m.attached[articleID], tagID)
+	return nil
+}
+
+// mockImportQuota allows limit imported articles with AI, less those the
+// repo has already recorded as sent to AI.
+type mockImportQuota struct {
+	repo  *mockImportRepo
+	limit int
+	err   error
+}
+
+func (m *mockImportQuota) ImportAIBudget(_ context.Context, _ string) (int, error) {
+	if m.err != nil {
+		return 0, m.err
+	}
+	return max(m.limit-len(m.repo.ai), 0), nil
+}
+
+// mockImportEnqueuer records tasks with the options passed at enqueue time.
+type mockImportEnqueuer struct {
+	tasks []*asynq.Task
+	opts  [][]asynq.Option
+}
+
+func (m *mockImportEnqueuer) EnqueueContext(_ context.Context, task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error) {
+	m.tasks = append(m.tasks, task)
+	m.opts = append(m.opts, opts)
+	return &asynq.TaskInfo{}, nil
+}
+
+func importItems(n int) []domain.ImportItem {
+	items := make([]domain.ImportItem, n)
+	for i := range items {
+		items[i] = domain.ImportItem{
+			JobID:      "job-1",
+			Position:   i,
+			URL:        fmt.Sprintf("https://example.com/%d", i),
+			SourceType: domain.SourceWeb,
+			Status:     domain.ImportItemPending,
+		}
+	}
+	return items
+}
+
+func newImportTestHandler(repo *mockImportRepo, articles *mockFeedArticleRepo) (*ImportHandler, *mockImportTagRepo, *mockImportQuota, *mockImportEnqueuer) {
+	tags := &mockImportTagRepo{created: map[string]int{}, attached: map[string][]string{}}
+	quota := &mockImportQuota{repo: repo, limit: 1000}
+	enq := &mockImportEnqueuer{}
+	return NewImportHandler(repo, articles, &mockFeedTaskRepo{}, tags, quota, enq), tags, quota, enq
+}
+
+func newImportProcessTask(jobID string) *asynq.Task {
+	payload, _ := json.Marshal(ImportPayload{JobID: jobID})
+	return asynq.NewTask(TypeImportProcess, payload)
+}
+
+func findOption(opts []asynq.Option, typ asynq.OptionType) (any, bool) {
+	for _, o := range opts {
+		if o.Type() == typ {
+			return o.Value(), true
+		}
+	}
+	return nil, false
+}
+
+func TestImportHandler_CompletesSmallJob(t *testing.T) {
+	saved := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	title := "Kept title"
+	items := importItems(3)
+	items[0].Title = &title
+	items[0].SavedAt = &saved
+	items[0].IsFavorite = true
+	items[0].Tags = []string{"Go", "go-web"}
+	items[1].IsArchived = true
+	items[1].Tags = []string{"Go"}
+
+	repo := &mockImportRepo{
+		job:      &domain.ImportJob{ID: "job-1", UserID: "user-1", Status: domain.ImportStatusPending, TotalItems: 3},
+		items:    items,
+		finished: map[int]domain.ImportItemStatus{},
+	}
+	articles := &mockFeedArticleRepo{existing: map[string]bool{"https://example.com/2": true}}
+	h, tags, _, enq := newImportTestHandler(repo, articles)
+
+	if err := h.ProcessTask(context.Background(), newImportProcessTask("job-1")); err != nil {
+		t.Fatalf("ProcessTask: %v", err)
+	}
+
+	if repo.job.Status != domain.ImportStatusCompleted {
+		t.Errorf("status = %q, want completed", repo.job.Status)
+	}
+	if repo.finished[2] != domain.ImportItemSkipped {
+		t.Errorf("duplicate item status = %q, want skipped", repo.finished[2])
+	}
+	if len(repo.ai) != 2 {
+		t.Errorf("%d items sent to AI, want 2 (not the skipped item)", len(repo.ai))
+	}
+
+	if len(articles.created) != 2 {
+		t.Fatalf("created %d articles, want 2", len(articles.created))
+	}
+	first := articles.created[0]
+	if first.CreatedAt == nil || !first.CreatedAt.Equal(saved) || !first.IsFavorite || *first.Title != title {
+		t.Errorf("first article params = %+v", first)
+	}
+	if !articles.created[1].IsArchived {
+		t.Error("second article should be archived")
+	}
+	if tags.created["Go"] != 1 {
+		t.Errorf("tag Go created %d times, want 1 (cached per batch)", tags.created["Go"])
+	}
+
+	// Two crawls on the low queue, spaced apart.
+	if len(enq.tasks) != 2 {
+		t.Fatalf("enqueued %d tasks, want 2 crawls", len(enq.tasks))
+	}
+	for i, opts := range enq.opts {
+		if q, _ := findOption(opts, asynq.QueueOpt); q != QueueLow {
+			t.Errorf("crawl %d queue = %v, want %q", i, q, QueueLow)
+		}
+		d, _ := findOption(opts, asynq.ProcessInOpt)
+		if want := time.Duration(i) * importCrawlSpacing; d != want {
+			t.Errorf("crawl %d delay = %v, want %v", i, d, want)
+		}
+	}
+}
+
+func TestImportHandler_ChainsBatches(t *testing.T) {
+	repo := &mockImportRepo{
+		job:      &domain.ImportJob{ID: "job-1", UserID: "user-1", Status: domain.ImportStatusRunning, TotalItems: importBatchSize + 5},
+		items:    importItems(importBatchSize + 5),
+		finished: map[int]domain.ImportItemStatus{},
+	}
+	h, _, _, enq := newImportTestHandler(repo, &mockFeedArticleRepo{existing: map[string]bool{}})
+
+	if err := h.ProcessTask(context.Background(), newImportProcessTask("job-1")); err != nil {
+		t.Fatalf("ProcessTask: %v", err)
+	}
+	if len(repo.finished) != importBatchSize {
+		t.Fatalf("processed %d items, want %d", len(repo.finished), importBatchSize)
+	}
+	last := enq.tasks[len(enq.tasks)-1]
+	if last.Type() != TypeImportProcess {
+		t.Fatalf("last task = %q, want next import batch", last.Type())
+	}
+	if repo.job.Status != domain.ImportStatusRunning {
+		t.Errorf("status = %q, want running", repo.job.Status)
+	}
+
+	if err := h.ProcessTask(context.Background(), newImportProcessTask("job-1")); err != nil {
+		t.Fatalf("ProcessTask (batch 2): %v", err)
+	}
+	if repo.job.Status != domain.ImportStatusCompleted || repo.job.ImportedItems != importBatchSize+5 {
+		t.Errorf("job = %+v, want completed with all items imported", repo.job)
+	}
+	if len(repo.ai) != importBatchSize+5 {
+		t.Errorf("%d items sent to AI, want %d", len(repo.ai), importBatchSize+5)
+	}
+}
+
+func TestImportHandler_OverQuotaImportsWithoutAI(t *testing.T) {
+	repo := &mockImportRepo{
+		job:      &domain.ImportJob{ID: "job-1", UserID: "user-1", Status: domain.ImportStatusPending, TotalItems: 3},
+		items:    importItems(3),
+		finished: map[int]domain.ImportItemStatus{},
+	}
+	articles := &mockFeedArticleRepo{existing: map[string]bool{}}
+	h, _, quota, enq := newImportTestHandler(repo, articles)
+	quota.limit = 1
+
+	if err := h.ProcessTask(context.Background(), newImportProcessTask("job-1")); err != nil {
+		t.Fatalf("ProcessTask: %v", err)
+	}
+
+	// Every item is imported and crawled; only the one within the quota
+	// goes on to AI processing.
+	if repo.job.Status != domain.ImportStatusCompleted || len(articles.created) != 3 {
+		t.Fatalf("status = %q, created %d articles; want completed with 3", repo.job.Status, len(articles.created))
+	}
+	if len(repo.ai) != 1 || !repo.ai[0] {
+		t.Errorf("items sent to AI = %v, want only item 0", repo.ai)
+	}
+	if len(enq.tasks) != 3 {
+		t.Fatalf("enqueued %d tasks, want 3 crawls", len(enq.tasks))
+	}
+	for i, task := range enq.tasks {
+		var p CrawlPayload
+		if err := json.Unmarshal(task.Payload(), &p); err != nil {
+			t.Fatal(err)
+		}
+		if want := i > 0; p.SkipAI != want {
+			t.Errorf("crawl %d SkipAI = %v, want %v", i, p.SkipAI, want)
+		}
+	}
+}
+
+func TestImportHandler_BudgetErrorRetries(t *testing.T) {
+	repo := &mockImportRepo{
+		job:      &domain.ImportJob{ID: "job-1", UserID: "user-1", Status: domain.ImportStatusPending, TotalItems: 2},
+		items:    importItems(2),
+		finished: map[int]domain.ImportItemStatus{},
+	}
+	articles := &mockFeedArticleRepo{existing: map[string]bool{}}
+	h, _, quota, enq := newImportTestHandler(repo, articles)
+	quota.err = fmt.Errorf("connection reset")
+
+	// A transient error must fail the task so asynq retries it, not import
+	// the batch without AI.
+	if err := h.ProcessTask(context.Background(), newImportProcessTask("job-1")); err == nil {
+		t.Fatal("ProcessTask succeeded, want the budget error")
+	}
+	if len(repo.finished) != 0 || len(articles.created) != 0 || len(enq.tasks) != 0 {
+		t.Errorf("processed %d items, created %d articles, enqueued %d tasks; want none",
+			len(repo.finished), len(articles.created), len(enq.tasks))
+	}
+}
+
+func TestImportHandler_SkipsFinishedJob(t *testing.T) {
+	repo := &mockImportRepo{
+		job:      &domain.ImportJob{ID: "job-1", UserID: "user-1", Status: domain.ImportStatusCompleted},
+		items:    importItems(2),
+		finished: map[int]domain.ImportItemStatus{},
+	}
+	articles := &mockFeedArticleRepo{existing: map[string]bool{}}
+	h, _, _, _ := newImportTestHandler(repo, articles)
+
+	if err := h.ProcessTask(context.Background(), newImportProcessTask("job-1")); err != nil {
+		t.Fatalf("ProcessTask: %v", err)
+	}
+	if len(articles.created) != 0 {
+		t.Errorf("created %d articles for a finished job", len(articles.created))
+	}
+}
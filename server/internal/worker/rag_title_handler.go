@@ -0,0 +1,102 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/hibiken/asynq"
+
+	"folio-server/internal/client"
+	"folio-server/internal/domain"
+)
+
+// ragTitleRepo abstracts the RAG repository methods used by RAGTitleHandler.
+type ragTitleRepo interface {
+	GetConversation(ctx context.Context, conversationID string) (*domain.RAGConversation, error)
+	GetConversationMessages(ctx context.Context, userID, conversationID string, limit int) ([]domain.RAGMessage, error)
+	ReplaceProvisionalTitle(ctx context.Context, conversationID, userID, provisional, title string) (bool, error)
+}
+
+// ragTitler abstracts the AI method for naming conversations.
+type ragTitler interface {
+	GenerateConversationTitle(ctx context.Context, question, answer string) (string, error)
+}
+
+// RAGTitleHandler processes rag:title tasks: it replaces the provisional
+// title of a new conversation (the truncated first question) with one
+// generated from the first question and answer.
+type RAGTitleHandler struct {
+	ragRepo  ragTitleRepo
+	aiClient ragTitler
+}
+
+// NewRAGTitleHandler creates a RAGTitleHandler.
+func NewRAGTitleHandler(ragRepo ragTitleRepo, aiClient ragTitler) *RAGTitleHandler {
+	return &RAGTitleHandler{ragRepo: ragRepo, aiClient: aiClient}
+}
+
+func (h *RAGTitleHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
+	var p RAGTitlePayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("unmarshal rag title payload: %w", err)
+	}
+
+	conv, err := h.ragRepo.GetConversation(ctx, p.ConversationID)
+	if err != nil {
+		return fmt.Errorf("get conversation %s: %w", p.ConversationID, err)
+	}
+	if conv == nil || conv.UserID != p.UserID {
+		slog.Info("[RAG_TITLE] conversation gone, skipping", "conversation_id", p.ConversationID)
+		return nil
+	}
+
+	msgs, err := h.ragRepo.GetConversationMessages(ctx, p.UserID, p.ConversationID, 0)
+	if err != nil {
+		return fmt.Errorf("get conversation messages: %w", err)
+	}
+	question, answer := firstTurn(msgs)
+	if question == "" {
+		return nil
+	}
+
+	title, err := h.aiClient.GenerateConversationTitle(ctx, question, answer)
+	if err != nil {
+		return fmt.Errorf("generate conversation title: %w", err)
+	}
+
+	provisional := ""
+	if conv.Title != nil {
+		provisional = *conv.Title
+	}
+	replaced, err := h.ragRepo.ReplaceProvisionalTitle(ctx, p.ConversationID, p.UserID, provisional, title)
+	if err != nil {
+		return fmt.Errorf("save conversation title: %w", err)
+	}
+
+	slog.Info("[RAG_TITLE] completed",
+		"conversation_id", p.ConversationID,
+		"replaced", replaced,
+	)
+	return nil
+}
+
+// firstTurn returns the first question of a conversation and the answer
+// text that followed it.
+func firstTurn(msgs []domain.RAGMessage) (question, answer string) {
+	for _, m := range msgs {
+		switch {
+		case m.Role == "user" && question == "":
+			question = m.Content
+		case m.Role == "assistant" && question != "":
+			// Assistant content is the stored RAG result JSON.
+			var result client.RAGResult
+			if err := json.Unmarshal([]byte(m.Content), &result); err == nil {
+				return question, result.Answer
+			}
+			return question, m.Content
+		}
+	}
+	return question, ""
+}
@@ -0,0 +1,78 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"folio-server/internal/domain"
+)
+
+type mockRAGTitleRepo struct {
+	conv     *domain.RAGConversation
+	msgs     []domain.RAGMessage
+	replaced []string // provisional, title
+}
+
+func (m *mockRAGTitleRepo) GetConversation(_ context.Context, _ string) (*domain.RAGConversation, error) {
+	return m.conv, nil
+}
+
+func (m *mockRAGTitleRepo) GetConversationMessages(_ context.Context, _, _ string, _ int) ([]domain.RAGMessage, error) {
+	return m.msgs, nil
+}
+
+func (m *mockRAGTitleRepo) ReplaceProvisionalTitle(_ context.Context, _, _, provisional, title string) (bool, error) {
+	m.replaced = []string{provisional, title}
+	return true, nil
+}
+
+type stubTitler struct{ question, answer string }
+
+func (s *stubTitler) GenerateConversationTitle(_ context.Context, question, answer string) (string, error) {
+	s.question, s.answer = question, answer
+	return "Go concurrency", nil
+}
+
+func TestRAGTitleHandler_ReplacesProvisionalTitle(t *testing.T) {
+	provisional := "Why are goroutines cheap?"
+	repo := &mockRAGTitleRepo{
+		conv: &domain.RAGConversation{ID: "conv-1", UserID: "user-1", Title: &provisional},
+		msgs: []domain.RAGMessage{
+			{Role: "user", Content: provisional},
+			{Role: "assistant", Content: `{"answer":"Small stacks.","cited_indices":[1]}`},
+		},
+	}
+	titler := &stubTitler{}
+
+	if err := NewRAGTitleHandler(repo, titler).ProcessTask(context.Background(), NewRAGTitleTask("conv-1", "user-1")); err != nil {
+		t.Fatalf("ProcessTask: %v", err)
+	}
+	if titler.question != provisional || titler.answer != "Small stacks." {
+		t.Errorf("titled from %q / %q", titler.question, titler.answer)
+	}
+	if len(repo.replaced) != 2 || repo.replaced[0] != provisional || repo.replaced[1] != "Go concurrency" {
+		t.Errorf("replaced = %v", repo.replaced)
+	}
+}
+
+func TestRAGTitleHandler_SkipsOtherUsersConversation(t *testing.T) {
+	repo := &mockRAGTitleRepo{conv: &domain.RAGConversation{ID: "conv-1", UserID: "someone-else"}}
+
+	if err := NewRAGTitleHandler(repo, &stubTitler{}).ProcessTask(context.Background(), NewRAGTitleTask("conv-1", "user-1")); err != nil {
+		t.Fatalf("ProcessTask: %v", err)
+	}
+	if repo.replaced != nil {
+		t.Errorf("replaced title of another user's conversation: %v", repo.replaced)
+	}
+}
+
+func TestNewRAGTitleTask(t *testing.T) {
+	var p RAGTitlePayload
+	if err := json.Unmarshal(NewRAGTitleTask("conv-1", "user-1").Payload(), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.ConversationID != "conv-1" || p.UserID != "user-1" {
+		t.Errorf("payload = %+v", p)
+	}
+}
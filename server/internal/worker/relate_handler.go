@@ -15,7 +15,11 @@ import (
 )
 
 type relateRAGRepo interface {
-	BroadRecallSummaries(ctx context.Context, userID string, keywords []string, limit int, excludeID string) ([]domain.RAGSource, error)
+	BroadRecallSummaries(ctx context.Context, userID string, keywords []string, limit int, excludeID string, scope *domain.RAGScope) ([]domain.RAGSource, error)
+}
+
+type relateVectorSearcher interface {
+	SimilarSummaries(ctx context.Context, userID, articleID string, limit int) ([]domain.RAGSource, error)
 }
 
 type relateSelector interface {
@@ -26,24 +30,33 @@ type relateRelationRepo interface {
 	SaveBatch(ctx context.Context, sourceID string, relations []repository.ArticleRelation) error
 }
 
+// relateRecallSize is how many candidates are recalled for the LLM to choose from.
+const relateRecallSize = 30
+
 type RelateHandler struct {
 	articleRepo  ArticleGetter
 	ragRepo      relateRAGRepo
+	vectors      relateVectorSearcher
 	aiClient     relateSelector
 	relationRepo relateRelationRepo
+	enqueuer     Enqueuer
 }
 
 func NewRelateHandler(
 	articleRepo ArticleGetter,
 	ragRepo relateRAGRepo,
+	vectors relateVectorSearcher,
 	aiClient relateSelector,
 	relationRepo relateRelationRepo,
+	enqueuer Enqueuer,
 ) *RelateHandler {
 	return &RelateHandler{
 		articleRepo:  articleRepo,
 		ragRepo:      ragRepo,
+		vectors:      vectors,
 		aiClient:     aiClient,
 		relationRepo: relationRepo,
+		enqueuer:     enqueuer,
 	}
 }
 
@@ -60,16 +73,24 @@ func (h *RelateHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
 		return fmt.Errorf("get article %s: %w", p.ArticleID, err)
 	}
 
-	// Need semantic_keywords to do recall
-	if len(article.SemanticKeywords) == 0 {
-		slog.Info("[RELATE] skipping — no semantic_keywords", "article_id", p.ArticleID)
-		return nil
+	// Hybrid recall, excluding self: nearest articles by embedding, plus
+	// keyword recall on the article's semantic_keywords.
+	var byVector, byKeyword []domain.RAGSource
+	if h.vectors != nil {
+		byVector, err = h.vectors.SimilarSummaries(ctx, p.UserID, p.ArticleID, relateRecallSize)
+		if err != nil {
+			slog.Warn("[RELATE] vector recall failed", "article_id", p.ArticleID, "error", err)
+		}
 	}
-
-	// Broad recall using article's semantic_keywords, excluding self
-	candidates, err := h.ragRepo.BroadRecallSummaries(ctx, p.UserID, article.SemanticKeywords, 30, p.ArticleID)
-	if err != nil || len(candidates) == 0 {
-		slog.Info("[RELATE] no candidates found", "article_id", p.ArticleID, "error", err)
+	if len(article.SemanticKeywords) > 0 {
+		byKeyword, err = h.ragRepo.BroadRecallSummaries(ctx, p.UserID, article.SemanticKeywords, relateRecallSize, p.ArticleID, nil)
+		if err != nil {
+			slog.Warn("[RELATE] keyword recall failed", "article_id", p.ArticleID, "error", err)
+		}
+	}
+	candidates := domain.FuseRAGSources(relateRecallSize, byVector, byKeyword)
+	if len(candidates) == 0 {
+		slog.Info("[RELATE] no candidates found", "article_id", p.ArticleID)
 		return nil // Not an error — just no related articles
 	}
 
@@ -122,11 +143,16 @@ func (h *RelateHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
 		if err := h.relationRepo.SaveBatch(ctx, p.ArticleID, relations); err != nil {
 			return fmt.Errorf("save relations: %w", err)
 		}
+		// Follow up with related Echo cards connecting the articles.
+		if _, err := h.enqueuer.EnqueueContext(ctx, NewEchoRelatedTask(p.ArticleID, p.UserID)); err != nil {
+			slog.Warn("[RELATE] enqueue echo:related failed", "article_id", p.ArticleID, "error", err)
+		}
 	}
 
 	slog.Info("[RELATE] completed",
 		"article_id", p.ArticleID,
 		"candidates", len(candidates),
+		"by_vector", len(byVector),
 		"related", len(relations),
 		"duration_ms", time.Since(start).Milliseconds(),
 	)
@@ -9,7 +9,7 @@ type WorkerServer struct {
 	mux    *asynq.ServeMux
 }
 
-func NewWorkerServer(redisAddr string, crawl *CrawlHandler, ai *AIHandler, image *ImageHandler, echo *EchoHandler, push *PushHandler, relate *RelateHandler) *WorkerServer {
+func NewWorkerServer(redisAddr string, crawl *CrawlHandler, ai *AIHandler, image *ImageHandler, echo *EchoHandler, echoOptimize *EchoOptimizeHandler, push *PushHandler, relate *RelateHandler, embed *EmbedHandler, ragTitle *RAGTitleHandler, feed *FeedHandler, imp *ImportHandler, export *ExportHandler, purge *AccountPurgeHandler) *WorkerServer {
 	srv := asynq.NewServer(
 		asynq.RedisClientOpt{Addr: redisAddr},
 		asynq.Config{
@@ -30,6 +30,11 @@ func NewWorkerServer(redisAddr string, crawl *CrawlHandler, ai *AIHandler, image
 	}
 	if echo != nil {
 		mux.HandleFunc(TypeEchoGenerate, echo.ProcessTask)
+		mux.HandleFunc(TypeEchoRelated, echo.ProcessRelated)
+	}
+	if echoOptimize != nil {
+		mux.HandleFunc(TypeEchoSchedule, echoOptimize.ProcessSchedule)
+		mux.HandleFunc(TypeEchoOptimize, echoOptimize.ProcessTask)
 	}
 	if push != nil {
 		mux.HandleFunc(TypePushEcho, push.ProcessTask)
@@ -37,6 +42,26 @@ func NewWorkerServer(redisAddr string, crawl *CrawlHandler, ai *AIHandler, image
 	if relate != nil {
 		mux.HandleFunc(TypeRelateArticle, relate.ProcessTask)
 	}
+	if embed != nil {
+		mux.HandleFunc(TypeEmbedArticle, embed.ProcessTask)
+	}
+	if ragTitle != nil {
+		mux.HandleFunc(TypeRAGTitle, ragTitle.ProcessTask)
+	}
+	if feed != nil {
+		mux.HandleFunc(TypeFeedSchedule, feed.ProcessSchedule)
+		mux.HandleFunc(TypeFeedPoll, feed.ProcessTask)
+	}
+	if imp != nil {
+		mux.HandleFunc(TypeImportProcess, imp.ProcessTask)
+	}
+	if export != nil {
+		mux.HandleFunc(TypeExportBuild, export.ProcessTask)
+		mux.HandleFunc(TypeExportExpire, export.ProcessExpire)
+	}
+	if purge != nil {
+		mux.HandleFunc(TypeAccountPurge, purge.ProcessTask)
+	}
 
 	return &WorkerServer{server: srv, mux: mux}
 }
@@ -12,8 +12,19 @@ const (
 	TypeAIProcess    = "article:ai"
 	TypeImageUpload  = "article:images"
 	TypeEchoGenerate  = "echo:generate"
+	TypeEchoOptimize  = "echo:optimize"
+	TypeEchoRelated   = "echo:related"
+	TypeEchoSchedule  = "echo:optimize-schedule"
 	TypePushEcho      = "push:echo"
 	TypeRelateArticle = "article:relate"
+	TypeEmbedArticle  = "article:embed"
+	TypeFeedSchedule  = "feed:schedule"
+	TypeFeedPoll      = "feed:poll"
+	TypeImportProcess = "import:process"
+	TypeExportBuild   = "export:build"
+	TypeExportExpire  = "export:expire"
+	TypeAccountPurge  = "account:purge"
+	TypeRAGTitle      = "rag:title"
 
 	QueueCritical = "critical"
 	QueueDefault  = "default"
@@ -25,6 +36,9 @@ type CrawlPayload struct {
 	TaskID    string `json:"task_id"`
 	URL       string `json:"url"`
 	UserID    string `json:"user_id"`
+	// SkipAI finishes the article after the crawl instead of enqueuing
+	// article:ai; set for imported articles over the monthly quota.
+	SkipAI bool `json:"skip_ai,omitempty"`
 }
 
 type AIProcessPayload struct {
@@ -43,12 +57,28 @@ type ImageUploadPayload struct {
 }
 
 func NewCrawlTask(articleID, taskID, url, userID string) *asynq.Task {
-	payload, _ := json.Marshal(CrawlPayload{
+	return newCrawlTask(CrawlPayload{
 		ArticleID: articleID,
 		TaskID:    taskID,
 		URL:       url,
 		UserID:    userID,
 	})
+}
+
+// NewCrawlTaskWithoutAI is NewCrawlTask for an article that is crawled but
+// not sent to article:ai.
+func NewCrawlTaskWithoutAI(articleID, taskID, url, userID string) *asynq.Task {
+	return newCrawlTask(CrawlPayload{
+		ArticleID: articleID,
+		TaskID:    taskID,
+		URL:       url,
+		UserID:    userID,
+		SkipAI:    true,
+	})
+}
+
+func newCrawlTask(p CrawlPayload) *asynq.Task {
+	payload, _ := json.Marshal(p)
 	return asynq.NewTask(TypeCrawlArticle, payload,
 		asynq.Queue(QueueCritical),
 		asynq.MaxRetry(3),
@@ -100,6 +130,25 @@ type RelatePayload struct {
 	UserID    string `json:"user_id"`
 }
 
+type EmbedPayload struct {
+	ArticleID string `json:"article_id"`
+	UserID    string `json:"user_id"`
+}
+
+// NewEmbedTask embeds an article's content for semantic retrieval. The
+// relate task is enqueued when it finishes, so relations can use the vectors.
+func NewEmbedTask(articleID, userID string) *asynq.Task {
+	payload, _ := json.Marshal(EmbedPayload{
+		ArticleID: articleID,
+		UserID:    userID,
+	})
+	return asynq.NewTask(TypeEmbedArticle, payload,
+		asynq.Queue(QueueLow),
+		asynq.MaxRetry(3),
+		asynq.Timeout(2*time.Minute),
+	)
+}
+
 func NewRelateTask(articleID, userID string) *asynq.Task {
 	payload, _ := json.Marshal(RelatePayload{
 		ArticleID: articleID,
@@ -112,6 +161,22 @@ func NewRelateTask(articleID, userID string) *asynq.Task {
 	)
 }
 
+// NewEchoRelatedTask creates related Echo cards from the relations saved for
+// an article. Reuses RelatePayload. The unique window keeps a burst of
+// re-relations from generating cards for the same article twice.
+func NewEchoRelatedTask(articleID, userID string) *asynq.Task {
+	payload, _ := json.Marshal(RelatePayload{
+		ArticleID: articleID,
+		UserID:    userID,
+	})
+	return asynq.NewTask(TypeEchoRelated, payload,
+		asynq.Queue(QueueLow),
+		asynq.MaxRetry(2),
+		asynq.Timeout(60*time.Second),
+		asynq.Unique(10*time.Minute),
+	)
+}
+
 func NewImageUploadTask(articleID string, imageURLs []string) *asynq.Task {
 	payload, _ := json.Marshal(ImageUploadPayload{
 		ArticleID: articleID,
@@ -123,3 +188,127 @@ func NewImageUploadTask(articleID string, imageURLs []string) *asynq.Task {
 		asynq.Timeout(5*time.Minute),
 	)
 }
+
+type FeedPollPayload struct {
+	FeedID string `json:"feed_id"`
+}
+
+// NewFeedPollTask polls a single feed. The unique window keeps the scheduler
+// and manual refreshes from polling the same feed concurrently.
+func NewFeedPollTask(feedID string) *asynq.Task {
+	payload, _ := json.Marshal(FeedPollPayload{FeedID: feedID})
+	return asynq.NewTask(TypeFeedPoll, payload,
+		asynq.Queue(QueueLow),
+		asynq.MaxRetry(1),
+		asynq.Timeout(2*time.Minute),
+		asynq.Unique(10*time.Minute),
+	)
+}
+
+// NewFeedScheduleTask fans out feed:poll tasks for every feed that is due.
+func NewFeedScheduleTask() *asynq.Task {
+	return asynq.NewTask(TypeFeedSchedule, nil,
+		asynq.Queue(QueueLow),
+		asynq.MaxRetry(1),
+		asynq.Timeout(2*time.Minute),
+	)
+}
+
+type EchoOptimizePayload struct {
+	UserID string `json:"user_id"`
+}
+
+// NewEchoOptimizeTask fits a user's FSRS weights to their review history.
+func NewEchoOptimizeTask(userID string) *asynq.Task {
+	payload, _ := json.Marshal(EchoOptimizePayload{UserID: userID})
+	return asynq.NewTask(TypeEchoOptimize, payload,
+		asynq.Queue(QueueLow),
+		asynq.MaxRetry(1),
+		asynq.Timeout(5*time.Minute),
+		asynq.Unique(time.Hour),
+	)
+}
+
+// NewEchoOptimizeScheduleTask fans out echo:optimize tasks for every user
+// whose weights are due to be refitted.
+func NewEchoOptimizeScheduleTask() *asynq.Task {
+	return asynq.NewTask(TypeEchoSchedule, nil,
+		asynq.Queue(QueueLow),
+		asynq.MaxRetry(1),
+		asynq.Timeout(2*time.Minute),
+	)
+}
+
+type ImportPayload struct {
+	JobID string `json:"job_id"`
+}
+
+// NewImportTask processes the next batch of an import job. Each batch
+// enqueues the following one, so a job is worked through one batch at a time.
+func NewImportTask(jobID string) *asynq.Task {
+	payload, _ := json.Marshal(ImportPayload{JobID: jobID})
+	return asynq.NewTask(TypeImportProcess, payload,
+		asynq.Queue(QueueLow),
+		asynq.MaxRetry(5),
+		asynq.Timeout(5*time.Minute),
+	)
+}
+
+type ExportPayload struct {
+	JobID string `json:"job_id"`
+}
+
+// NewExportBuildTask builds and uploads the archive for an export job.
+func NewExportBuildTask(jobID string) *asynq.Task {
+	payload, _ := json.Marshal(ExportPayload{JobID: jobID})
+	return asynq.NewTask(TypeExportBuild, payload,
+		asynq.Queue(QueueLow),
+		asynq.MaxRetry(2),
+		asynq.Timeout(30*time.Minute),
+	)
+}
+
+// NewExportExpireTask deletes an export's archive once its download link
+// has expired. It is enqueued with a ProcessIn delay when the export completes.
+func NewExportExpireTask(jobID string) *asynq.Task {
+	payload, _ := json.Marshal(ExportPayload{JobID: jobID})
+	return asynq.NewTask(TypeExportExpire, payload,
+		asynq.Queue(QueueLow),
+		asynq.MaxRetry(5),
+	)
+}
+
+type AccountPurgePayload struct {
+	UserID   string   `json:"user_id"`
+	Prefixes []string `json:"prefixes"`
+}
+
+// NewAccountPurgeTask deletes a deleted account's objects from storage.
+// The database rows are already gone when it runs, so the payload carries
+// every prefix to purge.
+func NewAccountPurgeTask(userID string, prefixes []string) *asynq.Task {
+	payload, _ := json.Marshal(AccountPurgePayload{UserID: userID, Prefixes: prefixes})
+	return asynq.NewTask(TypeAccountPurge, payload,
+		asynq.Queue(QueueLow),
+		asynq.MaxRetry(10),
+		asynq.Timeout(30*time.Minute),
+	)
+}
+
+// RAGTitlePayload names a RAG conversation to give a generated title.
+type RAGTitlePayload struct {
+	ConversationID string `json:"conversation_id"`
+	UserID         string `json:"user_id"`
+}
+
+func NewRAGTitleTask(conversationID, userID string) *asynq.Task {
+	payload, _ := json.Marshal(RAGTitlePayload{
+		ConversationID: conversationID,
+		UserID:         userID,
+	})
+	return asynq.NewTask(TypeRAGTitle, payload,
+		asynq.Queue(QueueLow),
+		asynq.MaxRetry(2),
+		asynq.Timeout(60*time.Second),
+	)
+}
@@ -0,0 +1,3 @@
+-- 013_feeds.down.sql
+DROP TABLE IF EXISTS feed_entries;
+DROP TABLE IF EXISTS feeds;
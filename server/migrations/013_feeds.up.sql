@@ -0,0 +1,35 @@
+-- 013_feeds.up.sql
+
+-- 1. Feed subscriptions (RSS / Atom / JSON Feed)
+CREATE TABLE feeds (
+    id             UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id        UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    url            TEXT NOT NULL,
+    title          TEXT,
+    site_url       TEXT,
+    etag           TEXT,
+    last_modified  TEXT,
+    last_polled_at TIMESTAMPTZ,
+    last_error     TEXT,
+    error_count    INT NOT NULL DEFAULT 0,
+    is_active      BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, url)
+);
+CREATE INDEX idx_feeds_user ON feeds (user_id);
+CREATE INDEX idx_feeds_poll ON feeds (last_polled_at NULLS FIRST) WHERE is_active;
+
+CREATE TRIGGER tr_feeds_updated_at
+    BEFORE UPDATE ON feeds
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at();
+
+-- 2. Seen entries, deduplicated per feed by GUID
+CREATE TABLE feed_entries (
+    id         UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    feed_id    UUID NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    guid       TEXT NOT NULL,
+    article_id UUID REFERENCES articles(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (feed_id, guid)
+);
@@ -0,0 +1,3 @@
+-- 014_mail_ingest.down.sql
+DROP TABLE IF EXISTS mail_allowed_senders;
+DROP TABLE IF EXISTS mail_inboxes;
@@ -0,0 +1,23 @@
+-- 014_mail_ingest.up.sql
+
+-- 1. Private ingest address per user: <token>@MAIL_DOMAIN
+CREATE TABLE mail_inboxes (
+    user_id    UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    token      VARCHAR(32) NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TRIGGER tr_mail_inboxes_updated_at
+    BEFORE UPDATE ON mail_inboxes
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at();
+
+-- 2. Optional sender allow-list; an empty list accepts any sender.
+--    pattern is either a full address or "@domain".
+CREATE TABLE mail_allowed_senders (
+    id         UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id    UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    pattern    TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, pattern)
+);
@@ -0,0 +1,3 @@
+-- 015_pdf_documents.down.sql
+ALTER TABLE highlights DROP COLUMN IF EXISTS page;
+ALTER TABLE articles DROP COLUMN IF EXISTS file_url;
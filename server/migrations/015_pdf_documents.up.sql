@@ -0,0 +1,7 @@
+-- 015_pdf_documents.up.sql
+-- Uploaded documents (PDF): link to the stored original, and page anchors
+-- for highlights made on them.
+
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS file_url TEXT;
+
+ALTER TABLE highlights ADD COLUMN IF NOT EXISTS page INTEGER;
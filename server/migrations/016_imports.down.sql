@@ -0,0 +1,3 @@
+-- 016_imports.down.sql
+DROP TABLE IF EXISTS import_items;
+DROP TABLE IF EXISTS import_jobs;
@@ -0,0 +1,44 @@
+-- 016_imports.up.sql
+
+-- 1. Import jobs (Pocket / Instapaper / Omnivore exports, bookmark files)
+CREATE TABLE import_jobs (
+    id              UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id         UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    source          VARCHAR(20) NOT NULL,
+    status          VARCHAR(20) NOT NULL DEFAULT 'pending',
+    total_items     INT NOT NULL,
+    imported_items  INT NOT NULL DEFAULT 0,
+    skipped_items   INT NOT NULL DEFAULT 0,
+    failed_items    INT NOT NULL DEFAULT 0,
+    error           TEXT,
+    finished_at     TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_import_jobs_user ON import_jobs (user_id, created_at DESC);
+
+CREATE TRIGGER tr_import_jobs_updated_at
+    BEFORE UPDATE ON import_jobs
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at();
+
+-- 2. Parsed export items, processed in position order
+CREATE TABLE import_items (
+    job_id      UUID NOT NULL REFERENCES import_jobs(id) ON DELETE CASCADE,
+    position    INT NOT NULL,
+    url         TEXT NOT NULL,
+    source_type VARCHAR(20) NOT NULL DEFAULT 'web',
+    title       TEXT,
+    tags        TEXT[] NOT NULL DEFAULT '{}',
+    saved_at    TIMESTAMPTZ,
+    is_favorite BOOLEAN NOT NULL DEFAULT FALSE,
+    is_archived BOOLEAN NOT NULL DEFAULT FALSE,
+    status      VARCHAR(20) NOT NULL DEFAULT 'pending',
+    article_id  UUID REFERENCES articles(id) ON DELETE SET NULL,
+    error       TEXT,
+    -- ai marks items sent on to AI processing; they count against the
+    -- monthly import AI budget (QuotaService.ImportAIBudget).
+    ai          BOOLEAN NOT NULL DEFAULT FALSE,
+    finished_at TIMESTAMPTZ,
+    PRIMARY KEY (job_id, position)
+);
+CREATE INDEX idx_import_items_pending ON import_items (job_id, position) WHERE status = 'pending';
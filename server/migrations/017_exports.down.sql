@@ -0,0 +1,2 @@
+-- 017_exports.down.sql
+DROP TABLE IF EXISTS export_jobs;
@@ -0,0 +1,21 @@
+-- 017_exports.up.sql
+
+-- Library exports (Markdown / Obsidian vault archives stored in R2)
+CREATE TABLE export_jobs (
+    id            UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id       UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status        VARCHAR(20) NOT NULL DEFAULT 'pending',
+    article_count INT NOT NULL DEFAULT 0,
+    object_key    TEXT,
+    size_bytes    BIGINT NOT NULL DEFAULT 0,
+    error         TEXT,
+    expires_at    TIMESTAMPTZ,
+    finished_at   TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_export_jobs_user ON export_jobs (user_id, created_at DESC);
+
+CREATE TRIGGER tr_export_jobs_updated_at
+    BEFORE UPDATE ON export_jobs
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at();
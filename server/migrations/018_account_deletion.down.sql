@@ -0,0 +1,7 @@
+-- 018_account_deletion.down.sql
+ALTER TABLE export_jobs DROP COLUMN IF EXISTS format;
+
+ALTER TABLE crawl_tasks DROP CONSTRAINT IF EXISTS crawl_tasks_user_id_fkey;
+ALTER TABLE crawl_tasks
+    ADD CONSTRAINT crawl_tasks_user_id_fkey
+        FOREIGN KEY (user_id) REFERENCES users(id);
@@ -0,0 +1,13 @@
+-- 018_account_deletion.up.sql
+
+-- 1. crawl_tasks was the only user-owned table without ON DELETE CASCADE,
+--    which blocked deleting a user row.
+ALTER TABLE crawl_tasks DROP CONSTRAINT IF EXISTS crawl_tasks_user_id_fkey;
+ALTER TABLE crawl_tasks
+    ADD CONSTRAINT crawl_tasks_user_id_fkey
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+-- 2. Exports can be a Markdown vault or a JSON bundle of all personal data.
+ALTER TABLE export_jobs
+    ADD COLUMN format VARCHAR(20) NOT NULL DEFAULT 'markdown'
+        CHECK (format IN ('markdown', 'json'));
@@ -0,0 +1,8 @@
+-- 019_subscription_lifecycle.down.sql
+
+DROP TABLE IF EXISTS subscription_events;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS subscription_event_at,
+    DROP COLUMN IF EXISTS subscription_auto_renew,
+    DROP COLUMN IF EXISTS subscription_state;
@@ -0,0 +1,35 @@
+-- 019_subscription_lifecycle.up.sql
+
+-- Explicit subscription state; users.subscription (free/pro) follows from it.
+ALTER TABLE users
+    ADD COLUMN subscription_state VARCHAR(20) NOT NULL DEFAULT 'none'
+        CHECK (subscription_state IN ('none', 'active', 'grace_period', 'billing_retry', 'expired', 'refunded', 'revoked')),
+    ADD COLUMN subscription_auto_renew BOOLEAN NOT NULL DEFAULT FALSE,
+    -- Signing time of the last applied event; older events are not applied.
+    ADD COLUMN subscription_event_at TIMESTAMPTZ;
+
+UPDATE users SET subscription_state = 'active', subscription_auto_renew = TRUE
+WHERE subscription = 'pro';
+UPDATE users SET subscription_state = 'expired'
+WHERE subscription = 'free' AND original_transaction_id IS NOT NULL;
+
+-- History of App Store notifications and the transitions they caused
+CREATE TABLE subscription_events (
+    id                      UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id                 UUID REFERENCES users(id) ON DELETE CASCADE,
+    notification_uuid       TEXT UNIQUE,
+    notification_type       VARCHAR(40) NOT NULL,
+    subtype                 VARCHAR(40) NOT NULL DEFAULT '',
+    original_transaction_id TEXT,
+    transaction_id          TEXT,
+    product_id              TEXT,
+    from_state              VARCHAR(20),
+    to_state                VARCHAR(20),
+    expires_at              TIMESTAMPTZ,
+    auto_renew              BOOLEAN,
+    applied                 BOOLEAN NOT NULL DEFAULT FALSE,
+    signed_at               TIMESTAMPTZ NOT NULL,
+    created_at              TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_subscription_events_user ON subscription_events (user_id, signed_at DESC);
+CREATE INDEX idx_subscription_events_original_txn ON subscription_events (original_transaction_id);
@@ -0,0 +1,5 @@
+-- 020_auth_sessions.down.sql
+ALTER TABLE users DROP COLUMN IF EXISTS sessions_revoked_at;
+DROP TABLE IF EXISTS legacy_refresh_tokens;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS auth_sessions;
@@ -0,0 +1,40 @@
+-- 020_auth_sessions.up.sql
+
+-- Signed-in devices. Each session is one refresh token family: logging in
+-- starts a session and every refresh rotates the token within it.
+CREATE TABLE auth_sessions (
+    id           UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id      UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    device_name  TEXT,
+    user_agent   TEXT,
+    expires_at   TIMESTAMPTZ NOT NULL,
+    revoked_at   TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_auth_sessions_user ON auth_sessions (user_id, last_used_at DESC)
+    WHERE revoked_at IS NULL;
+
+-- Refresh tokens are stored as SHA-256 hashes. Used tokens are kept so that
+-- presenting one again can be detected as reuse.
+CREATE TABLE refresh_tokens (
+    id         UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    session_id UUID NOT NULL REFERENCES auth_sessions(id) ON DELETE CASCADE,
+    token_hash BYTEA NOT NULL UNIQUE,
+    used_at    TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_refresh_tokens_session ON refresh_tokens (session_id);
+
+-- Refresh JWTs issued before sessions existed, by hash, once moved into a
+-- session. Kept after the session's used tokens are pruned so that each
+-- legacy token is accepted only once.
+CREATE TABLE legacy_refresh_tokens (
+    token_hash BYTEA PRIMARY KEY,
+    user_id    UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    adopted_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- When the user last signed out their other devices. Legacy refresh JWTs
+-- issued before then are no longer accepted.
+ALTER TABLE users ADD COLUMN sessions_revoked_at TIMESTAMPTZ;
@@ -0,0 +1,2 @@
+-- 021_article_embeddings.down.sql
+DROP TABLE IF EXISTS article_embeddings;
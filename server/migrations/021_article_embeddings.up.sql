@@ -0,0 +1,23 @@
+-- 021_article_embeddings.up.sql
+
+-- Chunk embeddings for semantic retrieval (replaces the placeholder dropped
+-- in 012). Requires the pgvector extension.
+CREATE EXTENSION IF NOT EXISTS vector;
+
+-- The vector column has no fixed dimension because the embedding model is
+-- configurable; model records which one produced each row, and searches
+-- only compare rows of the same model. Searches are always scoped to one
+-- user, so an exact scan over the user's rows is used instead of an ANN index.
+CREATE TABLE article_embeddings (
+    id          UUID        PRIMARY KEY DEFAULT uuid_generate_v4(),
+    article_id  UUID        NOT NULL REFERENCES articles(id) ON DELETE CASCADE,
+    user_id     UUID        NOT NULL REFERENCES users(id)    ON DELETE CASCADE,
+    model       TEXT        NOT NULL,
+    chunk_index INTEGER     NOT NULL,
+    chunk_text  TEXT        NOT NULL,
+    embedding   vector      NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (article_id, chunk_index)
+);
+
+CREATE INDEX idx_article_embeddings_user_model ON article_embeddings (user_id, model);
@@ -0,0 +1,4 @@
+-- 022_embedding_passages.down.sql
+ALTER TABLE article_embeddings
+    DROP COLUMN IF EXISTS start_offset,
+    DROP COLUMN IF EXISTS end_offset;
@@ -0,0 +1,9 @@
+-- 022_embedding_passages.up.sql
+
+-- Body chunks keep the exact passage in chunk_text and its rune offsets in
+-- articles.markdown_content (the units highlights use), so RAG answers can
+-- quote and deep-link to it. The title/summary chunk has no offsets, nor do
+-- chunks embedded before this migration until the article is re-embedded.
+ALTER TABLE article_embeddings
+    ADD COLUMN start_offset INTEGER,
+    ADD COLUMN end_offset   INTEGER;
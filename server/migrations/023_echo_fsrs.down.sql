@@ -0,0 +1,14 @@
+-- 023_echo_fsrs.down.sql
+DROP TABLE IF EXISTS echo_scheduler_params;
+
+ALTER TABLE echo_reviews DROP COLUMN IF EXISTS rating;
+
+-- Map difficulty back onto an ease factor, inverting the up migration.
+ALTER TABLE echo_cards ADD COLUMN ease_factor DECIMAL(4,2) NOT NULL DEFAULT 2.50;
+UPDATE echo_cards SET ease_factor = LEAST(3.0, GREATEST(1.3, 3.0 - (difficulty - 1) / 9 * 1.7))
+WHERE difficulty IS NOT NULL;
+
+ALTER TABLE echo_cards
+    DROP COLUMN IF EXISTS stability,
+    DROP COLUMN IF EXISTS difficulty,
+    DROP COLUMN IF EXISTS last_reviewed_at;
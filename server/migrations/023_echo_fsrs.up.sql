@@ -0,0 +1,43 @@
+-- 023_echo_fsrs.up.sql
+
+-- Echo cards are scheduled with FSRS instead of SM-2. A card's memory is its
+-- stability (days until recall probability falls to 90%) and difficulty
+-- (1-10); both stay NULL until the first review.
+ALTER TABLE echo_cards
+    ADD COLUMN stability        DOUBLE PRECISION,
+    ADD COLUMN difficulty       DOUBLE PRECISION,
+    ADD COLUMN last_reviewed_at TIMESTAMPTZ;
+
+-- Carry SM-2 state over. At the default 90% retention an FSRS interval equals
+-- the stability, so the current interval becomes the stability; ease factors
+-- 1.3-3.0 map linearly onto difficulty 10-1.
+UPDATE echo_cards SET
+    stability        = GREATEST(interval_days, 1),
+    difficulty       = LEAST(10, GREATEST(1, 1 + (3.0 - ease_factor) / 1.7 * 9)),
+    last_reviewed_at = next_review_at - make_interval(days => interval_days)
+WHERE review_count > 0;
+
+ALTER TABLE echo_cards DROP COLUMN ease_factor;
+
+-- Reviews are graded Again (1), Hard (2), Good (3) or Easy (4). Past binary
+-- reviews are graded as domain.RatingFromResult grades them: by response time.
+ALTER TABLE echo_reviews ADD COLUMN rating SMALLINT CHECK (rating BETWEEN 1 AND 4);
+
+UPDATE echo_reviews SET rating = CASE
+    WHEN result = 'forgot'          THEN 1
+    WHEN response_time_ms <= 4000   THEN 4
+    WHEN response_time_ms >= 15000  THEN 2
+    ELSE 3
+END;
+
+ALTER TABLE echo_reviews ALTER COLUMN rating SET NOT NULL;
+
+-- Per-user FSRS weights fitted to the user's review history by the
+-- echo:optimize job. Users without a row use the default weights.
+CREATE TABLE echo_scheduler_params (
+    user_id      UUID             PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    weights      DOUBLE PRECISION[] NOT NULL,
+    review_count INTEGER          NOT NULL,
+    log_loss     DOUBLE PRECISION NOT NULL,
+    optimized_at TIMESTAMPTZ      NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,9 @@
+-- 024_echo_card_management.down.sql
+ALTER TABLE echo_cards
+    DROP COLUMN IF EXISTS suspended_at,
+    DROP COLUMN IF EXISTS buried_until;
+
+DELETE FROM echo_cards WHERE card_type = 'manual';
+ALTER TABLE echo_cards DROP CONSTRAINT IF EXISTS echo_cards_card_type_check;
+ALTER TABLE echo_cards ADD CONSTRAINT echo_cards_card_type_check
+    CHECK (card_type IN ('insight', 'highlight', 'related'));
@@ -0,0 +1,12 @@
+-- 024_echo_card_management.up.sql
+
+-- Users can write their own cards.
+ALTER TABLE echo_cards DROP CONSTRAINT IF EXISTS echo_cards_card_type_check;
+ALTER TABLE echo_cards ADD CONSTRAINT echo_cards_card_type_check
+    CHECK (card_type IN ('insight', 'highlight', 'related', 'manual'));
+
+-- A suspended card is never due and keeps its schedule for when it is
+-- unsuspended; a buried card is not due before buried_until.
+ALTER TABLE echo_cards
+    ADD COLUMN suspended_at TIMESTAMPTZ,
+    ADD COLUMN buried_until TIMESTAMPTZ;
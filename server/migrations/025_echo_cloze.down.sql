@@ -0,0 +1,13 @@
+-- 025_echo_cloze.down.sql
+DROP INDEX IF EXISTS idx_echo_cards_sibling_group;
+ALTER TABLE echo_cards DROP CONSTRAINT IF EXISTS echo_cards_cloze_check;
+
+DELETE FROM echo_cards WHERE card_type = 'cloze';
+ALTER TABLE echo_cards
+    DROP COLUMN IF EXISTS cloze_start,
+    DROP COLUMN IF EXISTS cloze_end,
+    DROP COLUMN IF EXISTS sibling_group;
+
+ALTER TABLE echo_cards DROP CONSTRAINT IF EXISTS echo_cards_card_type_check;
+ALTER TABLE echo_cards ADD CONSTRAINT echo_cards_card_type_check
+    CHECK (card_type IN ('insight', 'highlight', 'related', 'manual'));
@@ -0,0 +1,20 @@
+-- 025_echo_cloze.up.sql
+
+-- Cloze cards blank a key term out of a highlighted passage: question holds
+-- the passage, answer the term, and cloze_start/cloze_end its rune offsets.
+ALTER TABLE echo_cards DROP CONSTRAINT IF EXISTS echo_cards_card_type_check;
+ALTER TABLE echo_cards ADD CONSTRAINT echo_cards_card_type_check
+    CHECK (card_type IN ('insight', 'highlight', 'related', 'manual', 'cloze'));
+
+ALTER TABLE echo_cards
+    ADD COLUMN cloze_start   INTEGER,
+    ADD COLUMN cloze_end     INTEGER,
+    ADD COLUMN sibling_group UUID;
+
+ALTER TABLE echo_cards ADD CONSTRAINT echo_cards_cloze_check
+    CHECK (card_type <> 'cloze' OR (cloze_start >= 0 AND cloze_end > cloze_start));
+
+-- Cards made from one note (the clozes of one highlight) share a sibling
+-- group; at most one sibling is due per day.
+CREATE INDEX idx_echo_cards_sibling_group
+    ON echo_cards (sibling_group) WHERE sibling_group IS NOT NULL;
@@ -0,0 +1,9 @@
+-- 026_article_fts.down.sql
+DROP TRIGGER IF EXISTS articles_search_vector_trigger ON articles;
+DROP FUNCTION IF EXISTS articles_search_vector_update();
+DROP INDEX IF EXISTS idx_articles_search_vector;
+ALTER TABLE articles DROP COLUMN IF EXISTS search_vector;
+DROP FUNCTION IF EXISTS folio_article_search_vector(TEXT, TEXT, TEXT, TEXT, TEXT);
+DROP FUNCTION IF EXISTS folio_fts_query(TEXT, BOOLEAN);
+DROP FUNCTION IF EXISTS folio_cjk_unigrams(TEXT);
+DROP FUNCTION IF EXISTS folio_cjk_bigrams(TEXT);
@@ -0,0 +1,80 @@
+-- 026_article_fts.up.sql
+
+-- Full-text search over articles. The built-in parsers do not segment CJK
+-- text, so CJK runs are split into overlapping bigrams ("机器学习" →
+-- "机器 器学 学习") before the 'simple' configuration indexes them; other
+-- text is indexed by 'simple' as-is. A CJK run of one character is kept
+-- as a unigram.
+CREATE OR REPLACE FUNCTION folio_cjk_bigrams(input TEXT) RETURNS TEXT
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+    SELECT COALESCE(string_agg(
+        CASE WHEN t.m[1] ~ '^[\u3040-\u30ff\u3400-\u4dbf\u4e00-\u9fff\uf900-\ufaff\uac00-\ud7af]'
+            THEN (SELECT string_agg(substr(t.m[1], i, 2), ' ' ORDER BY i)
+                  FROM generate_series(1, GREATEST(char_length(t.m[1]) - 1, 1)) AS i)
+            ELSE t.m[1]
+        END, ' ' ORDER BY t.ord), '')
+    FROM regexp_matches(COALESCE(input, ''),
+        '[\u3040-\u30ff\u3400-\u4dbf\u4e00-\u9fff\uf900-\ufaff\uac00-\ud7af]+|[^\u3040-\u30ff\u3400-\u4dbf\u4e00-\u9fff\uf900-\ufaff\uac00-\ud7af]+',
+        'g') WITH ORDINALITY AS t(m, ord)
+$$;
+
+-- folio_cjk_unigrams spaces out every CJK character. It is only used to
+-- build ts_headline snippets, whose highlights must line up with the text
+-- shown to the user; the API joins the characters back together.
+CREATE OR REPLACE FUNCTION folio_cjk_unigrams(input TEXT) RETURNS TEXT
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+    SELECT regexp_replace(COALESCE(input, ''),
+        '([\u3040-\u30ff\u3400-\u4dbf\u4e00-\u9fff\uf900-\ufaff\uac00-\ud7af])', ' \1 ', 'g')
+$$;
+
+-- folio_fts_query turns user input into a tsquery: every whitespace-separated
+-- term must match, and each term matches as a phrase of its tokens. With
+-- bigrams false the query targets folio_cjk_unigrams text instead. A single
+-- CJK character matches as a prefix of the bigrams it starts.
+CREATE OR REPLACE FUNCTION folio_fts_query(q TEXT, bigrams BOOLEAN DEFAULT TRUE) RETURNS tsquery
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+    SELECT COALESCE(string_agg('(' || t.query || ')', ' & '), '')::tsquery
+    FROM (
+        SELECT CASE
+            WHEN bigrams AND term ~ '^[\u3040-\u30ff\u3400-\u4dbf\u4e00-\u9fff\uf900-\ufaff\uac00-\ud7af]$'
+                THEN to_tsquery('simple', quote_literal(term) || ':*')::text
+            WHEN bigrams THEN phraseto_tsquery('simple', folio_cjk_bigrams(term))::text
+            ELSE phraseto_tsquery('simple', folio_cjk_unigrams(term))::text
+        END AS query
+        FROM regexp_split_to_table(btrim(COALESCE(q, '')), '\s+') AS term
+        WHERE term <> ''
+    ) t
+    WHERE t.query <> ''
+$$;
+
+-- Title ranks above summary, summary above byline, byline above the body.
+-- The body is capped well below the tsvector size limit.
+CREATE OR REPLACE FUNCTION folio_article_search_vector(
+    title TEXT, summary TEXT, author TEXT, site_name TEXT, body TEXT
+) RETURNS tsvector
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+    SELECT setweight(to_tsvector('simple', folio_cjk_bigrams(title)), 'A')
+        || setweight(to_tsvector('simple', folio_cjk_bigrams(summary)), 'B')
+        || setweight(to_tsvector('simple', folio_cjk_bigrams(concat_ws(' ', author, site_name))), 'C')
+        || setweight(to_tsvector('simple', folio_cjk_bigrams(left(body, 100000))), 'D')
+$$;
+
+ALTER TABLE articles ADD COLUMN search_vector tsvector;
+
+CREATE INDEX idx_articles_search_vector ON articles USING GIN (search_vector);
+
+CREATE OR REPLACE FUNCTION articles_search_vector_update() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    NEW.search_vector := folio_article_search_vector(
+        NEW.title, NEW.summary, NEW.author, NEW.site_name, NEW.markdown_content);
+    RETURN NEW;
+END;
+$$;
+
+-- Existing rows keep a NULL search_vector until the backfill command
+-- (cmd/backfill-search) fills them in; they are not searchable until then.
+CREATE TRIGGER articles_search_vector_trigger
+    BEFORE INSERT OR UPDATE OF title, summary, author, site_name, markdown_content
+    ON articles
+    FOR EACH ROW EXECUTE FUNCTION articles_search_vector_update();
@@ -0,0 +1,7 @@
+-- 027_unified_search.down.sql
+DROP INDEX IF EXISTS idx_rag_messages_search;
+DROP INDEX IF EXISTS idx_echo_cards_search;
+DROP INDEX IF EXISTS idx_highlights_search;
+DROP FUNCTION IF EXISTS folio_rag_message_search_vector(TEXT);
+DROP FUNCTION IF EXISTS folio_echo_card_search_vector(TEXT, TEXT);
+DROP FUNCTION IF EXISTS folio_highlight_search_vector(TEXT, TEXT);
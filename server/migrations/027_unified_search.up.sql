@@ -0,0 +1,32 @@
+-- 027_unified_search.up.sql
+
+-- Search vectors for the other searchable rows, tokenized like
+-- articles.search_vector (migration 026) so ranks are comparable. They are
+-- computed, not stored: each table gets an expression index, and queries
+-- must call the same function for the index to apply.
+
+-- A highlight's text ranks with an article title, its note with a summary.
+CREATE OR REPLACE FUNCTION folio_highlight_search_vector(highlight_text TEXT, note TEXT) RETURNS tsvector
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+    SELECT setweight(to_tsvector('simple', folio_cjk_bigrams(highlight_text)), 'A')
+        || setweight(to_tsvector('simple', folio_cjk_bigrams(note)), 'B')
+$$;
+
+CREATE OR REPLACE FUNCTION folio_echo_card_search_vector(question TEXT, answer TEXT) RETURNS tsvector
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+    SELECT setweight(to_tsvector('simple', folio_cjk_bigrams(question)), 'A')
+        || setweight(to_tsvector('simple', folio_cjk_bigrams(answer)), 'B')
+$$;
+
+-- RAG messages can be long answers; they rank with article bodies.
+CREATE OR REPLACE FUNCTION folio_rag_message_search_vector(content TEXT) RETURNS tsvector
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+    SELECT setweight(to_tsvector('simple', folio_cjk_bigrams(left(content, 100000))), 'D')
+$$;
+
+CREATE INDEX idx_highlights_search
+    ON highlights USING GIN (folio_highlight_search_vector(text, note));
+CREATE INDEX idx_echo_cards_search
+    ON echo_cards USING GIN (folio_echo_card_search_vector(question, answer));
+CREATE INDEX idx_rag_messages_search
+    ON rag_messages USING GIN (folio_rag_message_search_vector(content));
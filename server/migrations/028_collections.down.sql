@@ -0,0 +1,6 @@
+-- 028_collections.down.sql
+ALTER TABLE export_jobs
+    DROP COLUMN IF EXISTS article_filter,
+    DROP COLUMN IF EXISTS collection_id;
+
+DROP TABLE IF EXISTS collections;
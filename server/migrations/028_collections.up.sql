@@ -0,0 +1,24 @@
+-- 028_collections.up.sql
+
+-- 1. Smart collections: named saved searches, evaluated live. filter holds a
+--    domain.ArticleFilter as JSON.
+CREATE TABLE collections (
+    id         UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id    UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name       VARCHAR(100) NOT NULL,
+    filter     JSONB NOT NULL DEFAULT '{}',
+    position   INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, name)
+);
+
+CREATE TRIGGER tr_collections_updated_at
+    BEFORE UPDATE ON collections
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at();
+
+-- 2. Exports of a collection. The filter is copied when the export is
+--    created, so editing or deleting the collection doesn't change it.
+ALTER TABLE export_jobs
+    ADD COLUMN collection_id  UUID REFERENCES collections(id) ON DELETE SET NULL,
+    ADD COLUMN article_filter JSONB;
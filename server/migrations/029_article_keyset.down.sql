@@ -0,0 +1,5 @@
+-- 029_article_keyset.down.sql
+CREATE INDEX IF NOT EXISTS idx_articles_created_at ON articles (user_id, created_at DESC);
+
+DROP INDEX IF EXISTS idx_articles_user_updated_id;
+DROP INDEX IF EXISTS idx_articles_user_created_id;
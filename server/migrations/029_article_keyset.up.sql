@@ -0,0 +1,10 @@
+-- 029_article_keyset.up.sql
+
+-- Keyset pagination of article listings (ArticleRepo.ListPage). Browsing
+-- walks (created_at, id) newest first; sync walks (updated_at, id) oldest
+-- first. The id column makes each order total; the first index supersedes
+-- the created_at-only one.
+CREATE INDEX idx_articles_user_created_id ON articles (user_id, created_at DESC, id DESC);
+CREATE INDEX idx_articles_user_updated_id ON articles (user_id, updated_at, id);
+
+DROP INDEX IF EXISTS idx_articles_created_at;
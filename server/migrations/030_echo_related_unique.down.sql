@@ -0,0 +1,2 @@
+-- 030_echo_related_unique.down.sql
+DROP INDEX IF EXISTS idx_echo_cards_related_pair;
@@ -0,0 +1,24 @@
+-- 030_echo_related_unique.up.sql
+
+-- At most one related card per user and unordered pair of articles, so
+-- concurrent relate runs cannot both create a card for the same pair
+-- (EchoRepo.CreateRelatedCard). Existing duplicates keep their oldest card.
+--
+-- related_article_id is set to NULL when the related article is deleted, and
+-- LEAST/GREATEST ignore NULLs, so such cards are left out of the index;
+-- otherwise two orphaned cards of one source article would collide and the
+-- delete would fail.
+DELETE FROM echo_cards c
+USING echo_cards d
+WHERE c.card_type = 'related' AND d.card_type = 'related'
+  AND c.related_article_id IS NOT NULL AND d.related_article_id IS NOT NULL
+  AND c.user_id = d.user_id
+  AND LEAST(c.article_id, c.related_article_id) = LEAST(d.article_id, d.related_article_id)
+  AND GREATEST(c.article_id, c.related_article_id) = GREATEST(d.article_id, d.related_article_id)
+  AND (d.created_at, d.id) < (c.created_at, c.id);
+
+CREATE UNIQUE INDEX idx_echo_cards_related_pair ON echo_cards (
+    user_id,
+    LEAST(article_id, related_article_id),
+    GREATEST(article_id, related_article_id)
+) WHERE card_type = 'related' AND related_article_id IS NOT NULL;